@@ -0,0 +1,59 @@
+// acme-sidecar prevents shutdown while a reverse proxy is obtaining or
+// renewing a certificate over ACME. Caddy's orders are found from the lock
+// files in its storage, Traefik's by following its log.
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/acme"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	var proxies []acme.Source
+	if dir := sidecarmain.Env("CADDY_DATA_DIR", ""); dir != "" {
+		proxies = append(proxies, acme.NewCaddy(dir))
+	}
+	if path := sidecarmain.Env("TRAEFIK_LOG", ""); path != "" {
+		traefik := acme.NewTraefik(path)
+		// ACME_MAX_ORDER stops blocking for an order whose end wasn't logged
+		traefik.MaxOrder = sidecarmain.Duration("ACME_MAX_ORDER", acme.DefaultMaxOrder)
+		proxies = append(proxies, traefik)
+	}
+	if len(proxies) == 0 {
+		logging.Fatalf("CADDY_DATA_DIR or TRAEFIK_LOG required")
+	}
+
+	checker := &acmeChecker{
+		checker: acme.NewChecker(proxies),
+	}
+
+	sidecarmain.Run(checker)
+}
+
+type acmeChecker struct {
+	checker *acme.Checker
+}
+
+func (c *acmeChecker) Name() string {
+	return "acme"
+}
+
+func (c *acmeChecker) Check(ctx context.Context) (bool, string, error) {
+	reasons, err := c.checker.Activity(ctx)
+	if err != nil {
+		// The storage or log isn't readable, so an order could be running
+		return false, "", err
+	}
+
+	if len(reasons) > 0 {
+		return true, strings.Join(reasons, "; "), nil
+	}
+
+	return false, "", nil
+}
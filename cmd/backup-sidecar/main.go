@@ -0,0 +1,50 @@
+// backup-sidecar prevents shutdown while a restic or borg backup is running.
+// This runs on the host (or with the repositories and /proc mounted).
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/backup"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	// BACKUP_RESTIC_REPOS, BACKUP_BORG_REPOS, BACKUP_PROCESS_PATTERN and
+	// PROC_ROOT are shared with time-to-safe
+	detector, err := backup.DetectorFromEnv()
+	if err != nil {
+		logging.Fatalf("%v", err)
+	}
+
+	checker := &backupChecker{detector: detector}
+
+	sidecarmain.Run(checker)
+}
+
+type backupChecker struct {
+	detector *backup.Detector
+}
+
+func (c *backupChecker) Name() string {
+	return "backup"
+}
+
+func (c *backupChecker) Check(ctx context.Context) (bool, string, error) {
+	active, err := c.detector.Active(time.Now())
+	if err != nil {
+		return false, "", err
+	}
+
+	if len(active) > 0 {
+		return true, fmt.Sprintf("backup in progress: %s", strings.Join(active, "; ")), nil
+	}
+
+	return false, "", nil
+}
@@ -0,0 +1,50 @@
+// btrbk-sidecar prevents shutdown while btrbk or a plain btrfs send/receive
+// is transferring snapshots, since an interrupted incremental send means a
+// full send to the target next time.
+// This runs on the host, where it can see the transfer processes.
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/btrbk"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	// BTRBK_PROCESS_PATTERN and PROC_ROOT are shared with time-to-safe
+	detector, err := btrbk.DetectorFromEnv()
+	if err != nil {
+		logging.Fatalf("%v", err)
+	}
+
+	checker := &btrbkChecker{detector: detector}
+
+	sidecarmain.Run(checker)
+}
+
+type btrbkChecker struct {
+	detector *btrbk.Detector
+}
+
+func (c *btrbkChecker) Name() string {
+	return "btrbk"
+}
+
+func (c *btrbkChecker) Check(ctx context.Context) (bool, string, error) {
+	active, err := c.detector.Active()
+	if err != nil {
+		return false, "", err
+	}
+
+	if len(active) > 0 {
+		return true, fmt.Sprintf("btrfs send/receive in progress: %s", strings.Join(active, "; ")), nil
+	}
+
+	return false, "", nil
+}
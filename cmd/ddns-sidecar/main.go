@@ -0,0 +1,61 @@
+// ddns-sidecar watches that a dynamic DNS record points at the host's
+// public IP. It never blocks shutdown, since a reboot won't fix a stale
+// record: once the two have differed for DDNS_THRESHOLD each check fails,
+// which is notified and served on /healthz. Run with the "healthcheck"
+// argument it instead exits non-zero if they differ after boot, for use as
+// a greenboot health check.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/ddns"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	// DDNS_THRESHOLD allows for the updater noticing a new address and
+	// resolvers caching the old one for the record's TTL
+	checker := &ddnsChecker{
+		checker: ddns.NewChecker(sidecarmain.RequireEnv("DDNS_HOSTNAME"), sidecarmain.Duration("DDNS_THRESHOLD", 30*time.Minute), 10*time.Second),
+	}
+	// DDNS_IP_URL=https://api6.ipify.org checks the AAAA record instead
+	checker.checker.IPURL = sidecarmain.Env("DDNS_IP_URL", ddns.DefaultIPURL)
+	// DDNS_RESOLVER, e.g. 1.1.1.1:53, bypasses a local resolver that
+	// answers the name with a private address
+	if server := sidecarmain.Env("DDNS_RESOLVER", ""); server != "" {
+		checker.checker.Resolver = ddns.NewResolver(server)
+	}
+
+	if flag.Arg(0) == "healthcheck" {
+		// Wait for the record to match the public IP, e.g. after an update
+		// broke the DDNS updater, retrying while the updater catches up with
+		// an address that changed during the reboot
+		os.Exit(sidecarmain.Healthcheck{
+			Name:   "ddns",
+			Budget: sidecarmain.Duration("DDNS_HEALTH_TIMEOUT", 10*time.Minute),
+			Check:  checker.checker.Health,
+		}.Run(flag.Args()[1:]))
+	}
+
+	sidecarmain.Run(checker)
+}
+
+type ddnsChecker struct {
+	checker *ddns.Checker
+}
+
+func (c *ddnsChecker) Name() string {
+	return "ddns"
+}
+
+// Check never reports busy: a stale record is returned as an error, which
+// keeps the inhibitor released
+func (c *ddnsChecker) Check(ctx context.Context) (bool, string, error) {
+	return false, "", c.checker.Check(ctx)
+}
@@ -0,0 +1,56 @@
+// dns-healthcheck exits non-zero unless the local DNS filter (Pi-hole or
+// AdGuard Home) reports its DNS server running and the server answers a
+// query, for use as a greenboot health check. The filter is usually the
+// LAN's only resolver, so booting into an update that broke it takes the
+// whole network down, which is worth a rollback.
+//
+// Unlike the sidecars it takes no inhibitor: a resolver has no work a
+// shutdown would interrupt.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/dnsfilter"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	var filters []dnsfilter.Filter
+	if url := sidecarmain.Env("PIHOLE_URL", ""); url != "" {
+		// PIHOLE_PASSWORD, the web or an app password, if one is set
+		filters = append(filters, dnsfilter.NewPiHole(url, sidecarmain.Secret("PIHOLE_PASSWORD"), 10*time.Second))
+	}
+	if url := sidecarmain.Env("ADGUARD_URL", ""); url != "" {
+		filters = append(filters, dnsfilter.NewAdGuard(url, sidecarmain.Env("ADGUARD_USER", ""), sidecarmain.Secret("ADGUARD_PASS"), 10*time.Second))
+	}
+	if len(filters) == 0 {
+		logging.Fatalf("PIHOLE_URL or ADGUARD_URL required")
+	}
+
+	// DNS_QUERY_NAME is looked up through each filter. A name the filter
+	// answers itself, such as a local DNS record, keeps an upstream
+	// outage from failing the check.
+	name := sidecarmain.Env("DNS_QUERY_NAME", "example.com")
+
+	// "healthcheck" is accepted so the invocation matches the sidecars'
+	args := flag.Args()
+	if len(args) > 0 && args[0] == "healthcheck" {
+		args = args[1:]
+	}
+	// Wait for every filter to answer, retrying while the filter is still
+	// loading its blocklists
+	os.Exit(sidecarmain.Healthcheck{
+		Name:   "dns",
+		Budget: sidecarmain.Duration("DNS_HEALTH_TIMEOUT", 2*time.Minute),
+		Check: func(ctx context.Context) error {
+			return dnsfilter.Health(ctx, filters, name)
+		},
+	}.Run(args))
+}
@@ -0,0 +1,77 @@
+// dvr-sidecar prevents shutdown while a DVR is recording, or will start
+// recording before the machine would be back up: within DVR_LOOKAHEAD plus
+// DVR_BOOT_TIME. It reads the schedules of Jellyfin Live TV, Plex DVR and
+// Tvheadend, whichever are configured.
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/dvr"
+	"github.com/addisonbair/homelab-sidecars/pkg/jellyfin"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/timezone"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	var schedulers []dvr.Scheduler
+	if url := sidecarmain.Env("JELLYFIN_URL", ""); url != "" {
+		var client *jellyfin.Client
+		if keyFile := sidecarmain.Env("JELLYFIN_API_KEY_FILE", ""); keyFile != "" && sidecarmain.Env("JELLYFIN_API_KEY", "") == "" {
+			var err error
+			if client, err = jellyfin.NewClientFromKeyFile(url, keyFile, 10*time.Second); err != nil {
+				logging.Fatalf("%v", err)
+			}
+		} else {
+			client = jellyfin.NewClient(url, sidecarmain.RequireSecret("JELLYFIN_API_KEY"), 10*time.Second)
+		}
+		schedulers = append(schedulers, &dvr.Jellyfin{Client: client})
+	}
+	if url := sidecarmain.Env("PLEX_URL", ""); url != "" {
+		schedulers = append(schedulers, dvr.NewPlex(url, sidecarmain.RequireSecret("PLEX_TOKEN"), 10*time.Second))
+	}
+	if url := sidecarmain.Env("TVHEADEND_URL", ""); url != "" {
+		// TVHEADEND_USER and TVHEADEND_PASS if the API needs a login
+		schedulers = append(schedulers, dvr.NewTvheadend(url, sidecarmain.Env("TVHEADEND_USER", ""), sidecarmain.Secret("TVHEADEND_PASS"), 10*time.Second))
+	}
+	if len(schedulers) == 0 {
+		logging.Fatalf("JELLYFIN_URL, PLEX_URL or TVHEADEND_URL required")
+	}
+
+	checker := &dvrChecker{
+		checker: dvr.NewChecker(schedulers,
+			sidecarmain.Duration("DVR_LOOKAHEAD", 15*time.Minute),
+			// DVR_BOOT_TIME is how long until the DVR records again after a reboot
+			sidecarmain.Duration("DVR_BOOT_TIME", 5*time.Minute)),
+	}
+
+	sidecarmain.Run(checker)
+}
+
+type dvrChecker struct {
+	checker *dvr.Checker
+}
+
+func (c *dvrChecker) Name() string {
+	return "dvr"
+}
+
+func (c *dvrChecker) Check(ctx context.Context) (bool, string, error) {
+	reasons, err := c.checker.Activity(ctx, timezone.Now())
+	if err != nil {
+		// A scheduler rejected its credentials; that says nothing about
+		// what it has planned
+		return false, "", err
+	}
+
+	if len(reasons) > 0 {
+		return true, strings.Join(reasons, "; "), nil
+	}
+
+	return false, "", nil
+}
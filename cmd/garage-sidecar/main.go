@@ -0,0 +1,70 @@
+// garage-sidecar prevents shutdown of a Garage node while the cluster is
+// degraded or still resyncing data after a layout change or repair.
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/garage"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/selftest"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	url := sidecarmain.RequireEnv("GARAGE_ADMIN_URL")
+	token := sidecarmain.Env("GARAGE_ADMIN_TOKEN", "")
+	tokenFile := sidecarmain.Env("GARAGE_ADMIN_TOKEN_FILE", "")
+
+	// Read admin token from file if specified
+	if tokenFile != "" && token == "" {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			logging.Fatalf("reading token file: %v", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	if token == "" {
+		logging.Fatalf("GARAGE_ADMIN_TOKEN or GARAGE_ADMIN_TOKEN_FILE required")
+	}
+
+	// GARAGE_METRICS_TOKEN is only needed if metrics_token is set in garage.toml
+	client := garage.NewClient(url, token, sidecarmain.Env("GARAGE_METRICS_TOKEN", ""), 10*time.Second)
+
+	checker := &garageChecker{
+		checker: &garage.Checker{
+			Client:          client,
+			ResyncThreshold: sidecarmain.Int("GARAGE_RESYNC_THRESHOLD", 0),
+		},
+	}
+
+	sidecarmain.Run(checker)
+}
+
+type garageChecker struct {
+	checker *garage.Checker
+}
+
+func (c *garageChecker) Name() string {
+	return "garage"
+}
+
+func (c *garageChecker) Check(ctx context.Context) (bool, string, error) {
+	reasons, err := c.checker.Activity(ctx)
+	if err != nil {
+		// If Garage is unreachable, don't block shutdown
+		return false, "", selftest.Unreachable(ctx, err)
+	}
+
+	if len(reasons) > 0 {
+		return true, strings.Join(reasons, "; "), nil
+	}
+
+	return false, "", nil
+}
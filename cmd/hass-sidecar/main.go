@@ -0,0 +1,97 @@
+// hass-sidecar prevents shutdown while Home Assistant entities are active,
+// e.g. a "backup running" input_boolean or an occupancy sensor, and fires a
+// Home Assistant event whenever the inhibitor is acquired or released.
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/homeassistant"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/selftest"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	url := sidecarmain.RequireEnv("HASS_URL")
+	token := sidecarmain.Env("HASS_TOKEN", "")
+	tokenFile := sidecarmain.Env("HASS_TOKEN_FILE", "")
+
+	// Read token from file if specified
+	if tokenFile != "" && token == "" {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			logging.Fatalf("reading token file: %v", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	if token == "" {
+		logging.Fatalf("HASS_TOKEN or HASS_TOKEN_FILE required")
+	}
+
+	client := homeassistant.NewClient(url, token, 10*time.Second)
+
+	checker := &hassChecker{
+		client:   client,
+		entities: sidecarmain.SplitList(sidecarmain.RequireEnv("HASS_ENTITIES")),
+	}
+
+	// Mirror inhibitor transitions onto the Home Assistant event bus
+	event := sidecarmain.Env("HASS_EVENT", "homelab_inhibitor")
+	sidecarmain.RunWith(checker, sidecarmain.Options{
+		InhibitWhat: "shutdown",
+		OnBusy: func(reason string) {
+			fireEvent(client, event, true, reason)
+		},
+		OnIdle: func() {
+			fireEvent(client, event, false, "")
+		},
+	})
+}
+
+type hassChecker struct {
+	client   *homeassistant.Client
+	entities []string
+}
+
+func (c *hassChecker) Name() string {
+	return "homeassistant"
+}
+
+func (c *hassChecker) Check(ctx context.Context) (bool, string, error) {
+	active, err := c.client.ActiveEntities(ctx, c.entities)
+	if err != nil {
+		// If Home Assistant is unreachable, don't block shutdown
+		return false, "", selftest.Unreachable(ctx, err)
+	}
+
+	if len(active) > 0 {
+		return true, homeassistant.Describe(active), nil
+	}
+
+	return false, "", nil
+}
+
+// fireEvent reports an inhibitor transition to Home Assistant in the
+// background so a slow HA instance never delays the check loop.
+func fireEvent(client *homeassistant.Client, event string, held bool, reason string) {
+	host, _ := os.Hostname()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err := client.FireEvent(ctx, event, map[string]any{
+			"host":   host,
+			"held":   held,
+			"reason": reason,
+		})
+		if err != nil {
+			logging.Warnf("firing %s event: %v", event, err)
+		}
+	}()
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/registry"
+)
+
+const checksUsage = `Usage: homelab-sidecar checks <command> [flags] [args]
+
+Commands:
+  list       list the checks and the commands that run them
+  show       describe a check's options
+  validate   check a check's environment files before deploying them
+`
+
+// checks documents the checks and their options from the registry, and
+// validates configuration against it, for people and for tooling that
+// generates environment files.
+//
+//	homelab-sidecar checks list -json
+//	homelab-sidecar checks show raid
+//	homelab-sidecar checks validate -env-file /etc/homelab-sidecars/raid.env raid
+func checks(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, checksUsage)
+		return exitUsage
+	}
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "list":
+		return listChecks(args)
+	case "show":
+		return showCheck(args)
+	case "validate":
+		return validateCheck(args)
+	case "help", "-h", "-help", "--help":
+		fmt.Print(checksUsage)
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown checks command %q\n\n%s", cmd, checksUsage)
+		return exitUsage
+	}
+}
+
+func listChecks(args []string) int {
+	fs := flag.NewFlagSet("checks list", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print every check with its options as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: homelab-sidecar checks list [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if *asJSON {
+		all := registry.All()
+		for i, c := range all {
+			all[i].Options = slices.Concat(c.Options, c.Common())
+		}
+		return printJSON(all)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, c := range registry.All() {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", c.Name, c.Command, c.Description)
+	}
+	w.Flush()
+	return exitOK
+}
+
+func showCheck(args []string) int {
+	fs := flag.NewFlagSet("checks show", flag.ContinueOnError)
+	var (
+		asJSON = fs.Bool("json", false, "print the check as JSON")
+		common = fs.Bool("common", false, "also list the options every sidecar reads")
+	)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: homelab-sidecar checks show [flags] CHECK")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	c, ok := registry.Lookup(fs.Arg(0))
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown check %q\n", fs.Arg(0))
+		return exitUsage
+	}
+
+	if *asJSON {
+		c.Options = slices.Concat(c.Options, c.Common())
+		return printJSON(c)
+	}
+	printCheck(os.Stdout, c, *common)
+	return exitOK
+}
+
+func printCheck(out io.Writer, c registry.Check, common bool) {
+	fmt.Fprintf(out, "%s (%s): %s\n", c.Name, c.Command, c.Description)
+	if len(c.Subcommands) > 0 {
+		fmt.Fprintf(out, "Subcommands: %s\n", strings.Join(c.Subcommands, ", "))
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	printOptions := func(title string, opts []registry.Option) {
+		fmt.Fprintf(w, "\n%s:\n", title)
+		for _, o := range opts {
+			typ := string(o.Type)
+			if len(o.Values) > 0 {
+				typ = strings.Join(o.Values, "|")
+			}
+			def := o.Default
+			if o.Required {
+				def = "required"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", o.Name, typ, def, o.Description)
+		}
+	}
+	printOptions("Options", c.Options)
+	if common {
+		printOptions("Common options", c.Common())
+	} else if len(c.Defaults) > 0 {
+		var defaults []registry.Option
+		for _, o := range c.Common() {
+			if _, ok := c.Defaults[o.Name]; ok {
+				defaults = append(defaults, o)
+			}
+		}
+		printOptions("Common options with their own default (-common lists all)", defaults)
+	}
+	w.Flush()
+}
+
+func validateCheck(args []string) int {
+	fs := flag.NewFlagSet("checks validate", flag.ContinueOnError)
+	var files stringList
+	fs.Var(&files, "env-file", "environment file to read, as systemd's EnvironmentFile (repeatable; default the process environment)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: homelab-sidecar checks validate [flags] CHECK")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	env := map[string]string{}
+	if len(files) == 0 {
+		for _, kv := range os.Environ() {
+			if k, v, ok := strings.Cut(kv, "="); ok && isOptionName(k) {
+				env[k] = v
+			}
+		}
+	}
+	for _, file := range files {
+		if err := readEnvFile(file, env); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitError
+		}
+	}
+
+	errs := registry.Validate(fs.Arg(0), env)
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if len(errs) > 0 {
+		return exitError
+	}
+	return exitOK
+}
+
+// readEnvFile adds the KEY=VALUE lines of an environment file to env,
+// later files overriding earlier ones as with systemd. Blank lines and
+// comments are skipped, and a value may be quoted.
+func readEnvFile(name string, env map[string]string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("%s:%d: not KEY=VALUE", name, n)
+		}
+		v = strings.TrimSpace(v)
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		env[strings.TrimSpace(k)] = v
+	}
+	return sc.Err()
+}
+
+// isOptionName reports whether an environment variable could be meant as
+// a sidecar option: it shares its first word with one, as a typo would, or
+// is set for a profile. This leaves PATH, HOME and the like out.
+func isOptionName(k string) bool {
+	if strings.Contains(k, "__") {
+		return true
+	}
+	word, _, _ := strings.Cut(k, "_")
+	for _, c := range registry.All() {
+		for _, o := range slices.Concat(c.Options, c.Common()) {
+			if w, _, _ := strings.Cut(o.Name, "_"); w == word {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func printJSON(v any) int {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// stringList is a flag that can be given more than once
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
@@ -0,0 +1,53 @@
+// homelab-sidecar is a command-line companion to the sidecars, for use in
+// scripts and at the shell.
+//
+//	homelab-sidecar when-healthy [flags] -- command [args...]
+//	homelab-sidecar status [flags]
+//	homelab-sidecar manual [flags] [state [reason...]]
+//	homelab-sidecar checks list|show|validate [flags] [check]
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Exit codes
+const (
+	exitOK      = 0
+	exitError   = 1
+	exitUsage   = 2
+	exitTimeout = 124 // as timeout(1)
+)
+
+const usage = `Usage: homelab-sidecar <command> [flags] [args]
+
+Commands:
+  when-healthy   wait until no sidecar blocks shutdown, then run a command
+  status         list what is blocking shutdown, and why
+  manual         set the state manual-sidecar reports, for testing
+  checks         describe the checks and their options, and validate configuration
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(exitUsage)
+	}
+
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "when-healthy":
+		os.Exit(whenHealthy(args))
+	case "status":
+		os.Exit(status(args))
+	case "manual":
+		os.Exit(manualState(args))
+	case "checks":
+		os.Exit(checks(args))
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n%s", cmd, usage)
+		os.Exit(exitUsage)
+	}
+}
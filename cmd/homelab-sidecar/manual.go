@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/manual"
+)
+
+// manualState sets the state manual-sidecar reports, to test inhibitors,
+// notifications and force-allow handling end to end. With no state it
+// prints the current one.
+//
+//	homelab-sidecar manual busy testing the nightly update
+//	homelab-sidecar manual force-allow ups drill
+//	homelab-sidecar manual idle
+func manualState(args []string) int {
+	fs := flag.NewFlagSet("manual", flag.ContinueOnError)
+	file := fs.String("file", manual.DefaultPath, "flag file manual-sidecar follows")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: homelab-sidecar manual [flags] [idle|busy|error|force-allow [reason...]]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if fs.NArg() == 0 {
+		state, reason, err := manual.Read(*file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitError
+		}
+		if state == manual.Idle {
+			fmt.Println(state)
+		} else {
+			fmt.Printf("%s: %s\n", state, reason)
+		}
+		return exitOK
+	}
+
+	state, err := manual.ParseState(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	if err := manual.Write(*file, state, strings.Join(fs.Args()[1:], " ")); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logind"
+	"github.com/godbus/dbus/v5"
+)
+
+// status prints the block inhibitors covering the action, sidecars first,
+// with the reason each one gave, to answer why a reboot is waiting.
+//
+//	homelab-sidecar status
+//	homelab-sidecar status -what=sleep
+func status(args []string) int {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	var (
+		what     = fs.String("what", "shutdown", "inhibited action to report on")
+		procRoot = fs.String("proc-root", "/proc", "procfs mount, for telling sidecars from other lock holders")
+	)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: homelab-sidecar status [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: connect to system bus: %v\n", err)
+		return exitError
+	}
+	defer conn.Close()
+
+	inhibitors, err := logind.ListInhibitors(ctx, conn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	printStatus(os.Stdout, *what, logind.Blocking(inhibitors, *what), *procRoot)
+	return exitOK
+}
+
+func printStatus(w io.Writer, what string, blocking []logind.Inhibitor, procRoot string) {
+	if len(blocking) == 0 {
+		fmt.Fprintf(w, "Nothing is blocking %s.\n", what)
+		return
+	}
+
+	var sidecars, others []string
+	for _, inh := range blocking {
+		command := processName(procRoot, inh.PID)
+		line := fmt.Sprintf("  %s (pid %d, %s): %s", inh.Who, inh.PID, command, inh.Why)
+		if isSidecar(command) {
+			sidecars = append(sidecars, line)
+		} else {
+			others = append(others, line)
+		}
+	}
+
+	if len(sidecars) > 0 {
+		fmt.Fprintf(w, "Sidecars blocking %s:\n%s\n", what, strings.Join(sidecars, "\n"))
+	}
+	if len(others) > 0 {
+		if len(sidecars) > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Other locks blocking %s:\n%s\n", what, strings.Join(others, "\n"))
+	}
+}
+
+// processName returns the base name of pid's executable from its command
+// line (comm is cut off at 15 characters, too short for most sidecar
+// names), or "?" if it can't be read, e.g. because the process has exited.
+func processName(procRoot string, pid uint32) string {
+	data, err := os.ReadFile(filepath.Join(procRoot, fmt.Sprint(pid), "cmdline"))
+	if err != nil || len(data) == 0 {
+		return "?"
+	}
+	argv0, _, _ := strings.Cut(string(data), "\x00")
+	return filepath.Base(argv0)
+}
+
+// isSidecar reports whether a command name is one of the sidecars: the
+// host binaries are named <service>-sidecar, and the images run /sidecar.
+func isSidecar(command string) bool {
+	return command == "sidecar" || strings.HasSuffix(command, "-sidecar")
+}
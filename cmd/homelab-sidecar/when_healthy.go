@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logind"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/godbus/dbus/v5"
+)
+
+// whenHealthy waits until no block inhibitor covers the action, i.e. every
+// sidecar's check passes, then replaces itself with the command. Sidecars
+// hold their inhibitor exactly while their check is busy, so logind is the
+// one place that knows the state of all of them.
+//
+//	homelab-sidecar when-healthy -timeout=2h -- mdadm --grow /dev/md0 --raid-devices=4
+//	homelab-sidecar when-healthy -- systemctl reboot
+func whenHealthy(args []string) int {
+	fs := flag.NewFlagSet("when-healthy", flag.ContinueOnError)
+	var (
+		what     = fs.String("what", "shutdown", "inhibited action to wait on")
+		who      = fs.String("who", "", "comma-separated inhibitor owners to wait on, e.g. raid,jellyfin (empty waits on all)")
+		timeout  = fs.Duration("timeout", time.Hour, "give up after this long (0 waits forever)")
+		interval = fs.Duration("interval", 10*time.Second, "how often to check")
+		quiet    = fs.Bool("quiet", false, "don't report what is being waited on")
+	)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: homelab-sidecar when-healthy [flags] -- command [args...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	command := fs.Args()
+	if len(command) == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	path, err := exec.LookPath(command[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	if err := waitClear(ctx, *what, sidecarmain.SplitList(*who), *interval, *quiet); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if ctx.Err() != nil {
+			return exitTimeout
+		}
+		return exitError
+	}
+
+	if err := syscall.Exec(path, command, os.Environ()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: exec %s: %v\n", command[0], err)
+		return exitError
+	}
+	return exitOK
+}
+
+// waitClear polls logind until no matching block inhibitor is held.
+func waitClear(ctx context.Context, what string, who []string, interval time.Duration, quiet bool) error {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return fmt.Errorf("connect to system bus: %w", err)
+	}
+	defer conn.Close()
+
+	var last string
+	for {
+		inhibitors, err := logind.ListInhibitors(ctx, conn)
+		if err != nil {
+			return err
+		}
+		held := logind.HeldBy(logind.Blocking(inhibitors, what), who)
+		if len(held) == 0 {
+			return nil
+		}
+
+		reasons := make([]string, len(held))
+		for i, inh := range held {
+			reasons[i] = fmt.Sprintf("%s: %s", inh.Who, inh.Why)
+		}
+		if status := strings.Join(reasons, "; "); status != last {
+			if !quiet {
+				fmt.Fprintf(os.Stderr, "Waiting on %s\n", status)
+			}
+			last = status
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting on %s", last)
+		case <-time.After(interval):
+		}
+	}
+}
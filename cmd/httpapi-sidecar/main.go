@@ -0,0 +1,118 @@
+// httpapi-sidecar prevents shutdown while a JSON status endpoint says a
+// service is busy, for services without a sidecar of their own such as
+// PhotoPrism. For a service whose /api/status answers {"state": "indexing"}
+// while it indexes:
+//
+//	HTTPAPI_NAME=photos
+//	HTTPAPI_URL=http://localhost:8080/api/status
+//	HTTPAPI_PATH=$.state
+//	HTTPAPI_BUSY_WHEN=!=idle
+//
+// or for an *arr-style download queue, counting the records:
+//
+//	HTTPAPI_URL=http://localhost:8989/api/v3/queue
+//	HTTPAPI_PATH=$.records[?(@.status == 'downloading')]
+//	HTTPAPI_COUNT=true
+//	HTTPAPI_BUSY_WHEN=>0
+//
+// HTTPAPI_PATH is a JSONPath expression and HTTPAPI_BUSY_WHEN a condition,
+// as taken by jsoncheck.NewChecker. Run with the "healthcheck" argument it
+// instead exits non-zero unless HTTPAPI_HEALTHY_WHEN holds for the values
+// at HTTPAPI_HEALTH_PATH (default HTTPAPI_PATH), for use as a greenboot
+// health check.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/healthcheck"
+	"github.com/addisonbair/homelab-sidecars/pkg/jsoncheck"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/selftest"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	// HTTPAPI_HEADERS adds headers, e.g. "X-Api-Key: abc, Accept-Language: en";
+	// HTTPAPI_AUTHORIZATION (or _FILE) is the Authorization header
+	header := http.Header{}
+	for _, h := range sidecarmain.SplitList(sidecarmain.Env("HTTPAPI_HEADERS", "")) {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok {
+			logging.Fatalf("HTTPAPI_HEADERS: %q is not Name: value", h)
+		}
+		header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	if auth := sidecarmain.Secret("HTTPAPI_AUTHORIZATION"); auth != "" {
+		header.Set("Authorization", auth)
+	}
+
+	name, url := sidecarmain.Env("HTTPAPI_NAME", "httpapi"), sidecarmain.RequireEnv("HTTPAPI_URL")
+	path := sidecarmain.Env("HTTPAPI_PATH", "")
+
+	// HTTPAPI_COUNT=true compares the number of values at the path instead
+	// of each value
+	count := sidecarmain.Env("HTTPAPI_COUNT", "false") == "true"
+
+	if flag.Arg(0) == "healthcheck" {
+		health, err := jsoncheck.NewChecker(name, url, sidecarmain.Env("HTTPAPI_HEALTH_PATH", path),
+			sidecarmain.RequireEnv("HTTPAPI_HEALTHY_WHEN"), header, 10*time.Second)
+		if err != nil {
+			logging.Fatalf("HTTPAPI_HEALTHY_WHEN: %v", err)
+		}
+		health.Count = count
+		// Wait for the endpoint to report the service healthy, e.g. after an
+		// update broke it, retrying while the service is still starting;
+		// HTTPAPI_HEALTH_SEVERITY applies whatever HTTPAPI_NAME is
+		os.Exit(sidecarmain.Healthcheck{
+			Name:   health.Name(),
+			Budget: sidecarmain.Duration("HTTPAPI_HEALTH_TIMEOUT", 5*time.Minute),
+			Check:  health.Health,
+			Severity: func() (healthcheck.Severity, error) {
+				return healthcheck.SeverityFromEnv("httpapi")
+			},
+		}.Run(flag.Args()[1:]))
+	}
+
+	api, err := jsoncheck.NewChecker(name, url, path, sidecarmain.Env("HTTPAPI_BUSY_WHEN", ""), header, 10*time.Second)
+	if err != nil {
+		logging.Fatalf("HTTPAPI_BUSY_WHEN: %v", err)
+	}
+	api.Count = count
+	checker := &httpapiChecker{checker: api}
+
+	sidecarmain.Run(checker)
+}
+
+type httpapiChecker struct {
+	checker *jsoncheck.Checker
+}
+
+func (c *httpapiChecker) Name() string {
+	return c.checker.Name()
+}
+
+func (c *httpapiChecker) Check(ctx context.Context) (bool, string, error) {
+	reasons, err := c.checker.Activity(ctx)
+	if errors.Is(err, jsoncheck.ErrUnauthorized) {
+		return false, "", err
+	}
+	if err != nil {
+		// If the service is unreachable, don't block shutdown
+		return false, "", selftest.Unreachable(ctx, err)
+	}
+
+	if len(reasons) > 0 {
+		return true, strings.Join(reasons, "; "), nil
+	}
+
+	return false, "", nil
+}
@@ -0,0 +1,71 @@
+// immich-sidecar prevents shutdown while Immich is working through its job
+// queues, e.g. importing a library or running machine learning over new
+// uploads.
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/immich"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/scope"
+	"github.com/addisonbair/homelab-sidecars/pkg/selftest"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	client := immich.NewClient(sidecarmain.Env("IMMICH_URL", "http://localhost:2283"), sidecarmain.RequireSecret("IMMICH_API_KEY"), 10*time.Second)
+
+	// IMMICH_QUEUES limits the check to some queues, e.g.
+	// library,metadataExtraction; by default every queue blocks
+	checker := &immichChecker{
+		checker: immich.NewChecker(client, sidecarmain.SplitList(sidecarmain.Env("IMMICH_QUEUES", ""))),
+	}
+
+	// CREDENTIAL_SCOPE=warn (the default) warns about an API key that can
+	// do more than read the job queues; "enforce" refuses to start, "off"
+	// doesn't check
+	scopeMode, err := scope.ParseMode(sidecarmain.Env("CREDENTIAL_SCOPE", "warn"))
+	if err != nil {
+		logging.Fatalf("CREDENTIAL_SCOPE: %v", err)
+	}
+	if err := scope.Verify(context.Background(), scopeMode, scope.Credential{
+		Option: "IMMICH_API_KEY",
+		Need:   "a key with only the job.read permission",
+		Probe:  client.ExcessAccess,
+	}); err != nil {
+		logging.Fatalf("%v", err)
+	}
+
+	sidecarmain.Run(checker)
+}
+
+type immichChecker struct {
+	checker *immich.Checker
+}
+
+func (c *immichChecker) Name() string {
+	return "immich"
+}
+
+func (c *immichChecker) Check(ctx context.Context) (bool, string, error) {
+	reasons, err := c.checker.Activity(ctx)
+	if errors.Is(err, immich.ErrUnauthorized) {
+		return false, "", err
+	}
+	if err != nil {
+		// If Immich is unreachable, don't block shutdown
+		return false, "", selftest.Unreachable(ctx, err)
+	}
+
+	if len(reasons) > 0 {
+		return true, strings.Join(reasons, "; "), nil
+	}
+
+	return false, "", nil
+}
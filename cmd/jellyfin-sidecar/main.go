@@ -3,55 +3,83 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
 	"strings"
 	"sync"
 	"time"
 
 	sidecar "github.com/addisonbair/go-systemd-sidecar"
 	"github.com/addisonbair/homelab-sidecars/pkg/jellyfin"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/selftest"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/sleepwake"
 )
 
 func main() {
-	url := requireEnv("JELLYFIN_URL")
-	apiKey := getEnv("JELLYFIN_API_KEY", "")
-	apiKeyFile := getEnv("JELLYFIN_API_KEY_FILE", "")
-
-	// Read API key from file if specified
-	if apiKeyFile != "" && apiKey == "" {
-		data, err := os.ReadFile(apiKeyFile)
+	sidecarmain.Init()
+
+	url := sidecarmain.RequireEnv("JELLYFIN_URL")
+	apiKey := sidecarmain.Env("JELLYFIN_API_KEY", "")
+	apiKeyFile := sidecarmain.Env("JELLYFIN_API_KEY_FILE", "")
+
+	var client *jellyfin.Client
+	switch {
+	case apiKey != "":
+		client = jellyfin.NewClient(url, apiKey, 10*time.Second)
+	case apiKeyFile != "":
+		var err error
+		client, err = jellyfin.NewClientFromKeyFile(url, apiKeyFile, 10*time.Second)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error reading API key file: %v\n", err)
-			os.Exit(1)
+			logging.Fatalf("reading API key file: %v", err)
 		}
-		apiKey = strings.TrimSpace(string(data))
-	}
 
-	if apiKey == "" {
-		fmt.Fprintln(os.Stderr, "Error: JELLYFIN_API_KEY or JELLYFIN_API_KEY_FILE required")
-		os.Exit(1)
+		// Pick up key rotations without a restart
+		go func() {
+			err := client.WatchKeyFile(context.Background(), func(err error) {
+				logging.Warnf("reloading API key: %v", err)
+			})
+			if err != nil {
+				logging.Warnf("API key file not watched: %v", err)
+			}
+		}()
+	default:
+		logging.Fatalf("JELLYFIN_API_KEY or JELLYFIN_API_KEY_FILE required")
 	}
 
-	client := jellyfin.NewClient(url, apiKey, 10*time.Second)
-	gracePeriod := getDuration("JELLYFIN_GRACE_PERIOD", 5*time.Minute)
-
-	checker := &jellyfinChecker{
-		client:      client,
-		gracePeriod: gracePeriod,
+	// The JELLYFIN_* filters, grace period, pause timeout, scheduled tasks
+	// and minimum bitrate are shared with time-to-safe
+	jf, err := jellyfin.CheckerFromEnv(client)
+	if err != nil {
+		logging.Fatalf("%v", err)
+	}
+	checker := &jellyfinChecker{jf: jf}
+
+	var opts sidecarmain.Options
+
+	// SLEEP_HOOKS=true follows suspend and resume: media playing vetoes
+	// sleep, even with INHIBIT_WHAT=shutdown, and the check runs again as
+	// soon as the machine wakes
+	if sidecarmain.Env("SLEEP_HOOKS", "false") == "true" {
+		opts.Start = func(ctx context.Context, wrapped sidecar.Checker, pollInterval time.Duration) {
+			go func() {
+				cfg := sleepwake.Config{Interval: pollInterval, DryRun: sidecarmain.DryRun()}
+				if err := sleepwake.Watch(ctx, wrapped, checker, cfg); err != nil {
+					logging.Warnf("sleep hooks disabled: %v", err)
+				}
+			}()
+		}
 	}
 
-	sidecar.MustRun(context.Background(), checker, sidecar.Options{
-		InhibitWhat:  getEnv("INHIBIT_WHAT", "shutdown:sleep"),
-		PollInterval: getDuration("POLL_INTERVAL", 30*time.Second),
-		NotifyReady:  getEnv("NOTIFY_READY", "true") == "true",
-		NotifyStatus: true,
-	})
+	sidecarmain.RunWith(checker, opts)
 }
 
+// jellyfinChecker adapts jellyfin.Checker's session counting to the
+// sidecar: busy with a reason, and unreachable rather than idle when
+// Jellyfin is down.
 type jellyfinChecker struct {
-	client      *jellyfin.Client
-	gracePeriod time.Duration
+	jf *jellyfin.Checker
 
 	mu             sync.Mutex
 	lastActiveTime time.Time
@@ -62,12 +90,20 @@ func (c *jellyfinChecker) Name() string {
 }
 
 func (c *jellyfinChecker) Check(ctx context.Context) (bool, string, error) {
-	hasStreams, sessions, err := c.client.HasActiveStreams(ctx)
+	_, sessions, err := c.jf.Client.HasActiveStreams(ctx)
+	if errors.Is(err, jellyfin.ErrUnauthorized) {
+		// Jellyfin is up but the key was rejected even after re-reading
+		// it; that says nothing about whether anyone is streaming
+		return false, "", err
+	}
 	if err != nil {
 		// If Jellyfin is unreachable, don't block shutdown
-		return false, "", nil
+		return false, "", selftest.Unreachable(ctx, err)
 	}
 
+	sessions, total := c.jf.Counted(sessions, time.Now())
+	hasStreams := len(sessions) > 0
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -77,45 +113,62 @@ func (c *jellyfinChecker) Check(ctx context.Context) (bool, string, error) {
 		for _, s := range sessions {
 			descriptions = append(descriptions, s.Describe())
 		}
+		if c.jf.MinBitrate > 0 {
+			return true, fmt.Sprintf("%s: %s", jellyfin.FormatBitrate(total), strings.Join(descriptions, "; ")), nil
+		}
 		return true, strings.Join(descriptions, "; "), nil
 	}
 
 	// Check grace period
-	if c.gracePeriod > 0 && !c.lastActiveTime.IsZero() {
+	if c.jf.GracePeriod > 0 && !c.lastActiveTime.IsZero() {
 		elapsed := time.Since(c.lastActiveTime)
-		if elapsed < c.gracePeriod {
-			remaining := c.gracePeriod - elapsed
+		if elapsed < c.jf.GracePeriod {
+			remaining := c.jf.GracePeriod - elapsed
 			return true, fmt.Sprintf("grace period: %s remaining", remaining.Round(time.Second)), nil
 		}
 	}
 
-	return false, "", nil
+	return c.checkTasks(ctx)
 }
 
-func getEnv(key, fallback string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+// VetoSleep keeps the machine awake while media is playing, whatever the
+// bitrate; paused sessions and the grace period don't count.
+func (c *jellyfinChecker) VetoSleep(ctx context.Context) (bool, string, error) {
+	sessions, err := c.jf.Client.GetActiveSessions(ctx)
+	if errors.Is(err, jellyfin.ErrUnauthorized) {
+		return false, "", err
 	}
-	return fallback
-}
-
-func requireEnv(key string) string {
-	v := os.Getenv(key)
-	if v == "" {
-		fmt.Fprintf(os.Stderr, "Error: %s is required\n", key)
-		os.Exit(1)
+	if err != nil {
+		// Nothing is playing from a Jellyfin that is down
+		return false, "", nil
 	}
-	return v
+	var playing []string
+	for _, s := range c.jf.Filter.Apply(sessions) {
+		if s.PlayState == nil || !s.PlayState.IsPaused {
+			playing = append(playing, s.Describe())
+		}
+	}
+	if len(playing) == 0 {
+		return false, "", nil
+	}
+	return true, strings.Join(playing, "; "), nil
 }
 
-func getDuration(key string, fallback time.Duration) time.Duration {
-	v := os.Getenv(key)
-	if v == "" {
-		return fallback
+// checkTasks reports busy while a blocking scheduled task is running, such
+// as a library scan or backup.
+func (c *jellyfinChecker) checkTasks(ctx context.Context) (bool, string, error) {
+	if len(c.jf.BlockTasks) == 0 {
+		return false, "", nil
+	}
+	tasks, err := c.jf.Client.GetScheduledTasks(ctx)
+	if errors.Is(err, jellyfin.ErrUnauthorized) {
+		return false, "", err
 	}
-	d, err := time.ParseDuration(v)
 	if err != nil {
-		return fallback
+		return false, "", selftest.Unreachable(ctx, err)
 	}
-	return d
+	if running := jellyfin.RunningTasks(tasks, c.jf.BlockTasks); len(running) > 0 {
+		return true, jellyfin.DescribeTasks(running), nil
+	}
+	return false, "", nil
 }
@@ -0,0 +1,84 @@
+// load-sidecar reads the load average and pressure stall information
+// (PSI). Run with the "healthcheck" argument it exits non-zero while the
+// machine is thrashing, for use as a greenboot health check after an
+// update; with LOAD_BLOCK=true it also holds off reboots while heavy batch
+// jobs keep the machine busy.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/load"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	// LOAD_PRESSURE lists PSI thresholds as <resource>[:<kind>]=<percent>,
+	// compared over LOAD_PRESSURE_WINDOW (avg10, avg60 or avg300);
+	// LOAD_MAX_PER_CPU also limits the 5-minute load average
+	thresholds, err := load.ParseThresholds(sidecarmain.Env("LOAD_PRESSURE", "memory:full=10,io:full=25"))
+	if err != nil {
+		logging.Fatalf("LOAD_PRESSURE: %v", err)
+	}
+	window := sidecarmain.Env("LOAD_PRESSURE_WINDOW", "avg60")
+	if !slices.Contains(load.Windows, window) {
+		logging.Fatalf("LOAD_PRESSURE_WINDOW: want one of %s", strings.Join(load.Windows, ", "))
+	}
+	maxLoad, err := strconv.ParseFloat(sidecarmain.Env("LOAD_MAX_PER_CPU", "0"), 64)
+	if err != nil {
+		logging.Fatalf("LOAD_MAX_PER_CPU: %v", err)
+	}
+
+	checker := &loadChecker{
+		checker: &load.Checker{
+			ProcRoot:      sidecarmain.Env("PROC_ROOT", ""),
+			MaxLoadPerCPU: maxLoad,
+			Pressure:      thresholds,
+			Window:        window,
+		},
+		// LOAD_BLOCK=true holds the inhibitor while a threshold is
+		// exceeded; otherwise the sidecar only reports
+		block: sidecarmain.Env("LOAD_BLOCK", "false") == "true",
+	}
+
+	if flag.Arg(0) == "healthcheck" {
+		// Wait for the pressure to ease, retrying while services are still
+		// warming their caches after the restart
+		os.Exit(sidecarmain.Healthcheck{
+			Name:   "load",
+			Budget: sidecarmain.Duration("LOAD_HEALTH_TIMEOUT", 5*time.Minute),
+			Check:  checker.checker.Health,
+		}.Run(flag.Args()[1:]))
+	}
+
+	sidecarmain.Run(checker, checker.checker)
+}
+
+type loadChecker struct {
+	checker *load.Checker
+	block   bool
+}
+
+func (c *loadChecker) Name() string {
+	return "load"
+}
+
+func (c *loadChecker) Check(ctx context.Context) (bool, string, error) {
+	over, err := c.checker.Exceeded(ctx)
+	if err != nil {
+		return false, "", err
+	}
+	if c.block && len(over) > 0 {
+		return true, "under pressure: " + strings.Join(over, ", "), nil
+	}
+	return false, "", nil
+}
@@ -0,0 +1,96 @@
+// logstore-sidecar prevents shutdown while Elasticsearch is relocating
+// shards. Run with the "healthcheck" argument it instead exits non-zero
+// unless the log store is healthy (Loki ready with an active ring,
+// Elasticsearch green), for use as a greenboot health check.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/logstore"
+	"github.com/addisonbair/homelab-sidecars/pkg/scope"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	var stores []logstore.Store
+	var creds []scope.Credential
+	if url := sidecarmain.Env("LOKI_URL", ""); url != "" {
+		stores = append(stores, logstore.NewLoki(url, 10*time.Second))
+	}
+	if url := sidecarmain.Env("ELASTICSEARCH_URL", ""); url != "" {
+		// ELASTICSEARCH_API_KEY, or ELASTICSEARCH_USER and
+		// ELASTICSEARCH_PASS, if security is on
+		es := logstore.NewElasticsearch(url, sidecarmain.Env("ELASTICSEARCH_USER", ""), sidecarmain.Secret("ELASTICSEARCH_PASS"),
+			sidecarmain.Secret("ELASTICSEARCH_API_KEY"), 10*time.Second)
+		// ELASTICSEARCH_MIN_STATUS=yellow accepts unassigned replicas, as
+		// on a single node
+		es.MinStatus = sidecarmain.Env("ELASTICSEARCH_MIN_STATUS", "green")
+		stores = append(stores, es)
+		creds = append(creds, scope.Credential{
+			Option: "ELASTICSEARCH_API_KEY or ELASTICSEARCH_USER",
+			Need:   `the "monitor" cluster privilege`,
+			Probe:  es.ExcessAccess,
+		})
+	}
+	if len(stores) == 0 {
+		logging.Fatalf("LOKI_URL or ELASTICSEARCH_URL required")
+	}
+
+	checker := &logstoreChecker{
+		checker: logstore.NewChecker(stores),
+	}
+
+	if flag.Arg(0) == "healthcheck" {
+		// Wait for the log store to be healthy, retrying while Loki is still
+		// joining its ring or Elasticsearch is still assigning shards after
+		// the restart
+		os.Exit(sidecarmain.Healthcheck{
+			Name:   "logstore",
+			Budget: sidecarmain.Duration("LOGSTORE_HEALTH_TIMEOUT", 5*time.Minute),
+			Check:  checker.checker.Health,
+		}.Run(flag.Args()[1:]))
+	}
+
+	// CREDENTIAL_SCOPE=warn (the default) warns about Elasticsearch
+	// credentials that can do more than monitor the cluster; "enforce"
+	// refuses to start, "off" doesn't check
+	scopeMode, err := scope.ParseMode(sidecarmain.Env("CREDENTIAL_SCOPE", "warn"))
+	if err != nil {
+		logging.Fatalf("CREDENTIAL_SCOPE: %v", err)
+	}
+	if err := scope.Verify(context.Background(), scopeMode, creds...); err != nil {
+		logging.Fatalf("%v", err)
+	}
+
+	sidecarmain.Run(checker)
+}
+
+type logstoreChecker struct {
+	checker *logstore.Checker
+}
+
+func (c *logstoreChecker) Name() string {
+	return "logstore"
+}
+
+func (c *logstoreChecker) Check(ctx context.Context) (bool, string, error) {
+	reasons, err := c.checker.Activity(ctx)
+	if err != nil {
+		// A store rejected the credentials; unreachable ones are skipped
+		return false, "", err
+	}
+
+	if len(reasons) > 0 {
+		return true, strings.Join(reasons, "; "), nil
+	}
+
+	return false, "", nil
+}
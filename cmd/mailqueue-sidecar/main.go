@@ -0,0 +1,107 @@
+// mailqueue-sidecar prevents shutdown while the Postfix or Exim mail queue
+// is actively draining. Run with the "healthcheck" argument it instead
+// exits non-zero if the queue grows abnormally after boot, for use as a
+// greenboot health check.
+// This runs on the host, where it can run postqueue or exim.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/healthcheck"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/mailqueue"
+	"github.com/addisonbair/homelab-sidecars/pkg/privexec"
+	"github.com/addisonbair/homelab-sidecars/pkg/selftest"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	// MAILQUEUE_EXEC_WRAPPER (e.g. "sudo -n") runs the queue listing with
+	// more privileges, which Exim needs
+	runner := privexec.FromEnv("mailqueue")
+	// MAIL_QUEUE_COMMAND reaches a containerized mail server, e.g.
+	// "podman exec mailserver postqueue"
+	command := strings.Fields(sidecarmain.Env("MAIL_QUEUE_COMMAND", ""))
+
+	var source mailqueue.Source
+	switch server := sidecarmain.Env("MAIL_SERVER", "postfix"); server {
+	case "postfix":
+		source = &mailqueue.Postfix{Runner: runner, Command: command}
+	case "exim":
+		source = &mailqueue.Exim{Runner: runner, Command: command, ProcRoot: sidecarmain.Env("PROC_ROOT", "")}
+	default:
+		logging.Fatalf("MAIL_SERVER: unknown mail server %q (want postfix or exim)", server)
+	}
+
+	if flag.Arg(0) == "healthcheck" {
+		window := sidecarmain.Duration("MAIL_HEALTH_WINDOW", 2*time.Minute)
+		maxGrowth := sidecarmain.Int("MAIL_HEALTH_MAX_GROWTH", 50)
+
+		// Sample the queue twice, window apart, and fail if it grew by more
+		// than maxGrowth messages: after an update that broke outbound
+		// delivery, mail piles up instead of draining. The first sample is
+		// retried while the mail server is still starting
+		var before mailqueue.Queue
+		os.Exit(sidecarmain.Healthcheck{
+			Name:   "mailqueue",
+			Budget: sidecarmain.Duration("MAIL_HEALTH_TIMEOUT", healthcheck.DefaultPolicy.Budget),
+			Check: func(ctx context.Context) error {
+				q, err := source.Snapshot(ctx)
+				before = q
+				return err
+			},
+			Then: func(ctx context.Context, res *healthcheck.Result) {
+				time.Sleep(window)
+				after, err := source.Snapshot(ctx)
+				if err == nil {
+					err = mailqueue.Growth(before, after, maxGrowth)
+				}
+				res.Elapsed += window
+				res.Attempts = append(res.Attempts, healthcheck.Attempt{At: res.Elapsed, Err: err})
+				res.Healthy = err == nil
+				if res.Healthy {
+					fmt.Printf("mail queue: %s\n", after.Describe())
+				}
+			},
+			// MAIL_HEALTH_SEVERITY=warning reports a failure without rolling back
+			Severity: func() (healthcheck.Severity, error) {
+				return healthcheck.SeverityFromEnv("mail")
+			},
+		}.Run(flag.Args()[1:]))
+	}
+
+	checker := &mailqueueChecker{source: source}
+
+	sidecarmain.Run(checker)
+}
+
+type mailqueueChecker struct {
+	source mailqueue.Source
+}
+
+func (c *mailqueueChecker) Name() string {
+	return "mailqueue"
+}
+
+func (c *mailqueueChecker) Check(ctx context.Context) (bool, string, error) {
+	q, err := c.source.Snapshot(ctx)
+	if err != nil {
+		// If the mail server is down, don't block shutdown
+		return false, "", selftest.Unreachable(ctx, err)
+	}
+
+	// Deferred mail alone waits for its retry either way
+	if q.Draining() {
+		return true, fmt.Sprintf("mail queue draining: %s", q.Describe()), nil
+	}
+
+	return false, "", nil
+}
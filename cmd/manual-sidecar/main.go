@@ -0,0 +1,95 @@
+// manual-sidecar blocks shutdown, fails or force-allows shutdown on demand,
+// following the flag file described in package manual. It is for testing:
+// exercising inhibitor acquisition, notifications and the other sidecars'
+// response to a force-allow without degrading a real service.
+//
+//	homelab-sidecar manual busy testing the nightly update
+//	homelab-sidecar manual error
+//	homelab-sidecar manual idle
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/manual"
+	"github.com/addisonbair/homelab-sidecars/pkg/override"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	// MANUAL_FILE is the flag file; force-allow writes FORCE_ALLOW_FILE, as
+	// the UPS sidecar does, so the other sidecars stand down
+	checker := &manualChecker{
+		checker:    manual.NewChecker(sidecarmain.Env("MANUAL_FILE", manual.DefaultPath)),
+		forceAllow: sidecarmain.Env("FORCE_ALLOW_FILE", override.DefaultPath),
+		dryRun:     sidecarmain.DryRun(),
+	}
+
+	var opts sidecarmain.Options
+
+	// Check as soon as the flag file or the force-allow file changes;
+	// polling only reconciles what the watches miss. WATCH_FILES=false
+	// polls only.
+	if sidecarmain.Env("WATCH_FILES", "true") == "true" {
+		opts.Watch = []string{sidecarmain.Env("MANUAL_FILE", manual.DefaultPath)}
+	}
+	sidecarmain.RunWith(checker, opts)
+}
+
+type manualChecker struct {
+	checker    *manual.Checker
+	forceAllow string
+	dryRun     bool // don't touch the force-allow file
+
+	mu     sync.Mutex
+	forced bool
+}
+
+func (c *manualChecker) Name() string {
+	return "manual"
+}
+
+func (c *manualChecker) Check(ctx context.Context) (bool, string, error) {
+	state, reason, err := c.checker.State()
+	if err != nil {
+		return false, "", err
+	}
+
+	if err := c.force(state == manual.ForceAllow, reason); err != nil {
+		return false, "", err
+	}
+
+	switch state {
+	case manual.Busy:
+		return true, reason, nil
+	case manual.Error:
+		return false, "", errors.New(reason)
+	}
+	return false, "", nil
+}
+
+// force writes the force-allow file while forced, and removes it once
+// after, leaving one written by another sidecar alone.
+func (c *manualChecker) force(forced bool, reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dryRun || (!forced && !c.forced) {
+		return nil
+	}
+	var err error
+	if forced {
+		err = override.Set(c.forceAllow, fmt.Sprintf("manual: %s", reason))
+	} else {
+		err = override.Clear(c.forceAllow)
+	}
+	if err == nil {
+		c.forced = forced
+	}
+	return err
+}
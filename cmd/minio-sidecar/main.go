@@ -0,0 +1,78 @@
+// minio-sidecar prevents shutdown while MinIO is healing drives, rebalancing
+// pools or receiving multipart uploads.
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/minio"
+	"github.com/addisonbair/homelab-sidecars/pkg/selftest"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	url := sidecarmain.RequireEnv("MINIO_URL")
+	accessKey := sidecarmain.RequireEnv("MINIO_ACCESS_KEY")
+	secretKey := sidecarmain.Env("MINIO_SECRET_KEY", "")
+	secretKeyFile := sidecarmain.Env("MINIO_SECRET_KEY_FILE", "")
+
+	// Read secret key from file if specified
+	if secretKeyFile != "" && secretKey == "" {
+		data, err := os.ReadFile(secretKeyFile)
+		if err != nil {
+			logging.Fatalf("reading secret key file: %v", err)
+		}
+		secretKey = strings.TrimSpace(string(data))
+	}
+
+	if secretKey == "" {
+		logging.Fatalf("MINIO_SECRET_KEY or MINIO_SECRET_KEY_FILE required")
+	}
+
+	client := minio.NewClient(url, accessKey, secretKey, sidecarmain.Env("MINIO_REGION", ""), 10*time.Second)
+
+	checker := &minioChecker{
+		checker: &minio.Checker{
+			Client:       client,
+			Buckets:      sidecarmain.SplitList(sidecarmain.Env("MINIO_BUCKETS", "")),
+			MaxUploadAge: sidecarmain.Duration("MINIO_UPLOAD_MAX_AGE", minio.DefaultMaxUploadAge),
+			// MINIO_CHECK_UPLOADS=false skips listing uploads on big deployments
+			SkipUploads: sidecarmain.Env("MINIO_CHECK_UPLOADS", "true") != "true",
+		},
+	}
+
+	sidecarmain.Run(checker)
+}
+
+type minioChecker struct {
+	checker *minio.Checker
+}
+
+func (c *minioChecker) Name() string {
+	return "minio"
+}
+
+func (c *minioChecker) Check(ctx context.Context) (bool, string, error) {
+	reasons, err := c.checker.Activity(ctx)
+	if minio.IsAccessDenied(err) {
+		// MinIO is up but the key lacks permissions; that says nothing
+		// about whether it is healing
+		return false, "", err
+	}
+	if err != nil {
+		// If MinIO is unreachable, don't block shutdown
+		return false, "", selftest.Unreachable(ctx, err)
+	}
+
+	if len(reasons) > 0 {
+		return true, strings.Join(reasons, "; "), nil
+	}
+
+	return false, "", nil
+}
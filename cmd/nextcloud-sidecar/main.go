@@ -0,0 +1,85 @@
+// nextcloud-sidecar prevents shutdown while Nextcloud is uploading files or
+// upgrading itself. Run with the "healthcheck" argument it instead exits
+// non-zero if Nextcloud is stuck in maintenance mode, for use as a greenboot
+// health check.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/nextcloud"
+	"github.com/addisonbair/homelab-sidecars/pkg/selftest"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	url := sidecarmain.RequireEnv("NEXTCLOUD_URL")
+	token := sidecarmain.Env("NEXTCLOUD_TOKEN", "")
+	tokenFile := sidecarmain.Env("NEXTCLOUD_TOKEN_FILE", "")
+
+	// Read serverinfo token from file if specified
+	if tokenFile != "" && token == "" {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			logging.Fatalf("reading token file: %v", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	client := nextcloud.NewClient(url, token, 10*time.Second)
+
+	if flag.Arg(0) == "healthcheck" {
+		// Wait for Nextcloud to leave maintenance mode, since the container
+		// image runs pending upgrades on start
+		os.Exit(sidecarmain.Healthcheck{
+			Name:   "nextcloud",
+			Budget: sidecarmain.Duration("NEXTCLOUD_HEALTH_TIMEOUT", 5*time.Minute),
+			Check: func(ctx context.Context) error {
+				return nextcloud.Health(ctx, client)
+			},
+		}.Run(flag.Args()[1:]))
+	}
+
+	if token == "" {
+		logging.Warnf("no NEXTCLOUD_TOKEN, only maintenance mode is checked")
+	}
+
+	checker := &nextcloudChecker{
+		checker: &nextcloud.Checker{
+			Client:          client,
+			MinUploadSize:   int64(sidecarmain.Int("NEXTCLOUD_MIN_UPLOAD_MB", 10)) << 20,
+			WorkerThreshold: sidecarmain.Int("NEXTCLOUD_WORKER_THRESHOLD", 0),
+		},
+	}
+
+	sidecarmain.Run(checker)
+}
+
+type nextcloudChecker struct {
+	checker *nextcloud.Checker
+}
+
+func (c *nextcloudChecker) Name() string {
+	return "nextcloud"
+}
+
+func (c *nextcloudChecker) Check(ctx context.Context) (bool, string, error) {
+	reasons, err := c.checker.Activity(ctx)
+	if err != nil {
+		// If Nextcloud is unreachable, don't block shutdown
+		return false, "", selftest.Unreachable(ctx, err)
+	}
+
+	if len(reasons) > 0 {
+		return true, strings.Join(reasons, "; "), nil
+	}
+
+	return false, "", nil
+}
@@ -0,0 +1,78 @@
+// nvr-sidecar prevents shutdown while a ZoneMinder, Shinobi or Frigate
+// camera is recording an alarm or motion event. Run with the "healthcheck" argument it
+// instead exits non-zero if the cameras aren't capturing, for use as a
+// greenboot health check.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/nvr"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	var nvrs []nvr.Source
+	if url := sidecarmain.Env("ZONEMINDER_URL", ""); url != "" {
+		// ZONEMINDER_USER and ZONEMINDER_PASS if API authentication is on
+		nvrs = append(nvrs, nvr.NewZoneMinder(url, sidecarmain.Env("ZONEMINDER_USER", ""), sidecarmain.Secret("ZONEMINDER_PASS"), 10*time.Second))
+	}
+	if url := sidecarmain.Env("SHINOBI_URL", ""); url != "" {
+		nvrs = append(nvrs, nvr.NewShinobi(url, sidecarmain.RequireSecret("SHINOBI_API_KEY"), sidecarmain.RequireEnv("SHINOBI_GROUP_KEY"), 10*time.Second))
+	}
+	if url := sidecarmain.Env("FRIGATE_URL", ""); url != "" {
+		// FRIGATE_USER and FRIGATE_PASS for the authenticated port
+		nvrs = append(nvrs, nvr.NewFrigate(url, sidecarmain.Env("FRIGATE_USER", ""), sidecarmain.Secret("FRIGATE_PASS"), 10*time.Second))
+	}
+	if len(nvrs) == 0 {
+		logging.Fatalf("ZONEMINDER_URL, SHINOBI_URL or FRIGATE_URL required")
+	}
+
+	// NVR_MONITORS lists the cameras, by name or ID, the health check
+	// expects to be capturing; by default every enabled one
+	checker := &nvrChecker{
+		checker: nvr.NewChecker(nvrs, sidecarmain.SplitList(sidecarmain.Env("NVR_MONITORS", ""))),
+	}
+
+	if flag.Arg(0) == "healthcheck" {
+		// Wait for the cameras to be capturing, e.g. after an update broke the
+		// NVR or its access to the network, retrying while the NVR is still
+		// connecting to the cameras
+		os.Exit(sidecarmain.Healthcheck{
+			Name:   "nvr",
+			Budget: sidecarmain.Duration("NVR_HEALTH_TIMEOUT", 5*time.Minute),
+			Check:  checker.checker.Health,
+		}.Run(flag.Args()[1:]))
+	}
+
+	sidecarmain.Run(checker)
+}
+
+type nvrChecker struct {
+	checker *nvr.Checker
+}
+
+func (c *nvrChecker) Name() string {
+	return "nvr"
+}
+
+func (c *nvrChecker) Check(ctx context.Context) (bool, string, error) {
+	reasons, err := c.checker.Activity(ctx)
+	if err != nil {
+		// An NVR rejected the credentials; unreachable ones are skipped
+		return false, "", err
+	}
+
+	if len(reasons) > 0 {
+		return true, strings.Join(reasons, "; "), nil
+	}
+
+	return false, "", nil
+}
@@ -0,0 +1,115 @@
+// prometheus-sidecar prevents shutdown while Prometheus is writing a
+// compacted block or a snapshot, and, with PROMETHEUS_QUERIES, while
+// queries over the metrics of other hosts return anything, e.g. an md
+// array degraded on any node. Run with the "healthcheck" argument it
+// instead exits non-zero unless Prometheus is ready with a healthy TSDB
+// (and Grafana up, if GRAFANA_URL is set) and the queries return nothing,
+// for use as a greenboot health check.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/grafana"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/prometheus"
+	"github.com/addisonbair/homelab-sidecars/pkg/selftest"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	client := prometheus.NewClient(sidecarmain.Env("PROMETHEUS_URL", "http://localhost:9090"), 10*time.Second)
+
+	checker := &prometheusChecker{
+		checker: &prometheus.Checker{
+			Client: client,
+			// PROMETHEUS_BLOCK_COMPACTION=false only blocks for snapshots
+			BlockCompaction: sidecarmain.Env("PROMETHEUS_BLOCK_COMPACTION", "true") == "true",
+			// PROMETHEUS_DATA_DIR, the storage.tsdb.path mounted into the
+			// sidecar, also blocks while a snapshot is being written
+			DataDir: sidecarmain.Env("PROMETHEUS_DATA_DIR", ""),
+		},
+	}
+
+	// PROMETHEUS_QUERIES lists PromQL queries, separated by ";", each
+	// "<name>=<query>" or just the query, whose every result series blocks,
+	// like an alerting rule; "mdstat" checks the md arrays of every host
+	// running node_exporter
+	if v := sidecarmain.Env("PROMETHEUS_QUERIES", ""); v != "" {
+		rules, err := prometheus.ParseRules(v)
+		if err != nil {
+			logging.Fatalf("PROMETHEUS_QUERIES: %v", err)
+		}
+		checker.queries = &prometheus.QueryChecker{Client: client, Rules: rules}
+	}
+
+	if flag.Arg(0) == "healthcheck" {
+		var grafanaClient *grafana.Client
+		if url := sidecarmain.Env("GRAFANA_URL", ""); url != "" {
+			grafanaClient = grafana.NewClient(url, 10*time.Second)
+		}
+		// Wait for Prometheus to be ready, which it is once it has replayed
+		// its write-ahead log, retrying while the replay is slow. It fails
+		// if compactions have failed since it started, as they do when it
+		// is crash-looping on a bad block, or while any of the queries
+		// returns a series
+		os.Exit(sidecarmain.Healthcheck{
+			Name:   "prometheus",
+			Budget: sidecarmain.Duration("PROMETHEUS_HEALTH_TIMEOUT", 5*time.Minute),
+			Check: func(ctx context.Context) error {
+				if err := checker.checker.Health(ctx); err != nil {
+					return err
+				}
+				if grafanaClient != nil {
+					if err := grafanaClient.Health(ctx); err != nil {
+						return fmt.Errorf("grafana: %w", err)
+					}
+				}
+				if checker.queries != nil {
+					return checker.queries.Health(ctx)
+				}
+				return nil
+			},
+		}.Run(flag.Args()[1:]))
+	}
+
+	sidecarmain.Run(checker)
+}
+
+type prometheusChecker struct {
+	checker *prometheus.Checker
+	queries *prometheus.QueryChecker // nil without PROMETHEUS_QUERIES
+}
+
+func (c *prometheusChecker) Name() string {
+	return "prometheus"
+}
+
+func (c *prometheusChecker) Check(ctx context.Context) (bool, string, error) {
+	reasons, err := c.checker.Activity(ctx)
+	if err != nil {
+		// If Prometheus is unreachable, don't block shutdown
+		return false, "", selftest.Unreachable(ctx, err)
+	}
+
+	if c.queries != nil {
+		matches, err := c.queries.Matches(ctx)
+		if err != nil {
+			return false, "", selftest.Unreachable(ctx, err)
+		}
+		reasons = append(reasons, matches...)
+	}
+
+	if len(reasons) > 0 {
+		return true, strings.Join(reasons, "; "), nil
+	}
+
+	return false, "", nil
+}
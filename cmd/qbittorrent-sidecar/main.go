@@ -1,153 +1,207 @@
-// qbittorrent-sidecar prevents shutdown while qBittorrent is downloading.
+// qbittorrent-sidecar prevents shutdown while qBittorrent is downloading,
+// rechecking or moving torrents.
 package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
-	"net/http/cookiejar"
-	"os"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/metrics"
+	"github.com/addisonbair/homelab-sidecars/pkg/qbittorrent"
+	"github.com/addisonbair/homelab-sidecars/pkg/scope"
+	"github.com/addisonbair/homelab-sidecars/pkg/selftest"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/sleepwake"
 )
 
 func main() {
-	jar, _ := cookiejar.New(nil)
+	sidecarmain.Init()
+
+	client := qbittorrent.NewClient(
+		sidecarmain.RequireEnv("QBITTORRENT_URL"),
+		sidecarmain.Env("QBITTORRENT_USERNAME", ""),
+		sidecarmain.Env("QBITTORRENT_PASSWORD", ""),
+		10*time.Second,
+	)
+	// QBITTORRENT_COOKIE_FILE keeps the session across restarts; it needs a
+	// writable volume
+	client.CookieFile = sidecarmain.Env("QBITTORRENT_COOKIE_FILE", "")
 
 	checker := &qbittorrentChecker{
-		url:          requireEnv("QBITTORRENT_URL"),
-		username:     getEnv("QBITTORRENT_USERNAME", ""),
-		password:     getEnv("QBITTORRENT_PASSWORD", ""),
-		client:       &http.Client{Timeout: 10 * time.Second, Jar: jar},
-		etaThreshold: getDuration("ETA_THRESHOLD", 5*time.Minute),
-	}
-
-	sidecar.MustRun(context.Background(), checker, sidecar.Options{
-		InhibitWhat:  getEnv("INHIBIT_WHAT", "shutdown"),
-		PollInterval: getDuration("POLL_INTERVAL", 30*time.Second),
-		NotifyReady:  getEnv("NOTIFY_READY", "true") == "true",
-		NotifyStatus: true,
-	})
+		client:       client,
+		etaThreshold: sidecarmain.Duration("ETA_THRESHOLD", 5*time.Minute),
+	}
+
+	// CREDENTIAL_SCOPE=warn (the default) warns about a Web UI login that
+	// isn't needed because qBittorrent bypasses authentication for
+	// localhost; "enforce" refuses to start, "off" doesn't check
+	scopeMode, err := scope.ParseMode(sidecarmain.Env("CREDENTIAL_SCOPE", "warn"))
+	if err != nil {
+		logging.Fatalf("CREDENTIAL_SCOPE: %v", err)
+	}
+	if err := scope.Verify(context.Background(), scopeMode, scope.Credential{
+		Option: "QBITTORRENT_USERNAME",
+		Need:   "no login (leave QBITTORRENT_USERNAME and QBITTORRENT_PASSWORD unset)",
+		Probe:  client.ExcessAccess,
+	}); err != nil {
+		logging.Fatalf("%v", err)
+	}
+
+	opts := sidecarmain.Options{InhibitWhat: "shutdown"}
+
+	// SLEEP_HOOKS=true follows suspend and resume: torrents are paused
+	// before the machine sleeps and resumed once it wakes, and the check
+	// runs again straight away
+	if sidecarmain.Env("SLEEP_HOOKS", "false") == "true" {
+		cfg := sleepwake.Config{
+			PrepareTimeout: sidecarmain.Duration("SLEEP_PREPARE_TIMEOUT", sleepwake.DefaultPrepareTimeout),
+			DryRun:         sidecarmain.DryRun(),
+		}
+		opts.Start = func(ctx context.Context, wrapped sidecar.Checker, _ time.Duration) {
+			go func() {
+				if err := sleepwake.Watch(ctx, wrapped, checker, cfg); err != nil {
+					logging.Warnf("sleep hooks disabled: %v", err)
+				}
+			}()
+		}
+	}
+
+	sidecarmain.RunWith(checker, opts, checker)
 }
 
 type qbittorrentChecker struct {
-	url          string
-	username     string
-	password     string
-	client       *http.Client
-	loggedIn     bool
+	client       *qbittorrent.Client
 	etaThreshold time.Duration
+
+	mu     sync.Mutex
+	stats  *qbittorrent.Stats
+	paused []string // hashes PrepareSleep paused
 }
 
 func (c *qbittorrentChecker) Name() string {
 	return "qbittorrent"
 }
 
-func (c *qbittorrentChecker) login(ctx context.Context) error {
-	if c.username == "" {
-		return nil
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", c.url+"/api/v2/auth/login",
-		strings.NewReader(fmt.Sprintf("username=%s&password=%s", c.username, c.password)))
-	if err != nil {
-		return err
+func (c *qbittorrentChecker) Check(ctx context.Context) (bool, string, error) {
+	// Fetch every torrent so transfer stats cover seeding too
+	torrents, err := c.client.Torrents(ctx, "")
+	if errors.Is(err, qbittorrent.ErrAuthRequired) {
+		c.setStats(nil)
+		return false, "", err
 	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	resp, err := c.client.Do(req)
 	if err != nil {
-		return err
+		c.setStats(nil)
+		return false, "", selftest.Unreachable(ctx, err) // Can't reach qBittorrent
 	}
-	resp.Body.Close()
 
-	c.loggedIn = resp.StatusCode == http.StatusOK
-	return nil
-}
+	stats := qbittorrent.Summarize(torrents)
+	c.setStats(&stats)
 
-func (c *qbittorrentChecker) Check(ctx context.Context) (bool, string, error) {
-	if !c.loggedIn && c.username != "" {
-		if err := c.login(ctx); err != nil {
-			return false, "", nil // Can't reach qBittorrent
+	// Only inhibit for torrents finishing soon (within ETA threshold), and
+	// for any being rechecked or moved, which a shutdown would corrupt
+	var finishing, operations []string
+	for _, t := range torrents {
+		if !t.Blocks(c.etaThreshold) {
+			continue
 		}
+		if op := t.Operation(); op != "" {
+			operations = append(operations, fmt.Sprintf("%s %s (%.0f%%)", op, t.Name, t.Progress*100))
+			continue
+		}
+		finishing = append(finishing,
+			fmt.Sprintf("%s (%.0f%%, %ds)", t.Name, t.Progress*100, t.ETA))
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET",
-		c.url+"/api/v2/torrents/info?filter=downloading", nil)
-	if err != nil {
-		return false, "", err
-	}
-
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return false, "", nil // Can't reach qBittorrent
+	reasons := operations
+	if len(finishing) > 0 {
+		reasons = append(reasons, fmt.Sprintf("finishing soon: %s", strings.Join(finishing, ", ")))
 	}
-	defer resp.Body.Close()
-
-	// Re-login if unauthorized
-	if resp.StatusCode == http.StatusForbidden {
-		c.loggedIn = false
-		if err := c.login(ctx); err != nil {
-			return false, "", nil
-		}
-		return c.Check(ctx)
+	if len(reasons) > 0 {
+		return true, strings.Join(reasons, "; "), nil
 	}
 
-	var torrents []struct {
-		Name     string  `json:"name"`
-		Progress float64 `json:"progress"`
-		State    string  `json:"state"`
-		ETA      int     `json:"eta"` // seconds, 8640000 = unknown
-	}
+	return false, "", nil
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&torrents); err != nil {
-		return false, "", nil
+// PrepareSleep pauses the torrents that are running, so peers see them
+// leave rather than time out while the machine sleeps.
+func (c *qbittorrentChecker) PrepareSleep(ctx context.Context) error {
+	torrents, err := c.client.Torrents(ctx, "")
+	if err != nil {
+		return err
 	}
-
-	// Only inhibit for torrents finishing soon (within ETA threshold)
-	thresholdSecs := int(c.etaThreshold.Seconds())
-	var finishing []string
+	var hashes []string
 	for _, t := range torrents {
-		if t.Progress < 1.0 && t.ETA > 0 && t.ETA <= thresholdSecs {
-			finishing = append(finishing,
-				fmt.Sprintf("%s (%.0f%%, %ds)", t.Name, t.Progress*100, t.ETA))
+		if !t.Paused() {
+			hashes = append(hashes, t.Hash)
 		}
 	}
-
-	if len(finishing) > 0 {
-		return true, fmt.Sprintf("finishing soon: %s", strings.Join(finishing, ", ")), nil
+	if err := c.client.Pause(ctx, hashes); err != nil {
+		return err
 	}
+	logging.Infof("Paused %d torrent(s) for sleep", len(hashes))
 
-	return false, "", nil
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = hashes
+	return nil
 }
 
-func getEnv(key, fallback string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+// Resume resumes the torrents PrepareSleep paused, leaving those paused
+// by hand alone.
+func (c *qbittorrentChecker) Resume(ctx context.Context) error {
+	c.mu.Lock()
+	hashes := c.paused
+	c.paused = nil
+	c.mu.Unlock()
+
+	if err := c.client.Resume(ctx, hashes); err != nil {
+		return err
+	}
+	if len(hashes) > 0 {
+		logging.Infof("Resumed %d torrent(s) after sleep", len(hashes))
 	}
-	return fallback
+	return nil
 }
 
-func requireEnv(key string) string {
-	v := os.Getenv(key)
-	if v == "" {
-		fmt.Fprintf(os.Stderr, "Error: %s is required\n", key)
-		os.Exit(1)
-	}
-	return v
+func (c *qbittorrentChecker) setStats(stats *qbittorrent.Stats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats = stats
 }
 
-func getDuration(key string, fallback time.Duration) time.Duration {
-	v := os.Getenv(key)
-	if v == "" {
-		return fallback
-	}
-	d, err := time.ParseDuration(v)
-	if err != nil {
-		return fallback
+// Gauges exports transfer stats from the last successful poll.
+func (c *qbittorrentChecker) Gauges() []metrics.Gauge {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stats == nil {
+		return nil
 	}
-	return d
+	gauges := []metrics.Gauge{
+		{Name: "homelab_qbittorrent_download_bytes_per_second", Help: "Aggregate download rate.", Value: float64(c.stats.DownloadRate)},
+		{Name: "homelab_qbittorrent_upload_bytes_per_second", Help: "Aggregate upload rate.", Value: float64(c.stats.UploadRate)},
+		{Name: "homelab_qbittorrent_longest_eta_seconds", Help: "Longest known ETA of an incomplete torrent.", Value: c.stats.LongestETA.Seconds()},
+	}
+	states := make([]string, 0, len(c.stats.ByState))
+	for state := range c.stats.ByState {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+	for _, state := range states {
+		gauges = append(gauges, metrics.Gauge{
+			Name:   "homelab_qbittorrent_torrents",
+			Help:   "Number of torrents by state.",
+			Labels: map[string]string{"state": state},
+			Value:  float64(c.stats.ByState[state]),
+		})
+	}
+	return gauges
 }
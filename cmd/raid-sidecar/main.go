@@ -4,40 +4,114 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"maps"
+	"net/http"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
-	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/healthcheck"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/notify"
 	"github.com/addisonbair/homelab-sidecars/pkg/raid"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/timezone"
 )
 
 func main() {
-	arraysStr := requireEnv("RAID_ARRAYS")
-	arrays := strings.Split(arraysStr, ",")
-	for i := range arrays {
-		arrays[i] = strings.TrimSpace(arrays[i])
+	sidecarmain.Init()
+
+	// RAID_ARRAYS takes per-array options, e.g. "md0,md1:warn,md0:mdstat=http://node2:9101/mdstat"
+	arrays, err := raid.ParseArrays(sidecarmain.RequireEnv("RAID_ARRAYS"))
+	if err != nil {
+		logging.Fatalf("RAID_ARRAYS: %v", err)
+	}
+
+	// MDSTAT_PATH=/sys/block reads the md sysfs attributes instead, for
+	// exact rebuild ETAs
+	mdstatPath := sidecarmain.Env("MDSTAT_PATH", raid.DefaultMdstatPath)
+
+	// RAID_DETAIL=sysfs also reads state mdstat doesn't show, such as
+	// read-only arrays and mismatches found by a check
+	var detail bool
+	switch backend := sidecarmain.Env("RAID_DETAIL", "none"); backend {
+	case "sysfs":
+		detail = true
+	case "none":
+	default:
+		logging.Fatalf("RAID_DETAIL: unknown backend %q (want sysfs or none)", backend)
+	}
+
+	if flag.Arg(0) == "healthcheck" {
+		// Retried for RAID_HEALTH_TIMEOUT, since arrays may still be
+		// assembling
+		os.Exit(sidecarmain.Healthcheck{
+			Name:   "raid",
+			Budget: sidecarmain.Duration("RAID_HEALTH_TIMEOUT", time.Minute),
+			Check:  arrayHealth(mdstatPath, arrays, detail),
+		}.Run(flag.Args()[1:]))
 	}
 
-	mdstatPath := getEnv("MDSTAT_PATH", raid.DefaultMdstatPath)
+	// MDSTAT_LISTEN serves this node's mdstat to a controller node
+	if addr := sidecarmain.Env("MDSTAT_LISTEN", ""); addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/mdstat", raid.Handler(mdstatPath))
+		go func() {
+			if err := http.ListenAndServe(addr, mux); err != nil {
+				logging.Fatalf("MDSTAT_LISTEN: %v", err)
+			}
+		}()
+	}
+
+	notifier := sidecarmain.Notifier()
 
 	checker := &raidChecker{
 		mdstatPath: mdstatPath,
 		arrays:     arrays,
+		detail:     detail,
+		notifier:   notifier,
+	}
+
+	// Optional monthly scrubs, started only inside SCRUB_WINDOW, which is
+	// in SCHEDULE_TZ
+	if windowStr := sidecarmain.Env("SCRUB_WINDOW", ""); windowStr != "" {
+		window, err := raid.ParseWindow(windowStr)
+		if err != nil {
+			logging.Fatalf("SCRUB_WINDOW: %v", err)
+		}
+		if _, err := timezone.FromEnv(); err != nil {
+			logging.Fatalf("%v", err)
+		}
+		checker.scrubber = &raid.Scrubber{
+			Arrays:    raid.LocalNames(arrays),
+			Interval:  sidecarmain.Duration("SCRUB_INTERVAL", 30*24*time.Hour),
+			Window:    window,
+			StatePath: sidecarmain.Env("SCRUB_STATE", "/var/lib/raid-sidecar/scrub.json"),
+		}
 	}
 
-	sidecar.MustRun(context.Background(), checker, sidecar.Options{
-		InhibitWhat:  getEnv("INHIBIT_WHAT", "shutdown"),
-		PollInterval: getDuration("POLL_INTERVAL", 30*time.Second),
-		NotifyReady:  getEnv("NOTIFY_READY", "true") == "true",
-		NotifyStatus: true,
-	})
+	opts := sidecarmain.Options{InhibitWhat: "shutdown"}
+
+	// Check as soon as mdstat or the force-allow file changes; polling
+	// only reconciles what the watches miss. WATCH_FILES=false polls only.
+	if sidecarmain.Env("WATCH_FILES", "true") == "true" {
+		opts.Watch = []string{mdstatPath}
+	}
+	sidecarmain.RunWith(checker, opts)
 }
 
 type raidChecker struct {
 	mdstatPath string
-	arrays     []string
+	arrays     []raid.ArrayConfig
+	scrubber   *raid.Scrubber
+	detail     bool // add sysfs state to local arrays
+	notifier   notify.Notifier
+	monitors   map[string]*raid.Monitor // by mdstat path
+	warnings   map[string]string        // by array, last logged
 }
 
 func (c *raidChecker) Name() string {
@@ -45,43 +119,138 @@ func (c *raidChecker) Name() string {
 }
 
 func (c *raidChecker) Check(ctx context.Context) (bool, string, error) {
-	healthy, reason, err := raid.Check(c.mdstatPath, c.arrays)
+	snap, err := raid.Snapshot(ctx, c.mdstatPath, c.arrays)
 	if err != nil {
 		return false, "", err
 	}
+	if c.detail {
+		if err := raid.AddLocalDetail("", snap); err != nil {
+			return false, "", err
+		}
+	}
 
-	if !healthy {
+	// Announce transitions even when they don't change the inhibitor,
+	// e.g. a rebuild finishing on an array that is still degraded
+	if c.monitors == nil {
+		c.monitors = make(map[string]*raid.Monitor)
+	}
+	for _, path := range slices.Sorted(maps.Keys(snap)) {
+		monitor, ok := c.monitors[path]
+		if !ok {
+			monitor = &raid.Monitor{}
+			c.monitors[path] = monitor
+		}
+		for _, event := range monitor.Update(snap[path]) {
+			if host := raid.SourceLabel(path); host != "" {
+				event.Array += "@" + host
+			}
+			c.announce(ctx, event)
+		}
+	}
+
+	var blocking []string
+	for _, cfg := range c.arrays {
+		reason, policy := cfg.Assess(snap[cfg.Path(c.mdstatPath)])
+		if policy == raid.PolicyBlock {
+			blocking = append(blocking, reason)
+			continue
+		}
+		// Healthy, or only warning
+		c.warn(cfg.Label(), reason)
+	}
+
+	if len(blocking) > 0 {
 		// RAID is rebuilding or degraded - block shutdown
-		return true, reason, nil
+		return true, strings.Join(blocking, "; "), nil
+	}
+
+	if c.scrubber != nil {
+		running, err := c.scrubber.Run(timezone.Now())
+		if err != nil {
+			return false, "", err
+		}
+		if len(running) > 0 {
+			// Hold the inhibitor for scrubs we started
+			return true, fmt.Sprintf("scrub in progress: %s", strings.Join(running, ", ")), nil
+		}
 	}
 
 	return false, "", nil
 }
 
-func getEnv(key, fallback string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+// warn logs a warn-only array's problem when it changes, rather than on
+// every poll.
+func (c *raidChecker) warn(array, reason string) {
+	if c.warnings == nil {
+		c.warnings = make(map[string]string)
+	}
+	if c.warnings[array] == reason {
+		return
+	}
+	c.warnings[array] = reason
+	if reason != "" {
+		logging.Warnf("%s (not blocking shutdown)", reason)
 	}
-	return fallback
 }
 
-func requireEnv(key string) string {
-	v := os.Getenv(key)
-	if v == "" {
-		fmt.Fprintf(os.Stderr, "Error: %s is required\n", key)
-		os.Exit(1)
+func (c *raidChecker) announce(ctx context.Context, event raid.Event) {
+	logging.Infof("raid event: %s", event)
+	msg := notify.Message{
+		Title: fmt.Sprintf("raid: %s %s", event.Array, event.Kind),
+		Body:  event.String(),
 	}
-	return v
+	if event.Urgent() {
+		msg.Priority = notify.PriorityHigh
+	}
+	notify.Send(ctx, c.notifier, msg)
 }
 
-func getDuration(key string, fallback time.Duration) time.Duration {
-	v := os.Getenv(key)
-	if v == "" {
-		return fallback
+// arrayHealth returns a health check that fails if an array is degraded,
+// read-only or missing, e.g. because an update broke assembly. An array
+// that is rebuilding, running another sync or has mismatches only warns:
+// it is recovering on its own, and rolling back wouldn't help.
+func arrayHealth(mdstatPath string, arrays []raid.ArrayConfig, detail bool) func(context.Context) error {
+	return func(ctx context.Context) error {
+		snap, err := raid.Snapshot(ctx, mdstatPath, arrays)
+		if err != nil {
+			return err
+		}
+		if detail {
+			if err := raid.AddLocalDetail("", snap); err != nil {
+				return err
+			}
+		}
+
+		var failed, warnings []string
+		for _, cfg := range arrays {
+			statuses := snap[cfg.Path(mdstatPath)]
+			reason, _ := cfg.Assess(statuses)
+			switch {
+			case reason == "":
+			case recovering(cfg.Name, statuses):
+				warnings = append(warnings, reason)
+			default:
+				failed = append(failed, reason)
+			}
+		}
+		if len(failed) > 0 {
+			return errors.New(strings.Join(append(failed, warnings...), "; "))
+		}
+		if len(warnings) > 0 {
+			return healthcheck.Warning(errors.New(strings.Join(warnings, "; ")))
+		}
+		return nil
 	}
-	d, err := time.ParseDuration(v)
-	if err != nil {
-		return fallback
+}
+
+// recovering reports whether the named array's problem is one it is
+// already fixing: a rebuild onto a replacement, or a sync or mismatch on an
+// otherwise healthy, writable array.
+func recovering(name string, statuses []raid.Status) bool {
+	for _, s := range statuses {
+		if s.Name == name {
+			return s.Rebuilding || (s.Healthy && !s.ReadOnly)
+		}
 	}
-	return d
+	return false
 }
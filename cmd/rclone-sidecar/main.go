@@ -0,0 +1,76 @@
+// rclone-sidecar prevents shutdown while rclone is syncing, copying or
+// moving files, or a mount still has writes in its VFS cache that haven't
+// been uploaded, so files aren't left half uploaded to the remote.
+// This runs on the host, where it can see the rclone processes and caches.
+package main
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/rclone"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	detector := &rclone.Detector{ProcRoot: sidecarmain.Env("PROC_ROOT", "")}
+
+	pattern, err := regexp.Compile(sidecarmain.Env("RCLONE_PROCESS_PATTERN", rclone.DefaultProcessPattern))
+	if err != nil {
+		logging.Fatalf("RCLONE_PROCESS_PATTERN: %v", err)
+	}
+	detector.ProcessPattern = pattern
+
+	// RCLONE_RC_URLS are the remote control APIs of mounts started with --rc
+	password := sidecarmain.Env("RCLONE_RC_PASS", "")
+	if file := sidecarmain.Env("RCLONE_RC_PASS_FILE", ""); file != "" && password == "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			logging.Fatalf("reading password file: %v", err)
+		}
+		password = strings.TrimSpace(string(data))
+	}
+	var clients []*rclone.Client
+	for _, url := range sidecarmain.SplitList(sidecarmain.Env("RCLONE_RC_URLS", "")) {
+		clients = append(clients, rclone.NewClient(url, sidecarmain.Env("RCLONE_RC_USER", ""), password, 10*time.Second))
+	}
+
+	// RCLONE_CACHE_DIRS are the mounts' --cache-dir, for mounts without --rc
+	inner := rclone.NewChecker(detector, clients, sidecarmain.SplitList(sidecarmain.Env("RCLONE_CACHE_DIRS", "")))
+	mountPattern, err := regexp.Compile(sidecarmain.Env("RCLONE_MOUNT_PATTERN", rclone.DefaultMountPattern))
+	if err != nil {
+		logging.Fatalf("RCLONE_MOUNT_PATTERN: %v", err)
+	}
+	inner.MountPattern = mountPattern
+
+	checker := &rcloneChecker{checker: inner}
+
+	sidecarmain.Run(checker)
+}
+
+type rcloneChecker struct {
+	checker *rclone.Checker
+}
+
+func (c *rcloneChecker) Name() string {
+	return "rclone"
+}
+
+func (c *rcloneChecker) Check(ctx context.Context) (bool, string, error) {
+	reasons, err := c.checker.Activity(ctx)
+	if err != nil {
+		return false, "", err
+	}
+
+	if len(reasons) > 0 {
+		return true, strings.Join(reasons, "; "), nil
+	}
+
+	return false, "", nil
+}
@@ -0,0 +1,51 @@
+// seaweedfs-sidecar prevents shutdown while a SeaweedFS cluster is missing
+// volume servers or volumes are short of replicas.
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/seaweedfs"
+	"github.com/addisonbair/homelab-sidecars/pkg/selftest"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	client := seaweedfs.NewClient(sidecarmain.RequireEnv("SEAWEEDFS_MASTER_URL"), 10*time.Second)
+
+	checker := &seaweedfsChecker{
+		checker: &seaweedfs.Checker{
+			Client: client,
+			// SEAWEEDFS_VOLUME_SERVERS blocks while fewer servers are connected
+			VolumeServers: sidecarmain.Int("SEAWEEDFS_VOLUME_SERVERS", 0),
+		},
+	}
+
+	sidecarmain.Run(checker)
+}
+
+type seaweedfsChecker struct {
+	checker *seaweedfs.Checker
+}
+
+func (c *seaweedfsChecker) Name() string {
+	return "seaweedfs"
+}
+
+func (c *seaweedfsChecker) Check(ctx context.Context) (bool, string, error) {
+	reasons, err := c.checker.Activity(ctx)
+	if err != nil {
+		// If SeaweedFS is unreachable, don't block shutdown
+		return false, "", selftest.Unreachable(ctx, err)
+	}
+
+	if len(reasons) > 0 {
+		return true, strings.Join(reasons, "; "), nil
+	}
+
+	return false, "", nil
+}
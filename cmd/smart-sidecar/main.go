@@ -0,0 +1,58 @@
+// smart-sidecar prevents shutdown while a disk is running a SMART
+// self-test, as reported by smartctl, since a reboot aborts an extended
+// test hours in and it has to start over.
+// This needs smartctl and access to the disks, e.g. through a sudoers rule
+// for "smartctl -c *" and SMART_EXEC_WRAPPER="sudo -n".
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/privexec"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/smart"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	checker := &smartChecker{
+		checker: &smart.Checker{
+			// SMART_EXEC_WRAPPER (e.g. "sudo -n") if smartctl needs privileges
+			Runner: privexec.FromEnv("smart"),
+			// SMART_COMMAND runs another smartctl, e.g. "podman exec
+			// scrutiny smartctl" for the one in Scrutiny's collector
+			Command: strings.Fields(sidecarmain.Env("SMART_COMMAND", "")),
+			Devices: sidecarmain.SplitList(sidecarmain.RequireEnv("SMART_DEVICES")),
+		},
+	}
+
+	sidecarmain.Run(checker, checker.checker)
+}
+
+type smartChecker struct {
+	checker *smart.Checker
+}
+
+func (c *smartChecker) Name() string {
+	return "smart"
+}
+
+func (c *smartChecker) Check(ctx context.Context) (bool, string, error) {
+	tests, err := c.checker.Active(ctx)
+	if err != nil && len(tests) == 0 {
+		return false, "", err
+	}
+
+	if len(tests) > 0 {
+		var active []string
+		for _, t := range tests {
+			active = append(active, t.Describe())
+		}
+		return true, fmt.Sprintf("SMART self-test in progress: %s", strings.Join(active, "; ")), nil
+	}
+
+	return false, "", nil
+}
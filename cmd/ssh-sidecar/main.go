@@ -0,0 +1,55 @@
+// ssh-sidecar prevents shutdown while someone is logged in over SSH, as
+// reported by logind, so a scheduled reboot doesn't cut off a session in
+// the middle of a command. Automation accounts can be ignored.
+// This needs the system bus, to ask logind.
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/sshsession"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	checker := &sshChecker{
+		checker: &sshsession.Checker{
+			Lister:   &sshsession.Logind{},
+			Services: sidecarmain.SplitList(sidecarmain.Env("SSH_SERVICES", "sshd")),
+			// SSH_IGNORE_USERS never block, e.g. an automation account
+			IgnoreUsers: sidecarmain.SplitList(sidecarmain.Env("SSH_IGNORE_USERS", "")),
+			// SSH_INTERACTIVE=false also counts commands run over ssh
+			// without a terminal
+			Interactive: sidecarmain.Env("SSH_INTERACTIVE", "true") == "true",
+			// SSH_IDLE_TIMEOUT stops counting a terminal left idle that long
+			IdleTimeout: sidecarmain.Duration("SSH_IDLE_TIMEOUT", 0),
+		},
+	}
+
+	sidecarmain.Run(checker, checker.checker)
+}
+
+type sshChecker struct {
+	checker *sshsession.Checker
+}
+
+func (c *sshChecker) Name() string {
+	return "ssh"
+}
+
+func (c *sshChecker) Check(ctx context.Context) (bool, string, error) {
+	active, err := c.checker.Active(ctx)
+	if err != nil {
+		return false, "", err
+	}
+
+	if len(active) > 0 {
+		return true, fmt.Sprintf("logged in over SSH: %s", strings.Join(active, "; ")), nil
+	}
+
+	return false, "", nil
+}
@@ -0,0 +1,54 @@
+// sso-healthcheck exits non-zero unless the identity provider (Keycloak or
+// Authelia) is ready and serving its OpenID Connect discovery document and
+// signing keys, for use as a greenboot health check. Everything that logs
+// in through it is broken when it isn't, so it is worth a rollback.
+//
+// Unlike the sidecars it takes no inhibitor: an identity provider has no
+// work a shutdown would interrupt.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/sso"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	var providers []*sso.Provider
+	if url := sidecarmain.Env("KEYCLOAK_URL", ""); url != "" {
+		// KEYCLOAK_HEALTH_URL for Keycloak 25 and later, which serve
+		// /health/ready on the management port, e.g.
+		// http://localhost:9000/health/ready
+		providers = append(providers, sso.NewKeycloak(url, sidecarmain.Env("KEYCLOAK_REALM", "master"),
+			sidecarmain.Env("KEYCLOAK_HEALTH_URL", ""), 10*time.Second))
+	}
+	if url := sidecarmain.Env("AUTHELIA_URL", ""); url != "" {
+		// AUTHELIA_OIDC=false for an Authelia that only does forward auth
+		providers = append(providers, sso.NewAuthelia(url, sidecarmain.Env("AUTHELIA_OIDC", "true") == "true", 10*time.Second))
+	}
+	if len(providers) == 0 {
+		logging.Fatalf("KEYCLOAK_URL or AUTHELIA_URL required")
+	}
+
+	// "healthcheck" is accepted so the invocation matches the sidecars'
+	args := flag.Args()
+	if len(args) > 0 && args[0] == "healthcheck" {
+		args = args[1:]
+	}
+	// Wait for every provider to be healthy, retrying while Keycloak is
+	// still migrating its database
+	os.Exit(sidecarmain.Healthcheck{
+		Name:   "sso",
+		Budget: sidecarmain.Duration("SSO_HEALTH_TIMEOUT", 5*time.Minute),
+		Check: func(ctx context.Context) error {
+			return sso.Health(ctx, providers)
+		},
+	}.Run(args))
+}
@@ -0,0 +1,74 @@
+// synapse-sidecar prevents shutdown while a Synapse Matrix homeserver is
+// running database migrations, purging history or rooms, or receiving media
+// uploads.
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/selftest"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/synapse"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	url := sidecarmain.RequireEnv("SYNAPSE_URL")
+	token := sidecarmain.Env("SYNAPSE_ADMIN_TOKEN", "")
+	tokenFile := sidecarmain.Env("SYNAPSE_ADMIN_TOKEN_FILE", "")
+
+	// Read admin access token from file if specified
+	if tokenFile != "" && token == "" {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			logging.Fatalf("reading token file: %v", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	if token == "" {
+		logging.Fatalf("SYNAPSE_ADMIN_TOKEN or SYNAPSE_ADMIN_TOKEN_FILE required")
+	}
+
+	// SYNAPSE_METRICS_URLS lists the metrics endpoints of the main process
+	// and workers; without them only background updates are checked
+	client := synapse.NewClient(url, token, sidecarmain.SplitList(sidecarmain.Env("SYNAPSE_METRICS_URLS", "")), 10*time.Second)
+
+	checker := &synapseChecker{
+		checker: synapse.NewChecker(client),
+	}
+
+	sidecarmain.Run(checker)
+}
+
+type synapseChecker struct {
+	checker *synapse.Checker
+}
+
+func (c *synapseChecker) Name() string {
+	return "synapse"
+}
+
+func (c *synapseChecker) Check(ctx context.Context) (bool, string, error) {
+	reasons, err := c.checker.Activity(ctx)
+	if synapse.IsUnauthorized(err) {
+		// Synapse is up but the token isn't an admin's; that says nothing
+		// about whether it is migrating
+		return false, "", err
+	}
+	if err != nil {
+		// If Synapse is unreachable, don't block shutdown
+		return false, "", selftest.Unreachable(ctx, err)
+	}
+
+	if len(reasons) > 0 {
+		return true, strings.Join(reasons, "; "), nil
+	}
+
+	return false, "", nil
+}
@@ -0,0 +1,54 @@
+// syncthing-sidecar prevents shutdown while Syncthing is syncing a folder,
+// or optionally while a folder is too far out of sync.
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/selftest"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/syncthing"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	client := syncthing.NewClient(sidecarmain.Env("SYNCTHING_URL", "http://localhost:8384"), sidecarmain.RequireSecret("SYNCTHING_API_KEY"), 10*time.Second)
+
+	checker := &syncthingChecker{
+		checker: syncthing.NewChecker(client),
+	}
+	// SYNCTHING_OUT_OF_SYNC_THRESHOLD also blocks while an idle folder
+	// still needs more than that many items
+	checker.checker.OutOfSyncThreshold = sidecarmain.Int("SYNCTHING_OUT_OF_SYNC_THRESHOLD", -1)
+
+	sidecarmain.Run(checker)
+}
+
+type syncthingChecker struct {
+	checker *syncthing.Checker
+}
+
+func (c *syncthingChecker) Name() string {
+	return "syncthing"
+}
+
+func (c *syncthingChecker) Check(ctx context.Context) (bool, string, error) {
+	reasons, err := c.checker.Activity(ctx)
+	if errors.Is(err, syncthing.ErrUnauthorized) {
+		return false, "", err
+	}
+	if err != nil {
+		// If Syncthing is unreachable, don't block shutdown
+		return false, "", selftest.Unreachable(ctx, err)
+	}
+
+	if len(reasons) > 0 {
+		return true, strings.Join(reasons, "; "), nil
+	}
+
+	return false, "", nil
+}
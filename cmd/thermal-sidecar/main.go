@@ -0,0 +1,135 @@
+// thermal-sidecar watches the CPU, GPU and drive temperature sensors and,
+// once one reaches its critical temperature, force-allows shutdown: every
+// other sidecar releases its inhibitor, so a machine that is overheating
+// isn't kept running for a stream or a backup. Run with the "healthcheck"
+// argument it instead fails the boot while a sensor is that hot, e.g. as a
+// Greenboot check.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/notify"
+	"github.com/addisonbair/homelab-sidecars/pkg/override"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/thermal"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	// THERMAL_SENSORS and THERMAL_IGNORE pick sensors by pattern, e.g.
+	// "coretemp/*,amdgpu/*"; THERMAL_CRITICAL (°C) replaces each sensor's
+	// own critical temperature
+	therm := &thermal.Checker{
+		Sensors:  sidecarmain.SplitList(sidecarmain.Env("THERMAL_SENSORS", "")),
+		Ignore:   sidecarmain.SplitList(sidecarmain.Env("THERMAL_IGNORE", "")),
+		Critical: float64(sidecarmain.Int("THERMAL_CRITICAL", 0)),
+	}
+
+	if flag.Arg(0) == "healthcheck" {
+		os.Exit(sidecarmain.Healthcheck{
+			Name:   "thermal",
+			Budget: sidecarmain.Duration("THERMAL_HEALTH_TIMEOUT", time.Minute),
+			Check:  therm.Health,
+		}.Run(flag.Args()[1:]))
+	}
+
+	notifier := sidecarmain.Notifier()
+
+	checker := &thermalChecker{
+		thermal: therm,
+		// THERMAL_HYSTERESIS (°C) is how far a sensor must cool before
+		// the force-allow is lifted
+		hysteresis: float64(sidecarmain.Int("THERMAL_HYSTERESIS", 5)),
+		path:       sidecarmain.Env("FORCE_ALLOW_FILE", override.DefaultPath),
+		notifier:   notifier,
+		dryRun:     sidecarmain.DryRun(),
+	}
+
+	// The check never reports busy; the inhibitor is only ever idle
+	sidecarmain.RunWith(checker, sidecarmain.Options{
+		InhibitWhat: "shutdown",
+		AlwaysIdle:  true,
+	})
+}
+
+type thermalChecker struct {
+	thermal    *thermal.Checker
+	hysteresis float64
+	path       string
+	notifier   notify.Notifier
+	dryRun     bool // don't touch the force-allow file or notify
+
+	mu     sync.Mutex
+	forced bool
+}
+
+func (c *thermalChecker) Name() string {
+	return "thermal"
+}
+
+// Check never blocks shutdown. While a sensor is overheating it refreshes
+// the force-allow file that makes the other sidecars stand down, and
+// removes it once after, leaving one written by another sidecar alone.
+func (c *thermalChecker) Check(ctx context.Context) (bool, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	margin := 0.0
+	if c.forced {
+		margin = c.hysteresis
+	}
+	hot, err := c.thermal.Hot(margin)
+	if err != nil {
+		// Leave any force-allow file to expire on its own
+		return false, "", err
+	}
+
+	critical := len(hot) > 0
+	reason := strings.Join(hot, ", ")
+	switch {
+	case c.dryRun:
+		// The other sidecars would stand down on a real force-allow file
+	case critical:
+		err = override.Set(c.path, "thermal: "+reason)
+	case c.forced:
+		err = override.Clear(c.path)
+	}
+	if err != nil {
+		return false, "", err
+	}
+
+	if critical != c.forced {
+		c.forced = critical
+		c.announce(ctx, critical, reason)
+	}
+	return false, "", nil
+}
+
+func (c *thermalChecker) announce(ctx context.Context, critical bool, reason string) {
+	msg := notify.Message{
+		Title: "thermal: temperatures back to normal, inhibitors restored",
+		Body:  fmt.Sprintf("every sensor at least %.0f°C below critical", c.hysteresis),
+	}
+	if critical {
+		msg = notify.Message{
+			Title:    "thermal: overheating, shutdown force-allowed",
+			Body:     reason,
+			Priority: notify.PriorityHigh,
+		}
+	}
+	if c.dryRun {
+		logging.Infof("dry run: would announce %s: %s", msg.Title, msg.Body)
+		return
+	}
+	logging.Infof("%s: %s", msg.Title, msg.Body)
+	notify.Send(ctx, c.notifier, msg)
+}
@@ -0,0 +1,321 @@
+// time-to-safe estimates how long until the sidecars will stop blocking a
+// reboot, by combining the ETAs the services already report: RAID rebuild
+// finish time, remaining playback of active Jellyfin streams, and the ETA of
+// qBittorrent downloads close enough to completion to block. Torrents being
+// rechecked or moved, Jellyfin scheduled tasks and running backups have no
+// ETA and make the result unknown.
+//
+// Jellyfin and qBittorrent read the same environment variables as their
+// sidecars, e.g. JELLYFIN_URL and the JELLYFIN_* filters; the flags
+// override the URLs and credentials. -raid-arrays and -backups pick which
+// arrays and backup sidecars to check, and the backup detectors read their
+// sidecars' variables too.
+//
+// Without -minutes it also prints when that is, in -timezone (default
+// SCHEDULE_TZ, or local time). Intended for scheduling, e.g.:
+//
+//	shutdown -r +$(time-to-safe -minutes -raid-arrays=md0)
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/backup"
+	"github.com/addisonbair/homelab-sidecars/pkg/btrbk"
+	"github.com/addisonbair/homelab-sidecars/pkg/jellyfin"
+	"github.com/addisonbair/homelab-sidecars/pkg/qbittorrent"
+	"github.com/addisonbair/homelab-sidecars/pkg/raid"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/timemachine"
+	"github.com/addisonbair/homelab-sidecars/pkg/timezone"
+)
+
+// Exit codes
+const (
+	exitOK      = 0
+	exitError   = 1
+	exitUnknown = 2 // something is blocking with no known end
+)
+
+// estimate is one source's contribution
+type estimate struct {
+	source string
+	reason string
+	wait   time.Duration
+	known  bool
+}
+
+func main() {
+	var (
+		mdstatPath      = flag.String("mdstat", raid.DefaultMdstatPath, "path to mdstat, or /sys/block to read sysfs")
+		raidArrays      = flag.String("raid-arrays", "", "comma-separated arrays to check (empty skips RAID)")
+		jellyfinURL     = flag.String("jellyfin-url", os.Getenv("JELLYFIN_URL"), "Jellyfin URL (empty skips Jellyfin)")
+		jellyfinKeyFile = flag.String("jellyfin-key-file", "", "file containing the Jellyfin API key (default JELLYFIN_API_KEY, or JELLYFIN_API_KEY_FILE)")
+		jellyfinGrace   = flag.Duration("jellyfin-grace", sidecarmain.Duration("JELLYFIN_GRACE_PERIOD", 5*time.Minute), "Jellyfin sidecar grace period after streams end")
+		qbitURL         = flag.String("qbittorrent-url", os.Getenv("QBITTORRENT_URL"), "qBittorrent URL (empty skips qBittorrent)")
+		qbitUser        = flag.String("qbittorrent-username", os.Getenv("QBITTORRENT_USERNAME"), "qBittorrent username (password from QBITTORRENT_PASSWORD)")
+		etaThreshold    = flag.Duration("eta-threshold", sidecarmain.Duration("ETA_THRESHOLD", 5*time.Minute), "qBittorrent sidecar ETA threshold")
+		backups         = flag.String("backups", "", "comma-separated backup sidecars to check: backup, btrbk, timemachine (empty skips them)")
+		timeout         = flag.Duration("timeout", 10*time.Second, "timeout per API request")
+		minutes         = flag.Bool("minutes", false, "print only the wait in whole minutes")
+		tz              = flag.String("timezone", os.Getenv(timezone.Env), "IANA time zone for the \"safe at\" time (default local time)")
+	)
+	flag.Parse()
+
+	loc, err := timezone.Parse(*tz)
+	if err != nil {
+		fatal("%v", err)
+	}
+
+	ctx := context.Background()
+	var estimates []estimate
+
+	if *raidArrays != "" {
+		ests, err := raidEstimates(ctx, *mdstatPath, sidecarmain.SplitList(*raidArrays))
+		if err != nil {
+			fatal("raid: %v", err)
+		}
+		estimates = append(estimates, ests...)
+	}
+
+	if *jellyfinURL != "" {
+		apiKey, keyFile := os.Getenv("JELLYFIN_API_KEY"), *jellyfinKeyFile
+		if keyFile == "" && apiKey == "" {
+			// As the sidecar, which prefers JELLYFIN_API_KEY
+			keyFile = os.Getenv("JELLYFIN_API_KEY_FILE")
+		}
+		if keyFile != "" {
+			data, err := os.ReadFile(keyFile)
+			if err != nil {
+				fatal("reading Jellyfin API key file: %v", err)
+			}
+			apiKey = strings.TrimSpace(string(data))
+		}
+		// Count sessions through the sidecar's own JELLYFIN_* filters
+		jf, err := jellyfin.CheckerFromEnv(jellyfin.NewClient(*jellyfinURL, apiKey, *timeout))
+		if err != nil {
+			fatal("%v", err)
+		}
+		jf.GracePeriod = *jellyfinGrace
+		ests, err := jellyfinEstimates(ctx, jf)
+		if err != nil {
+			fatal("jellyfin: %v", err)
+		}
+		estimates = append(estimates, ests...)
+	}
+
+	if *qbitURL != "" {
+		client := qbittorrent.NewClient(*qbitURL, *qbitUser, os.Getenv("QBITTORRENT_PASSWORD"), *timeout)
+		ests, err := qbittorrentEstimates(ctx, client, *etaThreshold)
+		if err != nil {
+			fatal("qbittorrent: %v", err)
+		}
+		estimates = append(estimates, ests...)
+	}
+
+	if *backups != "" {
+		ests, err := backupEstimates(ctx, sidecarmain.SplitList(*backups))
+		if err != nil {
+			fatal("%v", err)
+		}
+		estimates = append(estimates, ests...)
+	}
+
+	var longest time.Duration
+	known := true
+	for _, e := range estimates {
+		if !e.known {
+			known = false
+		} else if e.wait > longest {
+			longest = e.wait
+		}
+		if !*minutes {
+			wait := "unknown"
+			if e.known {
+				wait = e.wait.Round(time.Second).String()
+			}
+			fmt.Printf("%s: %s (safe in %s)\n", e.source, e.reason, wait)
+		}
+	}
+
+	if !known {
+		if !*minutes {
+			fmt.Println("safe in: unknown")
+		}
+		os.Exit(exitUnknown)
+	}
+
+	if *minutes {
+		fmt.Println(int(math.Ceil(longest.Minutes())))
+	} else {
+		fmt.Printf("safe in: %s\n", longest.Round(time.Second))
+		fmt.Printf("safe at: %s\n", time.Now().Add(longest).In(loc).Format("Mon 15:04 MST"))
+	}
+	os.Exit(exitOK)
+}
+
+// raidEstimates reports arrays that are rebuilding or running another sync
+// operation (known ETA) or degraded without a rebuild (never safe on its own).
+func raidEstimates(ctx context.Context, mdstatPath string, arrays []string) ([]estimate, error) {
+	statuses, err := raid.ReadSource(ctx, mdstatPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []estimate
+	for _, s := range statuses {
+		if !contains(arrays, s.Name) || (s.Healthy && !s.Syncing()) {
+			continue
+		}
+		switch {
+		case s.Rebuilding:
+			out = append(out, estimate{
+				source: "raid",
+				reason: fmt.Sprintf("%s rebuilding: %s", s.Name, s.Progress),
+				wait:   s.Finish,
+				known:  s.Finish > 0,
+			})
+		case !s.Healthy:
+			out = append(out, estimate{
+				source: "raid",
+				reason: fmt.Sprintf("%s degraded: %s", s.Name, s.DegradedDetail()),
+			})
+		default:
+			out = append(out, estimate{
+				source: "raid",
+				reason: fmt.Sprintf("%s %s: %s", s.Name, s.SyncAction, s.Progress),
+				wait:   s.Finish,
+				known:  s.Finish > 0,
+			})
+		}
+	}
+	return out, nil
+}
+
+// jellyfinEstimates reports the streams the sidecar counts, as estimated
+// by jellyfin.Checker.Waits, and any blocking scheduled tasks, which have
+// no ETA.
+func jellyfinEstimates(ctx context.Context, jf *jellyfin.Checker) ([]estimate, error) {
+	sessions, err := jf.Client.GetActiveSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counted, _ := jf.Counted(sessions, time.Now())
+
+	var out []estimate
+	for _, w := range jf.Waits(counted) {
+		out = append(out, estimate{
+			source: "jellyfin",
+			reason: w.Session.Describe(),
+			wait:   w.Wait,
+			known:  w.Known,
+		})
+	}
+
+	if len(jf.BlockTasks) > 0 {
+		tasks, err := jf.Client.GetScheduledTasks(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range jellyfin.RunningTasks(tasks, jf.BlockTasks) {
+			out = append(out, estimate{source: "jellyfin", reason: t.Describe()})
+		}
+	}
+	return out, nil
+}
+
+// qbittorrentEstimates reports the torrents the sidecar blocks on:
+// downloads within the ETA threshold, and torrents being rechecked or
+// moved, which have no ETA.
+func qbittorrentEstimates(ctx context.Context, client *qbittorrent.Client, threshold time.Duration) ([]estimate, error) {
+	torrents, err := client.Torrents(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var out []estimate
+	for _, t := range torrents {
+		if !t.Blocks(threshold) {
+			continue
+		}
+		if op := t.Operation(); op != "" {
+			out = append(out, estimate{
+				source: "qbittorrent",
+				reason: fmt.Sprintf("%s %s (%.0f%%)", op, t.Name, t.Progress*100),
+			})
+			continue
+		}
+		out = append(out, estimate{
+			source: "qbittorrent",
+			reason: fmt.Sprintf("%s (%.0f%%)", t.Name, t.Progress*100),
+			wait:   time.Duration(t.ETA) * time.Second,
+			known:  true,
+		})
+	}
+	return out, nil
+}
+
+// backupEstimates reports the backups the named sidecars see running,
+// using the same environment as the sidecars. None has an ETA.
+func backupEstimates(ctx context.Context, sidecars []string) ([]estimate, error) {
+	var out []estimate
+	for _, name := range sidecars {
+		var active []string
+		switch name {
+		case "backup":
+			d, err := backup.DetectorFromEnv()
+			if err != nil {
+				return nil, err
+			}
+			if active, err = d.Active(time.Now()); err != nil {
+				return nil, fmt.Errorf("backup: %w", err)
+			}
+		case "btrbk":
+			d, err := btrbk.DetectorFromEnv()
+			if err != nil {
+				return nil, err
+			}
+			if active, err = d.Active(); err != nil {
+				return nil, fmt.Errorf("btrbk: %w", err)
+			}
+		case "timemachine":
+			d, err := timemachine.DetectorFromEnv()
+			if err != nil {
+				return nil, err
+			}
+			running, err := d.Active(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("timemachine: %w", err)
+			}
+			for _, b := range running {
+				active = append(active, b.Describe())
+			}
+		default:
+			return nil, fmt.Errorf("-backups: unknown sidecar %q (want backup, btrbk or timemachine)", name)
+		}
+		for _, a := range active {
+			out = append(out, estimate{source: name, reason: a})
+		}
+	}
+	return out, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func fatal(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
+	os.Exit(exitError)
+}
@@ -0,0 +1,51 @@
+// timemachine-sidecar prevents shutdown while a Mac is backing up with Time
+// Machine to a Samba or netatalk share.
+// This runs on the host: it reads the open files of the file server.
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/timemachine"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	// TIMEMACHINE_DIRS are the Time Machine shares, e.g. /srv/timemachine;
+	// TIMEMACHINE_SMBSTATUS=true also reads Samba's lock list, through
+	// TIMEMACHINE_EXEC_WRAPPER (e.g. "sudo -n") if smbstatus needs
+	// privileges. The configuration is shared with time-to-safe.
+	detector, err := timemachine.DetectorFromEnv()
+	if err != nil {
+		logging.Fatalf("%v", err)
+	}
+
+	checker := &timemachineChecker{detector: detector}
+
+	sidecarmain.Run(checker)
+}
+
+type timemachineChecker struct {
+	detector *timemachine.Detector
+}
+
+func (c *timemachineChecker) Name() string {
+	return "timemachine"
+}
+
+func (c *timemachineChecker) Check(ctx context.Context) (bool, string, error) {
+	backups, err := c.detector.Active(ctx)
+	if err != nil {
+		return false, "", err
+	}
+
+	if len(backups) > 0 {
+		return true, fmt.Sprintf("time machine backup in progress: %s", timemachine.Describe(backups)), nil
+	}
+
+	return false, "", nil
+}
@@ -0,0 +1,76 @@
+// transfer-sidecar prevents shutdown while SFTP or FTP uploads are in
+// flight, e.g. someone dropping large files into a shared folder.
+// This runs on the host: it reads the open files of other processes.
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/transfer"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	detector := &transfer.Detector{
+		// TRANSFER_DIRS are the upload folders to watch, e.g. /srv/drop
+		Dirs:     sidecarmain.SplitList(sidecarmain.RequireEnv("TRANSFER_DIRS")),
+		MinSize:  int64(sidecarmain.Int("TRANSFER_MIN_SIZE_MB", 0)) << 20,
+		ProcRoot: sidecarmain.Env("PROC_ROOT", ""),
+	}
+
+	pattern, err := regexp.Compile(sidecarmain.Env("TRANSFER_PROCESS_PATTERN", transfer.DefaultProcessPattern))
+	if err != nil {
+		logging.Fatalf("TRANSFER_PROCESS_PATTERN: %v", err)
+	}
+	detector.ProcessPattern = pattern
+
+	checker := &transferChecker{
+		detector:    detector,
+		gracePeriod: sidecarmain.Duration("TRANSFER_GRACE_PERIOD", 2*time.Minute),
+	}
+
+	sidecarmain.Run(checker)
+}
+
+type transferChecker struct {
+	detector    *transfer.Detector
+	gracePeriod time.Duration
+
+	mu         sync.Mutex
+	lastActive time.Time
+}
+
+func (c *transferChecker) Name() string {
+	return "transfer"
+}
+
+func (c *transferChecker) Check(ctx context.Context) (bool, string, error) {
+	active, err := c.detector.Active()
+	if err != nil {
+		return false, "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(active) > 0 {
+		c.lastActive = time.Now()
+		return true, fmt.Sprintf("upload in progress: %s", transfer.Describe(active)), nil
+	}
+
+	// Keep blocking between the files of a batch upload
+	if c.gracePeriod > 0 && !c.lastActive.IsZero() {
+		if elapsed := time.Since(c.lastActive); elapsed < c.gracePeriod {
+			return true, fmt.Sprintf("grace period: %s remaining", (c.gracePeriod - elapsed).Round(time.Second)), nil
+		}
+	}
+
+	return false, "", nil
+}
@@ -0,0 +1,55 @@
+// tvheadend-sidecar prevents shutdown while Tvheadend's tuners are in use:
+// someone is watching live TV or a recording is in progress.
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/selftest"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/tvheadend"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	// TVHEADEND_USER and TVHEADEND_PASS if the API needs a login; the user
+	// needs the admin permission to list subscriptions
+	client := tvheadend.NewClient(sidecarmain.RequireEnv("TVHEADEND_URL"),
+		sidecarmain.Env("TVHEADEND_USER", ""), sidecarmain.Secret("TVHEADEND_PASS"), 10*time.Second)
+
+	checker := &tvheadendChecker{
+		checker: tvheadend.NewChecker(client),
+	}
+
+	sidecarmain.Run(checker)
+}
+
+type tvheadendChecker struct {
+	checker *tvheadend.Checker
+}
+
+func (c *tvheadendChecker) Name() string {
+	return "tvheadend"
+}
+
+func (c *tvheadendChecker) Check(ctx context.Context) (bool, string, error) {
+	reasons, err := c.checker.Activity(ctx)
+	if errors.Is(err, tvheadend.ErrUnauthorized) {
+		// Tvheadend is up but won't tell us what it's doing
+		return false, "", err
+	}
+	if err != nil {
+		// If Tvheadend is unreachable, don't block shutdown
+		return false, "", selftest.Unreachable(ctx, err)
+	}
+
+	if len(reasons) > 0 {
+		return true, strings.Join(reasons, "; "), nil
+	}
+
+	return false, "", nil
+}
@@ -0,0 +1,73 @@
+// update-sidecar prevents shutdown while a package manager is changing
+// packages: dpkg or rpm holding their locks, an unattended-upgrades run, or
+// an rpm-ostree or PackageKit transaction. A reboot in the middle can leave
+// half-installed packages behind. Updates staged for the next boot don't
+// block; they are logged and exported as a metric.
+// This runs on the host (or with the host's / mounted at UPDATE_ROOT).
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/update"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	checker := &updateChecker{
+		checker: &update.Checker{
+			// UPDATE_ROOT is where the host's / is mounted in a container
+			Root:  sidecarmain.Env("UPDATE_ROOT", ""),
+			Locks: update.DefaultLocks,
+		},
+	}
+
+	// UPDATE_LOCKS replaces the lock files checked, each "<name>=<path>"
+	if v := sidecarmain.Env("UPDATE_LOCKS", ""); v != "" {
+		checker.checker.Locks = update.ParseLocks(sidecarmain.SplitList(v))
+	}
+
+	// UPDATE_DAEMONS=false doesn't ask rpm-ostree and PackageKit over D-Bus
+	if sidecarmain.Env("UPDATE_DAEMONS", "true") == "true" {
+		checker.checker.Daemons = &update.Daemons{}
+	}
+
+	sidecarmain.Run(checker, checker.checker)
+}
+
+type updateChecker struct {
+	checker *update.Checker
+	pending string // last logged
+}
+
+func (c *updateChecker) Name() string {
+	return "update"
+}
+
+func (c *updateChecker) Check(ctx context.Context) (bool, string, error) {
+	// An update staged for the next boot is only worth a log line
+	if pending, err := c.checker.Pending(); err != nil {
+		logging.Warnf("staged updates: %v", err)
+	} else if p := strings.Join(pending, ", "); p != c.pending {
+		if p != "" {
+			logging.Infof("Update pending, applied by the next boot: %s", p)
+		}
+		c.pending = p
+	}
+
+	active, err := c.checker.Active(ctx)
+	if err != nil && len(active) == 0 {
+		return false, "", err
+	}
+
+	if len(active) > 0 {
+		return true, fmt.Sprintf("package transaction in progress: %s", strings.Join(active, "; ")), nil
+	}
+
+	return false, "", nil
+}
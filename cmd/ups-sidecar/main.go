@@ -0,0 +1,116 @@
+// ups-sidecar watches a UPS through NUT or apcupsd and, once the host is on
+// battery with little charge left, force-allows shutdown: every other
+// sidecar releases its inhibitor so the UPS shutdown isn't held up by a
+// stream or a rebuild that is going to be cut short anyway.
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/notify"
+	"github.com/addisonbair/homelab-sidecars/pkg/override"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/ups"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	var source ups.Source
+	switch {
+	case sidecarmain.Env("UPS_NUT_ADDR", "") != "":
+		source = ups.NewNUT(sidecarmain.Env("UPS_NUT_ADDR", ""), sidecarmain.Env("UPS_NAME", "ups"), 10*time.Second)
+	case sidecarmain.Env("UPS_APCUPSD_ADDR", "") != "":
+		source = ups.NewAPCUPSD(sidecarmain.Env("UPS_APCUPSD_ADDR", ""), 10*time.Second)
+	default:
+		logging.Fatalf("UPS_NUT_ADDR or UPS_APCUPSD_ADDR required")
+	}
+
+	notifier := sidecarmain.Notifier()
+
+	checker := &upsChecker{
+		source:     source,
+		minCharge:  float64(sidecarmain.Int("UPS_MIN_CHARGE", 30)),
+		minRuntime: sidecarmain.Duration("UPS_MIN_RUNTIME", 5*time.Minute),
+		path:       sidecarmain.Env("FORCE_ALLOW_FILE", override.DefaultPath),
+		notifier:   notifier,
+		dryRun:     sidecarmain.DryRun(),
+	}
+
+	// The check never reports busy; the inhibitor is only ever idle
+	sidecarmain.RunWith(checker, sidecarmain.Options{
+		InhibitWhat: "shutdown",
+		AlwaysIdle:  true,
+	})
+}
+
+type upsChecker struct {
+	source     ups.Source
+	minCharge  float64
+	minRuntime time.Duration
+	path       string
+	notifier   notify.Notifier
+	dryRun     bool // don't touch the force-allow file or notify
+
+	mu     sync.Mutex
+	forced bool
+}
+
+func (c *upsChecker) Name() string {
+	return "ups"
+}
+
+// Check never blocks shutdown. While the battery is critical it refreshes
+// the force-allow file that makes the other sidecars stand down.
+func (c *upsChecker) Check(ctx context.Context) (bool, string, error) {
+	status, err := c.source.Status(ctx)
+	if err != nil {
+		// Leave any force-allow file to expire on its own
+		return false, "", err
+	}
+
+	critical := status.Critical(c.minCharge, c.minRuntime)
+	switch {
+	case c.dryRun:
+		// The other sidecars would stand down on a real force-allow file
+	case critical:
+		err = override.Set(c.path, fmt.Sprintf("ups: %s", status.Describe()))
+	default:
+		err = override.Clear(c.path)
+	}
+	if err != nil {
+		return false, "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if critical != c.forced {
+		c.forced = critical
+		c.announce(ctx, critical, status)
+	}
+	return false, "", nil
+}
+
+func (c *upsChecker) announce(ctx context.Context, critical bool, status *ups.Status) {
+	msg := notify.Message{
+		Title: "ups: battery recovered, inhibitors restored",
+		Body:  status.Describe(),
+	}
+	if critical {
+		msg = notify.Message{
+			Title:    "ups: battery critical, shutdown force-allowed",
+			Body:     status.Describe(),
+			Priority: notify.PriorityHigh,
+		}
+	}
+	if c.dryRun {
+		logging.Infof("dry run: would announce %s: %s", msg.Title, msg.Body)
+		return
+	}
+	logging.Infof("%s: %s", msg.Title, msg.Body)
+	notify.Send(ctx, c.notifier, msg)
+}
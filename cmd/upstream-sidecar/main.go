@@ -0,0 +1,106 @@
+// upstream-sidecar watches that the router and modem answer and, after a
+// sustained outage, power-cycles the modem through a smart plug's HTTP API.
+// It blocks shutdown only while the power cycle runs, so a reboot can't
+// leave the modem switched off; the outage itself is reported as a check
+// error. Run with the "healthcheck" argument it instead reports whether
+// the LAN targets answer after boot, failing the boot unless
+// UPSTREAM_HEALTH_SEVERITY=warning; it never power-cycles anything.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/upstream"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	// REMEDIATE_OFF_URL and REMEDIATE_ON_URL switch the modem's plug; without
+	// them the outage is only reported
+	var hook upstream.Hook
+	if off := sidecarmain.Env("REMEDIATE_OFF_URL", ""); off != "" {
+		plug := upstream.NewPowerCycle(off, sidecarmain.RequireEnv("REMEDIATE_ON_URL"), sidecarmain.Duration("REMEDIATE_OFF_FOR", 30*time.Second), 10*time.Second)
+		plug.Method = sidecarmain.Env("REMEDIATE_METHOD", "GET")
+		hook = plug
+	}
+
+	// UPSTREAM_TARGETS are host:port pairs, e.g. the router's and modem's
+	// web interfaces; any one unreachable counts as an outage
+	up := upstream.NewChecker(sidecarmain.SplitList(sidecarmain.RequireEnv("UPSTREAM_TARGETS")), hook)
+	up.FailAfter = sidecarmain.Duration("UPSTREAM_FAIL_AFTER", up.FailAfter)
+	// UPSTREAM_INTERFACE, e.g. eno1, must be up with carrier, a routable
+	// address and the default route before the targets are probed; a
+	// broken link is reported as such and never power-cycles the modem
+	up.Interface = sidecarmain.Env("UPSTREAM_INTERFACE", "")
+	// UPSTREAM_LOOKUP is a name resolved once the targets answer, through
+	// systemd-resolved when it runs; a failure is reported, not remediated
+	up.LookupName = sidecarmain.Env("UPSTREAM_LOOKUP", "")
+	// UPSTREAM_ICMP pings the targets first, for routers that drop TCP;
+	// where unprivileged ICMP sockets aren't allowed it falls back to TCP
+	up.ICMP = sidecarmain.Env("UPSTREAM_ICMP", "false") == "true"
+	// UPSTREAM_PROBES above 1 probes each target that many times and
+	// reports a target losing more than UPSTREAM_MAX_LOSS_PERCENT, or
+	// slower than UPSTREAM_MAX_LATENCY, as degraded; only an unreachable
+	// target is an outage
+	up.Probes = sidecarmain.Int("UPSTREAM_PROBES", up.Probes)
+	up.MaxLoss = float64(sidecarmain.Int("UPSTREAM_MAX_LOSS_PERCENT", int(up.MaxLoss*100))) / 100
+	up.MaxLatency = sidecarmain.Duration("UPSTREAM_MAX_LATENCY", 0)
+	// REMEDIATE_COOLDOWN, REMEDIATE_MAX and REMEDIATE_WINDOW keep a line
+	// that is down at the provider from being power-cycled all day;
+	// REMEDIATE_STATE keeps the count across restarts
+	up.Cooldown = sidecarmain.Duration("REMEDIATE_COOLDOWN", up.Cooldown)
+	up.MaxAttempts = sidecarmain.Int("REMEDIATE_MAX", up.MaxAttempts)
+	up.Window = sidecarmain.Duration("REMEDIATE_WINDOW", up.Window)
+	up.StatePath = sidecarmain.Env("REMEDIATE_STATE", "")
+
+	checker := &upstreamChecker{checker: up}
+
+	if flag.Arg(0) == "healthcheck" {
+		// Wait for the upstream targets to answer, e.g. after an update broke
+		// the network configuration, retrying while the link comes up
+		os.Exit(sidecarmain.Healthcheck{
+			Name:   "upstream",
+			Budget: sidecarmain.Duration("UPSTREAM_HEALTH_TIMEOUT", 2*time.Minute),
+			Check:  up.Health,
+		}.Run(flag.Args()[1:]))
+	}
+
+	sidecarmain.Run(checker)
+}
+
+type upstreamChecker struct {
+	checker *upstream.Checker
+
+	lastErr error
+}
+
+func (c *upstreamChecker) Name() string {
+	return "upstream"
+}
+
+func (c *upstreamChecker) Check(ctx context.Context) (bool, string, error) {
+	reasons, err := c.checker.Activity(ctx)
+
+	if last := c.checker.LastError(); last != nil && last != c.lastErr {
+		logging.Errorf("remediation failed: %v", last)
+	}
+	c.lastErr = c.checker.LastError()
+
+	if err != nil {
+		// The outage: reported, but no reason to hold up a shutdown
+		return false, "", err
+	}
+
+	if len(reasons) > 0 {
+		return true, strings.Join(reasons, "; "), nil
+	}
+
+	return false, "", nil
+}
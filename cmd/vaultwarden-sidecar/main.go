@@ -0,0 +1,76 @@
+// vaultwarden-sidecar prevents shutdown while the Vaultwarden SQLite
+// database is being backed up. Run with the "healthcheck" argument it instead
+// exits non-zero if Vaultwarden doesn't answer /alive after boot, for use as
+// a greenboot health check.
+// This runs on the host (or with /proc mounted) to see backup processes.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/vaultwarden"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	if flag.Arg(0) == "healthcheck" {
+		client := vaultwarden.NewClient(sidecarmain.Env("VAULTWARDEN_URL", "http://localhost"), 10*time.Second)
+		// Wait for Vaultwarden to answer /alive, which it only does once it
+		// has opened its database
+		os.Exit(sidecarmain.Healthcheck{
+			Name:   "vaultwarden",
+			Budget: sidecarmain.Duration("VAULTWARDEN_HEALTH_TIMEOUT", 5*time.Minute),
+			Check:  client.Alive,
+		}.Run(flag.Args()[1:]))
+	}
+
+	detector := &vaultwarden.Detector{
+		// VAULTWARDEN_BACKUP_LOCKS are files the backup script holds while it runs
+		LockFiles:    sidecarmain.SplitList(sidecarmain.Env("VAULTWARDEN_BACKUP_LOCKS", "")),
+		StaleLockAge: sidecarmain.Duration("VAULTWARDEN_STALE_LOCK_AGE", vaultwarden.DefaultStaleLockAge),
+		ProcRoot:     sidecarmain.Env("PROC_ROOT", ""),
+	}
+
+	// VAULTWARDEN_PROCESS_PATTERN=none disables process matching
+	if pattern := sidecarmain.Env("VAULTWARDEN_PROCESS_PATTERN", vaultwarden.DefaultProcessPattern); pattern != "none" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			logging.Fatalf("VAULTWARDEN_PROCESS_PATTERN: %v", err)
+		}
+		detector.ProcessPattern = re
+	}
+
+	checker := &vaultwardenChecker{detector: detector}
+
+	sidecarmain.Run(checker)
+}
+
+type vaultwardenChecker struct {
+	detector *vaultwarden.Detector
+}
+
+func (c *vaultwardenChecker) Name() string {
+	return "vaultwarden"
+}
+
+func (c *vaultwardenChecker) Check(ctx context.Context) (bool, string, error) {
+	active, err := c.detector.Active(time.Now())
+	if err != nil {
+		return false, "", err
+	}
+
+	if len(active) > 0 {
+		return true, fmt.Sprintf("vaultwarden backup in progress: %s", strings.Join(active, "; ")), nil
+	}
+
+	return false, "", nil
+}
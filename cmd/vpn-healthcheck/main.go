@@ -0,0 +1,59 @@
+// vpn-healthcheck exits non-zero unless the remote access tunnels
+// (WireGuard or Tailscale) are up and the expected peers have handshaken
+// recently, for use as a greenboot health check. An update that breaks
+// them locks out whoever would fix it, so it is worth a rollback.
+//
+// Unlike the sidecars it takes no inhibitor: a tunnel has no work a
+// shutdown would interrupt.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/vpn"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	var tunnels []vpn.Tunnel
+	if ifaces := sidecarmain.SplitList(sidecarmain.Env("WIREGUARD_INTERFACES", "")); len(ifaces) > 0 {
+		// WIREGUARD_PEERS are the public keys that must have handshaken,
+		// e.g. "laptop=<key>"; by default every peer of the interfaces
+		tunnels = append(tunnels, &vpn.WireGuard{
+			Lister:     &vpn.WGCtrl{},
+			Interfaces: ifaces,
+			Peers:      sidecarmain.SplitList(sidecarmain.Env("WIREGUARD_PEERS", "")),
+			MaxAge:     sidecarmain.Duration("WIREGUARD_MAX_HANDSHAKE_AGE", 5*time.Minute),
+		})
+	}
+	if socket := sidecarmain.Env("TAILSCALE_SOCKET", ""); socket != "" {
+		// TAILSCALE_PEERS are the host names that must be online;
+		// TAILSCALE_MAX_HANDSHAKE_AGE also requires a recent handshake
+		tunnels = append(tunnels, vpn.NewTailscale(socket, sidecarmain.SplitList(sidecarmain.Env("TAILSCALE_PEERS", "")),
+			sidecarmain.Duration("TAILSCALE_MAX_HANDSHAKE_AGE", 0), 10*time.Second))
+	}
+	if len(tunnels) == 0 {
+		logging.Fatalf("WIREGUARD_INTERFACES or TAILSCALE_SOCKET required")
+	}
+
+	// "healthcheck" is accepted so the invocation matches the sidecars'
+	args := flag.Args()
+	if len(args) > 0 && args[0] == "healthcheck" {
+		args = args[1:]
+	}
+	// Wait for every tunnel to be healthy, giving the peers' keepalives
+	// time to handshake
+	os.Exit(sidecarmain.Healthcheck{
+		Name:   "vpn",
+		Budget: sidecarmain.Duration("VPN_HEALTH_TIMEOUT", 5*time.Minute),
+		Check: func(ctx context.Context) error {
+			return vpn.Health(ctx, tunnels)
+		},
+	}.Run(args))
+}
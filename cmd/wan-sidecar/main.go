@@ -0,0 +1,112 @@
+// wan-sidecar measures the internet connection: latency and loss to a few
+// reflectors, and optionally the latest speedtest-tracker result. It never
+// blocks shutdown; a degraded connection is logged as a warning and
+// exported as metrics. Run with the "healthcheck" argument it instead
+// reports whether the connection is within its limits after boot, as a
+// warning unless WAN_HEALTH_SEVERITY=required; with "internet" it reports
+// whether the internet can be reached at all, or a captive portal is in
+// the way, as a warning unless INTERNET_HEALTH_SEVERITY=required.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/healthcheck"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/wan"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	var tracker *wan.SpeedtestTracker
+	if url := sidecarmain.Env("SPEEDTEST_TRACKER_URL", ""); url != "" {
+		tracker = wan.NewSpeedtestTracker(url, sidecarmain.RequireSecret("SPEEDTEST_TRACKER_TOKEN"), 10*time.Second)
+	}
+
+	// WAN_REFLECTORS are host:port pairs that accept TCP connections
+	checker := &wanChecker{
+		checker: wan.NewChecker(sidecarmain.SplitList(sidecarmain.Env("WAN_REFLECTORS", "1.1.1.1:443,8.8.8.8:443,9.9.9.9:443")), tracker),
+	}
+	limits := &checker.checker.Limits
+	limits.MaxLatency = sidecarmain.Duration("WAN_MAX_LATENCY", limits.MaxLatency)
+	limits.MaxLoss = float64(sidecarmain.Int("WAN_MAX_LOSS_PERCENT", int(limits.MaxLoss*100))) / 100
+	// WAN_MIN_DOWNLOAD_MBPS and WAN_MIN_UPLOAD_MBPS check the speedtest
+	limits.MinDownload = float64(sidecarmain.Int("WAN_MIN_DOWNLOAD_MBPS", 0)) * 1e6
+	limits.MinUpload = float64(sidecarmain.Int("WAN_MIN_UPLOAD_MBPS", 0)) * 1e6
+	limits.MaxTestAge = sidecarmain.Duration("WAN_MAX_SPEEDTEST_AGE", 0)
+
+	// INTERNET_URLS opts in to checking that the internet is reachable at
+	// all: HTTPS HEAD requests, and INTERNET_PORTAL_URL to tell a captive
+	// portal from an outage
+	internet := wan.NewInternet(sidecarmain.SplitList(sidecarmain.Env("INTERNET_URLS", "")),
+		sidecarmain.Env("INTERNET_PORTAL_URL", wan.DefaultPortalURL), 10*time.Second)
+	if len(internet.URLs) > 0 {
+		checker.internet = internet
+	}
+
+	switch flag.Arg(0) {
+	case "healthcheck":
+		os.Exit(runHealthcheck("wan", checker.checker.Health, "WAN_HEALTH_SEVERITY",
+			sidecarmain.Duration("WAN_HEALTH_TIMEOUT", 2*time.Minute)))
+	case "internet":
+		if len(internet.URLs) == 0 {
+			internet.URLs = []string{"https://www.cloudflare.com/", "https://www.google.com/"}
+		}
+		os.Exit(runHealthcheck("internet", internet.Health, "INTERNET_HEALTH_SEVERITY",
+			sidecarmain.Duration("INTERNET_HEALTH_TIMEOUT", 2*time.Minute)))
+	}
+
+	sidecarmain.Run(checker, checker.checker)
+}
+
+type wanChecker struct {
+	checker  *wan.Checker
+	internet *wan.Internet // nil unless INTERNET_URLS is set
+
+	degraded bool
+}
+
+func (c *wanChecker) Name() string {
+	return "wan"
+}
+
+// Check never reports busy: it measures the connection for the metrics and
+// logs when it becomes degraded or recovers
+func (c *wanChecker) Check(ctx context.Context) (bool, string, error) {
+	problems := c.checker.Problems(c.checker.Measure(ctx))
+	if c.internet != nil {
+		if err := c.internet.Health(ctx); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	switch {
+	case len(problems) > 0 && !c.degraded:
+		logging.Warnf("WAN degraded: %s", strings.Join(problems, "; "))
+	case len(problems) == 0 && c.degraded:
+		logging.Infof("WAN recovered")
+	}
+	c.degraded = len(problems) > 0
+	return false, "", nil
+}
+
+// runHealthcheck waits up to budget for health to pass, e.g. after an
+// update broke the network configuration, retrying while the link may
+// still be coming up. The connection is outside this host's control, so a
+// failure only warns unless severityKey is set to required. Returns the
+// process exit code.
+func runHealthcheck(name string, health func(context.Context) error, severityKey string, budget time.Duration) int {
+	return sidecarmain.Healthcheck{
+		Name:   name,
+		Budget: budget,
+		Check:  health,
+		Severity: func() (healthcheck.Severity, error) {
+			return healthcheck.ParseSeverity(sidecarmain.Env(severityKey, string(healthcheck.SeverityWarning)))
+		},
+	}.Run(flag.Args()[1:])
+}
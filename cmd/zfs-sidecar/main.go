@@ -0,0 +1,88 @@
+// zfs-sidecar prevents shutdown while ZFS replication (syncoid, zrepl or a
+// plain zfs send/receive) is transferring. Run with the "healthcheck"
+// argument it instead exits non-zero if the newest snapshot of any of
+// ZFS_DATASETS is too old, for use as a greenboot health check.
+// This runs on the host, where it can see the replication processes and
+// run zfs.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/privexec"
+	"github.com/addisonbair/homelab-sidecars/pkg/sidecarmain"
+	"github.com/addisonbair/homelab-sidecars/pkg/zfs"
+)
+
+func main() {
+	sidecarmain.Init()
+
+	if flag.Arg(0) == "healthcheck" {
+		lister := &zfs.Lister{
+			// ZFS_EXEC_WRAPPER (e.g. "sudo -n") if zfs list needs privileges
+			Runner:  privexec.FromEnv("zfs"),
+			Command: strings.Fields(sidecarmain.Env("ZFS_COMMAND", "")),
+		}
+		datasets := sidecarmain.SplitList(sidecarmain.RequireEnv("ZFS_DATASETS"))
+		maxAge := sidecarmain.Duration("ZFS_SNAPSHOT_MAX_AGE", 25*time.Hour)
+
+		// Fail if any dataset's newest snapshot is older than maxAge, e.g.
+		// because an update broke sanoid or its timer, retrying while the
+		// pools are still importing
+		os.Exit(sidecarmain.Healthcheck{
+			Name:   "zfs",
+			Budget: sidecarmain.Duration("ZFS_HEALTH_TIMEOUT", time.Minute),
+			Check: func(ctx context.Context) error {
+				stale, err := lister.Stale(ctx, datasets, maxAge, time.Now())
+				if err != nil {
+					return err
+				}
+				if len(stale) > 0 {
+					return errors.New(strings.Join(stale, "; "))
+				}
+				return nil
+			},
+		}.Run(flag.Args()[1:]))
+	}
+
+	detector := &zfs.Detector{ProcRoot: sidecarmain.Env("PROC_ROOT", "")}
+
+	pattern, err := regexp.Compile(sidecarmain.Env("ZFS_PROCESS_PATTERN", zfs.DefaultProcessPattern))
+	if err != nil {
+		logging.Fatalf("ZFS_PROCESS_PATTERN: %v", err)
+	}
+	detector.ProcessPattern = pattern
+
+	checker := &zfsChecker{detector: detector}
+
+	sidecarmain.Run(checker)
+}
+
+type zfsChecker struct {
+	detector *zfs.Detector
+}
+
+func (c *zfsChecker) Name() string {
+	return "zfs"
+}
+
+func (c *zfsChecker) Check(ctx context.Context) (bool, string, error) {
+	active, err := c.detector.Active()
+	if err != nil {
+		return false, "", err
+	}
+
+	if len(active) > 0 {
+		return true, fmt.Sprintf("zfs replication in progress: %s", strings.Join(active, "; ")), nil
+	}
+
+	return false, "", nil
+}
@@ -0,0 +1,24 @@
+#!/bin/sh
+# Greenboot health check: report if the dynamic DNS record doesn't point at
+# the host's public IP, which after an update usually means the DDNS
+# updater didn't start. Remote access is broken until it does.
+# Install to /etc/greenboot/check/wanted.d/
+#
+# A rollback won't fix the record, so by default a failure is only a
+# warning; DDNS_HEALTH_SEVERITY=required fails the boot instead. The check
+# is retried with backoff for DDNS_HEALTH_TIMEOUT, to give the updater time
+# to catch up with an address that changed during the reboot. Each result
+# is appended to /var/lib/homelab-sidecars/health-history.
+set -eu
+
+mkdir -p /var/lib/homelab-sidecars
+
+exec podman run --rm --network=host \
+    -e DDNS_HOSTNAME="${DDNS_HOSTNAME:?DDNS_HOSTNAME is required}" \
+    -e DDNS_IP_URL="${DDNS_IP_URL:-}" \
+    -e DDNS_RESOLVER="${DDNS_RESOLVER:-}" \
+    -e DDNS_HEALTH_TIMEOUT="${DDNS_HEALTH_TIMEOUT:-10m}" \
+    -e DDNS_HEALTH_SEVERITY="${DDNS_HEALTH_SEVERITY:-warning}" \
+    -e HEALTH_HISTORY=/history/health-history \
+    -v /var/lib/homelab-sidecars:/history:Z \
+    ghcr.io/addisonbair/homelab-sidecars:ddns healthcheck
@@ -0,0 +1,31 @@
+#!/bin/sh
+# Greenboot health check: fail the boot if the DNS filter isn't running or
+# doesn't answer queries, since it is usually the LAN's only resolver and
+# every client is offline until it is back.
+# Install to /etc/greenboot/check/required.d/
+#
+# Set PIHOLE_URL (with PIHOLE_PASSWORD_FILE if a password is set),
+# ADGUARD_URL (with ADGUARD_USER and ADGUARD_PASS_FILE), or both.
+# DNS_QUERY_NAME is looked up through each; a local DNS record keeps an
+# upstream outage from failing the boot. The check is retried with backoff
+# for DNS_HEALTH_TIMEOUT, so a filter still loading its blocklists doesn't
+# trigger a rollback. Each result is appended to
+# /var/lib/homelab-sidecars/health-history. DNS_HEALTH_SEVERITY=warning
+# reports a failure without failing the boot.
+set -eu
+
+mkdir -p /var/lib/homelab-sidecars
+
+exec podman run --rm --network=host \
+    -e PIHOLE_URL="${PIHOLE_URL:-}" \
+    -e PIHOLE_PASSWORD_FILE="${PIHOLE_PASSWORD_FILE:-}" \
+    -e ADGUARD_URL="${ADGUARD_URL:-}" \
+    -e ADGUARD_USER="${ADGUARD_USER:-}" \
+    -e ADGUARD_PASS_FILE="${ADGUARD_PASS_FILE:-}" \
+    -e DNS_QUERY_NAME="${DNS_QUERY_NAME:-example.com}" \
+    -e DNS_HEALTH_TIMEOUT="${DNS_HEALTH_TIMEOUT:-2m}" \
+    -e DNS_HEALTH_SEVERITY="${DNS_HEALTH_SEVERITY:-required}" \
+    -e HEALTH_HISTORY=/history/health-history \
+    -v /var/lib/homelab-sidecars:/history:Z \
+    -v /etc/homelab:/secrets:ro,z \
+    ghcr.io/addisonbair/homelab-sidecars:dns healthcheck
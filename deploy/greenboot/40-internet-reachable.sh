@@ -0,0 +1,23 @@
+#!/bin/sh
+# Greenboot health check: report if the internet can't be reached, i.e.
+# none of INTERNET_URLS answers an HTTPS request, and say whether a captive
+# portal is in the way. Pair it with 40-lan-upstream.sh, which fails the
+# boot if the router and modem themselves are unreachable.
+# Install to /etc/greenboot/check/wanted.d/
+#
+# An outage at the provider isn't fixed by a rollback, so a failure is only
+# a warning unless INTERNET_HEALTH_SEVERITY=required. The check is retried
+# with backoff for INTERNET_HEALTH_TIMEOUT, while the link comes up. Each
+# result is appended to /var/lib/homelab-sidecars/health-history.
+set -eu
+
+mkdir -p /var/lib/homelab-sidecars
+
+exec podman run --rm --network=host \
+    -e INTERNET_URLS="${INTERNET_URLS:-}" \
+    -e INTERNET_PORTAL_URL="${INTERNET_PORTAL_URL:-}" \
+    -e INTERNET_HEALTH_TIMEOUT="${INTERNET_HEALTH_TIMEOUT:-2m}" \
+    -e INTERNET_HEALTH_SEVERITY="${INTERNET_HEALTH_SEVERITY:-warning}" \
+    -e HEALTH_HISTORY=/history/health-history \
+    -v /var/lib/homelab-sidecars:/history:Z \
+    ghcr.io/addisonbair/homelab-sidecars:wan internet
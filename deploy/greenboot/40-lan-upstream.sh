@@ -0,0 +1,32 @@
+#!/bin/sh
+# Greenboot health check: fail the boot if the router or modem in
+# UPSTREAM_TARGETS doesn't answer, which after an update usually means the
+# network configuration is broken. It never power-cycles the modem.
+# Install to /etc/greenboot/check/required.d/
+#
+# UPSTREAM_ICMP=true pings the targets first, for routers that drop TCP.
+# UPSTREAM_INTERFACE, e.g. eno1, is checked first and the step that fails
+# is reported: link down, no carrier, only a link-local address or no
+# default route.
+# UPSTREAM_LOOKUP, e.g. example.com, must resolve too, through
+# systemd-resolved when it runs.
+# The check is retried with backoff for UPSTREAM_HEALTH_TIMEOUT, while the
+# link comes up. Each result is appended to
+# /var/lib/homelab-sidecars/health-history.
+# UPSTREAM_HEALTH_SEVERITY=warning reports a failure without failing the
+# boot.
+set -eu
+
+mkdir -p /var/lib/homelab-sidecars
+
+exec podman run --rm --network=host \
+    -e UPSTREAM_TARGETS="${UPSTREAM_TARGETS:?set UPSTREAM_TARGETS to the router and modem}" \
+    -e UPSTREAM_ICMP="${UPSTREAM_ICMP:-false}" \
+    -e UPSTREAM_INTERFACE="${UPSTREAM_INTERFACE:-}" \
+    -e UPSTREAM_LOOKUP="${UPSTREAM_LOOKUP:-}" \
+    -e UPSTREAM_HEALTH_TIMEOUT="${UPSTREAM_HEALTH_TIMEOUT:-2m}" \
+    -e UPSTREAM_HEALTH_SEVERITY="${UPSTREAM_HEALTH_SEVERITY:-required}" \
+    -e HEALTH_HISTORY=/history/health-history \
+    -v /var/lib/homelab-sidecars:/history:Z \
+    -v /run/dbus:/var/run/dbus:ro \
+    ghcr.io/addisonbair/homelab-sidecars:upstream healthcheck
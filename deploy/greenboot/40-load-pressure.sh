@@ -0,0 +1,22 @@
+#!/bin/sh
+# Greenboot health check: fail the boot while the machine is thrashing,
+# e.g. after an update left a service leaking memory. Tasks stalled on
+# memory or I/O for more than LOAD_PRESSURE of the time fail the check.
+# Install to /etc/greenboot/check/required.d/
+#
+# LOAD_MAX_PER_CPU also limits the 5-minute load average. The check is
+# retried with backoff for LOAD_HEALTH_TIMEOUT, so the burst of work at
+# startup can settle. Each result is appended to
+# /var/lib/homelab-sidecars/health-history.
+# LOAD_HEALTH_SEVERITY=warning reports pressure without failing the boot.
+set -eu
+
+mkdir -p /var/lib/homelab-sidecars
+
+LOAD_PRESSURE="${LOAD_PRESSURE:-memory:full=10,io:full=25}" \
+LOAD_PRESSURE_WINDOW="${LOAD_PRESSURE_WINDOW:-avg60}" \
+LOAD_MAX_PER_CPU="${LOAD_MAX_PER_CPU:-0}" \
+LOAD_HEALTH_TIMEOUT="${LOAD_HEALTH_TIMEOUT:-5m}" \
+LOAD_HEALTH_SEVERITY="${LOAD_HEALTH_SEVERITY:-required}" \
+HEALTH_HISTORY=/var/lib/homelab-sidecars/health-history \
+    exec /usr/local/bin/load-sidecar healthcheck
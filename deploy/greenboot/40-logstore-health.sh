@@ -0,0 +1,28 @@
+#!/bin/sh
+# Greenboot health check: fail the boot if the log store is unhealthy:
+# Loki not ready or with ring members that aren't active, or the
+# Elasticsearch cluster worse than ELASTICSEARCH_MIN_STATUS (green).
+# Install to /etc/greenboot/check/required.d/
+#
+# Set LOKI_URL, ELASTICSEARCH_URL or both. The check is retried with
+# backoff for LOGSTORE_HEALTH_TIMEOUT, so shards still being assigned
+# after the restart don't trigger a rollback. Each result is appended to
+# /var/lib/homelab-sidecars/health-history. LOGSTORE_HEALTH_SEVERITY=warning
+# reports a failure without failing the boot.
+set -eu
+
+mkdir -p /var/lib/homelab-sidecars
+
+exec podman run --rm --network=host \
+    -e LOKI_URL="${LOKI_URL:-}" \
+    -e ELASTICSEARCH_URL="${ELASTICSEARCH_URL:-}" \
+    -e ELASTICSEARCH_USER="${ELASTICSEARCH_USER:-}" \
+    -e ELASTICSEARCH_PASS_FILE="${ELASTICSEARCH_PASS_FILE:-}" \
+    -e ELASTICSEARCH_API_KEY_FILE="${ELASTICSEARCH_API_KEY_FILE:-}" \
+    -e ELASTICSEARCH_MIN_STATUS="${ELASTICSEARCH_MIN_STATUS:-green}" \
+    -e LOGSTORE_HEALTH_TIMEOUT="${LOGSTORE_HEALTH_TIMEOUT:-10m}" \
+    -e LOGSTORE_HEALTH_SEVERITY="${LOGSTORE_HEALTH_SEVERITY:-required}" \
+    -e HEALTH_HISTORY=/history/health-history \
+    -v /var/lib/homelab-sidecars:/history:Z \
+    -v /etc/homelab:/secrets:ro,z \
+    ghcr.io/addisonbair/homelab-sidecars:logstore healthcheck
@@ -0,0 +1,20 @@
+#!/bin/sh
+# Greenboot health check: fail the boot if the mail queue keeps growing,
+# which after an update usually means outbound delivery is broken.
+# Install to /etc/greenboot/check/required.d/
+#
+# The queue is sampled twice, MAIL_HEALTH_WINDOW apart, and the check fails
+# if it grew by more than MAIL_HEALTH_MAX_GROWTH messages. Each result is
+# appended to /var/lib/homelab-sidecars/health-history.
+# MAIL_HEALTH_SEVERITY=warning reports a growing queue without failing the
+# boot.
+set -eu
+
+mkdir -p /var/lib/homelab-sidecars
+
+MAIL_SERVER="${MAIL_SERVER:-postfix}" \
+MAIL_HEALTH_WINDOW="${MAIL_HEALTH_WINDOW:-2m}" \
+MAIL_HEALTH_MAX_GROWTH="${MAIL_HEALTH_MAX_GROWTH:-50}" \
+MAIL_HEALTH_SEVERITY="${MAIL_HEALTH_SEVERITY:-required}" \
+HEALTH_HISTORY=/var/lib/homelab-sidecars/health-history \
+    exec /usr/local/bin/mailqueue-sidecar healthcheck
@@ -0,0 +1,21 @@
+#!/bin/sh
+# Greenboot health check: fail the boot if Nextcloud is left in maintenance
+# mode, which after an update means the upgrade didn't finish.
+# Install to /etc/greenboot/check/required.d/
+#
+# The check is retried with backoff for NEXTCLOUD_HEALTH_TIMEOUT, so a
+# Nextcloud that is merely slow to start doesn't trigger a rollback. Each
+# result is appended to /var/lib/homelab-sidecars/health-history.
+# NEXTCLOUD_HEALTH_SEVERITY=warning reports a failure without failing the
+# boot.
+set -eu
+
+mkdir -p /var/lib/homelab-sidecars
+
+exec podman run --rm --network=host \
+    -e NEXTCLOUD_URL="${NEXTCLOUD_URL:-http://localhost:8080}" \
+    -e NEXTCLOUD_HEALTH_TIMEOUT="${NEXTCLOUD_HEALTH_TIMEOUT:-5m}" \
+    -e NEXTCLOUD_HEALTH_SEVERITY="${NEXTCLOUD_HEALTH_SEVERITY:-required}" \
+    -e HEALTH_HISTORY=/history/health-history \
+    -v /var/lib/homelab-sidecars:/history:Z \
+    ghcr.io/addisonbair/homelab-sidecars:nextcloud healthcheck
@@ -0,0 +1,31 @@
+#!/bin/sh
+# Greenboot health check: fail the boot if the cameras aren't capturing,
+# which after an update means the NVR didn't start or can't reach them.
+# Install to /etc/greenboot/check/required.d/
+#
+# NVR_MONITORS limits the check to the listed cameras, by name or ID. The
+# check is retried with backoff for NVR_HEALTH_TIMEOUT, so cameras that are
+# slow to reconnect don't trigger a rollback. Each result is appended to
+# /var/lib/homelab-sidecars/health-history. NVR_HEALTH_SEVERITY=warning
+# reports a failure without failing the boot.
+set -eu
+
+mkdir -p /var/lib/homelab-sidecars
+
+exec podman run --rm --network=host \
+    -e ZONEMINDER_URL="${ZONEMINDER_URL:-}" \
+    -e ZONEMINDER_USER="${ZONEMINDER_USER:-}" \
+    -e ZONEMINDER_PASS_FILE="${ZONEMINDER_PASS_FILE:-}" \
+    -e SHINOBI_URL="${SHINOBI_URL:-}" \
+    -e SHINOBI_API_KEY_FILE="${SHINOBI_API_KEY_FILE:-}" \
+    -e SHINOBI_GROUP_KEY="${SHINOBI_GROUP_KEY:-}" \
+    -e FRIGATE_URL="${FRIGATE_URL:-}" \
+    -e FRIGATE_USER="${FRIGATE_USER:-}" \
+    -e FRIGATE_PASS_FILE="${FRIGATE_PASS_FILE:-}" \
+    -e NVR_MONITORS="${NVR_MONITORS:-}" \
+    -e NVR_HEALTH_TIMEOUT="${NVR_HEALTH_TIMEOUT:-5m}" \
+    -e NVR_HEALTH_SEVERITY="${NVR_HEALTH_SEVERITY:-required}" \
+    -e HEALTH_HISTORY=/history/health-history \
+    -v /var/lib/homelab-sidecars:/history:Z \
+    -v /etc/homelab:/secrets:ro,z \
+    ghcr.io/addisonbair/homelab-sidecars:nvr healthcheck
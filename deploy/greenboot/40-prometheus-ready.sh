@@ -0,0 +1,22 @@
+#!/bin/sh
+# Greenboot health check: fail the boot if Prometheus isn't ready or its
+# TSDB is failing compactions, which after an update usually means it is
+# crash-looping on a block it can't compact.
+# Install to /etc/greenboot/check/required.d/
+#
+# Set GRAFANA_URL to also check that Grafana is up. The check is retried
+# with backoff for PROMETHEUS_HEALTH_TIMEOUT, so a slow WAL replay doesn't
+# trigger a rollback. Each result is appended to
+# /var/lib/homelab-sidecars/health-history.
+set -eu
+
+mkdir -p /var/lib/homelab-sidecars
+
+exec podman run --rm --network=host \
+    -e PROMETHEUS_URL="${PROMETHEUS_URL:-http://localhost:9090}" \
+    -e GRAFANA_URL="${GRAFANA_URL:-}" \
+    -e PROMETHEUS_HEALTH_TIMEOUT="${PROMETHEUS_HEALTH_TIMEOUT:-5m}" \
+    -e PROMETHEUS_HEALTH_SEVERITY="${PROMETHEUS_HEALTH_SEVERITY:-required}" \
+    -e HEALTH_HISTORY=/history/health-history \
+    -v /var/lib/homelab-sidecars:/history:Z \
+    ghcr.io/addisonbair/homelab-sidecars:prometheus healthcheck
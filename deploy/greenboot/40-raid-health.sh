@@ -0,0 +1,19 @@
+#!/bin/sh
+# Greenboot health check: fail the boot if a RAID array is degraded,
+# read-only or missing, which after an update can mean assembly broke.
+# Install to /etc/greenboot/check/required.d/
+#
+# An array that is rebuilding, scrubbing or has mismatches only produces a
+# warning and exits 0: it is recovering on its own, and a rollback wouldn't
+# help. RAID_HEALTH_SEVERITY=warning turns every failure into a warning.
+# Each result is appended to /var/lib/homelab-sidecars/health-history.
+set -eu
+
+mkdir -p /var/lib/homelab-sidecars
+
+RAID_ARRAYS="${RAID_ARRAYS:?set RAID_ARRAYS to the arrays to check}" \
+RAID_DETAIL="${RAID_DETAIL:-sysfs}" \
+RAID_HEALTH_TIMEOUT="${RAID_HEALTH_TIMEOUT:-1m}" \
+RAID_HEALTH_SEVERITY="${RAID_HEALTH_SEVERITY:-required}" \
+HEALTH_HISTORY=/var/lib/homelab-sidecars/health-history \
+    exec /usr/local/bin/raid-sidecar healthcheck
@@ -0,0 +1,27 @@
+#!/bin/sh
+# Greenboot health check: fail the boot if the identity provider isn't
+# ready or doesn't serve its OpenID Connect discovery document and signing
+# keys, since every service that logs in through it is broken until it is.
+# Install to /etc/greenboot/check/required.d/
+#
+# Set KEYCLOAK_URL (with KEYCLOAK_REALM, and KEYCLOAK_HEALTH_URL for
+# Keycloak 25 and later), AUTHELIA_URL, or both. The check is retried with
+# backoff for SSO_HEALTH_TIMEOUT, so a Keycloak still migrating its
+# database doesn't trigger a rollback. Each result is appended to
+# /var/lib/homelab-sidecars/health-history. SSO_HEALTH_SEVERITY=warning
+# reports a failure without failing the boot.
+set -eu
+
+mkdir -p /var/lib/homelab-sidecars
+
+exec podman run --rm --network=host \
+    -e KEYCLOAK_URL="${KEYCLOAK_URL:-}" \
+    -e KEYCLOAK_REALM="${KEYCLOAK_REALM:-master}" \
+    -e KEYCLOAK_HEALTH_URL="${KEYCLOAK_HEALTH_URL:-}" \
+    -e AUTHELIA_URL="${AUTHELIA_URL:-}" \
+    -e AUTHELIA_OIDC="${AUTHELIA_OIDC:-true}" \
+    -e SSO_HEALTH_TIMEOUT="${SSO_HEALTH_TIMEOUT:-5m}" \
+    -e SSO_HEALTH_SEVERITY="${SSO_HEALTH_SEVERITY:-required}" \
+    -e HEALTH_HISTORY=/history/health-history \
+    -v /var/lib/homelab-sidecars:/history:Z \
+    ghcr.io/addisonbair/homelab-sidecars:sso healthcheck
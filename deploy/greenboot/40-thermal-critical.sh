@@ -0,0 +1,20 @@
+#!/bin/sh
+# Greenboot health check: fail the boot while a CPU, GPU or drive sensor is
+# at its critical temperature, e.g. after an update broke fan control.
+# Install to /etc/greenboot/check/required.d/
+#
+# THERMAL_SENSORS and THERMAL_IGNORE pick sensors by pattern; THERMAL_CRITICAL
+# replaces each sensor's own critical temperature. Each result is appended
+# to /var/lib/homelab-sidecars/health-history.
+# THERMAL_HEALTH_SEVERITY=warning reports overheating without failing the
+# boot.
+set -eu
+
+mkdir -p /var/lib/homelab-sidecars
+
+THERMAL_SENSORS="${THERMAL_SENSORS:-}" \
+THERMAL_IGNORE="${THERMAL_IGNORE:-}" \
+THERMAL_CRITICAL="${THERMAL_CRITICAL:-0}" \
+THERMAL_HEALTH_SEVERITY="${THERMAL_HEALTH_SEVERITY:-required}" \
+HEALTH_HISTORY=/var/lib/homelab-sidecars/health-history \
+    exec /usr/local/bin/thermal-sidecar healthcheck
@@ -0,0 +1,22 @@
+#!/bin/sh
+# Greenboot health check: fail the boot if Vaultwarden doesn't answer its
+# /alive endpoint, which after an update means it couldn't open its
+# database or didn't start.
+# Install to /etc/greenboot/check/required.d/
+#
+# The check is retried with backoff for VAULTWARDEN_HEALTH_TIMEOUT, so a
+# Vaultwarden that is merely slow to start doesn't trigger a rollback. Each
+# result is appended to /var/lib/homelab-sidecars/health-history.
+# VAULTWARDEN_HEALTH_SEVERITY=warning reports a failure without failing the
+# boot.
+set -eu
+
+mkdir -p /var/lib/homelab-sidecars
+
+exec podman run --rm --network=host \
+    -e VAULTWARDEN_URL="${VAULTWARDEN_URL:-http://localhost}" \
+    -e VAULTWARDEN_HEALTH_TIMEOUT="${VAULTWARDEN_HEALTH_TIMEOUT:-5m}" \
+    -e VAULTWARDEN_HEALTH_SEVERITY="${VAULTWARDEN_HEALTH_SEVERITY:-required}" \
+    -e HEALTH_HISTORY=/history/health-history \
+    -v /var/lib/homelab-sidecars:/history:Z \
+    ghcr.io/addisonbair/homelab-sidecars:vaultwarden healthcheck
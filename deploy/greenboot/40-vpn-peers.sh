@@ -0,0 +1,32 @@
+#!/bin/sh
+# Greenboot health check: fail the boot if the WireGuard or Tailscale
+# tunnels used for remote access aren't up, since an update that breaks
+# them locks out whoever would fix it.
+# Install to /etc/greenboot/check/required.d/
+#
+# Set WIREGUARD_INTERFACES (with WIREGUARD_PEERS to name the peers that
+# must have handshaken within WIREGUARD_MAX_HANDSHAKE_AGE; they need
+# PersistentKeepalive), TAILSCALE_SOCKET (with TAILSCALE_PEERS to name the
+# peers that must be online), or both. The check is retried with backoff
+# for VPN_HEALTH_TIMEOUT, so peers that take a while to handshake after
+# boot don't trigger a rollback. Each result is appended to
+# /var/lib/homelab-sidecars/health-history. VPN_HEALTH_SEVERITY=warning
+# reports a failure without failing the boot.
+set -eu
+
+mkdir -p /var/lib/homelab-sidecars
+
+exec podman run --rm --network=host --cap-add=NET_ADMIN \
+    --security-opt label=disable \
+    -e WIREGUARD_INTERFACES="${WIREGUARD_INTERFACES:-}" \
+    -e WIREGUARD_PEERS="${WIREGUARD_PEERS:-}" \
+    -e WIREGUARD_MAX_HANDSHAKE_AGE="${WIREGUARD_MAX_HANDSHAKE_AGE:-5m}" \
+    -e TAILSCALE_SOCKET="${TAILSCALE_SOCKET:-}" \
+    -e TAILSCALE_PEERS="${TAILSCALE_PEERS:-}" \
+    -e TAILSCALE_MAX_HANDSHAKE_AGE="${TAILSCALE_MAX_HANDSHAKE_AGE:-0s}" \
+    -e VPN_HEALTH_TIMEOUT="${VPN_HEALTH_TIMEOUT:-5m}" \
+    -e VPN_HEALTH_SEVERITY="${VPN_HEALTH_SEVERITY:-required}" \
+    -e HEALTH_HISTORY=/history/health-history \
+    -v /var/lib/homelab-sidecars:/history:Z \
+    -v /run/tailscale:/run/tailscale \
+    ghcr.io/addisonbair/homelab-sidecars:vpn healthcheck
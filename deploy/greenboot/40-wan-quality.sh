@@ -0,0 +1,28 @@
+#!/bin/sh
+# Greenboot health check: report if the internet connection is degraded,
+# i.e. the reflectors in WAN_REFLECTORS are slow or unreachable, or the
+# latest speedtest-tracker result is below the configured speeds.
+# Install to /etc/greenboot/check/wanted.d/
+#
+# A rollback rarely fixes the ISP, so a failure is only a warning unless
+# WAN_HEALTH_SEVERITY=required. The check is retried with backoff for
+# WAN_HEALTH_TIMEOUT, while the link comes up. Each result is appended to
+# /var/lib/homelab-sidecars/health-history.
+set -eu
+
+mkdir -p /var/lib/homelab-sidecars
+
+exec podman run --rm --network=host \
+    -e WAN_REFLECTORS="${WAN_REFLECTORS:-}" \
+    -e WAN_MAX_LATENCY="${WAN_MAX_LATENCY:-}" \
+    -e WAN_MAX_LOSS_PERCENT="${WAN_MAX_LOSS_PERCENT:-}" \
+    -e SPEEDTEST_TRACKER_URL="${SPEEDTEST_TRACKER_URL:-}" \
+    -e SPEEDTEST_TRACKER_TOKEN_FILE="${SPEEDTEST_TRACKER_TOKEN_FILE:-}" \
+    -e WAN_MIN_DOWNLOAD_MBPS="${WAN_MIN_DOWNLOAD_MBPS:-}" \
+    -e WAN_MIN_UPLOAD_MBPS="${WAN_MIN_UPLOAD_MBPS:-}" \
+    -e WAN_HEALTH_TIMEOUT="${WAN_HEALTH_TIMEOUT:-2m}" \
+    -e WAN_HEALTH_SEVERITY="${WAN_HEALTH_SEVERITY:-warning}" \
+    -e HEALTH_HISTORY=/history/health-history \
+    -v /var/lib/homelab-sidecars:/history:Z \
+    -v /etc/homelab:/secrets:ro,z \
+    ghcr.io/addisonbair/homelab-sidecars:wan healthcheck
@@ -0,0 +1,18 @@
+#!/bin/sh
+# Greenboot health check: fail the boot if a ZFS dataset's newest snapshot
+# is too old, which usually means sanoid or zrepl stopped taking them.
+# Install to /etc/greenboot/check/required.d/
+#
+# Set ZFS_DATASETS to the comma-separated datasets to check. Each result is
+# appended to /var/lib/homelab-sidecars/health-history.
+# ZFS_HEALTH_SEVERITY=warning reports stale snapshots without failing the
+# boot.
+set -eu
+
+mkdir -p /var/lib/homelab-sidecars
+
+ZFS_DATASETS="${ZFS_DATASETS:?set ZFS_DATASETS to the datasets to check}" \
+ZFS_SNAPSHOT_MAX_AGE="${ZFS_SNAPSHOT_MAX_AGE:-25h}" \
+ZFS_HEALTH_SEVERITY="${ZFS_HEALTH_SEVERITY:-required}" \
+HEALTH_HISTORY=/var/lib/homelab-sidecars/health-history \
+    exec /usr/local/bin/zfs-sidecar healthcheck
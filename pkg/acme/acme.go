@@ -0,0 +1,65 @@
+// Package acme detects ACME certificate orders in progress in a reverse
+// proxy, so a reboot doesn't abort one halfway. An aborted order has to
+// start over, and each attempt counts against the CA's rate limits, which
+// for Let's Encrypt lock a domain out for hours after a few failures.
+package acme
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Source reports the ACME orders a proxy has in flight
+type Source interface {
+	Name() string
+	// Orders returns a description of each order in progress, e.g.
+	// "obtaining certificate for example.com"
+	Orders(ctx context.Context) ([]string, error)
+}
+
+// Checker implements check.Checker for ACME orders.
+// Returns an error while any source has an order in flight.
+type Checker struct {
+	Sources []Source
+}
+
+// NewChecker creates an ACME checker.
+func NewChecker(sources []Source) *Checker {
+	return &Checker{Sources: sources}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "acme"
+}
+
+// Check returns nil if no order is in flight, error otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	reasons, err := c.Activity(ctx)
+	if err != nil {
+		return fmt.Errorf("acme check failed: %w", err)
+	}
+	if len(reasons) > 0 {
+		return errors.New(strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// Activity describes the orders in flight, prefixed with the source name.
+// A source that fails is returned as an error, since it can't say whether
+// an order is running.
+func (c *Checker) Activity(ctx context.Context) ([]string, error) {
+	var reasons []string
+	for _, s := range c.Sources {
+		orders, err := s.Orders(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Name(), err)
+		}
+		for _, o := range orders {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", s.Name(), o))
+		}
+	}
+	return reasons, nil
+}
@@ -0,0 +1,141 @@
+package acme
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCaddy(t *testing.T) {
+	dir := t.TempDir()
+	locks := filepath.Join(dir, "locks")
+	if err := os.Mkdir(locks, 0755); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	files := map[string]string{
+		// Refreshed 3s ago
+		"issue_cert_example.com.lock":           `{"created": "2024-06-01T11:59:00Z", "updated": "2024-06-01T11:59:57Z"}`,
+		"issue_cert_wildcard_.example.org.lock": `{"created": "2024-06-01T11:59:50Z", "updated": "2024-06-01T11:59:55Z"}`,
+		// Left behind by a Caddy that was killed
+		"issue_cert_stale.example.com.lock": `{"created": "2024-06-01T10:00:00Z", "updated": "2024-06-01T10:30:00Z"}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(locks, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := NewCaddy(dir)
+	c.now = func() time.Time { return now }
+	orders, err := c.Orders(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "obtaining certificate for example.com|obtaining certificate for *.example.org"
+	if got := strings.Join(orders, "|"); got != want {
+		t.Errorf("Orders() = %q, want %q", got, want)
+	}
+
+	// No locks directory until the first order
+	if orders, err := NewCaddy(t.TempDir()).Orders(context.Background()); err != nil || len(orders) != 0 {
+		t.Errorf("Orders() = %q, %v, want none", orders, err)
+	}
+	if _, err := NewCaddy(filepath.Join(dir, "missing")).Orders(context.Background()); err == nil {
+		t.Error("Orders() with missing data dir: want error")
+	}
+}
+
+func TestTraefik(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traefik.log")
+	// History from before the sidecar started is ignored
+	write(t, path, `time="2024-06-01T11:00:00Z" level=info msg="legolog: [INFO] [old.example.com] acme: Obtaining bundled SAN certificate"`+"\n", false)
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	l := NewTraefik(path)
+	l.now = func() time.Time { return now }
+
+	orders := mustOrders(t, l)
+	if len(orders) != 0 {
+		t.Errorf("first Orders() = %q, want none", orders)
+	}
+
+	write(t, path, `time="2024-06-01T12:00:00Z" level=info msg="legolog: [INFO] [example.com] acme: Obtaining bundled SAN certificate"
+time="2024-06-01T12:00:00Z" level=info msg="legolog: [INFO] [Other.example.com] acme: Trying renewal with 719 hours remaining"
+`, true)
+	mustOrders(t, l)
+	now = now.Add(30 * time.Second)
+	want := "obtaining certificate for example.com (30s)|obtaining certificate for other.example.com (30s)"
+	if got := strings.Join(mustOrders(t, l), "|"); got != want {
+		t.Errorf("Orders() = %q, want %q", got, want)
+	}
+
+	// One succeeds, the other fails
+	write(t, path, `time="2024-06-01T12:01:00Z" level=info msg="legolog: [INFO] [example.com] Server responded with a certificate."
+time="2024-06-01T12:01:00Z" level=error msg="Unable to obtain ACME certificate for domains \"other.example.com\": unable to generate a certificate"
+`, true)
+	if orders := mustOrders(t, l); len(orders) != 0 {
+		t.Errorf("Orders() after end = %q, want none", orders)
+	}
+
+	// An order whose end is never logged expires
+	write(t, path, `level=info msg="legolog: [INFO] [lost.example.com] acme: Obtaining bundled SAN certificate"`+"\n", true)
+	if orders := mustOrders(t, l); len(orders) != 1 {
+		t.Errorf("Orders() = %q, want lost.example.com", orders)
+	}
+	now = now.Add(DefaultMaxOrder + time.Second)
+	if orders := mustOrders(t, l); len(orders) != 0 {
+		t.Errorf("Orders() after MaxOrder = %q, want none", orders)
+	}
+
+	// Rotated: the new file is read from the start
+	write(t, path, `msg="legolog: [INFO] [new.example.com] acme: Obtaining bundled SAN certificate"`+"\n", false)
+	if orders := mustOrders(t, l); len(orders) != 1 || !strings.Contains(orders[0], "new.example.com") {
+		t.Errorf("Orders() after rotation = %q, want new.example.com", orders)
+	}
+}
+
+func TestChecker(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "locks"), 0755)
+	os.WriteFile(filepath.Join(dir, "locks", "issue_cert_example.com.lock"), nil, 0644)
+
+	checker := NewChecker([]Source{NewCaddy(dir)})
+	err := checker.Check(context.Background())
+	if err == nil || err.Error() != "caddy: obtaining certificate for example.com" {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	checker = NewChecker([]Source{NewTraefik(filepath.Join(dir, "missing.log"))})
+	if err := checker.Check(context.Background()); err == nil || !strings.HasPrefix(err.Error(), "acme check failed: traefik:") {
+		t.Errorf("Check() error = %v, want acme check failed", err)
+	}
+}
+
+func mustOrders(t *testing.T, s Source) []string {
+	t.Helper()
+	orders, err := s.Orders(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return orders
+}
+
+func write(t *testing.T, path, content string, appendTo bool) {
+	t.Helper()
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendTo {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
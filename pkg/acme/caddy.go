@@ -0,0 +1,102 @@
+package acme
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultLockFresh is how recently a Caddy lock must have been refreshed to
+// count as held; certmagic refreshes its locks every 5 seconds
+const DefaultLockFresh = 30 * time.Second
+
+// Caddy finds orders in progress from the lock files certmagic keeps in
+// Caddy's storage while it obtains or renews a certificate.
+type Caddy struct {
+	// DataDir is Caddy's storage directory, e.g. /data/caddy in the
+	// official image or ~/.local/share/caddy; locks are in DataDir/locks
+	DataDir string
+	// Fresh is how recently a lock must have been refreshed to count;
+	// certmagic leaves a lock behind if Caddy is killed mid-order, and
+	// gives up on it after two hours
+	Fresh time.Duration
+
+	now func() time.Time // replaced in tests
+}
+
+// NewCaddy creates a Caddy source for the storage in dataDir.
+func NewCaddy(dataDir string) *Caddy {
+	return &Caddy{DataDir: dataDir, Fresh: DefaultLockFresh, now: time.Now}
+}
+
+// lockMeta is the content of a certmagic lock file
+type lockMeta struct {
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// Name returns the source name.
+func (c *Caddy) Name() string {
+	return "caddy"
+}
+
+// Orders describes each certificate whose lock is held.
+func (c *Caddy) Orders(ctx context.Context) ([]string, error) {
+	if _, err := os.Stat(c.DataDir); err != nil {
+		return nil, fmt.Errorf("data dir: %w", err)
+	}
+	paths, err := filepath.Glob(filepath.Join(c.DataDir, "locks", "*.lock"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var orders []string
+	for _, path := range paths {
+		updated, ok := lockUpdated(path)
+		if !ok || c.now().Sub(updated) > c.Fresh {
+			continue
+		}
+		orders = append(orders, describeLock(strings.TrimSuffix(filepath.Base(path), ".lock")))
+	}
+	return orders, nil
+}
+
+// lockUpdated returns when a lock was last refreshed: the time recorded in
+// it, or its modification time for certmagic releases that leave it empty.
+func lockUpdated(path string) (time.Time, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		// Released since the glob
+		return time.Time{}, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return info.ModTime(), true
+	}
+	var meta lockMeta
+	if json.Unmarshal(data, &meta) == nil && !meta.Updated.IsZero() {
+		return meta.Updated, true
+	}
+	return info.ModTime(), true
+}
+
+// describeLock names the operation behind a lock, e.g.
+// "issue_cert_example.com" is "obtaining certificate for example.com".
+func describeLock(name string) string {
+	for prefix, op := range map[string]string{
+		"issue_cert_": "obtaining certificate for ",
+		"renew_cert_": "renewing certificate for ",
+	} {
+		if domain, ok := strings.CutPrefix(name, prefix); ok {
+			// certmagic stores wildcards as wildcard_.example.com
+			return op + strings.Replace(domain, "wildcard_", "*", 1)
+		}
+	}
+	return "holding lock " + name
+}
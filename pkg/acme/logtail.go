@@ -0,0 +1,151 @@
+package acme
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxOrder is how long a started order is assumed to be in flight if
+// the log never says how it ended
+const DefaultMaxOrder = 10 * time.Minute
+
+// Traefik logs its ACME orders through lego, whose lines name the domain in
+// brackets; Traefik's own failure line quotes the domains instead
+var (
+	TraefikStart = regexp.MustCompile(`\[([^\]\s]+)\] acme: (?:Obtaining bundled SAN certificate|Trying renewal)`)
+	TraefikEnd   = regexp.MustCompile(`\[([^\]\s]+)\] Server responded with a certificate|[Uu]nable to obtain ACME certificate for domains \\?"([^",\\]+)`)
+)
+
+// LogTail follows a proxy's log and reports orders that have started but
+// not yet finished or failed. It only sees lines written while it runs: the
+// first call starts at the end of the file.
+type LogTail struct {
+	name       string
+	path       string
+	start, end *regexp.Regexp
+	// MaxOrder drops an order that has neither finished nor failed within
+	// it, e.g. because its last line was missed across a log rotation
+	MaxOrder time.Duration
+
+	mu      sync.Mutex
+	offset  int64
+	opened  bool
+	pending map[string]time.Time // domain → when its order was seen starting
+
+	now func() time.Time // replaced in tests
+}
+
+// NewTraefik creates a source following Traefik's log at path. Traefik
+// only logs lego's progress at info level or below.
+func NewTraefik(path string) *LogTail {
+	return NewLogTail("traefik", path, TraefikStart, TraefikEnd)
+}
+
+// NewLogTail creates a source following the log at path. start and end
+// match the lines that begin and end an order, with the domain in the
+// first non-empty capture group.
+func NewLogTail(name, path string, start, end *regexp.Regexp) *LogTail {
+	return &LogTail{
+		name:     name,
+		path:     path,
+		start:    start,
+		end:      end,
+		MaxOrder: DefaultMaxOrder,
+		pending:  make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+// Name returns the source name.
+func (l *LogTail) Name() string {
+	return l.name
+}
+
+// Orders reads the lines logged since the last call and describes each
+// order still in flight, with how long ago it started.
+func (l *LogTail) Orders(ctx context.Context) ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.read(); err != nil {
+		return nil, err
+	}
+
+	now := l.now()
+	var orders []string
+	for domain, started := range l.pending {
+		age := now.Sub(started)
+		if age > l.MaxOrder {
+			delete(l.pending, domain)
+			continue
+		}
+		orders = append(orders, fmt.Sprintf("obtaining certificate for %s (%s)", domain, age.Truncate(time.Second)))
+	}
+	sort.Strings(orders)
+	return orders, nil
+}
+
+// read scans the lines appended since the last call. A file shorter than
+// the last offset has been truncated or rotated, and is read from the
+// start.
+func (l *LogTail) read() error {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !l.opened {
+		l.opened = true
+		l.offset = info.Size()
+		return nil
+	}
+	if info.Size() < l.offset {
+		l.offset = 0
+	}
+	if _, err := f.Seek(l.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	now := l.now()
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			// Leave a partly written last line for the next call
+			break
+		}
+		l.offset += int64(len(line))
+		if domain := match(l.end, line); domain != "" {
+			delete(l.pending, domain)
+		} else if domain := match(l.start, line); domain != "" {
+			if _, ok := l.pending[domain]; !ok {
+				l.pending[domain] = now
+			}
+		}
+	}
+	return nil
+}
+
+// match returns the first non-empty capture group of re in line.
+func match(re *regexp.Regexp, line string) string {
+	m := re.FindStringSubmatch(line)
+	for _, g := range m[min(len(m), 1):] {
+		if g != "" {
+			return strings.ToLower(g)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,300 @@
+// Package audit records shutdown, reboot and sleep requests sent to logind,
+// so it is possible to tell who tried to reboot the machine while an
+// inhibitor was blocking it.
+//
+// logind does not emit a signal for refused requests, so the package
+// becomes a D-Bus monitor and observes the method calls and their replies
+// directly. This requires running as root on the host bus.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/logind"
+	"github.com/addisonbair/homelab-sidecars/pkg/notify"
+	"github.com/godbus/dbus/v5"
+)
+
+// methods maps logind manager methods to the action they inhibit
+var methods = map[string]string{
+	"PowerOff":                      "shutdown",
+	"PowerOffWithFlags":             "shutdown",
+	"Reboot":                        "shutdown",
+	"RebootWithFlags":               "shutdown",
+	"Halt":                          "shutdown",
+	"HaltWithFlags":                 "shutdown",
+	"KExec":                         "shutdown",
+	"KExecWithFlags":                "shutdown",
+	"SoftReboot":                    "shutdown",
+	"SoftRebootWithFlags":           "shutdown",
+	"ScheduleShutdown":              "shutdown",
+	"Suspend":                       "sleep",
+	"SuspendWithFlags":              "sleep",
+	"Hibernate":                     "sleep",
+	"HibernateWithFlags":            "sleep",
+	"HybridSleep":                   "sleep",
+	"HybridSleepWithFlags":          "sleep",
+	"SuspendThenHibernate":          "sleep",
+	"SuspendThenHibernateWithFlags": "sleep",
+}
+
+// Attempt is a shutdown or sleep request observed on the bus
+type Attempt struct {
+	Time    time.Time
+	Method  string // logind method, e.g. "Reboot"
+	What    string // "shutdown" or "sleep"
+	UID     uint32
+	User    string
+	PID     uint32
+	Command string
+
+	// Refused is set when logind answered with an error
+	Refused bool
+	Error   string
+	// Unanswered is set when no reply was seen: the caller asked for
+	// none, or it didn't arrive within PendingTimeout
+	Unanswered bool
+
+	// BlockedBy lists the block-mode inhibitors held at the time
+	BlockedBy []logind.Inhibitor
+}
+
+// String returns a one-line description of the attempt.
+func (a Attempt) String() string {
+	outcome := "allowed"
+	if a.Unanswered {
+		outcome = "no reply seen"
+	}
+	if a.Refused {
+		outcome = "refused"
+		if a.Error != "" {
+			outcome += " (" + a.Error + ")"
+		}
+	}
+	caller := a.User
+	if caller == "" {
+		caller = "uid " + strconv.FormatUint(uint64(a.UID), 10)
+	}
+	s := fmt.Sprintf("%s by %s (pid %d, %s): %s", a.Method, caller, a.PID, a.Command, outcome)
+	if len(a.BlockedBy) > 0 {
+		var held []string
+		for _, inh := range a.BlockedBy {
+			held = append(held, fmt.Sprintf("%s: %s", inh.Who, inh.Why))
+		}
+		s += "; inhibitors held: " + strings.Join(held, ", ")
+	}
+	return s
+}
+
+// HeldBy reports whether one of the blocking inhibitors belongs to who.
+func (a Attempt) HeldBy(who string) bool {
+	for _, inh := range a.BlockedBy {
+		if inh.Who == who {
+			return true
+		}
+	}
+	return false
+}
+
+// Watch monitors the system bus and calls fn for every shutdown or sleep
+// request once logind has replied to it. It blocks until ctx is cancelled.
+func Watch(ctx context.Context, fn func(Attempt)) error {
+	// Monitoring turns the connection receive-only, so lookups go
+	// through a second connection.
+	monitor, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return fmt.Errorf("connecting to system bus: %w", err)
+	}
+	defer monitor.Close()
+
+	lookup, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return fmt.Errorf("connecting to system bus: %w", err)
+	}
+	defer lookup.Close()
+
+	rules := []string{
+		fmt.Sprintf("type='method_call',interface='%s'", logind.Interface),
+		"type='method_return'",
+		"type='error'",
+	}
+	call := monitor.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.Monitoring.BecomeMonitor", 0, rules, uint32(0))
+	if call.Err != nil {
+		return fmt.Errorf("becoming monitor: %w", call.Err)
+	}
+
+	messages := make(chan *dbus.Message, 64)
+	monitor.Eavesdrop(messages)
+
+	pending := newPending()
+	expire := time.NewTicker(PendingTimeout)
+	defer expire.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case msg, ok := <-messages:
+			if !ok {
+				return fmt.Errorf("monitor connection closed")
+			}
+			handleMessage(ctx, lookup, pending, msg, fn)
+
+		case now := <-expire.C:
+			for _, a := range pending.expire(now) {
+				fn(a)
+			}
+		}
+	}
+}
+
+// PendingTimeout is how long a call waits for its reply before it is
+// reported as unanswered; D-Bus itself gives up on a reply after 25s
+const PendingTimeout = time.Minute
+
+// pending holds the calls waiting for their reply, keyed by callKey
+type pending struct {
+	calls map[string]Attempt
+}
+
+func newPending() *pending {
+	return &pending{calls: make(map[string]Attempt)}
+}
+
+func (p *pending) add(key string, a Attempt) {
+	p.calls[key] = a
+}
+
+// take removes and returns the call waiting under key.
+func (p *pending) take(key string) (Attempt, bool) {
+	a, ok := p.calls[key]
+	delete(p.calls, key)
+	return a, ok
+}
+
+// expire removes the calls made more than PendingTimeout before now,
+// whose replies the monitor never saw, and returns them marked unanswered.
+func (p *pending) expire(now time.Time) []Attempt {
+	var out []Attempt
+	for key, a := range p.calls {
+		if now.Sub(a.Time) > PendingTimeout {
+			delete(p.calls, key)
+			a.Unanswered = true
+			out = append(out, a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	return out
+}
+
+func handleMessage(ctx context.Context, lookup *dbus.Conn, pending *pending, msg *dbus.Message, fn func(Attempt)) {
+	switch msg.Type {
+	case dbus.TypeMethodCall:
+		iface, _ := header(msg, dbus.FieldInterface)
+		member, _ := header(msg, dbus.FieldMember)
+		what, ok := methods[member]
+		if iface != logind.Interface || !ok {
+			return
+		}
+		sender, _ := header(msg, dbus.FieldSender)
+
+		a := Attempt{Time: time.Now(), Method: member, What: what}
+		describeCaller(ctx, lookup, sender, &a)
+		if inhibitors, err := logind.ListInhibitors(ctx, lookup); err == nil {
+			a.BlockedBy = logind.Blocking(inhibitors, what)
+		}
+		if msg.Flags&dbus.FlagNoReplyExpected != 0 {
+			// logind won't reply, so there is nothing to wait for
+			a.Unanswered = true
+			fn(a)
+			return
+		}
+		pending.add(callKey(sender, msg.Serial()), a)
+
+	case dbus.TypeMethodReply, dbus.TypeError:
+		dest, _ := header(msg, dbus.FieldDestination)
+		serial, ok := msg.Headers[dbus.FieldReplySerial].Value().(uint32)
+		if !ok {
+			return
+		}
+		a, ok := pending.take(callKey(dest, serial))
+		if !ok {
+			return
+		}
+
+		if msg.Type == dbus.TypeError {
+			a.Refused = true
+			a.Error, _ = header(msg, dbus.FieldErrorName)
+		}
+		fn(a)
+	}
+}
+
+func callKey(sender string, serial uint32) string {
+	return sender + "/" + strconv.FormatUint(uint64(serial), 10)
+}
+
+func header(msg *dbus.Message, field dbus.HeaderField) (string, bool) {
+	v, ok := msg.Headers[field]
+	if !ok {
+		return "", false
+	}
+	switch s := v.Value().(type) {
+	case string:
+		return s, true
+	case dbus.ObjectPath:
+		return string(s), true
+	}
+	return "", false
+}
+
+// describeCaller fills in the UID, user name, PID and command of sender.
+func describeCaller(ctx context.Context, conn *dbus.Conn, sender string, a *Attempt) {
+	bus := conn.BusObject()
+	if err := bus.CallWithContext(ctx, "org.freedesktop.DBus.GetConnectionUnixUser", 0, sender).Store(&a.UID); err == nil {
+		if u, err := user.LookupId(strconv.FormatUint(uint64(a.UID), 10)); err == nil {
+			a.User = u.Username
+		}
+	}
+	if err := bus.CallWithContext(ctx, "org.freedesktop.DBus.GetConnectionUnixProcessID", 0, sender).Store(&a.PID); err == nil {
+		a.Command = processCommand(a.PID)
+	}
+}
+
+// processCommand returns the command line of pid, or its comm name if the
+// command line is unavailable.
+func processCommand(pid uint32) string {
+	dir := "/proc/" + strconv.FormatUint(uint64(pid), 10)
+	if data, err := os.ReadFile(dir + "/cmdline"); err == nil && len(data) > 0 {
+		return strings.TrimSpace(strings.ReplaceAll(string(data), "\x00", " "))
+	}
+	if data, err := os.ReadFile(dir + "/comm"); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	return "unknown"
+}
+
+// Log watches for shutdown and sleep requests made while who holds a
+// blocking inhibitor, logging each one and sending a notification if n
+// is non-nil. It blocks until ctx is cancelled.
+func Log(ctx context.Context, who string, n notify.Notifier) error {
+	return Watch(ctx, func(a Attempt) {
+		if !a.HeldBy(who) {
+			return
+		}
+		logging.Infof("Audit: %s", a)
+		notify.Send(ctx, n, notify.Message{
+			Title:    fmt.Sprintf("%s: %s attempted while blocked", who, strings.ToLower(a.Method)),
+			Body:     a.String(),
+			Priority: notify.PriorityHigh,
+		})
+	})
+}
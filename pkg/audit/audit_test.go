@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logind"
+	"github.com/godbus/dbus/v5"
+)
+
+func TestAttempt_String(t *testing.T) {
+	a := Attempt{
+		Method:  "Reboot",
+		What:    "shutdown",
+		UID:     0,
+		User:    "root",
+		PID:     4242,
+		Command: "/usr/bin/python3 /usr/bin/unattended-upgrade",
+		Refused: true,
+		Error:   "org.freedesktop.login1.BlockedByInhibitorLock",
+		BlockedBy: []logind.Inhibitor{
+			{Who: "raid", Why: "md0 rebuilding: 17.5%", Mode: "block", What: "shutdown"},
+		},
+	}
+
+	got := a.String()
+	for _, want := range []string{"Reboot by root", "pid 4242", "unattended-upgrade", "refused", "raid: md0 rebuilding"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("String() = %q, want to contain %q", got, want)
+		}
+	}
+
+	if !a.HeldBy("raid") {
+		t.Error("HeldBy(raid) = false, want true")
+	}
+	if a.HeldBy("jellyfin") {
+		t.Error("HeldBy(jellyfin) = true, want false")
+	}
+}
+
+func TestAttempt_StringUnknownUser(t *testing.T) {
+	a := Attempt{Method: "Suspend", UID: 1000, PID: 1, Command: "systemctl"}
+	if got := a.String(); !strings.Contains(got, "uid 1000") || !strings.Contains(got, "allowed") {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestPending(t *testing.T) {
+	start := time.Date(2024, 3, 1, 3, 0, 0, 0, time.UTC)
+	p := newPending()
+	p.add(callKey(":1.42", 7), Attempt{Time: start, Method: "Reboot"})
+	p.add(callKey(":1.43", 9), Attempt{Time: start.Add(time.Minute), Method: "Suspend"})
+
+	// A reply removes its call
+	var replied []Attempt
+	reply := &dbus.Message{Type: dbus.TypeError, Headers: map[dbus.HeaderField]dbus.Variant{
+		dbus.FieldDestination: dbus.MakeVariant(":1.42"),
+		dbus.FieldReplySerial: dbus.MakeVariant(uint32(7)),
+		dbus.FieldErrorName:   dbus.MakeVariant("org.freedesktop.login1.BlockedByInhibitorLock"),
+	}}
+	handleMessage(context.Background(), nil, p, reply, func(a Attempt) { replied = append(replied, a) })
+	if len(replied) != 1 || !replied[0].Refused || len(p.calls) != 1 {
+		t.Fatalf("after reply: replied = %+v, %d pending", replied, len(p.calls))
+	}
+
+	// A call whose reply never comes is dropped and reported once it is old
+	if got := p.expire(start.Add(time.Minute + PendingTimeout)); len(got) != 0 {
+		t.Errorf("expired too early: %+v", got)
+	}
+	got := p.expire(start.Add(2*time.Minute + PendingTimeout))
+	if len(got) != 1 || got[0].Method != "Suspend" || !got[0].Unanswered || len(p.calls) != 0 {
+		t.Errorf("expire = %+v, %d still pending", got, len(p.calls))
+	}
+	if s := got[0].String(); !strings.Contains(s, "no reply seen") {
+		t.Errorf("String() = %q, want no reply seen", s)
+	}
+}
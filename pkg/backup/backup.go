@@ -0,0 +1,102 @@
+// Package backup detects in-progress restic and borg backups.
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/procscan"
+)
+
+// DefaultProcessPattern matches restic and borg backup commands
+const DefaultProcessPattern = `(^|/)(restic|borg)\s.*\b(backup|create)\b`
+
+// ResticStaleLockAge is how old a restic lock can get before it is treated
+// as left over from a crashed run. restic refreshes live locks every 5
+// minutes and considers them stale after 30.
+const ResticStaleLockAge = 30 * time.Minute
+
+// Detector looks for evidence of a running backup
+type Detector struct {
+	// ResticRepos are local restic repository paths whose locks/ directory is checked
+	ResticRepos []string
+	// BorgRepos are local borg repository paths checked for lock.exclusive
+	BorgRepos []string
+	// ProcessPattern matches backup command lines; nil disables process matching
+	ProcessPattern *regexp.Regexp
+	// ProcRoot defaults to /proc
+	ProcRoot string
+}
+
+// Active returns a description of each backup that appears to be running.
+func (d *Detector) Active(now time.Time) ([]string, error) {
+	var active []string
+
+	for _, repo := range d.ResticRepos {
+		locked, err := resticLocked(repo, now)
+		if err != nil {
+			return nil, err
+		}
+		if locked {
+			active = append(active, fmt.Sprintf("restic repository %s locked", repo))
+		}
+	}
+
+	for _, repo := range d.BorgRepos {
+		locked, err := borgLocked(repo)
+		if err != nil {
+			return nil, err
+		}
+		if locked {
+			active = append(active, fmt.Sprintf("borg repository %s locked", repo))
+		}
+	}
+
+	if d.ProcessPattern != nil {
+		procs, err := procscan.Find(d.ProcRoot, d.ProcessPattern)
+		if err != nil {
+			return nil, fmt.Errorf("scan processes: %w", err)
+		}
+		for _, p := range procs {
+			active = append(active, fmt.Sprintf("pid %d: %s", p.PID, p.Cmdline))
+		}
+	}
+
+	return active, nil
+}
+
+// resticLocked reports whether the repository holds a lock refreshed
+// within ResticStaleLockAge.
+func resticLocked(repo string, now time.Time) (bool, error) {
+	entries, err := os.ReadDir(filepath.Join(repo, "locks"))
+	if err != nil {
+		return false, fmt.Errorf("read restic locks: %w", err)
+	}
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			// Lock removed while scanning
+			continue
+		}
+		if now.Sub(info.ModTime()) < ResticStaleLockAge {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// borgLocked reports whether the repository has an exclusive lock.
+func borgLocked(repo string) (bool, error) {
+	_, err := os.Stat(filepath.Join(repo, "lock.exclusive"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("stat borg lock: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,107 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestDetector_Active(t *testing.T) {
+	now := time.Now()
+
+	restic := t.TempDir()
+	os.MkdirAll(filepath.Join(restic, "locks"), 0755)
+	borg := t.TempDir()
+	procRoot := t.TempDir()
+
+	writeLock := func(age time.Duration) {
+		t.Helper()
+		path := filepath.Join(restic, "locks", "abc123")
+		if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, now.Add(-age), now.Add(-age))
+	}
+
+	d := &Detector{
+		ResticRepos:    []string{restic},
+		BorgRepos:      []string{borg},
+		ProcessPattern: regexp.MustCompile(DefaultProcessPattern),
+		ProcRoot:       procRoot,
+	}
+
+	active, err := d.Active(now)
+	if err != nil || len(active) != 0 {
+		t.Fatalf("idle: active = %v, err = %v", active, err)
+	}
+
+	writeLock(time.Minute)
+	if active, _ := d.Active(now); len(active) != 1 {
+		t.Errorf("fresh restic lock: active = %v", active)
+	}
+
+	writeLock(2 * time.Hour)
+	if active, _ := d.Active(now); len(active) != 0 {
+		t.Errorf("stale restic lock counted: active = %v", active)
+	}
+
+	os.Mkdir(filepath.Join(borg, "lock.exclusive"), 0755)
+	if active, _ := d.Active(now); len(active) != 1 {
+		t.Errorf("borg lock: active = %v", active)
+	}
+
+	os.MkdirAll(filepath.Join(procRoot, "4242"), 0755)
+	os.WriteFile(filepath.Join(procRoot, "4242", "cmdline"), []byte("/usr/bin/restic\x00backup\x00/srv\x00"), 0644)
+	if active, _ := d.Active(now); len(active) != 2 {
+		t.Errorf("restic process: active = %v", active)
+	}
+}
+
+func TestDefaultProcessPattern(t *testing.T) {
+	pattern := regexp.MustCompile(DefaultProcessPattern)
+	tests := []struct {
+		cmdline string
+		want    bool
+	}{
+		{"/usr/bin/restic backup /srv", true},
+		{"restic -r /mnt/backup backup --tag nightly /home", true},
+		{"borg create ::{now} /home", true},
+		{"restic snapshots", false},
+		{"borg list ::", false},
+		{"vim restic-backup.sh", false},
+	}
+	for _, tt := range tests {
+		if got := pattern.MatchString(tt.cmdline); got != tt.want {
+			t.Errorf("match(%q) = %v, want %v", tt.cmdline, got, tt.want)
+		}
+	}
+}
+
+func TestDetectorFromEnv(t *testing.T) {
+	t.Setenv("BACKUP_RESTIC_REPOS", "/srv/restic, /mnt/usb/restic")
+	t.Setenv("BACKUP_BORG_REPOS", "")
+	t.Setenv("BACKUP_PROCESS_PATTERN", "none")
+	t.Setenv("PROC_ROOT", "/host/proc")
+
+	d, err := DetectorFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.ResticRepos) != 2 || d.ResticRepos[1] != "/mnt/usb/restic" || d.BorgRepos != nil {
+		t.Errorf("repos = %q, %q", d.ResticRepos, d.BorgRepos)
+	}
+	if d.ProcessPattern != nil || d.ProcRoot != "/host/proc" {
+		t.Errorf("ProcessPattern = %v, ProcRoot = %q; want none and /host/proc", d.ProcessPattern, d.ProcRoot)
+	}
+
+	t.Setenv("BACKUP_PROCESS_PATTERN", "")
+	if d, err := DetectorFromEnv(); err != nil || d.ProcessPattern.String() != DefaultProcessPattern {
+		t.Errorf("default pattern = %v, %v", d, err)
+	}
+	t.Setenv("BACKUP_PROCESS_PATTERN", "(")
+	if _, err := DetectorFromEnv(); err == nil {
+		t.Error("DetectorFromEnv accepted an invalid BACKUP_PROCESS_PATTERN")
+	}
+}
@@ -0,0 +1,42 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Checker implements check.Checker for running backups.
+// Returns an error while a backup is in progress, so reboots wait for it.
+type Checker struct {
+	Detector *Detector
+}
+
+// NewChecker creates a backup checker.
+func NewChecker(d *Detector) *Checker {
+	return &Checker{Detector: d}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "backup"
+}
+
+// Check returns nil if no backup is running, error otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	active, err := c.Detector.Active(time.Now())
+	if err != nil {
+		return fmt.Errorf("backup check failed: %w", err)
+	}
+	if len(active) > 0 {
+		return fmt.Errorf("backup in progress: %s", strings.Join(active, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// DetectorFromEnv returns a Detector configured from the environment
+// variables backup-sidecar reads, so time-to-safe looks for the same
+// backups:
+//
+//	BACKUP_RESTIC_REPOS     restic repositories whose locks show a backup
+//	BACKUP_BORG_REPOS       borg repositories whose locks show a backup
+//	BACKUP_PROCESS_PATTERN  backup command lines (default DefaultProcessPattern); "none" disables
+//	PROC_ROOT               procfs to scan, when /proc isn't the host's
+func DetectorFromEnv() (*Detector, error) {
+	d := &Detector{
+		ResticRepos: splitList(os.Getenv("BACKUP_RESTIC_REPOS")),
+		BorgRepos:   splitList(os.Getenv("BACKUP_BORG_REPOS")),
+		ProcRoot:    os.Getenv("PROC_ROOT"),
+	}
+	pattern := os.Getenv("BACKUP_PROCESS_PATTERN")
+	if pattern == "" {
+		pattern = DefaultProcessPattern
+	}
+	if pattern != "none" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("BACKUP_PROCESS_PATTERN: %w", err)
+		}
+		d.ProcessPattern = re
+	}
+	return d, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
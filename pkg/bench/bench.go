@@ -0,0 +1,215 @@
+// Package bench runs a sidecar's checks repeatedly and reports how long
+// they take and how much they allocate, to find the checker behind a
+// daemon's CPU wakeups on a low-power machine.
+//
+//	raid-sidecar bench -n 200 -cpuprofile raid.pprof
+//
+// The checks run for real, as they would in the poll loop, so any side
+// effects they have (a UPS force-allow file, a notification) happen too.
+// No inhibitor lock is taken.
+package bench
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/pprof"
+	"slices"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+)
+
+// Options configures a benchmark
+type Options struct {
+	N        int           // runs per check, default 20
+	Interval time.Duration // pause between runs
+	Timeout  time.Duration // per run, default 30s
+
+	CPUProfile string // write a CPU profile of all runs here
+	MemProfile string // write a heap profile after the runs here
+}
+
+// Stats summarises one check's runs
+type Stats struct {
+	Name   string
+	Runs   int
+	Busy   int
+	Errors int
+
+	Min, Max, Mean time.Duration
+	P50, P90, P99  time.Duration
+
+	AllocsPerRun uint64
+	BytesPerRun  uint64
+}
+
+// Run checks each checker opts.N times, one checker after another, and
+// returns their stats in the same order.
+func Run(ctx context.Context, checkers []sidecar.Checker, opts Options) ([]Stats, error) {
+	if opts.N <= 0 {
+		opts.N = 20
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+
+	if opts.CPUProfile != "" {
+		f, err := os.Create(opts.CPUProfile)
+		if err != nil {
+			return nil, fmt.Errorf("cpu profile: %w", err)
+		}
+		defer f.Close()
+		if err := pprof.StartCPUProfile(f); err != nil {
+			return nil, fmt.Errorf("cpu profile: %w", err)
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	stats := make([]Stats, 0, len(checkers))
+	for _, c := range checkers {
+		s, err := runOne(ctx, c, opts)
+		if err != nil {
+			return stats, err
+		}
+		stats = append(stats, s)
+	}
+
+	if opts.MemProfile != "" {
+		if err := writeHeapProfile(opts.MemProfile); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+func runOne(ctx context.Context, c sidecar.Checker, opts Options) (Stats, error) {
+	s := Stats{Name: c.Name()}
+	latencies := make([]time.Duration, 0, opts.N)
+	var before, after runtime.MemStats
+
+	for i := 0; i < opts.N; i++ {
+		if i > 0 && opts.Interval > 0 {
+			select {
+			case <-ctx.Done():
+				return s, ctx.Err()
+			case <-time.After(opts.Interval):
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return s, err
+		}
+
+		runCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		busy, _, err := c.Check(runCtx)
+		elapsed := time.Since(start)
+		runtime.ReadMemStats(&after)
+		cancel()
+
+		latencies = append(latencies, elapsed)
+		s.AllocsPerRun += after.Mallocs - before.Mallocs
+		s.BytesPerRun += after.TotalAlloc - before.TotalAlloc
+		if err != nil {
+			s.Errors++
+		} else if busy {
+			s.Busy++
+		}
+	}
+
+	s.Runs = len(latencies)
+	s.AllocsPerRun /= uint64(s.Runs)
+	s.BytesPerRun /= uint64(s.Runs)
+	summarise(&s, latencies)
+	return s, nil
+}
+
+// summarise fills in the latency distribution
+func summarise(s *Stats, latencies []time.Duration) {
+	slices.Sort(latencies)
+	var total time.Duration
+	for _, d := range latencies {
+		total += d
+	}
+	s.Min = latencies[0]
+	s.Max = latencies[len(latencies)-1]
+	s.Mean = total / time.Duration(len(latencies))
+	s.P50 = percentile(latencies, 50)
+	s.P90 = percentile(latencies, 90)
+	s.P99 = percentile(latencies, 99)
+}
+
+// percentile uses the nearest-rank method on sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("memory profile: %w", err)
+	}
+	defer f.Close()
+	// Up-to-date statistics, as go test -memprofile does
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		return fmt.Errorf("memory profile: %w", err)
+	}
+	return nil
+}
+
+// Print writes stats as a table
+func Print(w io.Writer, stats []Stats) {
+	fmt.Fprintf(w, "%-20s %5s %5s %6s %10s %10s %10s %10s %10s %10s %10s %10s\n",
+		"CHECK", "RUNS", "BUSY", "ERRORS", "MIN", "P50", "P90", "P99", "MAX", "MEAN", "ALLOCS/RUN", "BYTES/RUN")
+	for _, s := range stats {
+		fmt.Fprintf(w, "%-20s %5d %5d %6d %10s %10s %10s %10s %10s %10s %10d %10d\n",
+			s.Name, s.Runs, s.Busy, s.Errors,
+			round(s.Min), round(s.P50), round(s.P90), round(s.P99), round(s.Max), round(s.Mean),
+			s.AllocsPerRun, s.BytesPerRun)
+	}
+}
+
+func round(d time.Duration) time.Duration {
+	return d.Round(time.Microsecond)
+}
+
+// Main is the "bench" subcommand: it parses args, benchmarks checkers,
+// prints the table to stdout and returns the process exit code.
+//
+//	if flag.Arg(0) == "bench" {
+//		os.Exit(bench.Main(flag.Args()[1:], checker))
+//	}
+func Main(args []string, checkers ...sidecar.Checker) int {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	var opts Options
+	fs.IntVar(&opts.N, "n", 20, "runs per check")
+	fs.DurationVar(&opts.Interval, "interval", 0, "pause between runs")
+	fs.DurationVar(&opts.Timeout, "timeout", 30*time.Second, "timeout for each run")
+	fs.StringVar(&opts.CPUProfile, "cpuprofile", "", "write a CPU profile to this file")
+	fs.StringVar(&opts.MemProfile, "memprofile", "", "write a heap profile to this file")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s [flags] bench [bench flags]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	stats, err := Run(ctx, checkers, opts)
+	Print(os.Stdout, stats)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,97 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+)
+
+func TestRun(t *testing.T) {
+	runs := 0
+	flaky := sidecar.NewCheckerFunc("flaky", func(context.Context) (bool, string, error) {
+		runs++
+		switch runs % 3 {
+		case 0:
+			return false, "", errors.New("unreachable")
+		case 1:
+			return true, "syncing", nil
+		}
+		return false, "", nil
+	})
+	var sink [][]byte
+	allocating := sidecar.NewCheckerFunc("allocating", func(context.Context) (bool, string, error) {
+		sink = append(sink, make([]byte, 4096))
+		return false, "", nil
+	})
+
+	dir := t.TempDir()
+	opts := Options{
+		N:          6,
+		CPUProfile: filepath.Join(dir, "cpu.pprof"),
+		MemProfile: filepath.Join(dir, "mem.pprof"),
+	}
+	stats, err := Run(context.Background(), []sidecar.Checker{flaky, allocating}, opts)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("got %d stats, want 2", len(stats))
+	}
+
+	s := stats[0]
+	if s.Name != "flaky" || s.Runs != 6 || s.Busy != 2 || s.Errors != 2 {
+		t.Errorf("flaky stats = %+v", s)
+	}
+	if s.Min > s.P50 || s.P50 > s.P90 || s.P90 > s.P99 || s.P99 > s.Max {
+		t.Errorf("latencies out of order: %+v", s)
+	}
+	if stats[1].BytesPerRun < 4096 {
+		t.Errorf("allocating BytesPerRun = %d, want at least 4096", stats[1].BytesPerRun)
+	}
+
+	for _, name := range []string{"cpu.pprof", "mem.pprof"} {
+		if fi, err := os.Stat(filepath.Join(dir, name)); err != nil || fi.Size() == 0 {
+			t.Errorf("%s not written: %v", name, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	Print(&buf, stats)
+	if out := buf.String(); !strings.Contains(out, "flaky") || !strings.Contains(out, "ALLOCS/RUN") {
+		t.Errorf("Print output:\n%s", out)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := sidecar.NewCheckerFunc("slow", func(context.Context) (bool, string, error) {
+		cancel()
+		return false, "", nil
+	})
+	_, err := Run(ctx, []sidecar.Checker{c}, Options{N: 5, Interval: time.Second})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Run = %v, want context.Canceled", err)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 10; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+	for p, want := range map[int]time.Duration{50: 5 * time.Millisecond, 90: 9 * time.Millisecond, 99: 10 * time.Millisecond} {
+		if got := percentile(sorted, p); got != want {
+			t.Errorf("percentile(%d) = %s, want %s", p, got, want)
+		}
+	}
+	if got := percentile(sorted[:1], 50); got != time.Millisecond {
+		t.Errorf("percentile of one = %s", got)
+	}
+}
@@ -0,0 +1,43 @@
+// Package btrbk detects btrfs send/receive transfers in progress, whether
+// run by btrbk or by hand.
+package btrbk
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/procscan"
+)
+
+// DefaultProcessPattern matches btrbk runs that transfer backups (run,
+// resume and archive, not list or stats) and the "btrfs send" and
+// "btrfs receive" processes that btrbk and hand-written pipelines spawn.
+// btrbk is a Perl script, so its command line starts with the interpreter.
+const DefaultProcessPattern = `(^|/)btrbk\s(.*\s)?(run|resume|archive)(\s|$)|(^|/)btrfs\s+(send|receive)\b`
+
+// Detector looks for send/receive jobs in progress
+type Detector struct {
+	// ProcessPattern matches transfer command lines
+	ProcessPattern *regexp.Regexp
+	// ProcRoot defaults to /proc
+	ProcRoot string
+}
+
+// Active returns a description of each transfer process running.
+func (d *Detector) Active() ([]string, error) {
+	pattern := d.ProcessPattern
+	if pattern == nil {
+		pattern = regexp.MustCompile(DefaultProcessPattern)
+	}
+
+	procs, err := procscan.Find(d.ProcRoot, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("scan processes: %w", err)
+	}
+
+	var active []string
+	for _, p := range procs {
+		active = append(active, fmt.Sprintf("pid %d: %s", p.PID, p.Cmdline))
+	}
+	return active, nil
+}
@@ -0,0 +1,69 @@
+package btrbk
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestDefaultProcessPattern(t *testing.T) {
+	pattern := regexp.MustCompile(DefaultProcessPattern)
+
+	tests := []struct {
+		cmdline string
+		want    bool
+	}{
+		{"/usr/bin/perl /usr/bin/btrbk -q run", true},
+		{"/usr/bin/perl /usr/bin/btrbk -c /etc/btrbk/offsite.conf resume", true},
+		{"btrbk archive /mnt/backup /mnt/offsite", true},
+		{"btrbk run --progress home", true},
+		{"btrfs send -p /mnt/snap/home.20240301 /mnt/snap/home.20240302", true},
+		{"/usr/sbin/btrfs receive /mnt/backup/home", true},
+		{"/usr/bin/perl /usr/bin/btrbk list latest", false},
+		{"/usr/bin/perl /usr/bin/btrbk stats", false},
+		{"btrfs subvolume list /", false},
+		{"btrfs scrub start /", false},
+	}
+
+	for _, tt := range tests {
+		if got := pattern.MatchString(tt.cmdline); got != tt.want {
+			t.Errorf("MatchString(%q) = %v, want %v", tt.cmdline, got, tt.want)
+		}
+	}
+}
+
+func TestChecker_Check(t *testing.T) {
+	root := t.TempDir()
+	checker := NewChecker(&Detector{ProcRoot: root})
+
+	os.MkdirAll(filepath.Join(root, "100"), 0755)
+	os.WriteFile(filepath.Join(root, "100", "cmdline"), []byte("/usr/bin/perl\x00/usr/bin/btrbk\x00list\x00"), 0644)
+	if err := checker.Check(context.Background()); err != nil {
+		t.Fatalf("idle: Check = %v, want nil", err)
+	}
+
+	// The receiving end of a btrbk pipeline to a local backup disk
+	os.MkdirAll(filepath.Join(root, "200"), 0755)
+	os.WriteFile(filepath.Join(root, "200", "cmdline"), []byte("btrfs\x00receive\x00/mnt/backup/home\x00"), 0644)
+	err := checker.Check(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "pid 200") {
+		t.Errorf("receive: Check = %v, want pid 200 reported", err)
+	}
+}
+
+func TestDetectorFromEnv(t *testing.T) {
+	t.Setenv("BTRBK_PROCESS_PATTERN", "")
+	t.Setenv("PROC_ROOT", "/host/proc")
+	d, err := DetectorFromEnv()
+	if err != nil || d.ProcessPattern.String() != DefaultProcessPattern || d.ProcRoot != "/host/proc" {
+		t.Errorf("DetectorFromEnv = %+v, %v; want the default pattern and /host/proc", d, err)
+	}
+
+	t.Setenv("BTRBK_PROCESS_PATTERN", "(")
+	if _, err := DetectorFromEnv(); err == nil {
+		t.Error("DetectorFromEnv accepted an invalid BTRBK_PROCESS_PATTERN")
+	}
+}
@@ -0,0 +1,43 @@
+package btrbk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Checker implements check.Checker for btrfs send/receive.
+// Returns an error while a transfer is running, since an interrupted
+// incremental send leaves no parent on the target to resume from and the
+// next run falls back to a full send.
+type Checker struct {
+	Detector *Detector
+}
+
+// NewChecker creates a btrbk checker.
+func NewChecker(d *Detector) *Checker {
+	return &Checker{Detector: d}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "btrbk"
+}
+
+// Check returns nil if no transfer is running, error otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	active, err := c.Detector.Active()
+	if err != nil {
+		return fmt.Errorf("btrbk check failed: %w", err)
+	}
+	if len(active) > 0 {
+		return fmt.Errorf("btrfs send/receive in progress: %s", strings.Join(active, "; "))
+	}
+	return nil
+}
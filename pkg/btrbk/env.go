@@ -0,0 +1,25 @@
+package btrbk
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// DetectorFromEnv returns a Detector configured from the environment
+// variables btrbk-sidecar reads, so time-to-safe looks for the same
+// transfers:
+//
+//	BTRBK_PROCESS_PATTERN  transfer command lines (default DefaultProcessPattern)
+//	PROC_ROOT              procfs to scan, when /proc isn't the host's
+func DetectorFromEnv() (*Detector, error) {
+	pattern := os.Getenv("BTRBK_PROCESS_PATTERN")
+	if pattern == "" {
+		pattern = DefaultProcessPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("BTRBK_PROCESS_PATTERN: %w", err)
+	}
+	return &Detector{ProcessPattern: re, ProcRoot: os.Getenv("PROC_ROOT")}, nil
+}
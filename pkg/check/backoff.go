@@ -0,0 +1,104 @@
+package check
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/filewatch"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/selftest"
+)
+
+// breaker is a circuit breaker around a checker whose service keeps failing
+type breaker struct {
+	sidecar.Checker
+	after    int           // consecutive failures that open the circuit
+	initial  time.Duration // first pause once open
+	maxDelay time.Duration
+
+	now func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	delay    time.Duration // current pause, 0 while closed
+	retryAt  time.Time
+	last     result // returned while open
+}
+
+type result struct {
+	busy   bool
+	reason string
+	err    error
+}
+
+// Backoff wraps checker in a circuit breaker, so a service that is down
+// isn't hammered every poll. After failures consecutive failed checks it
+// stops calling checker for initial, then twice as long after each further
+// failure, up to maxDelay. The first success restores the normal cadence.
+//
+// A check fails when it returns an error, or when it hides one through
+// selftest.Unreachable as most checkers do for an unreachable service.
+// While the circuit is open the last failed result is repeated, so the
+// inhibitor stays as it was, except for checks marked with
+// filewatch.WithChanged, which run as runloop.Run promises. With failures 0 or less checker is returned
+// unchanged.
+func Backoff(checker sidecar.Checker, failures int, initial, maxDelay time.Duration) sidecar.Checker {
+	if failures <= 0 {
+		return checker
+	}
+	return &breaker{
+		Checker:  checker,
+		after:    failures,
+		initial:  initial,
+		maxDelay: max(maxDelay, initial),
+		now:      time.Now,
+	}
+}
+
+// Check runs the wrapped checker unless the circuit is open. A check run
+// because a watched file changed always goes through.
+func (b *breaker) Check(ctx context.Context) (bool, string, error) {
+	b.mu.Lock()
+	if b.delay > 0 && b.now().Before(b.retryAt) && !filewatch.Changed(ctx) {
+		last := b.last
+		b.mu.Unlock()
+		return last.busy, last.reason, last.err
+	}
+	b.mu.Unlock()
+
+	// Atomic, as a check abandoned by Timeout may still report later
+	var hidden atomic.Pointer[error]
+	busy, reason, err := b.Checker.Check(selftest.WithReporter(ctx, func(err error) { hidden.Store(&err) }))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failure := err
+	if p := hidden.Load(); failure == nil && p != nil {
+		failure = *p
+	}
+	if failure == nil {
+		if b.delay > 0 {
+			logging.Infof("%s recovered, polling normally again", b.Name())
+		}
+		b.failures, b.delay = 0, 0
+		return busy, reason, err
+	}
+
+	b.failures++
+	if b.failures < b.after {
+		return busy, reason, err
+	}
+	if b.delay == 0 {
+		b.delay = b.initial
+	} else {
+		b.delay = min(2*b.delay, b.maxDelay)
+	}
+	b.retryAt = b.now().Add(b.delay)
+	b.last = result{busy, reason, err}
+	logging.Warnf("%s failed %d times in a row, next check in %s: %v", b.Name(), b.failures, b.delay, failure)
+	return busy, reason, err
+}
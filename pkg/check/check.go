@@ -0,0 +1,89 @@
+// Package check defines the interface the service checkers in this module
+// implement, and how their results combine when several run together.
+//
+// Most checks can only block: Check returns an error while the service is
+// busy. Some conditions should instead let a shutdown through regardless of
+// what else is busy, e.g. a UPS about to run out or a disk overheating.
+// Such checks implement Evaluator and return ForceAllow, which outranks
+// Block.
+package check
+
+import (
+	"context"
+	"strings"
+)
+
+// Checker is a service check. Check returns nil if it is safe to shut
+// down, or an error describing why not.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+// Verdict is a check's say in whether shutdown may proceed
+type Verdict int
+
+const (
+	// Neutral has no objection
+	Neutral Verdict = iota
+	// Block delays shutdown
+	Block
+	// ForceAllow lets shutdown proceed even if other checks block
+	ForceAllow
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Block:
+		return "block"
+	case ForceAllow:
+		return "force-allow"
+	}
+	return "neutral"
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Check   string
+	Verdict Verdict
+	Reason  string
+}
+
+// Evaluator is implemented by checkers that can return verdicts other than
+// Block, e.g. ForceAllow.
+type Evaluator interface {
+	Checker
+	Evaluate(ctx context.Context) Result
+}
+
+// Evaluate runs a checker. Evaluators decide their own verdict; for plain
+// checkers an error means Block and nil means Neutral.
+func Evaluate(ctx context.Context, c Checker) Result {
+	if e, ok := c.(Evaluator); ok {
+		return e.Evaluate(ctx)
+	}
+	if err := c.Check(ctx); err != nil {
+		return Result{Check: c.Name(), Verdict: Block, Reason: err.Error()}
+	}
+	return Result{Check: c.Name(), Verdict: Neutral}
+}
+
+// Combine reduces results to one verdict: ForceAllow if any check forces
+// it, otherwise Block if any check blocks, otherwise Neutral. reason joins
+// the reasons of the results that decided it.
+func Combine(results []Result) (verdict Verdict, reason string) {
+	for _, r := range results {
+		verdict = max(verdict, r.Verdict)
+	}
+	if verdict == Neutral {
+		return Neutral, ""
+	}
+
+	var reasons []string
+	for _, r := range results {
+		if r.Verdict == verdict {
+			reasons = append(reasons, r.Check+": "+r.Reason)
+		}
+	}
+	return verdict, strings.Join(reasons, "; ")
+}
@@ -0,0 +1,237 @@
+package check
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/filewatch"
+	"github.com/addisonbair/homelab-sidecars/pkg/selftest"
+)
+
+type fakeChecker struct {
+	name string
+	err  error
+}
+
+func (f *fakeChecker) Name() string                    { return f.name }
+func (f *fakeChecker) Check(ctx context.Context) error { return f.err }
+
+type fakeEvaluator struct {
+	fakeChecker
+	result Result
+}
+
+func (f *fakeEvaluator) Evaluate(ctx context.Context) Result { return f.result }
+
+func TestCombine(t *testing.T) {
+	tests := []struct {
+		name        string
+		results     []Result
+		wantVerdict Verdict
+		wantReason  string
+	}{
+		{"empty", nil, Neutral, ""},
+		{
+			name: "all neutral",
+			results: []Result{
+				{Check: "raid", Verdict: Neutral},
+				{Check: "jellyfin", Verdict: Neutral},
+			},
+			wantVerdict: Neutral,
+		},
+		{
+			name: "block",
+			results: []Result{
+				{Check: "raid", Verdict: Block, Reason: "md0 rebuilding: 40%"},
+				{Check: "jellyfin", Verdict: Neutral},
+				{Check: "qbittorrent", Verdict: Block, Reason: "1 torrent finishing"},
+			},
+			wantVerdict: Block,
+			wantReason:  "raid: md0 rebuilding: 40%; qbittorrent: 1 torrent finishing",
+		},
+		{
+			name: "force-allow outranks block",
+			results: []Result{
+				{Check: "raid", Verdict: Block, Reason: "md0 rebuilding: 40%"},
+				{Check: "ups", Verdict: ForceAllow, Reason: "on battery, 12% charge"},
+			},
+			wantVerdict: ForceAllow,
+			wantReason:  "ups: on battery, 12% charge",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict, reason := Combine(tt.results)
+			if verdict != tt.wantVerdict || reason != tt.wantReason {
+				t.Errorf("Combine = (%s, %q), want (%s, %q)", verdict, reason, tt.wantVerdict, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestSet_Check(t *testing.T) {
+	raid := &fakeChecker{name: "raid", err: errors.New("md0 degraded: [U_]")}
+	ups := &fakeEvaluator{fakeChecker: fakeChecker{name: "ups"}, result: Result{Check: "ups", Verdict: Neutral}}
+	set := NewSet("homelab", raid, ups)
+
+	busy, reason, err := set.Check(context.Background())
+	if err != nil || !busy || reason != "raid: md0 degraded: [U_]" {
+		t.Errorf("Check = (%v, %q, %v), want busy with raid reason", busy, reason, err)
+	}
+
+	ups.result = Result{Check: "ups", Verdict: ForceAllow, Reason: "battery critical"}
+	busy, reason, err = set.Check(context.Background())
+	if err != nil || busy {
+		t.Errorf("Check = (%v, %q, %v), want not busy while force-allowed", busy, reason, err)
+	}
+
+	raid.err = nil
+	ups.result = Result{Check: "ups", Verdict: Neutral}
+	if busy, _, _ := set.Check(context.Background()); busy {
+		t.Error("busy with all checks neutral")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	var (
+		calls int
+		down  = true
+	)
+	// Hides its error as the sidecars do for an unreachable service
+	inner := sidecar.NewCheckerFunc("jellyfin", func(ctx context.Context) (bool, string, error) {
+		calls++
+		if down {
+			return false, "", selftest.Unreachable(ctx, errors.New("connection refused"))
+		}
+		return true, "2 streams", nil
+	})
+	now := time.Unix(0, 0)
+	c := Backoff(inner, 2, time.Minute, 3*time.Minute)
+	c.(*breaker).now = func() time.Time { return now }
+
+	poll := func(advance time.Duration) {
+		t.Helper()
+		now = now.Add(advance)
+		if _, _, err := c.Check(context.Background()); err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+	}
+
+	poll(0)
+	poll(30 * time.Second) // second failure opens the circuit for 1m
+	if calls != 2 {
+		t.Fatalf("calls = %d, want 2", calls)
+	}
+	poll(30 * time.Second)
+	if calls != 2 {
+		t.Errorf("checked while open: calls = %d", calls)
+	}
+	poll(30 * time.Second) // retry fails, next in 2m
+	poll(time.Minute)
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+	poll(time.Minute) // retry fails, capped at 3m
+	if calls != 4 {
+		t.Errorf("calls = %d, want 4", calls)
+	}
+	if d := c.(*breaker).delay; d != 3*time.Minute {
+		t.Errorf("delay = %s, want 3m", d)
+	}
+
+	down = false
+	poll(3 * time.Minute)
+	busy, reason, _ := c.Check(context.Background())
+	if calls != 6 || !busy || reason != "2 streams" {
+		t.Errorf("after recovery: calls = %d, Check = (%v, %q)", calls, busy, reason)
+	}
+
+	if got := Backoff(inner, 0, time.Minute, time.Hour); got != inner {
+		t.Error("Backoff with 0 failures should return the checker unchanged")
+	}
+}
+
+func TestBackoffChanged(t *testing.T) {
+	var calls int
+	down := true
+	inner := sidecar.NewCheckerFunc("raid", func(ctx context.Context) (bool, string, error) {
+		calls++
+		if down {
+			return false, "", errors.New("mdstat unreadable")
+		}
+		return true, "md0 rebuilding", nil
+	})
+	c := Backoff(inner, 1, time.Hour, time.Hour)
+	c.Check(context.Background()) // opens the circuit for an hour
+	if c.Check(context.Background()); calls != 1 {
+		t.Fatalf("calls = %d while open, want 1", calls)
+	}
+
+	// A watched file changed, so the check runs even though it is open
+	down = false
+	busy, reason, err := c.Check(filewatch.WithChanged(context.Background()))
+	if calls != 2 || !busy || reason != "md0 rebuilding" || err != nil {
+		t.Errorf("file change while open: calls = %d, Check = (%v, %q, %v)", calls, busy, reason, err)
+	}
+}
+
+// blockingChecker ignores its context, like a read stuck on a failing disk
+type blockingChecker struct {
+	fakeChecker
+	release chan struct{}
+}
+
+func (b *blockingChecker) Check(ctx context.Context) error {
+	<-b.release
+	return nil
+}
+
+func TestTimeout(t *testing.T) {
+	slow := &blockingChecker{fakeChecker: fakeChecker{name: "raid"}, release: make(chan struct{})}
+	defer close(slow.release)
+	fast := &fakeEvaluator{fakeChecker: fakeChecker{name: "ups"}, result: Result{Check: "ups", Verdict: ForceAllow, Reason: "battery low"}}
+
+	s := NewSet("storage", Timeout(slow, 20*time.Millisecond), Timeout(fast, time.Second))
+	start := time.Now()
+	results := s.Results(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Results took %s", elapsed)
+	}
+	if r := results[0]; r.Verdict != Block || r.Reason != "timed out after 20ms" {
+		t.Errorf("slow result = %+v", r)
+	}
+	if r := results[1]; r.Verdict != ForceAllow || r.Reason != "battery low" {
+		t.Errorf("evaluator result = %+v, want its own verdict", r)
+	}
+
+	if c := Timeout(fast, 0); c != Checker(fast) {
+		t.Error("Timeout(c, 0) should return c unchanged")
+	}
+}
+
+func TestSidecarTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	slow := sidecar.NewCheckerFunc("raid", func(context.Context) (bool, string, error) {
+		<-release
+		return true, "md0 rebuilding", nil
+	})
+	_, _, err := SidecarTimeout(slow, 20*time.Millisecond).Check(context.Background())
+	if err == nil || err.Error() != "timed out after 20ms" {
+		t.Errorf("err = %v, want timed out after 20ms", err)
+	}
+
+	fast := sidecar.NewCheckerFunc("jellyfin", func(ctx context.Context) (bool, string, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("no deadline on the check's context")
+		}
+		return true, "1 stream", nil
+	})
+	if busy, reason, err := SidecarTimeout(fast, time.Second).Check(context.Background()); !busy || reason != "1 stream" || err != nil {
+		t.Errorf("Check = (%v, %q, %v)", busy, reason, err)
+	}
+}
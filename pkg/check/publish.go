@@ -0,0 +1,20 @@
+package check
+
+import "context"
+
+// Publisher reports lock states somewhere other than the journal, e.g. an
+// mqtt.Publisher for Home Assistant. Publish is called from the run loop
+// after every check, so it should not block for long.
+type Publisher interface {
+	Publish(ctx context.Context, state State) error
+}
+
+// State is a lock's state after a check
+type State struct {
+	Who     string
+	What    string // e.g. "shutdown:sleep"
+	Set     string // the check, or set of checks, driving the lock
+	Held    bool
+	Reason  string   // why the lock is held
+	Results []Result // each check's result, if the set reports them
+}
@@ -0,0 +1,62 @@
+package check
+
+import (
+	"context"
+	"sync"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+)
+
+// Set runs several checkers as one sidecar checker, honouring ForceAllow.
+type Set struct {
+	name     string
+	checkers []Checker
+
+	mu     sync.Mutex
+	forced bool
+}
+
+// NewSet creates a Set. It satisfies the sidecar's checker interface, so
+// it can be passed to sidecar.Run directly.
+func NewSet(name string, checkers ...Checker) *Set {
+	return &Set{name: name, checkers: checkers}
+}
+
+// Name returns the set's name.
+func (s *Set) Name() string {
+	return s.name
+}
+
+// Results runs every checker in order.
+func (s *Set) Results(ctx context.Context) []Result {
+	results := make([]Result, len(s.checkers))
+	for i, c := range s.checkers {
+		results[i] = Evaluate(ctx, c)
+	}
+	return results
+}
+
+// Check reports busy only if the combined verdict is Block.
+func (s *Set) Check(ctx context.Context) (bool, string, error) {
+	busy, reason, _ := s.evaluate(ctx)
+	return busy, reason, nil
+}
+
+// evaluate is Check, also returning each checker's result.
+func (s *Set) evaluate(ctx context.Context) (bool, string, []Result) {
+	results := s.Results(ctx)
+	verdict, reason := Combine(results)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if forced := verdict == ForceAllow; forced != s.forced {
+		s.forced = forced
+		if forced {
+			logging.Infof("%s: shutdown force-allowed: %s", s.name, reason)
+		} else {
+			logging.Infof("%s: force-allow cleared", s.name)
+		}
+	}
+
+	return verdict == Block, reason, results
+}
@@ -0,0 +1,112 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+)
+
+// timeoutChecker gives a checker its own time budget
+type timeoutChecker struct {
+	Checker
+	timeout time.Duration
+}
+
+// timeoutEvaluator is a timeoutChecker around an Evaluator
+type timeoutEvaluator struct {
+	timeoutChecker
+	evaluator Evaluator
+}
+
+// Timeout limits each run of c to d, so one slow check can't hold up the
+// others in its Set. A run that overruns blocks with the reason "timed
+// out after d". c is returned unchanged if d is 0 or less.
+//
+// The check is given a context with the deadline, but it is also
+// abandoned at the deadline, for checks that don't honour their context,
+// e.g. a read of /proc/mdstat stuck behind a failing disk.
+func Timeout(c Checker, d time.Duration) Checker {
+	if d <= 0 {
+		return c
+	}
+	t := timeoutChecker{Checker: c, timeout: d}
+	if e, ok := c.(Evaluator); ok {
+		return &timeoutEvaluator{timeoutChecker: t, evaluator: e}
+	}
+	return &t
+}
+
+// Check runs the wrapped check within the timeout.
+func (t *timeoutChecker) Check(ctx context.Context) error {
+	err, ok := within(ctx, t.timeout, t.Checker.Check)
+	if !ok {
+		return timedOut(t.timeout)
+	}
+	return err
+}
+
+// Evaluate runs the wrapped evaluator within the timeout.
+func (t *timeoutEvaluator) Evaluate(ctx context.Context) Result {
+	r, ok := within(ctx, t.timeout, t.evaluator.Evaluate)
+	if !ok {
+		return Result{Check: t.Name(), Verdict: Block, Reason: timedOut(t.timeout).Error()}
+	}
+	return r
+}
+
+// sidecarTimeout is SidecarTimeout's wrapper
+type sidecarTimeout struct {
+	sidecar.Checker
+	timeout time.Duration
+}
+
+type sidecarResult struct {
+	busy   bool
+	reason string
+	err    error
+}
+
+// SidecarTimeout is Timeout for a sidecar's checker. An overrun returns
+// the error "timed out after d", so the inhibitor keeps its state as for
+// any other failed check.
+func SidecarTimeout(c sidecar.Checker, d time.Duration) sidecar.Checker {
+	if d <= 0 {
+		return c
+	}
+	return &sidecarTimeout{Checker: c, timeout: d}
+}
+
+// Check runs the wrapped checker within the timeout.
+func (t *sidecarTimeout) Check(ctx context.Context) (bool, string, error) {
+	r, ok := within(ctx, t.timeout, func(ctx context.Context) sidecarResult {
+		busy, reason, err := t.Checker.Check(ctx)
+		return sidecarResult{busy, reason, err}
+	})
+	if !ok {
+		return false, "", timedOut(t.timeout)
+	}
+	return r.busy, r.reason, r.err
+}
+
+// within calls fn with a context that expires after d. It reports false if
+// fn hadn't returned by then, leaving it to finish in the background.
+func within[T any](ctx context.Context, d time.Duration, fn func(context.Context) T) (T, bool) {
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	done := make(chan T, 1)
+	go func() { done <- fn(ctx) }()
+	select {
+	case r := <-done:
+		return r, true
+	case <-ctx.Done():
+		var zero T
+		return zero, false
+	}
+}
+
+func timedOut(d time.Duration) error {
+	return fmt.Errorf("timed out after %s", d)
+}
@@ -0,0 +1,124 @@
+// Package convergence measures how long after boot a sidecar's check first
+// becomes healthy, so slow recoveries can be spotted across updates.
+package convergence
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/metrics"
+	"github.com/addisonbair/homelab-sidecars/pkg/notify"
+)
+
+// DefaultProcStat is read for the kernel boot time
+const DefaultProcStat = "/proc/stat"
+
+// BootTime returns the system boot time from the btime line of /proc/stat.
+func BootTime(procStat string) (time.Time, error) {
+	f, err := os.Open(procStat)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "btime" {
+			secs, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("parse btime: %w", err)
+			}
+			return time.Unix(secs, 0), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return time.Time{}, err
+	}
+	return time.Time{}, fmt.Errorf("btime not found in %s", procStat)
+}
+
+// Tracker wraps a checker and reports, once, how long after boot the check
+// first passed (ran without error and was not blocking).
+type Tracker struct {
+	sidecar.Checker
+	boot     time.Time
+	notifier notify.Notifier
+
+	mu        sync.Mutex
+	converged time.Duration
+	done      bool
+}
+
+// Track wraps checker. Tracking only makes sense right after boot: if the
+// system has been up longer than window, or the boot time can't be read,
+// Track returns nil and the caller should use checker unchanged.
+func Track(checker sidecar.Checker, n notify.Notifier, window time.Duration) *Tracker {
+	if window <= 0 {
+		return nil
+	}
+	boot, err := BootTime(DefaultProcStat)
+	if err != nil {
+		logging.Warnf("boot convergence disabled: %v", err)
+		return nil
+	}
+	if time.Since(boot) > window {
+		return nil
+	}
+	return &Tracker{Checker: checker, boot: boot, notifier: n}
+}
+
+// Check runs the wrapped checker and records the first healthy result.
+func (t *Tracker) Check(ctx context.Context) (bool, string, error) {
+	busy, reason, err := t.Checker.Check(ctx)
+	if err != nil || busy {
+		return busy, reason, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.done {
+		return busy, reason, err
+	}
+	t.done = true
+	t.converged = time.Since(t.boot)
+
+	summary := fmt.Sprintf("%s healthy %s after boot", t.Name(), t.converged.Round(time.Second))
+	logging.Infof("Boot convergence: %s", summary)
+	notify.Send(ctx, t.notifier, notify.Message{
+		Title: fmt.Sprintf("%s: boot convergence", t.Name()),
+		Body:  summary,
+	})
+	return busy, reason, err
+}
+
+// Converged returns the time from boot to the first healthy check, and
+// whether that has happened yet.
+func (t *Tracker) Converged() (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.converged, t.done
+}
+
+// Gauges implements metrics.Source.
+func (t *Tracker) Gauges() []metrics.Gauge {
+	d, ok := t.Converged()
+	if !ok {
+		return nil
+	}
+	return []metrics.Gauge{{
+		Name:   "homelab_check_boot_convergence_seconds",
+		Help:   "Time from boot until the check first passed.",
+		Labels: map[string]string{"check": t.Name()},
+		Value:  d.Seconds(),
+	}}
+}
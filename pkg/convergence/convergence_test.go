@@ -0,0 +1,67 @@
+package convergence
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+)
+
+func TestBootTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stat")
+	content := "cpu  1 2 3 4\nintr 12345\nctxt 999\nbtime 1700000000\nprocesses 42\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	boot, err := BootTime(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !boot.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("boot = %v", boot)
+	}
+
+	os.WriteFile(path, []byte("cpu 1 2 3\n"), 0644)
+	if _, err := BootTime(path); err == nil {
+		t.Error("expected error without btime")
+	}
+}
+
+func TestTracker(t *testing.T) {
+	busy := true
+	checker := sidecar.NewCheckerFunc("raid", func(ctx context.Context) (bool, string, error) {
+		return busy, "md0 rebuilding", nil
+	})
+	tracker := &Tracker{Checker: checker, boot: time.Now().Add(-90 * time.Second)}
+
+	tracker.Check(context.Background())
+	if _, ok := tracker.Converged(); ok {
+		t.Fatal("converged while still busy")
+	}
+	if len(tracker.Gauges()) != 0 {
+		t.Error("gauge reported before convergence")
+	}
+
+	busy = false
+	tracker.Check(context.Background())
+	d, ok := tracker.Converged()
+	if !ok || d < 90*time.Second || d > 100*time.Second {
+		t.Errorf("Converged() = %v, %v", d, ok)
+	}
+
+	// Later failures don't reset the report
+	busy = true
+	tracker.Check(context.Background())
+	if d2, _ := tracker.Converged(); d2 != d {
+		t.Errorf("convergence changed from %v to %v", d, d2)
+	}
+
+	gauges := tracker.Gauges()
+	if len(gauges) != 1 || gauges[0].Labels["check"] != "raid" {
+		t.Errorf("Gauges() = %+v", gauges)
+	}
+}
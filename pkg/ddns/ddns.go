@@ -0,0 +1,185 @@
+// Package ddns checks that a dynamic DNS record still points at the host's
+// public IP address. When the updater stops working the record goes stale
+// and remote access breaks without anything failing locally.
+package ddns
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultIPURL answers with the caller's public IPv4 address as plain text
+const DefaultIPURL = "https://api.ipify.org"
+
+// Checker implements check.Checker for a dynamic DNS record.
+// Returns an error once the record and the public IP have differed for
+// longer than Threshold, since updaters take a few minutes to notice a new
+// address and resolvers cache the old one for the record's TTL.
+type Checker struct {
+	// Hostname is the dynamic DNS name, e.g. home.example.com
+	Hostname string
+	// IPURL answers with the public IP as plain text; an IPv6 address
+	// checks the record's AAAA addresses instead of its A addresses
+	IPURL string
+	// Resolver looks up Hostname; nil uses the system resolver, which
+	// on a LAN with split-horizon DNS may answer with a private address
+	Resolver *net.Resolver
+	// Threshold is how long a difference is tolerated
+	Threshold time.Duration
+
+	httpClient *http.Client
+
+	mu            sync.Mutex
+	divergedSince time.Time
+
+	now func() time.Time // replaced in tests
+}
+
+// NewChecker creates a dynamic DNS checker for hostname.
+func NewChecker(hostname string, threshold, timeout time.Duration) *Checker {
+	return &Checker{
+		Hostname:   hostname,
+		IPURL:      DefaultIPURL,
+		Threshold:  threshold,
+		httpClient: &http.Client{Timeout: timeout},
+		now:        time.Now,
+	}
+}
+
+// NewResolver returns a resolver that sends every query to server, e.g.
+// "1.1.1.1:53" or the domain's authoritative name server, bypassing the
+// local one.
+func NewResolver(server string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// Status is one comparison of the record against the public IP
+type Status struct {
+	PublicIP net.IP
+	Records  []net.IP
+}
+
+// Match reports whether the record includes the public IP.
+func (s *Status) Match() bool {
+	for _, ip := range s.Records {
+		if ip.Equal(s.PublicIP) {
+			return true
+		}
+	}
+	return false
+}
+
+// String describes a mismatch, e.g.
+// "resolves to 203.0.113.1, public IP is 198.51.100.7".
+func (s *Status) String() string {
+	records := make([]string, len(s.Records))
+	for i, ip := range s.Records {
+		records[i] = ip.String()
+	}
+	return fmt.Sprintf("resolves to %s, public IP is %s", strings.Join(records, ", "), s.PublicIP)
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "ddns"
+}
+
+// Compare looks up the public IP and the record.
+func (c *Checker) Compare(ctx context.Context) (*Status, error) {
+	ip, err := c.PublicIP(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("public ip: %w", err)
+	}
+
+	network := "ip4"
+	if ip.To4() == nil {
+		network = "ip6"
+	}
+	resolver := c.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	records, err := resolver.LookupIP(ctx, network, c.Hostname)
+	if err != nil {
+		return nil, fmt.Errorf("lookup %s: %w", c.Hostname, err)
+	}
+	return &Status{PublicIP: ip, Records: records}, nil
+}
+
+// PublicIP asks IPURL for the host's public address.
+func (c *Checker) PublicIP(ctx context.Context) (net.IP, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.IPURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil {
+		return nil, fmt.Errorf("not an IP address: %q", strings.TrimSpace(string(body)))
+	}
+	return ip, nil
+}
+
+// Check returns nil unless the record has differed from the public IP for
+// longer than Threshold. Lookup failures are returned as they are, without
+// starting or resetting the clock.
+func (c *Checker) Check(ctx context.Context) error {
+	status, err := c.Compare(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if status.Match() {
+		c.divergedSince = time.Time{}
+		return nil
+	}
+	now := c.now()
+	if c.divergedSince.IsZero() {
+		c.divergedSince = now
+	}
+	if d := now.Sub(c.divergedSince); d >= c.Threshold {
+		return fmt.Errorf("%s %s (for %s)", c.Hostname, status, d.Truncate(time.Second))
+	}
+	return nil
+}
+
+// Health returns nil if the record matches the public IP right now, e.g.
+// for a health check retried for the length of Threshold.
+func (c *Checker) Health(ctx context.Context) error {
+	status, err := c.Compare(ctx)
+	if err != nil {
+		return err
+	}
+	if !status.Match() {
+		return fmt.Errorf("%s %s", c.Hostname, status)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package ddns
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ipServer answers with *ip, which the test changes between checks
+func ipServer(t *testing.T, ip *string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(*ip + "\n"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// localhost resolves to 127.0.0.1 through /etc/hosts, standing in for the
+// dynamic DNS record
+func newTestChecker(t *testing.T, ip *string, now *time.Time) *Checker {
+	t.Helper()
+	c := NewChecker("localhost", 10*time.Minute, 5*time.Second)
+	c.IPURL = ipServer(t, ip).URL
+	c.now = func() time.Time { return *now }
+	return c
+}
+
+func TestCheck(t *testing.T) {
+	ip := "127.0.0.1"
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := newTestChecker(t, &ip, &now)
+
+	if err := c.Check(context.Background()); err != nil {
+		t.Fatalf("Check() matching = %v", err)
+	}
+
+	// The address changed: tolerated until the threshold
+	ip = "127.0.0.2"
+	if err := c.Check(context.Background()); err != nil {
+		t.Errorf("Check() just diverged = %v, want nil", err)
+	}
+	now = now.Add(9 * time.Minute)
+	if err := c.Check(context.Background()); err != nil {
+		t.Errorf("Check() after 9m = %v, want nil", err)
+	}
+	now = now.Add(time.Minute)
+	err := c.Check(context.Background())
+	if err == nil || err.Error() != "localhost resolves to 127.0.0.1, public IP is 127.0.0.2 (for 10m0s)" {
+		t.Errorf("Check() after 10m = %v", err)
+	}
+
+	// The record caught up
+	ip = "127.0.0.1"
+	if err := c.Check(context.Background()); err != nil {
+		t.Errorf("Check() after update = %v", err)
+	}
+	ip = "127.0.0.2"
+	now = now.Add(time.Hour)
+	if err := c.Check(context.Background()); err != nil {
+		t.Errorf("Check() diverged again = %v, want nil until the threshold", err)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	ip := "127.0.0.2"
+	now := time.Now()
+	c := newTestChecker(t, &ip, &now)
+	if err := c.Health(context.Background()); err == nil || !strings.Contains(err.Error(), "public IP is 127.0.0.2") {
+		t.Errorf("Health() = %v, want mismatch", err)
+	}
+
+	ip = "not an ip"
+	if err := c.Health(context.Background()); err == nil || !strings.Contains(err.Error(), "not an IP address") {
+		t.Errorf("Health() = %v, want parse error", err)
+	}
+}
@@ -0,0 +1,248 @@
+// Package dnsfilter checks that a local DNS filter (Pi-hole or AdGuard
+// Home) is up and answering queries. It is usually the LAN's only
+// resolver, so a box that boots without it takes every client offline.
+package dnsfilter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter is a DNS filter's API
+type Filter interface {
+	Name() string
+	// Status returns nil if the filter reports its DNS server running,
+	// and the address the server listens on
+	Status(ctx context.Context) (string, error)
+}
+
+// PiHole reads the blocking status from the Pi-hole v6 FTL API
+type PiHole struct {
+	baseURL    string
+	password   string
+	httpClient *http.Client
+}
+
+// NewPiHole creates a Pi-hole filter. baseURL is the web interface, e.g.
+// http://localhost; password is the web or an app password, empty if
+// none is set.
+func NewPiHole(baseURL, password string, timeout time.Duration) *PiHole {
+	return &PiHole{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		password:   password,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Name returns the filter name.
+func (p *PiHole) Name() string {
+	return "pihole"
+}
+
+// Status checks that FTL has its blocklists loaded. Blocking may be
+// disabled, which is the user's choice; "failed" means FTL couldn't load
+// them. The DNS server is assumed to be on port 53 of the same host.
+func (p *PiHole) Status(ctx context.Context) (string, error) {
+	sid, err := p.login(ctx)
+	if err != nil {
+		return "", err
+	}
+	if sid != "" {
+		// Sessions are limited, so don't leave one behind for each check
+		defer p.logout(sid)
+	}
+
+	var status struct {
+		Blocking string `json:"blocking"`
+	}
+	if err := p.do(ctx, "GET", "/api/dns/blocking", sid, nil, &status); err != nil {
+		return "", err
+	}
+	if status.Blocking != "enabled" && status.Blocking != "disabled" {
+		return "", fmt.Errorf("blocking %q", status.Blocking)
+	}
+	return dnsAddr(p.baseURL, 53)
+}
+
+// login returns a session ID, or "" if Pi-hole has no password.
+func (p *PiHole) login(ctx context.Context) (string, error) {
+	if p.password == "" {
+		return "", nil
+	}
+	var auth struct {
+		Session struct {
+			Valid   bool   `json:"valid"`
+			SID     string `json:"sid"`
+			Message string `json:"message"`
+		} `json:"session"`
+	}
+	body, err := json.Marshal(map[string]string{"password": p.password})
+	if err != nil {
+		return "", err
+	}
+	if err := p.do(ctx, "POST", "/api/auth", "", body, &auth); err != nil {
+		return "", fmt.Errorf("login: %w", err)
+	}
+	if !auth.Session.Valid {
+		return "", fmt.Errorf("login: %s", auth.Session.Message)
+	}
+	return auth.Session.SID, nil
+}
+
+func (p *PiHole) logout(sid string) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.httpClient.Timeout)
+	defer cancel()
+	p.do(ctx, "DELETE", "/api/auth", sid, nil, nil)
+}
+
+func (p *PiHole) do(ctx context.Context, method, path, sid string, body []byte, v any) error {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, r)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if sid != "" {
+		req.Header.Set("X-FTL-SID", sid)
+	}
+	return doJSON(p.httpClient, req, v)
+}
+
+// AdGuard reads the status from the AdGuard Home control API
+type AdGuard struct {
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+// NewAdGuard creates an AdGuard Home filter. baseURL is the web
+// interface, e.g. http://localhost:3000.
+func NewAdGuard(baseURL, username, password string, timeout time.Duration) *AdGuard {
+	return &AdGuard{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		username:   username,
+		password:   password,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Name returns the filter name.
+func (a *AdGuard) Name() string {
+	return "adguard"
+}
+
+// Status checks that the DNS server is running. Protection may be off,
+// which is the user's choice. The DNS server is on the port AdGuard
+// reports, on the same host.
+func (a *AdGuard) Status(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", a.baseURL+"/control/status", nil)
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if a.username != "" {
+		req.SetBasicAuth(a.username, a.password)
+	}
+
+	var status struct {
+		Running bool `json:"running"`
+		DNSPort int  `json:"dns_port"`
+	}
+	if err := doJSON(a.httpClient, req, &status); err != nil {
+		return "", err
+	}
+	if !status.Running {
+		return "", errors.New("DNS server not running")
+	}
+	port := status.DNSPort
+	if port == 0 {
+		port = 53
+	}
+	return dnsAddr(a.baseURL, port)
+}
+
+func doJSON(client *http.Client, req *http.Request, v any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// dnsAddr returns port on the host of baseURL.
+func dnsAddr(baseURL string, port int) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("no host in %q", baseURL)
+	}
+	return net.JoinHostPort(u.Hostname(), strconv.Itoa(port)), nil
+}
+
+// Query resolves name at the DNS server addr. A name that doesn't exist
+// still counts: the server answered.
+func Query(ctx context.Context, addr, name string) error {
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+	// Fully qualified, so the search domains aren't tried first
+	_, err := resolver.LookupHost(ctx, strings.TrimSuffix(name, ".")+".")
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil
+	}
+	return err
+}
+
+// Health returns nil if every filter reports its DNS server running and
+// the server answers a query for name.
+func Health(ctx context.Context, filters []Filter, name string) error {
+	var problems []string
+	for _, f := range filters {
+		addr, err := f.Status(ctx)
+		if err == nil {
+			err = Query(ctx, addr, name)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", f.Name(), err))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package dnsfilter
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dnsServer answers A queries for found.test with 192.0.2.1 and everything
+// else with NXDOMAIN, returning its address
+func dnsServer(t *testing.T) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			q := buf[:n]
+			end := 12
+			for end < n && q[end] != 0 {
+				end += int(q[end]) + 1
+			}
+			end += 5 // root label, type and class
+			qtype := binary.BigEndian.Uint16(q[end-4:])
+			found := strings.EqualFold(string(q[12:end-4]), "\x05found\x04test\x00")
+
+			resp := append([]byte{q[0], q[1], 0x81, 0x83, 0, 1, 0, 0, 0, 0, 0, 0}, q[12:end]...)
+			if found {
+				resp[3] = 0x80
+				if qtype == 1 {
+					resp[7] = 1
+					resp = append(resp, 0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 192, 0, 2, 1)
+				}
+			}
+			pc.WriteTo(resp, addr)
+		}
+	}()
+	return pc.LocalAddr().String()
+}
+
+func TestQuery(t *testing.T) {
+	addr := dnsServer(t)
+	ctx := context.Background()
+	for _, name := range []string{"found.test", "missing.test."} {
+		if err := Query(ctx, addr, name); err != nil {
+			t.Errorf("Query(%s) = %v", name, err)
+		}
+	}
+
+	// Nothing listening
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := pc.LocalAddr().String()
+	pc.Close()
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if err := Query(ctx, closed, "found.test"); err == nil {
+		t.Error("Query() = nil with no server")
+	}
+}
+
+func TestPiHole(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		blocking string
+		wantErr  string
+	}{
+		{name: "enabled", password: "secret", blocking: "enabled"},
+		{name: "disabled", password: "secret", blocking: "disabled"},
+		{name: "no password", blocking: "enabled"},
+		{name: "failed", password: "secret", blocking: "failed", wantErr: `blocking "failed"`},
+		{name: "wrong password", password: "wrong", blocking: "enabled", wantErr: "login: unexpected status: 401"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == "POST" && r.URL.Path == "/api/auth":
+					var body struct{ Password string }
+					json.NewDecoder(r.Body).Decode(&body)
+					if body.Password != "secret" {
+						w.WriteHeader(http.StatusUnauthorized)
+						w.Write([]byte(`{"session": {"valid": false, "message": "password incorrect"}}`))
+						return
+					}
+					sessions++
+					w.Write([]byte(`{"session": {"valid": true, "sid": "sid1", "message": "password correct"}}`))
+				case r.Method == "DELETE" && r.URL.Path == "/api/auth":
+					sessions--
+					w.WriteHeader(http.StatusNoContent)
+				case r.URL.Path == "/api/dns/blocking":
+					if tt.password != "" && r.Header.Get("X-FTL-SID") != "sid1" {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					w.Write([]byte(`{"blocking": "` + tt.blocking + `", "timer": null}`))
+				default:
+					t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			t.Cleanup(server.Close)
+
+			addr, err := NewPiHole(server.URL+"/", tt.password, 5*time.Second).Status(context.Background())
+			if sessions != 0 {
+				t.Errorf("%d sessions left open", sessions)
+			}
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Status() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Status() error = %v", err)
+			}
+			if addr != "127.0.0.1:53" {
+				t.Errorf("Status() = %s, want 127.0.0.1:53", addr)
+			}
+		})
+	}
+}
+
+func TestAdGuard(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		wantAddr string
+		wantErr  string
+	}{
+		{name: "running", status: `{"running": true, "dns_port": 5353, "protection_enabled": false}`, wantAddr: "127.0.0.1:5353"},
+		{name: "stopped", status: `{"running": false, "dns_port": 53}`, wantErr: "DNS server not running"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.Write([]byte(tt.status))
+			}))
+			t.Cleanup(server.Close)
+
+			addr, err := NewAdGuard(server.URL, "admin", "secret", 5*time.Second).Status(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Status() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || addr != tt.wantAddr {
+				t.Errorf("Status() = %s, %v, want %s", addr, err, tt.wantAddr)
+			}
+		})
+	}
+}
+
+// fakeFilter is a filter whose DNS server is at addr
+type fakeFilter string
+
+func (f fakeFilter) Name() string { return "fake" }
+
+func (f fakeFilter) Status(context.Context) (string, error) { return string(f), nil }
+
+func TestHealth(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := Health(ctx, []Filter{fakeFilter(dnsServer(t))}, "found.test"); err != nil {
+		t.Errorf("Health() = %v", err)
+	}
+
+	down := NewAdGuard("http://127.0.0.1:1", "", "", time.Second)
+	err := Health(ctx, []Filter{fakeFilter(dnsServer(t)), down}, "found.test")
+	if err == nil || !strings.HasPrefix(err.Error(), "adguard: request failed") {
+		t.Errorf("Health() = %v, want adguard down", err)
+	}
+}
@@ -0,0 +1,88 @@
+// Package dryrun is a lock for the run loop that only logs when the
+// inhibitor would be taken or released. It never talks to logind, so a new
+// configuration can be tried on a production machine without holding up
+// its shutdowns.
+package dryrun
+
+import (
+	"context"
+	"sync"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/inhibitor"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+)
+
+// logf reports a would-be transition; replaced in tests.
+var logf = logging.Infof
+
+// StatusPrefix marks the STATUS the run loop sends in a dry run
+const StatusPrefix = "Dry run: "
+
+// Lock tracks the inhibitor lock a sidecar would hold, logging each change.
+// It is safe for concurrent use.
+type Lock struct {
+	What string // e.g. "shutdown:sleep"
+
+	mu   sync.Mutex
+	held bool
+	why  string
+}
+
+// NewLock returns a Lock for what; it has the signature of
+// runloop.Config.NewLock.
+func NewLock(ctx context.Context, what, who, mode string) (inhibitor.Lock, error) {
+	logging.Infof("Dry run: no %s inhibitor will be taken for %s", what, who)
+	return &Lock{What: what}, nil
+}
+
+// Acquire logs that the lock would be taken. It returns
+// inhibitor.ErrAlreadyHeld if it is already held, as the inhibitor would.
+func (l *Lock) Acquire(ctx context.Context, why string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held {
+		return inhibitor.ErrAlreadyHeld
+	}
+	logf("dry run: would acquire %s inhibitor: %s", l.What, why)
+	l.held, l.why = true, why
+	return nil
+}
+
+// Replace logs that the lock would be taken again with a new reason.
+func (l *Lock) Replace(ctx context.Context, why string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.held {
+		return inhibitor.ErrNotHeld
+	}
+	logf("dry run: would replace %s inhibitor: %s", l.What, why)
+	l.why = why
+	return nil
+}
+
+// Release logs that the lock would be released.
+func (l *Lock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.held {
+		return inhibitor.ErrNotHeld
+	}
+	logf("dry run: would release %s inhibitor", l.What)
+	l.held, l.why = false, ""
+	return nil
+}
+
+// Held reports whether the lock would be held, and with what reason.
+func (l *Lock) Held() (bool, string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.held, l.why
+}
+
+// Close forgets the lock; there is nothing to release.
+func (l *Lock) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.held, l.why = false, ""
+	return nil
+}
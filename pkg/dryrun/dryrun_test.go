@@ -0,0 +1,50 @@
+package dryrun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/inhibitor"
+)
+
+func TestLock(t *testing.T) {
+	var logged []string
+	logf = func(format string, args ...any) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}
+
+	ctx := context.Background()
+	l, err := NewLock(ctx, "shutdown:sleep", "raid", "block")
+	if err != nil {
+		t.Fatalf("NewLock = %v", err)
+	}
+	if err := l.Release(ctx); !errors.Is(err, inhibitor.ErrNotHeld) {
+		t.Errorf("Release before Acquire = %v, want ErrNotHeld", err)
+	}
+	if err := l.Acquire(ctx, "md0 rebuilding"); err != nil {
+		t.Fatalf("Acquire = %v", err)
+	}
+	if err := l.Acquire(ctx, "md0 rebuilding"); !errors.Is(err, inhibitor.ErrAlreadyHeld) {
+		t.Errorf("second Acquire = %v, want ErrAlreadyHeld", err)
+	}
+	if err := l.Replace(ctx, "md0 checking"); err != nil {
+		t.Fatalf("Replace = %v", err)
+	}
+	if held, why := l.Held(); !held || why != "md0 checking" {
+		t.Errorf("Held = %v, %q; want true, md0 checking", held, why)
+	}
+	if err := l.Release(ctx); err != nil {
+		t.Fatalf("Release = %v", err)
+	}
+
+	want := []string{
+		"dry run: would acquire shutdown:sleep inhibitor: md0 rebuilding",
+		"dry run: would replace shutdown:sleep inhibitor: md0 checking",
+		"dry run: would release shutdown:sleep inhibitor",
+	}
+	if fmt.Sprint(logged) != fmt.Sprint(want) {
+		t.Errorf("logged %q, want %q", logged, want)
+	}
+}
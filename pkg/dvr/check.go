@@ -0,0 +1,68 @@
+package dvr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/timezone"
+)
+
+// Checker implements check.Checker for DVR recordings.
+// Returns an error while a recording is running or one starts within
+// Lookahead plus BootTime, i.e. before the machine would be back up.
+type Checker struct {
+	Schedulers []Scheduler
+	// Lookahead is how far ahead of the reboot to keep clear
+	Lookahead time.Duration
+	// BootTime is how long a reboot takes until the schedulers are
+	// recording again
+	BootTime time.Duration
+}
+
+// NewChecker creates a DVR checker.
+func NewChecker(schedulers []Scheduler, lookahead, bootTime time.Duration) *Checker {
+	return &Checker{Schedulers: schedulers, Lookahead: lookahead, BootTime: bootTime}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "dvr"
+}
+
+// Check returns nil if no recording is due, error otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	reasons, err := c.Activity(ctx, timezone.Now())
+	if err != nil {
+		return fmt.Errorf("dvr check failed: %w", err)
+	}
+	if len(reasons) > 0 {
+		return errors.New(strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// Activity describes each recording that is running or due. Schedulers
+// that can't be reached are skipped, since they can't record either;
+// rejected credentials are returned as an error.
+func (c *Checker) Activity(ctx context.Context, now time.Time) ([]string, error) {
+	var all []Recording
+	for _, s := range c.Schedulers {
+		recordings, err := s.Recordings(ctx)
+		if errors.Is(err, ErrUnauthorized) {
+			return nil, fmt.Errorf("%s: %w", s.Name(), err)
+		}
+		if err != nil {
+			continue
+		}
+		all = append(all, recordings...)
+	}
+
+	var reasons []string
+	for _, r := range Due(all, now, c.Lookahead+c.BootTime) {
+		reasons = append(reasons, r.Describe(now))
+	}
+	return reasons, nil
+}
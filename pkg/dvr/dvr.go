@@ -0,0 +1,61 @@
+// Package dvr looks ahead at the recordings DVR schedulers (Jellyfin Live
+// TV, Plex DVR and Tvheadend) have planned, so a reboot isn't started just
+// before one begins and the box is still booting when it should be
+// recording.
+package dvr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrUnauthorized is returned when a scheduler rejects the credentials
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Recording is a scheduled or running recording
+type Recording struct {
+	Scheduler string // e.g. "tvheadend"
+	Title     string
+	Channel   string
+	// Start and End include any padding the scheduler adds
+	Start time.Time
+	End   time.Time
+}
+
+// Describe returns a human-readable description of the recording relative
+// to now. Clock times are given in now's location.
+func (r Recording) Describe(now time.Time) string {
+	what := fmt.Sprintf("%q", r.Title)
+	if r.Channel != "" {
+		what += " on " + r.Channel
+	}
+	if !r.Start.After(now) {
+		return fmt.Sprintf("%s: recording %s until %s", r.Scheduler, what, r.End.In(now.Location()).Format("15:04"))
+	}
+	return fmt.Sprintf("%s: %s starts in %s", r.Scheduler, what, r.Start.Sub(now).Round(time.Minute))
+}
+
+// Scheduler is a DVR backend
+type Scheduler interface {
+	Name() string
+	// Recordings returns the recordings that haven't finished yet
+	Recordings(ctx context.Context) ([]Recording, error)
+}
+
+// Due returns the recordings running at now or starting within window,
+// sorted by start time.
+func Due(recordings []Recording, now time.Time, window time.Duration) []Recording {
+	var due []Recording
+	for _, r := range recordings {
+		if r.End.After(now) && !r.Start.After(now.Add(window)) {
+			due = append(due, r)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].Start.Before(due[j].Start)
+	})
+	return due
+}
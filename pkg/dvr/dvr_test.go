@@ -0,0 +1,81 @@
+package dvr
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/jellyfin"
+)
+
+func TestChecker_Activity(t *testing.T) {
+	now := time.Date(2024, 3, 4, 19, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) int64 { return now.Add(d).Unix() }
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/jellyfin/LiveTv/Timers":
+			fmt.Fprintf(w, `{"Items": [
+				{"Name": "News", "ChannelName": "BBC One", "Status": "New",
+				 "StartDate": %q, "EndDate": %q, "PrePaddingSeconds": 120, "PostPaddingSeconds": 300},
+				{"Name": "Film", "ChannelName": "BBC Two", "Status": "Cancelled",
+				 "StartDate": %q, "EndDate": %q}
+			]}`,
+				now.Add(25*time.Minute).Format(time.RFC3339), now.Add(55*time.Minute).Format(time.RFC3339),
+				now.Add(5*time.Minute).Format(time.RFC3339), now.Add(2*time.Hour).Format(time.RFC3339))
+		case "/plex/media/subscriptions/scheduled":
+			if r.Header.Get("X-Plex-Token") != "plex-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(w, `{"MediaContainer": {"MediaGrabOperation": [
+				{"status": "inprogress", "Metadata": {"title": "Pilot", "grandparentTitle": "Drama",
+				 "Media": [{"beginsAt": %d, "endsAt": %d, "channelTitle": "ITV"}]}},
+				{"status": "complete", "Metadata": {"title": "Old", "Media": [{"beginsAt": %d, "endsAt": %d}]}}
+			]}}`, at(-10*time.Minute), at(50*time.Minute), at(-2*time.Hour), at(-time.Hour))
+		case "/tvheadend/api/dvr/entry/grid_upcoming":
+			fmt.Fprintf(w, `{"entries": [
+				{"disp_title": "Late Show", "channelname": "Channel 4", "start_real": %d, "stop_real": %d}
+			], "total": 1}`, at(3*time.Hour), at(4*time.Hour))
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	schedulers := []Scheduler{
+		&Jellyfin{Client: jellyfin.NewClient(server.URL+"/jellyfin", "key", 5*time.Second)},
+		NewPlex(server.URL+"/plex", "plex-token", 5*time.Second),
+		NewTvheadend(server.URL+"/tvheadend", "", "", 5*time.Second),
+		// Down; can't record anyway
+		NewTvheadend("http://127.0.0.1:1", "", "", time.Second),
+	}
+
+	// The news starts at 19:23 with padding, inside 15m + 10m
+	reasons, err := NewChecker(schedulers, 15*time.Minute, 10*time.Minute).Activity(context.Background(), now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		`plex: recording "Drama - Pilot" on ITV until 19:50`,
+		`jellyfin: "News" on BBC One starts in 23m0s`,
+	}
+	if strings.Join(reasons, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Activity =\n%s\nwant\n%s", strings.Join(reasons, "\n"), strings.Join(want, "\n"))
+	}
+
+	// A shorter window only sees the running recording
+	reasons, _ = NewChecker(schedulers, 5*time.Minute, 5*time.Minute).Activity(context.Background(), now)
+	if len(reasons) != 1 {
+		t.Errorf("short window: Activity = %q", reasons)
+	}
+
+	badPlex := NewChecker([]Scheduler{NewPlex(server.URL+"/plex", "wrong", 5*time.Second)}, time.Hour, 0)
+	if err := badPlex.Check(context.Background()); err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Errorf("Check with a rejected token = %v, want an error", err)
+	}
+}
@@ -0,0 +1,45 @@
+package dvr
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/jellyfin"
+)
+
+// Jellyfin reads Live TV timers from Jellyfin
+type Jellyfin struct {
+	Client *jellyfin.Client
+}
+
+// Name returns the scheduler name.
+func (j *Jellyfin) Name() string {
+	return "jellyfin"
+}
+
+// Recordings returns the pending timers, padded as Jellyfin records them.
+func (j *Jellyfin) Recordings(ctx context.Context) ([]Recording, error) {
+	timers, err := j.Client.GetTimers(ctx)
+	if errors.Is(err, jellyfin.ErrUnauthorized) {
+		return nil, errors.Join(err, ErrUnauthorized)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var recordings []Recording
+	for _, t := range timers {
+		if !t.Pending() {
+			continue
+		}
+		recordings = append(recordings, Recording{
+			Scheduler: j.Name(),
+			Title:     t.Name,
+			Channel:   t.ChannelName,
+			Start:     t.StartDate.Add(-time.Duration(t.PrePaddingSeconds) * time.Second),
+			End:       t.EndDate.Add(time.Duration(t.PostPaddingSeconds) * time.Second),
+		})
+	}
+	return recordings, nil
+}
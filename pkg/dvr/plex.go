@@ -0,0 +1,100 @@
+package dvr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Plex reads the DVR's scheduled recordings from Plex Media Server
+type Plex struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewPlex creates a Plex DVR scheduler. token is an X-Plex-Token of the
+// server owner.
+func NewPlex(baseURL, token string, timeout time.Duration) *Plex {
+	return &Plex{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+type plexScheduledResponse struct {
+	MediaContainer struct {
+		MediaGrabOperation []struct {
+			Status   string `json:"status"` // scheduled, inprogress, complete, error
+			Metadata struct {
+				Title            string `json:"title"`
+				GrandparentTitle string `json:"grandparentTitle"` // the show, for episodes
+				Media            []struct {
+					BeginsAt     int64  `json:"beginsAt"`
+					EndsAt       int64  `json:"endsAt"`
+					ChannelTitle string `json:"channelTitle"`
+				} `json:"Media"`
+			} `json:"Metadata"`
+		} `json:"MediaGrabOperation"`
+	} `json:"MediaContainer"`
+}
+
+// Name returns the scheduler name.
+func (p *Plex) Name() string {
+	return "plex"
+}
+
+// Recordings returns the scheduled and running recordings.
+func (p *Plex) Recordings(ctx context.Context) ([]Recording, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/media/subscriptions/scheduled", nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Plex-Token", p.token)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("unexpected status: %d: %w", resp.StatusCode, ErrUnauthorized)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	var scheduled plexScheduledResponse
+	if err := json.NewDecoder(resp.Body).Decode(&scheduled); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	var recordings []Recording
+	for _, op := range scheduled.MediaContainer.MediaGrabOperation {
+		if op.Status != "scheduled" && op.Status != "inprogress" {
+			continue
+		}
+		title := op.Metadata.Title
+		if op.Metadata.GrandparentTitle != "" {
+			title = op.Metadata.GrandparentTitle + " - " + title
+		}
+		for _, m := range op.Metadata.Media {
+			recordings = append(recordings, Recording{
+				Scheduler: p.Name(),
+				Title:     title,
+				Channel:   m.ChannelTitle,
+				Start:     time.Unix(m.BeginsAt, 0),
+				End:       time.Unix(m.EndsAt, 0),
+			})
+		}
+	}
+	return recordings, nil
+}
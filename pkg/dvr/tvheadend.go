@@ -0,0 +1,49 @@
+package dvr
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/tvheadend"
+)
+
+// Tvheadend reads upcoming DVR entries from Tvheadend
+type Tvheadend struct {
+	Client *tvheadend.Client
+}
+
+// NewTvheadend creates a Tvheadend scheduler. The user needs the
+// "Video recorder" permission; Tvheadend's HTTP authentication type must
+// allow plain (basic) authentication if a password is set.
+func NewTvheadend(baseURL, username, password string, timeout time.Duration) *Tvheadend {
+	return &Tvheadend{Client: tvheadend.NewClient(baseURL, username, password, timeout)}
+}
+
+// Name returns the scheduler name.
+func (t *Tvheadend) Name() string {
+	return "tvheadend"
+}
+
+// Recordings returns the upcoming and running DVR entries.
+func (t *Tvheadend) Recordings(ctx context.Context) ([]Recording, error) {
+	entries, err := t.Client.Upcoming(ctx)
+	if errors.Is(err, tvheadend.ErrUnauthorized) {
+		return nil, errors.Join(err, ErrUnauthorized)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	recordings := make([]Recording, 0, len(entries))
+	for _, e := range entries {
+		recordings = append(recordings, Recording{
+			Scheduler: t.Name(),
+			Title:     e.Title,
+			Channel:   e.Channel,
+			Start:     e.Start(),
+			End:       e.Stop(),
+		})
+	}
+	return recordings, nil
+}
@@ -0,0 +1,97 @@
+// Package filewatch calls back when a file changes. On Linux it uses
+// inotify on the file's directory, so atomic replacements (rename over the
+// file, Kubernetes/Podman secret symlink swaps) are seen immediately;
+// elsewhere it falls back to polling the modification time.
+//
+// Kernel files on procfs and sysfs never change on disk, so inotify can't
+// see them. Those that support it, such as /proc/mdstat and md's sysfs
+// attributes, are instead waited on with epoll for the priority event the
+// kernel raises when their contents change. The filesystem is told by its
+// statfs magic, so a host's /proc mounted elsewhere in a container counts.
+package filewatch
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+)
+
+// PollInterval is how often the polling fallback checks the file
+var PollInterval = 5 * time.Second
+
+// Watch calls fn whenever path is written, created or replaced, until ctx
+// is cancelled. If event-based watching is unavailable it polls instead.
+func Watch(ctx context.Context, path string, fn func()) error {
+	if err := watchEvents(ctx, path, fn); err != errUnsupported {
+		return err
+	}
+	return watchPoll(ctx, path, fn)
+}
+
+// watchPoll detects changes by comparing modification time and size.
+func watchPoll(ctx context.Context, path string, fn func()) error {
+	stat := func() (time.Time, int64) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, -1
+		}
+		return info.ModTime(), info.Size()
+	}
+
+	lastMod, lastSize := stat()
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			mod, size := stat()
+			if !mod.Equal(lastMod) || size != lastSize {
+				lastMod, lastSize = mod, size
+				fn()
+			}
+		}
+	}
+}
+
+type changedKey struct{}
+
+// Changes watches every path and returns a channel that receives when any
+// of them changes, until ctx is cancelled. Changes arriving before the
+// last one was received are merged, so a burst of writes is one receive.
+// A path that can't be watched is logged and skipped.
+func Changes(ctx context.Context, paths ...string) <-chan struct{} {
+	ch := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+
+	for _, path := range paths {
+		go func() {
+			if err := Watch(ctx, path, notify); err != nil {
+				logging.Warnf("Not watching %s, changes wait for the next poll: %v", path, err)
+			}
+		}()
+	}
+	return ch
+}
+
+// WithChanged marks ctx as belonging to a check run because a watched
+// file changed, rather than on the poll interval.
+func WithChanged(ctx context.Context) context.Context {
+	return context.WithValue(ctx, changedKey{}, true)
+}
+
+// Changed reports whether ctx was marked by WithChanged, for wrappers that
+// would otherwise skip a check, e.g. lowpower.
+func Changed(ctx context.Context) bool {
+	changed, _ := ctx.Value(changedKey{}).(bool)
+	return changed
+}
@@ -0,0 +1,189 @@
+//go:build linux
+
+package filewatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+var errUnsupported = errors.New("event watching unsupported")
+
+// watchMask sees a file once it has been written and closed, renamed into
+// place or deleted. IN_CREATE is left out: it fires before the new file
+// has any contents, so a check would read it empty, and the write that
+// follows raises IN_CLOSE_WRITE anyway.
+const watchMask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_DELETE
+
+// watchEvents watches the file's directory with inotify and calls fn for
+// events on the file's name. Kernel files are waited on with epoll instead.
+func watchEvents(ctx context.Context, path string, fn func()) error {
+	if isKernelFile(path) {
+		return watchPriority(ctx, path, fn)
+	}
+
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return errUnsupported
+	}
+	// Going through os.File puts reads on the runtime poller, so closing it
+	// when ctx is cancelled unblocks the read loop
+	file := os.NewFile(uintptr(fd), "inotify")
+
+	dir, name := filepath.Split(filepath.Clean(path))
+	if dir == "" {
+		dir = "."
+	}
+	if _, err := syscall.InotifyAddWatch(fd, dir, watchMask); err != nil {
+		file.Close()
+		return fmt.Errorf("watch %s: %w", dir, err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		file.Close()
+	}()
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := file.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("read inotify events: %w", err)
+		}
+
+		changed := false
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+			if cString(nameBytes) == name || isSymlinkSwap(cString(nameBytes)) {
+				changed = true
+			}
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+		}
+		if changed {
+			fn()
+		}
+	}
+}
+
+// Filesystem magic numbers, from statfs(2)
+const (
+	procSuperMagic = 0x9fa0
+	sysfsMagic     = 0x62656572
+)
+
+// isKernelFile reports whether path is on procfs or sysfs, wherever they
+// are mounted, e.g. the host's /proc bind-mounted at /host/proc.
+func isKernelFile(path string) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return false
+	}
+	switch int64(st.Type) {
+	case procSuperMagic, sysfsMagic:
+		return true
+	}
+	return false
+}
+
+// watchPriority waits for the EPOLLPRI event that pollable kernel files,
+// e.g. /proc/mdstat and sysfs attributes updated with sysfs_notify, raise
+// when their contents change. Reading the file from the start acknowledges
+// the event. Files that don't support poll return errUnsupported.
+func watchPriority(ctx context.Context, path string, fn func()) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := reread(f); err != nil {
+		return err
+	}
+
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return errUnsupported
+	}
+	defer syscall.Close(epfd)
+
+	fd := int(f.Fd())
+	event := syscall.EpollEvent{Events: syscall.EPOLLPRI | syscall.EPOLLERR, Fd: int32(fd)}
+	if err := syscall.EpollCtl(epfd, syscall.EPOLL_CTL_ADD, fd, &event); err != nil {
+		return errUnsupported
+	}
+
+	// Closing the pipe's write end wakes EpollWait when ctx is cancelled
+	stop, wake, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("watch %s: %w", path, err)
+	}
+	defer stop.Close()
+	stopFd := int(stop.Fd())
+	stopEvent := syscall.EpollEvent{Events: syscall.EPOLLIN | syscall.EPOLLHUP, Fd: int32(stopFd)}
+	if err := syscall.EpollCtl(epfd, syscall.EPOLL_CTL_ADD, stopFd, &stopEvent); err != nil {
+		wake.Close()
+		return fmt.Errorf("watch %s: %w", path, err)
+	}
+	go func() {
+		<-ctx.Done()
+		wake.Close()
+	}()
+
+	events := make([]syscall.EpollEvent, 2)
+	for {
+		n, err := syscall.EpollWait(epfd, events, -1)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("wait for %s: %w", path, err)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		for _, e := range events[:n] {
+			if int(e.Fd) != fd {
+				continue
+			}
+			if err := reread(f); err != nil {
+				return err
+			}
+			fn()
+		}
+	}
+}
+
+// reread reads f from the start, which acknowledges a priority event
+func reread(f *os.File) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("read %s: %w", f.Name(), err)
+	}
+	if _, err := io.Copy(io.Discard, f); err != nil {
+		return fmt.Errorf("read %s: %w", f.Name(), err)
+	}
+	return nil
+}
+
+// isSymlinkSwap matches the ..data link that Kubernetes and Podman secret
+// mounts atomically replace on update.
+func isSymlinkSwap(name string) bool {
+	return name == "..data"
+}
+
+func cString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
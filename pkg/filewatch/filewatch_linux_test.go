@@ -0,0 +1,27 @@
+package filewatch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsKernelFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flag")
+	os.WriteFile(path, nil, 0600)
+
+	for path, want := range map[string]bool{
+		"/proc/self/stat": true,
+		"/sys/kernel":     true,
+		path:              false,
+		"/nonexistent":    false,
+	} {
+		if _, err := os.Stat(path); err != nil && want {
+			t.Logf("skipping %s: %v", path, err)
+			continue
+		}
+		if got := isKernelFile(path); got != want {
+			t.Errorf("isKernelFile(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
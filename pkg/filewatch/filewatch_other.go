@@ -0,0 +1,14 @@
+//go:build !linux
+
+package filewatch
+
+import (
+	"context"
+	"errors"
+)
+
+var errUnsupported = errors.New("event watching unsupported")
+
+func watchEvents(ctx context.Context, path string, fn func()) error {
+	return errUnsupported
+}
@@ -0,0 +1,88 @@
+package filewatch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	testWatch(t, Watch)
+}
+
+func TestWatchPoll(t *testing.T) {
+	old := PollInterval
+	PollInterval = 10 * time.Millisecond
+	defer func() { PollInterval = old }()
+
+	testWatch(t, watchPoll)
+}
+
+func testWatch(t *testing.T, watch func(context.Context, string, func()) error) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "api-key")
+	if err := os.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changed := make(chan struct{}, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- watch(ctx, path, func() { changed <- struct{}{} })
+	}()
+
+	// Give the watcher time to start
+	time.Sleep(50 * time.Millisecond)
+
+	// Unrelated files in the same directory are ignored
+	os.WriteFile(filepath.Join(dir, "other"), []byte("x"), 0600)
+
+	// Atomic replace, as secret rotation tools do
+	tmp := filepath.Join(dir, ".api-key.tmp")
+	os.WriteFile(tmp, []byte("rotated"), 0600)
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("change not detected")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("watch did not stop after cancel")
+	}
+}
+
+func TestChanges(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "manual"), filepath.Join(dir, "force-allow")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := Changes(ctx, a, b)
+	time.Sleep(50 * time.Millisecond)
+
+	// A burst of writes to both files is merged
+	os.WriteFile(a, []byte("busy"), 0600)
+	os.WriteFile(b, []byte("ups"), 0600)
+	select {
+	case <-changes:
+	case <-time.After(2 * time.Second):
+		t.Fatal("change not detected")
+	}
+
+	if Changed(ctx) || !Changed(WithChanged(ctx)) {
+		t.Error("Changed doesn't follow WithChanged")
+	}
+}
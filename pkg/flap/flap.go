@@ -0,0 +1,157 @@
+// Package flap detects checks that toggle between busy and idle too often
+// and pins them to their last stable state until they settle down, so a
+// flaky check doesn't toggle the inhibitor all night.
+package flap
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/metrics"
+	"github.com/addisonbair/homelab-sidecars/pkg/notify"
+)
+
+// Detector wraps a checker and quarantines it while it flaps
+type Detector struct {
+	sidecar.Checker
+
+	// Threshold is the number of state changes within Window that marks
+	// the check as flapping.
+	Threshold int
+	// Window is the period over which state changes are counted.
+	Window time.Duration
+	// StableAfter is how long a state must hold to become the stable
+	// state the check is pinned to while flapping.
+	StableAfter time.Duration
+
+	notifier notify.Notifier
+	now      func() time.Time
+
+	mu           sync.Mutex
+	started      bool
+	last         bool
+	lastChange   time.Time
+	stable       bool
+	stableReason string
+	changes      []time.Time
+	flapping     bool
+}
+
+// Wrap returns a Detector around checker that flags more than threshold
+// state changes per hour. A threshold of 0 returns nil, meaning disabled.
+func Wrap(checker sidecar.Checker, threshold int, stableAfter time.Duration, n notify.Notifier) *Detector {
+	if threshold <= 0 {
+		return nil
+	}
+	return &Detector{
+		Checker:     checker,
+		Threshold:   threshold,
+		Window:      time.Hour,
+		StableAfter: stableAfter,
+		notifier:    n,
+		now:         time.Now,
+	}
+}
+
+// Check runs the wrapped checker, returning the pinned stable state while
+// the check is flapping.
+func (d *Detector) Check(ctx context.Context) (bool, string, error) {
+	busy, reason, err := d.Checker.Check(ctx)
+	if err != nil {
+		return busy, reason, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	switch {
+	case !d.started:
+		d.started = true
+		d.last, d.stable, d.stableReason = busy, busy, reason
+		d.lastChange = now
+	case busy != d.last:
+		d.last = busy
+		d.lastChange = now
+		d.changes = append(d.changes, now)
+	case now.Sub(d.lastChange) >= d.StableAfter:
+		d.stable, d.stableReason = busy, reason
+	}
+
+	// Drop changes that fell out of the window
+	cutoff := now.Add(-d.Window)
+	for len(d.changes) > 0 && d.changes[0].Before(cutoff) {
+		d.changes = d.changes[1:]
+	}
+
+	flapping := len(d.changes) > d.Threshold
+	if flapping != d.flapping {
+		d.flapping = flapping
+		d.announce(ctx, flapping)
+	}
+
+	if flapping {
+		state := "idle"
+		if d.stable {
+			state = "busy"
+		}
+		// No change count here: a reason that changes every poll would
+		// retake the inhibitor every poll. It is in Gauges instead.
+		pinned := "flapping, pinned " + state
+		if d.stable && d.stableReason != "" {
+			pinned += ": " + d.stableReason
+		}
+		return d.stable, pinned, nil
+	}
+	return busy, reason, nil
+}
+
+func (d *Detector) announce(ctx context.Context, flapping bool) {
+	msg := notify.Message{
+		Title: fmt.Sprintf("%s: check stopped flapping", d.Name()),
+		Body:  "state changes are back under the threshold, following the check again",
+	}
+	if flapping {
+		msg = notify.Message{
+			Title:    fmt.Sprintf("%s: check is flapping", d.Name()),
+			Body:     fmt.Sprintf("more than %d state changes in %s, pinned to last stable state", d.Threshold, d.Window),
+			Priority: notify.PriorityHigh,
+		}
+	}
+	logging.Infof("%s: %s", msg.Title, msg.Body)
+
+	notify.Send(ctx, d.notifier, msg)
+}
+
+// Flapping reports whether the check is currently quarantined.
+func (d *Detector) Flapping() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.flapping
+}
+
+// Gauges implements metrics.Source.
+func (d *Detector) Gauges() []metrics.Gauge {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	v := 0.0
+	if d.flapping {
+		v = 1
+	}
+	labels := map[string]string{"check": d.Name()}
+	return []metrics.Gauge{{
+		Name:   "homelab_check_flapping",
+		Help:   "Whether the check is flapping and pinned to its last stable state.",
+		Labels: labels,
+		Value:  v,
+	}, {
+		Name:   "homelab_check_state_changes",
+		Help:   "State changes of the check within the flap detection window.",
+		Labels: labels,
+		Value:  float64(len(d.changes)),
+	}}
+}
@@ -0,0 +1,73 @@
+package flap
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+)
+
+func TestDetector(t *testing.T) {
+	busy := false
+	checker := sidecar.NewCheckerFunc("jellyfin", func(ctx context.Context) (bool, string, error) {
+		return busy, "bob watching Avatar on TV", nil
+	})
+
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	d := Wrap(checker, 3, 10*time.Minute, nil)
+	d.now = func() time.Time { return now }
+
+	poll := func(b bool) (bool, string) {
+		t.Helper()
+		busy = b
+		now = now.Add(time.Minute)
+		got, reason, err := d.Check(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return got, reason
+	}
+
+	// Idle long enough to become the stable state
+	for i := 0; i < 15; i++ {
+		poll(false)
+	}
+
+	// Three changes is at the threshold, still followed
+	poll(true)
+	poll(false)
+	if got, _ := poll(true); !got || d.Flapping() {
+		t.Fatalf("flagged at threshold: busy = %v, flapping = %v", got, d.Flapping())
+	}
+
+	// The fourth change crosses it; pinned to stable idle
+	poll(false)
+	got, reason := poll(true)
+	if got || !d.Flapping() || !strings.Contains(reason, "flapping") {
+		t.Errorf("busy = %v, flapping = %v, reason = %q", got, d.Flapping(), reason)
+	}
+	// Further changes keep the same reason, so the inhibitor isn't retaken
+	poll(false)
+	if _, again := poll(true); again != reason {
+		t.Errorf("reason changed while pinned: %q, then %q", reason, again)
+	}
+	if g := d.Gauges(); len(g) != 2 || g[1].Value != 7 {
+		t.Errorf("Gauges = %+v, want 7 state changes", g)
+	}
+
+	// Once changes age out of the window the check is followed again
+	for i := 0; i < 61; i++ {
+		poll(true)
+	}
+	if got, _ := poll(true); !got || d.Flapping() {
+		t.Errorf("after settling: busy = %v, flapping = %v", got, d.Flapping())
+	}
+}
+
+func TestWrap_Disabled(t *testing.T) {
+	if Wrap(sidecar.NewCheckerFunc("x", nil), 0, time.Minute, nil) != nil {
+		t.Error("expected nil detector for threshold 0")
+	}
+}
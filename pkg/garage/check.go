@@ -0,0 +1,63 @@
+package garage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Checker implements check.Checker for a Garage node.
+// Returns an error while the cluster is degraded (rebooting this node too
+// could lose quorum) or while blocks are still being resynced after a
+// layout change or repair.
+//
+// The resync check needs the metrics endpoint; if it can't be read only
+// cluster health is checked.
+type Checker struct {
+	Client *Client
+	// ResyncThreshold is the queued block count that blocks, 0 = any
+	ResyncThreshold int
+}
+
+// NewChecker creates a Garage checker.
+func NewChecker(client *Client) *Checker {
+	return &Checker{Client: client}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "garage"
+}
+
+// Check returns nil if the cluster is healthy and settled, error otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	reasons, err := c.Activity(ctx)
+	if err != nil {
+		// Garage being down shouldn't hold up reboots
+		return nil
+	}
+	if len(reasons) > 0 {
+		return errors.New(strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// Activity returns a description of each reason the node shouldn't reboot.
+func (c *Checker) Activity(ctx context.Context) ([]string, error) {
+	health, err := c.Client.Health(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reasons []string
+	if !health.Healthy() {
+		reasons = append(reasons, health.Describe())
+	}
+
+	if queued, err := c.Client.ResyncQueue(ctx); err == nil && queued > c.ResyncThreshold {
+		reasons = append(reasons, fmt.Sprintf("%d block(s) queued for resync", queued))
+	}
+
+	return reasons, nil
+}
@@ -0,0 +1,149 @@
+// Package garage provides a client for the Garage object store's admin API,
+// used to detect nodes being down and data still being moved between them.
+package garage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Health is the /v1/health response
+type Health struct {
+	Status           string `json:"status"` // healthy, degraded or unavailable
+	KnownNodes       int    `json:"knownNodes"`
+	ConnectedNodes   int    `json:"connectedNodes"`
+	StorageNodes     int    `json:"storageNodes"`
+	StorageNodesOK   int    `json:"storageNodesOk"`
+	Partitions       int    `json:"partitions"`
+	PartitionsQuorum int    `json:"partitionsQuorum"`
+	PartitionsAllOK  int    `json:"partitionsAllOk"`
+}
+
+// Healthy reports whether every storage node is up and every partition
+// fully replicated.
+func (h *Health) Healthy() bool {
+	return h.Status == "healthy" && h.PartitionsAllOK == h.Partitions
+}
+
+// Describe summarizes an unhealthy cluster
+func (h *Health) Describe() string {
+	return fmt.Sprintf("cluster %s: %d/%d storage nodes up, %d/%d partitions fully replicated",
+		h.Status, h.StorageNodesOK, h.StorageNodes, h.PartitionsAllOK, h.Partitions)
+}
+
+// Client handles communication with the Garage admin API (port 3903)
+type Client struct {
+	baseURL      string
+	adminToken   string
+	metricsToken string
+	httpClient   *http.Client
+}
+
+// NewClient creates a new Garage admin API client. metricsToken may be
+// empty if the metrics endpoint isn't protected.
+func NewClient(baseURL, adminToken, metricsToken string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		adminToken:   adminToken,
+		metricsToken: metricsToken,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// Health returns the cluster health as seen by this node.
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+	resp, err := c.get(ctx, "/v1/health", c.adminToken)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var h Health
+	if err := json.NewDecoder(resp.Body).Decode(&h); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &h, nil
+}
+
+// ResyncQueue returns the number of blocks waiting to be copied to or
+// removed from this node. It grows after a layout change (rebalancing), a
+// "garage repair" or a node coming back, and drains to 0 once done.
+func (c *Client) ResyncQueue(ctx context.Context) (int, error) {
+	resp, err := c.get(ctx, "/metrics", c.metricsToken)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	v, err := metric(resp.Body, "block_resync_queue_length")
+	if err != nil {
+		return 0, err
+	}
+	return int(v), nil
+}
+
+func (c *Client) get(ctx context.Context, path, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	// /v1/health answers 503 with a body when the cluster is unavailable
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// metric returns the value of an unlabelled gauge, or the sum over its
+// label sets, from Prometheus text exposition.
+func metric(r io.Reader, name string) (float64, error) {
+	var total float64
+	found := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, name) {
+			continue
+		}
+		rest := line[len(name):]
+		if rest == "" || (rest[0] != ' ' && rest[0] != '{') {
+			// A longer metric name sharing the prefix
+			continue
+		}
+		fields := strings.Fields(rest[strings.LastIndex(rest, "}")+1:])
+		if len(fields) == 0 {
+			continue
+		}
+		v, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse %s: %w", name, err)
+		}
+		total += v
+		found = true
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, fmt.Errorf("metric %s not found", name)
+	}
+	return total, nil
+}
@@ -0,0 +1,108 @@
+package garage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const healthyCluster = `{"status": "healthy", "knownNodes": 3, "connectedNodes": 3, "storageNodes": 3, "storageNodesOk": 3,
+	"partitions": 256, "partitionsQuorum": 256, "partitionsAllOk": 256}`
+
+func TestChecker_Activity(t *testing.T) {
+	tests := []struct {
+		name          string
+		health        string
+		healthStatus  int
+		metrics       string
+		metricsStatus int
+		want          string
+	}{
+		{
+			name:    "healthy and settled",
+			health:  healthyCluster,
+			metrics: "# TYPE block_resync_queue_length gauge\nblock_resync_queue_length 0\nblock_resync_errored_blocks 0\n",
+			want:    "",
+		},
+		{
+			name: "node down",
+			health: `{"status": "degraded", "knownNodes": 3, "connectedNodes": 2, "storageNodes": 3, "storageNodesOk": 2,
+				"partitions": 256, "partitionsQuorum": 256, "partitionsAllOk": 0}`,
+			metrics: "block_resync_queue_length 0\n",
+			want:    "cluster degraded: 2/3 storage nodes up, 0/256 partitions fully replicated",
+		},
+		{
+			name:         "cluster unavailable",
+			healthStatus: 503,
+			health: `{"status": "unavailable", "knownNodes": 3, "connectedNodes": 1, "storageNodes": 3, "storageNodesOk": 1,
+				"partitions": 256, "partitionsQuorum": 0, "partitionsAllOk": 0}`,
+			metrics: "block_resync_queue_length 0\n",
+			want:    "cluster unavailable: 1/3 storage nodes up, 0/256 partitions fully replicated",
+		},
+		{
+			name:    "rebalancing after layout change",
+			health:  healthyCluster,
+			metrics: "block_resync_errored_blocks 0\nblock_resync_queue_length 1834\n",
+			want:    "1834 block(s) queued for resync",
+		},
+		{
+			name:          "metrics unavailable",
+			health:        healthyCluster,
+			metricsStatus: 401,
+			want:          "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v1/health":
+					if r.Header.Get("Authorization") != "Bearer admin-token" {
+						t.Errorf("missing admin token")
+					}
+					if tt.healthStatus != 0 {
+						w.WriteHeader(tt.healthStatus)
+					}
+					w.Write([]byte(tt.health))
+				case "/metrics":
+					if tt.metricsStatus != 0 {
+						w.WriteHeader(tt.metricsStatus)
+					}
+					w.Write([]byte(tt.metrics))
+				default:
+					t.Errorf("unexpected path: %s", r.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			checker := NewChecker(NewClient(server.URL, "admin-token", "", 5*time.Second))
+			reasons, err := checker.Activity(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := strings.Join(reasons, "; "); got != tt.want {
+				t.Errorf("activity = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetric(t *testing.T) {
+	exposition := `# HELP block_resync_queue_length Number of block hashes queued for resync
+# TYPE block_resync_queue_length gauge
+block_resync_queue_length_total 99
+block_resync_queue_length{node="a"} 3
+block_resync_queue_length{node="b"} 4
+`
+	got, err := metric(strings.NewReader(exposition), "block_resync_queue_length")
+	if err != nil || got != 7 {
+		t.Errorf("metric = %v, %v, want 7", got, err)
+	}
+	if _, err := metric(strings.NewReader(exposition), "missing"); err == nil {
+		t.Error("expected error for a missing metric")
+	}
+}
@@ -0,0 +1,290 @@
+// Package gate stops systemd units while a check is unhealthy and starts
+// them again once it recovers, e.g. the download stack while a RAID array
+// is degraded, so the sidecars can keep dependent services off a failing
+// resource rather than only holding off reboots.
+//
+// Only units the gate stopped itself are started again: a unit already
+// stopped by hand stays stopped. The units stopped are kept in a state
+// file, so a restarted sidecar still starts them on recovery.
+package gate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/notify"
+)
+
+// Config configures a gate
+type Config struct {
+	// Units are stopped, in reverse order, while the check is unhealthy,
+	// and started in order once it recovers; none disables the gate
+	Units []string
+	// OnBusy and OnError pick which results are unhealthy
+	OnBusy, OnError bool
+	// Match, if set, must match the busy reason or error as well
+	Match *regexp.Regexp
+	// StatePath keeps the units the gate stopped across restarts; empty
+	// keeps them in memory only
+	StatePath string
+	// DryRun only logs what would be started or stopped
+	DryRun bool
+}
+
+// ConfigFromEnv reads the config from GATE_* environment variables:
+//
+//	GATE_UNITS  units to stop while unhealthy, e.g. "qbittorrent.service,sonarr.service"
+//	GATE_WHEN   results that are unhealthy: busy, error or both (default "busy,error")
+//	GATE_MATCH  regexp the reason or error must also match, e.g. "degraded"
+//	GATE_STATE  file keeping the stopped units, default /run/homelab-sidecars/<name>-gate
+func ConfigFromEnv(name string) (Config, error) {
+	cfg := Config{
+		Units:     splitList(os.Getenv("GATE_UNITS")),
+		StatePath: filepath.Join("/run/homelab-sidecars", name+"-gate"),
+	}
+	if v := os.Getenv("GATE_STATE"); v != "" {
+		cfg.StatePath = v
+	}
+	when := os.Getenv("GATE_WHEN")
+	if when == "" {
+		when = "busy,error"
+	}
+	for _, w := range splitList(when) {
+		switch w {
+		case "busy":
+			cfg.OnBusy = true
+		case "error":
+			cfg.OnError = true
+		default:
+			return cfg, fmt.Errorf("GATE_WHEN: unknown result %q (want busy or error)", w)
+		}
+	}
+	if v := os.Getenv("GATE_MATCH"); v != "" {
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return cfg, fmt.Errorf("GATE_MATCH: %w", err)
+		}
+		cfg.Match = re
+	}
+	return cfg, nil
+}
+
+// Units starts and stops systemd units
+type Units interface {
+	// Active reports whether the unit is active or activating
+	Active(ctx context.Context, unit string) (bool, error)
+	Start(ctx context.Context, unit string) error
+	Stop(ctx context.Context, unit string) error
+}
+
+// Gate wraps a checker and stops its dependent units while it is unhealthy
+type Gate struct {
+	sidecar.Checker
+	cfg      Config
+	units    Units
+	notifier notify.Notifier
+
+	mu      sync.Mutex
+	closed  bool     // the check was last unhealthy
+	stopped []string // units to start on recovery
+}
+
+// Wrap returns a Gate around checker that starts and stops cfg.Units with
+// units. With no units configured it returns nil, meaning disabled.
+func Wrap(checker sidecar.Checker, cfg Config, units Units, n notify.Notifier) *Gate {
+	if len(cfg.Units) == 0 {
+		return nil
+	}
+	g := &Gate{Checker: checker, cfg: cfg, units: units, notifier: n}
+	if stopped, err := readState(cfg.StatePath); err != nil {
+		logging.Warnf("gate: %v", err)
+	} else if len(stopped) > 0 {
+		// Recovery is detected afresh, so these are started on the first
+		// healthy check
+		g.closed, g.stopped = true, stopped
+	}
+	return g
+}
+
+// Check runs the wrapped checker and starts or stops the units when it
+// becomes healthy or unhealthy. Its result is returned unchanged.
+func (g *Gate) Check(ctx context.Context) (bool, string, error) {
+	busy, reason, err := g.Checker.Check(ctx)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	switch unhealthy := g.unhealthy(busy, reason, err); {
+	case unhealthy && !g.closed:
+		why := reason
+		if err != nil {
+			why = err.Error()
+		}
+		g.closed = true
+		g.stop(ctx, why)
+	case !unhealthy && len(g.stopped) > 0:
+		// Retried each poll until every unit has started
+		g.start(ctx)
+	case !unhealthy:
+		if g.closed && g.cfg.DryRun {
+			logging.Infof("dry run: would start the units stopped")
+		}
+		g.closed = false
+	}
+	return busy, reason, err
+}
+
+func (g *Gate) unhealthy(busy bool, reason string, err error) bool {
+	switch {
+	case err != nil && g.cfg.OnError:
+		reason = err.Error()
+	case err == nil && busy && g.cfg.OnBusy:
+	default:
+		return false
+	}
+	return g.cfg.Match == nil || g.cfg.Match.MatchString(reason)
+}
+
+// stop stops the active units, last first, and records them
+func (g *Gate) stop(ctx context.Context, why string) {
+	var stopped, failed []string
+	for _, unit := range slices.Backward(g.cfg.Units) {
+		if slices.Contains(g.stopped, unit) {
+			continue
+		}
+		active, err := g.units.Active(ctx, unit)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", unit, err))
+			continue
+		}
+		if !active {
+			continue
+		}
+		if g.cfg.DryRun {
+			logging.Infof("dry run: would stop %s", unit)
+			continue
+		}
+		if err := g.units.Stop(ctx, unit); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", unit, err))
+			continue
+		}
+		stopped = append(stopped, unit)
+	}
+	// Started again in the configured order
+	slices.Reverse(stopped)
+	g.stopped = append(g.stopped, stopped...)
+	g.save()
+
+	if len(stopped) > 0 {
+		g.announce(ctx, notify.Message{
+			Title:    fmt.Sprintf("%s: stopped %s", g.Name(), strings.Join(stopped, ", ")),
+			Body:     why,
+			Priority: notify.PriorityHigh,
+		})
+	}
+	if len(failed) > 0 {
+		logging.Warnf("gate: stopping units: %s", strings.Join(failed, "; "))
+	}
+}
+
+// start starts the units the gate stopped, in order, keeping any that
+// fail to be retried
+func (g *Gate) start(ctx context.Context) {
+	var started, remaining, failed []string
+	for _, unit := range g.stopped {
+		if err := g.units.Start(ctx, unit); err != nil {
+			remaining = append(remaining, unit)
+			failed = append(failed, fmt.Sprintf("%s: %v", unit, err))
+			continue
+		}
+		started = append(started, unit)
+	}
+	g.stopped = remaining
+	g.closed = len(remaining) > 0
+	g.save()
+
+	if len(started) > 0 {
+		g.announce(ctx, notify.Message{
+			Title: fmt.Sprintf("%s: started %s", g.Name(), strings.Join(started, ", ")),
+			Body:  "check healthy again",
+		})
+	}
+	if len(failed) > 0 {
+		logging.Warnf("gate: starting units: %s", strings.Join(failed, "; "))
+	}
+}
+
+// Stopped returns the units the gate has stopped and will start on
+// recovery.
+func (g *Gate) Stopped() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return slices.Clone(g.stopped)
+}
+
+func (g *Gate) save() {
+	if g.cfg.StatePath == "" {
+		return
+	}
+	if err := writeState(g.cfg.StatePath, g.stopped); err != nil {
+		logging.Warnf("gate: %v", err)
+	}
+}
+
+func (g *Gate) announce(ctx context.Context, msg notify.Message) {
+	logging.Infof("%s: %s", msg.Title, msg.Body)
+	notify.Send(ctx, g.notifier, msg)
+}
+
+// readState returns the units listed one per line in path, or none if it
+// doesn't exist
+func readState(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(data)), nil
+}
+
+// writeState replaces path with units, or removes it if there are none
+func writeState(path string, units []string) error {
+	if len(units) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(units, "\n")+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
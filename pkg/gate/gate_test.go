@@ -0,0 +1,141 @@
+package gate
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+)
+
+// fakeUnits records start and stop calls
+type fakeUnits struct {
+	active map[string]bool
+	fail   map[string]bool
+	calls  []string
+}
+
+func (f *fakeUnits) Active(_ context.Context, unit string) (bool, error) {
+	return f.active[unit], nil
+}
+
+func (f *fakeUnits) Start(_ context.Context, unit string) error {
+	f.calls = append(f.calls, "start "+unit)
+	if f.fail[unit] {
+		return errors.New("job failed")
+	}
+	f.active[unit] = true
+	return nil
+}
+
+func (f *fakeUnits) Stop(_ context.Context, unit string) error {
+	f.calls = append(f.calls, "stop "+unit)
+	f.active[unit] = false
+	return nil
+}
+
+func TestGate(t *testing.T) {
+	var (
+		busy   bool
+		reason string
+		err    error
+	)
+	checker := sidecar.NewCheckerFunc("raid", func(ctx context.Context) (bool, string, error) {
+		return busy, reason, err
+	})
+	units := &fakeUnits{
+		// sonarr was stopped by hand, so it is left alone
+		active: map[string]bool{"qbittorrent.service": true, "sabnzbd.service": true},
+		fail:   map[string]bool{},
+	}
+	state := filepath.Join(t.TempDir(), "raid-gate")
+	cfg := Config{
+		Units:     []string{"qbittorrent.service", "sabnzbd.service", "sonarr.service"},
+		OnBusy:    true,
+		Match:     regexp.MustCompile("degraded"),
+		StatePath: state,
+	}
+	g := Wrap(checker, cfg, units, nil)
+
+	poll := func(b bool, r string) {
+		t.Helper()
+		busy, reason = b, r
+		if gotBusy, gotReason, _ := g.Check(context.Background()); gotBusy != b || gotReason != r {
+			t.Fatalf("Check() = %v, %q, want the result unchanged", gotBusy, gotReason)
+		}
+	}
+
+	// A rebuild doesn't match, so nothing is stopped
+	poll(true, "md0 rebuilding")
+	if len(units.calls) != 0 {
+		t.Fatalf("calls = %v, want none", units.calls)
+	}
+
+	poll(true, "md0 degraded: sdb missing")
+	poll(true, "md0 degraded: sdb missing")
+	want := []string{"stop sabnzbd.service", "stop qbittorrent.service"}
+	if !reflect.DeepEqual(units.calls, want) {
+		t.Fatalf("calls = %v, want %v", units.calls, want)
+	}
+
+	// A restarted sidecar picks up what was stopped
+	units.calls = nil
+	units.fail["sabnzbd.service"] = true
+	g = Wrap(checker, cfg, units, nil)
+	poll(false, "")
+	if got := g.Stopped(); !reflect.DeepEqual(got, []string{"sabnzbd.service"}) {
+		t.Errorf("Stopped() = %v, want the failed start kept", got)
+	}
+
+	// The failed start is retried next poll
+	units.fail["sabnzbd.service"] = false
+	poll(false, "")
+	want = []string{"start qbittorrent.service", "start sabnzbd.service", "start sabnzbd.service"}
+	if !reflect.DeepEqual(units.calls, want) {
+		t.Errorf("calls = %v, want %v", units.calls, want)
+	}
+	if stopped, _ := readState(state); len(stopped) != 0 {
+		t.Errorf("state = %v, want it removed", stopped)
+	}
+	if units.active["sonarr.service"] {
+		t.Error("started a unit the gate didn't stop")
+	}
+}
+
+func TestGateOnError(t *testing.T) {
+	checker := sidecar.NewCheckerFunc("jellyfin", func(ctx context.Context) (bool, string, error) {
+		return false, "", errors.New("connection refused")
+	})
+	units := &fakeUnits{active: map[string]bool{"jellyseerr.service": true}}
+	g := Wrap(checker, Config{Units: []string{"jellyseerr.service"}, OnBusy: true}, units, nil)
+	g.Check(context.Background())
+	if len(units.calls) != 0 {
+		t.Errorf("calls = %v, want errors ignored without OnError", units.calls)
+	}
+
+	g = Wrap(checker, Config{Units: []string{"jellyseerr.service"}, OnError: true}, units, nil)
+	g.Check(context.Background())
+	if want := []string{"stop jellyseerr.service"}; !reflect.DeepEqual(units.calls, want) {
+		t.Errorf("calls = %v, want %v", units.calls, want)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("GATE_UNITS", "a.service, b.service")
+	t.Setenv("GATE_WHEN", "")
+	cfg, err := ConfigFromEnv("raid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.OnBusy || !cfg.OnError || len(cfg.Units) != 2 || cfg.StatePath != "/run/homelab-sidecars/raid-gate" {
+		t.Errorf("ConfigFromEnv() = %+v", cfg)
+	}
+
+	t.Setenv("GATE_WHEN", "degraded")
+	if _, err := ConfigFromEnv("raid"); err == nil {
+		t.Error("ConfigFromEnv() accepted an unknown GATE_WHEN")
+	}
+}
@@ -0,0 +1,179 @@
+package gate
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/coreos/go-systemd/v22/dbus"
+)
+
+// Systemd starts and stops units through the system manager over D-Bus.
+// It connects on first use, so a sidecar without a gate never needs the
+// bus, and reconnects after the connection drops.
+//
+// The state of a unit Active has been asked about is then kept from the
+// manager's PropertiesChanged signals rather than asked for again. If the
+// manager won't send them, Active asks every time.
+type Systemd struct {
+	mu     sync.Mutex
+	conn   *dbus.Conn
+	states *unitStates // nil while not subscribed
+	done   chan struct{}
+}
+
+func (s *Systemd) connect(ctx context.Context) (*dbus.Conn, *unitStates, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil && s.conn.Connected() {
+		return s.conn, s.states, nil
+	}
+	conn, err := dbus.NewSystemConnectionContext(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connecting to systemd: %w", err)
+	}
+	if s.conn != nil {
+		// Changes were missed while disconnected, so the old states are
+		// dropped along with the connection
+		close(s.done)
+		s.conn.Close()
+	}
+	s.conn, s.states, s.done = conn, nil, make(chan struct{})
+	if err := conn.Subscribe(); err != nil {
+		logging.Warnf("gate: following unit changes: %v; asking for each check instead", err)
+		return conn, nil, nil
+	}
+	s.states = &unitStates{m: make(map[string]string)}
+	updates := make(chan *dbus.PropertiesUpdate, 64)
+	errs := make(chan error, 1)
+	conn.SetPropertiesSubscriber(updates, errs)
+	go s.states.follow(updates, errs, s.done)
+	return conn, s.states, nil
+}
+
+// Active reports whether unit is active, activating or reloading.
+func (s *Systemd) Active(ctx context.Context, unit string) (bool, error) {
+	conn, states, err := s.connect(ctx)
+	if err != nil {
+		return false, err
+	}
+	state, ok := states.get(unit)
+	if !ok {
+		if state, err = activeState(ctx, conn, unit); err != nil {
+			return false, err
+		}
+		states.set(unit, state)
+	}
+	switch state {
+	case "active", "activating", "reloading":
+		return true, nil
+	}
+	return false, nil
+}
+
+func activeState(ctx context.Context, conn *dbus.Conn, unit string) (string, error) {
+	statuses, err := conn.ListUnitsByNamesContext(ctx, []string{unit})
+	if err != nil {
+		return "", err
+	}
+	if len(statuses) == 0 {
+		return "inactive", nil
+	}
+	return statuses[0].ActiveState, nil
+}
+
+// Start queues a start job for unit. It doesn't wait for the job: a
+// slow unit mustn't hold up the check loop.
+func (s *Systemd) Start(ctx context.Context, unit string) error {
+	conn, _, err := s.connect(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = conn.StartUnitContext(ctx, unit, "replace", nil)
+	return err
+}
+
+// Stop queues a stop job for unit, without waiting for it.
+func (s *Systemd) Stop(ctx context.Context, unit string) error {
+	conn, _, err := s.connect(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = conn.StopUnitContext(ctx, unit, "replace", nil)
+	return err
+}
+
+// unitStates keeps the ActiveState of the units being followed. Its
+// methods are no-ops on a nil unitStates, so Active always asks.
+type unitStates struct {
+	mu sync.Mutex
+	m  map[string]string // "" while the first answer is awaited
+}
+
+// get returns unit's state, or false if it isn't known yet. The unit is
+// followed from then on, so a change signalled while it is being asked for
+// isn't lost.
+func (u *unitStates) get(unit string) (string, bool) {
+	if u == nil {
+		return "", false
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	state, ok := u.m[unit]
+	if !ok {
+		u.m[unit] = ""
+	}
+	return state, state != ""
+}
+
+func (u *unitStates) set(unit, state string) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.m[unit] = state
+}
+
+// update applies a PropertiesChanged signal to the unit it is for, if it
+// is being followed
+func (u *unitStates) update(p *dbus.PropertiesUpdate) {
+	v, ok := p.Changed["ActiveState"]
+	if !ok {
+		return
+	}
+	state, ok := v.Value().(string)
+	if !ok {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if _, followed := u.m[p.UnitName]; followed {
+		u.m[p.UnitName] = state
+	}
+}
+
+// forget drops every state, so each is asked for again
+func (u *unitStates) forget() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	clear(u.m)
+}
+
+// follow applies updates until done is closed. An error means an update
+// was dropped because the channel was full, so nothing kept can be
+// trusted any more.
+func (u *unitStates) follow(updates <-chan *dbus.PropertiesUpdate, errs <-chan error, done <-chan struct{}) {
+	for {
+		select {
+		case p := <-updates:
+			u.update(p)
+		case err := <-errs:
+			logging.Debugf("gate: %v; asking for unit states again", err)
+			u.forget()
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package gate
+
+import (
+	"testing"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	godbus "github.com/godbus/dbus/v5"
+)
+
+func activeStateChanged(unit, state string) *dbus.PropertiesUpdate {
+	return &dbus.PropertiesUpdate{
+		UnitName: unit,
+		Changed:  map[string]godbus.Variant{"ActiveState": godbus.MakeVariant(state)},
+	}
+}
+
+func TestUnitStates(t *testing.T) {
+	u := &unitStates{m: make(map[string]string)}
+
+	// Units nobody asked about aren't kept
+	u.update(activeStateChanged("sshd.service", "active"))
+	if _, ok := u.m["sshd.service"]; ok {
+		t.Error("kept the state of a unit not asked about")
+	}
+
+	// A change signalled while the first answer is awaited wins over an
+	// answer that was never stored
+	if _, ok := u.get("qbittorrent.service"); ok {
+		t.Fatal("state known before it was asked for")
+	}
+	u.update(activeStateChanged("qbittorrent.service", "deactivating"))
+	if state, ok := u.get("qbittorrent.service"); !ok || state != "deactivating" {
+		t.Errorf("get = %q, %v, want the signalled deactivating", state, ok)
+	}
+
+	u.set("qbittorrent.service", "inactive")
+	u.update(&dbus.PropertiesUpdate{
+		UnitName: "qbittorrent.service",
+		Changed:  map[string]godbus.Variant{"SubState": godbus.MakeVariant("dead")},
+	})
+	if state, _ := u.get("qbittorrent.service"); state != "inactive" {
+		t.Errorf("a change to another property gave %q", state)
+	}
+	u.update(activeStateChanged("qbittorrent.service", "activating"))
+	if state, _ := u.get("qbittorrent.service"); state != "activating" {
+		t.Errorf("get = %q, want activating", state)
+	}
+
+	// After a dropped update everything is asked for again
+	u.forget()
+	if _, ok := u.get("qbittorrent.service"); ok {
+		t.Error("state still known after forget")
+	}
+
+	// Without a subscription nothing is known
+	var none *unitStates
+	none.set("qbittorrent.service", "active")
+	if _, ok := none.get("qbittorrent.service"); ok {
+		t.Error("nil unitStates knew a state")
+	}
+}
@@ -0,0 +1,44 @@
+package grafana
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_Health(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{name: "healthy", status: 200, body: `{"commit": "abc", "database": "ok", "version": "11.2.0"}`},
+		{name: "database down", status: 503, body: `{"database": "failing", "version": "11.2.0"}`, wantErr: "grafana database: failing"},
+		{name: "proxy error", status: 502, body: `Bad Gateway`, wantErr: "unexpected status: 502"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/health" {
+					t.Errorf("unexpected path: %s", r.URL.Path)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			err := NewClient(server.URL+"/", 5*time.Second).Health(context.Background())
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Health() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Health() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
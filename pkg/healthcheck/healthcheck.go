@@ -0,0 +1,312 @@
+// Package healthcheck runs a one-shot health check with retries, for
+// greenboot. Services are often still starting when greenboot runs, so a
+// single failed probe would roll back a good update; instead the check is
+// retried with backoff up to a total budget, and the output says whether it
+// never became healthy or merely took a while.
+//
+// Greenboot rolls back an update when a required.d script fails. Not every
+// problem is worth a rollback, e.g. a RAID rebuild that happens to be
+// running, so a check can be configured with SeverityWarning, or its check
+// function can return a Warning, to report the problem and still exit 0.
+package healthcheck
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// now and sleep are replaced in tests
+var (
+	now   = time.Now
+	sleep = func(ctx context.Context, d time.Duration) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d):
+			return nil
+		}
+	}
+)
+
+// Policy controls retries.
+type Policy struct {
+	// Budget is the total time to keep retrying (0 tries once)
+	Budget time.Duration
+	// Initial is the delay after the first failure, doubled after each
+	// further failure up to Max
+	Initial time.Duration
+	Max     time.Duration
+	// AttemptTimeout bounds each individual check
+	AttemptTimeout time.Duration
+	// MaxAttempts stops retrying after that many attempts even with budget
+	// left (0 = no limit)
+	MaxAttempts int
+}
+
+// DefaultPolicy suits services that take a minute or two to come up.
+var DefaultPolicy = Policy{
+	Budget:         5 * time.Minute,
+	Initial:        5 * time.Second,
+	Max:            30 * time.Second,
+	AttemptTimeout: 10 * time.Second,
+}
+
+// ParseFlags parses the healthcheck subcommand's flags from args, e.g.
+// flag.Args()[1:], on top of policy:
+//
+//	-retries N          retry a failing check at most N times (default
+//	                    HEALTH_RETRIES, or until the budget runs out)
+//	-retry-interval D   wait D before the first retry, doubling up to
+//	                    policy.Max (default policy.Initial)
+//
+// Either way the retries stop when the budget runs out.
+func ParseFlags(args []string, policy Policy) (Policy, error) {
+	retries := -1
+	if v := os.Getenv("HEALTH_RETRIES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return policy, fmt.Errorf("HEALTH_RETRIES: %w", err)
+		}
+		retries = n
+	}
+
+	fs := flag.NewFlagSet("healthcheck", flag.ContinueOnError)
+	fs.IntVar(&retries, "retries", retries, "retry a failing check at most this many times (default until the timeout)")
+	fs.DurationVar(&policy.Initial, "retry-interval", policy.Initial, "wait before the first retry, doubled after each further failure")
+	if err := fs.Parse(args); err != nil {
+		return policy, err
+	}
+	if fs.NArg() > 0 {
+		return policy, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	if retries >= 0 {
+		policy.MaxAttempts = retries + 1
+	}
+	if policy.Max > 0 && policy.Initial > policy.Max {
+		policy.Max = policy.Initial
+	}
+	return policy, nil
+}
+
+// Severity says whether an unhealthy check should fail the boot
+type Severity string
+
+const (
+	// SeverityRequired fails the boot, so greenboot rolls back
+	SeverityRequired Severity = "required"
+	// SeverityWarning reports the problem but passes
+	SeverityWarning Severity = "warning"
+)
+
+// ParseSeverity parses "required" or "warning"; empty is required.
+func ParseSeverity(s string) (Severity, error) {
+	switch sev := Severity(s); sev {
+	case "":
+		return SeverityRequired, nil
+	case SeverityRequired, SeverityWarning:
+		return sev, nil
+	}
+	return "", fmt.Errorf("invalid severity %q (want required or warning)", s)
+}
+
+// SeverityFromEnv returns the severity for the named check, read from
+// <CHECK>_HEALTH_SEVERITY (e.g. RAID_HEALTH_SEVERITY) and falling back to
+// HEALTH_SEVERITY.
+func SeverityFromEnv(check string) (Severity, error) {
+	key := strings.ToUpper(strings.ReplaceAll(check, "-", "_")) + "_HEALTH_SEVERITY"
+	v := os.Getenv(key)
+	if v == "" {
+		key, v = "HEALTH_SEVERITY", os.Getenv("HEALTH_SEVERITY")
+	}
+	sev, err := ParseSeverity(v)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", key, err)
+	}
+	return sev, nil
+}
+
+// warningError marks a problem that should only warn
+type warningError struct {
+	err error
+}
+
+func (w *warningError) Error() string { return w.err.Error() }
+func (w *warningError) Unwrap() error { return w.err }
+
+// Warning marks err as a problem that passes with a warning whatever the
+// check's severity, e.g. one that retrying won't fix in time but isn't a
+// reason to roll back. Run stops retrying when a check returns one.
+// Warning(nil) is nil.
+func Warning(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &warningError{err: err}
+}
+
+// IsWarning reports whether err was marked with Warning.
+func IsWarning(err error) bool {
+	var w *warningError
+	return errors.As(err, &w)
+}
+
+// Attempt is one run of the check.
+type Attempt struct {
+	At  time.Duration // since the first attempt
+	Err error
+}
+
+// Result is the outcome of Run.
+type Result struct {
+	Name     string
+	Healthy  bool
+	Elapsed  time.Duration // until the first healthy attempt, or giving up
+	Attempts []Attempt
+	// Severity is set by the caller; empty is SeverityRequired
+	Severity Severity
+}
+
+// Failed reports whether the result should fail the boot: it is unhealthy,
+// the check is required, and the problem wasn't marked as a Warning.
+func (r Result) Failed() bool {
+	return !r.Healthy && r.Severity != SeverityWarning && !IsWarning(r.Err())
+}
+
+// ExitCode returns the process exit code for the result: 1 if Failed,
+// otherwise 0.
+func (r Result) ExitCode() int {
+	if r.Failed() {
+		return 1
+	}
+	return 0
+}
+
+// Err returns the last attempt's error, nil if healthy.
+func (r Result) Err() error {
+	if r.Healthy || len(r.Attempts) == 0 {
+		return nil
+	}
+	return r.Attempts[len(r.Attempts)-1].Err
+}
+
+// String summarizes the result, e.g. "nextcloud healthy after 1m30s (4
+// attempts)", "nextcloud never became healthy in 5m0s (14 attempts):
+// maintenance mode" or "raid warning: md0 rebuilding: 12.5% done".
+func (r Result) String() string {
+	n := len(r.Attempts)
+	switch {
+	case r.Healthy && n == 1:
+		return fmt.Sprintf("%s healthy", r.Name)
+	case r.Healthy:
+		return fmt.Sprintf("%s healthy after %s (%d attempts)", r.Name, r.Elapsed.Round(time.Second), n)
+	case IsWarning(r.Err()):
+		return fmt.Sprintf("%s warning: %v", r.Name, r.Err())
+	case r.Severity == SeverityWarning:
+		return fmt.Sprintf("%s never became healthy in %s (%d attempts), warning only: %v", r.Name, r.Elapsed.Round(time.Second), n, r.Err())
+	default:
+		return fmt.Sprintf("%s never became healthy in %s (%d attempts): %v", r.Name, r.Elapsed.Round(time.Second), n, r.Err())
+	}
+}
+
+// Run calls check until it returns nil, returns a Warning, or the policy's
+// budget or attempts run out.
+func Run(ctx context.Context, name string, p Policy, check func(context.Context) error) Result {
+	res := Result{Name: name}
+	start := now()
+	delay := p.Initial
+	for {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if p.AttemptTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, p.AttemptTimeout)
+		}
+		err := check(attemptCtx)
+		cancel()
+
+		res.Elapsed = now().Sub(start)
+		res.Attempts = append(res.Attempts, Attempt{At: res.Elapsed, Err: err})
+		if err == nil {
+			res.Healthy = true
+			return res
+		}
+		if IsWarning(err) {
+			return res
+		}
+		if p.MaxAttempts > 0 && len(res.Attempts) >= p.MaxAttempts {
+			return res
+		}
+
+		remaining := p.Budget - res.Elapsed
+		if remaining <= 0 {
+			return res
+		}
+		if delay <= 0 {
+			delay = time.Second
+		}
+		if delay > remaining {
+			delay = remaining
+		}
+		if sleep(ctx, delay) != nil {
+			res.Elapsed = now().Sub(start)
+			return res
+		}
+		delay *= 2
+		if p.Max > 0 && delay > p.Max {
+			delay = p.Max
+		}
+	}
+}
+
+// Report writes the result to stdout if healthy or stderr if not. Failed
+// attempts are listed either way, since "healthy after 3 failures" is worth
+// seeing in the boot log too.
+func Report(r Result) {
+	out := os.Stdout
+	if !r.Healthy {
+		out = os.Stderr
+	}
+	for i, a := range r.Attempts {
+		if a.Err != nil {
+			fmt.Fprintf(out, "  attempt %d at %s: %v\n", i+1, a.At.Round(time.Second), a.Err)
+		}
+	}
+	fmt.Fprintln(out, r)
+}
+
+// Record appends a one-line summary of the result to the history file at
+// path, e.g. /var/lib/homelab-sidecars/health-history, and returns the
+// previous line for the same check, so slow boots can be compared against
+// the last one. An empty path records nothing.
+func Record(path string, r Result) (previous string, err error) {
+	if path == "" {
+		return "", nil
+	}
+	prefix := r.Name + " "
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			// Lines are "<RFC3339 time> <summary>"
+			if _, summary, ok := strings.Cut(scanner.Text(), " "); ok && strings.HasPrefix(summary, prefix) {
+				previous = scanner.Text()
+			}
+		}
+		f.Close()
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return previous, err
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintf(f, "%s %s\n", now().Format(time.RFC3339), r); err != nil {
+		return previous, err
+	}
+	return previous, nil
+}
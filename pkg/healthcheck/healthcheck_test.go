@@ -0,0 +1,196 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock advances only when the code under test sleeps
+func fakeClock(t *testing.T) {
+	t.Helper()
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	origNow, origSleep := now, sleep
+	now = func() time.Time { return clock }
+	sleep = func(ctx context.Context, d time.Duration) error {
+		clock = clock.Add(d)
+		return nil
+	}
+	t.Cleanup(func() { now, sleep = origNow, origSleep })
+}
+
+func TestRun(t *testing.T) {
+	policy := Policy{Budget: time.Minute, Initial: 5 * time.Second, Max: 20 * time.Second}
+	starting := errors.New("connection refused")
+
+	tests := []struct {
+		name         string
+		failures     int
+		wantHealthy  bool
+		wantAttempts int
+		wantElapsed  time.Duration
+		wantString   string
+	}{
+		{name: "healthy first time", failures: 0, wantHealthy: true, wantAttempts: 1, wantString: "svc healthy"},
+		// waits 5s, 10s, 20s
+		{name: "healthy after retries", failures: 3, wantHealthy: true, wantAttempts: 4, wantElapsed: 35 * time.Second, wantString: "svc healthy after 35s (4 attempts)"},
+		// waits 5s, 10s, 20s, 20s, then the last 5s of budget
+		{name: "never healthy", failures: 100, wantHealthy: false, wantAttempts: 6, wantElapsed: time.Minute, wantString: "svc never became healthy in 1m0s (6 attempts): connection refused"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClock(t)
+			calls := 0
+			res := Run(context.Background(), "svc", policy, func(context.Context) error {
+				calls++
+				if calls <= tt.failures {
+					return starting
+				}
+				return nil
+			})
+			if res.Healthy != tt.wantHealthy || len(res.Attempts) != tt.wantAttempts || res.Elapsed != tt.wantElapsed {
+				t.Errorf("Run = healthy %v, %d attempts, %v elapsed; want %v, %d, %v",
+					res.Healthy, len(res.Attempts), res.Elapsed, tt.wantHealthy, tt.wantAttempts, tt.wantElapsed)
+			}
+			if got := res.String(); got != tt.wantString {
+				t.Errorf("String() = %q, want %q", got, tt.wantString)
+			}
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	fakeClock(t)
+	path := filepath.Join(t.TempDir(), "history")
+
+	if prev, err := Record(path, Result{Name: "nextcloud", Healthy: true, Elapsed: 40 * time.Second, Attempts: make([]Attempt, 3)}); err != nil || prev != "" {
+		t.Fatalf("first Record = %q, %v", prev, err)
+	}
+	if _, err := Record(path, Result{Name: "raid", Healthy: true, Attempts: make([]Attempt, 1)}); err != nil {
+		t.Fatal(err)
+	}
+	prev, err := Record(path, Result{Name: "nextcloud", Healthy: true, Attempts: make([]Attempt, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(prev, " nextcloud healthy after 40s (3 attempts)") {
+		t.Errorf("previous = %q", prev)
+	}
+
+	if prev, err := Record("", Result{Name: "nextcloud"}); err != nil || prev != "" {
+		t.Errorf("Record with no path = %q, %v", prev, err)
+	}
+}
+
+func TestWarning(t *testing.T) {
+	policy := Policy{Budget: time.Minute, Initial: 5 * time.Second, Max: 20 * time.Second}
+	rebuilding := errors.New("md0 rebuilding: 12.5% done")
+
+	fakeClock(t)
+	calls := 0
+	res := Run(context.Background(), "raid", policy, func(context.Context) error {
+		calls++
+		return Warning(rebuilding)
+	})
+	if calls != 1 {
+		t.Errorf("check ran %d times, want 1: a warning isn't retried", calls)
+	}
+	if res.Healthy || res.Failed() || res.ExitCode() != 0 {
+		t.Errorf("Run = healthy %v, failed %v, exit %d; want unhealthy, not failed, exit 0", res.Healthy, res.Failed(), res.ExitCode())
+	}
+	if !errors.Is(res.Err(), rebuilding) {
+		t.Errorf("Err() = %v, want to wrap %v", res.Err(), rebuilding)
+	}
+	if got, want := res.String(), "raid warning: md0 rebuilding: 12.5% done"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if Warning(nil) != nil {
+		t.Error("Warning(nil) != nil")
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	policy := Policy{Budget: 10 * time.Second, Initial: 5 * time.Second, Max: 20 * time.Second}
+	down := errors.New("connection refused")
+
+	tests := []struct {
+		severity   Severity
+		wantExit   int
+		wantString string
+	}{
+		{severity: "", wantExit: 1, wantString: "svc never became healthy in 10s (3 attempts): connection refused"},
+		{severity: SeverityRequired, wantExit: 1, wantString: "svc never became healthy in 10s (3 attempts): connection refused"},
+		{severity: SeverityWarning, wantExit: 0, wantString: "svc never became healthy in 10s (3 attempts), warning only: connection refused"},
+	}
+	for _, tt := range tests {
+		fakeClock(t)
+		res := Run(context.Background(), "svc", policy, func(context.Context) error { return down })
+		res.Severity = tt.severity
+		if got := res.ExitCode(); got != tt.wantExit {
+			t.Errorf("severity %q: ExitCode() = %d, want %d", tt.severity, got, tt.wantExit)
+		}
+		if got := res.String(); got != tt.wantString {
+			t.Errorf("severity %q: String() = %q, want %q", tt.severity, got, tt.wantString)
+		}
+	}
+
+	healthy := Result{Name: "svc", Healthy: true, Attempts: make([]Attempt, 1)}
+	if healthy.ExitCode() != 0 {
+		t.Errorf("healthy ExitCode() = %d", healthy.ExitCode())
+	}
+}
+
+func TestSeverityFromEnv(t *testing.T) {
+	t.Setenv("HEALTH_SEVERITY", "warning")
+	t.Setenv("RAID_HEALTH_SEVERITY", "")
+	t.Setenv("NEXTCLOUD_HEALTH_SEVERITY", "required")
+	t.Setenv("ZFS_HEALTH_SEVERITY", "optional")
+
+	for check, want := range map[string]Severity{"raid": SeverityWarning, "nextcloud": SeverityRequired} {
+		if got, err := SeverityFromEnv(check); err != nil || got != want {
+			t.Errorf("SeverityFromEnv(%q) = %q, %v; want %q", check, got, err, want)
+		}
+	}
+	if _, err := SeverityFromEnv("zfs"); err == nil || !strings.Contains(err.Error(), "ZFS_HEALTH_SEVERITY") {
+		t.Errorf("SeverityFromEnv(zfs) error = %v, want one naming ZFS_HEALTH_SEVERITY", err)
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	policy := Policy{Budget: time.Minute, Initial: 5 * time.Second, Max: 20 * time.Second}
+
+	p, err := ParseFlags(nil, policy)
+	if err != nil || p != policy {
+		t.Errorf("ParseFlags(nil) = %+v, %v; want the policy unchanged", p, err)
+	}
+
+	p, err = ParseFlags([]string{"-retries=2", "-retry-interval=30s"}, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.MaxAttempts != 3 || p.Initial != 30*time.Second || p.Max != 30*time.Second {
+		t.Errorf("ParseFlags = %+v, want 3 attempts starting at 30s", p)
+	}
+
+	fakeClock(t)
+	calls := 0
+	res := Run(context.Background(), "svc", p, func(context.Context) error {
+		calls++
+		return errors.New("connection refused")
+	})
+	if calls != 3 || res.Elapsed != time.Minute {
+		t.Errorf("Run made %d attempts in %v, want 3 in 1m0s", calls, res.Elapsed)
+	}
+
+	t.Setenv("HEALTH_RETRIES", "0")
+	if p, err := ParseFlags(nil, policy); err != nil || p.MaxAttempts != 1 {
+		t.Errorf("HEALTH_RETRIES=0: MaxAttempts = %d, %v; want 1", p.MaxAttempts, err)
+	}
+	if _, err := ParseFlags([]string{"extra"}, policy); err == nil {
+		t.Error("ParseFlags accepted a stray argument")
+	}
+}
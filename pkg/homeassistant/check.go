@@ -0,0 +1,50 @@
+package homeassistant
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Checker implements check.Checker for Home Assistant entity states.
+// Returns an error while any configured entity is truthy (e.g. a "backup
+// running" boolean or an occupancy sensor), so reboots wait for it.
+type Checker struct {
+	Client   *Client
+	Entities []string
+}
+
+// NewChecker creates a Home Assistant entity checker.
+func NewChecker(client *Client, entities []string) *Checker {
+	return &Checker{
+		Client:   client,
+		Entities: entities,
+	}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "homeassistant"
+}
+
+// Check returns nil if no entity is active, error otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	active, err := c.Client.ActiveEntities(ctx, c.Entities)
+	if err != nil {
+		// Home Assistant being down shouldn't hold up reboots
+		return nil
+	}
+	if len(active) > 0 {
+		return fmt.Errorf("%s", Describe(active))
+	}
+	return nil
+}
+
+// Describe summarizes active entity states for an inhibitor reason.
+func Describe(states []State) string {
+	parts := make([]string, len(states))
+	for i, s := range states {
+		parts[i] = fmt.Sprintf("%s is %s", s.FriendlyName(), s.State)
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,130 @@
+// Package homeassistant provides a client for the Home Assistant REST API.
+package homeassistant
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// State represents an entity state from the Home Assistant API
+type State struct {
+	EntityID    string         `json:"entity_id"`
+	State       string         `json:"state"`
+	Attributes  map[string]any `json:"attributes"`
+	LastChanged time.Time      `json:"last_changed"`
+}
+
+// FriendlyName returns the entity's friendly_name attribute, or its ID.
+func (s *State) FriendlyName() string {
+	if name, ok := s.Attributes["friendly_name"].(string); ok && name != "" {
+		return name
+	}
+	return s.EntityID
+}
+
+// Truthy reports whether the state means "active": on, true, home, open,
+// playing, active, or a non-zero number. unavailable and unknown are false.
+func (s *State) Truthy() bool {
+	switch strings.ToLower(s.State) {
+	case "on", "true", "yes", "home", "open", "playing", "active":
+		return true
+	case "", "off", "false", "no", "not_home", "closed", "idle", "unavailable", "unknown":
+		return false
+	}
+	if n, err := strconv.ParseFloat(s.State, 64); err == nil {
+		return n != 0
+	}
+	return false
+}
+
+// Client handles communication with the Home Assistant API
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a new Home Assistant API client using a long-lived
+// access token.
+func NewClient(baseURL, token string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// GetState returns the current state of an entity.
+func (c *Client) GetState(ctx context.Context, entityID string) (*State, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/states/"+url.PathEscape(entityID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status for %s: %d", entityID, resp.StatusCode)
+	}
+
+	var state State
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &state, nil
+}
+
+// FireEvent fires an event on the Home Assistant event bus.
+func (c *Client) FireEvent(ctx context.Context, eventType string, data map[string]any) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/events/"+url.PathEscape(eventType), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ActiveEntities returns the states of entities that are currently truthy.
+func (c *Client) ActiveEntities(ctx context.Context, entityIDs []string) ([]State, error) {
+	var active []State
+	for _, id := range entityIDs {
+		state, err := c.GetState(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if state.Truthy() {
+			active = append(active, *state)
+		}
+	}
+	return active, nil
+}
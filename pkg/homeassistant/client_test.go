@@ -0,0 +1,91 @@
+package homeassistant
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestState_Truthy(t *testing.T) {
+	tests := []struct {
+		state string
+		want  bool
+	}{
+		{"on", true},
+		{"ON", true},
+		{"home", true},
+		{"playing", true},
+		{"3", true},
+		{"0", false},
+		{"off", false},
+		{"unavailable", false},
+		{"unknown", false},
+		{"not_home", false},
+		{"something else", false},
+	}
+	for _, tt := range tests {
+		s := State{State: tt.state}
+		if got := s.Truthy(); got != tt.want {
+			t.Errorf("Truthy(%q) = %v, want %v", tt.state, got, tt.want)
+		}
+	}
+}
+
+func TestClient_ActiveEntities(t *testing.T) {
+	states := map[string]string{
+		"input_boolean.backup_running": `{"entity_id": "input_boolean.backup_running", "state": "on", "attributes": {"friendly_name": "Backup running"}}`,
+		"binary_sensor.anyone_home":    `{"entity_id": "binary_sensor.anyone_home", "state": "off", "attributes": {}}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("missing or incorrect token")
+		}
+		id := strings.TrimPrefix(r.URL.Path, "/api/states/")
+		body, ok := states[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/", "test-token", 5*time.Second)
+	active, err := client.ActiveEntities(context.Background(), []string{"input_boolean.backup_running", "binary_sensor.anyone_home"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(active) != 1 || Describe(active) != "Backup running is on" {
+		t.Errorf("active = %+v", active)
+	}
+
+	if _, err := client.ActiveEntities(context.Background(), []string{"sensor.missing"}); err == nil || !strings.Contains(err.Error(), "unexpected status") {
+		t.Errorf("err = %v, want unexpected status", err)
+	}
+}
+
+func TestClient_FireEvent(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/events/homelab_inhibitor" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"message": "Event homelab_inhibitor fired."}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-token", 5*time.Second)
+	err := client.FireEvent(context.Background(), "homelab_inhibitor", map[string]any{"held": true, "reason": "raid"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got["held"] != true || got["reason"] != "raid" {
+		t.Errorf("event data = %v", got)
+	}
+}
@@ -0,0 +1,76 @@
+// Package hysteresis debounces a check, so a single busy poll doesn't take
+// the inhibitor only for the next idle poll to release it 30 seconds later,
+// and a single idle poll in the middle of a busy spell doesn't drop it.
+package hysteresis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+)
+
+// debouncer reports a state change only once it has held for enough polls
+type debouncer struct {
+	sidecar.Checker
+	acquireAfter int
+	releaseAfter int
+
+	mu     sync.Mutex
+	busy   bool   // the reported state
+	reason string // last busy reason, kept while a release is pending
+	streak int    // consecutive polls disagreeing with busy
+}
+
+// Wrap wraps checker so it reports busy only after acquireAfter consecutive
+// busy results, and idle again only after releaseAfter consecutive idle
+// ones. The check starts out idle. Errors are passed through and neither
+// extend nor break a streak, as sidecar.Run keeps the previous state on
+// errors. With both counts at 1 or less checker is returned unchanged.
+func Wrap(checker sidecar.Checker, acquireAfter, releaseAfter int) sidecar.Checker {
+	if acquireAfter <= 1 && releaseAfter <= 1 {
+		return checker
+	}
+	return &debouncer{
+		Checker:      checker,
+		acquireAfter: max(acquireAfter, 1),
+		releaseAfter: max(releaseAfter, 1),
+	}
+}
+
+// Check runs the wrapped checker and returns the debounced state.
+func (d *debouncer) Check(ctx context.Context) (bool, string, error) {
+	busy, reason, err := d.Checker.Check(ctx)
+	if err != nil {
+		return busy, reason, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if busy == d.busy {
+		d.streak = 0
+		if busy {
+			d.reason = reason
+		}
+		return busy, reason, nil
+	}
+
+	d.streak++
+	if busy {
+		if d.streak < d.acquireAfter {
+			logging.Debugf("%s busy for %d of %d polls: %s", d.Name(), d.streak, d.acquireAfter, reason)
+			return false, "", nil
+		}
+		d.busy, d.reason, d.streak = true, reason, 0
+		return true, reason, nil
+	}
+
+	if d.streak < d.releaseAfter {
+		return true, fmt.Sprintf("%s (idle for %d of %d polls)", d.reason, d.streak, d.releaseAfter), nil
+	}
+	d.busy, d.reason, d.streak = false, "", 0
+	return false, reason, nil
+}
@@ -0,0 +1,59 @@
+package hysteresis
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+)
+
+func TestWrap(t *testing.T) {
+	var busy bool
+	var checkErr error
+	inner := sidecar.NewCheckerFunc("jellyfin", func(ctx context.Context) (bool, string, error) {
+		return busy, "1 active stream(s)", checkErr
+	})
+	checker := Wrap(inner, 3, 2)
+
+	steps := []struct {
+		busy       bool
+		err        error
+		wantBusy   bool
+		wantReason string
+	}{
+		{busy: true, wantBusy: false},                                  // 1 of 3
+		{busy: false, wantBusy: false},                                 // streak broken
+		{busy: true, wantBusy: false},                                  // 1 of 3
+		{busy: true, wantBusy: false},                                  // 2 of 3
+		{err: errors.New("timeout"), wantBusy: false},                  // doesn't count
+		{busy: true, wantBusy: true, wantReason: "1 active stream(s)"}, // 3 of 3
+		{busy: false, wantBusy: true, wantReason: "1 active stream(s) (idle for 1 of 2 polls)"},
+		{busy: true, wantBusy: true, wantReason: "1 active stream(s)"}, // streak broken
+		{busy: false, wantBusy: true, wantReason: "1 active stream(s) (idle for 1 of 2 polls)"},
+		{busy: false, wantBusy: false}, // 2 of 2
+	}
+
+	for i, step := range steps {
+		busy, checkErr = step.busy, step.err
+		gotBusy, gotReason, err := checker.Check(context.Background())
+		if !errors.Is(err, step.err) {
+			t.Fatalf("step %d: err = %v, want %v", i, err, step.err)
+		}
+		if err != nil {
+			continue
+		}
+		if gotBusy != step.wantBusy || (step.wantBusy && gotReason != step.wantReason) {
+			t.Errorf("step %d: Check() = %v, %q; want %v, %q", i, gotBusy, gotReason, step.wantBusy, step.wantReason)
+		}
+	}
+}
+
+func TestWrapDisabled(t *testing.T) {
+	inner := sidecar.NewCheckerFunc("jellyfin", func(ctx context.Context) (bool, string, error) {
+		return false, "", nil
+	})
+	if got := Wrap(inner, 1, 0); got != inner {
+		t.Errorf("Wrap(1, 0) = %T, want the checker unchanged", got)
+	}
+}
@@ -0,0 +1,57 @@
+// Package idleexit stops a sidecar once its check has been clear for a
+// while, so a sidecar can run as a blocking step ("wait until qBittorrent
+// is done") instead of a permanent daemon.
+package idleexit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+)
+
+// now is replaced in tests
+var now = time.Now
+
+// idleChecker cancels the run context after a continuous idle period
+type idleChecker struct {
+	sidecar.Checker
+	after  time.Duration
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	idleSince time.Time
+}
+
+// Wrap wraps checker so that cancel is called once Check has reported not
+// busy, without error, continuously for at least after. Pass the cancel
+// func of the context given to sidecar.Run, which then returns nil.
+func Wrap(checker sidecar.Checker, after time.Duration, cancel context.CancelFunc) sidecar.Checker {
+	return &idleChecker{Checker: checker, after: after, cancel: cancel}
+}
+
+// Check runs the wrapped checker and tracks how long it has been clear.
+func (c *idleChecker) Check(ctx context.Context) (bool, string, error) {
+	busy, reason, err := c.Checker.Check(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if busy || err != nil {
+		// An error isn't evidence of idleness; start over
+		c.idleSince = time.Time{}
+		return busy, reason, err
+	}
+
+	t := now()
+	if c.idleSince.IsZero() {
+		c.idleSince = t
+	}
+	if idle := t.Sub(c.idleSince); idle >= c.after {
+		logging.Infof("%s idle for %s, exiting", c.Name(), idle.Round(time.Second))
+		c.cancel()
+	}
+	return busy, reason, err
+}
@@ -0,0 +1,51 @@
+package idleexit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+)
+
+func TestWrap(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	var busy bool
+	var checkErr error
+	inner := sidecar.NewCheckerFunc("qbittorrent", func(ctx context.Context) (bool, string, error) {
+		return busy, "", checkErr
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	checker := Wrap(inner, 5*time.Minute, cancel)
+
+	steps := []struct {
+		advance time.Duration
+		busy    bool
+		err     error
+		exited  bool
+	}{
+		{0, true, nil, false},                                  // busy at start
+		{time.Minute, false, nil, false},                       // idle from here
+		{4 * time.Minute, false, nil, false},                   // 4m idle
+		{30 * time.Second, true, nil, false},                   // busy again resets
+		{time.Minute, false, nil, false},                       // idle from here
+		{4 * time.Minute, false, errors.New("timeout"), false}, // error resets
+		{time.Minute, false, nil, false},                       // idle from here
+		{5 * time.Minute, false, nil, true},                    // 5m idle
+	}
+
+	for i, step := range steps {
+		clock = clock.Add(step.advance)
+		busy, checkErr = step.busy, step.err
+		checker.Check(ctx)
+		if exited := ctx.Err() != nil; exited != step.exited {
+			t.Fatalf("step %d: exited = %v, want %v", i, exited, step.exited)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package immich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Checker implements check.Checker for Immich.
+// Returns an error while a job queue has jobs running or waiting, e.g. a
+// library import or machine learning over a new upload.
+type Checker struct {
+	Client *Client
+	// Queues limits the check to these queues, e.g. "library" and
+	// "smartSearch"; empty checks them all
+	Queues []string
+}
+
+// NewChecker creates an Immich checker.
+func NewChecker(client *Client, queues []string) *Checker {
+	return &Checker{Client: client, Queues: queues}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "immich"
+}
+
+// Check returns nil if no queue is busy, error otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	reasons, err := c.Activity(ctx)
+	if err != nil {
+		return fmt.Errorf("immich check failed: %w", err)
+	}
+	if len(reasons) > 0 {
+		return errors.New(strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// Activity describes each busy queue, e.g.
+// "smartSearch: 2 active, 1500 waiting".
+func (c *Checker) Activity(ctx context.Context) ([]string, error) {
+	queues, err := c.Client.Queues(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reasons []string
+	for _, q := range queues {
+		if !q.Busy() || !c.includes(q.Name) {
+			continue
+		}
+		reasons = append(reasons, fmt.Sprintf("%s: %d active, %d waiting", q.Name, q.Active, q.Waiting))
+	}
+	return reasons, nil
+}
+
+func (c *Checker) includes(queue string) bool {
+	if len(c.Queues) == 0 {
+		return true
+	}
+	for _, q := range c.Queues {
+		if strings.EqualFold(q, queue) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+// Package immich provides a client for the Immich job queue API.
+package immich
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrUnauthorized is returned when Immich rejects the API key
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Queue is the state of one of Immich's job queues, e.g. thumbnail
+// generation, face detection or library import
+type Queue struct {
+	Name    string
+	Active  int
+	Waiting int
+	Delayed int
+	Failed  int
+	Paused  bool // paused from the admin UI
+}
+
+// Busy reports whether the queue is working through jobs. A paused queue
+// isn't, whatever it holds.
+func (q Queue) Busy() bool {
+	return !q.Paused && (q.Active > 0 || q.Waiting > 0)
+}
+
+// Client handles communication with the Immich API
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewClient creates a new Immich API client. The API key needs the
+// job.read permission.
+func NewClient(baseURL, apiKey string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+type jobsResponse map[string]struct {
+	JobCounts struct {
+		Active  int `json:"active"`
+		Failed  int `json:"failed"`
+		Delayed int `json:"delayed"`
+		Waiting int `json:"waiting"`
+	} `json:"jobCounts"`
+	QueueStatus struct {
+		IsPaused bool `json:"isPaused"`
+	} `json:"queueStatus"`
+}
+
+// Queues returns every job queue, sorted by name.
+func (c *Client) Queues(ctx context.Context) ([]Queue, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/jobs", nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("unexpected status: %d: %w", resp.StatusCode, ErrUnauthorized)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	var jobs jobsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&jobs); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	queues := make([]Queue, 0, len(jobs))
+	for name, j := range jobs {
+		queues = append(queues, Queue{
+			Name:    name,
+			Active:  j.JobCounts.Active,
+			Waiting: j.JobCounts.Waiting,
+			Delayed: j.JobCounts.Delayed,
+			Failed:  j.JobCounts.Failed,
+			Paused:  j.QueueStatus.IsPaused,
+		})
+	}
+	sort.Slice(queues, func(i, j int) bool { return queues[i].Name < queues[j].Name })
+	return queues, nil
+}
+
+// ExcessAccess describes the permissions the API key has beyond job.read,
+// e.g. "all permissions", or "" if it has no more. Older Immich releases
+// can't report a key's own permissions and answer with an error.
+func (c *Client) ExcessAccess(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/api-keys/me", nil)
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	var key struct {
+		Permissions []string `json:"permissions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&key); err != nil {
+		return "", fmt.Errorf("decode response: %w", err)
+	}
+
+	if slices.Contains(key.Permissions, "all") {
+		return "all permissions", nil
+	}
+	var extra []string
+	for _, p := range key.Permissions {
+		if p != "job.read" {
+			extra = append(extra, p)
+		}
+	}
+	if len(extra) == 0 {
+		return "", nil
+	}
+	return "the permissions " + strings.Join(extra, ", "), nil
+}
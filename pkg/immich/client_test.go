@@ -0,0 +1,99 @@
+package immich
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const jobsBody = `{
+	"thumbnailGeneration": {"jobCounts": {"active": 1, "completed": 0, "failed": 0, "delayed": 0, "waiting": 40, "paused": 0},
+		"queueStatus": {"isActive": true, "isPaused": false}},
+	"smartSearch": {"jobCounts": {"active": 0, "completed": 0, "failed": 2, "delayed": 0, "waiting": 1500, "paused": 0},
+		"queueStatus": {"isActive": false, "isPaused": true}},
+	"library": {"jobCounts": {"active": 0, "completed": 0, "failed": 0, "delayed": 0, "waiting": 0, "paused": 0},
+		"queueStatus": {"isActive": false, "isPaused": false}}
+}`
+
+func immichServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/jobs" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(jobsBody))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_Queues(t *testing.T) {
+	server := immichServer(t)
+	queues, err := NewClient(server.URL+"/", "key", 5*time.Second).Queues(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queues) != 3 || queues[0].Name != "library" || queues[1].Name != "smartSearch" {
+		t.Fatalf("Queues() = %+v", queues)
+	}
+	if q := queues[1]; q.Waiting != 1500 || q.Failed != 2 || !q.Paused || q.Busy() {
+		t.Errorf("smartSearch = %+v, busy %v", q, q.Busy())
+	}
+
+	_, err = NewClient(server.URL, "wrong", 5*time.Second).Queues(context.Background())
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Queues() error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestChecker(t *testing.T) {
+	server := immichServer(t)
+	client := NewClient(server.URL, "key", 5*time.Second)
+
+	err := NewChecker(client, nil).Check(context.Background())
+	if err == nil || err.Error() != "thumbnailGeneration: 1 active, 40 waiting" {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	// Paused and idle queues never block
+	if err := NewChecker(client, []string{"smartsearch", "library"}).Check(context.Background()); err != nil {
+		t.Errorf("Check() limited to idle queues = %v", err)
+	}
+
+	if err := NewChecker(NewClient(server.URL, "wrong", time.Second), nil).Check(context.Background()); err == nil || !strings.HasPrefix(err.Error(), "immich check failed") {
+		t.Errorf("Check() unauthorized = %v", err)
+	}
+}
+
+func TestClient_ExcessAccess(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{body: `{"name": "sidecar", "permissions": ["job.read"]}`, want: ""},
+		{body: `{"name": "sidecar", "permissions": ["all"]}`, want: "all permissions"},
+		{body: `{"name": "sidecar", "permissions": ["job.read", "job.create"]}`, want: "the permissions job.create"},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/api-keys/me" || r.Header.Get("x-api-key") != "key" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(tt.body))
+		}))
+		got, err := NewClient(server.URL, "key", 5*time.Second).ExcessAccess(context.Background())
+		server.Close()
+		if err != nil || got != tt.want {
+			t.Errorf("ExcessAccess() with %s = %q, %v, want %q", tt.body, got, err, tt.want)
+		}
+	}
+}
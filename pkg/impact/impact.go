@@ -0,0 +1,51 @@
+// Package impact adds a human-oriented description of what a sidecar's
+// inhibitor means for the household, e.g. "Plex is unavailable for the whole
+// house", to the reason it gives while busy. The reason is the inhibitor's
+// why string and the body of busy notifications, and is shown in /status,
+// so whoever sees it knows what waiting means without knowing the service.
+package impact
+
+import (
+	"context"
+	"strings"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+)
+
+// impactChecker appends the impact to busy reasons
+type impactChecker struct {
+	sidecar.Checker
+	impact string
+}
+
+// Wrap wraps checker so that a busy reason ends with impact, e.g.
+// "2 active streams (Plex is unavailable for the whole house)". An empty
+// impact returns checker unchanged.
+func Wrap(checker sidecar.Checker, impact string) sidecar.Checker {
+	impact = strings.TrimSpace(impact)
+	if impact == "" {
+		return checker
+	}
+	return &impactChecker{Checker: checker, impact: impact}
+}
+
+// Check runs the wrapped checker and annotates a busy reason.
+func (c *impactChecker) Check(ctx context.Context) (bool, string, error) {
+	busy, reason, err := c.Checker.Check(ctx)
+	if !busy || err != nil {
+		return busy, reason, err
+	}
+	return busy, annotate(reason, c.impact), nil
+}
+
+// annotate returns reason with impact appended in parentheses, or impact
+// alone if there's no reason.
+func annotate(reason, impact string) string {
+	switch {
+	case impact == "":
+		return reason
+	case reason == "":
+		return impact
+	}
+	return reason + " (" + impact + ")"
+}
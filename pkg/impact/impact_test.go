@@ -0,0 +1,42 @@
+package impact
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+)
+
+func TestWrap(t *testing.T) {
+	var busy bool
+	var reason string
+	var checkErr error
+	inner := sidecar.NewCheckerFunc("jellyfin", func(ctx context.Context) (bool, string, error) {
+		return busy, reason, checkErr
+	})
+	checker := Wrap(inner, " Jellyfin is unavailable for the whole house ")
+
+	tests := []struct {
+		busy   bool
+		reason string
+		err    error
+		want   string
+	}{
+		{true, "2 active streams", nil, "2 active streams (Jellyfin is unavailable for the whole house)"},
+		{true, "", nil, "Jellyfin is unavailable for the whole house"},
+		{false, "", nil, ""},
+		{true, "2 active streams", errors.New("timeout"), "2 active streams"},
+	}
+	for _, tt := range tests {
+		busy, reason, checkErr = tt.busy, tt.reason, tt.err
+		gotBusy, got, err := checker.Check(context.Background())
+		if gotBusy != tt.busy || got != tt.want || err != tt.err {
+			t.Errorf("Check() with %v, %q, %v = %v, %q, %v; want reason %q", tt.busy, tt.reason, tt.err, gotBusy, got, err, tt.want)
+		}
+	}
+
+	if Wrap(inner, "") != inner {
+		t.Error("Wrap() with no impact should return the checker unchanged")
+	}
+}
@@ -0,0 +1,63 @@
+//go:build darwin
+
+package inhibitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// caffeinateBackend holds IOKit power assertions by running caffeinate,
+// which keeps its assertion until it exits. -w ties it to our pid so a
+// crashed daemon can't leave the Mac awake forever.
+type caffeinateBackend struct {
+	path string
+}
+
+func newBackend(ctx context.Context) (Backend, error) {
+	path, err := exec.LookPath("caffeinate")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotSupported, err)
+	}
+	return &caffeinateBackend{path: path}, nil
+}
+
+// Inhibit ignores who, why and mode: power assertions started by
+// caffeinate are named after it, and always block.
+func (b *caffeinateBackend) Inhibit(ctx context.Context, what, who, why, mode string) (io.Closer, error) {
+	sleep, _, err := sleepWhat(what)
+	if err != nil {
+		return nil, err
+	}
+	// -i holds off idle sleep, -s system sleep while on AC power
+	args := []string{"-w", strconv.Itoa(os.Getpid()), "-i"}
+	if sleep {
+		args = append(args, "-s")
+	}
+
+	// Not CommandContext: the assertion outlives the Acquire call
+	cmd := exec.Command(b.path, args...)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("caffeinate: %w", err)
+	}
+	return &caffeinateLock{cmd: cmd}, nil
+}
+
+func (b *caffeinateBackend) Close() error { return nil }
+
+type caffeinateLock struct {
+	cmd *exec.Cmd
+}
+
+func (l *caffeinateLock) Close() error {
+	if err := l.cmd.Process.Kill(); err != nil {
+		return fmt.Errorf("stop caffeinate: %w", err)
+	}
+	// Killed is the expected exit; only reap it
+	l.cmd.Wait()
+	return nil
+}
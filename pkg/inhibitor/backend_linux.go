@@ -0,0 +1,39 @@
+//go:build linux
+
+package inhibitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logind"
+	"github.com/godbus/dbus/v5"
+)
+
+// logindBackend takes logind inhibitor locks over the system bus
+type logindBackend struct {
+	conn *dbus.Conn
+}
+
+func newBackend(ctx context.Context) (Backend, error) {
+	conn, err := dbus.ConnectSystemBus(dbus.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotSupported, err)
+	}
+
+	// Fail early if logind isn't there to talk to
+	if _, err := logind.ListInhibitors(ctx, conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %v", ErrNotSupported, err)
+	}
+	return &logindBackend{conn: conn}, nil
+}
+
+func (b *logindBackend) Inhibit(ctx context.Context, what, who, why, mode string) (io.Closer, error) {
+	return logind.Inhibit(ctx, b.conn, what, who, why, mode)
+}
+
+func (b *logindBackend) Close() error {
+	return b.conn.Close()
+}
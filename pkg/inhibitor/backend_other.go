@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !windows
+
+package inhibitor
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+)
+
+func newBackend(ctx context.Context) (Backend, error) {
+	return nil, fmt.Errorf("%w: no backend for %s", ErrNotSupported, runtime.GOOS)
+}
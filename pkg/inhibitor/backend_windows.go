@@ -0,0 +1,82 @@
+//go:build windows
+
+package inhibitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"syscall"
+)
+
+// SetThreadExecutionState flags
+const (
+	esSystemRequired  = 0x00000001
+	esDisplayRequired = 0x00000002
+	esContinuous      = 0x80000000
+)
+
+var setThreadExecutionState = syscall.NewLazyDLL("kernel32.dll").NewProc("SetThreadExecutionState")
+
+// executionStateBackend holds SetThreadExecutionState requests. The state
+// belongs to the calling thread, so each lock pins a goroutine to its own
+// OS thread for as long as it's held.
+type executionStateBackend struct{}
+
+func newBackend(ctx context.Context) (Backend, error) {
+	if err := setThreadExecutionState.Find(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotSupported, err)
+	}
+	return executionStateBackend{}, nil
+}
+
+// Inhibit ignores who, why and mode: execution state requests carry no
+// description, and always block.
+func (executionStateBackend) Inhibit(ctx context.Context, what, who, why, mode string) (io.Closer, error) {
+	sleep, idle, err := sleepWhat(what)
+	if err != nil {
+		return nil, err
+	}
+	flags := uintptr(esContinuous)
+	if sleep {
+		flags |= esSystemRequired
+	}
+	if idle {
+		flags |= esDisplayRequired
+	}
+
+	l := &executionStateLock{done: make(chan struct{}), released: make(chan struct{})}
+	started := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		defer close(l.released)
+
+		// Returns the previous state, or 0 on failure
+		if prev, _, err := setThreadExecutionState.Call(flags); prev == 0 {
+			started <- fmt.Errorf("SetThreadExecutionState: %w", err)
+			return
+		}
+		started <- nil
+		<-l.done
+		setThreadExecutionState.Call(esContinuous)
+	}()
+	if err := <-started; err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (executionStateBackend) Close() error { return nil }
+
+type executionStateLock struct {
+	done     chan struct{}
+	released chan struct{}
+}
+
+func (l *executionStateLock) Close() error {
+	close(l.done)
+	<-l.released
+	return nil
+}
@@ -0,0 +1,237 @@
+// Package inhibitor manages a single sleep/shutdown inhibitor lock, for
+// daemons that want to delay shutdown or sleep without adopting the
+// sidecar polling loop.
+//
+// The lock is taken through a platform Backend: logind over D-Bus on
+// Linux, a caffeinate power assertion on macOS, and
+// SetThreadExecutionState on Windows. Only Linux can block shutdown; the
+// other backends honour "sleep" and "idle" and reject anything else.
+//
+//	inh, err := inhibitor.New(ctx, "shutdown", "my-daemon", "block")
+//	if errors.Is(err, inhibitor.ErrNotSupported) {
+//		// no logind, e.g. in a container without the system bus
+//	}
+//	defer inh.Close()
+//	err = inh.AcquireFor(ctx, "migrating database", 30*time.Minute)
+package inhibitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+)
+
+var (
+	// ErrNotSupported means the platform backend isn't available, e.g.
+	// logind isn't reachable over the system bus
+	ErrNotSupported = errors.New("inhibitor locks not supported")
+	// ErrAlreadyHeld is returned by Acquire while the lock is held
+	ErrAlreadyHeld = errors.New("inhibitor already held")
+	// ErrNotHeld is returned by Release when the lock isn't held
+	ErrNotHeld = errors.New("inhibitor not held")
+
+	errClosed = errors.New("inhibitor closed")
+)
+
+// Backend takes locks from the platform's power manager. Each lock is
+// held until the returned io.Closer is closed.
+type Backend interface {
+	Inhibit(ctx context.Context, what, who, why, mode string) (io.Closer, error)
+	// Close releases any connection the backend holds
+	Close() error
+}
+
+// Lock is an inhibitor lock that can be taken and released repeatedly.
+// The run loop and the sleep hooks take their locks through it, so a dry
+// run or a test can stand in for the platform.
+type Lock interface {
+	Acquire(ctx context.Context, why string) error
+	Release(ctx context.Context) error
+	Replace(ctx context.Context, why string) error
+	Held() (held bool, why string)
+	Close() error
+}
+
+var _ Lock = (*Inhibitor)(nil)
+
+// Inhibitor is one inhibitor lock that can be taken and released
+// repeatedly. It is safe for concurrent use.
+type Inhibitor struct {
+	What string // e.g. "shutdown:sleep"
+	Who  string
+	Mode string // "block" or "delay"
+
+	backend Backend
+
+	mu     sync.Mutex
+	fd     io.Closer
+	why    string
+	expiry *time.Timer
+}
+
+// New connects to the platform backend. It returns an error wrapping
+// ErrNotSupported if the backend is unavailable, e.g. logind without a
+// system bus, or on a platform with no backend at all.
+func New(ctx context.Context, what, who, mode string) (*Inhibitor, error) {
+	b, err := newBackend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithBackend(b, what, who, mode), nil
+}
+
+// NewWithBackend returns an Inhibitor that takes its locks from b.
+func NewWithBackend(b Backend, what, who, mode string) *Inhibitor {
+	return &Inhibitor{What: what, Who: who, Mode: mode, backend: b}
+}
+
+// Acquire takes the lock with the given reason. It returns ErrAlreadyHeld
+// if the lock is already held; Replace changes the reason.
+func (i *Inhibitor) Acquire(ctx context.Context, why string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.acquireLocked(ctx, why)
+}
+
+// AcquireFor takes the lock and releases it automatically after ttl, so a
+// daemon that hangs or forgets can't block shutdown forever.
+func (i *Inhibitor) AcquireFor(ctx context.Context, why string, ttl time.Duration) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if err := i.acquireLocked(ctx, why); err != nil {
+		return err
+	}
+	fd := i.fd
+	i.expiry = time.AfterFunc(ttl, func() {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		// Only expire the lock this call took
+		if i.fd == fd {
+			logging.Infof("Inhibitor %q expired after %s", why, ttl)
+			i.releaseLocked()
+		}
+	})
+	return nil
+}
+
+func (i *Inhibitor) acquireLocked(ctx context.Context, why string) error {
+	if i.fd != nil {
+		return ErrAlreadyHeld
+	}
+	if i.backend == nil {
+		return errClosed
+	}
+	fd, err := i.backend.Inhibit(ctx, i.What, i.Who, why, i.Mode)
+	if err != nil {
+		return fmt.Errorf("acquire inhibitor: %w", err)
+	}
+	i.fd = fd
+	i.why = why
+	return nil
+}
+
+// Release drops the lock. It returns ErrNotHeld if the lock isn't held.
+// ctx is accepted for symmetry; releasing never blocks.
+func (i *Inhibitor) Release(ctx context.Context) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.fd == nil {
+		return ErrNotHeld
+	}
+	return i.releaseLocked()
+}
+
+func (i *Inhibitor) releaseLocked() error {
+	if i.expiry != nil {
+		i.expiry.Stop()
+		i.expiry = nil
+	}
+	err := i.fd.Close()
+	i.fd = nil
+	i.why = ""
+	if err != nil {
+		return fmt.Errorf("release inhibitor: %w", err)
+	}
+	return nil
+}
+
+// Replace changes the reason of a held lock. The lock with the new reason
+// is taken before the old one is dropped, so shutdown is never briefly
+// allowed in between. It returns ErrNotHeld if the lock isn't held.
+func (i *Inhibitor) Replace(ctx context.Context, why string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.fd == nil {
+		return ErrNotHeld
+	}
+	if i.backend == nil {
+		return errClosed
+	}
+	fd, err := i.backend.Inhibit(ctx, i.What, i.Who, why, i.Mode)
+	if err != nil {
+		return fmt.Errorf("acquire inhibitor: %w", err)
+	}
+	old := i.fd
+	if i.expiry != nil {
+		i.expiry.Stop()
+		i.expiry = nil
+	}
+	i.fd = fd
+	i.why = why
+	if err := old.Close(); err != nil {
+		return fmt.Errorf("release inhibitor: %w", err)
+	}
+	return nil
+}
+
+// Held reports whether the lock is held, and with what reason.
+func (i *Inhibitor) Held() (held bool, why string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.fd != nil, i.why
+}
+
+// Close releases the lock if held and closes the backend.
+func (i *Inhibitor) Close() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var err error
+	if i.fd != nil {
+		err = i.releaseLocked()
+	}
+	if i.backend != nil {
+		err = errors.Join(err, i.backend.Close())
+		i.backend = nil
+	}
+	return err
+}
+
+// sleepWhat picks sleep and idle out of what, a colon-separated logind
+// "what" list, since that's all the non-logind backends can inhibit. The
+// rest is skipped with a log line; it's an error if nothing is left.
+func sleepWhat(what string) (sleep, idle bool, err error) {
+	for _, w := range strings.Split(what, ":") {
+		switch w {
+		case "sleep":
+			sleep = true
+		case "idle":
+			idle = true
+		default:
+			logging.Debugf("Cannot inhibit %q on this platform, skipping", w)
+		}
+	}
+	if !sleep && !idle {
+		return false, false, fmt.Errorf("%w: cannot inhibit %q on this platform", ErrNotSupported, what)
+	}
+	return sleep, idle, nil
+}
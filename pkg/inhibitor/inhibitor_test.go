@@ -0,0 +1,189 @@
+package inhibitor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLogind hands out pipes; a lock is held while the read end sees no EOF.
+type fakeLogind struct {
+	mu    sync.Mutex
+	locks []*os.File // read ends
+	err   error
+}
+
+func (f *fakeLogind) Inhibit(ctx context.Context, what, who, why, mode string) (io.Closer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	f.locks = append(f.locks, r)
+	return w, nil
+}
+
+func (f *fakeLogind) Close() error { return nil }
+
+// released reports whether lock n has been closed by its holder.
+func (f *fakeLogind) released(t *testing.T, n int) bool {
+	t.Helper()
+	f.mu.Lock()
+	r := f.locks[n]
+	f.mu.Unlock()
+	r.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	_, err := r.Read(make([]byte, 1))
+	return err == io.EOF
+}
+
+func newFake() (*Inhibitor, *fakeLogind) {
+	fake := &fakeLogind{}
+	return NewWithBackend(fake, "shutdown", "test", "block"), fake
+}
+
+func TestAcquireRelease(t *testing.T) {
+	inh, fake := newFake()
+	ctx := context.Background()
+
+	if err := inh.Release(ctx); !errors.Is(err, ErrNotHeld) {
+		t.Errorf("Release before Acquire = %v, want ErrNotHeld", err)
+	}
+
+	if err := inh.Acquire(ctx, "backup running"); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if held, why := inh.Held(); !held || why != "backup running" {
+		t.Errorf("Held = (%v, %q)", held, why)
+	}
+	if err := inh.Acquire(ctx, "again"); !errors.Is(err, ErrAlreadyHeld) {
+		t.Errorf("second Acquire = %v, want ErrAlreadyHeld", err)
+	}
+	if fake.released(t, 0) {
+		t.Error("lock released while held")
+	}
+
+	if err := inh.Release(ctx); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if !fake.released(t, 0) {
+		t.Error("lock not released")
+	}
+	if held, _ := inh.Held(); held {
+		t.Error("Held after Release")
+	}
+
+	fake.err = errors.New("access denied")
+	if err := inh.Acquire(ctx, "x"); err == nil {
+		t.Error("expected Acquire error")
+	}
+}
+
+func TestAcquireFor(t *testing.T) {
+	inh, fake := newFake()
+	ctx := context.Background()
+
+	if err := inh.AcquireFor(ctx, "short", 20*time.Millisecond); err != nil {
+		t.Fatalf("AcquireFor: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if held, _ := inh.Held(); held {
+		t.Error("lock still held after ttl")
+	}
+	if !fake.released(t, 0) {
+		t.Error("lock not released after ttl")
+	}
+
+	// An early release stops the timer from touching a later lock
+	if err := inh.AcquireFor(ctx, "second", 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	inh.Release(ctx)
+	if err := inh.Acquire(ctx, "third"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if held, why := inh.Held(); !held || why != "third" {
+		t.Errorf("Held = (%v, %q), want third still held", held, why)
+	}
+	inh.Close()
+	if !fake.released(t, 2) {
+		t.Error("Close did not release the lock")
+	}
+}
+
+func TestReplace(t *testing.T) {
+	inh, fake := newFake()
+	ctx := context.Background()
+
+	if err := inh.Replace(ctx, "x"); !errors.Is(err, ErrNotHeld) {
+		t.Errorf("Replace before Acquire = %v, want ErrNotHeld", err)
+	}
+	if err := inh.Acquire(ctx, "rebuilding md0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := inh.Replace(ctx, "checking md0"); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	if held, why := inh.Held(); !held || why != "checking md0" {
+		t.Errorf("Held = (%v, %q), want checking md0", held, why)
+	}
+	if !fake.released(t, 0) {
+		t.Error("old lock not released")
+	}
+	if fake.released(t, 1) {
+		t.Error("new lock released")
+	}
+
+	// A failure keeps the old lock
+	fake.err = errors.New("access denied")
+	if err := inh.Replace(ctx, "y"); err == nil {
+		t.Error("expected Replace error")
+	}
+	if held, why := inh.Held(); !held || why != "checking md0" || fake.released(t, 1) {
+		t.Errorf("Held = (%v, %q) after failed Replace, want checking md0", held, why)
+	}
+}
+
+func TestSleepWhat(t *testing.T) {
+	tests := []struct {
+		what        string
+		sleep, idle bool
+		err         bool
+	}{
+		{"sleep", true, false, false},
+		{"idle", false, true, false},
+		{"shutdown:sleep", true, false, false},
+		{"sleep:idle", true, true, false},
+		{"shutdown", false, false, true},
+		{"handle-lid-switch", false, false, true},
+	}
+	for _, tt := range tests {
+		sleep, idle, err := sleepWhat(tt.what)
+		if (err != nil) != tt.err {
+			t.Errorf("sleepWhat(%q) err = %v, want error %v", tt.what, err, tt.err)
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrNotSupported) {
+			t.Errorf("sleepWhat(%q) err = %v, want ErrNotSupported", tt.what, err)
+		}
+		if sleep != tt.sleep || idle != tt.idle {
+			t.Errorf("sleepWhat(%q) = (%v, %v), want (%v, %v)", tt.what, sleep, idle, tt.sleep, tt.idle)
+		}
+	}
+}
+
+func TestAcquireAfterClose(t *testing.T) {
+	inh, _ := newFake()
+	inh.Close()
+	if err := inh.Acquire(context.Background(), "x"); err == nil {
+		t.Error("expected Acquire error after Close")
+	}
+}
@@ -2,6 +2,7 @@ package jellyfin
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -15,12 +16,27 @@ import (
 //
 // Includes a grace period after streams end to prevent interrupting
 // users who briefly pause.
+//
+// MinBitrate, if set, only counts streams as blocking when their combined
+// bitrate reaches it, so a few music streams don't hold up a reboot.
+// Streams with an unknown bitrate always count.
+//
+// PauseTimeout, if set, stops counting a session once it has been paused
+// for that long, so a movie paused overnight doesn't block forever.
+//
+// BlockTasks, if set, also blocks while a matching scheduled task is
+// running (see RunningTasks), e.g. DefaultBlockTasks.
 type Checker struct {
-	Client      *Client
-	GracePeriod time.Duration
+	Client       *Client
+	GracePeriod  time.Duration
+	MinBitrate   int64         // bits/s, 0 = any stream blocks
+	Filter       Filter        // selects which sessions count, e.g. ignore Audio
+	PauseTimeout time.Duration // 0 = paused sessions always count
+	BlockTasks   []string      // scheduled tasks that block, nil = ignore tasks
 
 	mu             sync.Mutex
 	lastActiveTime time.Time
+	pauses         PauseTracker
 }
 
 // NewChecker creates a Jellyfin stream checker with the given grace period.
@@ -41,12 +57,19 @@ func (c *Checker) Name() string {
 // error if streams are active or within grace period (not safe to reboot).
 func (c *Checker) Check(ctx context.Context) error {
 	hasStreams, sessions, err := c.Client.HasActiveStreams(ctx)
+	if errors.Is(err, ErrUnauthorized) {
+		// Jellyfin is up but we can't see its sessions; don't assume idle
+		return fmt.Errorf("cannot list sessions: %w", err)
+	}
 	if err != nil {
 		// If we can't reach Jellyfin, assume it's safe to reboot
 		// (Jellyfin is down anyway)
 		return nil
 	}
 
+	sessions, total := c.Counted(sessions, time.Now())
+	hasStreams = len(sessions) > 0
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -57,6 +80,9 @@ func (c *Checker) Check(ctx context.Context) error {
 		for _, s := range sessions {
 			descriptions = append(descriptions, s.Describe())
 		}
+		if c.MinBitrate > 0 {
+			return fmt.Errorf("%d active stream(s) at %s: %s", len(sessions), FormatBitrate(total), strings.Join(descriptions, "; "))
+		}
 		return fmt.Errorf("%d active stream(s): %s", len(sessions), strings.Join(descriptions, "; "))
 	}
 
@@ -69,5 +95,40 @@ func (c *Checker) Check(ctx context.Context) error {
 		}
 	}
 
+	return c.checkTasks(ctx)
+}
+
+// Counted returns the sessions that block shutdown: those that pass
+// Filter and PauseTimeout, or none if their combined bitrate is known and
+// below MinBitrate. total is their combined bitrate. Pause times are
+// tracked across calls, so call it once per poll.
+func (c *Checker) Counted(sessions []Session, now time.Time) (counted []Session, total int64) {
+	sessions = c.Filter.Apply(sessions)
+	c.pauses.Timeout = c.PauseTimeout
+	sessions = c.pauses.Apply(sessions, now)
+
+	total, unknown := TotalBitrate(sessions)
+	if c.MinBitrate > 0 && !unknown && total < c.MinBitrate {
+		// Only low-bitrate streams, fine to interrupt
+		return nil, total
+	}
+	return sessions, total
+}
+
+// checkTasks returns an error while a BlockTasks task is running.
+func (c *Checker) checkTasks(ctx context.Context) error {
+	if len(c.BlockTasks) == 0 {
+		return nil
+	}
+	tasks, err := c.Client.GetScheduledTasks(ctx)
+	if errors.Is(err, ErrUnauthorized) {
+		return fmt.Errorf("cannot list scheduled tasks: %w", err)
+	}
+	if err != nil {
+		return nil
+	}
+	if running := RunningTasks(tasks, c.BlockTasks); len(running) > 0 {
+		return fmt.Errorf("%s", DescribeTasks(running))
+	}
 	return nil
 }
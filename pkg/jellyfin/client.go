@@ -4,32 +4,56 @@ package jellyfin
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/filewatch"
 )
 
+// ErrUnauthorized is returned when Jellyfin rejects the API key
+var ErrUnauthorized = errors.New("jellyfin rejected API key")
+
 // Session represents a session from the Jellyfin API
 type Session struct {
-	ID             string          `json:"Id"`
-	UserID         string          `json:"UserId"`
-	UserName       string          `json:"UserName"`
-	Client         string          `json:"Client"`
-	DeviceName     string          `json:"DeviceName"`
-	NowPlayingItem *NowPlayingItem `json:"NowPlayingItem,omitempty"`
-	PlayState      *PlayState      `json:"PlayState,omitempty"`
+	ID              string           `json:"Id"`
+	UserID          string           `json:"UserId"`
+	UserName        string           `json:"UserName"`
+	Client          string           `json:"Client"`
+	DeviceName      string           `json:"DeviceName"`
+	RemoteEndPoint  string           `json:"RemoteEndPoint,omitempty"`
+	NowPlayingItem  *NowPlayingItem  `json:"NowPlayingItem,omitempty"`
+	PlayState       *PlayState       `json:"PlayState,omitempty"`
+	TranscodingInfo *TranscodingInfo `json:"TranscodingInfo,omitempty"`
 }
 
 // NowPlayingItem represents what's currently playing
 type NowPlayingItem struct {
 	Name       string `json:"Name"`
-	Type       string `json:"Type"` // Movie, Episode, etc.
+	Type       string `json:"Type"`                // Movie, Episode, Audio, etc.
+	MediaType  string `json:"MediaType,omitempty"` // Video, Audio, etc.
 	SeriesName string `json:"SeriesName,omitempty"`
+	// RunTimeTicks is the item duration in 100ns ticks
+	RunTimeTicks int64 `json:"RunTimeTicks,omitempty"`
+	// Bitrate is the source media bitrate in bits/s
+	Bitrate int64 `json:"Bitrate,omitempty"`
+}
+
+// TranscodingInfo is present when the server is transcoding the stream
+type TranscodingInfo struct {
+	Bitrate int64 `json:"Bitrate,omitempty"` // bits/s
 }
 
 // PlayState represents the current play state
 type PlayState struct {
 	IsPaused bool `json:"IsPaused"`
+	// PositionTicks is the playback position in 100ns ticks
+	PositionTicks int64 `json:"PositionTicks,omitempty"`
 }
 
 // Describe returns a human-readable description of the session
@@ -46,11 +70,80 @@ func (s *Session) Describe() string {
 	return fmt.Sprintf("%s watching %s on %s", s.UserName, item, s.DeviceName)
 }
 
+// Bitrate returns the stream bitrate in bits/s: the transcode bitrate if
+// transcoding, otherwise the source bitrate. Returns 0 if unknown.
+func (s *Session) Bitrate() int64 {
+	if s.TranscodingInfo != nil && s.TranscodingInfo.Bitrate > 0 {
+		return s.TranscodingInfo.Bitrate
+	}
+	if s.NowPlayingItem != nil {
+		return s.NowPlayingItem.Bitrate
+	}
+	return 0
+}
+
+// TotalBitrate sums the bitrate of sessions. unknown is true if any
+// session's bitrate could not be determined.
+func TotalBitrate(sessions []Session) (total int64, unknown bool) {
+	for _, s := range sessions {
+		b := s.Bitrate()
+		if b == 0 {
+			unknown = true
+		}
+		total += b
+	}
+	return total, unknown
+}
+
+// ParseBitrate parses a bitrate in bits/s with an optional k, M or G
+// suffix, e.g. "500k" or "8M".
+func ParseBitrate(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	mult := 1.0
+	if n := len(s); n > 0 {
+		switch s[n-1] {
+		case 'k', 'K':
+			mult, s = 1e3, s[:n-1]
+		case 'm', 'M':
+			mult, s = 1e6, s[:n-1]
+		case 'g', 'G':
+			mult, s = 1e9, s[:n-1]
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid bitrate %q", s)
+	}
+	return int64(v * mult), nil
+}
+
+// FormatBitrate renders bits/s as Mbps.
+func FormatBitrate(bps int64) string {
+	return fmt.Sprintf("%.1f Mbps", float64(bps)/1e6)
+}
+
+// Remaining returns the playback time left in the current item.
+// Returns false if the runtime or position is unknown.
+func (s *Session) Remaining() (time.Duration, bool) {
+	if s.NowPlayingItem == nil || s.NowPlayingItem.RunTimeTicks == 0 || s.PlayState == nil {
+		return 0, false
+	}
+	left := s.NowPlayingItem.RunTimeTicks - s.PlayState.PositionTicks
+	if left < 0 {
+		left = 0
+	}
+	// Jellyfin ticks are 100ns
+	return time.Duration(left) * 100, true
+}
+
 // Client handles communication with Jellyfin API
 type Client struct {
 	baseURL    string
-	apiKey     string
 	httpClient *http.Client
+
+	mu      sync.RWMutex
+	apiKey  string
+	keyFile string
 }
 
 // NewClient creates a new Jellyfin API client
@@ -64,28 +157,72 @@ func NewClient(baseURL, apiKey string, timeout time.Duration) *Client {
 	}
 }
 
-// GetActiveSessions returns all sessions that are currently playing content
-func (c *Client) GetActiveSessions(ctx context.Context) ([]Session, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/Sessions", nil)
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+// NewClientFromKeyFile creates a client that reads its API key from a file.
+// The file is re-read when Jellyfin rejects the key, and on every change
+// while WatchKeyFile is running.
+func NewClientFromKeyFile(baseURL, keyFile string, timeout time.Duration) (*Client, error) {
+	c := NewClient(baseURL, "", timeout)
+	c.keyFile = keyFile
+	if _, err := c.ReloadAPIKey(); err != nil {
+		return nil, err
 	}
+	return c, nil
+}
 
-	req.Header.Set("X-Emby-Token", c.apiKey)
+// SetAPIKey replaces the API key used for subsequent requests.
+func (c *Client) SetAPIKey(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.apiKey = key
+}
 
-	resp, err := c.httpClient.Do(req)
+func (c *Client) key() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.apiKey
+}
+
+// ReloadAPIKey re-reads the key file. changed reports whether the key
+// differs from the one in use. It is a no-op for clients without a key file.
+func (c *Client) ReloadAPIKey() (changed bool, err error) {
+	if c.keyFile == "" {
+		return false, nil
+	}
+	data, err := os.ReadFile(c.keyFile)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return false, fmt.Errorf("read API key file: %w", err)
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return false, fmt.Errorf("API key file %s is empty", c.keyFile)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	changed = key != c.apiKey
+	c.apiKey = key
+	return changed, nil
+}
+
+// WatchKeyFile reloads the API key whenever the key file changes, until
+// ctx is cancelled. Reload errors are passed to onError, if set, and the
+// previous key stays in use.
+func (c *Client) WatchKeyFile(ctx context.Context, onError func(error)) error {
+	if c.keyFile == "" {
+		return nil
 	}
+	return filewatch.Watch(ctx, c.keyFile, func() {
+		if _, err := c.ReloadAPIKey(); err != nil && onError != nil {
+			onError(err)
+		}
+	})
+}
 
+// GetActiveSessions returns all sessions that are currently playing content.
+func (c *Client) GetActiveSessions(ctx context.Context) ([]Session, error) {
 	var sessions []Session
-	if err := json.NewDecoder(resp.Body).Decode(&sessions); err != nil {
-		return nil, fmt.Errorf("decode response: %w", err)
+	if err := c.get(ctx, "/Sessions", &sessions); err != nil {
+		return nil, err
 	}
 
 	// Filter to only active sessions (those with NowPlayingItem)
@@ -99,6 +236,48 @@ func (c *Client) GetActiveSessions(ctx context.Context) ([]Session, error) {
 	return active, nil
 }
 
+// get decodes the JSON response for path into out. If the key is rejected
+// and comes from a file, the file is re-read and the request retried once
+// with the new key.
+func (c *Client) get(ctx context.Context, path string, out any) error {
+	err := c.getOnce(ctx, path, out)
+	if errors.Is(err, ErrUnauthorized) {
+		if changed, reloadErr := c.ReloadAPIKey(); reloadErr != nil {
+			return errors.Join(err, reloadErr)
+		} else if changed {
+			return c.getOnce(ctx, path, out)
+		}
+	}
+	return err
+}
+
+func (c *Client) getOnce(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("X-Emby-Token", c.key())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("unexpected status: %d: %w", resp.StatusCode, ErrUnauthorized)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
 // HasActiveStreams returns true if there are any active streaming sessions
 func (c *Client) HasActiveStreams(ctx context.Context) (bool, []Session, error) {
 	sessions, err := c.GetActiveSessions(ctx)
@@ -2,8 +2,11 @@ package jellyfin
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -179,6 +182,84 @@ func TestSession_Describe(t *testing.T) {
 	}
 }
 
+func TestSession_Remaining(t *testing.T) {
+	tests := []struct {
+		name    string
+		session Session
+		want    time.Duration
+		wantOK  bool
+	}{
+		{
+			name: "halfway through a movie",
+			session: Session{
+				NowPlayingItem: &NowPlayingItem{Name: "Avatar", RunTimeTicks: 2 * 60 * 60 * 10_000_000},
+				PlayState:      &PlayState{PositionTicks: 60 * 60 * 10_000_000},
+			},
+			want:   time.Hour,
+			wantOK: true,
+		},
+		{
+			name: "unknown runtime",
+			session: Session{
+				NowPlayingItem: &NowPlayingItem{Name: "Live TV"},
+				PlayState:      &PlayState{PositionTicks: 100},
+			},
+			wantOK: false,
+		},
+		{
+			name:    "idle session",
+			session: Session{},
+			wantOK:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.session.Remaining()
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Remaining() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTotalBitrate(t *testing.T) {
+	sessions := []Session{
+		{NowPlayingItem: &NowPlayingItem{Name: "4K remux", Bitrate: 80_000_000}},
+		{NowPlayingItem: &NowPlayingItem{Name: "Song", Bitrate: 320_000}, TranscodingInfo: &TranscodingInfo{Bitrate: 128_000}},
+	}
+	total, unknown := TotalBitrate(sessions)
+	if total != 80_128_000 || unknown {
+		t.Errorf("TotalBitrate = %d, %v", total, unknown)
+	}
+
+	sessions = append(sessions, Session{NowPlayingItem: &NowPlayingItem{Name: "Live TV"}})
+	if _, unknown := TotalBitrate(sessions); !unknown {
+		t.Error("expected unknown bitrate to be reported")
+	}
+}
+
+func TestParseBitrate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "8000000", want: 8_000_000},
+		{in: "500k", want: 500_000},
+		{in: "8M", want: 8_000_000},
+		{in: "1.5G", want: 1_500_000_000},
+		{in: "fast", wantErr: true},
+		{in: "-1M", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseBitrate(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseBitrate(%q) = %d, %v", tt.in, got, err)
+		}
+	}
+}
+
 func TestClient_HasActiveStreams(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -219,6 +300,56 @@ func TestClient_HasActiveStreams(t *testing.T) {
 	}
 }
 
+func TestClient_KeyFileRotation(t *testing.T) {
+	validKey := "old-key"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Emby-Token") != validKey {
+			w.WriteHeader(401)
+			return
+		}
+		w.Write([]byte(`[{"UserName": "dad", "NowPlayingItem": {"Name": "Movie"}}]`))
+	}))
+	defer server.Close()
+
+	keyFile := filepath.Join(t.TempDir(), "api-key")
+	if err := os.WriteFile(keyFile, []byte("old-key\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewClientFromKeyFile(server.URL, keyFile, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetActiveSessions(context.Background()); err != nil {
+		t.Fatalf("initial key: %v", err)
+	}
+
+	// Key rotated on the server and in the file: picked up on 401
+	validKey = "new-key"
+	if err := os.WriteFile(keyFile, []byte("new-key\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := client.GetActiveSessions(context.Background())
+	if err != nil {
+		t.Fatalf("rotated key: %v", err)
+	}
+	if len(sessions) != 1 {
+		t.Errorf("got %d sessions, want 1", len(sessions))
+	}
+
+	// Key revoked without a replacement: unauthorized, not "no streams"
+	validKey = "newer-key"
+	_, err = client.GetActiveSessions(context.Background())
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("err = %v, want ErrUnauthorized", err)
+	}
+
+	checker := NewChecker(client, 0)
+	if err := checker.Check(context.Background()); err == nil {
+		t.Error("checker reported safe with a rejected API key")
+	}
+}
+
 func contains(s, substr string) bool {
 	for i := 0; i <= len(s)-len(substr); i++ {
 		if s[i:i+len(substr)] == substr {
@@ -0,0 +1,77 @@
+package jellyfin
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// CheckerFromEnv returns a Checker for client configured from the
+// JELLYFIN_* environment variables, so the sidecar and time-to-safe count
+// the same sessions:
+//
+//	JELLYFIN_GRACE_PERIOD    keep blocking this long after the last stream (default 5m)
+//	JELLYFIN_BLOCK_TYPES     only these media types block, e.g. "Movie,Episode"
+//	JELLYFIN_IGNORE_TYPES    media types that never block
+//	JELLYFIN_BLOCK_USERS     and IGNORE_USERS, _CLIENTS and _DEVICES likewise
+//	JELLYFIN_PAUSE_TIMEOUT   stop counting sessions paused longer than this
+//	JELLYFIN_BLOCK_TASKS     scheduled tasks that block; "default" is DefaultBlockTasks
+//	JELLYFIN_IGNORE_LOCAL    "true" ignores playback from the server itself
+//	JELLYFIN_MIN_BITRATE     ignore streams below this combined bitrate, e.g. "20M"
+func CheckerFromEnv(client *Client) (*Checker, error) {
+	c := NewChecker(client, duration("JELLYFIN_GRACE_PERIOD", 5*time.Minute))
+	c.PauseTimeout = duration("JELLYFIN_PAUSE_TIMEOUT", 0)
+
+	c.Filter = Filter{
+		BlockTypes:    splitList(os.Getenv("JELLYFIN_BLOCK_TYPES")),
+		IgnoreTypes:   splitList(os.Getenv("JELLYFIN_IGNORE_TYPES")),
+		BlockUsers:    splitList(os.Getenv("JELLYFIN_BLOCK_USERS")),
+		IgnoreUsers:   splitList(os.Getenv("JELLYFIN_IGNORE_USERS")),
+		BlockClients:  splitList(os.Getenv("JELLYFIN_BLOCK_CLIENTS")),
+		IgnoreClients: splitList(os.Getenv("JELLYFIN_IGNORE_CLIENTS")),
+		BlockDevices:  splitList(os.Getenv("JELLYFIN_BLOCK_DEVICES")),
+		IgnoreDevices: splitList(os.Getenv("JELLYFIN_IGNORE_DEVICES")),
+	}
+	if os.Getenv("JELLYFIN_IGNORE_LOCAL") == "true" {
+		c.Filter.IgnoreLocal = true
+		c.Filter.LocalAddrs = LocalAddrs()
+	}
+
+	switch v := os.Getenv("JELLYFIN_BLOCK_TASKS"); v {
+	case "":
+	case "default":
+		c.BlockTasks = DefaultBlockTasks
+	default:
+		c.BlockTasks = splitList(v)
+	}
+
+	if v := os.Getenv("JELLYFIN_MIN_BITRATE"); v != "" {
+		b, err := ParseBitrate(v)
+		if err != nil {
+			return nil, fmt.Errorf("JELLYFIN_MIN_BITRATE: %w", err)
+		}
+		c.MinBitrate = b
+	}
+	return c, nil
+}
+
+// duration reads key as a duration, or fallback if it is unset or doesn't
+// parse, as sidecarmain.Duration does
+func duration(key string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return d
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
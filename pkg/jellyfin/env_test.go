@@ -0,0 +1,41 @@
+package jellyfin
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckerFromEnv(t *testing.T) {
+	t.Setenv("JELLYFIN_GRACE_PERIOD", "")
+	t.Setenv("JELLYFIN_IGNORE_TYPES", "Audio, AudioBook")
+	t.Setenv("JELLYFIN_BLOCK_USERS", "bob")
+	t.Setenv("JELLYFIN_PAUSE_TIMEOUT", "30m")
+	t.Setenv("JELLYFIN_BLOCK_TASKS", "default")
+	t.Setenv("JELLYFIN_MIN_BITRATE", "20M")
+
+	c, err := CheckerFromEnv(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.GracePeriod != 5*time.Minute {
+		t.Errorf("GracePeriod = %v, want the 5m default", c.GracePeriod)
+	}
+	if want := []string{"Audio", "AudioBook"}; !reflect.DeepEqual(c.Filter.IgnoreTypes, want) {
+		t.Errorf("IgnoreTypes = %q, want %q", c.Filter.IgnoreTypes, want)
+	}
+	if want := []string{"bob"}; !reflect.DeepEqual(c.Filter.BlockUsers, want) {
+		t.Errorf("BlockUsers = %q, want %q", c.Filter.BlockUsers, want)
+	}
+	if c.PauseTimeout != 30*time.Minute || c.MinBitrate != 20e6 {
+		t.Errorf("PauseTimeout = %v, MinBitrate = %d", c.PauseTimeout, c.MinBitrate)
+	}
+	if !reflect.DeepEqual(c.BlockTasks, DefaultBlockTasks) {
+		t.Errorf("BlockTasks = %q, want the defaults", c.BlockTasks)
+	}
+
+	t.Setenv("JELLYFIN_MIN_BITRATE", "fast")
+	if _, err := CheckerFromEnv(nil); err == nil {
+		t.Error("CheckerFromEnv accepted an invalid JELLYFIN_MIN_BITRATE")
+	}
+}
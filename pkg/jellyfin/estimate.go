@@ -0,0 +1,50 @@
+package jellyfin
+
+import (
+	"sort"
+	"time"
+)
+
+// Wait is how long a session keeps the Checker blocking
+type Wait struct {
+	Session Session
+	// Wait is the remaining playback plus the grace period
+	Wait time.Duration
+	// Known is false if the remaining playback is unknown
+	Known bool
+}
+
+// Waits estimates how long each session returned by Counted keeps Check
+// blocking: its remaining playback, or at most PauseTimeout while paused,
+// plus GracePeriod. They are sorted by Wait, unknown ones last.
+//
+// With MinBitrate, Check stops blocking once the streams still playing fall
+// below it, so only the sessions that have to end first are returned. A
+// session of unknown bitrate keeps every session counting, as in Counted,
+// so then all are returned.
+func (c *Checker) Waits(counted []Session) []Wait {
+	var waits []Wait
+	for _, s := range counted {
+		remaining, ok := s.Remaining()
+		if s.PlayState != nil && s.PlayState.IsPaused && c.PauseTimeout > 0 && (!ok || remaining > c.PauseTimeout) {
+			// It may have been paused for a while already, so this is the
+			// longest it can still count
+			remaining, ok = c.PauseTimeout, true
+		}
+		waits = append(waits, Wait{Session: s, Wait: remaining + c.GracePeriod, Known: ok})
+	}
+	sort.SliceStable(waits, func(i, j int) bool {
+		return waits[i].Known && (!waits[j].Known || waits[i].Wait < waits[j].Wait)
+	})
+
+	total, unknown := TotalBitrate(counted)
+	if c.MinBitrate <= 0 || unknown {
+		return waits
+	}
+	for i, w := range waits {
+		if total -= w.Session.Bitrate(); total < c.MinBitrate {
+			return waits[:i+1]
+		}
+	}
+	return waits
+}
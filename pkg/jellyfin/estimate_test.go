@@ -0,0 +1,44 @@
+package jellyfin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChecker_Waits(t *testing.T) {
+	// stream runs for the given time at the given bitrate; 0 is unknown
+	stream := func(id string, left time.Duration, bitrate int64) Session {
+		return Session{
+			ID:             id,
+			NowPlayingItem: &NowPlayingItem{Type: "Movie", RunTimeTicks: int64(left / 100), Bitrate: bitrate},
+			PlayState:      &PlayState{},
+		}
+	}
+	ids := func(waits []Wait) string {
+		var s string
+		for _, w := range waits {
+			s += w.Session.ID
+		}
+		return s
+	}
+
+	c := &Checker{GracePeriod: 5 * time.Minute, MinBitrate: 20e6}
+	short, long := stream("a", 10*time.Minute, 8e6), stream("b", time.Hour, 15e6)
+	waits := c.Waits([]Session{long, short})
+	if ids(waits) != "a" || waits[0].Wait != 15*time.Minute {
+		t.Errorf("Waits = %+v, want only a, ending in 15m", waits)
+	}
+
+	// Unknown bitrate: the sidecar counts every session while it plays
+	unknown := stream("c", 30*time.Minute, 0)
+	if got := ids(c.Waits([]Session{long, unknown, short})); got != "acb" {
+		t.Errorf("Waits with an unknown bitrate = %s, want acb", got)
+	}
+
+	paused := stream("d", 2*time.Hour, 0)
+	paused.PlayState.IsPaused = true
+	c = &Checker{PauseTimeout: 30 * time.Minute}
+	if waits := c.Waits([]Session{paused}); len(waits) != 1 || waits[0].Wait != 30*time.Minute || !waits[0].Known {
+		t.Errorf("Waits paused = %+v, want the 30m pause timeout", waits)
+	}
+}
@@ -0,0 +1,126 @@
+package jellyfin
+
+import (
+	"net"
+	"strings"
+)
+
+// Filter selects which active sessions count towards blocking shutdown.
+// Types are matched case-insensitively against both NowPlayingItem.Type
+// (Movie, Episode, Audio, AudioBook, ...) and NowPlayingItem.MediaType
+// (Video, Audio, ...). Users, clients and devices are matched
+// case-insensitively against UserName, Client (e.g. "Jellyfin Web", "DLNA")
+// and DeviceName.
+//
+// For each list pair, the Block list (if non-empty) restricts which
+// sessions count and the Ignore list excludes sessions; Ignore wins.
+type Filter struct {
+	// BlockTypes, if non-empty, only counts items of these types
+	BlockTypes []string
+	// IgnoreTypes never counts items of these types
+	IgnoreTypes []string
+
+	BlockUsers    []string
+	IgnoreUsers   []string
+	BlockClients  []string
+	IgnoreClients []string
+	BlockDevices  []string
+	IgnoreDevices []string
+
+	// IgnoreLocal never counts sessions from loopback or LocalAddrs,
+	// e.g. test playback on the server itself
+	IgnoreLocal bool
+	// LocalAddrs are the server's own IP addresses (see LocalAddrs)
+	LocalAddrs []string
+}
+
+// Match reports whether the session should count as blocking.
+func (f Filter) Match(s Session) bool {
+	if s.NowPlayingItem == nil {
+		return false
+	}
+	item := s.NowPlayingItem
+	if !allowed(f.BlockTypes, f.IgnoreTypes, item.Type, item.MediaType) ||
+		!allowed(f.BlockUsers, f.IgnoreUsers, s.UserName) ||
+		!allowed(f.BlockClients, f.IgnoreClients, s.Client) ||
+		!allowed(f.BlockDevices, f.IgnoreDevices, s.DeviceName) {
+		return false
+	}
+	if f.IgnoreLocal && f.isLocal(s.RemoteEndPoint) {
+		return false
+	}
+	return true
+}
+
+// Apply returns the sessions that match the filter.
+func (f Filter) Apply(sessions []Session) []Session {
+	var out []Session
+	for _, s := range sessions {
+		if f.Match(s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// allowed applies a block/ignore list pair to a session's values.
+func allowed(block, ignore []string, values ...string) bool {
+	if matchesAny(ignore, values...) {
+		return false
+	}
+	if len(block) > 0 && !matchesAny(block, values...) {
+		return false
+	}
+	return true
+}
+
+func (f Filter) isLocal(endpoint string) bool {
+	if endpoint == "" {
+		return false
+	}
+	// RemoteEndPoint is usually a bare IP, but may carry a port
+	host := endpoint
+	if h, _, err := net.SplitHostPort(endpoint); err == nil {
+		host = h
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() {
+		return true
+	}
+	for _, addr := range f.LocalAddrs {
+		if local := net.ParseIP(addr); local != nil && local.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalAddrs returns the IP addresses of this host's interfaces, for
+// Filter.LocalAddrs.
+func LocalAddrs() []string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok {
+			out = append(out, ipnet.IP.String())
+		}
+	}
+	return out
+}
+
+func matchesAny(list []string, values ...string) bool {
+	for _, want := range list {
+		for _, v := range values {
+			if v != "" && strings.EqualFold(want, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package jellyfin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFilter_Match(t *testing.T) {
+	movie := Session{UserName: "bob", Client: "Jellyfin Web", DeviceName: "Living Room TV", RemoteEndPoint: "192.168.1.23", NowPlayingItem: &NowPlayingItem{Name: "Avatar", Type: "Movie", MediaType: "Video"}}
+	song := Session{UserName: "alice", NowPlayingItem: &NowPlayingItem{Name: "Song", Type: "Audio", MediaType: "Audio"}}
+	book := Session{UserName: "carol", NowPlayingItem: &NowPlayingItem{Name: "Dune", Type: "AudioBook", MediaType: "Audio"}}
+	idle := Session{UserName: "dave"}
+	dlna := Session{UserName: "alice", Client: "DLNA", DeviceName: "Kitchen Speaker", NowPlayingItem: &NowPlayingItem{Type: "Audio"}}
+	local := Session{UserName: "admin", RemoteEndPoint: "127.0.0.1", NowPlayingItem: &NowPlayingItem{Type: "Movie"}}
+	server := Session{UserName: "admin", RemoteEndPoint: "192.168.1.10:52100", NowPlayingItem: &NowPlayingItem{Type: "Movie"}}
+
+	tests := []struct {
+		name    string
+		filter  Filter
+		session Session
+		want    bool
+	}{
+		{name: "no filter", session: song, want: true},
+		{name: "idle never matches", session: idle, want: false},
+		{name: "ignore audio media type", filter: Filter{IgnoreTypes: []string{"audio"}}, session: book, want: false},
+		{name: "ignore audio keeps video", filter: Filter{IgnoreTypes: []string{"Audio"}}, session: movie, want: true},
+		{name: "block only movies", filter: Filter{BlockTypes: []string{"Movie", "Episode"}}, session: movie, want: true},
+		{name: "block only movies skips audio", filter: Filter{BlockTypes: []string{"Movie", "Episode"}}, session: song, want: false},
+		{name: "ignore wins over block", filter: Filter{BlockTypes: []string{"Audio"}, IgnoreTypes: []string{"AudioBook"}}, session: book, want: false},
+		{name: "ignore user", filter: Filter{IgnoreUsers: []string{"Bob"}}, session: movie, want: false},
+		{name: "block only user", filter: Filter{BlockUsers: []string{"alice"}}, session: movie, want: false},
+		{name: "block only user matches", filter: Filter{BlockUsers: []string{"alice"}}, session: song, want: true},
+		{name: "ignore dlna client", filter: Filter{IgnoreClients: []string{"dlna"}}, session: dlna, want: false},
+		{name: "ignore client keeps others", filter: Filter{IgnoreClients: []string{"DLNA"}}, session: movie, want: true},
+		{name: "ignore device", filter: Filter{IgnoreDevices: []string{"Kitchen Speaker"}}, session: dlna, want: false},
+		{name: "block only device", filter: Filter{BlockDevices: []string{"Living Room TV"}}, session: movie, want: true},
+		{name: "ignore local loopback", filter: Filter{IgnoreLocal: true}, session: local, want: false},
+		{name: "ignore local server address", filter: Filter{IgnoreLocal: true, LocalAddrs: []string{"192.168.1.10"}}, session: server, want: false},
+		{name: "ignore local keeps remote", filter: Filter{IgnoreLocal: true, LocalAddrs: []string{"192.168.1.10"}}, session: movie, want: true},
+		{name: "local not ignored by default", session: local, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(tt.session); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_Apply(t *testing.T) {
+	sessions := []Session{
+		{NowPlayingItem: &NowPlayingItem{Type: "Episode", MediaType: "Video"}},
+		{NowPlayingItem: &NowPlayingItem{Type: "Audio", MediaType: "Audio"}},
+	}
+	got := Filter{IgnoreTypes: []string{"Audio"}}.Apply(sessions)
+	if len(got) != 1 || got[0].NowPlayingItem.Type != "Episode" {
+		t.Errorf("Apply() = %+v", got)
+	}
+}
+
+func TestChecker_Counted(t *testing.T) {
+	movie := Session{ID: "1", NowPlayingItem: &NowPlayingItem{Type: "Movie", Bitrate: 15e6}, PlayState: &PlayState{}}
+	song := Session{ID: "2", NowPlayingItem: &NowPlayingItem{Type: "Audio", Bitrate: 320e3}, PlayState: &PlayState{}}
+	paused := Session{ID: "3", NowPlayingItem: &NowPlayingItem{Type: "Episode", Bitrate: 8e6}, PlayState: &PlayState{IsPaused: true}}
+
+	c := &Checker{Filter: Filter{IgnoreTypes: []string{"Audio"}}, PauseTimeout: time.Hour}
+	start := time.Now()
+	if got, total := c.Counted([]Session{movie, song, paused}, start); len(got) != 2 || total != 23e6 {
+		t.Errorf("Counted = %d sessions at %d bit/s, want the movie and the paused episode", len(got), total)
+	}
+	if got, _ := c.Counted([]Session{movie, paused}, start.Add(2*time.Hour)); len(got) != 1 || got[0].ID != "1" {
+		t.Errorf("Counted after the pause timeout = %+v, want only the movie", got)
+	}
+
+	c.MinBitrate = 20e6
+	if got, _ := c.Counted([]Session{movie}, start); got != nil {
+		t.Errorf("Counted below MinBitrate = %+v, want none", got)
+	}
+}
@@ -0,0 +1,42 @@
+package jellyfin
+
+import (
+	"context"
+	"time"
+)
+
+// Timer is a scheduled Live TV recording
+type Timer struct {
+	ID          string    `json:"Id"`
+	Name        string    `json:"Name"`
+	ChannelName string    `json:"ChannelName"`
+	StartDate   time.Time `json:"StartDate"`
+	EndDate     time.Time `json:"EndDate"`
+	// Status is New until the recording starts, then InProgress, and
+	// Completed, Cancelled or Error afterwards
+	Status             string `json:"Status"`
+	PrePaddingSeconds  int    `json:"PrePaddingSeconds"`
+	PostPaddingSeconds int    `json:"PostPaddingSeconds"`
+}
+
+type timersResponse struct {
+	Items []Timer `json:"Items"`
+}
+
+// Pending reports whether the timer is still to record or recording.
+func (t *Timer) Pending() bool {
+	switch t.Status {
+	case "Completed", "Cancelled", "Error":
+		return false
+	}
+	return true
+}
+
+// GetTimers returns the scheduled Live TV recordings.
+func (c *Client) GetTimers(ctx context.Context) ([]Timer, error) {
+	var resp timersResponse
+	if err := c.get(ctx, "/LiveTv/Timers", &resp); err != nil {
+		return nil, err
+	}
+	return resp.Items, nil
+}
@@ -0,0 +1,56 @@
+package jellyfin
+
+import (
+	"sync"
+	"time"
+)
+
+// PauseTracker remembers when each session was first seen paused, so a
+// movie left paused for hours stops holding the inhibitor.
+type PauseTracker struct {
+	// Timeout is how long a session may stay paused and still count;
+	// 0 counts paused sessions indefinitely
+	Timeout time.Duration
+
+	mu          sync.Mutex
+	pausedSince map[string]time.Time
+}
+
+// Apply returns the sessions that still count: playing, or paused for no
+// longer than Timeout. Call it on every poll; a session that resumes or
+// ends is forgotten, and pausing again starts a new timeout.
+func (p *PauseTracker) Apply(sessions []Session, now time.Time) []Session {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	seen := make(map[string]time.Time)
+	var out []Session
+	for _, s := range sessions {
+		if s.PlayState == nil || !s.PlayState.IsPaused {
+			out = append(out, s)
+			continue
+		}
+
+		key := sessionKey(s)
+		since, ok := p.pausedSince[key]
+		if !ok {
+			since = now
+		}
+		seen[key] = since
+
+		if p.Timeout <= 0 || now.Sub(since) <= p.Timeout {
+			out = append(out, s)
+		}
+	}
+
+	p.pausedSince = seen
+	return out
+}
+
+// sessionKey identifies a session across polls.
+func sessionKey(s Session) string {
+	if s.ID != "" {
+		return s.ID
+	}
+	return s.UserName + "/" + s.DeviceName
+}
@@ -0,0 +1,61 @@
+package jellyfin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPauseTracker_Apply(t *testing.T) {
+	playing := func(id string) Session {
+		return Session{ID: id, NowPlayingItem: &NowPlayingItem{Name: "Movie"}, PlayState: &PlayState{}}
+	}
+	paused := func(id string) Session {
+		s := playing(id)
+		s.PlayState.IsPaused = true
+		return s
+	}
+
+	start := time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC)
+	tracker := &PauseTracker{Timeout: 30 * time.Minute}
+
+	steps := []struct {
+		at       time.Duration
+		sessions []Session
+		want     []string
+	}{
+		{0, []Session{playing("a"), paused("b")}, []string{"a", "b"}},
+		{20 * time.Minute, []Session{playing("a"), paused("b")}, []string{"a", "b"}},
+		{31 * time.Minute, []Session{playing("a"), paused("b")}, []string{"a"}},
+		{3 * time.Hour, []Session{paused("a"), paused("b")}, []string{"a"}},
+		// b resumes briefly, then pauses again: a fresh timeout
+		{3*time.Hour + time.Minute, []Session{paused("a"), playing("b")}, []string{"a", "b"}},
+		{3*time.Hour + 2*time.Minute, []Session{paused("a"), paused("b")}, []string{"a", "b"}},
+		{3*time.Hour + 40*time.Minute, []Session{paused("a"), paused("b")}, nil},
+	}
+
+	for i, step := range steps {
+		got := tracker.Apply(step.sessions, start.Add(step.at))
+		var ids []string
+		for _, s := range got {
+			ids = append(ids, s.ID)
+		}
+		if len(ids) != len(step.want) {
+			t.Fatalf("step %d: got %v, want %v", i, ids, step.want)
+		}
+		for j := range ids {
+			if ids[j] != step.want[j] {
+				t.Errorf("step %d: got %v, want %v", i, ids, step.want)
+			}
+		}
+	}
+}
+
+func TestPauseTracker_NoTimeout(t *testing.T) {
+	tracker := &PauseTracker{}
+	s := Session{ID: "a", NowPlayingItem: &NowPlayingItem{}, PlayState: &PlayState{IsPaused: true}}
+	start := time.Now()
+	tracker.Apply([]Session{s}, start)
+	if got := tracker.Apply([]Session{s}, start.Add(24*time.Hour)); len(got) != 1 {
+		t.Errorf("paused session dropped with no timeout")
+	}
+}
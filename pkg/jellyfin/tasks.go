@@ -0,0 +1,88 @@
+package jellyfin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ScheduledTask represents a server task from the Jellyfin Tasks API, such
+// as a library scan or a backup.
+type ScheduledTask struct {
+	ID       string `json:"Id"`
+	Key      string `json:"Key"` // e.g. RefreshLibrary
+	Name     string `json:"Name"`
+	Category string `json:"Category"`
+	State    string `json:"State"` // Idle, Running or Cancelling
+	// CurrentProgressPercentage is only set while running
+	CurrentProgressPercentage *float64 `json:"CurrentProgressPercentage,omitempty"`
+}
+
+// DefaultBlockTasks are the tasks worth waiting for: interrupting a library
+// scan or people metadata refresh leaves it half done, and a half-written
+// backup is useless.
+var DefaultBlockTasks = []string{"RefreshLibrary", "RefreshPeople", "Backup"}
+
+// Running reports whether the task is in progress.
+func (t *ScheduledTask) Running() bool {
+	return t.State == "Running" || t.State == "Cancelling"
+}
+
+// Describe returns a human-readable description of the task
+func (t *ScheduledTask) Describe() string {
+	if t.CurrentProgressPercentage != nil {
+		return fmt.Sprintf("%s running (%.0f%%)", t.Name, *t.CurrentProgressPercentage)
+	}
+	return fmt.Sprintf("%s running", t.Name)
+}
+
+// GetScheduledTasks returns all scheduled tasks, running or not.
+func (c *Client) GetScheduledTasks(ctx context.Context) ([]ScheduledTask, error) {
+	var tasks []ScheduledTask
+	if err := c.get(ctx, "/ScheduledTasks?isHidden=false", &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// RunningTasks returns the running tasks whose key, name or category
+// matches one of match, case-insensitively. A match containing "backup"
+// also catches plugin backup tasks named e.g. "Backup Jellyfin Data".
+func RunningTasks(tasks []ScheduledTask, match []string) []ScheduledTask {
+	var out []ScheduledTask
+	for _, t := range tasks {
+		if !t.Running() {
+			continue
+		}
+		if matchesAny(match, t.Key, t.Name, t.Category) || matchesWord(match, t.Name) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// DescribeTasks summarizes running tasks for an inhibitor reason.
+func DescribeTasks(tasks []ScheduledTask) string {
+	parts := make([]string, len(tasks))
+	for i := range tasks {
+		parts[i] = tasks[i].Describe()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// matchesWord reports whether any single-word entry of list appears as a
+// word in name, so "Backup" matches "Backup Jellyfin Data".
+func matchesWord(list []string, name string) bool {
+	words := strings.Fields(name)
+	for _, want := range list {
+		if strings.ContainsAny(want, " \t") {
+			continue
+		}
+		for _, w := range words {
+			if strings.EqualFold(want, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
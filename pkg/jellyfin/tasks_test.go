@@ -0,0 +1,79 @@
+package jellyfin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const tasksResponse = `[
+	{"Id": "1", "Key": "RefreshLibrary", "Name": "Scan Media Library", "Category": "Library", "State": "Running", "CurrentProgressPercentage": 42.4},
+	{"Id": "2", "Key": "RefreshPeople", "Name": "Refresh People", "Category": "Library", "State": "Idle"},
+	{"Id": "3", "Key": "", "Name": "Backup Jellyfin Data", "Category": "Backup", "State": "Idle"},
+	{"Id": "4", "Key": "TrickplayImages", "Name": "Generate Trickplay Images", "Category": "Library", "State": "Running"}
+]`
+
+func TestRunningTasks(t *testing.T) {
+	tests := []struct {
+		name  string
+		match []string
+		state string // state of the backup task
+		want  string
+	}{
+		{name: "no match list", match: nil, want: ""},
+		{name: "library scan by key", match: []string{"refreshlibrary"}, want: "Scan Media Library running (42%)"},
+		{name: "by name", match: []string{"Generate Trickplay Images"}, want: "Generate Trickplay Images running"},
+		{name: "by category", match: []string{"Library"}, want: "Scan Media Library running (42%); Generate Trickplay Images running"},
+		{name: "idle task ignored", match: []string{"RefreshPeople"}, want: ""},
+		{name: "backup by word", match: DefaultBlockTasks, state: "Running", want: "Scan Media Library running (42%); Backup Jellyfin Data running"},
+		{name: "cancelling still running", match: []string{"Backup"}, state: "Cancelling", want: "Backup Jellyfin Data running"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/ScheduledTasks" {
+					t.Errorf("unexpected path: %s", r.URL.Path)
+				}
+				w.Write([]byte(tasksResponse))
+			}))
+			defer server.Close()
+
+			tasks, err := NewClient(server.URL, "test-key", 5*time.Second).GetScheduledTasks(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.state != "" {
+				tasks[2].State = tt.state
+			}
+
+			if got := DescribeTasks(RunningTasks(tasks, tt.match)); got != tt.want {
+				t.Errorf("running = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChecker_BlockTasks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/Sessions":
+			w.Write([]byte(`[]`))
+		case "/ScheduledTasks":
+			w.Write([]byte(tasksResponse))
+		}
+	}))
+	defer server.Close()
+
+	checker := NewChecker(NewClient(server.URL, "test-key", 5*time.Second), 0)
+	if err := checker.Check(context.Background()); err != nil {
+		t.Errorf("tasks not configured, got %v", err)
+	}
+
+	checker.BlockTasks = DefaultBlockTasks
+	if err := checker.Check(context.Background()); err == nil {
+		t.Error("expected library scan to block")
+	}
+}
@@ -0,0 +1,186 @@
+// Package jsoncheck is a checker for services without a client of their
+// own: it fetches a JSON document, selects values from it with a JSONPath
+// expression and compares them against a threshold or an expected value.
+// Run as a sidecar it blocks while the comparison holds, e.g. while a
+// Sonarr-style /api/v3/queue has records downloading; as a health check it
+// fails unless the comparison holds, e.g. unless /api/status says "ok".
+package jsoncheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrUnauthorized is returned when the service rejects the credentials
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Checker implements check.Checker for a JSON endpoint.
+// Returns an error while a selected value meets the condition.
+type Checker struct {
+	// Count compares the number of selected values against the
+	// condition instead of each value, e.g. "$.records[?(@.status ==
+	// 'downloading')]" with ">0"
+	Count bool
+
+	name       string
+	url        string
+	header     http.Header
+	path       *Path
+	cond       Condition
+	httpClient *http.Client
+}
+
+// NewChecker creates a checker that GETs url with header, selects expr
+// from the response and compares the values against cond. expr is a
+// JSONPath expression (see Path) and cond is a Condition, e.g. "==idle"
+// or ">0".
+func NewChecker(name, url, expr, cond string, header http.Header, timeout time.Duration) (*Checker, error) {
+	path, err := Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	c, err := ParseCondition(cond)
+	if err != nil {
+		return nil, err
+	}
+	return &Checker{
+		name:   name,
+		url:    url,
+		header: header,
+		path:   path,
+		cond:   c,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}, nil
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return c.name
+}
+
+// Check returns nil if no selected value meets the condition, error
+// otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	reasons, err := c.Activity(ctx)
+	if err != nil {
+		return fmt.Errorf("%s check failed: %w", c.name, err)
+	}
+	if len(reasons) > 0 {
+		return errors.New(strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// Activity describes each selected value that meets the condition, as its
+// path and value, e.g. "$.queues.thumbnails.active = 3", or with Count
+// the number selected, e.g. "count($.records[*]) = 2".
+func (c *Checker) Activity(ctx context.Context) ([]string, error) {
+	matches, err := c.Select(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Count {
+		if c.cond.Match(json.Number(fmt.Sprint(len(matches)))) {
+			return []string{c.count(matches)}, nil
+		}
+		return nil, nil
+	}
+
+	var reasons []string
+	for _, m := range matches {
+		if c.cond.Match(m.Value) {
+			reasons = append(reasons, describe(m))
+		}
+	}
+	return reasons, nil
+}
+
+// Health returns nil if the condition holds: for every selected value, of
+// which there must be at least one, or with Count for their number.
+func (c *Checker) Health(ctx context.Context) error {
+	matches, err := c.Select(ctx)
+	if err != nil {
+		return err
+	}
+
+	if c.Count {
+		if !c.cond.Match(json.Number(fmt.Sprint(len(matches)))) {
+			return fmt.Errorf("%s, want %s", c.count(matches), c.cond)
+		}
+		return nil
+	}
+
+	if len(matches) == 0 {
+		return fmt.Errorf("%s matched nothing", c.path)
+	}
+	var problems []string
+	for _, m := range matches {
+		if !c.cond.Match(m.Value) {
+			problems = append(problems, fmt.Sprintf("%s, want %s", describe(m), c.cond))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Select fetches the document and returns the values the expression
+// selects from it.
+func (c *Checker) Select(ctx context.Context) ([]Match, error) {
+	doc, err := c.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.path.Select(doc), nil
+}
+
+func (c *Checker) count(matches []Match) string {
+	return fmt.Sprintf("count(%s) = %d", c.path, len(matches))
+}
+
+func describe(m Match) string {
+	return fmt.Sprintf("%s = %s", m.Path, Format(m.Value))
+}
+
+func (c *Checker) fetch(ctx context.Context) (any, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	for k, vs := range c.header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("unexpected status: %d: %w", resp.StatusCode, ErrUnauthorized)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	var doc any
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return doc, nil
+}
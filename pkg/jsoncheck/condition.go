@@ -0,0 +1,121 @@
+package jsoncheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// operators in the order they're tried, longest first
+var operators = []string{"==", "!=", ">=", "<=", ">", "<"}
+
+// Condition is a comparison against a selected value: an expected value
+// ("==indexing", "!=idle") or a threshold (">0", ">=10", "<1", "<=5").
+// Numbers compare as numbers and anything else as text. The zero
+// Condition is true for true, non-zero numbers and non-empty strings,
+// arrays and objects.
+type Condition struct {
+	op    string // "", "==", "!=", ">", ">=", "<" or "<="
+	value string
+}
+
+// ParseCondition parses an operator and a value, e.g. ">=10"; an empty
+// string is the zero Condition.
+func ParseCondition(s string) (Condition, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Condition{}, nil
+	}
+	for _, op := range operators {
+		if v, ok := strings.CutPrefix(s, op); ok {
+			v = strings.TrimSpace(v)
+			if op != "==" && op != "!=" {
+				if _, err := strconv.ParseFloat(v, 64); err != nil {
+					return Condition{}, fmt.Errorf("condition %q: %s needs a number", s, op)
+				}
+			}
+			return Condition{op: op, value: v}, nil
+		}
+	}
+	return Condition{}, fmt.Errorf("condition %q: want ==, !=, >, >=, < or <= and a value", s)
+}
+
+func (c Condition) String() string {
+	if c.op == "" {
+		return "truthy"
+	}
+	return c.op + " " + c.value
+}
+
+// Match reports whether v meets the condition.
+func (c Condition) Match(v any) bool {
+	if c.op == "" {
+		return truthy(v)
+	}
+
+	text := Format(v)
+	n, numErr := strconv.ParseFloat(text, 64)
+	want, wantErr := strconv.ParseFloat(c.value, 64)
+	numeric := numErr == nil && wantErr == nil
+
+	switch c.op {
+	case "==":
+		if numeric {
+			return n == want
+		}
+		return text == c.value
+	case "!=":
+		if numeric {
+			return n != want
+		}
+		return text != c.value
+	}
+	if numErr != nil {
+		return false
+	}
+	switch c.op {
+	case ">":
+		return n > want
+	case ">=":
+		return n >= want
+	case "<":
+		return n < want
+	case "<=":
+		return n <= want
+	}
+	return false
+}
+
+func truthy(v any) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case json.Number:
+		f, err := v.Float64()
+		return err == nil && f != 0
+	case string:
+		return v != ""
+	case []any:
+		return len(v) > 0
+	case map[string]any:
+		return len(v) > 0
+	}
+	return false
+}
+
+// Format renders a selected value for a reason or a comparison.
+func Format(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,209 @@
+package jsoncheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const statusBody = `{
+	"state": "indexing",
+	"progress": 0.4,
+	"queues": {"thumbnails": {"active": 3}, "faces": {"active": 0}},
+	"running": [{"name": "import", "files": 120}, {"name": "index", "files": 0}],
+	"paused": false
+}`
+
+const queueBody = `{
+	"totalRecords": 3,
+	"records": [
+		{"title": "Show A", "status": "downloading", "sizeleft": 1024, "a>b": 0},
+		{"title": "Show B", "status": "completed", "sizeleft": 0, "note": "eta < 1h"},
+		{"title": "Show C", "status": "downloading", "sizeleft": 2048, "tracked download": true, "a>b": 5}
+	]
+}`
+
+func decode(t *testing.T, body string) any {
+	t.Helper()
+	var doc any
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestSelect(t *testing.T) {
+	status, queue := decode(t, statusBody), decode(t, queueBody)
+
+	tests := []struct {
+		doc  any
+		expr string
+		want string
+	}{
+		{status, "$.state", "$.state=indexing"},
+		{status, "state", "$.state=indexing"},
+		{status, "$['state']", "$.state=indexing"},
+		{status, "$.queues.*.active", "$.queues.faces.active=0|$.queues.thumbnails.active=3"},
+		{status, "queues.*.active", "$.queues.faces.active=0|$.queues.thumbnails.active=3"},
+		{status, "$.running.length()", "$.running.length()=2"},
+		{status, "running.#", "$.running.length()=2"},
+		{status, "$.running[1].name", "$.running[1].name=index"},
+		{status, "running.1.name", "$.running[1].name=index"},
+		{status, "$.running[-1].name", "$.running[1].name=index"},
+		{status, "$.running[*].files", "$.running[0].files=120|$.running[1].files=0"},
+		{status, "$..active", "$.queues.faces.active=0|$.queues.thumbnails.active=3"},
+		{status, "$.running[5].name", ""},
+		{status, "$.missing.key", ""},
+		{queue, "$.records[?(@.status == 'downloading')].title", "$.records[0].title=Show A|$.records[2].title=Show C"},
+		{queue, `$.records[?(@.sizeleft > 1500)].title`, "$.records[2].title=Show C"},
+		{queue, "$.records[?(@['tracked download'])].title", "$.records[2].title=Show C"},
+		{queue, "$.records[?(@['a>b'] > 1)].title", "$.records[2].title=Show C"},
+		{queue, "$.records[?(@.note == 'eta < 1h')].title", "$.records[1].title=Show B"},
+		{queue, "$.records[?(@.status != 'completed')].length()", "$.records[0].length()=4|$.records[2].length()=5"},
+	}
+	for _, tt := range tests {
+		p, err := Compile(tt.expr)
+		if err != nil {
+			t.Errorf("Compile(%s): %v", tt.expr, err)
+			continue
+		}
+		var got []string
+		for _, m := range p.Select(tt.doc) {
+			got = append(got, m.Path+"="+Format(m.Value))
+		}
+		if strings.Join(got, "|") != tt.want {
+			t.Errorf("Select(%s) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	for _, bad := range []string{"$.", "$[", "$['a'", "$[x]", "$[?(@.a == 1]", "$[?(a == 1)]", "$.a.length().b"} {
+		if _, err := Compile(bad); err == nil {
+			t.Errorf("Compile(%q): want error", bad)
+		}
+	}
+}
+
+func TestChecker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(statusBody))
+	}))
+	defer server.Close()
+	header := http.Header{"Authorization": {"Bearer tok"}}
+
+	tests := []struct {
+		expr, cond string
+		want       string
+	}{
+		{"state", "==indexing", "$.state = indexing"},
+		{"state", "!=idle", "$.state = indexing"},
+		{"state", "==idle", ""},
+		{"queues.*.active", ">0", "$.queues.thumbnails.active = 3"},
+		{"$.running[*].files", ">=120", "$.running[0].files = 120"},
+		{"progress", "<0.5", "$.progress = 0.4"},
+		{"running.#", "", "$.running.length() = 2"},
+		{"paused", "", ""},
+	}
+	for _, tt := range tests {
+		c, err := NewChecker("photos", server.URL, tt.expr, tt.cond, header, 5*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.Check(context.Background())
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("%s %s: Check() = %q, want %q", tt.expr, tt.cond, got, tt.want)
+		}
+	}
+
+	c, _ := NewChecker("photos", server.URL, "state", "", nil, 5*time.Second)
+	if _, err := c.Activity(context.Background()); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Activity() without token = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestChecker_Count(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(queueBody))
+	}))
+	defer server.Close()
+
+	c, err := NewChecker("sonarr", server.URL, "$.records[?(@.status == 'downloading')]", ">0", nil, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Count = true
+	reasons, err := c.Activity(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "count($.records[?(@.status == 'downloading')]) = 2"
+	if len(reasons) != 1 || reasons[0] != want {
+		t.Errorf("Activity() = %q, want %q", reasons, want)
+	}
+
+	c, _ = NewChecker("sonarr", server.URL, "$.records[?(@.status == 'failed')]", ">0", nil, 5*time.Second)
+	c.Count = true
+	if reasons, err := c.Activity(context.Background()); err != nil || len(reasons) != 0 {
+		t.Errorf("Activity() with nothing failed = %q, %v", reasons, err)
+	}
+}
+
+func TestChecker_Health(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(queueBody))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		expr, cond string
+		count      bool
+		want       string
+	}{
+		{"totalRecords", "<10", false, ""},
+		{"totalRecords", "<3", false, "$.totalRecords = 3, want < 3"},
+		{"$.records[*].status", "!=failed", false, ""},
+		{"$.records[*].sizeleft", "==0", false, "$.records[0].sizeleft = 1024, want == 0; $.records[2].sizeleft = 2048, want == 0"},
+		{"$.missing", "", false, "$.missing matched nothing"},
+		{"$.records[?(@.status == 'failed')]", "==0", true, ""},
+		{"$.records[*]", "<=2", true, "count($.records[*]) = 3, want <= 2"},
+	}
+	for _, tt := range tests {
+		c, err := NewChecker("sonarr", server.URL, tt.expr, tt.cond, nil, 5*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Count = tt.count
+		err = c.Health(context.Background())
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("%s %s: Health() = %q, want %q", tt.expr, tt.cond, got, tt.want)
+		}
+	}
+}
+
+func TestParseCondition(t *testing.T) {
+	for _, bad := range []string{">many", "~x", "indexing"} {
+		if _, err := ParseCondition(bad); err == nil {
+			t.Errorf("ParseCondition(%q): want error", bad)
+		}
+	}
+}
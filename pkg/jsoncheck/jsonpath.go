@@ -0,0 +1,382 @@
+package jsoncheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Path is a compiled JSONPath expression. The supported subset covers
+// what status APIs need:
+//
+//	$.a.b  $['a']  $.a[0]  $.a[-1]  $.a[*]  $.a.*  $..a
+//	$.records[?(@.status == 'downloading')]  $.items[?(@.enabled)]
+//	$.records.length()
+//
+// The leading "$" may be left out, so "records.0.status" works too.
+type Path struct {
+	expr  string
+	steps []step
+}
+
+// Match is a value selected by a Path, with its normalized path, e.g.
+// "$.records[0].status"
+type Match struct {
+	Path  string
+	Value any
+}
+
+type stepKind int
+
+const (
+	stepChild stepKind = iota
+	stepIndex
+	stepWildcard
+	stepDescend // ..name or ..*
+	stepFilter
+	stepLength
+)
+
+type step struct {
+	kind   stepKind
+	name   string // stepChild, stepDescend ("*" for any)
+	index  int    // stepIndex
+	filter *filter
+}
+
+// filter is [?(@.path op value)], or [?(@.path)] when cond is nil
+type filter struct {
+	path *Path
+	cond *Condition
+}
+
+// Compile parses a JSONPath expression.
+func Compile(expr string) (*Path, error) {
+	s := strings.TrimSpace(expr)
+	switch {
+	case s == "" || s == "$":
+		return &Path{expr: "$"}, nil
+	case strings.HasPrefix(s, "$"), strings.HasPrefix(s, "@"):
+		s = s[1:]
+	case strings.HasPrefix(s, "[") || strings.HasPrefix(s, "."):
+	default:
+		s = "." + s
+	}
+
+	p := &Path{expr: expr}
+	for s != "" {
+		var st step
+		var err error
+		st, s, err = parseStep(s)
+		if err != nil {
+			return nil, fmt.Errorf("jsonpath %q: %w", expr, err)
+		}
+		if n := len(p.steps); n > 0 && p.steps[n-1].kind == stepLength {
+			return nil, fmt.Errorf("jsonpath %q: length() must come last", expr)
+		}
+		p.steps = append(p.steps, st)
+	}
+	return p, nil
+}
+
+// MustCompile is Compile, panicking on error.
+func MustCompile(expr string) *Path {
+	p, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (p *Path) String() string {
+	return p.expr
+}
+
+func parseStep(s string) (step, string, error) {
+	switch {
+	case strings.HasPrefix(s, ".."):
+		name, rest := splitName(s[2:])
+		if name == "" {
+			return step{}, "", fmt.Errorf("missing name after ..")
+		}
+		return step{kind: stepDescend, name: name}, rest, nil
+	case strings.HasPrefix(s, "."):
+		name, rest := splitName(s[1:])
+		switch name {
+		case "":
+			return step{}, "", fmt.Errorf("missing name after .")
+		case "*":
+			return step{kind: stepWildcard}, rest, nil
+		case "length()", "#":
+			return step{kind: stepLength}, rest, nil
+		}
+		return step{kind: stepChild, name: name}, rest, nil
+	case strings.HasPrefix(s, "["):
+		return parseBracket(s)
+	}
+	return step{}, "", fmt.Errorf("unexpected %q", s)
+}
+
+// splitName splits a dotted name from the rest of the expression.
+func splitName(s string) (string, string) {
+	if strings.HasPrefix(s, "length()") {
+		return "length()", s[len("length()"):]
+	}
+	i := strings.IndexAny(s, ".[")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+func parseBracket(s string) (step, string, error) {
+	inner := s[1:]
+	switch {
+	case strings.HasPrefix(inner, "'") || strings.HasPrefix(inner, `"`):
+		q := inner[:1]
+		end := strings.Index(inner[1:], q)
+		if end < 0 || !strings.HasPrefix(inner[end+2:], "]") {
+			return step{}, "", fmt.Errorf("unterminated [%s", q)
+		}
+		return step{kind: stepChild, name: inner[1 : end+1]}, inner[end+3:], nil
+	case strings.HasPrefix(inner, "*]"):
+		return step{kind: stepWildcard}, inner[2:], nil
+	case strings.HasPrefix(inner, "?("):
+		end := closingParen(inner[2:])
+		if end < 0 || !strings.HasPrefix(inner[2+end+1:], "]") {
+			return step{}, "", fmt.Errorf("unterminated filter")
+		}
+		f, err := parseFilter(inner[2 : 2+end])
+		if err != nil {
+			return step{}, "", err
+		}
+		return step{kind: stepFilter, filter: f}, inner[2+end+2:], nil
+	}
+	end := strings.Index(inner, "]")
+	if end < 0 {
+		return step{}, "", fmt.Errorf("unterminated [")
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(inner[:end]))
+	if err != nil {
+		return step{}, "", fmt.Errorf("bad index %q", inner[:end])
+	}
+	return step{kind: stepIndex, index: i}, inner[end+1:], nil
+}
+
+// closingParen returns the index of the ")" closing a filter, skipping
+// quoted strings, or -1.
+func closingParen(s string) int {
+	var quote byte
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+func parseFilter(s string) (*filter, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "@") {
+		return nil, fmt.Errorf("filter %q must start with @", s)
+	}
+	// The path ends at the first operator outside quotes
+	end := operatorAt(s)
+	if end < 0 {
+		end = len(s)
+	}
+	path, err := Compile(strings.TrimSpace(s[:end]))
+	if err != nil {
+		return nil, err
+	}
+	f := &filter{path: path}
+	if end == len(s) {
+		return f, nil
+	}
+	cond, err := ParseCondition(unquote(s[end:]))
+	if err != nil {
+		return nil, err
+	}
+	f.cond = &cond
+	return f, nil
+}
+
+// operatorAt returns the index of the first comparison operator in s
+// that isn't inside a quoted string, e.g. a key like @['a>b'], or -1.
+func operatorAt(s string) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		default:
+			for _, op := range operators {
+				if strings.HasPrefix(s[i:], op) {
+					return i
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// unquote strips the quotes from the value in "== 'x'".
+func unquote(cond string) string {
+	for _, op := range operators {
+		if v, ok := strings.CutPrefix(cond, op); ok {
+			v = strings.TrimSpace(v)
+			if len(v) >= 2 && (v[0] == '\'' || v[0] == '"') && v[len(v)-1] == v[0] {
+				v = v[1 : len(v)-1]
+			}
+			return op + v
+		}
+	}
+	return cond
+}
+
+// Select returns the values the path selects from doc, a document decoded
+// by encoding/json (with UseNumber for exact numbers).
+func (p *Path) Select(doc any) []Match {
+	matches := []Match{{Path: "$", Value: doc}}
+	for _, st := range p.steps {
+		var next []Match
+		for _, m := range matches {
+			next = append(next, st.apply(m)...)
+		}
+		matches = next
+	}
+	return matches
+}
+
+func (st step) apply(m Match) []Match {
+	switch st.kind {
+	case stepChild:
+		switch v := m.Value.(type) {
+		case map[string]any:
+			if x, ok := v[st.name]; ok {
+				return []Match{{Path: childPath(m.Path, st.name), Value: x}}
+			}
+		case []any:
+			// records.0.status: a dotted index
+			if i, err := strconv.Atoi(st.name); err == nil {
+				return step{kind: stepIndex, index: i}.apply(m)
+			}
+		}
+	case stepIndex:
+		if v, ok := m.Value.([]any); ok {
+			i := st.index
+			if i < 0 {
+				i += len(v)
+			}
+			if i >= 0 && i < len(v) {
+				return []Match{{Path: fmt.Sprintf("%s[%d]", m.Path, i), Value: v[i]}}
+			}
+		}
+	case stepWildcard:
+		return children(m)
+	case stepDescend:
+		var out []Match
+		for _, d := range descendants(m) {
+			if st.name == "*" {
+				out = append(out, children(d)...)
+			} else {
+				out = append(out, step{kind: stepChild, name: st.name}.apply(d)...)
+			}
+		}
+		return out
+	case stepFilter:
+		var out []Match
+		for _, c := range children(m) {
+			if st.filter.match(c.Value) {
+				out = append(out, c)
+			}
+		}
+		return out
+	case stepLength:
+		n := -1
+		switch v := m.Value.(type) {
+		case []any:
+			n = len(v)
+		case map[string]any:
+			n = len(v)
+		case string:
+			n = len(v)
+		}
+		if n >= 0 {
+			return []Match{{Path: m.Path + ".length()", Value: json.Number(strconv.Itoa(n))}}
+		}
+	}
+	return nil
+}
+
+func (f *filter) match(v any) bool {
+	for _, m := range f.path.Select(v) {
+		if f.cond == nil || f.cond.Match(m.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+// children returns an array's elements or an object's values, the latter
+// sorted by key so results are stable.
+func children(m Match) []Match {
+	switch v := m.Value.(type) {
+	case []any:
+		out := make([]Match, len(v))
+		for i, x := range v {
+			out[i] = Match{Path: fmt.Sprintf("%s[%d]", m.Path, i), Value: x}
+		}
+		return out
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := make([]Match, len(keys))
+		for i, k := range keys {
+			out[i] = Match{Path: childPath(m.Path, k), Value: v[k]}
+		}
+		return out
+	}
+	return nil
+}
+
+// descendants returns m and everything below it, depth first.
+func descendants(m Match) []Match {
+	out := []Match{m}
+	for _, c := range children(m) {
+		out = append(out, descendants(c)...)
+	}
+	return out
+}
+
+func childPath(parent, name string) string {
+	for _, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return fmt.Sprintf("%s[%q]", parent, name)
+		}
+	}
+	return parent + "." + name
+}
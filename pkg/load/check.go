@@ -0,0 +1,177 @@
+package load
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/metrics"
+)
+
+// Threshold is the most a resource may be stalled, in percent of the time
+type Threshold struct {
+	Resource string // "cpu", "memory" or "io"
+	Kind     string // "some" or "full"
+	Percent  float64
+}
+
+// DefaultThresholds catch a machine that is thrashing: all tasks stalled
+// on memory a tenth of the time, or on I/O a quarter of it
+var DefaultThresholds = []Threshold{
+	{Resource: "memory", Kind: "full", Percent: 10},
+	{Resource: "io", Kind: "full", Percent: 25},
+}
+
+// ParseThresholds parses a comma-separated list of thresholds, each
+// "<resource>[:<kind>]=<percent>", e.g. "memory:full=10,cpu=80"; the kind
+// defaults to "some".
+func ParseThresholds(s string) ([]Threshold, error) {
+	var thresholds []Threshold
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		spec, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("threshold %q: want <resource>[:<kind>]=<percent>", item)
+		}
+		resource, kind, _ := strings.Cut(spec, ":")
+		if kind == "" {
+			kind = "some"
+		}
+		if !slices.Contains(Resources, resource) {
+			return nil, fmt.Errorf("threshold %q: unknown resource %q", item, resource)
+		}
+		if kind != "some" && kind != "full" {
+			return nil, fmt.Errorf("threshold %q: kind must be some or full", item)
+		}
+		percent, err := strconv.ParseFloat(value, 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return nil, fmt.Errorf("threshold %q: percent must be above 0 and at most 100", item)
+		}
+		thresholds = append(thresholds, Threshold{Resource: resource, Kind: kind, Percent: percent})
+	}
+	return thresholds, nil
+}
+
+// Checker compares the load average and pressure against thresholds
+type Checker struct {
+	// ProcRoot is where procfs is mounted ("" = DefaultProcRoot)
+	ProcRoot string
+	// MaxLoadPerCPU is the most the 5-minute load average may be per
+	// CPU; 0 ignores the load average
+	MaxLoadPerCPU float64
+	// CPUs is the number of CPUs (0 = runtime.NumCPU)
+	CPUs int
+	// Pressure are the PSI thresholds
+	Pressure []Threshold
+	// Window is the PSI averaging window compared ("" = "avg60")
+	Window string
+
+	mu       sync.Mutex
+	average  *Average
+	pressure []Pressure
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "load"
+}
+
+// Exceeded describes each threshold the machine is over, e.g. "memory
+// full 12.5% over 60s (max 10%)". It fails if a pressure threshold is set
+// but the kernel doesn't report PSI.
+func (c *Checker) Exceeded(ctx context.Context) ([]string, error) {
+	var over []string
+
+	var avg *Average
+	if c.MaxLoadPerCPU > 0 {
+		a, err := ReadAverage(c.ProcRoot)
+		if err != nil {
+			return nil, err
+		}
+		avg = &a
+		cpus := c.CPUs
+		if cpus <= 0 {
+			cpus = runtime.NumCPU()
+		}
+		if limit := c.MaxLoadPerCPU * float64(cpus); a.Load5 > limit {
+			over = append(over, fmt.Sprintf("load %.2f on %d CPU(s) (max %.2f)", a.Load5, cpus, limit))
+		}
+	}
+
+	window := c.Window
+	if window == "" {
+		window = "avg60"
+	}
+	var lines []Pressure
+	read := map[string]bool{}
+	for _, t := range c.Pressure {
+		if !read[t.Resource] {
+			read[t.Resource] = true
+			p, err := ReadPressure(c.ProcRoot, t.Resource)
+			if err != nil {
+				return nil, fmt.Errorf("%w (is PSI enabled?)", err)
+			}
+			lines = append(lines, p...)
+		}
+		for _, p := range lines {
+			if p.Resource != t.Resource || p.Kind != t.Kind {
+				continue
+			}
+			if v := p.Avg[window]; v > t.Percent {
+				over = append(over, fmt.Sprintf("%s %s %.1f%% over %ss (max %g%%)",
+					t.Resource, t.Kind, v, strings.TrimPrefix(window, "avg"), t.Percent))
+			}
+		}
+	}
+
+	c.mu.Lock()
+	c.average, c.pressure = avg, lines
+	c.mu.Unlock()
+	return over, nil
+}
+
+// Health returns an error naming the thresholds exceeded.
+func (c *Checker) Health(ctx context.Context) error {
+	over, err := c.Exceeded(ctx)
+	if err != nil {
+		return err
+	}
+	if len(over) > 0 {
+		return fmt.Errorf("under pressure: %s", strings.Join(over, ", "))
+	}
+	return nil
+}
+
+// Gauges returns the load average and pressure read by the last call to
+// Exceeded.
+func (c *Checker) Gauges() []metrics.Gauge {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var gauges []metrics.Gauge
+	if c.average != nil {
+		gauges = append(gauges, metrics.Gauge{
+			Name: "homelab_load_average5", Help: "5-minute load average.", Value: c.average.Load5,
+		})
+	}
+	for _, p := range c.pressure {
+		for _, window := range Windows {
+			if v, ok := p.Avg[window]; ok {
+				gauges = append(gauges, metrics.Gauge{
+					Name:   "homelab_load_pressure_percent",
+					Help:   "Share of time tasks were stalled on a resource.",
+					Labels: map[string]string{"resource": p.Resource, "kind": p.Kind, "window": window},
+					Value:  v,
+				})
+			}
+		}
+	}
+	return gauges
+}
@@ -0,0 +1,87 @@
+package load
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testProc is a procfs of a four-CPU machine swapping hard
+func testProc(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"loadavg":         "9.50 8.20 3.10 3/612 48213\n",
+		"pressure/cpu":    "some avg10=4.00 avg60=2.50 avg300=1.00 total=123456\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+		"pressure/memory": "some avg10=61.20 avg60=40.00 avg300=12.00 total=9876543\nfull avg10=35.50 avg60=22.75 avg300=6.00 total=5432100\n",
+		"pressure/io":     "some avg10=30.00 avg60=20.00 avg300=8.00 total=4567890\nfull avg10=12.00 avg60=9.00 avg300=3.00 total=2345678\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestRead(t *testing.T) {
+	root := testProc(t)
+	avg, err := ReadAverage(root)
+	if err != nil || avg != (Average{Load1: 9.5, Load5: 8.2, Load15: 3.1}) {
+		t.Errorf("ReadAverage() = %+v, %v", avg, err)
+	}
+	p, err := ReadPressure(root, "memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Pressure{
+		{Resource: "memory", Kind: "some", Avg: map[string]float64{"avg10": 61.2, "avg60": 40, "avg300": 12}},
+		{Resource: "memory", Kind: "full", Avg: map[string]float64{"avg10": 35.5, "avg60": 22.75, "avg300": 6}},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("ReadPressure() = %+v\nwant %+v", p, want)
+	}
+}
+
+func TestParseThresholds(t *testing.T) {
+	got, err := ParseThresholds("memory:full=10, cpu=80")
+	want := []Threshold{{Resource: "memory", Kind: "full", Percent: 10}, {Resource: "cpu", Kind: "some", Percent: 80}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseThresholds() = %+v, %v", got, err)
+	}
+	for _, bad := range []string{"swap=10", "memory:most=10", "memory=0", "memory"} {
+		if _, err := ParseThresholds(bad); err == nil {
+			t.Errorf("ParseThresholds(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestChecker(t *testing.T) {
+	c := &Checker{ProcRoot: testProc(t), Pressure: DefaultThresholds}
+	err := c.Health(context.Background())
+	if err == nil || err.Error() != "under pressure: memory full 22.8% over 60s (max 10%)" {
+		t.Errorf("Health() = %v", err)
+	}
+
+	// A batch job: the load is high, but nothing waits on memory over 5 minutes
+	c.MaxLoadPerCPU, c.CPUs, c.Window = 1.5, 4, "avg300"
+	over, err := c.Exceeded(context.Background())
+	if err != nil || !reflect.DeepEqual(over, []string{"load 8.20 on 4 CPU(s) (max 6.00)"}) {
+		t.Errorf("Exceeded() = %v, %v", over, err)
+	}
+	if gauges := c.Gauges(); len(gauges) != 1+3*2*2 {
+		t.Errorf("Gauges() = %d gauges, want load and 2 resources x 2 kinds x 3 windows", len(gauges))
+	}
+
+	c.ProcRoot = t.TempDir()
+	if err := c.Health(context.Background()); err == nil || !strings.Contains(err.Error(), "loadavg") {
+		t.Errorf("Health() without procfs = %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+// Package load reads the load average from /proc/loadavg and pressure
+// stall information (PSI) from /proc/pressure, to tell when the machine is
+// thrashing: after an update that left a service leaking memory, say, or
+// while a batch job saturates the disks.
+//
+// Pressure is the share of time some, or all ("full"), runnable tasks
+// were stalled waiting for the CPU, memory or I/O. Unlike the load
+// average it doesn't grow with the number of CPUs, so one threshold suits
+// any machine.
+package load
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultProcRoot is the default procfs mount
+const DefaultProcRoot = "/proc"
+
+// Resources are the /proc/pressure files
+var Resources = []string{"cpu", "memory", "io"}
+
+// Windows are the averaging windows PSI reports
+var Windows = []string{"avg10", "avg60", "avg300"}
+
+// Average is the load average over 1, 5 and 15 minutes
+type Average struct {
+	Load1, Load5, Load15 float64
+}
+
+// Pressure is one line of a /proc/pressure file: the percentage of time
+// tasks were stalled on a resource, per averaging window
+type Pressure struct {
+	Resource string             // "cpu", "memory" or "io"
+	Kind     string             // "some" or "full"
+	Avg      map[string]float64 // per window, e.g. "avg60"
+}
+
+// ReadAverage reads loadavg under procRoot ("" = DefaultProcRoot).
+func ReadAverage(procRoot string) (Average, error) {
+	data, err := os.ReadFile(filepath.Join(root(procRoot), "loadavg"))
+	if err != nil {
+		return Average{}, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return Average{}, fmt.Errorf("loadavg: unexpected content %q", data)
+	}
+	var avg Average
+	for i, dst := range []*float64{&avg.Load1, &avg.Load5, &avg.Load15} {
+		if *dst, err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return Average{}, fmt.Errorf("loadavg: %w", err)
+		}
+	}
+	return avg, nil
+}
+
+// ReadPressure reads the pressure file of resource under procRoot (""
+// = DefaultProcRoot). Kernels without PSI, or with it disabled by
+// psi=0, have no such files.
+func ReadPressure(procRoot, resource string) ([]Pressure, error) {
+	data, err := os.ReadFile(filepath.Join(root(procRoot), "pressure", resource))
+	if err != nil {
+		return nil, err
+	}
+	var lines []Pressure
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		p := Pressure{Resource: resource, Kind: fields[0], Avg: map[string]float64{}}
+		for _, f := range fields[1:] {
+			key, value, ok := strings.Cut(f, "=")
+			if !ok || !strings.HasPrefix(key, "avg") {
+				// total= is cumulative stall time, not a percentage
+				continue
+			}
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("pressure/%s: %w", resource, err)
+			}
+			p.Avg[key] = v
+		}
+		lines = append(lines, p)
+	}
+	return lines, nil
+}
+
+func root(procRoot string) string {
+	if procRoot == "" {
+		return DefaultProcRoot
+	}
+	return procRoot
+}
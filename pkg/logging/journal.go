@@ -0,0 +1,133 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-systemd/v22/journal"
+)
+
+// defaultFormat is journald when stderr goes to the journal, e.g. when run
+// as a systemd service, and text otherwise.
+func defaultFormat() string {
+	if ok, err := journal.StderrIsJournalStream(); err == nil && ok {
+		return FormatJournald
+	}
+	return FormatText
+}
+
+// journalHandler sends entries over the native journal protocol, with the
+// level as PRIORITY and attributes as extra fields. Without a journal
+// socket, e.g. in a container that doesn't mount it, it writes the message
+// with a "<N>" priority prefix instead, which journald understands on
+// stderr streams.
+type journalHandler struct {
+	w      io.Writer
+	native bool // journal socket available
+	mu     *sync.Mutex
+	min    slog.Level
+	ident  string
+	fields map[string]string
+	prefix string // group prefix for field names
+}
+
+func newJournalHandler(w io.Writer, min slog.Level) *journalHandler {
+	return &journalHandler{
+		w:      w,
+		native: journal.Enabled(),
+		mu:     &sync.Mutex{},
+		min:    min,
+		ident:  filepath.Base(os.Args[0]),
+	}
+}
+
+func (h *journalHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.min
+}
+
+func (h *journalHandler) Handle(_ context.Context, r slog.Record) error {
+	pri := priority(r.Level)
+
+	vars := map[string]string{"SYSLOG_IDENTIFIER": h.ident}
+	for k, v := range h.fields {
+		vars[k] = v
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addField(vars, h.prefix, a)
+		return true
+	})
+
+	if h.native {
+		if err := journal.Send(r.Message, pri, vars); err == nil {
+			return nil
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := fmt.Fprintf(h.w, "<%d>%s\n", pri, r.Message)
+	return err
+}
+
+func (h *journalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.fields = make(map[string]string, len(h.fields)+len(attrs))
+	for k, v := range h.fields {
+		h2.fields[k] = v
+	}
+	for _, a := range attrs {
+		addField(h2.fields, h.prefix, a)
+	}
+	return &h2
+}
+
+func (h *journalHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.prefix = h.prefix + name + "_"
+	return &h2
+}
+
+func priority(level slog.Level) journal.Priority {
+	switch {
+	case level >= slog.LevelError:
+		return journal.PriErr
+	case level >= slog.LevelWarn:
+		return journal.PriWarning
+	case level >= slog.LevelInfo:
+		return journal.PriInfo
+	}
+	return journal.PriDebug
+}
+
+// addField adds an attribute as a journal field. Field names may only hold
+// upper case letters, digits and underscores, and can't start with one.
+func addField(vars map[string]string, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			addField(vars, prefix+a.Key+"_", ga)
+		}
+		return
+	}
+
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, prefix+a.Key)
+	name = strings.TrimLeft(name, "_0123456789")
+	if name == "" {
+		return
+	}
+	vars[name] = a.Value.String()
+}
@@ -0,0 +1,137 @@
+// Package logging is the sidecars' shared logger: leveled messages written
+// as text, JSON or native journald entries, so journalctl can filter them
+// by priority.
+package logging
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Output formats
+const (
+	FormatText     = "text"
+	FormatJSON     = "json"
+	FormatJournald = "journald"
+)
+
+// ParseFlags adds -log-format and -log-level to the command line, parses
+// it and sets up the default logger. The flags default to the LOG_FORMAT and
+// LOG_LEVEL environment variables, so they can also come from a profile or
+// the shared environment file.
+func ParseFlags() error {
+	format := flag.String("log-format", os.Getenv("LOG_FORMAT"),
+		"log output: text, json or journald (default journald when stderr is the journal, otherwise text)")
+	level := flag.String("log-level", os.Getenv("LOG_LEVEL"),
+		"minimum level to log: debug, info, warn or error (default info)")
+	flag.Parse()
+	return Setup(*format, *level)
+}
+
+// Setup makes a logger with the given format and minimum level the default
+// for this package, log/slog and the log package. An empty format picks
+// journald when stderr is connected to the journal and text otherwise; an
+// empty level is info.
+func Setup(format, level string) error {
+	if format == "" {
+		format = defaultFormat()
+	}
+	h, err := newHandler(format, level, os.Stderr)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+func newHandler(format, level string, w io.Writer) (slog.Handler, error) {
+	var min slog.Level
+	switch strings.ToLower(level) {
+	case "debug":
+		min = slog.LevelDebug
+	case "", "info":
+		min = slog.LevelInfo
+	case "warn", "warning":
+		min = slog.LevelWarn
+	case "error":
+		min = slog.LevelError
+	default:
+		return nil, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", level)
+	}
+	opts := &slog.HandlerOptions{Level: min}
+
+	switch format {
+	case FormatText:
+		return slog.NewTextHandler(w, opts), nil
+	case FormatJSON:
+		return slog.NewJSONHandler(w, opts), nil
+	case FormatJournald:
+		return newJournalHandler(w, min), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q (want text, json or journald)", format)
+}
+
+// Debugf logs at debug level.
+func Debugf(format string, args ...any) {
+	logf(slog.LevelDebug, format, args...)
+}
+
+// Infof logs at info level.
+func Infof(format string, args ...any) {
+	logf(slog.LevelInfo, format, args...)
+}
+
+// Warnf logs at warning level.
+func Warnf(format string, args ...any) {
+	logf(slog.LevelWarn, format, args...)
+}
+
+// Errorf logs at error level.
+func Errorf(format string, args ...any) {
+	logf(slog.LevelError, format, args...)
+}
+
+// Fatalf logs at error level and exits with status 1.
+func Fatalf(format string, args ...any) {
+	logf(slog.LevelError, format, args...)
+	os.Exit(1)
+}
+
+func logf(level slog.Level, format string, args ...any) {
+	ctx := context.Background()
+	logger := slog.Default()
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	logger.Log(ctx, level, fmt.Sprintf(format, args...))
+}
+
+// StdLogger returns a *log.Logger for libraries that take one, such as the
+// sidecar framework. Its messages are logged at a level guessed from their
+// prefix: "Warning:" is a warning, "Failed" or "Error" an error, anything
+// else info.
+func StdLogger() *log.Logger {
+	return log.New(stdWriter{}, "", 0)
+}
+
+type stdWriter struct{}
+
+func (stdWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSuffix(string(p), "\n")
+	level := slog.LevelInfo
+	switch {
+	case strings.HasPrefix(msg, "Warning: "):
+		level = slog.LevelWarn
+		msg = strings.TrimPrefix(msg, "Warning: ")
+	case strings.HasPrefix(msg, "Failed"), strings.HasPrefix(msg, "Error"), strings.Contains(msg, " error: "):
+		level = slog.LevelError
+	}
+	logf(level, "%s", msg)
+	return len(p), nil
+}
@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewHandler_Levels(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := newHandler(FormatJSON, "warn", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(h)
+	logger.Info("skipped")
+	logger.Warn("inhibitor held", "check", "raid")
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("output %q is not one JSON entry: %v", buf.String(), err)
+	}
+	if entry["level"] != "WARN" || entry["msg"] != "inhibitor held" || entry["check"] != "raid" {
+		t.Errorf("entry = %v", entry)
+	}
+
+	for _, tt := range []struct{ format, level string }{{"xml", "info"}, {FormatText, "loud"}} {
+		if _, err := newHandler(tt.format, tt.level, &buf); err == nil {
+			t.Errorf("newHandler(%q, %q) succeeded, want error", tt.format, tt.level)
+		}
+	}
+}
+
+func TestJournalHandler_Fallback(t *testing.T) {
+	var buf bytes.Buffer
+	h := newJournalHandler(&buf, slog.LevelDebug)
+	h.native = false
+	logger := slog.New(h)
+
+	logger.Debug("polling")
+	logger.Info("released inhibitor")
+	logger.Warn("notification failed")
+	logger.Error("check failed")
+
+	want := "<7>polling\n<6>released inhibitor\n<4>notification failed\n<3>check failed\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestAddField(t *testing.T) {
+	vars := make(map[string]string)
+	addField(vars, "", slog.String("array", "md0"))
+	addField(vars, "raid_", slog.Int("active-devices", 2))
+	addField(vars, "", slog.Group("ups", slog.Float64("charge", 87.5)))
+	addField(vars, "", slog.String("_private", "x"))
+
+	want := map[string]string{"ARRAY": "md0", "RAID_ACTIVE_DEVICES": "2", "UPS_CHARGE": "87.5", "PRIVATE": "x"}
+	if len(vars) != len(want) {
+		t.Errorf("vars = %v, want %v", vars, want)
+	}
+	for k, v := range want {
+		if vars[k] != v {
+			t.Errorf("vars[%s] = %q, want %q", k, vars[k], v)
+		}
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	h, _ := newHandler(FormatJSON, "debug", &buf)
+	prev := slog.Default()
+	slog.SetDefault(slog.New(h))
+	defer slog.SetDefault(prev)
+
+	logger := StdLogger()
+	logger.Printf("Acquired inhibitor: %s", "streaming")
+	logger.Printf("Warning: watchdog ping failed: %v", "timeout")
+	logger.Printf("Check error: %v", "connection refused")
+
+	var levels, msgs []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("bad entry %q: %v", line, err)
+		}
+		levels = append(levels, entry["level"].(string))
+		msgs = append(msgs, entry["msg"].(string))
+	}
+	if got := strings.Join(levels, ","); got != "INFO,WARN,ERROR" {
+		t.Errorf("levels = %s, want INFO,WARN,ERROR", got)
+	}
+	if msgs[1] != "watchdog ping failed: timeout" {
+		t.Errorf("warning message = %q, want prefix stripped", msgs[1])
+	}
+}
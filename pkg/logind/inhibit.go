@@ -0,0 +1,20 @@
+package logind
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Inhibit takes an inhibitor lock. The lock is held until the returned
+// file is closed.
+func Inhibit(ctx context.Context, conn *dbus.Conn, what, who, why, mode string) (*os.File, error) {
+	var fd dbus.UnixFD
+	obj := conn.Object(dest, path)
+	if err := obj.CallWithContext(ctx, Interface+".Inhibit", 0, what, who, why, mode).Store(&fd); err != nil {
+		return nil, fmt.Errorf("Inhibit: %w", err)
+	}
+	return os.NewFile(uintptr(fd), "inhibitor"), nil
+}
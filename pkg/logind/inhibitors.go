@@ -0,0 +1,97 @@
+// Package logind provides helpers for systemd-logind over D-Bus.
+package logind
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	dest = "org.freedesktop.login1"
+	path = "/org/freedesktop/login1"
+	// Interface is the logind manager D-Bus interface
+	Interface = "org.freedesktop.login1.Manager"
+)
+
+// Inhibitor is a lock currently registered with logind
+type Inhibitor struct {
+	What string // colon-separated list, e.g. "shutdown:sleep"
+	Who  string
+	Why  string
+	Mode string // "block" or "delay"
+	UID  uint32
+	PID  uint32
+}
+
+// Inhibits reports whether the lock covers the given action (e.g. "shutdown").
+func (i Inhibitor) Inhibits(what string) bool {
+	for _, w := range strings.Split(i.What, ":") {
+		if w == what {
+			return true
+		}
+	}
+	return false
+}
+
+// ListInhibitors returns all inhibitor locks known to logind.
+func ListInhibitors(ctx context.Context, conn *dbus.Conn) ([]Inhibitor, error) {
+	var raw [][]any
+	obj := conn.Object(dest, path)
+	if err := obj.CallWithContext(ctx, Interface+".ListInhibitors", 0).Store(&raw); err != nil {
+		return nil, fmt.Errorf("ListInhibitors: %w", err)
+	}
+
+	inhibitors := make([]Inhibitor, 0, len(raw))
+	for _, r := range raw {
+		if len(r) != 6 {
+			return nil, fmt.Errorf("unexpected inhibitor record: %v", r)
+		}
+		var inh Inhibitor
+		var ok [6]bool
+		inh.What, ok[0] = r[0].(string)
+		inh.Who, ok[1] = r[1].(string)
+		inh.Why, ok[2] = r[2].(string)
+		inh.Mode, ok[3] = r[3].(string)
+		inh.UID, ok[4] = r[4].(uint32)
+		inh.PID, ok[5] = r[5].(uint32)
+		for _, o := range ok {
+			if !o {
+				return nil, fmt.Errorf("unexpected inhibitor record: %v", r)
+			}
+		}
+		inhibitors = append(inhibitors, inh)
+	}
+	return inhibitors, nil
+}
+
+// Blocking filters inhibitors to block-mode locks covering what.
+func Blocking(inhibitors []Inhibitor, what string) []Inhibitor {
+	var out []Inhibitor
+	for _, inh := range inhibitors {
+		if inh.Mode == "block" && inh.Inhibits(what) {
+			out = append(out, inh)
+		}
+	}
+	return out
+}
+
+// HeldBy filters inhibitors to those whose Who is in who. An empty who
+// matches every inhibitor.
+func HeldBy(inhibitors []Inhibitor, who []string) []Inhibitor {
+	if len(who) == 0 {
+		return inhibitors
+	}
+	var out []Inhibitor
+	for _, inh := range inhibitors {
+		for _, w := range who {
+			if inh.Who == w {
+				out = append(out, inh)
+				break
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+package logind
+
+import "testing"
+
+func TestBlocking(t *testing.T) {
+	inhibitors := []Inhibitor{
+		{What: "shutdown:sleep", Who: "jellyfin", Mode: "block"},
+		{What: "shutdown", Who: "raid", Mode: "block"},
+		{What: "sleep", Who: "NetworkManager", Mode: "delay"},
+		{What: "handle-lid-switch", Who: "gnome", Mode: "block"},
+	}
+
+	tests := []struct {
+		what string
+		want []string
+	}{
+		{what: "shutdown", want: []string{"jellyfin", "raid"}},
+		{what: "sleep", want: []string{"jellyfin"}},
+		{what: "idle", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.what, func(t *testing.T) {
+			got := Blocking(inhibitors, tt.what)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d inhibitors, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i].Who != tt.want[i] {
+					t.Errorf("got[%d] = %q, want %q", i, got[i].Who, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHeldBy(t *testing.T) {
+	inhibitors := []Inhibitor{
+		{Who: "jellyfin"},
+		{Who: "raid"},
+		{Who: "NetworkManager"},
+	}
+
+	if got := HeldBy(inhibitors, nil); len(got) != 3 {
+		t.Errorf("HeldBy(nil) = %d inhibitors, want 3", len(got))
+	}
+	got := HeldBy(inhibitors, []string{"raid", "jellyfin"})
+	if len(got) != 2 || got[0].Who != "jellyfin" || got[1].Who != "raid" {
+		t.Errorf("HeldBy = %v, want jellyfin and raid", got)
+	}
+}
@@ -0,0 +1,91 @@
+package logind
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// SessionInterface is the logind session D-Bus interface
+const SessionInterface = "org.freedesktop.login1.Session"
+
+// Session is a login session known to logind
+type Session struct {
+	ID         string
+	User       string
+	Service    string // PAM service, e.g. "sshd"
+	Type       string // "tty" for sessions with a terminal, "unspecified" without
+	Class      string // "user", "greeter", ...
+	State      string // "active", "online" or "closing"
+	TTY        string // e.g. "pts/0"
+	RemoteHost string
+	Remote     bool
+	Idle       bool
+	IdleSince  time.Time // zero unless Idle
+	Since      time.Time
+}
+
+// ListSessions returns every session known to logind.
+func ListSessions(ctx context.Context, conn *dbus.Conn) ([]Session, error) {
+	var raw [][]any // (id, uid, user, seat, object path)
+	obj := conn.Object(dest, path)
+	if err := obj.CallWithContext(ctx, Interface+".ListSessions", 0).Store(&raw); err != nil {
+		return nil, fmt.Errorf("ListSessions: %w", err)
+	}
+
+	sessions := make([]Session, 0, len(raw))
+	for _, r := range raw {
+		if len(r) != 5 {
+			return nil, fmt.Errorf("unexpected session record: %v", r)
+		}
+		p, ok := r[4].(dbus.ObjectPath)
+		if !ok {
+			return nil, fmt.Errorf("unexpected session record: %v", r)
+		}
+		var props map[string]dbus.Variant
+		err := conn.Object(dest, p).CallWithContext(ctx, "org.freedesktop.DBus.Properties.GetAll", 0, SessionInterface).Store(&props)
+		if err != nil {
+			// Closed since it was listed
+			continue
+		}
+		sessions = append(sessions, sessionFromProperties(props))
+	}
+	return sessions, nil
+}
+
+func sessionFromProperties(props map[string]dbus.Variant) Session {
+	str := func(name string) string {
+		s, _ := props[name].Value().(string)
+		return s
+	}
+	flag := func(name string) bool {
+		b, _ := props[name].Value().(bool)
+		return b
+	}
+	usec := func(name string) time.Time {
+		if us, _ := props[name].Value().(uint64); us > 0 {
+			return time.UnixMicro(int64(us))
+		}
+		return time.Time{}
+	}
+
+	s := Session{
+		ID:         str("Id"),
+		User:       str("Name"),
+		Service:    str("Service"),
+		Type:       str("Type"),
+		Class:      str("Class"),
+		State:      str("State"),
+		TTY:        str("TTY"),
+		RemoteHost: str("RemoteHost"),
+		Remote:     flag("Remote"),
+		Idle:       flag("IdleHint"),
+		Since:      usec("Timestamp"),
+	}
+	if s.Idle {
+		s.IdleSince = usec("IdleSinceHint")
+	}
+	return s
+}
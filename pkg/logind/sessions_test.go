@@ -0,0 +1,35 @@
+package logind
+
+import (
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestSessionFromProperties(t *testing.T) {
+	since := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+	s := sessionFromProperties(map[string]dbus.Variant{
+		"Id":            dbus.MakeVariant("4"),
+		"Name":          dbus.MakeVariant("alice"),
+		"Service":       dbus.MakeVariant("sshd"),
+		"Type":          dbus.MakeVariant("tty"),
+		"TTY":           dbus.MakeVariant("pts/0"),
+		"RemoteHost":    dbus.MakeVariant("192.168.1.20"),
+		"Remote":        dbus.MakeVariant(true),
+		"IdleHint":      dbus.MakeVariant(false),
+		"IdleSinceHint": dbus.MakeVariant(uint64(since.UnixMicro())),
+		"Timestamp":     dbus.MakeVariant(uint64(since.UnixMicro())),
+	})
+	want := Session{
+		ID: "4", User: "alice", Service: "sshd", Type: "tty", TTY: "pts/0",
+		RemoteHost: "192.168.1.20", Remote: true, Since: since,
+	}
+	if !s.Since.Equal(want.Since) || s.IdleSince != (time.Time{}) {
+		t.Errorf("times = %v, %v, want %v and no idle time", s.Since, s.IdleSince, since)
+	}
+	s.Since = want.Since
+	if s != want {
+		t.Errorf("sessionFromProperties() = %+v, want %+v", s, want)
+	}
+}
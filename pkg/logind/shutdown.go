@@ -0,0 +1,55 @@
+package logind
+
+import (
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// PrepareForShutdown subscribes to logind's PrepareForShutdown signal. The
+// channel receives true when a shutdown starts and false if it is
+// cancelled. cancel unsubscribes and closes the channel.
+func PrepareForShutdown(conn *dbus.Conn) (signals <-chan bool, cancel func(), err error) {
+	return prepareFor(conn, "PrepareForShutdown")
+}
+
+// PrepareForSleep subscribes to logind's PrepareForSleep signal. The
+// channel receives true just before the machine suspends or hibernates and
+// false once it has resumed. cancel unsubscribes and closes the channel.
+func PrepareForSleep(conn *dbus.Conn) (signals <-chan bool, cancel func(), err error) {
+	return prepareFor(conn, "PrepareForSleep")
+}
+
+// prepareFor follows one of logind's boolean Prepare* signals
+func prepareFor(conn *dbus.Conn, member string) (<-chan bool, func(), error) {
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(path),
+		dbus.WithMatchInterface(Interface),
+		dbus.WithMatchMember(member),
+	}
+	if err := conn.AddMatchSignal(match...); err != nil {
+		return nil, nil, fmt.Errorf("subscribe to %s: %w", member, err)
+	}
+
+	raw := make(chan *dbus.Signal, 4)
+	conn.Signal(raw)
+	out := make(chan bool, 1)
+	go func() {
+		defer close(out)
+		for sig := range raw {
+			if sig.Name != Interface+"."+member || len(sig.Body) != 1 {
+				continue
+			}
+			if active, ok := sig.Body[0].(bool); ok {
+				out <- active
+			}
+		}
+	}()
+
+	cancel := func() {
+		conn.RemoveSignal(raw)
+		conn.RemoveMatchSignal(match...)
+		close(raw)
+	}
+	return out, cancel, nil
+}
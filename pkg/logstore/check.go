@@ -0,0 +1,71 @@
+package logstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Checker implements check.Checker for log stores.
+// Returns an error while Elasticsearch is relocating shards, so a reboot
+// doesn't abort the move and leave the cluster to start it over.
+type Checker struct {
+	Stores []Store
+}
+
+// NewChecker creates a log store checker.
+func NewChecker(stores []Store) *Checker {
+	return &Checker{Stores: stores}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "logstore"
+}
+
+// Check returns nil if no store is busy, error otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	reasons, err := c.Activity(ctx)
+	if err != nil {
+		return fmt.Errorf("logstore check failed: %w", err)
+	}
+	if len(reasons) > 0 {
+		return errors.New(strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// Activity describes the work each store is doing. Stores that can't be
+// reached are skipped, since they aren't doing anything either; rejected
+// credentials are returned as an error.
+func (c *Checker) Activity(ctx context.Context) ([]string, error) {
+	var reasons []string
+	for _, s := range c.Stores {
+		activity, err := s.Activity(ctx)
+		if errors.Is(err, ErrUnauthorized) {
+			return nil, fmt.Errorf("%s: %w", s.Name(), err)
+		}
+		if err != nil {
+			continue
+		}
+		reasons = append(reasons, activity...)
+	}
+	return reasons, nil
+}
+
+// Health returns nil if every store is healthy, e.g. for a post-boot
+// health check. Unlike Activity, a store that can't be reached is an
+// error.
+func (c *Checker) Health(ctx context.Context) error {
+	var problems []string
+	for _, s := range c.Stores {
+		if err := s.Health(ctx); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", s.Name(), err))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package logstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Elasticsearch checks an Elasticsearch (or OpenSearch) cluster's health
+type Elasticsearch struct {
+	baseURL    string
+	username   string
+	password   string
+	apiKey     string
+	httpClient *http.Client
+
+	// MinStatus is the worst cluster status Health accepts: "green", or
+	// "yellow" for a single node whose replicas can never be assigned
+	MinStatus string
+}
+
+// NewElasticsearch creates an Elasticsearch store, e.g. for
+// http://localhost:9200. Pass an API key, or a username and password for
+// basic authentication, or neither if security is off. The user needs the
+// "monitor" cluster privilege.
+func NewElasticsearch(baseURL, username, password, apiKey string, timeout time.Duration) *Elasticsearch {
+	return &Elasticsearch{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		username: username,
+		password: password,
+		apiKey:   apiKey,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		MinStatus: "green",
+	}
+}
+
+// ClusterHealth is the response of /_cluster/health
+type ClusterHealth struct {
+	ClusterName        string `json:"cluster_name"`
+	Status             string `json:"status"` // green, yellow or red
+	RelocatingShards   int    `json:"relocating_shards"`
+	InitializingShards int    `json:"initializing_shards"`
+	UnassignedShards   int    `json:"unassigned_shards"`
+	NumberOfNodes      int    `json:"number_of_nodes"`
+}
+
+// Name returns the store name.
+func (e *Elasticsearch) Name() string {
+	return "elasticsearch"
+}
+
+// ClusterHealth returns the cluster's health.
+func (e *Elasticsearch) ClusterHealth(ctx context.Context) (*ClusterHealth, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", e.baseURL+"/_cluster/health", nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	switch {
+	case e.apiKey != "":
+		req.Header.Set("Authorization", "ApiKey "+e.apiKey)
+	case e.username != "":
+		req.SetBasicAuth(e.username, e.password)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("unexpected status: %d: %w", resp.StatusCode, ErrUnauthorized)
+	}
+	// A red cluster answers 408 if asked to wait for a status; without
+	// wait_for_status it is always 200
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	var health ClusterHealth
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &health, nil
+}
+
+// privileged are cluster privileges beyond "monitor" that ExcessAccess
+// looks for
+var privileged = []string{"all", "manage", "manage_security"}
+
+// ExcessAccess describes the cluster privileges the credentials have
+// beyond "monitor", e.g. "the cluster privileges all, manage", or "" if
+// they have none of them. With security off there are no credentials to
+// check.
+func (e *Elasticsearch) ExcessAccess(ctx context.Context) (string, error) {
+	if e.apiKey == "" && e.username == "" {
+		return "", nil
+	}
+	body, err := json.Marshal(map[string][]string{"cluster": privileged})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", e.baseURL+"/_security/user/_has_privileges", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	switch {
+	case e.apiKey != "":
+		req.Header.Set("Authorization", "ApiKey "+e.apiKey)
+	default:
+		req.SetBasicAuth(e.username, e.password)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	var privileges struct {
+		Cluster map[string]bool `json:"cluster"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&privileges); err != nil {
+		return "", fmt.Errorf("decode response: %w", err)
+	}
+
+	var held []string
+	for _, p := range privileged {
+		if privileges.Cluster[p] {
+			held = append(held, p)
+		}
+	}
+	if len(held) == 0 {
+		return "", nil
+	}
+	return "the cluster privileges " + strings.Join(held, ", "), nil
+}
+
+// Health returns nil if the cluster status is MinStatus or better.
+func (e *Elasticsearch) Health(ctx context.Context) error {
+	health, err := e.ClusterHealth(ctx)
+	if err != nil {
+		return err
+	}
+	if statusRank(health.Status) < statusRank(e.MinStatus) {
+		return fmt.Errorf("cluster %s is %s (%d unassigned, %d initializing shard(s))",
+			health.ClusterName, health.Status, health.UnassignedShards, health.InitializingShards)
+	}
+	return nil
+}
+
+// Activity describes shards being relocated between nodes.
+func (e *Elasticsearch) Activity(ctx context.Context) ([]string, error) {
+	health, err := e.ClusterHealth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if health.RelocatingShards > 0 {
+		return []string{fmt.Sprintf("%d shard(s) relocating in %s", health.RelocatingShards, health.ClusterName)}, nil
+	}
+	return nil, nil
+}
+
+// statusRank orders cluster statuses from red (0) to green (2); anything
+// else ranks below red.
+func statusRank(status string) int {
+	switch status {
+	case "green":
+		return 2
+	case "yellow":
+		return 1
+	case "red":
+		return 0
+	}
+	return -1
+}
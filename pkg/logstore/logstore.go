@@ -0,0 +1,21 @@
+// Package logstore checks the local log store (Loki or Elasticsearch):
+// whether it is healthy after boot, and whether Elasticsearch is moving
+// shards between nodes, which a reboot would interrupt and restart.
+package logstore
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUnauthorized is returned when a log store rejects the credentials
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Store is a log store backend
+type Store interface {
+	Name() string
+	// Health returns nil if the store is serving, or what is wrong
+	Health(ctx context.Context) error
+	// Activity describes work a reboot would interrupt
+	Activity(ctx context.Context) ([]string, error)
+}
@@ -0,0 +1,231 @@
+package logstore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func lokiServer(t *testing.T, ready bool, ring string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ready":
+			if !ready {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte("Ingester not ready: waiting for 15s after being ready"))
+				return
+			}
+			w.Write([]byte("ready"))
+		case "/ring":
+			if r.Header.Get("Accept") != "application/json" {
+				t.Errorf("Accept = %q, want application/json", r.Header.Get("Accept"))
+			}
+			w.Write([]byte(ring))
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLokiHealth(t *testing.T) {
+	tests := []struct {
+		name    string
+		ready   bool
+		ring    string
+		wantErr string
+	}{
+		{
+			name:  "healthy",
+			ready: true,
+			ring:  `{"shards": [{"id": "loki-0", "state": "ACTIVE"}], "now": "2024-01-01T00:00:00Z"}`,
+		},
+		{
+			name:    "not ready",
+			ready:   false,
+			wantErr: "not ready",
+		},
+		{
+			name:    "unhealthy ingester",
+			ready:   true,
+			ring:    `{"shards": [{"id": "loki-0", "state": "ACTIVE"}, {"id": "loki-1", "state": "UNHEALTHY"}]}`,
+			wantErr: "loki-1 UNHEALTHY",
+		},
+		{
+			name:    "empty ring",
+			ready:   true,
+			ring:    `{"shards": []}`,
+			wantErr: "ring is empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := lokiServer(t, tt.ready, tt.ring)
+			err := NewLoki(server.URL, 5*time.Second).Health(context.Background())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Health() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Health() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func elasticsearchServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_cluster/health" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if user, pass, ok := r.BasicAuth(); ok && (user != "elastic" || pass != "secret") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestElasticsearch(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		minStatus    string
+		wantActivity []string
+		wantErr      string
+	}{
+		{
+			name: "green",
+			body: `{"cluster_name": "logs", "status": "green", "relocating_shards": 0}`,
+		},
+		{
+			name:         "relocating",
+			body:         `{"cluster_name": "logs", "status": "green", "relocating_shards": 3}`,
+			wantActivity: []string{"3 shard(s) relocating in logs"},
+		},
+		{
+			name:    "yellow",
+			body:    `{"cluster_name": "logs", "status": "yellow", "unassigned_shards": 5}`,
+			wantErr: "cluster logs is yellow (5 unassigned, 0 initializing shard(s))",
+		},
+		{
+			name:      "yellow accepted",
+			body:      `{"cluster_name": "logs", "status": "yellow", "unassigned_shards": 5}`,
+			minStatus: "yellow",
+		},
+		{
+			name:      "red",
+			body:      `{"cluster_name": "logs", "status": "red", "unassigned_shards": 2, "initializing_shards": 1}`,
+			minStatus: "yellow",
+			wantErr:   "cluster logs is red (2 unassigned, 1 initializing shard(s))",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := elasticsearchServer(t, tt.body)
+			es := NewElasticsearch(server.URL, "elastic", "secret", "", 5*time.Second)
+			if tt.minStatus != "" {
+				es.MinStatus = tt.minStatus
+			}
+
+			activity, err := es.Activity(context.Background())
+			if err != nil {
+				t.Fatalf("Activity() error = %v", err)
+			}
+			if strings.Join(activity, "|") != strings.Join(tt.wantActivity, "|") {
+				t.Errorf("Activity() = %q, want %q", activity, tt.wantActivity)
+			}
+
+			err = es.Health(context.Background())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Health() error = %v", err)
+				}
+			} else if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Health() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestElasticsearchAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "ApiKey abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"status": "green"}`))
+	}))
+	defer server.Close()
+
+	if err := NewElasticsearch(server.URL, "", "", "abc", 5*time.Second).Health(context.Background()); err != nil {
+		t.Errorf("Health() error = %v", err)
+	}
+	_, err := NewElasticsearch(server.URL, "", "", "wrong", 5*time.Second).ClusterHealth(context.Background())
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("ClusterHealth() error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestElasticsearchExcessAccess(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_security/user/_has_privileges" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// The elastic superuser has everything; a monitoring key nothing asked for
+		if user, _, _ := r.BasicAuth(); user == "elastic" {
+			w.Write([]byte(`{"has_all_requested": true, "cluster": {"all": true, "manage": true, "manage_security": true}}`))
+			return
+		}
+		w.Write([]byte(`{"has_all_requested": false, "cluster": {"all": false, "manage": false, "manage_security": false}}`))
+	}))
+	defer server.Close()
+
+	got, err := NewElasticsearch(server.URL, "elastic", "changeme", "", 5*time.Second).ExcessAccess(context.Background())
+	if err != nil || got != "the cluster privileges all, manage, manage_security" {
+		t.Errorf("ExcessAccess() as elastic = %q, %v", got, err)
+	}
+	if got, err := NewElasticsearch(server.URL, "", "", "abc", 5*time.Second).ExcessAccess(context.Background()); err != nil || got != "" {
+		t.Errorf("ExcessAccess() with a monitoring key = %q, %v", got, err)
+	}
+}
+
+func TestChecker(t *testing.T) {
+	es := elasticsearchServer(t, `{"cluster_name": "logs", "status": "green", "relocating_shards": 1}`)
+	loki := lokiServer(t, false, "")
+
+	checker := NewChecker([]Store{
+		NewLoki(loki.URL, 5*time.Second),
+		NewElasticsearch(es.URL, "elastic", "secret", "", 5*time.Second),
+		// Unreachable: skipped by Activity, a failure for Health
+		NewElasticsearch("http://127.0.0.1:1", "", "", "", 5*time.Second),
+	})
+
+	err := checker.Check(context.Background())
+	if err == nil || err.Error() != "1 shard(s) relocating in logs" {
+		t.Errorf("Check() error = %v, want relocating shards", err)
+	}
+
+	err = checker.Health(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "loki: not ready") || !strings.Contains(err.Error(), "elasticsearch: request failed") {
+		t.Errorf("Health() error = %v, want loki and unreachable elasticsearch", err)
+	}
+
+	bad := NewChecker([]Store{NewElasticsearch(es.URL, "elastic", "wrong", "", 5*time.Second)})
+	if _, err := bad.Activity(context.Background()); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Activity() error = %v, want ErrUnauthorized", err)
+	}
+}
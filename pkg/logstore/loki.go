@@ -0,0 +1,99 @@
+package logstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Loki checks a Loki instance's readiness and ring
+type Loki struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewLoki creates a Loki store, e.g. for http://localhost:3100.
+func NewLoki(baseURL string, timeout time.Duration) *Loki {
+	return &Loki{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+type lokiRingResponse struct {
+	Shards []struct {
+		ID    string `json:"id"`
+		State string `json:"state"` // ACTIVE, JOINING, LEAVING, PENDING, or UNHEALTHY when heartbeats stopped
+	} `json:"shards"`
+}
+
+// Name returns the store name.
+func (l *Loki) Name() string {
+	return "loki"
+}
+
+// Health returns nil if Loki is ready and every ingester in its ring is
+// active.
+func (l *Loki) Health(ctx context.Context) error {
+	resp, err := l.get(ctx, "/ready", "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("not ready: status %d", resp.StatusCode)
+	}
+
+	resp, err = l.get(ctx, "/ring", "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ring: unexpected status: %d", resp.StatusCode)
+	}
+
+	var ring lokiRingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ring); err != nil {
+		return fmt.Errorf("ring: decode response: %w", err)
+	}
+	if len(ring.Shards) == 0 {
+		return fmt.Errorf("ring is empty")
+	}
+	var bad []string
+	for _, s := range ring.Shards {
+		if s.State != "ACTIVE" {
+			bad = append(bad, fmt.Sprintf("%s %s", s.ID, s.State))
+		}
+	}
+	if len(bad) > 0 {
+		return fmt.Errorf("ring members not active: %s", strings.Join(bad, ", "))
+	}
+	return nil
+}
+
+// Activity returns nothing: Loki flushes its ingesters on shutdown, so
+// there is no work to wait for.
+func (l *Loki) Activity(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (l *Loki) get(ctx context.Context, path, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", l.baseURL+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,213 @@
+// Package lowpower cuts the CPU wakeups the sidecars cause on fanless or
+// battery-backed boxes. Poll intervals are rounded up to a coarse tick and
+// aligned to the wall clock, so every sidecar polls in the same wakeup
+// rather than each on its own schedule, and polls are skipped while the
+// CPU has been idle since the last one.
+package lowpower
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/filewatch"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+)
+
+// Config configures low-power mode
+type Config struct {
+	Enabled bool
+
+	// Tick is the coarse tick poll intervals are rounded up to and aligned
+	// on, default 1m
+	Tick time.Duration
+	// IdlePercent skips a poll while the CPU was at least this idle since
+	// the previous one and the check was last idle; 0 never skips
+	IdlePercent float64
+	// MaxSkip is the most polls skipped in a row, default 4
+	MaxSkip int
+}
+
+// ConfigFromEnv reads the config from LOW_POWER_* environment variables:
+//
+//	LOW_POWER               "true" to enable
+//	LOW_POWER_TICK          coarse tick, default 1m
+//	LOW_POWER_IDLE_PERCENT  skip polls while the CPU is this idle, default 95
+//	LOW_POWER_MAX_SKIP      most polls skipped in a row, default 4
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Enabled:     os.Getenv("LOW_POWER") == "true",
+		Tick:        time.Minute,
+		IdlePercent: 95,
+		MaxSkip:     4,
+	}
+	if v := os.Getenv("LOW_POWER_TICK"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("LOW_POWER_TICK: invalid duration %q", v)
+		}
+		cfg.Tick = d
+	}
+	if v := os.Getenv("LOW_POWER_IDLE_PERCENT"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || f > 100 {
+			return cfg, fmt.Errorf("LOW_POWER_IDLE_PERCENT: want 0 to 100, got %q", v)
+		}
+		cfg.IdlePercent = f
+	}
+	if v := os.Getenv("LOW_POWER_MAX_SKIP"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("LOW_POWER_MAX_SKIP: invalid count %q", v)
+		}
+		cfg.MaxSkip = n
+	}
+	return cfg, nil
+}
+
+// Interval rounds interval up to a whole number of ticks. It returns
+// interval unchanged when low-power mode is off.
+func (c Config) Interval(interval time.Duration) time.Duration {
+	if !c.Enabled || c.Tick <= 0 {
+		return interval
+	}
+	ticks := max((interval+c.Tick-1)/c.Tick, 1)
+	return ticks * c.Tick
+}
+
+// Align waits until the wall clock reaches a multiple of interval, so a
+// poll loop started afterwards ticks at the same moments as every other
+// sidecar with the same interval. It returns at once when low-power mode
+// is off, or early if ctx is cancelled.
+func (c Config) Align(ctx context.Context, interval time.Duration) {
+	if !c.Enabled || interval <= 0 {
+		return
+	}
+	now := time.Now()
+	wait := now.Truncate(interval).Add(interval).Sub(now)
+	logging.Debugf("Low-power mode: aligning polls, first in %s", wait.Round(time.Millisecond))
+	select {
+	case <-ctx.Done():
+	case <-time.After(wait):
+	}
+}
+
+// cpuSample is cumulative CPU time from /proc/stat, in clock ticks
+type cpuSample struct {
+	idle, total uint64
+}
+
+// skipper skips polls while the CPU is idle
+type skipper struct {
+	sidecar.Checker
+	idlePercent float64
+	maxSkip     int
+
+	// readCPU samples CPU time; replaced in tests
+	readCPU func() (cpuSample, error)
+
+	mu      sync.Mutex
+	last    cpuSample
+	sampled bool
+	idle    bool // the last real poll was idle
+	skipped int
+}
+
+// Wrap wraps checker so that, in low-power mode, a poll is skipped while
+// the CPU was at least IdlePercent idle since the previous poll. Only an
+// idle check is skipped, and never more than MaxSkip times in a row, so a
+// busy check is always released promptly and a quiet machine is still
+// checked now and then. Without low-power mode, or where CPU time can't be
+// read, checker is called every poll.
+func Wrap(checker sidecar.Checker, cfg Config) sidecar.Checker {
+	if !cfg.Enabled || cfg.IdlePercent <= 0 || cfg.MaxSkip <= 0 {
+		return checker
+	}
+	return &skipper{
+		Checker:     checker,
+		idlePercent: cfg.IdlePercent,
+		maxSkip:     cfg.MaxSkip,
+		readCPU:     readProcStat,
+	}
+}
+
+// Check runs the wrapped checker unless the poll can be skipped.
+func (s *skipper) Check(ctx context.Context) (bool, string, error) {
+	// A check run because a watched file changed is never skipped
+	if !filewatch.Changed(ctx) && s.skip() {
+		return false, "", nil
+	}
+	busy, reason, err := s.Checker.Check(ctx)
+
+	s.mu.Lock()
+	s.idle = !busy && err == nil
+	s.skipped = 0
+	s.mu.Unlock()
+	return busy, reason, err
+}
+
+// skip samples the CPU and reports whether this poll can be skipped
+func (s *skipper) skip() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sample, err := s.readCPU()
+	if err != nil {
+		return false
+	}
+	prev, sampled := s.last, s.sampled
+	s.last, s.sampled = sample, true
+
+	if !sampled || !s.idle || s.skipped >= s.maxSkip || sample.total <= prev.total {
+		return false
+	}
+	percent := 100 * float64(sample.idle-prev.idle) / float64(sample.total-prev.total)
+	if percent < s.idlePercent {
+		return false
+	}
+	s.skipped++
+	logging.Debugf("%s: CPU %.1f%% idle, skipping poll (%d of %d)", s.Name(), percent, s.skipped, s.maxSkip)
+	return true
+}
+
+// readProcStat reads the aggregate "cpu" line of /proc/stat
+func readProcStat() (cpuSample, error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return cpuSample{}, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	if !sc.Scan() {
+		return cpuSample{}, fmt.Errorf("/proc/stat: empty")
+	}
+	return parseCPULine(sc.Text())
+}
+
+// parseCPULine parses "cpu user nice system idle iowait irq softirq steal
+// ...". Guest time is already counted in user and nice, so it's left out.
+func parseCPULine(line string) (cpuSample, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return cpuSample{}, fmt.Errorf("/proc/stat: unexpected line %q", line)
+	}
+	var s cpuSample
+	for i, f := range fields[1:min(len(fields), 9)] {
+		n, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return cpuSample{}, fmt.Errorf("/proc/stat: %w", err)
+		}
+		s.total += n
+		if i == 3 || i == 4 { // idle, iowait
+			s.idle += n
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,110 @@
+package lowpower
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+)
+
+func TestInterval(t *testing.T) {
+	cfg := Config{Enabled: true, Tick: time.Minute}
+	tests := map[time.Duration]time.Duration{
+		10 * time.Second: time.Minute,
+		time.Minute:      time.Minute,
+		90 * time.Second: 2 * time.Minute,
+	}
+	for in, want := range tests {
+		if got := cfg.Interval(in); got != want {
+			t.Errorf("Interval(%s) = %s, want %s", in, got, want)
+		}
+	}
+	if got := (Config{Tick: time.Minute}).Interval(10 * time.Second); got != 10*time.Second {
+		t.Errorf("Interval when disabled = %s", got)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("LOW_POWER", "true")
+	t.Setenv("LOW_POWER_TICK", "2m")
+	t.Setenv("LOW_POWER_IDLE_PERCENT", "90")
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Enabled || cfg.Tick != 2*time.Minute || cfg.IdlePercent != 90 || cfg.MaxSkip != 4 {
+		t.Errorf("cfg = %+v", cfg)
+	}
+
+	t.Setenv("LOW_POWER_IDLE_PERCENT", "150")
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Error("expected error for LOW_POWER_IDLE_PERCENT=150")
+	}
+}
+
+func TestParseCPULine(t *testing.T) {
+	s, err := parseCPULine("cpu  100 5 50 800 40 1 4 0 7 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.idle != 840 || s.total != 1000 {
+		t.Errorf("sample = %+v, want idle 840 total 1000", s)
+	}
+	if _, err := parseCPULine("intr 12345"); err == nil {
+		t.Error("expected error for a non-cpu line")
+	}
+}
+
+func TestWrapSkipsWhileIdle(t *testing.T) {
+	var calls int
+	busy := false
+	inner := sidecar.NewCheckerFunc("jellyfin", func(context.Context) (bool, string, error) {
+		calls++
+		return busy, "1 stream", nil
+	})
+	c := Wrap(inner, Config{Enabled: true, IdlePercent: 95, MaxSkip: 2}).(*skipper)
+
+	// Each poll advances 1000 ticks, idlePerPoll of them idle
+	var sample cpuSample
+	idlePerPoll := uint64(990)
+	c.readCPU = func() (cpuSample, error) {
+		sample.total += 1000
+		sample.idle += idlePerPoll
+		return sample, nil
+	}
+	poll := func() {
+		t.Helper()
+		if _, _, err := c.Check(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	poll() // first poll always runs
+	poll() // skipped
+	poll() // skipped
+	poll() // MaxSkip reached, runs
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+
+	// A stream starts: the CPU is busy, so the poll runs
+	busy = true
+	idlePerPoll = 500
+	poll()
+	if calls != 3 {
+		t.Errorf("busy CPU: calls = %d, want 3", calls)
+	}
+
+	// A busy check is never skipped
+	idlePerPoll = 990
+	poll()
+	poll()
+	if calls != 5 {
+		t.Errorf("busy check: calls = %d, want 5", calls)
+	}
+
+	if got := Wrap(inner, Config{IdlePercent: 95, MaxSkip: 2}); got != inner {
+		t.Error("Wrap when disabled should return the checker unchanged")
+	}
+}
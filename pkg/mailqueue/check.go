@@ -0,0 +1,47 @@
+package mailqueue
+
+import (
+	"context"
+	"fmt"
+)
+
+// Checker implements check.Checker for a mail server queue.
+// Returns an error while the queue is actively draining, so a reboot
+// doesn't cut off deliveries in progress. Deferred mail alone doesn't
+// block: it may wait for days and survives a reboot.
+type Checker struct {
+	Source Source
+}
+
+// NewChecker creates a mail queue checker.
+func NewChecker(source Source) *Checker {
+	return &Checker{Source: source}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "mailqueue"
+}
+
+// Check returns nil if no deliveries are in progress, error otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	q, err := c.Source.Snapshot(ctx)
+	if err != nil {
+		// The mail server being down shouldn't hold up reboots
+		return nil
+	}
+	if q.Draining() {
+		return fmt.Errorf("mail queue draining: %s", q.Describe())
+	}
+	return nil
+}
+
+// Growth compares two snapshots taken some time apart and returns an error
+// if the queue grew by more than maxGrowth messages, e.g. because outbound
+// delivery is broken after an update and everything is piling up.
+func Growth(before, after Queue, maxGrowth int) error {
+	if grew := after.Total - before.Total; grew > maxGrowth {
+		return fmt.Errorf("mail queue grew by %d message(s), from %d to %d (%d deferred)", grew, before.Total, after.Total, after.Deferred)
+	}
+	return nil
+}
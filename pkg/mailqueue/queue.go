@@ -0,0 +1,143 @@
+// Package mailqueue reads the Postfix or Exim mail queue, to tell a queue
+// that is actively draining from one that is merely holding deferred mail.
+package mailqueue
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/privexec"
+	"github.com/addisonbair/homelab-sidecars/pkg/procscan"
+)
+
+// Queue is a snapshot of the mail queue
+type Queue struct {
+	Total    int // every queued message
+	Active   int // messages being delivered right now
+	Deferred int // messages waiting for a retry
+	Hold     int // messages held by the administrator
+}
+
+// Draining reports whether deliveries are in progress.
+func (q Queue) Draining() bool {
+	return q.Active > 0
+}
+
+// Describe summarizes the queue
+func (q Queue) Describe() string {
+	return fmt.Sprintf("%d message(s) queued, %d being delivered, %d deferred", q.Total, q.Active, q.Deferred)
+}
+
+// Source reads the queue of a mail server
+type Source interface {
+	Snapshot(ctx context.Context) (Queue, error)
+}
+
+// Postfix reads the queue with "postqueue -j" (Postfix 3.1+). Listing the
+// queue is allowed for any user by default (authorized_mailq_users).
+type Postfix struct {
+	Runner privexec.Runner
+	// Command defaults to "postqueue"; set it to reach a containerized
+	// Postfix, e.g. "podman exec mailserver postqueue"
+	Command []string
+}
+
+// Snapshot lists the queue.
+func (p *Postfix) Snapshot(ctx context.Context) (Queue, error) {
+	command := p.Command
+	if len(command) == 0 {
+		command = []string{"postqueue"}
+	}
+	out, err := p.Runner.Output(ctx, command[0], append(command[1:], "-j")...)
+	if err != nil {
+		return Queue{}, err
+	}
+	return parsePostqueue(strings.NewReader(string(out)))
+}
+
+// parsePostqueue counts "postqueue -j" output: one JSON object per message.
+func parsePostqueue(r io.Reader) (Queue, error) {
+	var q Queue
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var msg struct {
+			QueueName string `json:"queue_name"`
+		}
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			return Queue{}, fmt.Errorf("decode postqueue output: %w", err)
+		}
+		q.Total++
+		switch msg.QueueName {
+		case "active":
+			q.Active++
+		case "deferred":
+			q.Deferred++
+		case "hold":
+			q.Hold++
+		}
+	}
+	return q, scanner.Err()
+}
+
+// DefaultEximDeliveryPattern matches Exim delivery processes: single
+// message deliveries (-M, -Mc) and queue runners (-q...), but not the
+// listening daemon (-bd).
+const DefaultEximDeliveryPattern = `(^|/)exim4?\s+-(Mc?|q\S*)(\s|$)`
+
+// Exim counts the queue with "exim -bpc" and active deliveries by their
+// processes, since Exim has no separate active queue. Listing the queue
+// needs an Exim admin user (root, or a member of admin_groups).
+type Exim struct {
+	Runner privexec.Runner
+	// Command defaults to "exim"; Debian calls it "exim4"
+	Command []string
+	// DeliveryPattern defaults to DefaultEximDeliveryPattern
+	DeliveryPattern *regexp.Regexp
+	// ProcRoot defaults to /proc
+	ProcRoot string
+}
+
+// Snapshot counts the queue and the running deliveries.
+func (e *Exim) Snapshot(ctx context.Context) (Queue, error) {
+	command := e.Command
+	if len(command) == 0 {
+		command = []string{"exim"}
+	}
+	out, err := e.Runner.Output(ctx, command[0], append(command[1:], "-bpc")...)
+	if err != nil {
+		return Queue{}, err
+	}
+	total, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return Queue{}, fmt.Errorf("parse exim -bpc output %q: %w", strings.TrimSpace(string(out)), err)
+	}
+
+	pattern := e.DeliveryPattern
+	if pattern == nil {
+		pattern = regexp.MustCompile(DefaultEximDeliveryPattern)
+	}
+	procs, err := procscan.Find(e.ProcRoot, pattern)
+	if err != nil {
+		return Queue{}, fmt.Errorf("scan processes: %w", err)
+	}
+
+	q := Queue{Total: total, Active: len(procs)}
+	// Whatever isn't being delivered is waiting for a retry
+	q.Deferred = max(total-q.Active, 0)
+	if total == 0 {
+		// Queue runners with nothing to deliver
+		q.Active = 0
+	}
+	return q, nil
+}
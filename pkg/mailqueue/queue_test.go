@@ -0,0 +1,115 @@
+package mailqueue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePostqueue(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    Queue
+		wantErr bool
+	}{
+		{name: "empty queue", output: "", want: Queue{}},
+		{
+			name: "mixed queues",
+			output: `{"queue_name": "active", "queue_id": "4F8A21C2B3", "arrival_time": 1760600000, "message_size": 2340, "sender": "alerts@example.com", "recipients": [{"address": "me@example.net"}]}
+{"queue_name": "deferred", "queue_id": "5B1C22D3E4", "arrival_time": 1760500000, "message_size": 1200, "sender": "", "recipients": [{"address": "old@example.org", "delay_reason": "connect to mx.example.org[192.0.2.1]:25: Connection timed out"}]}
+{"queue_name": "deferred", "queue_id": "6C2D33E4F5", "arrival_time": 1760500100, "message_size": 900, "sender": "", "recipients": [{"address": "old@example.org"}]}
+{"queue_name": "hold", "queue_id": "7D3E44F5A6", "arrival_time": 1760500200, "message_size": 800, "sender": "spam@example.com", "recipients": [{"address": "me@example.net"}]}
+{"queue_name": "incoming", "queue_id": "8E4F55A6B7", "arrival_time": 1760600001, "message_size": 700, "sender": "x@example.com", "recipients": []}
+`,
+			want: Queue{Total: 5, Active: 1, Deferred: 2, Hold: 1},
+		},
+		{name: "not json", output: "Mail queue is empty\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePostqueue(strings.NewReader(tt.output))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("queue = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExim_Snapshot(t *testing.T) {
+	root := t.TempDir()
+	procs := map[string]string{
+		"100": "/usr/sbin/exim4\x00-bd\x00-q30m\x00",            // daemon
+		"200": "/usr/sbin/exim4\x00-Mc\x001vAbCd-000123-Xy\x00", // delivery
+		"300": "/usr/sbin/exim4\x00-q30m\x00",                   // queue runner
+	}
+	for pid, cmdline := range procs {
+		os.MkdirAll(filepath.Join(root, pid), 0755)
+		if err := os.WriteFile(filepath.Join(root, pid, "cmdline"), []byte(cmdline), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// "sh -c 'echo 7' -bpc" stands in for exim, with -bpc landing in $0
+	e := &Exim{Command: []string{"sh", "-c", "echo 7"}, ProcRoot: root}
+	q, err := e.Snapshot(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (Queue{Total: 7, Active: 2, Deferred: 5}); q != want {
+		t.Errorf("queue = %+v, want %+v", q, want)
+	}
+
+	e.Command = []string{"sh", "-c", "echo 0"}
+	if q, _ := e.Snapshot(context.Background()); q.Draining() {
+		t.Errorf("empty queue draining: %+v", q)
+	}
+}
+
+type fakeSource struct {
+	q   Queue
+	err error
+}
+
+func (f *fakeSource) Snapshot(context.Context) (Queue, error) { return f.q, f.err }
+
+func TestChecker_Check(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  fakeSource
+		wantErr bool
+	}{
+		{name: "empty", source: fakeSource{}, wantErr: false},
+		{name: "deferred only", source: fakeSource{q: Queue{Total: 12, Deferred: 12}}, wantErr: false},
+		{name: "draining", source: fakeSource{q: Queue{Total: 3, Active: 2, Deferred: 1}}, wantErr: true},
+		{name: "mail server down", source: fakeSource{err: errors.New("postqueue: fatal: Queue report unavailable")}, wantErr: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewChecker(&tt.source).Check(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Check = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGrowth(t *testing.T) {
+	before := Queue{Total: 10, Deferred: 10}
+	if err := Growth(before, Queue{Total: 15, Deferred: 15}, 20); err != nil {
+		t.Errorf("small growth: %v", err)
+	}
+	if err := Growth(before, Queue{Total: 40, Deferred: 38}, 20); err == nil || !strings.Contains(err.Error(), "grew by 30") {
+		t.Errorf("Growth = %v, want growth error", err)
+	}
+	if err := Growth(before, Queue{Total: 2}, 0); err != nil {
+		t.Errorf("shrinking queue: %v", err)
+	}
+}
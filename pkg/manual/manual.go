@@ -0,0 +1,145 @@
+// Package manual is a checker whose state is set by hand through a flag
+// file, for exercising inhibitors, notifications and shutdown orchestration
+// end to end without degrading a real array or starting a real stream.
+//
+// The file holds a state and an optional reason, e.g. "busy testing the
+// nightly update". An empty file is busy and a missing one idle, so
+// touching and removing it is enough for most tests:
+//
+//	homelab-sidecar manual busy testing the nightly update
+//	touch /run/homelab-sidecars/manual
+//	rm /run/homelab-sidecars/manual
+package manual
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/check"
+)
+
+// DefaultPath is where the flag file lives
+const DefaultPath = "/run/homelab-sidecars/manual"
+
+// DefaultReason is the reason given when the file doesn't have one
+const DefaultReason = "manual test"
+
+// State is what the checker reports
+type State string
+
+const (
+	// Idle doesn't block
+	Idle State = "idle"
+	// Busy blocks shutdown
+	Busy State = "busy"
+	// Error fails the check, as an unreachable or misbehaving service would
+	Error State = "error"
+	// ForceAllow lets shutdown through regardless of other checks
+	ForceAllow State = "force-allow"
+)
+
+// ParseState parses a state name.
+func ParseState(s string) (State, error) {
+	switch st := State(strings.ToLower(s)); st {
+	case Idle, Busy, Error, ForceAllow:
+		return st, nil
+	}
+	return "", fmt.Errorf("unknown state %q (want idle, busy, error or force-allow)", s)
+}
+
+// Read returns the state in the file at path; a missing file is Idle.
+func Read(path string) (State, string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Idle, "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("read state: %w", err)
+	}
+
+	line, _, _ := strings.Cut(strings.TrimSpace(string(data)), "\n")
+	name, reason, _ := strings.Cut(strings.TrimSpace(line), " ")
+	if name == "" {
+		return Busy, DefaultReason, nil
+	}
+	state, err := ParseState(name)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", path, err)
+	}
+	if reason = strings.TrimSpace(reason); reason == "" {
+		reason = DefaultReason
+	}
+	return state, reason, nil
+}
+
+// Write sets the state in the file at path. Idle removes the file.
+func Write(path string, state State, reason string) error {
+	if state == Idle {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove state: %w", err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create state directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.TrimSpace(string(state)+" "+reason)+"\n"), 0644); err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write state: %w", err)
+	}
+	return nil
+}
+
+// Checker implements check.Evaluator for the flag file.
+type Checker struct {
+	Path string
+}
+
+// NewChecker creates a checker for the flag file at path.
+func NewChecker(path string) *Checker {
+	return &Checker{Path: path}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "manual"
+}
+
+// State reads the flag file.
+func (c *Checker) State() (State, string, error) {
+	return Read(c.Path)
+}
+
+// Check returns an error while the state is Busy or Error, nil otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	state, reason, err := c.State()
+	if err != nil {
+		return fmt.Errorf("manual check failed: %w", err)
+	}
+	switch state {
+	case Busy:
+		return errors.New(reason)
+	case Error:
+		return fmt.Errorf("manual check failed: %s", reason)
+	}
+	return nil
+}
+
+// Evaluate is Check, also returning ForceAllow for a check.Set.
+func (c *Checker) Evaluate(ctx context.Context) check.Result {
+	if state, reason, err := c.State(); err == nil && state == ForceAllow {
+		return check.Result{Check: c.Name(), Verdict: check.ForceAllow, Reason: reason}
+	}
+	if err := c.Check(ctx); err != nil {
+		return check.Result{Check: c.Name(), Verdict: check.Block, Reason: err.Error()}
+	}
+	return check.Result{Check: c.Name(), Verdict: check.Neutral}
+}
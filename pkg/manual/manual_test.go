@@ -0,0 +1,45 @@
+package manual
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/check"
+)
+
+func TestChecker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manual")
+	c := NewChecker(path)
+
+	tests := []struct {
+		name    string
+		set     func()
+		verdict check.Verdict
+		reason  string
+	}{
+		{"missing file", func() {}, check.Neutral, ""},
+		{"touched", func() { os.WriteFile(path, nil, 0644) }, check.Block, DefaultReason},
+		{"busy", func() { Write(path, Busy, "testing the nightly update") }, check.Block, "testing the nightly update"},
+		{"error", func() { Write(path, Error, "") }, check.Block, "manual check failed: manual test"},
+		{"force-allow", func() { Write(path, ForceAllow, "ups drill") }, check.ForceAllow, "ups drill"},
+		{"idle", func() { Write(path, Idle, "") }, check.Neutral, ""},
+		{"garbage", func() { os.WriteFile(path, []byte("maybe\n"), 0644) }, check.Block, "manual check failed: " + path + `: unknown state "maybe" (want idle, busy, error or force-allow)`},
+	}
+	for _, tt := range tests {
+		tt.set()
+		r := c.Evaluate(context.Background())
+		if r.Verdict != tt.verdict || r.Reason != tt.reason {
+			t.Errorf("%s: Evaluate() = %v %q, want %v %q", tt.name, r.Verdict, r.Reason, tt.verdict, tt.reason)
+		}
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+	Write(path, Idle, "")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Write(Idle) left the file: %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+// Package metrics writes check results in the Prometheus text exposition
+// format for node_exporter's textfile collector.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+)
+
+// Result is the outcome of a single check run
+type Result struct {
+	Check    string
+	Busy     bool
+	Err      error
+	Duration time.Duration
+	Time     time.Time
+}
+
+// Gauge is an additional metric sample contributed by a Source
+type Gauge struct {
+	Name   string
+	Help   string
+	Labels map[string]string
+	Value  float64
+}
+
+// Source contributes extra gauges alongside the check results
+type Source interface {
+	Gauges() []Gauge
+}
+
+// Healthy reports whether the check ran cleanly and is not blocking.
+func (r Result) Healthy() bool {
+	return r.Err == nil && !r.Busy
+}
+
+// Write renders results and extra gauges in the Prometheus text format.
+func Write(w io.Writer, results []Result, extra []Gauge) error {
+	var b strings.Builder
+
+	gauge := func(name, help string, value func(Result) float64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, r := range results {
+			fmt.Fprintf(&b, "%s{check=%q} %g\n", name, r.Check, value(r))
+		}
+	}
+
+	gauge("homelab_check_healthy", "Whether the check is passing (1) or failing/blocking (0).",
+		func(r Result) float64 { return boolValue(r.Healthy()) })
+	gauge("homelab_check_busy", "Whether the check is currently blocking shutdown.",
+		func(r Result) float64 { return boolValue(r.Busy) })
+	gauge("homelab_check_error", "Whether the last check run returned an error.",
+		func(r Result) float64 { return boolValue(r.Err != nil) })
+	gauge("homelab_check_duration_seconds", "Duration of the last check run.",
+		func(r Result) float64 { return r.Duration.Seconds() })
+	gauge("homelab_check_last_run_timestamp_seconds", "Unix time of the last check run.",
+		func(r Result) float64 { return float64(r.Time.Unix()) })
+
+	// Extra gauges sharing a name are grouped under one HELP/TYPE header
+	seen := make(map[string]bool)
+	for i, g := range extra {
+		if seen[g.Name] {
+			continue
+		}
+		seen[g.Name] = true
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", g.Name, g.Help, g.Name)
+		for _, other := range extra[i:] {
+			if other.Name == g.Name {
+				fmt.Fprintf(&b, "%s%s %g\n", other.Name, formatLabels(other.Labels), other.Value)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteFile atomically replaces path with the rendered results and gauges. The file is
+// written to a temporary name in the same directory and renamed, so the
+// collector never reads a partial file.
+func WriteFile(path string, results []Result, extra []Gauge) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := Write(tmp, results, extra); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write metrics: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("chmod: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%q", k, labels[k])
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// textfileChecker records each check run to a textfile
+type textfileChecker struct {
+	sidecar.Checker
+	path    string
+	sources []Source
+
+	mu sync.Mutex
+}
+
+// Textfile wraps checker so every run is written to path for the
+// node_exporter textfile collector, together with any gauges from sources.
+// An empty path returns checker unchanged.
+func Textfile(checker sidecar.Checker, path string, sources ...Source) sidecar.Checker {
+	if path == "" {
+		return checker
+	}
+	return &textfileChecker{Checker: checker, path: path, sources: sources}
+}
+
+// Check runs the wrapped checker and writes the result.
+func (c *textfileChecker) Check(ctx context.Context) (bool, string, error) {
+	start := time.Now()
+	busy, reason, err := c.Checker.Check(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := Result{
+		Check:    c.Name(),
+		Busy:     busy,
+		Err:      err,
+		Duration: time.Since(start),
+		Time:     start,
+	}
+	var extra []Gauge
+	for _, src := range c.sources {
+		extra = append(extra, src.Gauges()...)
+	}
+	if werr := WriteFile(c.path, []Result{result}, extra); werr != nil {
+		logging.Warnf("writing metrics textfile: %v", werr)
+	}
+	return busy, reason, err
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+)
+
+func TestWrite(t *testing.T) {
+	results := []Result{
+		{Check: "raid", Busy: true, Duration: 1500 * time.Millisecond, Time: time.Unix(1700000000, 0)},
+		{Check: "jellyfin", Err: errors.New("unreachable"), Time: time.Unix(1700000000, 0)},
+		{Check: "qbittorrent", Time: time.Unix(1700000000, 0)},
+	}
+
+	extra := []Gauge{
+		{Name: "homelab_extra", Help: "An extra gauge.", Labels: map[string]string{"b": "2", "a": "1"}, Value: 3},
+		{Name: "homelab_extra", Help: "An extra gauge.", Value: 4},
+	}
+
+	var b strings.Builder
+	if err := Write(&b, results, extra); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"# TYPE homelab_check_healthy gauge",
+		`homelab_check_healthy{check="raid"} 0`,
+		`homelab_check_healthy{check="jellyfin"} 0`,
+		`homelab_check_healthy{check="qbittorrent"} 1`,
+		`homelab_check_busy{check="raid"} 1`,
+		`homelab_check_error{check="jellyfin"} 1`,
+		`homelab_check_duration_seconds{check="raid"} 1.5`,
+		`homelab_check_last_run_timestamp_seconds{check="raid"} 1.7e+09`,
+		`homelab_extra{a="1",b="2"} 3`,
+		"homelab_extra 4",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "# TYPE homelab_extra gauge"); n != 1 {
+		t.Errorf("extra gauge header written %d times", n)
+	}
+}
+
+func TestTextfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "raid.prom")
+	checker := Textfile(sidecar.NewCheckerFunc("raid", func(ctx context.Context) (bool, string, error) {
+		return true, "md0 degraded", nil
+	}), path)
+
+	busy, reason, err := checker.Check(context.Background())
+	if !busy || reason != "md0 degraded" || err != nil {
+		t.Errorf("wrapped result changed: %v %q %v", busy, reason, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("textfile not written: %v", err)
+	}
+	if !strings.Contains(string(data), `homelab_check_busy{check="raid"} 1`) {
+		t.Errorf("unexpected textfile content:\n%s", data)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("temp files left behind: %d entries", len(entries))
+	}
+}
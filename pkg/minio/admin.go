@@ -0,0 +1,74 @@
+package minio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const adminPrefix = "/minio/admin/v3"
+
+// HealState is the subset of the background heal status we use
+type HealState struct {
+	// HealDisks are the endpoints of drives currently being healed, e.g.
+	// after a drive replacement
+	HealDisks []string `json:"heal_disks"`
+	// OfflineEndpoints are drives that can't be reached
+	OfflineEndpoints []string `json:"offline_endpoints"`
+}
+
+// HealStatus returns the state of background healing.
+func (c *Client) HealStatus(ctx context.Context) (*HealState, error) {
+	body, err := c.get(ctx, adminPrefix+"/background-heal/status", nil)
+	if err != nil {
+		return nil, err
+	}
+	var state HealState
+	if err := json.Unmarshal(body, &state); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &state, nil
+}
+
+// RebalanceStatus is the subset of the rebalance status we use
+type RebalanceStatus struct {
+	ID    string          `json:"id"`
+	Pools []RebalancePool `json:"pools"`
+}
+
+// RebalancePool is the rebalance state of one server pool
+type RebalancePool struct {
+	ID     int     `json:"id"`
+	Status string  `json:"status"` // Started, Completed, Stopped or Failed
+	Used   float64 `json:"used"`   // fraction of pool capacity used
+}
+
+// Running reports whether any pool is still rebalancing.
+func (s *RebalanceStatus) Running() bool {
+	for _, p := range s.Pools {
+		if p.Status == "Started" {
+			return true
+		}
+	}
+	return false
+}
+
+// RebalanceStatus returns the state of the pool rebalance. It returns nil,
+// nil if no rebalance was ever started or the deployment has a single pool.
+func (c *Client) RebalanceStatus(ctx context.Context) (*RebalanceStatus, error) {
+	body, err := c.get(ctx, adminPrefix+"/rebalance/status", nil)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && (apiErr.Code == "XMinioAdminRebalanceNotStarted" || apiErr.StatusCode == http.StatusNotImplemented) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var status RebalanceStatus
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &status, nil
+}
@@ -0,0 +1,142 @@
+package minio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultMaxUploadAge is how long a multipart upload counts as in progress.
+// Clients that crash leave their uploads behind until a lifecycle rule
+// aborts them, so older ones are treated as abandoned.
+const DefaultMaxUploadAge = 24 * time.Hour
+
+// Checker implements check.Checker for MinIO background work.
+// Returns an error while drives are healing, a pool rebalance is running,
+// or multipart uploads are in progress, so the object-storage box isn't
+// rebooted in the middle of them.
+type Checker struct {
+	Client *Client
+	// Buckets limits the multipart upload check; nil checks every bucket
+	Buckets []string
+	// MaxUploadAge ignores older uploads, 0 = DefaultMaxUploadAge
+	MaxUploadAge time.Duration
+	// SkipUploads disables the multipart upload check
+	SkipUploads bool
+}
+
+// NewChecker creates a MinIO activity checker.
+func NewChecker(client *Client) *Checker {
+	return &Checker{
+		Client:       client,
+		MaxUploadAge: DefaultMaxUploadAge,
+	}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "minio"
+}
+
+// Check returns nil if MinIO is idle, error describing the activity
+// otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	reasons, err := c.Activity(ctx)
+	if IsAccessDenied(err) {
+		// MinIO is up but we can't see what it's doing; don't assume idle
+		return fmt.Errorf("cannot query minio: %w", err)
+	}
+	if err != nil {
+		// MinIO being down shouldn't hold up reboots
+		return nil
+	}
+	if len(reasons) > 0 {
+		return errors.New(strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// Activity returns a description of each reason MinIO is busy.
+func (c *Checker) Activity(ctx context.Context) ([]string, error) {
+	var reasons []string
+
+	heal, err := c.Client.HealStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("heal status: %w", err)
+	}
+	if len(heal.HealDisks) > 0 {
+		reasons = append(reasons, fmt.Sprintf("healing %s", strings.Join(heal.HealDisks, ", ")))
+	}
+
+	rebalance, err := c.Client.RebalanceStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("rebalance status: %w", err)
+	}
+	if rebalance != nil && rebalance.Running() {
+		reasons = append(reasons, "rebalancing pools")
+	}
+
+	if !c.SkipUploads {
+		uploads, err := c.uploads(ctx, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if len(uploads) > 0 {
+			reasons = append(reasons, describeUploads(uploads))
+		}
+	}
+
+	return reasons, nil
+}
+
+// uploads returns multipart uploads started within MaxUploadAge.
+func (c *Checker) uploads(ctx context.Context, now time.Time) ([]Upload, error) {
+	buckets := c.Buckets
+	if buckets == nil {
+		var err error
+		if buckets, err = c.Client.Buckets(ctx); err != nil {
+			return nil, fmt.Errorf("list buckets: %w", err)
+		}
+	}
+
+	maxAge := c.MaxUploadAge
+	if maxAge <= 0 {
+		maxAge = DefaultMaxUploadAge
+	}
+
+	var recent []Upload
+	for _, bucket := range buckets {
+		uploads, err := c.Client.MultipartUploads(ctx, bucket)
+		if err != nil {
+			return nil, fmt.Errorf("list uploads in %s: %w", bucket, err)
+		}
+		for _, u := range uploads {
+			if now.Sub(u.Initiated) < maxAge {
+				recent = append(recent, u)
+			}
+		}
+	}
+	return recent, nil
+}
+
+// IsAccessDenied reports whether err is MinIO rejecting the credentials or
+// their permissions, as opposed to MinIO being unreachable.
+func IsAccessDenied(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == 403
+}
+
+func describeUploads(uploads []Upload) string {
+	const maxListed = 3
+	names := make([]string, 0, maxListed)
+	for i, u := range uploads {
+		if i == maxListed {
+			names = append(names, fmt.Sprintf("and %d more", len(uploads)-maxListed))
+			break
+		}
+		names = append(names, u.Bucket+"/"+u.Key)
+	}
+	return fmt.Sprintf("%d multipart upload(s): %s", len(uploads), strings.Join(names, ", "))
+}
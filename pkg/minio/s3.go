@@ -0,0 +1,57 @@
+package minio
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Upload is an in-progress multipart upload
+type Upload struct {
+	Bucket    string
+	Key       string    `xml:"Key"`
+	UploadID  string    `xml:"UploadId"`
+	Initiated time.Time `xml:"Initiated"`
+}
+
+// Buckets returns the names of all buckets.
+func (c *Client) Buckets(ctx context.Context) ([]string, error) {
+	body, err := c.get(ctx, "/", nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Buckets []struct {
+			Name string `xml:"Name"`
+		} `xml:"Buckets>Bucket"`
+	}
+	if err := xml.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	names := make([]string, len(result.Buckets))
+	for i, b := range result.Buckets {
+		names[i] = b.Name
+	}
+	return names, nil
+}
+
+// MultipartUploads returns the multipart uploads in progress in bucket (the
+// first page of up to 1000, which is plenty to know whether any exist).
+func (c *Client) MultipartUploads(ctx context.Context, bucket string) ([]Upload, error) {
+	body, err := c.get(ctx, "/"+url.PathEscape(bucket), url.Values{"uploads": {""}})
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Uploads []Upload `xml:"Upload"`
+	}
+	if err := xml.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	for i := range result.Uploads {
+		result.Uploads[i].Bucket = bucket
+	}
+	return result.Uploads, nil
+}
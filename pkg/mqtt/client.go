@@ -0,0 +1,290 @@
+// Package mqtt publishes inhibitor state to an MQTT broker, with Home
+// Assistant discovery configs so each lock and check shows up as a binary
+// sensor. It speaks just enough MQTT 3.1.1 for that: retained QoS 0
+// publishes, keepalive pings and a last will marking the sidecar offline.
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ErrUnauthorized is returned when the broker rejects the credentials
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Message is a retained-or-not publish
+type Message struct {
+	Topic   string
+	Payload []byte
+	Retain  bool
+}
+
+// Client is a publish-only MQTT connection that reconnects as needed.
+// Set the fields before the first Publish.
+type Client struct {
+	Address   string // host:port
+	TLS       *tls.Config
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive time.Duration // default 60s
+	Will      *Message      // published by the broker if the connection is lost
+	Birth     *Message      // published after each connect, e.g. to undo the will
+	Timeout   time.Duration // for connecting and each write, default 10s
+
+	mu       sync.Mutex
+	conn     net.Conn
+	dead     chan struct{} // closed when the read loop sees the connection end
+	lastSent time.Time
+}
+
+// NewClient creates a client from a broker URL: mqtt://host[:1883] or
+// mqtts://host[:8883] for TLS, with optional user:password.
+func NewClient(rawURL, clientID string) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("broker URL: %w", err)
+	}
+	c := &Client{ClientID: clientID}
+	port := "1883"
+	switch u.Scheme {
+	case "mqtt", "tcp":
+	case "mqtts", "ssl", "tls":
+		port = "8883"
+		c.TLS = &tls.Config{ServerName: u.Hostname()}
+	default:
+		return nil, fmt.Errorf("broker URL: unsupported scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, errors.New("broker URL: missing host")
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	c.Address = net.JoinHostPort(u.Hostname(), port)
+	if u.User != nil {
+		c.Username = u.User.Username()
+		c.Password, _ = u.User.Password()
+	}
+	return c, nil
+}
+
+// Publish sends msgs at QoS 0, connecting first if needed. On a write
+// error the connection is dropped and the next Publish reconnects.
+func (c *Client) Publish(ctx context.Context, msgs ...Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.connect(ctx); err != nil {
+		return err
+	}
+	for _, m := range msgs {
+		if err := c.write(publishPacket(m)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ping sends a keepalive if nothing has been sent for half the keepalive
+// interval. It does nothing while disconnected.
+func (c *Client) Ping() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil || time.Since(c.lastSent) < c.keepAlive()/2 {
+		return nil
+	}
+	return c.write([]byte{0xc0, 0x00}) // PINGREQ
+}
+
+// Close disconnects. A clean disconnect stops the broker from publishing
+// the will, so Close publishes it first.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	if c.Will != nil {
+		if err := c.write(publishPacket(*c.Will)); err != nil {
+			return err
+		}
+	}
+	if err := c.write([]byte{0xe0, 0x00}); err != nil { // DISCONNECT
+		return err
+	}
+	return c.drop()
+}
+
+func (c *Client) keepAlive() time.Duration {
+	if c.KeepAlive > 0 {
+		return c.KeepAlive
+	}
+	return 60 * time.Second
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return 10 * time.Second
+}
+
+// connect opens the connection unless it is already up. Caller holds mu.
+func (c *Client) connect(ctx context.Context) error {
+	if c.conn != nil {
+		select {
+		case <-c.dead:
+			c.drop()
+		default:
+			return nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout())
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.Address)
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	if c.TLS != nil {
+		tc := tls.Client(conn, c.TLS)
+		if err := tc.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return fmt.Errorf("connect: %w", err)
+		}
+		conn = tc
+	}
+
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	if _, err := conn.Write(c.connectPacket()); err != nil {
+		conn.Close()
+		return fmt.Errorf("connect: %w", err)
+	}
+	var ack [4]byte
+	if _, err := io.ReadFull(conn, ack[:]); err != nil {
+		conn.Close()
+		return fmt.Errorf("connect: read CONNACK: %w", err)
+	}
+	conn.SetDeadline(time.Time{})
+	if ack[0] != 0x20 || ack[1] != 0x02 {
+		conn.Close()
+		return fmt.Errorf("connect: unexpected packet 0x%02x", ack[0])
+	}
+	switch code := ack[3]; code {
+	case 0:
+	case 4, 5:
+		conn.Close()
+		return fmt.Errorf("connect: refused: %d: %w", code, ErrUnauthorized)
+	default:
+		conn.Close()
+		return fmt.Errorf("connect: refused: %d", code)
+	}
+
+	c.conn = conn
+	c.dead = make(chan struct{})
+	c.lastSent = time.Now()
+	go discard(conn, c.dead)
+	if c.Birth != nil {
+		return c.write(publishPacket(*c.Birth))
+	}
+	return nil
+}
+
+// discard reads and drops whatever the broker sends (PINGRESP), closing
+// dead when the connection ends.
+func discard(conn net.Conn, dead chan struct{}) {
+	io.Copy(io.Discard, conn)
+	close(dead)
+}
+
+// write sends a packet, dropping the connection on error. Caller holds mu.
+func (c *Client) write(packet []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout()))
+	if _, err := c.conn.Write(packet); err != nil {
+		c.drop()
+		return fmt.Errorf("write: %w", err)
+	}
+	c.lastSent = time.Now()
+	return nil
+}
+
+func (c *Client) drop() error {
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *Client) connectPacket() []byte {
+	flags := byte(0x02) // clean session
+	payload := appendString(nil, c.ClientID)
+	if c.Will != nil {
+		flags |= 0x04
+		if c.Will.Retain {
+			flags |= 0x20
+		}
+		payload = appendString(payload, c.Will.Topic)
+		payload = appendBytes(payload, c.Will.Payload)
+	}
+	if c.Username != "" {
+		flags |= 0x80
+		payload = appendString(payload, c.Username)
+		if c.Password != "" {
+			flags |= 0x40
+			payload = appendString(payload, c.Password)
+		}
+	}
+
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags) // protocol level 3.1.1
+	body = binary.BigEndian.AppendUint16(body, uint16(c.keepAlive()/time.Second))
+	body = append(body, payload...)
+	return packet(0x10, body)
+}
+
+func publishPacket(m Message) []byte {
+	header := byte(0x30)
+	if m.Retain {
+		header |= 0x01
+	}
+	return packet(header, append(appendString(nil, m.Topic), m.Payload...))
+}
+
+// packet prefixes body with the fixed header and its variable-length size.
+func packet(header byte, body []byte) []byte {
+	out := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(out, body...)
+}
+
+func appendString(b []byte, s string) []byte {
+	return appendBytes(b, []byte(s))
+}
+
+func appendBytes(b, data []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+	return append(b, data...)
+}
@@ -0,0 +1,259 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/check"
+)
+
+// broker accepts one connection at a time and records what it is sent
+type broker struct {
+	ln       net.Listener
+	code     byte // CONNACK return code
+	connects chan []byte
+	packets  chan packetRecord
+}
+
+type packetRecord struct {
+	header byte
+	topic  string
+	body   string
+}
+
+func newBroker(t *testing.T, code byte) *broker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &broker{ln: ln, code: code, connects: make(chan []byte, 10), packets: make(chan packetRecord, 100)}
+	go b.serve()
+	t.Cleanup(func() { ln.Close() })
+	return b
+}
+
+func (b *broker) serve() {
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(conn)
+	}
+}
+
+func (b *broker) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	header, body, err := readPacket(r)
+	if err != nil || header != 0x10 {
+		return
+	}
+	b.connects <- body
+	conn.Write([]byte{0x20, 0x02, 0x00, b.code})
+	for {
+		header, body, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		rec := packetRecord{header: header}
+		if header&0xf0 == 0x30 {
+			n := binary.BigEndian.Uint16(body)
+			rec.topic, rec.body = string(body[2:2+n]), string(body[2+n:])
+		}
+		b.packets <- rec
+	}
+}
+
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, mult := 0, 1
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n += int(c&0x7f) * mult
+		mult *= 128
+		if c&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, n)
+	_, err = io.ReadFull(r, body)
+	return header, body, err
+}
+
+// drain returns the packets received until none arrive for a moment
+func (b *broker) drain() []packetRecord {
+	var out []packetRecord
+	for {
+		select {
+		case p := <-b.packets:
+			out = append(out, p)
+		case <-time.After(100 * time.Millisecond):
+			return out
+		}
+	}
+}
+
+func TestPacket_Length(t *testing.T) {
+	p := packet(0x30, make([]byte, 200))
+	if p[1] != 0xc8 || p[2] != 0x01 || len(p) != 203 {
+		t.Errorf("packet header = % x, want 30 c8 01", p[:3])
+	}
+}
+
+func TestPublisher(t *testing.T) {
+	b := newBroker(t, 0)
+	client, err := NewClient("mqtt://ha:secret@"+b.ln.Addr().String(), "nas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewPublisher(client)
+
+	state := check.State{
+		Who: "nas", What: "shutdown", Set: "Storage", Held: true, Reason: "raid: md0 rebuilding",
+		Results: []check.Result{
+			{Check: "raid", Verdict: check.Block, Reason: "md0 rebuilding"},
+			{Check: "zfs", Verdict: check.Neutral},
+		},
+	}
+	if err := p.Publish(context.Background(), state); err != nil {
+		t.Fatal(err)
+	}
+
+	connect := string(<-b.connects)
+	for _, want := range []string{"MQTT", "nas", "homelab-sidecars/nas/availability", "offline", "ha", "secret"} {
+		if !strings.Contains(connect, want) {
+			t.Errorf("CONNECT missing %q", want)
+		}
+	}
+
+	got := map[string]string{}
+	var order []string
+	for _, pkt := range b.drain() {
+		if pkt.header != 0x31 {
+			t.Errorf("publish %s: header 0x%02x, want retained QoS 0", pkt.topic, pkt.header)
+		}
+		got[pkt.topic] = pkt.body
+		order = append(order, pkt.topic)
+	}
+	if len(order) == 0 || order[0] != "homelab-sidecars/nas/availability" || got[order[0]] != "online" {
+		t.Errorf("first publish = %v, want availability online", order)
+	}
+	for topic, want := range map[string]string{
+		"homelab-sidecars/nas/storage/state":      "ON",
+		"homelab-sidecars/nas/storage/raid/state": "ON",
+		"homelab-sidecars/nas/storage/zfs/state":  "OFF",
+	} {
+		if got[topic] != want {
+			t.Errorf("%s = %q, want %q", topic, got[topic], want)
+		}
+	}
+
+	var config map[string]any
+	if err := json.Unmarshal([]byte(got["homeassistant/binary_sensor/nas/storage_raid/config"]), &config); err != nil {
+		t.Fatalf("raid discovery config: %v", err)
+	}
+	if config["state_topic"] != "homelab-sidecars/nas/storage/raid/state" || config["unique_id"] != "nas_storage_raid" {
+		t.Errorf("raid discovery config = %v", config)
+	}
+	var attrs map[string]any
+	json.Unmarshal([]byte(got["homelab-sidecars/nas/storage/attributes"]), &attrs)
+	if attrs["reason"] != "raid: md0 rebuilding" || attrs["what"] != "shutdown" {
+		t.Errorf("lock attributes = %v", attrs)
+	}
+
+	// Nothing changed: nothing is republished
+	if err := p.Publish(context.Background(), state); err != nil {
+		t.Fatal(err)
+	}
+	if pkts := b.drain(); len(pkts) != 0 {
+		t.Errorf("unchanged state republished %d packets", len(pkts))
+	}
+
+	// The rebuild finished: only the changed states go out
+	state.Held, state.Reason = false, ""
+	state.Results[0] = check.Result{Check: "raid", Verdict: check.Neutral}
+	if err := p.Publish(context.Background(), state); err != nil {
+		t.Fatal(err)
+	}
+	got = map[string]string{}
+	for _, pkt := range b.drain() {
+		got[pkt.topic] = pkt.body
+	}
+	if len(got) != 4 || got["homelab-sidecars/nas/storage/state"] != "OFF" || got["homelab-sidecars/nas/storage/raid/state"] != "OFF" {
+		t.Errorf("after rebuild published %v, want lock and raid state and attributes", got)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	pkts := b.drain()
+	if len(pkts) != 2 || pkts[0].body != "offline" || pkts[1].header != 0xe0 {
+		t.Errorf("Close() sent %+v, want offline then DISCONNECT", pkts)
+	}
+}
+
+func TestPublisher_NoDiscovery(t *testing.T) {
+	b := newBroker(t, 0)
+	client, _ := NewClient("mqtt://"+b.ln.Addr().String(), "nas")
+	p := NewPublisher(client)
+	p.DiscoveryPrefix = ""
+
+	if err := p.Publish(context.Background(), check.State{Who: "nas", What: "sleep", Set: "media"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, pkt := range b.drain() {
+		if strings.HasPrefix(pkt.topic, "homeassistant/") || pkt.topic == "" {
+			t.Errorf("published %q with discovery disabled", pkt.topic)
+		}
+	}
+}
+
+func TestClient_Unauthorized(t *testing.T) {
+	b := newBroker(t, 5)
+	client, _ := NewClient("mqtt://"+b.ln.Addr().String(), "nas")
+	err := client.Publish(context.Background(), Message{Topic: "t", Payload: []byte("x")})
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Publish() = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		url, addr string
+		tls       bool
+	}{
+		{"mqtt://broker", "broker:1883", false},
+		{"tcp://broker:1884", "broker:1884", false},
+		{"mqtts://broker", "broker:8883", true},
+	}
+	for _, tt := range tests {
+		c, err := NewClient(tt.url, "x")
+		if err != nil {
+			t.Errorf("NewClient(%s): %v", tt.url, err)
+			continue
+		}
+		if c.Address != tt.addr || (c.TLS != nil) != tt.tls {
+			t.Errorf("NewClient(%s) = %s, TLS %v", tt.url, c.Address, c.TLS != nil)
+		}
+	}
+	for _, bad := range []string{"http://broker", "mqtt://"} {
+		if _, err := NewClient(bad, "x"); err == nil {
+			t.Errorf("NewClient(%s): want error", bad)
+		}
+	}
+}
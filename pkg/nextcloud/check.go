@@ -0,0 +1,128 @@
+package nextcloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultMinUploadSize is the request body size from which a single PUT
+// counts as a large upload worth waiting for.
+const DefaultMinUploadSize = 10 << 20
+
+// Checker implements check.Checker for Nextcloud activity.
+// Returns an error while maintenance mode is on (an upgrade is running),
+// while large or chunked uploads are in flight, or, if WorkerThreshold is
+// set, while at least that many PHP workers are busy.
+//
+// Upload and worker detection needs PHP-FPM and the serverinfo token; without
+// them only maintenance mode is checked.
+type Checker struct {
+	Client          *Client
+	MinUploadSize   int64 // bytes, 0 = DefaultMinUploadSize
+	WorkerThreshold int   // busy workers that block, 0 = disabled
+}
+
+// NewChecker creates a Nextcloud activity checker.
+func NewChecker(client *Client) *Checker {
+	return &Checker{
+		Client:        client,
+		MinUploadSize: DefaultMinUploadSize,
+	}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "nextcloud"
+}
+
+// Check returns nil if Nextcloud is idle, error describing the activity
+// otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	reasons, err := c.Activity(ctx)
+	if err != nil {
+		// Nextcloud being down shouldn't hold up reboots
+		return nil
+	}
+	if len(reasons) > 0 {
+		return errors.New(strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// Activity returns a description of each reason Nextcloud is busy.
+// If the serverinfo report is unavailable only maintenance mode is
+// reported.
+func (c *Checker) Activity(ctx context.Context) ([]string, error) {
+	status, err := c.Client.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if status.Maintenance {
+		// Interrupting an upgrade leaves the instance broken
+		return []string{"maintenance mode"}, nil
+	}
+
+	info, err := c.Client.ServerInfo(ctx)
+	if err != nil || info.FPM == nil {
+		return nil, nil
+	}
+
+	minSize := c.MinUploadSize
+	if minSize <= 0 {
+		minSize = DefaultMinUploadSize
+	}
+
+	var reasons, uploads []string
+	busy := 0
+	for _, p := range info.FPM.Procs {
+		if !p.Running() || p.Self() {
+			continue
+		}
+		busy++
+		if p.ChunkedUpload() || (p.Method == "PUT" && p.Length >= minSize) {
+			uploads = append(uploads, describeUpload(&p))
+		}
+	}
+	if len(info.FPM.Procs) == 0 {
+		// Process list not exposed; discount our own request
+		busy = max(info.FPM.ActiveProcesses-1, 0)
+	}
+
+	if len(uploads) > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d upload(s) in progress: %s", len(uploads), strings.Join(uploads, ", ")))
+	}
+	if c.WorkerThreshold > 0 && busy >= c.WorkerThreshold {
+		reasons = append(reasons, fmt.Sprintf("%d PHP workers busy", busy))
+	}
+	return reasons, nil
+}
+
+// Health returns an error if Nextcloud is stuck mid-update: maintenance
+// mode is on or the database still needs upgrading. Used as a post-boot
+// health check, where either means an update didn't finish.
+func Health(ctx context.Context, client *Client) error {
+	status, err := client.Status(ctx)
+	if err != nil {
+		return err
+	}
+	switch {
+	case status.Maintenance:
+		return fmt.Errorf("nextcloud %s is in maintenance mode", status.Version)
+	case status.NeedsDBUpgrade:
+		return fmt.Errorf("nextcloud %s needs a database upgrade", status.Version)
+	case !status.Installed:
+		return fmt.Errorf("nextcloud is not installed")
+	}
+	return nil
+}
+
+func describeUpload(p *FPMProcess) string {
+	elapsed := p.Duration().Round(time.Second)
+	if p.Length > 0 {
+		return fmt.Sprintf("%s %s (%.1f MB, %s)", p.Method, p.URI, float64(p.Length)/1e6, elapsed)
+	}
+	return fmt.Sprintf("%s %s (%s)", p.Method, p.URI, elapsed)
+}
@@ -0,0 +1,184 @@
+// Package nextcloud provides a client for Nextcloud's status and serverinfo
+// APIs, used to detect uploads in flight and unfinished updates.
+package nextcloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Status is the unauthenticated /status.php response
+type Status struct {
+	Installed      bool   `json:"installed"`
+	Maintenance    bool   `json:"maintenance"`
+	NeedsDBUpgrade bool   `json:"needsDbUpgrade"`
+	Version        string `json:"versionstring"`
+}
+
+// ServerInfo is the subset of the serverinfo app's report we use
+type ServerInfo struct {
+	Version     string
+	ActiveUsers ActiveUsers
+	// FPM is nil when PHP isn't running under PHP-FPM
+	FPM *FPMStatus
+}
+
+// ActiveUsers counts users seen recently
+type ActiveUsers struct {
+	Last5Minutes int `json:"last5minutes"`
+	Last1Hour    int `json:"last1hour"`
+	Last24Hours  int `json:"last24hours"`
+}
+
+// FPMStatus is PHP's fpm_get_status() as reported by serverinfo
+type FPMStatus struct {
+	ActiveProcesses int          `json:"active-processes"`
+	IdleProcesses   int          `json:"idle-processes"`
+	Procs           []FPMProcess `json:"procs"`
+}
+
+// FPMProcess is a single PHP-FPM worker
+type FPMProcess struct {
+	PID    int    `json:"pid"`
+	State  string `json:"state"` // Running, Idle, ...
+	Method string `json:"request-method"`
+	URI    string `json:"request-uri"`
+	// Length is the request body size in bytes
+	Length int64 `json:"request-length"`
+	// DurationUsec is how long the request has been running
+	DurationUsec int64 `json:"request-duration"`
+}
+
+// Running reports whether the worker is handling a request.
+func (p *FPMProcess) Running() bool {
+	return strings.EqualFold(p.State, "Running")
+}
+
+// Duration returns how long the current request has been running.
+func (p *FPMProcess) Duration() time.Duration {
+	return time.Duration(p.DurationUsec) * time.Microsecond
+}
+
+// ChunkedUpload reports whether the request is part of a chunked upload:
+// a chunk, or the final MOVE that assembles the file.
+func (p *FPMProcess) ChunkedUpload() bool {
+	return strings.Contains(p.URI, "/remote.php/dav/uploads/")
+}
+
+// serverInfoPath is excluded from worker counts, since our own request
+// always occupies one
+const serverInfoPath = "/ocs/v2.php/apps/serverinfo/api/v1/info"
+
+// Self reports whether the worker is serving our own serverinfo request.
+func (p *FPMProcess) Self() bool {
+	return strings.Contains(p.URI, "/apps/serverinfo/")
+}
+
+// Client handles communication with the Nextcloud API
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a new Nextcloud client. token is the serverinfo token
+// (occ config:app:set serverinfo token --value ...); it is only needed for
+// ServerInfo.
+func NewClient(baseURL, token string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// Status returns the instance status, including whether maintenance mode
+// is on.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/status.php", nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// status.php answers 503 in maintenance mode but still sends the body
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	var status Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &status, nil
+}
+
+// ServerInfo returns the serverinfo report.
+func (c *Client) ServerInfo(ctx context.Context) (*ServerInfo, error) {
+	url := c.baseURL + serverInfoPath + "?format=json&skipApps=true&skipUpdate=true"
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("OCS-APIRequest", "true")
+	if c.token != "" {
+		req.Header.Set("NC-Token", c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	var body struct {
+		OCS struct {
+			Data struct {
+				Nextcloud struct {
+					System struct {
+						Version string `json:"version"`
+					} `json:"system"`
+				} `json:"nextcloud"`
+				Server struct {
+					PHP struct {
+						// false when not running under FPM
+						FPM json.RawMessage `json:"fpm"`
+					} `json:"php"`
+				} `json:"server"`
+				ActiveUsers ActiveUsers `json:"activeUsers"`
+			} `json:"data"`
+		} `json:"ocs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	data := body.OCS.Data
+	info := &ServerInfo{
+		Version:     data.Nextcloud.System.Version,
+		ActiveUsers: data.ActiveUsers,
+	}
+	if fpm := bytes.TrimSpace(data.Server.PHP.FPM); len(fpm) > 0 && fpm[0] == '{' {
+		info.FPM = &FPMStatus{}
+		if err := json.Unmarshal(fpm, info.FPM); err != nil {
+			return nil, fmt.Errorf("decode fpm status: %w", err)
+		}
+	}
+	return info, nil
+}
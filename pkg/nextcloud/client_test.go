@@ -0,0 +1,207 @@
+package nextcloud
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const serverInfoFPM = `{"ocs": {"meta": {"status": "ok"}, "data": {
+	"nextcloud": {"system": {"version": "28.0.1.1"}},
+	"server": {"php": {"version": "8.2.14", "fpm": {
+		"pool": "www", "active-processes": 4, "idle-processes": 6,
+		"procs": [
+			{"pid": 10, "state": "Running", "request-method": "GET", "request-uri": "/ocs/v2.php/apps/serverinfo/api/v1/info", "request-length": 0, "request-duration": 1000},
+			{"pid": 11, "state": "Running", "request-method": "PUT", "request-uri": "/remote.php/dav/files/alice/holiday.mkv", "request-length": 2100000000, "request-duration": 35000000},
+			{"pid": 12, "state": "Running", "request-method": "PUT", "request-uri": "/remote.php/dav/uploads/bob/web-file-upload-1234/00001", "request-length": 10485760, "request-duration": 2000000},
+			{"pid": 13, "state": "Running", "request-method": "PROPFIND", "request-uri": "/remote.php/dav/files/alice/", "request-length": 300, "request-duration": 50000},
+			{"pid": 14, "state": "Idle", "request-method": "PUT", "request-uri": "/remote.php/dav/files/alice/old.mkv", "request-length": 900000000, "request-duration": 60000000}
+		]}}},
+	"activeUsers": {"last5minutes": 2, "last1hour": 3, "last24hours": 5}
+}}}`
+
+const serverInfoNoFPM = `{"ocs": {"data": {
+	"nextcloud": {"system": {"version": "28.0.1.1"}},
+	"server": {"php": {"version": "8.2.14", "fpm": false}},
+	"activeUsers": {"last5minutes": 1}
+}}}`
+
+func newServer(t *testing.T, statusCode int, status, serverInfo string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/status.php":
+			w.WriteHeader(statusCode)
+			w.Write([]byte(status))
+		case serverInfoPath:
+			if r.Header.Get("NC-Token") != "test-token" || r.Header.Get("OCS-APIRequest") != "true" {
+				w.WriteHeader(401)
+				return
+			}
+			w.Write([]byte(serverInfo))
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(404)
+		}
+	}))
+}
+
+func TestClient_ServerInfo(t *testing.T) {
+	server := newServer(t, 200, `{}`, serverInfoFPM)
+	defer server.Close()
+
+	info, err := NewClient(server.URL, "test-token", 5*time.Second).ServerInfo(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Version != "28.0.1.1" {
+		t.Errorf("Version = %q", info.Version)
+	}
+	if info.ActiveUsers.Last5Minutes != 2 {
+		t.Errorf("Last5Minutes = %d, want 2", info.ActiveUsers.Last5Minutes)
+	}
+	if info.FPM == nil || len(info.FPM.Procs) != 5 {
+		t.Fatalf("FPM = %+v, want 5 procs", info.FPM)
+	}
+	if got := info.FPM.Procs[1].Duration(); got != 35*time.Second {
+		t.Errorf("Duration = %v, want 35s", got)
+	}
+
+	server = newServer(t, 200, `{}`, serverInfoNoFPM)
+	defer server.Close()
+	info, err = NewClient(server.URL, "test-token", 5*time.Second).ServerInfo(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.FPM != nil {
+		t.Errorf("FPM = %+v, want nil without php-fpm", info.FPM)
+	}
+
+	if _, err := NewClient(server.URL, "wrong", 5*time.Second).ServerInfo(context.Background()); err == nil {
+		t.Error("expected error with a bad token")
+	}
+}
+
+func TestChecker_Activity(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		status     string
+		serverInfo string
+		token      string
+		threshold  int
+		want       []string // substrings, one per reason
+	}{
+		{
+			name:       "maintenance mode",
+			statusCode: 503,
+			status:     `{"installed": true, "maintenance": true, "versionstring": "28.0.1"}`,
+			token:      "test-token",
+			want:       []string{"maintenance mode"},
+		},
+		{
+			name:       "uploads in progress",
+			statusCode: 200,
+			status:     `{"installed": true, "maintenance": false}`,
+			serverInfo: serverInfoFPM,
+			token:      "test-token",
+			want:       []string{"2 upload(s) in progress: PUT /remote.php/dav/files/alice/holiday.mkv (2100.0 MB, 35s)"},
+		},
+		{
+			name:       "worker threshold",
+			statusCode: 200,
+			status:     `{"installed": true, "maintenance": false}`,
+			serverInfo: serverInfoFPM,
+			token:      "test-token",
+			threshold:  3,
+			want:       []string{"2 upload(s)", "3 PHP workers busy"},
+		},
+		{
+			name:       "worker threshold not reached",
+			statusCode: 200,
+			status:     `{"installed": true, "maintenance": false}`,
+			serverInfo: serverInfoFPM,
+			token:      "test-token",
+			threshold:  4,
+			want:       []string{"2 upload(s)"},
+		},
+		{
+			name:       "no php-fpm",
+			statusCode: 200,
+			status:     `{"installed": true, "maintenance": false}`,
+			serverInfo: serverInfoNoFPM,
+			token:      "test-token",
+			threshold:  1,
+		},
+		{
+			name:       "no serverinfo token",
+			statusCode: 200,
+			status:     `{"installed": true, "maintenance": false}`,
+			serverInfo: serverInfoFPM,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newServer(t, tt.statusCode, tt.status, tt.serverInfo)
+			defer server.Close()
+
+			checker := NewChecker(NewClient(server.URL, tt.token, 5*time.Second))
+			checker.WorkerThreshold = tt.threshold
+
+			reasons, err := checker.Activity(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(reasons) != len(tt.want) {
+				t.Fatalf("reasons = %q, want %d", reasons, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(reasons[i], want) {
+					t.Errorf("reason[%d] = %q, want to contain %q", i, reasons[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestChecker_Unreachable(t *testing.T) {
+	checker := NewChecker(NewClient("http://127.0.0.1:1", "", time.Second))
+	if err := checker.Check(context.Background()); err != nil {
+		t.Errorf("unreachable Nextcloud should not block: %v", err)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		status     string
+		wantErr    string
+	}{
+		{"healthy", 200, `{"installed": true, "maintenance": false, "needsDbUpgrade": false}`, ""},
+		{"maintenance", 503, `{"installed": true, "maintenance": true, "versionstring": "28.0.1"}`, "maintenance mode"},
+		{"db upgrade", 200, `{"installed": true, "needsDbUpgrade": true, "versionstring": "28.0.1"}`, "database upgrade"},
+		{"server error", 500, `oops`, "unexpected status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newServer(t, tt.statusCode, tt.status, "")
+			defer server.Close()
+
+			err := Health(context.Background(), NewClient(server.URL, "", 5*time.Second))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
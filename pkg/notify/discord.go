@@ -0,0 +1,26 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Discord posts messages to a Discord channel webhook
+type Discord struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+// Notify posts msg to the webhook.
+func (d *Discord) Notify(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(map[string]string{
+		"content": fmt.Sprintf("**%s**\n%s", msg.Title, msg.Body),
+	})
+	if err != nil {
+		return fmt.Errorf("encode message: %w", err)
+	}
+	return post(ctx, d.Client, d.WebhookURL, "application/json", bytes.NewReader(body), nil)
+}
@@ -0,0 +1,217 @@
+// Package notify sends push notifications when a sidecar changes state.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+)
+
+// Priority controls how urgently a backend should deliver a message.
+type Priority int
+
+const (
+	PriorityNormal Priority = iota
+	PriorityHigh
+)
+
+// Message is a single notification
+type Message struct {
+	Title    string
+	Body     string
+	Priority Priority
+}
+
+// Notifier delivers messages to a push service
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Multi fans a message out to several notifiers.
+// All notifiers are attempted; errors are joined.
+type Multi []Notifier
+
+// Notify sends msg to every notifier in m.
+func (m Multi) Notify(ctx context.Context, msg Message) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FromEnv builds a notifier from NOTIFY_* environment variables.
+// Returns nil if no backend is configured.
+//
+//	NOTIFY_NTFY_URL          ntfy topic URL (e.g. https://ntfy.sh/homelab)
+//	NOTIFY_NTFY_TOKEN        optional ntfy access token
+//	NOTIFY_PUSHOVER_TOKEN    Pushover application token
+//	NOTIFY_PUSHOVER_USER     Pushover user key
+//	NOTIFY_DISCORD_WEBHOOK   Discord webhook URL
+//	NOTIFY_TELEGRAM_TOKEN    Telegram bot token
+//	NOTIFY_TELEGRAM_CHAT_ID  Telegram chat ID
+//	NOTIFY_WEBHOOK_URL       generic webhook, receives the message as JSON
+//	NOTIFY_URLS              Apprise-style URLs, e.g. "ntfy://homelab tgram://token/chat"
+//	NOTIFY_APPRISE_API       Apprise API server for URLs without a native backend
+//
+// An invalid NOTIFY_URLS is logged and ignored, leaving the other backends.
+func FromEnv() Notifier {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	var m Multi
+	if u := os.Getenv("NOTIFY_NTFY_URL"); u != "" {
+		m = append(m, &Ntfy{URL: u, Token: os.Getenv("NOTIFY_NTFY_TOKEN"), Client: client})
+	}
+	if token, user := os.Getenv("NOTIFY_PUSHOVER_TOKEN"), os.Getenv("NOTIFY_PUSHOVER_USER"); token != "" && user != "" {
+		m = append(m, &Pushover{Token: token, User: user, Client: client})
+	}
+	if u := os.Getenv("NOTIFY_DISCORD_WEBHOOK"); u != "" {
+		m = append(m, &Discord{WebhookURL: u, Client: client})
+	}
+	if token, chat := os.Getenv("NOTIFY_TELEGRAM_TOKEN"), os.Getenv("NOTIFY_TELEGRAM_CHAT_ID"); token != "" && chat != "" {
+		m = append(m, &Telegram{Token: token, ChatID: chat, Client: client})
+	}
+	if u := os.Getenv("NOTIFY_WEBHOOK_URL"); u != "" {
+		m = append(m, &Webhook{URL: u, Client: client})
+	}
+	if urls := os.Getenv("NOTIFY_URLS"); urls != "" {
+		apprise, err := ParseAppriseURLs(urls, os.Getenv("NOTIFY_APPRISE_API"), client)
+		if err != nil {
+			logging.Warnf("NOTIFY_URLS: %v", err)
+		}
+		m = append(m, apprise...)
+	}
+
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+// post sends body to url and treats any non-2xx response as an error.
+func post(ctx context.Context, client *http.Client, url, contentType string, body io.Reader, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SendTimeout bounds a notification sent by Send
+const SendTimeout = 30 * time.Second
+
+// Send delivers msg through n in the background, so a slow push service
+// never delays the check loop, and logs a failure. It keeps ctx's values
+// but not its cancellation, so a notification raised as the sidecar stops
+// still goes out, within SendTimeout. A nil notifier sends nothing.
+func Send(ctx context.Context, n Notifier, msg Message) {
+	if n == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), SendTimeout)
+		defer cancel()
+		if err := n.Notify(ctx, msg); err != nil {
+			logging.Warnf("notification failed: %v", err)
+		}
+	}()
+}
+
+// OnBusy returns a sidecar.Options.OnBusy callback that notifies when
+// the inhibitor is acquired. A nil notifier yields a nil callback.
+func OnBusy(n Notifier, name string) func(reason string) {
+	if n == nil {
+		return nil
+	}
+	return func(reason string) {
+		Send(context.Background(), n, Message{
+			Title:    fmt.Sprintf("%s: shutdown blocked", name),
+			Body:     reason,
+			Priority: PriorityHigh,
+		})
+	}
+}
+
+// OnIdle returns a sidecar.Options.OnIdle callback that notifies when
+// the inhibitor is released. A nil notifier yields a nil callback.
+func OnIdle(n Notifier, name string) func() {
+	if n == nil {
+		return nil
+	}
+	return func() {
+		Send(context.Background(), n, Message{
+			Title: fmt.Sprintf("%s: shutdown allowed", name),
+			Body:  "inhibitor released",
+		})
+	}
+}
+
+// errorChecker wraps a checker and notifies when it starts or stops failing.
+type errorChecker struct {
+	sidecar.Checker
+	notifier Notifier
+
+	mu      sync.Mutex
+	failing bool
+}
+
+// Errors wraps checker so that a notification is sent when Check starts
+// returning errors, and again when it recovers. Repeated failures are
+// only reported once. A nil notifier returns checker unchanged.
+func Errors(checker sidecar.Checker, n Notifier) sidecar.Checker {
+	if n == nil {
+		return checker
+	}
+	return &errorChecker{Checker: checker, notifier: n}
+}
+
+// Check runs the wrapped checker and reports error transitions.
+func (c *errorChecker) Check(ctx context.Context) (bool, string, error) {
+	busy, reason, err := c.Checker.Check(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err != nil && !c.failing {
+		c.failing = true
+		Send(ctx, c.notifier, Message{
+			Title:    fmt.Sprintf("%s: check failing", c.Name()),
+			Body:     err.Error(),
+			Priority: PriorityHigh,
+		})
+	} else if err == nil && c.failing {
+		c.failing = false
+		Send(ctx, c.notifier, Message{
+			Title: fmt.Sprintf("%s: check recovered", c.Name()),
+			Body:  "check is succeeding again",
+		})
+	}
+	return busy, reason, err
+}
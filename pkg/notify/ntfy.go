@@ -0,0 +1,26 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Ntfy publishes messages to an ntfy topic
+type Ntfy struct {
+	URL    string // full topic URL, e.g. https://ntfy.sh/homelab
+	Token  string // optional access token
+	Client *http.Client
+}
+
+// Notify publishes msg to the topic.
+func (n *Ntfy) Notify(ctx context.Context, msg Message) error {
+	headers := map[string]string{"Title": msg.Title}
+	if msg.Priority == PriorityHigh {
+		headers["Priority"] = "high"
+	}
+	if n.Token != "" {
+		headers["Authorization"] = "Bearer " + n.Token
+	}
+	return post(ctx, n.Client, n.URL, "text/plain", strings.NewReader(msg.Body), headers)
+}
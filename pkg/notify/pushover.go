@@ -0,0 +1,38 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PushoverURL is the Pushover messages endpoint
+const PushoverURL = "https://api.pushover.net/1/messages.json"
+
+// Pushover sends messages through the Pushover API
+type Pushover struct {
+	Token  string // application token
+	User   string // user or group key
+	URL    string // defaults to PushoverURL
+	Client *http.Client
+}
+
+// Notify sends msg to the configured user.
+func (p *Pushover) Notify(ctx context.Context, msg Message) error {
+	form := url.Values{
+		"token":   {p.Token},
+		"user":    {p.User},
+		"title":   {msg.Title},
+		"message": {msg.Body},
+	}
+	if msg.Priority == PriorityHigh {
+		form.Set("priority", "1")
+	}
+
+	endpoint := p.URL
+	if endpoint == "" {
+		endpoint = PushoverURL
+	}
+	return post(ctx, p.Client, endpoint, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()), nil)
+}
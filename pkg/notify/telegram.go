@@ -0,0 +1,38 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// TelegramAPI is the Telegram Bot API base URL
+const TelegramAPI = "https://api.telegram.org"
+
+// Telegram sends messages through a Telegram bot
+type Telegram struct {
+	Token  string // bot token from @BotFather
+	ChatID string
+	APIURL string // defaults to TelegramAPI
+	Client *http.Client
+}
+
+// Notify sends msg to the configured chat.
+func (t *Telegram) Notify(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(map[string]any{
+		"chat_id":              t.ChatID,
+		"text":                 fmt.Sprintf("%s\n%s", msg.Title, msg.Body),
+		"disable_notification": msg.Priority != PriorityHigh,
+	})
+	if err != nil {
+		return fmt.Errorf("encode message: %w", err)
+	}
+
+	api := t.APIURL
+	if api == "" {
+		api = TelegramAPI
+	}
+	return post(ctx, t.Client, api+"/bot"+t.Token+"/sendMessage", "application/json", bytes.NewReader(body), nil)
+}
@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Webhook posts messages as JSON to an arbitrary URL
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// webhookPayload is the JSON body sent by Webhook
+type webhookPayload struct {
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+	Priority string `json:"priority"`
+}
+
+// Notify posts msg to the webhook URL.
+func (w *Webhook) Notify(ctx context.Context, msg Message) error {
+	priority := "normal"
+	if msg.Priority == PriorityHigh {
+		priority = "high"
+	}
+	body, err := json.Marshal(webhookPayload{Title: msg.Title, Body: msg.Body, Priority: priority})
+	if err != nil {
+		return fmt.Errorf("encode message: %w", err)
+	}
+	return post(ctx, w.Client, w.URL, "application/json", bytes.NewReader(body), nil)
+}
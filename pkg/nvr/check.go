@@ -0,0 +1,107 @@
+package nvr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Checker implements check.Checker for NVR recordings.
+// Returns an error while any monitor is recording an alarm or motion
+// event, so the recording isn't cut off by a reboot. Continuous recording
+// doesn't block, or the NVR would never reboot.
+type Checker struct {
+	Sources []Source
+	// Required are the monitors, by name or ID, that Health expects to be
+	// capturing; empty means every enabled monitor
+	Required []string
+}
+
+// NewChecker creates an NVR checker.
+func NewChecker(sources []Source, required []string) *Checker {
+	return &Checker{Sources: sources, Required: required}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "nvr"
+}
+
+// Check returns nil if no event is being recorded, error otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	reasons, err := c.Activity(ctx)
+	if err != nil {
+		return fmt.Errorf("nvr check failed: %w", err)
+	}
+	if len(reasons) > 0 {
+		return errors.New(strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// Activity describes each monitor recording an event. NVRs that can't be
+// reached are skipped, since they can't record either; rejected
+// credentials are returned as an error.
+func (c *Checker) Activity(ctx context.Context) ([]string, error) {
+	var reasons []string
+	for _, s := range c.Sources {
+		monitors, err := s.Monitors(ctx)
+		if errors.Is(err, ErrUnauthorized) {
+			return nil, fmt.Errorf("%s: %w", s.Name(), err)
+		}
+		if err != nil {
+			continue
+		}
+		for _, m := range monitors {
+			if m.Alarm {
+				reasons = append(reasons, fmt.Sprintf("%s recording an event", m.Describe()))
+			}
+		}
+	}
+	return reasons, nil
+}
+
+// Health returns an error unless every required monitor is capturing, e.g.
+// for a post-boot health check. Unlike Activity, an NVR that can't be
+// reached is an error.
+func (c *Checker) Health(ctx context.Context) error {
+	var all []Monitor
+	for _, s := range c.Sources {
+		monitors, err := s.Monitors(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.Name(), err)
+		}
+		all = append(all, monitors...)
+	}
+
+	var problems []string
+	if len(c.Required) == 0 {
+		for _, m := range all {
+			if m.Enabled && !m.Capturing {
+				problems = append(problems, notCapturing(m))
+			}
+		}
+	}
+	for _, want := range c.Required {
+		i := slices.IndexFunc(all, func(m Monitor) bool { return m.Name == want || m.ID == want })
+		switch {
+		case i < 0:
+			problems = append(problems, fmt.Sprintf("monitor %s not found", want))
+		case !all[i].Capturing:
+			problems = append(problems, notCapturing(all[i]))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func notCapturing(m Monitor) string {
+	if m.State != "" {
+		return fmt.Sprintf("%s not capturing (%s)", m.Describe(), m.State)
+	}
+	return fmt.Sprintf("%s not capturing", m.Describe())
+}
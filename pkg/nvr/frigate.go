@@ -0,0 +1,209 @@
+package nvr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Frigate reads camera, event and review state from the Frigate API
+type Frigate struct {
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	token string // frigate_token cookie
+}
+
+// NewFrigate creates a Frigate source. baseURL is the unauthenticated port,
+// e.g. http://localhost:5000, or with a username the authenticated one,
+// e.g. https://localhost:8971, where any user can read the API.
+func NewFrigate(baseURL, username, password string, timeout time.Duration) *Frigate {
+	return &Frigate{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		username: username,
+		password: password,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+type frigateConfig struct {
+	Cameras map[string]struct {
+		Enabled *bool `json:"enabled"` // absent before 0.14, when every camera was
+		Record  struct {
+			Enabled bool `json:"enabled"`
+		} `json:"record"`
+	} `json:"cameras"`
+}
+
+type frigateStats struct {
+	Cameras map[string]struct {
+		CameraFPS float64 `json:"camera_fps"`
+	} `json:"cameras"`
+}
+
+// frigateItem is an event, or a review item from 0.14; either is still
+// being recorded while its end_time is null
+type frigateItem struct {
+	Camera  string   `json:"camera"`
+	EndTime *float64 `json:"end_time"`
+}
+
+// Name returns the source name.
+func (f *Frigate) Name() string {
+	return "frigate"
+}
+
+// Monitors returns the cameras. A camera that records is in alarm while an
+// event, or a review item (an alert or detection being grouped for
+// review), is still open: its recording segments are being written.
+func (f *Frigate) Monitors(ctx context.Context) ([]Monitor, error) {
+	var config frigateConfig
+	if err := f.get(ctx, "/api/config", &config); err != nil {
+		return nil, err
+	}
+	var stats frigateStats
+	if err := f.get(ctx, "/api/stats", &stats); err != nil {
+		return nil, err
+	}
+
+	open := make(map[string]bool)
+	var events []frigateItem
+	if err := f.get(ctx, "/api/events?in_progress=1&limit=100", &events); err != nil {
+		return nil, err
+	}
+	for _, e := range events {
+		open[e.Camera] = true
+	}
+	var reviews []frigateItem
+	if err := f.get(ctx, "/api/review?limit=100", &reviews); err != nil && !isStatus(err, http.StatusNotFound) {
+		return nil, err
+	}
+	for _, r := range reviews {
+		if r.EndTime == nil {
+			open[r.Camera] = true
+		}
+	}
+
+	monitors := make([]Monitor, 0, len(config.Cameras))
+	for _, name := range slices.Sorted(maps.Keys(config.Cameras)) {
+		cam := config.Cameras[name]
+		enabled := cam.Enabled == nil || *cam.Enabled
+		fps := stats.Cameras[name].CameraFPS
+		state := fmt.Sprintf("%.1f fps", fps)
+		if !enabled {
+			state = "disabled"
+		}
+		monitors = append(monitors, Monitor{
+			Source:    f.Name(),
+			ID:        name,
+			Name:      name,
+			Enabled:   enabled,
+			Capturing: enabled && fps > 0,
+			Alarm:     enabled && cam.Record.Enabled && open[name],
+			State:     state,
+		})
+	}
+	return monitors, nil
+}
+
+// statusError is an unexpected response status
+type statusError int
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("unexpected status: %d", int(e))
+}
+
+func isStatus(err error, status int) bool {
+	s, ok := err.(statusError)
+	return ok && int(s) == status
+}
+
+// get fetches path, logging in first if needed and again once if the
+// token was rejected.
+func (f *Frigate) get(ctx context.Context, path string, v any) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := f.do(ctx, path, v)
+	if isStatus(err, http.StatusUnauthorized) && f.username != "" {
+		f.token = ""
+		err = f.do(ctx, path, v)
+	}
+	if isStatus(err, http.StatusUnauthorized) || isStatus(err, http.StatusForbidden) {
+		return fmt.Errorf("%w: %w", err, ErrUnauthorized)
+	}
+	return err
+}
+
+func (f *Frigate) do(ctx context.Context, path string, v any) error {
+	if f.username != "" && f.token == "" {
+		if err := f.login(ctx); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", f.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	if f.token != "" {
+		req.AddCookie(&http.Cookie{Name: "frigate_token", Value: f.token})
+	}
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+func (f *Frigate) login(ctx context.Context) error {
+	body, err := json.Marshal(map[string]string{"user": f.username, "password": f.password})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", f.baseURL+"/api/login", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("login failed: status %d: %w", resp.StatusCode, ErrUnauthorized)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("login failed: status %d", resp.StatusCode)
+	}
+	for _, c := range resp.Cookies() {
+		if c.Name == "frigate_token" && c.Value != "" {
+			f.token = c.Value
+			return nil
+		}
+	}
+	return fmt.Errorf("login failed: no frigate_token cookie: %w", ErrUnauthorized)
+}
@@ -0,0 +1,41 @@
+// Package nvr checks network video recorders (ZoneMinder, Shinobi and
+// Frigate): whether a camera is recording an alarm or motion event, which a
+// reboot would cut short, and whether the configured cameras are capturing
+// again after boot.
+package nvr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrUnauthorized is returned when an NVR rejects the credentials
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Monitor is one camera as an NVR sees it
+type Monitor struct {
+	Source    string // e.g. "zoneminder"
+	ID        string
+	Name      string
+	Enabled   bool   // configured to capture
+	Capturing bool   // receiving frames from the camera
+	Alarm     bool   // recording an alarm or motion event
+	State     string // the NVR's own status, e.g. "Connected" or "Died"
+}
+
+// Describe names the monitor, e.g. "zoneminder: Front Door".
+func (m Monitor) Describe() string {
+	name := m.Name
+	if name == "" {
+		name = m.ID
+	}
+	return fmt.Sprintf("%s: %s", m.Source, name)
+}
+
+// Source is an NVR backend
+type Source interface {
+	Name() string
+	// Monitors returns the NVR's monitors with their current state
+	Monitors(ctx context.Context) ([]Monitor, error)
+}
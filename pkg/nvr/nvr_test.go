@@ -0,0 +1,262 @@
+package nvr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// zoneMinderServer serves two monitors: Front Door capturing in alarm state
+// alarm, and Garage, which isn't connected
+func zoneMinderServer(t *testing.T, alarm string) *httptest.Server {
+	t.Helper()
+	logins := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/zm/api/host/login.json" {
+			logins++
+			if r.FormValue("user") != "admin" || r.FormValue("pass") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"access_token": "tok", "access_token_expires": 3600}`))
+			return
+		}
+		if r.URL.Query().Get("token") != "tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/zm/api/monitors.json":
+			w.Write([]byte(`{"monitors": [
+				{"Monitor": {"Id": "1", "Name": "Front Door", "Function": "Modect", "Enabled": "1"},
+				 "Monitor_Status": {"Status": "Connected", "CaptureFPS": "5.00"}},
+				{"Monitor": {"Id": "2", "Name": "Garage", "Function": "Modect", "Enabled": "1"},
+				 "Monitor_Status": {"Status": "NotRunning", "CaptureFPS": "0.00"}},
+				{"Monitor": {"Id": "3", "Name": "Spare", "Function": "None", "Enabled": "1"},
+				 "Monitor_Status": {"Status": "NotRunning", "CaptureFPS": "0.00"}}
+			]}`))
+		case "/zm/api/monitors/alarm/id:1/command:status.json":
+			w.Write([]byte(`{"status": ` + alarm + `}`))
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() {
+		if logins != 1 {
+			t.Errorf("logged in %d times, want once", logins)
+		}
+	})
+	return server
+}
+
+func shinobiServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/key/monitor/home" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestActivity(t *testing.T) {
+	tests := []struct {
+		name    string
+		alarm   string
+		shinobi string
+		want    []string
+	}{
+		{
+			name:  "idle",
+			alarm: `"0"`,
+			shinobi: `[{"mid": "a", "name": "Drive", "mode": "start", "status": "Watching"},
+				{"mid": "b", "name": "Yard", "mode": "record", "status": "Recording"}]`,
+		},
+		{
+			name:    "alarms",
+			alarm:   `2`,
+			shinobi: `[{"mid": "a", "name": "Drive", "mode": "start", "status": "Recording"}]`,
+			want:    []string{"zoneminder: Front Door recording an event", "shinobi: Drive recording an event"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zm := zoneMinderServer(t, tt.alarm)
+			shinobi := shinobiServer(t, tt.shinobi)
+			checker := NewChecker([]Source{
+				NewZoneMinder(zm.URL+"/zm/", "admin", "secret", 5*time.Second),
+				NewShinobi(shinobi.URL, "key", "home", 5*time.Second),
+				NewShinobi("http://127.0.0.1:1", "key", "home", time.Second), // down, skipped
+			}, nil)
+
+			got, err := checker.Activity(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, "; ") != strings.Join(tt.want, "; ") {
+				t.Errorf("Activity() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActivity_Unauthorized(t *testing.T) {
+	shinobi := shinobiServer(t, `{"ok": false, "msg": "Not Authorized"}`)
+	checker := NewChecker([]Source{NewShinobi(shinobi.URL, "key", "home", 5*time.Second)}, nil)
+	if _, err := checker.Activity(context.Background()); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Activity() error = %v, want ErrUnauthorized", err)
+	}
+
+	zm := NewZoneMinder("http://127.0.0.1:1", "", "", time.Second)
+	if err := NewChecker([]Source{zm}, nil).Check(context.Background()); err != nil {
+		t.Errorf("Check() = %v, want nil when the NVR is down", err)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	tests := []struct {
+		name     string
+		required []string
+		wantErr  string
+	}{
+		{name: "all enabled", wantErr: "zoneminder: Garage not capturing (NotRunning); shinobi: Yard not capturing (Died)"},
+		{name: "required capturing", required: []string{"Front Door", "a"}},
+		{name: "required missing", required: []string{"Front Door", "Porch"}, wantErr: "monitor Porch not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zm := zoneMinderServer(t, `"0"`)
+			shinobi := shinobiServer(t, `[{"mid": "a", "name": "Drive", "mode": "start", "status": "Watching"},
+				{"mid": "b", "name": "Yard", "mode": "record", "status": "Died"},
+				{"mid": "c", "name": "Old", "mode": "stop", "status": "Stopped"}]`)
+			checker := NewChecker([]Source{
+				NewZoneMinder(zm.URL+"/zm", "admin", "secret", 5*time.Second),
+				NewShinobi(shinobi.URL, "key", "home", 5*time.Second),
+			}, tt.required)
+
+			err := checker.Health(context.Background())
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Health() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Errorf("Health() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	down := NewChecker([]Source{NewShinobi("http://127.0.0.1:1", "key", "home", time.Second)}, nil)
+	if err := down.Health(context.Background()); err == nil {
+		t.Error("Health() = nil with the NVR down")
+	}
+}
+
+// frigateServer serves three cameras behind a login: Driveway recording,
+// Porch detecting only and Shed disabled. events and reviews are the
+// in-progress events and the recent review items.
+func frigateServer(t *testing.T, events, reviews string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/login" {
+			var creds struct{ User, Password string }
+			if err := json.NewDecoder(r.Body).Decode(&creds); err != nil || creds.User != "viewer" || creds.Password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "frigate_token", Value: "tok"})
+			return
+		}
+		if c, err := r.Cookie("frigate_token"); err != nil || c.Value != "tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/config":
+			w.Write([]byte(`{"cameras": {
+				"driveway": {"enabled": true, "record": {"enabled": true}},
+				"porch": {"enabled": true, "record": {"enabled": false}},
+				"shed": {"enabled": false, "record": {"enabled": true}}
+			}}`))
+		case "/api/stats":
+			w.Write([]byte(`{"cameras": {"driveway": {"camera_fps": 5.1}, "porch": {"camera_fps": 0}}}`))
+		case "/api/events":
+			if r.URL.Query().Get("in_progress") != "1" {
+				t.Errorf("events query = %q, want in_progress=1", r.URL.RawQuery)
+			}
+			w.Write([]byte(events))
+		case "/api/review":
+			if reviews == "" {
+				w.WriteHeader(http.StatusNotFound) // before 0.14
+				return
+			}
+			w.Write([]byte(reviews))
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFrigate(t *testing.T) {
+	tests := []struct {
+		name    string
+		events  string
+		reviews string
+		want    []string
+	}{
+		{
+			name:    "idle",
+			events:  `[]`,
+			reviews: `[{"camera": "driveway", "end_time": 1700000100.5}]`,
+		},
+		{
+			name:   "event in progress",
+			events: `[{"camera": "driveway", "end_time": null}, {"camera": "porch", "end_time": null}]`,
+			want:   []string{"frigate: driveway recording an event"},
+		},
+		{
+			name:    "reviewing",
+			events:  `[]`,
+			reviews: `[{"camera": "shed", "end_time": null}, {"camera": "driveway", "end_time": null}]`,
+			want:    []string{"frigate: driveway recording an event"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := frigateServer(t, tt.events, tt.reviews)
+			checker := NewChecker([]Source{NewFrigate(server.URL, "viewer", "secret", 5*time.Second)}, nil)
+
+			got, err := checker.Activity(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, "; ") != strings.Join(tt.want, "; ") {
+				t.Errorf("Activity() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	server := frigateServer(t, `[]`, `[]`)
+	err := NewChecker([]Source{NewFrigate(server.URL, "viewer", "secret", 5*time.Second)}, nil).Health(context.Background())
+	if err == nil || err.Error() != "frigate: porch not capturing (0.0 fps)" {
+		t.Errorf("Health() = %v, want porch not capturing", err)
+	}
+
+	bad := NewChecker([]Source{NewFrigate(server.URL, "viewer", "wrong", 5*time.Second)}, nil)
+	if _, err := bad.Activity(context.Background()); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Activity() error = %v, want ErrUnauthorized", err)
+	}
+}
@@ -0,0 +1,107 @@
+package nvr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Shinobi reads monitor state from the Shinobi API
+type Shinobi struct {
+	baseURL    string
+	apiKey     string
+	groupKey   string
+	httpClient *http.Client
+}
+
+// NewShinobi creates a Shinobi source for the monitors of one group. The
+// API key needs the "Get Monitors" permission.
+func NewShinobi(baseURL, apiKey, groupKey string, timeout time.Duration) *Shinobi {
+	return &Shinobi{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		apiKey:   apiKey,
+		groupKey: groupKey,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+type shinobiMonitor struct {
+	MID  string `json:"mid"`
+	Name string `json:"name"`
+	// Mode is "stop" (disabled), "start" (watch only, recording events)
+	// or "record" (continuous)
+	Mode string `json:"mode"`
+	// Status is e.g. "Watching", "Recording", "Starting", "Died" or "Stopped"
+	Status string `json:"status"`
+}
+
+// Name returns the source name.
+func (s *Shinobi) Name() string {
+	return "shinobi"
+}
+
+// Monitors returns the group's monitors. A watch-only monitor that is
+// recording has been triggered by motion or an event.
+func (s *Shinobi) Monitors(ctx context.Context) ([]Monitor, error) {
+	u := fmt.Sprintf("%s/%s/monitor/%s", s.baseURL, url.PathEscape(s.apiKey), url.PathEscape(s.groupKey))
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("unexpected status: %d: %w", resp.StatusCode, ErrUnauthorized)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	// A bad API key gets a 200 with {"ok": false, "msg": "Not Authorized"}
+	if body = bytes.TrimSpace(body); len(body) > 0 && body[0] == '{' {
+		var failure struct {
+			OK  bool   `json:"ok"`
+			Msg string `json:"msg"`
+		}
+		if err := json.Unmarshal(body, &failure); err == nil && !failure.OK {
+			return nil, fmt.Errorf("%s: %w", failure.Msg, ErrUnauthorized)
+		}
+	}
+
+	var raw []shinobiMonitor
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	monitors := make([]Monitor, 0, len(raw))
+	for _, m := range raw {
+		capturing := m.Status == "Watching" || m.Status == "Recording"
+		monitors = append(monitors, Monitor{
+			Source:    s.Name(),
+			ID:        m.MID,
+			Name:      m.Name,
+			Enabled:   m.Mode != "stop",
+			Capturing: capturing,
+			Alarm:     m.Mode == "start" && m.Status == "Recording",
+			State:     m.Status,
+		})
+	}
+	return monitors, nil
+}
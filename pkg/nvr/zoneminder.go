@@ -0,0 +1,204 @@
+package nvr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ZoneMinder reads monitor and alarm state from the ZoneMinder API
+type ZoneMinder struct {
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewZoneMinder creates a ZoneMinder source. baseURL is the web console,
+// e.g. http://localhost/zm. An empty username is for installs with
+// OPT_USE_AUTH off; otherwise the user needs view access to monitors.
+func NewZoneMinder(baseURL, username, password string, timeout time.Duration) *ZoneMinder {
+	return &ZoneMinder{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		username: username,
+		password: password,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+type zmMonitorsResponse struct {
+	Monitors []struct {
+		Monitor struct {
+			ID       string `json:"Id"`
+			Name     string `json:"Name"`
+			Function string `json:"Function"` // None, Monitor, Modect, Record, Mocord, Nodect
+			Enabled  string `json:"Enabled"`
+			// Capturing replaces Function from 1.37: None, OnDemand or Always
+			Capturing string `json:"Capturing"`
+		} `json:"Monitor"`
+		MonitorStatus struct {
+			Status     string `json:"Status"` // Unknown, NotRunning, Running, Connected, Signal
+			CaptureFPS string `json:"CaptureFPS"`
+		} `json:"Monitor_Status"`
+	} `json:"monitors"`
+}
+
+// Alarm states from zm_monitor.h that mean an event is being recorded
+const (
+	zmStatePrealarm = 1
+	zmStateAlarm    = 2
+	zmStateAlert    = 3
+)
+
+// Name returns the source name.
+func (z *ZoneMinder) Name() string {
+	return "zoneminder"
+}
+
+// Monitors returns the monitors, with the alarm state of those capturing.
+func (z *ZoneMinder) Monitors(ctx context.Context) ([]Monitor, error) {
+	var resp zmMonitorsResponse
+	if err := z.get(ctx, "/api/monitors.json", &resp); err != nil {
+		return nil, err
+	}
+
+	monitors := make([]Monitor, 0, len(resp.Monitors))
+	for _, m := range resp.Monitors {
+		enabled := m.Monitor.Enabled != "0"
+		if m.Monitor.Capturing != "" {
+			enabled = enabled && m.Monitor.Capturing != "None"
+		} else {
+			enabled = enabled && m.Monitor.Function != "" && m.Monitor.Function != "None"
+		}
+		fps, _ := strconv.ParseFloat(m.MonitorStatus.CaptureFPS, 64)
+		mon := Monitor{
+			Source:    z.Name(),
+			ID:        m.Monitor.ID,
+			Name:      m.Monitor.Name,
+			Enabled:   enabled,
+			Capturing: m.MonitorStatus.Status == "Connected" && fps > 0,
+			State:     m.MonitorStatus.Status,
+		}
+		if enabled && mon.Capturing {
+			state, err := z.alarmState(ctx, mon.ID)
+			if err != nil {
+				return nil, fmt.Errorf("monitor %s: %w", mon.Name, err)
+			}
+			mon.Alarm = state == zmStatePrealarm || state == zmStateAlarm || state == zmStateAlert
+		}
+		monitors = append(monitors, mon)
+	}
+	return monitors, nil
+}
+
+// alarmState returns the monitor's zm_monitor.h state, e.g. 2 for ALARM.
+func (z *ZoneMinder) alarmState(ctx context.Context, id string) (int, error) {
+	var resp struct {
+		// A number or a string of one, depending on the version
+		Status json.RawMessage `json:"status"`
+	}
+	if err := z.get(ctx, "/api/monitors/alarm/id:"+url.PathEscape(id)+"/command:status.json", &resp); err != nil {
+		return 0, err
+	}
+	state, err := strconv.Atoi(strings.Trim(string(resp.Status), `"`))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected alarm status %s", resp.Status)
+	}
+	return state, nil
+}
+
+// get fetches path with the session token, logging in first if needed and
+// again once if the token was rejected.
+func (z *ZoneMinder) get(ctx context.Context, path string, v any) error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	status, err := z.do(ctx, path, v)
+	if status == http.StatusUnauthorized && z.username != "" {
+		z.token = ""
+		status, err = z.do(ctx, path, v)
+	}
+	if status == http.StatusUnauthorized || status == http.StatusForbidden {
+		return fmt.Errorf("unexpected status: %d: %w", status, ErrUnauthorized)
+	}
+	return err
+}
+
+func (z *ZoneMinder) do(ctx context.Context, path string, v any) (int, error) {
+	u := z.baseURL + path
+	if z.username != "" {
+		if z.token == "" || time.Now().After(z.expires) {
+			if err := z.login(ctx); err != nil {
+				return 0, err
+			}
+		}
+		u += "?token=" + url.QueryEscape(z.token)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	}
+	resp, err := z.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return resp.StatusCode, fmt.Errorf("decode response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+func (z *ZoneMinder) login(ctx context.Context) error {
+	form := url.Values{"user": {z.username}, "pass": {z.password}}
+	req, err := http.NewRequestWithContext(ctx, "POST", z.baseURL+"/api/host/login.json", strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := z.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("login failed: status %d: %w", resp.StatusCode, ErrUnauthorized)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("login failed: status %d", resp.StatusCode)
+	}
+
+	var login struct {
+		AccessToken   string `json:"access_token"`
+		AccessExpires int    `json:"access_token_expires"` // seconds
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&login); err != nil {
+		return fmt.Errorf("decode login response: %w", err)
+	}
+	if login.AccessToken == "" {
+		return fmt.Errorf("login failed: no access token: %w", ErrUnauthorized)
+	}
+	z.token = login.AccessToken
+	// Renew a minute early rather than have a poll rejected
+	z.expires = time.Now().Add(time.Duration(login.AccessExpires)*time.Second - time.Minute)
+	return nil
+}
@@ -0,0 +1,131 @@
+// Package override lets one sidecar force every other sidecar to release
+// its inhibitor, e.g. the UPS sidecar when the battery is about to run out
+// and a clean shutdown matters more than anything the checks protect.
+//
+// The override is a file holding the reason, shared through a common
+// directory. It only counts while its modification time is recent, so a
+// writer that dies can't leave shutdown unblocked forever.
+package override
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/check"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+)
+
+// DefaultPath is where the force-allow file lives
+const DefaultPath = "/run/homelab-sidecars/force-allow"
+
+// MaxAge is how long a force-allow file stays valid without being rewritten
+var MaxAge = 5 * time.Minute
+
+// Set writes the force-allow file with the reason, refreshing its age.
+func Set(path, reason string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create override directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(reason+"\n"), 0644); err != nil {
+		return fmt.Errorf("write override: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write override: %w", err)
+	}
+	return nil
+}
+
+// Clear removes the force-allow file.
+func Clear(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove override: %w", err)
+	}
+	return nil
+}
+
+// Active returns the reason if a valid force-allow file exists at path.
+func Active(path string, now time.Time) (reason string, ok bool) {
+	info, err := os.Stat(path)
+	if err != nil || now.Sub(info.ModTime()) > MaxAge {
+		return "", false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(data)), true
+}
+
+// overrideChecker reports not busy while the force-allow file is active
+type overrideChecker struct {
+	sidecar.Checker
+	path string
+
+	mu     sync.Mutex
+	active bool
+}
+
+// Wrap wraps checker so it reports not busy while a force-allow file is
+// active at path. The wrapped checker still runs, so metrics and state
+// tracking keep up. An empty path returns checker unchanged.
+func Wrap(checker sidecar.Checker, path string) sidecar.Checker {
+	if path == "" {
+		return checker
+	}
+	return &overrideChecker{Checker: checker, path: path}
+}
+
+// Check runs the wrapped checker and overrides a busy result if forced.
+func (c *overrideChecker) Check(ctx context.Context) (bool, string, error) {
+	busy, reason, err := c.Checker.Check(ctx)
+
+	forced, active := Active(c.path, time.Now())
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if active != c.active {
+		c.active = active
+		if active {
+			logging.Infof("%s: shutdown force-allowed: %s", c.Name(), forced)
+		} else {
+			logging.Infof("%s: force-allow cleared, following the check again", c.Name())
+		}
+	}
+	if active {
+		return false, "", nil
+	}
+	return busy, reason, err
+}
+
+// Checker implements check.Evaluator for the force-allow file, so a
+// check.Set honours an override written by another process.
+type Checker struct {
+	Path string
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "force-allow"
+}
+
+// Check always returns nil; the override never blocks shutdown.
+func (c *Checker) Check(ctx context.Context) error {
+	return nil
+}
+
+// Evaluate returns ForceAllow while the force-allow file is active.
+func (c *Checker) Evaluate(ctx context.Context) check.Result {
+	if reason, ok := Active(c.Path, time.Now()); ok {
+		return check.Result{Check: c.Name(), Verdict: check.ForceAllow, Reason: reason}
+	}
+	return check.Result{Check: c.Name(), Verdict: check.Neutral}
+}
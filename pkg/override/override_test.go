@@ -0,0 +1,84 @@
+package override
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/check"
+)
+
+func TestWrap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "force-allow")
+	busy := sidecar.NewCheckerFunc("jellyfin", func(ctx context.Context) (bool, string, error) {
+		return true, "1 active stream", nil
+	})
+	checker := Wrap(busy, path)
+
+	check := func() bool {
+		t.Helper()
+		got, _, err := checker.Check(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return got
+	}
+
+	if !check() {
+		t.Error("busy without override, got not busy")
+	}
+
+	if err := Set(path, "on battery, 12% charge"); err != nil {
+		t.Fatal(err)
+	}
+	if reason, ok := Active(path, time.Now()); !ok || reason != "on battery, 12% charge" {
+		t.Errorf("Active = (%q, %v)", reason, ok)
+	}
+	if check() {
+		t.Error("busy with override active")
+	}
+
+	// A stale override no longer counts
+	old := time.Now().Add(-2 * MaxAge)
+	os.Chtimes(path, old, old)
+	if !check() {
+		t.Error("not busy with stale override")
+	}
+
+	Set(path, "on battery")
+	if err := Clear(path); err != nil {
+		t.Fatal(err)
+	}
+	if !check() {
+		t.Error("not busy after override cleared")
+	}
+	if err := Clear(path); err != nil {
+		t.Errorf("clearing twice: %v", err)
+	}
+}
+
+func TestWrap_EmptyPath(t *testing.T) {
+	checker := sidecar.NewCheckerFunc("raid", func(ctx context.Context) (bool, string, error) {
+		return true, "", nil
+	})
+	if Wrap(checker, "") != checker {
+		t.Error("empty path should return checker unchanged")
+	}
+}
+
+func TestChecker_Evaluate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "force-allow")
+	checker := &Checker{Path: path}
+
+	if r := checker.Evaluate(context.Background()); r.Verdict != check.Neutral {
+		t.Errorf("verdict = %s, want neutral without a file", r.Verdict)
+	}
+
+	Set(path, "ups: on battery")
+	if r := checker.Evaluate(context.Background()); r.Verdict != check.ForceAllow || r.Reason != "ups: on battery" {
+		t.Errorf("result = %+v, want force-allow", r)
+	}
+}
@@ -0,0 +1,69 @@
+// Package privexec runs external tools that need more privileges than the
+// sidecar itself, by prefixing them with a configurable wrapper such as
+// "sudo -n". This lets the daemon stay unprivileged while a narrow
+// sudoers rule grants access to individual commands:
+//
+//	# /etc/sudoers.d/homelab-sidecars
+//	homelab ALL=(root) NOPASSWD: /usr/sbin/mdadm --detail --export *
+//	homelab ALL=(root) NOPASSWD: /usr/sbin/smartctl -c *
+//
+// sudo is setuid, so it fails in a unit with NoNewPrivileges=true, or with
+// any sandbox option that implies it for a User= service, such as
+// SystemCallFilter=. deploy/exec-wrapper-sudo.conf is a drop-in that lifts
+// them; granting the unit capabilities instead keeps the sandbox.
+package privexec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Runner executes commands, optionally through a wrapper
+type Runner struct {
+	// Wrapper is prepended to every command, e.g. []string{"sudo", "-n"}.
+	// Empty runs commands directly.
+	Wrapper []string
+}
+
+// FromEnv returns a Runner for the named check. The wrapper is read from
+// <CHECK>_EXEC_WRAPPER (e.g. RAID_EXEC_WRAPPER) and falls back to
+// EXEC_WRAPPER, so privileges can be granted to individual checks only.
+func FromEnv(check string) Runner {
+	key := strings.ToUpper(strings.ReplaceAll(check, "-", "_")) + "_EXEC_WRAPPER"
+	v := os.Getenv(key)
+	if v == "" {
+		v = os.Getenv("EXEC_WRAPPER")
+	}
+	return Runner{Wrapper: strings.Fields(v)}
+}
+
+// Command builds an exec.Cmd for name and args, applying the wrapper.
+func (r Runner) Command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	if len(r.Wrapper) == 0 {
+		return exec.CommandContext(ctx, name, args...)
+	}
+	argv := append(append(append([]string{}, r.Wrapper[1:]...), name), args...)
+	return exec.CommandContext(ctx, r.Wrapper[0], argv...)
+}
+
+// Output runs the command and returns its stdout. On failure the error
+// includes the command's stderr, which is where sudo explains a missing
+// sudoers rule.
+func (r Runner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := r.Command(ctx, name, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return out, fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return out, fmt.Errorf("%s: %w", name, err)
+	}
+	return out, nil
+}
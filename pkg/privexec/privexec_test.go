@@ -0,0 +1,53 @@
+package privexec
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRunner_Command(t *testing.T) {
+	tests := []struct {
+		name    string
+		wrapper []string
+		want    []string
+	}{
+		{
+			name: "no wrapper",
+			want: []string{"mdadm", "--detail", "/dev/md0"},
+		},
+		{
+			name:    "sudo",
+			wrapper: []string{"sudo", "-n"},
+			want:    []string{"sudo", "-n", "mdadm", "--detail", "/dev/md0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := Runner{Wrapper: tt.wrapper}.Command(context.Background(), "mdadm", "--detail", "/dev/md0")
+			if strings.Join(cmd.Args, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("args = %q, want %q", cmd.Args, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("EXEC_WRAPPER", "doas")
+	t.Setenv("SMART_TEST_EXEC_WRAPPER", "sudo -n")
+
+	if got := FromEnv("smart-test").Wrapper; strings.Join(got, " ") != "sudo -n" {
+		t.Errorf("per-check wrapper = %q", got)
+	}
+	if got := FromEnv("raid").Wrapper; strings.Join(got, " ") != "doas" {
+		t.Errorf("fallback wrapper = %q", got)
+	}
+}
+
+func TestRunner_OutputIncludesStderr(t *testing.T) {
+	_, err := Runner{}.Output(context.Background(), "sh", "-c", "echo 'no sudoers rule' >&2; exit 1")
+	if err == nil || !strings.Contains(err.Error(), "no sudoers rule") {
+		t.Errorf("err = %v, want stderr included", err)
+	}
+}
@@ -0,0 +1,51 @@
+// Package procscan finds running processes by matching their command lines.
+package procscan
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultProcRoot is the default procfs mount
+const DefaultProcRoot = "/proc"
+
+// Process is a running process
+type Process struct {
+	PID     int
+	Cmdline string // arguments joined with spaces
+}
+
+// Find returns processes whose command line matches pattern.
+// Processes that exit during the scan are skipped.
+func Find(procRoot string, pattern *regexp.Regexp) ([]Process, error) {
+	if procRoot == "" {
+		procRoot = DefaultProcRoot
+	}
+
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	self := os.Getpid()
+	var found []Process
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(procRoot, e.Name(), "cmdline"))
+		if err != nil || len(data) == 0 {
+			// Exited, or a kernel thread
+			continue
+		}
+		cmdline := strings.TrimSpace(strings.ReplaceAll(string(data), "\x00", " "))
+		if pattern.MatchString(cmdline) {
+			found = append(found, Process{PID: pid, Cmdline: cmdline})
+		}
+	}
+	return found, nil
+}
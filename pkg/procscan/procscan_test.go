@@ -0,0 +1,40 @@
+package procscan
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	procs := map[string]string{
+		"100": "/usr/bin/restic\x00backup\x00/srv\x00",
+		"200": "borg\x00create\x00::archive\x00/home\x00",
+		"300": "/usr/sbin/sshd\x00-D\x00",
+		"400": "", // kernel thread
+	}
+	for pid, cmdline := range procs {
+		dir := filepath.Join(root, pid)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "cmdline"), []byte(cmdline), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Non-process entries are ignored
+	os.MkdirAll(filepath.Join(root, "sys"), 0755)
+
+	found, err := Find(root, regexp.MustCompile(`(^|/)(restic|borg)\s`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("found %d processes, want 2: %v", len(found), found)
+	}
+	if found[0].PID != 100 || found[0].Cmdline != "/usr/bin/restic backup /srv" {
+		t.Errorf("found[0] = %+v", found[0])
+	}
+}
@@ -0,0 +1,55 @@
+// Package profile selects a named set of settings from the environment, so
+// one shared EnvironmentFile can configure several units differently:
+//
+//	JELLYFIN_GRACE_PERIOD=5m
+//	NIGHTLY_UPDATES__JELLYFIN_GRACE_PERIOD=30m
+//	MANUAL_MAINTENANCE__JELLYFIN_GRACE_PERIOD=0s
+//	MANUAL_MAINTENANCE__RAID_ARRAYS=md0:warn
+//
+// With SIDECAR_PROFILE=nightly-updates the prefixed variables replace the
+// unprefixed ones before the sidecar reads its configuration.
+package profile
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Separator joins the profile prefix to the variable name
+const Separator = "__"
+
+// Prefix returns the variable prefix for a profile name: upper-cased, with
+// dashes and dots turned into underscores, e.g. "nightly-updates" becomes
+// "NIGHTLY_UPDATES__".
+func Prefix(name string) string {
+	name = strings.NewReplacer("-", "_", ".", "_").Replace(strings.ToUpper(name))
+	return name + Separator
+}
+
+// Apply copies every variable of the named profile over its unprefixed
+// name. An empty name does nothing. It is an error if the profile sets no
+// variables, which is almost always a typo.
+func Apply(name string) error {
+	if name == "" {
+		return nil
+	}
+	prefix := Prefix(name)
+
+	found := false
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(key, prefix) || len(key) == len(prefix) {
+			continue
+		}
+		if err := os.Setenv(strings.TrimPrefix(key, prefix), value); err != nil {
+			return fmt.Errorf("apply %s: %w", key, err)
+		}
+		found = true
+	}
+
+	if !found {
+		return fmt.Errorf("profile %q sets no %s* variables", name, prefix)
+	}
+	return nil
+}
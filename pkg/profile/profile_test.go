@@ -0,0 +1,39 @@
+package profile
+
+import (
+	"os"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	t.Setenv("JELLYFIN_GRACE_PERIOD", "5m")
+	t.Setenv("POLL_INTERVAL", "30s")
+	t.Setenv("NIGHTLY_UPDATES__JELLYFIN_GRACE_PERIOD", "30m")
+	t.Setenv("NIGHTLY_UPDATES__EXIT_AFTER_IDLE", "10m")
+	t.Setenv("MANUAL__JELLYFIN_GRACE_PERIOD", "0s")
+
+	if err := Apply("nightly-updates"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"JELLYFIN_GRACE_PERIOD": "30m",
+		"EXIT_AFTER_IDLE":       "10m",
+		"POLL_INTERVAL":         "30s", // not in the profile
+	}
+	for key, value := range want {
+		if got := os.Getenv(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+	os.Unsetenv("EXIT_AFTER_IDLE")
+}
+
+func TestApply_Unknown(t *testing.T) {
+	if err := Apply("nightly-updtes"); err == nil {
+		t.Error("expected error for a profile with no variables")
+	}
+	if err := Apply(""); err != nil {
+		t.Errorf("empty profile: %v", err)
+	}
+}
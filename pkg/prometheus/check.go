@@ -0,0 +1,93 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Checker implements check.Checker for a local Prometheus server.
+// Returns an error while the TSDB is writing a compacted block, if
+// BlockCompaction is set, or a snapshot under DataDir, if that is set: a
+// block cut short is thrown away, and the work starts over after boot while
+// the dashboards lag.
+type Checker struct {
+	Client          *Client
+	BlockCompaction bool
+	DataDir         string // "" = don't look for snapshots
+}
+
+// NewChecker creates a Prometheus checker that blocks during compactions.
+func NewChecker(client *Client) *Checker {
+	return &Checker{Client: client, BlockCompaction: true}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "prometheus"
+}
+
+// Check returns nil if Prometheus can be restarted, error otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	reasons, err := c.Activity(ctx)
+	if err != nil {
+		// Prometheus being down shouldn't hold up reboots
+		return nil
+	}
+	if len(reasons) > 0 {
+		return errors.New(strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// Activity describes the compaction or snapshots being written.
+func (c *Checker) Activity(ctx context.Context) ([]string, error) {
+	var reasons []string
+	if c.BlockCompaction {
+		tsdb, err := c.Client.TSDB(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if tsdb.Compacting {
+			reasons = append(reasons, "TSDB compaction writing a block")
+		}
+	}
+	if c.DataDir != "" {
+		snapshots, err := Snapshots(c.DataDir)
+		if err != nil {
+			return nil, fmt.Errorf("snapshots: %w", err)
+		}
+		if len(snapshots) > 0 {
+			reasons = append(reasons, fmt.Sprintf("writing snapshot %s", strings.Join(snapshots, ", ")))
+		}
+	}
+	return reasons, nil
+}
+
+// Health returns nil if Prometheus is ready and its TSDB has had no failed
+// compactions or WAL corruption since it started. A compaction that keeps
+// failing, e.g. running out of memory or disk, is retried every minute and
+// leaves the head growing until the server falls over.
+func (c *Checker) Health(ctx context.Context) error {
+	if err := c.Client.Ready(ctx); err != nil {
+		return err
+	}
+	tsdb, err := c.Client.TSDB(ctx)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	if tsdb.FailedCompactions > 0 {
+		problems = append(problems, fmt.Sprintf("%d failed compaction(s)", tsdb.FailedCompactions))
+	}
+	if tsdb.WALCorruptions > 0 {
+		problems = append(problems, fmt.Sprintf("%d WAL corruption(s)", tsdb.WALCorruptions))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s since start %s ago", strings.Join(problems, ", "), time.Since(tsdb.Started).Round(time.Second))
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Package prometheus checks a local Prometheus server: whether it is ready
+// and its TSDB healthy after boot, and whether it is writing a compacted
+// block or a snapshot that a reboot would interrupt. QueryChecker instead
+// uses a Prometheus as the source of truth about other hosts, through
+// PromQL queries over the metrics they already export.
+package prometheus
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotReady is returned while Prometheus is starting up, e.g. replaying
+// its write-ahead log
+var ErrNotReady = errors.New("prometheus not ready")
+
+// TSDB is the storage state from Prometheus' own metrics
+type TSDB struct {
+	// Compacting is set while a compaction is writing a block to disk
+	Compacting bool
+	// FailedCompactions and WALCorruptions count since the process started
+	FailedCompactions int
+	WALCorruptions    int
+	Started           time.Time
+}
+
+// Client handles communication with the Prometheus HTTP API
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a Prometheus client, e.g. for http://localhost:9090.
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// Ready returns nil once Prometheus serves queries, ErrNotReady before.
+func (c *Client) Ready(ctx context.Context) error {
+	resp, err := c.get(ctx, "/-/ready")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusServiceUnavailable:
+		return ErrNotReady
+	}
+	return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+}
+
+const (
+	populatingBlockMetric   = "prometheus_tsdb_compaction_populating_block"
+	failedCompactionsMetric = "prometheus_tsdb_compactions_failed_total"
+	walCorruptionsMetric    = "prometheus_tsdb_wal_corruptions_total"
+	startTimeMetric         = "process_start_time_seconds"
+)
+
+// TSDB reads the storage state from /metrics.
+func (c *Client) TSDB(ctx context.Context) (*TSDB, error) {
+	resp, err := c.get(ctx, "/metrics")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	values, err := parseMetrics(resp.Body, populatingBlockMetric, failedCompactionsMetric, walCorruptionsMetric, startTimeMetric)
+	if err != nil {
+		return nil, fmt.Errorf("metrics: %w", err)
+	}
+	if _, ok := values[populatingBlockMetric]; !ok {
+		return nil, fmt.Errorf("metrics: %s not found", populatingBlockMetric)
+	}
+	return &TSDB{
+		Compacting:        values[populatingBlockMetric] > 0,
+		FailedCompactions: int(values[failedCompactionsMetric]),
+		WALCorruptions:    int(values[walCorruptionsMetric]),
+		Started:           time.Unix(int64(values[startTimeMetric]), 0),
+	}, nil
+}
+
+func (c *Client) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// parseMetrics returns the named metrics from Prometheus text exposition,
+// summed over their label sets.
+func parseMetrics(r io.Reader, names ...string) (map[string]float64, error) {
+	values := make(map[string]float64)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		name, rest, _ := strings.Cut(line, "{")
+		if rest != "" {
+			rest = rest[strings.LastIndex(rest, "}")+1:]
+		} else {
+			name, rest, _ = strings.Cut(line, " ")
+		}
+		if !slices.Contains(names, name) {
+			continue
+		}
+
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		v, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", name, err)
+		}
+		values[name] += v
+	}
+	return values, scanner.Err()
+}
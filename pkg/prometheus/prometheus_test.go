@@ -0,0 +1,195 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func prometheusServer(t *testing.T, ready int, populating, failed, corruptions int) *httptest.Server {
+	t.Helper()
+	start := time.Now().Add(-10 * time.Minute).Unix()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/-/ready":
+			w.WriteHeader(ready)
+		case "/metrics":
+			fmt.Fprintf(w, `# HELP prometheus_tsdb_compaction_populating_block Set to 1 when a block is currently being written to the disk.
+# TYPE prometheus_tsdb_compaction_populating_block gauge
+prometheus_tsdb_compaction_populating_block %d
+prometheus_tsdb_compactions_failed_total %d
+prometheus_tsdb_compactions_total 12
+prometheus_tsdb_wal_corruptions_total %d
+process_start_time_seconds %d.5
+prometheus_http_requests_total{code="200",handler="/metrics"} 40
+`, populating, failed, corruptions, start)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestChecker_Check(t *testing.T) {
+	server := prometheusServer(t, 200, 1, 0, 0)
+	checker := NewChecker(NewClient(server.URL, 5*time.Second))
+	if err := checker.Check(context.Background()); err == nil || err.Error() != "TSDB compaction writing a block" {
+		t.Errorf("Check() = %v, want compaction", err)
+	}
+
+	checker.BlockCompaction = false
+	if err := checker.Check(context.Background()); err != nil {
+		t.Errorf("Check() without BlockCompaction = %v, want nil", err)
+	}
+
+	down := NewChecker(NewClient("http://127.0.0.1:1", time.Second))
+	if err := down.Check(context.Background()); err != nil {
+		t.Errorf("Check() = %v, want nil when Prometheus is down", err)
+	}
+}
+
+func TestChecker_Health(t *testing.T) {
+	tests := []struct {
+		name        string
+		ready       int
+		failed      int
+		corruptions int
+		wantErr     string
+	}{
+		{name: "healthy", ready: 200},
+		{name: "replaying WAL", ready: 503, wantErr: "prometheus not ready"},
+		{name: "compactions failing", ready: 200, failed: 3, corruptions: 1, wantErr: "3 failed compaction(s), 1 WAL corruption(s) since start 10m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := prometheusServer(t, tt.ready, 0, tt.failed, tt.corruptions)
+			checker := NewChecker(NewClient(server.URL, 5*time.Second))
+			err := checker.Health(context.Background())
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Health() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Health() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	server := prometheusServer(t, 503, 0, 0, 0)
+	if err := NewClient(server.URL, 5*time.Second).Ready(context.Background()); !errors.Is(err, ErrNotReady) {
+		t.Errorf("Ready() = %v, want ErrNotReady", err)
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	for _, d := range []string{
+		"01HZX0000000000000000000AA",                  // a block
+		"01HZX0000000000000000000AB.tmp-for-creation", // a compaction
+		"snapshots/20260101T000000Z-1a2b3c/01HZX0000000000000000000AA",
+		"snapshots/20260102T000000Z-4d5e6f/01HZX0000000000000000000AA",
+		"snapshots/20260102T000000Z-4d5e6f/01HZX0000000000000000000AC.tmp-for-creation",
+	} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := Snapshots(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "20260102T000000Z-4d5e6f" {
+		t.Errorf("Snapshots() = %q, want the one being written", got)
+	}
+
+	checker := &Checker{DataDir: dir}
+	if err := checker.Check(context.Background()); err == nil || !strings.Contains(err.Error(), "writing snapshot 20260102T000000Z-4d5e6f") {
+		t.Errorf("Check() = %v, want the snapshot", err)
+	}
+}
+
+func queryServer(t *testing.T, results map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		result, ok := results[r.URL.Query().Get("query")]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"parse error"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"status":"success","data":%s}`, result)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestQueryChecker(t *testing.T) {
+	degraded := `{"resultType":"vector","result":[{"metric":{"device":"md0","instance":"node2:9100"},"value":[1760000000,"1"]}]}`
+	clear := `{"resultType":"vector","result":[]}`
+	server := queryServer(t, map[string]string{
+		MdstatRules[0].Expr: degraded,
+		MdstatRules[1].Expr: clear,
+		"up == 0":           clear,
+		"count(up)":         `{"resultType":"scalar","result":[1760000000,"3"]}`,
+	})
+	client := NewClient(server.URL, 5*time.Second)
+
+	rules, err := ParseRules("mdstat; up == 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker := &QueryChecker{Client: client, Rules: rules}
+	want := "md degraded: device=md0 instance=node2:9100"
+	if err := checker.Health(context.Background()); err == nil || err.Error() != want {
+		t.Errorf("Health() = %v, want %q", err, want)
+	}
+
+	samples, err := client.Query(context.Background(), "count(up)")
+	if err != nil || len(samples) != 1 || samples[0].Value != 3 {
+		t.Errorf("Query(scalar) = %v, %v, want 3", samples, err)
+	}
+
+	checker.Rules = []Rule{{Name: "bad", Expr: "up{"}}
+	if err := checker.Health(context.Background()); err == nil || !strings.Contains(err.Error(), "bad_data: parse error") {
+		t.Errorf("Health() = %v, want the query error", err)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules(`raid degraded = node_md_disks_active < node_md_disks; up == 0; node_md_state{state=~"resync"} == 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Rule{
+		{Name: "raid degraded", Expr: "node_md_disks_active < node_md_disks"},
+		{Name: "up == 0", Expr: "up == 0"},
+		{Name: `node_md_state{state=~"resync"} == 1`, Expr: `node_md_state{state=~"resync"} == 1`},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("ParseRules() = %v, want %v", rules, want)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("rule %d = %+v, want %+v", i, rules[i], want[i])
+		}
+	}
+
+	if _, err := ParseRules("empty="); err == nil {
+		t.Error("ParseRules() with an empty query succeeded")
+	}
+}
@@ -0,0 +1,194 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Sample is one series of an instant query result
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// Query runs an instant PromQL query through /api/v1/query. A scalar
+// result is returned as a single sample without labels.
+func (c *Client) Query(ctx context.Context, expr string) ([]Sample, error) {
+	resp, err := c.get(ctx, "/api/v1/query?query="+url.QueryEscape(expr))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Status    string `json:"status"`
+		ErrorType string `json:"errorType"`
+		Error     string `json:"error"`
+		Data      struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	if body.Status != "success" {
+		// Bad queries come back as 400 with the reason in the body
+		return nil, fmt.Errorf("query %q: %s: %s", expr, body.ErrorType, body.Error)
+	}
+
+	switch body.Data.ResultType {
+	case "vector":
+		var series []struct {
+			Metric map[string]string `json:"metric"`
+			Value  [2]any            `json:"value"`
+		}
+		if err := json.Unmarshal(body.Data.Result, &series); err != nil {
+			return nil, fmt.Errorf("query %q: %w", expr, err)
+		}
+		samples := make([]Sample, 0, len(series))
+		for _, s := range series {
+			v, err := sampleValue(s.Value)
+			if err != nil {
+				return nil, fmt.Errorf("query %q: %w", expr, err)
+			}
+			samples = append(samples, Sample{Labels: s.Metric, Value: v})
+		}
+		return samples, nil
+	case "scalar":
+		var value [2]any
+		if err := json.Unmarshal(body.Data.Result, &value); err != nil {
+			return nil, fmt.Errorf("query %q: %w", expr, err)
+		}
+		v, err := sampleValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("query %q: %w", expr, err)
+		}
+		return []Sample{{Value: v}}, nil
+	}
+	return nil, fmt.Errorf("query %q: unsupported result type %q", expr, body.Data.ResultType)
+}
+
+// sampleValue parses the [<time>, "<value>"] pair Prometheus returns
+func sampleValue(pair [2]any) (float64, error) {
+	s, ok := pair[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected sample value %v", pair[1])
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// Rule is a query whose every result series is a problem, in the style of
+// an alerting rule, e.g. "node_md_disks_active < node_md_disks"
+type Rule struct {
+	Name string
+	Expr string
+}
+
+// MdstatRules find degraded and resyncing md arrays on every host whose
+// node_exporter Prometheus scrapes, from the metrics of its mdadm
+// collector
+var MdstatRules = []Rule{
+	{Name: "md degraded", Expr: `node_md_disks_required - ignoring(state) node_md_disks{state="active"} > 0`},
+	{Name: "md resyncing", Expr: `node_md_state{state=~"recovering|resync|check"} == 1`},
+}
+
+// ParseRules parses a semicolon-separated list of rules, each
+// "<name>=<expr>" or a bare expression named after itself; "mdstat" stands
+// for MdstatRules. Semicolons never appear in PromQL, unlike commas.
+func ParseRules(s string) ([]Rule, error) {
+	var rules []Rule
+	for _, item := range strings.Split(s, ";") {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "":
+			continue
+		case item == "mdstat":
+			rules = append(rules, MdstatRules...)
+			continue
+		}
+		rule := Rule{Name: item, Expr: item}
+		// A name is a plain word before "=", which can't start an expression
+		if name, expr, ok := strings.Cut(item, "="); ok && isRuleName(strings.TrimSpace(name)) && !strings.HasPrefix(expr, "=") {
+			rule = Rule{Name: strings.TrimSpace(name), Expr: strings.TrimSpace(expr)}
+		}
+		if rule.Expr == "" {
+			return nil, fmt.Errorf("rule %q: empty query", rule.Name)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func isRuleName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r == ' ' || r == '_' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// QueryChecker checks rules against a central Prometheus, e.g. to gate
+// reboots on the state of every host it scrapes. Hosts that stopped being
+// scraped return no series; add a rule such as "up == 0" to catch them.
+type QueryChecker struct {
+	Client *Client
+	Rules  []Rule
+}
+
+// Matches describes each series the rules return, e.g. "md degraded:
+// instance=node2:9100 device=md0".
+func (c *QueryChecker) Matches(ctx context.Context) ([]string, error) {
+	var matches []string
+	for _, rule := range c.Rules {
+		samples, err := c.Client.Query(ctx, rule.Expr)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range samples {
+			matches = append(matches, rule.Name+describeLabels(s.Labels))
+		}
+	}
+	return matches, nil
+}
+
+// Health returns an error naming the series the rules return.
+func (c *QueryChecker) Health(ctx context.Context) error {
+	matches, err := c.Matches(ctx)
+	if err != nil {
+		return err
+	}
+	if len(matches) > 0 {
+		return fmt.Errorf("%s", strings.Join(matches, "; "))
+	}
+	return nil
+}
+
+// describeLabels formats labels as ": k=v k=v", sorted, without the
+// metric name
+func describeLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		if k != "__name__" {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	slices.Sort(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + labels[k]
+	}
+	return ": " + strings.Join(parts, " ")
+}
@@ -0,0 +1,23 @@
+package prometheus
+
+import "path/filepath"
+
+// Snapshots returns the names of the TSDB snapshots being written under
+// dataDir, e.g. /var/lib/prometheus. A snapshot hard-links the persisted
+// blocks and then writes the in-memory head as a new block, which lives in
+// a "<ulid>.tmp-for-creation" directory until it is complete.
+func Snapshots(dataDir string) ([]string, error) {
+	tmps, err := filepath.Glob(filepath.Join(dataDir, "snapshots", "*", "*.tmp-for-creation"))
+	if err != nil {
+		return nil, err
+	}
+	// Glob sorts, so a snapshot's entries are adjacent
+	var names []string
+	for _, tmp := range tmps {
+		name := filepath.Base(filepath.Dir(tmp))
+		if len(names) == 0 || names[len(names)-1] != name {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
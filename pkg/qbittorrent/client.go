@@ -0,0 +1,440 @@
+// Package qbittorrent provides a client for the qBittorrent Web API.
+package qbittorrent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrAuthRequired is returned when qBittorrent wants a login but the client
+// has no username, i.e. "Bypass authentication for clients on localhost"
+// isn't on or doesn't cover the sidecar's address
+var ErrAuthRequired = errors.New("authentication required: set a username or enable bypass authentication for localhost")
+
+// UnknownETA is the ETA qBittorrent reports when it cannot estimate one
+const UnknownETA = 8640000
+
+// Torrent represents a torrent from the qBittorrent API
+type Torrent struct {
+	Hash     string  `json:"hash"`
+	Name     string  `json:"name"`
+	Progress float64 `json:"progress"`
+	State    string  `json:"state"`
+	ETA      int     `json:"eta"`     // seconds, UnknownETA = unknown
+	DlSpeed  int64   `json:"dlspeed"` // bytes/s
+	UpSpeed  int64   `json:"upspeed"` // bytes/s
+}
+
+// Operation names the file operation a torrent is in the middle of:
+// "rechecking" while its data is being hashed (checkingDL, checkingUP, or
+// checkingResumeData at startup) or "moving" while its files are moved to
+// another directory. Interrupting either leaves the torrent to be rechecked
+// from scratch or its files split between directories. Empty otherwise.
+func (t Torrent) Operation() string {
+	switch t.State {
+	case "checkingDL", "checkingUP", "checkingResumeData":
+		return "rechecking"
+	case "moving":
+		return "moving"
+	}
+	return ""
+}
+
+// Blocks reports whether the sidecar waits for the torrent: it is in the
+// middle of an Operation, or its download will finish within threshold.
+func (t Torrent) Blocks(threshold time.Duration) bool {
+	if t.Operation() != "" {
+		return true
+	}
+	return t.Progress < 1.0 && t.ETA > 0 && t.ETA != UnknownETA && time.Duration(t.ETA)*time.Second <= threshold
+}
+
+// Paused reports whether the torrent is paused, or "stopped" as
+// qBittorrent 5 calls it.
+func (t Torrent) Paused() bool {
+	switch t.State {
+	case "pausedDL", "pausedUP", "stoppedDL", "stoppedUP":
+		return true
+	}
+	return false
+}
+
+// Stats summarizes transfer activity across torrents
+type Stats struct {
+	DownloadRate int64          // bytes/s
+	UploadRate   int64          // bytes/s
+	ByState      map[string]int // torrent count per qBittorrent state
+	LongestETA   time.Duration  // longest known ETA of an incomplete torrent
+}
+
+// Summarize aggregates rates, state counts and the longest remaining ETA.
+func Summarize(torrents []Torrent) Stats {
+	stats := Stats{ByState: make(map[string]int)}
+	for _, t := range torrents {
+		stats.DownloadRate += t.DlSpeed
+		stats.UploadRate += t.UpSpeed
+		stats.ByState[t.State]++
+
+		if t.Progress < 1.0 && t.ETA > 0 && t.ETA != UnknownETA {
+			if eta := time.Duration(t.ETA) * time.Second; eta > stats.LongestETA {
+				stats.LongestETA = eta
+			}
+		}
+	}
+	return stats
+}
+
+// Client handles communication with the qBittorrent Web API
+type Client struct {
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+
+	// CookieFile, if set, keeps the session cookie across restarts, so the
+	// sidecar doesn't log in again each time it starts; qBittorrent bans
+	// an address after too many logins that fail
+	CookieFile string
+
+	mu       sync.Mutex
+	loggedIn bool
+	restored bool
+	bypassed bool
+}
+
+// NewClient creates a new qBittorrent API client.
+// An empty username skips authentication.
+func NewClient(baseURL, username, password string, timeout time.Duration) *Client {
+	jar, _ := cookiejar.New(nil)
+	return &Client{
+		baseURL:  baseURL,
+		username: username,
+		password: password,
+		httpClient: &http.Client{
+			Timeout: timeout,
+			Jar:     jar,
+		},
+	}
+}
+
+// Login authenticates and stores the session cookie.
+func (c *Client) Login(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.login(ctx)
+}
+
+func (c *Client) login(ctx context.Context) error {
+	if c.username == "" {
+		return nil
+	}
+
+	form := url.Values{"username": {c.username}, "password": {c.password}}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/v2/auth/login",
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64))
+	resp.Body.Close()
+
+	// Wrong credentials are a 200 with "Fails." as the body; 403 means the
+	// address is banned after too many failures
+	c.loggedIn = resp.StatusCode == http.StatusOK && strings.TrimSpace(string(body)) != "Fails."
+	if c.loggedIn {
+		c.bypassed = false
+		c.saveSession()
+		return nil
+	}
+
+	// With bypass authentication for localhost, the API answers without a
+	// session whatever the credentials
+	if ok, err := c.authBypassed(ctx); err == nil && ok {
+		c.loggedIn = true
+		c.bypassed = true
+		return nil
+	}
+	return fmt.Errorf("login failed: status %d", resp.StatusCode)
+}
+
+// AuthBypassed reports whether the last login was skipped because
+// qBittorrent answered without a session, as it does for clients covered by
+// "Bypass authentication for clients on localhost".
+func (c *Client) AuthBypassed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bypassed
+}
+
+// authBypassed asks for the version, which needs a session unless
+// authentication is bypassed for this client.
+func (c *Client) authBypassed(ctx context.Context) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/v2/app/version", nil)
+	if err != nil {
+		return false, fmt.Errorf("create request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("request failed: %w", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK, nil
+}
+
+// ExcessAccess reports a Web UI login the sidecar doesn't need, when
+// qBittorrent bypasses authentication for its address anyway; "" without
+// a username or when a login is required.
+func (c *Client) ExcessAccess(ctx context.Context) (string, error) {
+	if c.username == "" {
+		return "", nil
+	}
+	// Ask without the session cookie
+	anonymous := &http.Client{Timeout: c.httpClient.Timeout}
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/v2/app/version", nil)
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	resp, err := anonymous.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil
+	}
+	return "a Web UI login qBittorrent doesn't ask for from this address", nil
+}
+
+// restoreSession loads the cookies saved by saveSession into the jar and
+// reports whether there were any. A session that has since expired is
+// answered with 403 and replaced by logging in again.
+func (c *Client) restoreSession() bool {
+	if c.CookieFile == "" {
+		return false
+	}
+	data, err := os.ReadFile(c.CookieFile)
+	if err != nil {
+		return false
+	}
+	var saved []savedCookie
+	if err := json.Unmarshal(data, &saved); err != nil || len(saved) == 0 {
+		return false
+	}
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return false
+	}
+	cookies := make([]*http.Cookie, len(saved))
+	for i, sc := range saved {
+		cookies[i] = &http.Cookie{Name: sc.Name, Value: sc.Value, Path: "/"}
+	}
+	c.httpClient.Jar.SetCookies(u, cookies)
+	return true
+}
+
+// saveSession writes the session cookies to CookieFile, readable only by
+// the owner. Failing to save only costs a login after the next restart, so
+// errors are ignored.
+func (c *Client) saveSession() {
+	if c.CookieFile == "" {
+		return
+	}
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return
+	}
+	var saved []savedCookie
+	for _, ck := range c.httpClient.Jar.Cookies(u) {
+		saved = append(saved, savedCookie{Name: ck.Name, Value: ck.Value})
+	}
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.CookieFile), "."+filepath.Base(c.CookieFile)+".*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		return
+	}
+	os.Rename(tmp.Name(), c.CookieFile)
+}
+
+type savedCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Torrents returns torrents matching filter (e.g. "downloading"; empty for all).
+// Logs in first if needed and re-authenticates once if the session expired.
+func (c *Client) Torrents(ctx context.Context, filter string) ([]Torrent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.session(ctx); err != nil {
+		return nil, err
+	}
+
+	torrents, status, err := c.getTorrents(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if status == http.StatusForbidden && c.username == "" {
+		return nil, ErrAuthRequired
+	}
+
+	// Re-login if the session expired
+	if status == http.StatusForbidden {
+		c.loggedIn = false
+		if err := c.login(ctx); err != nil {
+			return nil, err
+		}
+		torrents, status, err = c.getTorrents(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", status)
+	}
+	return torrents, nil
+}
+
+// session restores the saved session or logs in, unless already logged in
+func (c *Client) session(ctx context.Context) error {
+	if !c.restored {
+		c.restored = true
+		if c.restoreSession() {
+			c.loggedIn = true
+		}
+	}
+	if !c.loggedIn && c.username != "" {
+		return c.login(ctx)
+	}
+	return nil
+}
+
+// Pause pauses the torrents with the given hashes, or "stops" them as
+// qBittorrent 5 calls it.
+func (c *Client) Pause(ctx context.Context, hashes []string) error {
+	return c.act(ctx, hashes, "stop", "pause")
+}
+
+// Resume resumes the torrents with the given hashes, or "starts" them as
+// qBittorrent 5 calls it.
+func (c *Client) Resume(ctx context.Context, hashes []string) error {
+	return c.act(ctx, hashes, "start", "resume")
+}
+
+// act posts hashes to /api/v2/torrents/<action>, falling back to the name
+// qBittorrent 4 used, and re-authenticates once if the session expired.
+func (c *Client) act(ctx context.Context, hashes []string, action, legacy string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.session(ctx); err != nil {
+		return err
+	}
+
+	form := url.Values{"hashes": {strings.Join(hashes, "|")}}
+	post := func() (int, error) {
+		status, err := c.post(ctx, "/api/v2/torrents/"+action, form)
+		if err == nil && status == http.StatusNotFound {
+			status, err = c.post(ctx, "/api/v2/torrents/"+legacy, form)
+		}
+		return status, err
+	}
+
+	status, err := post()
+	if err != nil {
+		return err
+	}
+	if status == http.StatusForbidden && c.username == "" {
+		return ErrAuthRequired
+	}
+	if status == http.StatusForbidden {
+		c.loggedIn = false
+		if err := c.login(ctx); err != nil {
+			return err
+		}
+		if status, err = post(); err != nil {
+			return err
+		}
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("%s: unexpected status: %d", action, status)
+	}
+	return nil
+}
+
+func (c *Client) post(ctx context.Context, path string, form url.Values) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1024))
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func (c *Client) getTorrents(ctx context.Context, filter string) ([]Torrent, int, error) {
+	url := c.baseURL + "/api/v2/torrents/info"
+	if filter != "" {
+		url += "?filter=" + filter
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, nil
+	}
+
+	var torrents []Torrent
+	if err := json.NewDecoder(resp.Body).Decode(&torrents); err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("decode response: %w", err)
+	}
+	return torrents, resp.StatusCode, nil
+}
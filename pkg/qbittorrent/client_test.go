@@ -0,0 +1,352 @@
+package qbittorrent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_Torrents(t *testing.T) {
+	tests := []struct {
+		name           string
+		username       string
+		responseCode   int
+		responseBody   string
+		wantCount      int
+		wantErr        bool
+		wantErrContain string
+	}{
+		{
+			name:         "no auth, no torrents",
+			responseCode: 200,
+			responseBody: `[]`,
+			wantCount:    0,
+		},
+		{
+			name:         "with auth",
+			username:     "admin",
+			responseCode: 200,
+			responseBody: `[
+				{"hash": "a", "name": "ubuntu.iso", "progress": 0.5, "state": "downloading", "eta": 120},
+				{"hash": "b", "name": "debian.iso", "progress": 0.1, "state": "stalledDL", "eta": 8640000}
+			]`,
+			wantCount: 2,
+		},
+		{
+			name:           "server error",
+			responseCode:   500,
+			responseBody:   `oops`,
+			wantErr:        true,
+			wantErrContain: "unexpected status",
+		},
+		{
+			name:           "invalid json",
+			responseCode:   200,
+			responseBody:   `{not valid json`,
+			wantErr:        true,
+			wantErrContain: "decode response",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logins := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/v2/auth/login":
+					logins++
+					http.SetCookie(w, &http.Cookie{Name: "SID", Value: "session"})
+					w.Write([]byte("Ok."))
+				case "/api/v2/torrents/info":
+					if r.URL.Query().Get("filter") != "downloading" {
+						t.Errorf("filter = %q", r.URL.Query().Get("filter"))
+					}
+					w.WriteHeader(tt.responseCode)
+					w.Write([]byte(tt.responseBody))
+				default:
+					t.Errorf("unexpected path: %s", r.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL, tt.username, "secret", 5*time.Second)
+			torrents, err := client.Torrents(context.Background(), "downloading")
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				} else if !strings.Contains(err.Error(), tt.wantErrContain) {
+					t.Errorf("error = %q, want to contain %q", err.Error(), tt.wantErrContain)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(torrents) != tt.wantCount {
+				t.Errorf("got %d torrents, want %d", len(torrents), tt.wantCount)
+			}
+			if tt.username != "" && logins != 1 {
+				t.Errorf("logins = %d, want 1", logins)
+			}
+		})
+	}
+}
+
+func TestClient_ReloginOnForbidden(t *testing.T) {
+	logins := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/auth/login":
+			logins++
+			http.SetCookie(w, &http.Cookie{Name: "SID", Value: "session"})
+		case "/api/v2/torrents/info":
+			// First session has expired
+			if logins < 2 {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`[{"name": "x"}]`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "admin", "secret", 5*time.Second)
+	torrents, err := client.Torrents(context.Background(), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(torrents) != 1 || logins != 2 {
+		t.Errorf("torrents = %d, logins = %d", len(torrents), logins)
+	}
+}
+
+func TestClient_PauseResume(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		// qBittorrent 4 has no stop or start
+		case "/api/v2/torrents/pause", "/api/v2/torrents/resume":
+			r.ParseForm()
+			calls = append(calls, strings.TrimPrefix(r.URL.Path, "/api/v2/torrents/")+" "+r.PostForm.Get("hashes"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "", "", 5*time.Second)
+	if err := client.Pause(context.Background(), []string{"a", "b"}); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if err := client.Resume(context.Background(), []string{"a", "b"}); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if err := client.Resume(context.Background(), nil); err != nil {
+		t.Fatalf("Resume(nil) error = %v", err)
+	}
+	if want := []string{"pause a|b", "resume a|b"}; strings.Join(calls, ",") != strings.Join(want, ",") {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestClient_ExcessAccess(t *testing.T) {
+	bypass := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("SID"); err != nil && !bypass {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "admin", "secret", 5*time.Second)
+	if got, err := client.ExcessAccess(context.Background()); err != nil || got == "" {
+		t.Errorf("ExcessAccess() = %q, %v, want the unneeded login reported", got, err)
+	}
+	bypass = false
+	if got, err := client.ExcessAccess(context.Background()); err != nil || got != "" {
+		t.Errorf("ExcessAccess() = %q, %v, want nothing while a login is required", got, err)
+	}
+	if got, _ := NewClient(server.URL, "", "", 5*time.Second).ExcessAccess(context.Background()); got != "" {
+		t.Errorf("ExcessAccess() without a username = %q", got)
+	}
+}
+
+func TestClient_LoginFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "admin", "wrong", 5*time.Second)
+	if _, err := client.Torrents(context.Background(), ""); err == nil || !strings.Contains(err.Error(), "login failed") {
+		t.Errorf("err = %v, want login failed", err)
+	}
+}
+
+func TestClient_LoginEncoding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/auth/login":
+			if r.FormValue("username") != "admin" || r.FormValue("password") != "p&ss=word" {
+				w.Write([]byte("Fails."))
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "SID", Value: "session", Path: "/"})
+			w.Write([]byte("Ok."))
+		default:
+			if c, err := r.Cookie("SID"); err != nil || c.Value != "session" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "admin", "p&ss=word", 5*time.Second)
+	if _, err := client.Torrents(context.Background(), ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Wrong credentials are a 200 with "Fails."
+	client = NewClient(server.URL, "admin", "wrong", 5*time.Second)
+	if _, err := client.Torrents(context.Background(), ""); err == nil || !strings.Contains(err.Error(), "login failed") {
+		t.Errorf("err = %v, want login failed", err)
+	}
+}
+
+func TestClient_AuthBypassed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/auth/login":
+			w.Write([]byte("Fails."))
+		case "/api/v2/app/version":
+			w.Write([]byte("v4.6.0"))
+		case "/api/v2/torrents/info":
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "admin", "stale", 5*time.Second)
+	if _, err := client.Torrents(context.Background(), ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !client.AuthBypassed() {
+		t.Error("AuthBypassed() = false, want true")
+	}
+}
+
+func TestClient_AuthRequired(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "", "", 5*time.Second)
+	if _, err := client.Torrents(context.Background(), ""); !errors.Is(err, ErrAuthRequired) {
+		t.Errorf("err = %v, want ErrAuthRequired", err)
+	}
+}
+
+func TestClient_CookieFile(t *testing.T) {
+	logins := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/auth/login":
+			logins++
+			http.SetCookie(w, &http.Cookie{Name: "SID", Value: "session", Path: "/"})
+			w.Write([]byte("Ok."))
+		case "/api/v2/torrents/info":
+			if c, err := r.Cookie("SID"); err != nil || c.Value != "session" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	for i := 0; i < 2; i++ {
+		// A new client each time, as after a restart
+		client := NewClient(server.URL, "admin", "secret", 5*time.Second)
+		client.CookieFile = path
+		if _, err := client.Torrents(context.Background(), ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if logins != 1 {
+		t.Errorf("logins = %d, want 1", logins)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestTorrent_Operation(t *testing.T) {
+	tests := map[string]string{
+		"checkingDL":         "rechecking",
+		"checkingUP":         "rechecking",
+		"checkingResumeData": "rechecking",
+		"moving":             "moving",
+		"downloading":        "",
+		"stalledUP":          "",
+	}
+	for state, want := range tests {
+		if got := (Torrent{State: state}).Operation(); got != want {
+			t.Errorf("Operation(%s) = %q, want %q", state, got, want)
+		}
+	}
+}
+
+func TestTorrent_Blocks(t *testing.T) {
+	tests := []struct {
+		torrent Torrent
+		want    bool
+	}{
+		{Torrent{State: "downloading", Progress: 0.9, ETA: 60}, true},
+		{Torrent{State: "downloading", Progress: 0.5, ETA: 600}, false},
+		{Torrent{State: "stalledDL", Progress: 0.1, ETA: UnknownETA}, false},
+		{Torrent{State: "uploading", Progress: 1.0, ETA: 60}, false},
+		{Torrent{State: "checkingUP", Progress: 1.0}, true},
+		{Torrent{State: "moving", Progress: 1.0}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.torrent.Blocks(5 * time.Minute); got != tt.want {
+			t.Errorf("Blocks(%+v) = %v, want %v", tt.torrent, got, tt.want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	torrents := []Torrent{
+		{Name: "a", State: "downloading", Progress: 0.5, ETA: 600, DlSpeed: 1000, UpSpeed: 10},
+		{Name: "b", State: "downloading", Progress: 0.9, ETA: 60, DlSpeed: 500},
+		{Name: "c", State: "stalledDL", Progress: 0.1, ETA: UnknownETA},
+		{Name: "d", State: "uploading", Progress: 1.0, ETA: 8000, UpSpeed: 200},
+	}
+
+	stats := Summarize(torrents)
+	if stats.DownloadRate != 1500 || stats.UploadRate != 210 {
+		t.Errorf("rates = %d/%d, want 1500/210", stats.DownloadRate, stats.UploadRate)
+	}
+	if stats.ByState["downloading"] != 2 || stats.ByState["stalledDL"] != 1 || stats.ByState["uploading"] != 1 {
+		t.Errorf("ByState = %v", stats.ByState)
+	}
+	if stats.LongestETA != 10*time.Minute {
+		t.Errorf("LongestETA = %v, want 10m", stats.LongestETA)
+	}
+}
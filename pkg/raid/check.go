@@ -3,12 +3,20 @@ package raid
 import (
 	"context"
 	"fmt"
+	"strings"
 )
 
 // Checker implements check.Checker for RAID health.
+// If Configs is set it replaces Arrays, and arrays with a warn policy never
+// fail the check.
 type Checker struct {
 	MdstatPath string
 	Arrays     []string
+	Configs    []ArrayConfig
+	// Detail adds state from sysfs under SysfsRoot to local arrays; see
+	// AddDetail
+	Detail    bool
+	SysfsRoot string
 }
 
 // NewChecker creates a RAID health checker.
@@ -37,12 +45,45 @@ func (c *Checker) Check(ctx context.Context) error {
 	default:
 	}
 
-	healthy, reason, err := Check(c.MdstatPath, c.Arrays)
+	if len(c.Configs) > 0 {
+		return c.checkConfigs(ctx)
+	}
+
+	statuses, err := ReadSource(ctx, c.MdstatPath)
 	if err != nil {
-		return fmt.Errorf("raid check failed: %w", err)
+		return fmt.Errorf("raid check failed: failed to read mdstat: %w", err)
+	}
+	if c.Detail {
+		if err := AddDetail(c.SysfsRoot, statuses); err != nil {
+			return fmt.Errorf("raid check failed: %w", err)
+		}
 	}
-	if !healthy {
+	if healthy, reason := Evaluate(statuses, c.Arrays); !healthy {
 		return fmt.Errorf("%s", reason)
 	}
 	return nil
 }
+
+func (c *Checker) checkConfigs(ctx context.Context) error {
+	snap, err := Snapshot(ctx, c.MdstatPath, c.Configs)
+	if err != nil {
+		return fmt.Errorf("raid check failed: %w", err)
+	}
+	if c.Detail {
+		if err := AddLocalDetail(c.SysfsRoot, snap); err != nil {
+			return fmt.Errorf("raid check failed: %w", err)
+		}
+	}
+
+	var blocking []string
+	for _, cfg := range c.Configs {
+		reason, policy := cfg.Assess(snap[cfg.Path(c.MdstatPath)])
+		if reason != "" && policy == PolicyBlock {
+			blocking = append(blocking, reason)
+		}
+	}
+	if len(blocking) > 0 {
+		return fmt.Errorf("%s", strings.Join(blocking, "; "))
+	}
+	return nil
+}
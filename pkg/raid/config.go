@@ -0,0 +1,172 @@
+package raid
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Policy says what an unhealthy array does to the inhibitor
+type Policy string
+
+const (
+	// PolicyBlock holds the inhibitor, delaying shutdown
+	PolicyBlock Policy = "block"
+	// PolicyWarn only logs and notifies
+	PolicyWarn Policy = "warn"
+)
+
+// ArrayConfig is the per-array policy for the RAID checker
+type ArrayConfig struct {
+	Name       string
+	MdstatPath string // path or URL, "" = the checker's default
+	Rebuild    Policy // while rebuilding, resyncing, checking or reshaping
+	Degraded   Policy // while degraded and not rebuilding, or read-only
+	Mismatch   Policy // after a check found mismatches (needs AddDetail)
+}
+
+// ParseArrays parses a comma-separated list of arrays. Each array name may
+// be followed by colon-separated options:
+//
+//	warn                 shorthand for rebuild=warn:degraded=warn
+//	rebuild=block|warn   policy while rebuilding or running any other sync
+//	                     operation, e.g. a check scrub (default block)
+//	degraded=block|warn  policy while degraded or read-only (default block)
+//	mismatch=block|warn  policy once a check found mismatches (default warn)
+//	mdstat=SOURCE        read this array's status from SOURCE, a path or
+//	                     URL (see ReadSource); must be the last option
+//
+// e.g. "md0,md1:warn,md0:mdstat=http://node2:9101/mdstat".
+func ParseArrays(spec string) ([]ArrayConfig, error) {
+	var configs []ArrayConfig
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		cfg := ArrayConfig{
+			Name:     parts[0],
+			Rebuild:  PolicyBlock,
+			Degraded: PolicyBlock,
+			Mismatch: PolicyWarn,
+		}
+		for i := 1; i < len(parts); i++ {
+			key, value, _ := strings.Cut(parts[i], "=")
+			var err error
+			switch key {
+			case "warn":
+				cfg.Rebuild, cfg.Degraded = PolicyWarn, PolicyWarn
+			case "rebuild":
+				cfg.Rebuild, err = parsePolicy(value)
+			case "degraded":
+				cfg.Degraded, err = parsePolicy(value)
+			case "mismatch":
+				cfg.Mismatch, err = parsePolicy(value)
+			case "mdstat":
+				// URLs contain colons, so the source takes the rest
+				value = strings.Join(append([]string{value}, parts[i+1:]...), ":")
+				i = len(parts)
+				if value == "" {
+					err = fmt.Errorf("empty mdstat path")
+				}
+				cfg.MdstatPath = value
+			default:
+				err = fmt.Errorf("unknown option %q", key)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("array %s: %w", cfg.Name, err)
+			}
+		}
+		configs = append(configs, cfg)
+	}
+
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no arrays configured")
+	}
+	return configs, nil
+}
+
+func parsePolicy(s string) (Policy, error) {
+	switch p := Policy(s); p {
+	case PolicyBlock, PolicyWarn:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid policy %q (want block or warn)", s)
+}
+
+// Path returns the mdstat source for the array.
+func (a ArrayConfig) Path(defaultPath string) string {
+	if a.MdstatPath != "" {
+		return a.MdstatPath
+	}
+	return defaultPath
+}
+
+// Label names the array in reasons: its name, plus the host for arrays on
+// remote sources, e.g. "md0@node2".
+func (a ArrayConfig) Label() string {
+	if host := SourceLabel(a.MdstatPath); host != "" {
+		return a.Name + "@" + host
+	}
+	return a.Name
+}
+
+// Assess finds the array in statuses and describes what's wrong with it,
+// along with the policy that applies. reason is empty when the array is
+// healthy. A missing array always blocks.
+func (a ArrayConfig) Assess(statuses []Status) (reason string, policy Policy) {
+	for _, s := range statuses {
+		if s.Name != a.Name {
+			continue
+		}
+		switch {
+		case s.Rebuilding:
+			return fmt.Sprintf("%s rebuilding: %s", a.Label(), s.SyncDetail()), a.Rebuild
+		case !s.Healthy:
+			return fmt.Sprintf("%s degraded: %s", a.Label(), s.DegradedDetail()), a.Degraded
+		case s.Syncing():
+			return fmt.Sprintf("%s %s: %s", a.Label(), s.SyncAction, s.SyncDetail()), a.Rebuild
+		case s.ReadOnly:
+			return fmt.Sprintf("%s read-only", a.Label()), a.Degraded
+		case s.Mismatched():
+			return fmt.Sprintf("%s has %d mismatched sectors", a.Label(), s.MismatchCount), a.Mismatch
+		default:
+			return "", ""
+		}
+	}
+	return fmt.Sprintf("expected array %s not found", a.Label()), PolicyBlock
+}
+
+// Snapshot reads each distinct mdstat source used by configs once.
+func Snapshot(ctx context.Context, defaultPath string, configs []ArrayConfig) (map[string][]Status, error) {
+	snap := make(map[string][]Status)
+	for _, cfg := range configs {
+		path := cfg.Path(defaultPath)
+		if _, ok := snap[path]; ok {
+			continue
+		}
+		statuses, err := ReadSource(ctx, path)
+		if err != nil {
+			if label := SourceLabel(path); label != "" {
+				return nil, fmt.Errorf("failed to read mdstat from %s: %w", label, err)
+			}
+			return nil, fmt.Errorf("failed to read mdstat: %w", err)
+		}
+		snap[path] = statuses
+	}
+	return snap, nil
+}
+
+// LocalNames returns the names of arrays not read from a remote source,
+// i.e. those this host can act on.
+func LocalNames(configs []ArrayConfig) []string {
+	var names []string
+	for _, cfg := range configs {
+		if SourceLabel(cfg.MdstatPath) == "" {
+			names = append(names, cfg.Name)
+		}
+	}
+	return names
+}
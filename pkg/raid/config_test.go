@@ -0,0 +1,141 @@
+package raid
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseArrays(t *testing.T) {
+	got, err := ParseArrays("md0:mismatch=block, md1:warn ,md2:degraded=warn:mdstat=/host/proc/mdstat")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []ArrayConfig{
+		{Name: "md0", Rebuild: PolicyBlock, Degraded: PolicyBlock, Mismatch: PolicyBlock},
+		{Name: "md1", Rebuild: PolicyWarn, Degraded: PolicyWarn, Mismatch: PolicyWarn},
+		{Name: "md2", Rebuild: PolicyBlock, Degraded: PolicyWarn, Mismatch: PolicyWarn, MdstatPath: "/host/proc/mdstat"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseArrays = %+v, want %+v", got, want)
+	}
+
+	for _, spec := range []string{"", "md0:rebuild=maybe", "md0:bogus", "md0:mdstat="} {
+		if _, err := ParseArrays(spec); err == nil {
+			t.Errorf("ParseArrays(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestArrayConfig_Assess(t *testing.T) {
+	statuses := []Status{
+		{Name: "md0", Healthy: true, DeviceList: "[UU]"},
+		{Name: "md1", Rebuilding: true, SyncAction: "recovery", Progress: "40.0%", DeviceList: "[U_]"},
+		{Name: "md2", DeviceList: "[U_]"},
+		{Name: "md4", Healthy: true, SyncAction: "check", Progress: "5.0%", Finish: 90 * time.Minute, DeviceList: "[UU]"},
+		{Name: "md5", Healthy: true, ReadOnly: true, DeviceList: "[UU]"},
+		{Name: "md6", Healthy: true, MismatchCount: 128, DeviceList: "[UU]"},
+		{Name: "md7", Healthy: true, SyncAction: "check", Progress: "50.0%", MismatchCount: 128, DeviceList: "[UU]"},
+	}
+	cfg := ArrayConfig{Rebuild: PolicyBlock, Degraded: PolicyWarn, Mismatch: PolicyWarn}
+
+	tests := []struct {
+		array      string
+		wantReason string
+		wantPolicy Policy
+	}{
+		{"md0", "", ""},
+		{"md1", "md1 rebuilding: 40.0%", PolicyBlock},
+		{"md2", "md2 degraded: [U_]", PolicyWarn},
+		{"md3", "expected array md3 not found", PolicyBlock},
+		{"md4", "md4 check: 5.0%, 1h30m0s left", PolicyBlock},
+		{"md5", "md5 read-only", PolicyWarn},
+		{"md6", "md6 has 128 mismatched sectors", PolicyWarn},
+		{"md7", "md7 check: 50.0%", PolicyBlock},
+	}
+	for _, tt := range tests {
+		cfg.Name = tt.array
+		reason, policy := cfg.Assess(statuses)
+		if reason != tt.wantReason || policy != tt.wantPolicy {
+			t.Errorf("%s: Assess = (%q, %q), want (%q, %q)", tt.array, reason, policy, tt.wantReason, tt.wantPolicy)
+		}
+	}
+}
+
+func TestChecker_Configs(t *testing.T) {
+	dir := t.TempDir()
+	host := filepath.Join(dir, "mdstat")
+	container := filepath.Join(dir, "container-mdstat")
+	os.WriteFile(host, []byte(`Personalities : [raid1]
+md0 : active raid1 sda[0] sdb[1]
+      3906886464 blocks super 1.2 [2/2] [UU]
+md1 : active raid1 sdc[0]
+      976630464 blocks super 1.2 [2/1] [U_]
+`), 0644)
+	os.WriteFile(container, []byte(`Personalities : [raid1]
+md5 : active raid1 sdd[0]
+      976630464 blocks super 1.2 [2/1] [U_]
+`), 0644)
+
+	configs, err := ParseArrays("md0,md1:warn,md5:mdstat=" + container)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker := &Checker{MdstatPath: host, Configs: configs}
+
+	err = checker.Check(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "md5 degraded") {
+		t.Errorf("err = %v, want md5 degraded", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "md1") {
+		t.Errorf("warn-only md1 blocked: %v", err)
+	}
+
+	checker.Configs[2].Degraded = PolicyWarn
+	if err := checker.Check(context.Background()); err != nil {
+		t.Errorf("unexpected error with only warn arrays degraded: %v", err)
+	}
+}
+
+func TestSnapshot_Remote(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "mdstat")
+	os.WriteFile(local, []byte(`Personalities : [raid1]
+md0 : active raid1 sda[0] sdb[1]
+      3906886464 blocks super 1.2 [2/2] [UU]
+`), 0644)
+	remote := filepath.Join(t.TempDir(), "mdstat")
+	os.WriteFile(remote, []byte(`Personalities : [raid1]
+md0 : active raid1 sdc[0]
+      976630464 blocks super 1.2 [2/1] [U_]
+`), 0644)
+
+	server := httptest.NewServer(Handler(remote))
+	defer server.Close()
+
+	configs, err := ParseArrays("md0,md0:mdstat=" + server.URL + "/mdstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if configs[1].MdstatPath != server.URL+"/mdstat" {
+		t.Fatalf("MdstatPath = %q, want %q", configs[1].MdstatPath, server.URL+"/mdstat")
+	}
+	if got := LocalNames(configs); !reflect.DeepEqual(got, []string{"md0"}) {
+		t.Errorf("LocalNames = %v, want [md0]", got)
+	}
+
+	checker := &Checker{MdstatPath: local, Configs: configs}
+	err = checker.Check(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "md0@127.0.0.1 degraded") {
+		t.Errorf("err = %v, want remote md0 degraded", err)
+	}
+
+	server.Close()
+	if err := checker.Check(context.Background()); err == nil || !strings.Contains(err.Error(), "from 127.0.0.1") {
+		t.Errorf("err = %v, want remote read failure", err)
+	}
+}
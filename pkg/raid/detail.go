@@ -0,0 +1,78 @@
+package raid
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// AddDetail fills in state that /proc/mdstat doesn't show from each array's
+// sysfs md/ directory under root ("" = DefaultSysfsRoot): the array state,
+// which tells arrays assembled read-only apart, and the mismatch count left
+// by the last check or repair. Arrays without a sysfs directory, e.g. ones
+// that were stopped since mdstat was read, are left as they are.
+func AddDetail(root string, statuses []Status) error {
+	if root == "" {
+		root = DefaultSysfsRoot
+	}
+	for i := range statuses {
+		if err := readDetail(filepath.Join(root, statuses[i].Name, "md"), &statuses[i]); err != nil {
+			return fmt.Errorf("%s: %w", statuses[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// AddLocalDetail runs AddDetail on the local sources in a Snapshot. Remote
+// sources only serve mdstat.
+func AddLocalDetail(root string, snap map[string][]Status) error {
+	for path, statuses := range snap {
+		if SourceLabel(path) != "" {
+			continue
+		}
+		if err := AddDetail(root, statuses); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readDetail(dir string, s *Status) error {
+	state, err := readAttr(dir, "array_state")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	s.ArrayState = state
+	// read-auto arrays switch to read-write on the first write, so only
+	// an explicit read-only assembly is a problem
+	if state == "readonly" {
+		s.ReadOnly = true
+	}
+
+	// Only redundant levels have a mismatch count
+	cnt, err := readAttr(dir, "mismatch_cnt")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if s.MismatchCount, err = strconv.ParseInt(cnt, 10, 64); err != nil {
+		return fmt.Errorf("parse mismatch_cnt: %w", err)
+	}
+	return nil
+}
+
+func readAttr(dir, attr string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, attr))
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", attr, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,94 @@
+package raid
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSysfs(t *testing.T, root, array string, attrs map[string]string) {
+	t.Helper()
+	writeAttrs(t, filepath.Join(root, array, "md"), attrs)
+}
+
+func writeAttrs(t *testing.T, dir string, attrs map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range attrs {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(value+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAddDetail(t *testing.T) {
+	root := t.TempDir()
+	writeSysfs(t, root, "md0", map[string]string{"array_state": "clean", "mismatch_cnt": "128"})
+	writeSysfs(t, root, "md1", map[string]string{"array_state": "readonly", "mismatch_cnt": "0"})
+	writeSysfs(t, root, "md2", map[string]string{"array_state": "read-auto"})
+
+	statuses := []Status{
+		{Name: "md0", Healthy: true},
+		{Name: "md1", Healthy: true},
+		{Name: "md2", Healthy: true},
+		{Name: "md3", Healthy: true}, // stopped since mdstat was read
+	}
+	if err := AddDetail(root, statuses); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if s := statuses[0]; s.ArrayState != "clean" || s.MismatchCount != 128 || s.ReadOnly || !s.Mismatched() {
+		t.Errorf("md0 = %+v, want clean with 128 mismatches", s)
+	}
+	if s := statuses[1]; !s.ReadOnly || s.Mismatched() {
+		t.Errorf("md1 = %+v, want read-only", s)
+	}
+	// Auto-read-only arrays are fine, and raid0 has no mismatch_cnt
+	if s := statuses[2]; s.ArrayState != "read-auto" || s.ReadOnly {
+		t.Errorf("md2 = %+v, want read-auto, not read-only", s)
+	}
+	if s := statuses[3]; s.ArrayState != "" {
+		t.Errorf("md3 = %+v, want no detail", s)
+	}
+
+	if healthy, reason := Evaluate(statuses, []string{"md0", "md1"}); healthy || reason != "md1 read-only" {
+		t.Errorf("Evaluate = %v, %q", healthy, reason)
+	}
+}
+
+func TestAddDetail_Invalid(t *testing.T) {
+	root := t.TempDir()
+	writeSysfs(t, root, "md0", map[string]string{"array_state": "clean", "mismatch_cnt": "lots"})
+
+	err := AddDetail(root, []Status{{Name: "md0"}})
+	if err == nil || !strings.Contains(err.Error(), "md0: parse mismatch_cnt") {
+		t.Errorf("err = %v, want mismatch_cnt parse error", err)
+	}
+}
+
+func TestParseMdstat_ReadOnly(t *testing.T) {
+	statuses, err := parseMdstatReader(strings.NewReader(`Personalities : [raid1]
+md0 : active (read-only) raid1 sdb1[1] sda1[0]
+      3906886464 blocks super 1.2 [2/2] [UU]
+
+md1 : active (auto-read-only) raid1 sdd1[1] sdc1[0]
+      976630464 blocks super 1.2 [2/2] [UU]
+
+unused devices: <none>
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("got %d arrays, want 2", len(statuses))
+	}
+	if s := statuses[0]; !s.ReadOnly || s.Level != "raid1" || len(s.Devices) != 2 {
+		t.Errorf("md0 = %+v, want read-only raid1", s)
+	}
+	if s := statuses[1]; s.ReadOnly || !s.Healthy {
+		t.Errorf("md1 = %+v, want healthy and writable", s)
+	}
+}
@@ -0,0 +1,135 @@
+package raid
+
+import (
+	"fmt"
+	"slices"
+)
+
+// EventKind identifies an array state transition
+type EventKind string
+
+const (
+	EventDegraded         EventKind = "degraded"
+	EventRebuildStarted   EventKind = "rebuild started"
+	EventRebuildCompleted EventKind = "rebuild completed"
+	EventRebuildStopped   EventKind = "rebuild stopped"
+	EventRecovered        EventKind = "healthy"
+	EventDeviceFaulty     EventKind = "device faulty"
+	EventMissing          EventKind = "missing"
+	// Sync events cover operations other than recovery: check, repair,
+	// resync and reshape
+	EventSyncStarted  EventKind = "sync started"
+	EventSyncFinished EventKind = "sync finished"
+	// Read-only and mismatch events need AddDetail
+	EventReadOnly EventKind = "read-only"
+	EventMismatch EventKind = "mismatches found"
+)
+
+// Event is a single array state transition
+type Event struct {
+	Array  string
+	Kind   EventKind
+	Detail string
+}
+
+func (e Event) String() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%s %s", e.Array, e.Kind)
+	}
+	return fmt.Sprintf("%s %s: %s", e.Array, e.Kind, e.Detail)
+}
+
+// Urgent reports whether the event means redundancy was lost.
+func (e Event) Urgent() bool {
+	switch e.Kind {
+	case EventDegraded, EventDeviceFaulty, EventMissing, EventRebuildStopped:
+		return true
+	}
+	return false
+}
+
+// Monitor turns successive mdstat snapshots into state transition events.
+// The first snapshot only sets the baseline.
+type Monitor struct {
+	prev map[string]Status
+}
+
+// Update records a snapshot and returns the transitions since the last one.
+func (m *Monitor) Update(statuses []Status) []Event {
+	cur := make(map[string]Status, len(statuses))
+	for _, s := range statuses {
+		cur[s.Name] = s
+	}
+
+	var events []Event
+	if m.prev != nil {
+		for _, s := range statuses {
+			if prev, ok := m.prev[s.Name]; ok {
+				events = append(events, diff(prev, s)...)
+			}
+		}
+		for name := range m.prev {
+			if _, ok := cur[name]; !ok {
+				events = append(events, Event{Array: name, Kind: EventMissing})
+			}
+		}
+	}
+
+	m.prev = cur
+	return events
+}
+
+func diff(prev, cur Status) []Event {
+	var events []Event
+	add := func(kind EventKind, detail string) {
+		events = append(events, Event{Array: cur.Name, Kind: kind, Detail: detail})
+	}
+
+	for _, dev := range cur.Faulty {
+		if !slices.Contains(prev.Faulty, dev) {
+			add(EventDeviceFaulty, dev)
+		}
+	}
+
+	if prev.Healthy && !cur.Healthy && !cur.Rebuilding {
+		add(EventDegraded, cur.DeviceList)
+	}
+
+	switch {
+	case !prev.Rebuilding && cur.Rebuilding:
+		add(EventRebuildStarted, cur.Progress)
+	case prev.Rebuilding && !cur.Rebuilding && cur.Healthy:
+		add(EventRebuildCompleted, cur.DeviceList)
+	case prev.Rebuilding && !cur.Rebuilding:
+		add(EventRebuildStopped, cur.DeviceList)
+	case !prev.Healthy && cur.Healthy:
+		add(EventRecovered, cur.DeviceList)
+	}
+
+	prevSync, curSync := nonRecoverySync(prev), nonRecoverySync(cur)
+	switch {
+	case curSync != "" && curSync != prevSync:
+		add(EventSyncStarted, fmt.Sprintf("%s %s", curSync, cur.Progress))
+	case prevSync != "" && curSync == "":
+		add(EventSyncFinished, prevSync)
+	}
+
+	if !prev.ReadOnly && cur.ReadOnly {
+		add(EventReadOnly, cur.ArrayState)
+	}
+	// A finished check leaves its count behind, which may match the last one
+	if cur.Mismatched() && (prev.Syncing() || prev.MismatchCount != cur.MismatchCount) {
+		add(EventMismatch, fmt.Sprintf("%d sectors", cur.MismatchCount))
+	}
+
+	return events
+}
+
+// nonRecoverySync returns the sync action unless it is a recovery, which
+// has its own rebuild events.
+func nonRecoverySync(s Status) string {
+	if s.SyncAction == "recovery" {
+		return ""
+	}
+	return s.SyncAction
+}
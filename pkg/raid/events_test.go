@@ -0,0 +1,185 @@
+package raid
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMonitor_Update(t *testing.T) {
+	healthy := Status{Name: "md0", DeviceList: "[UU]", Healthy: true}
+	degraded := Status{Name: "md0", DeviceList: "[U_]"}
+	faulty := Status{Name: "md0", DeviceList: "[U_]", Faulty: []string{"sdb1"}}
+	rebuilding := Status{Name: "md0", DeviceList: "[U_]", Rebuilding: true, SyncAction: "recovery", Progress: "1.2%"}
+	checking := Status{Name: "md0", DeviceList: "[UU]", Healthy: true, SyncAction: "check", Progress: "0.1%"}
+	mismatched := Status{Name: "md0", DeviceList: "[UU]", Healthy: true, MismatchCount: 256}
+	readOnly := Status{Name: "md0", DeviceList: "[UU]", Healthy: true, ReadOnly: true, ArrayState: "readonly"}
+
+	tests := []struct {
+		name  string
+		steps [][]Status
+		want  []Event // events from the last step
+	}{
+		{
+			name:  "baseline only",
+			steps: [][]Status{{degraded}},
+		},
+		{
+			name:  "no change",
+			steps: [][]Status{{healthy}, {healthy}},
+		},
+		{
+			name:  "healthy to degraded",
+			steps: [][]Status{{healthy}, {degraded}},
+			want:  []Event{{"md0", EventDegraded, "[U_]"}},
+		},
+		{
+			name:  "device marked faulty",
+			steps: [][]Status{{healthy}, {faulty}},
+			want: []Event{
+				{"md0", EventDeviceFaulty, "sdb1"},
+				{"md0", EventDegraded, "[U_]"},
+			},
+		},
+		{
+			name:  "faulty device reported once",
+			steps: [][]Status{{healthy}, {faulty}, {faulty}},
+		},
+		{
+			name:  "rebuild starts",
+			steps: [][]Status{{degraded}, {rebuilding}},
+			want:  []Event{{"md0", EventRebuildStarted, "1.2%"}},
+		},
+		{
+			name:  "rebuild completes",
+			steps: [][]Status{{rebuilding}, {healthy}},
+			want:  []Event{{"md0", EventRebuildCompleted, "[UU]"}},
+		},
+		{
+			name:  "rebuild stops",
+			steps: [][]Status{{rebuilding}, {degraded}},
+			want:  []Event{{"md0", EventRebuildStopped, "[U_]"}},
+		},
+		{
+			name:  "degraded to healthy",
+			steps: [][]Status{{degraded}, {healthy}},
+			want:  []Event{{"md0", EventRecovered, "[UU]"}},
+		},
+		{
+			name:  "check starts",
+			steps: [][]Status{{healthy}, {checking}},
+			want:  []Event{{"md0", EventSyncStarted, "check 0.1%"}},
+		},
+		{
+			name:  "check finishes",
+			steps: [][]Status{{checking}, {healthy}},
+			want:  []Event{{"md0", EventSyncFinished, "check"}},
+		},
+		{
+			name:  "check finds mismatches",
+			steps: [][]Status{{checking}, {mismatched}},
+			want: []Event{
+				{"md0", EventSyncFinished, "check"},
+				{"md0", EventMismatch, "256 sectors"},
+			},
+		},
+		{
+			name:  "mismatches reported once",
+			steps: [][]Status{{healthy}, {mismatched}, {mismatched}},
+		},
+		{
+			name:  "array goes read-only",
+			steps: [][]Status{{healthy}, {readOnly}},
+			want:  []Event{{"md0", EventReadOnly, "readonly"}},
+		},
+		{
+			name:  "array disappears",
+			steps: [][]Status{{healthy}, {}},
+			want:  []Event{{"md0", EventMissing, ""}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m Monitor
+			var got []Event
+			for _, step := range tt.steps {
+				got = m.Update(step)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMdstat_Faulty(t *testing.T) {
+	mdstatPath := filepath.Join(t.TempDir(), "mdstat")
+	content := `Personalities : [raid1]
+md0 : active raid1 sdb1[1](F) sda1[0]
+      3906886464 blocks super 1.2 [2/1] [U_]
+
+unused devices: <none>
+`
+	if err := os.WriteFile(mdstatPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp mdstat: %v", err)
+	}
+
+	statuses, err := ParseMdstat(mdstatPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(statuses) != 1 || !reflect.DeepEqual(statuses[0].Faulty, []string{"sdb1"}) {
+		t.Errorf("statuses = %+v, want md0 with sdb1 faulty", statuses)
+	}
+}
+
+func TestParseMdstat_DeviceFlags(t *testing.T) {
+	mdstatPath := filepath.Join(t.TempDir(), "mdstat")
+	content := `Personalities : [raid1] [raid5]
+md0 : active raid1 sdc1[2](S) sdb1[1](W) sda1[0]
+      3906886464 blocks super 1.2 [2/2] [UU]
+
+md1 : active raid5 sdf1[3](F) sde1[1] sdd1[0] sdg1[2]
+      7813771264 blocks super 1.2 level 5, 512k chunk, algorithm 2 [3/3] [UUU]
+
+unused devices: <none>
+`
+	if err := os.WriteFile(mdstatPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp mdstat: %v", err)
+	}
+
+	statuses, err := ParseMdstat(mdstatPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("got %d arrays, want 2", len(statuses))
+	}
+
+	md0 := statuses[0]
+	wantDevices := []DeviceStatus{
+		{Name: "sdc1", Slot: 2, Spare: true},
+		{Name: "sdb1", Slot: 1, WriteMostly: true},
+		{Name: "sda1", Slot: 0},
+	}
+	if !reflect.DeepEqual(md0.Devices, wantDevices) {
+		t.Errorf("md0 devices = %+v, want %+v", md0.Devices, wantDevices)
+	}
+	if !md0.Healthy || md0.Total != 2 || !reflect.DeepEqual(md0.Spares(), []string{"sdc1"}) {
+		t.Errorf("md0 = %+v, want healthy with spare sdc1", md0)
+	}
+
+	// [3/3] looks complete, but a member has failed
+	md1 := statuses[1]
+	if md1.Healthy {
+		t.Error("md1 healthy with a failed member")
+	}
+	if got := md1.DegradedDetail(); got != "[UUU], sdf1 failed" {
+		t.Errorf("DegradedDetail = %q", got)
+	}
+	if healthy, reason, _ := Check(mdstatPath, []string{"md0", "md1"}); healthy || reason != "md1 degraded: [UUU], sdf1 failed" {
+		t.Errorf("Check = %v, %q", healthy, reason)
+	}
+}
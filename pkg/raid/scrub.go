@@ -0,0 +1,197 @@
+package raid
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSysfsRoot is where md arrays expose their sysfs attributes
+const DefaultSysfsRoot = "/sys/block"
+
+// Window is a daily time-of-day range, e.g. 02:00-05:00.
+// End before Start wraps past midnight.
+type Window struct {
+	Start time.Duration // offset from midnight
+	End   time.Duration
+}
+
+// ParseWindow parses "HH:MM-HH:MM".
+func ParseWindow(s string) (Window, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return Window{}, fmt.Errorf("invalid window %q: want HH:MM-HH:MM", s)
+	}
+	start, err := parseClock(parts[0])
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid window %q: %w", s, err)
+	}
+	end, err := parseClock(parts[1])
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid window %q: %w", s, err)
+	}
+	return Window{Start: start, End: end}, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains reports whether t falls inside the window, using t's location.
+func (w Window) Contains(t time.Time) bool {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+	if w.Start <= w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// Scrubber starts periodic "check" scrubs on md arrays during a maintenance
+// window and tracks the scrubs it started, so the caller can hold an
+// inhibitor until they complete.
+type Scrubber struct {
+	Arrays    []string
+	Interval  time.Duration // minimum time between scrubs of an array
+	Window    Window
+	StatePath string // JSON file recording the last scrub of each array
+	SysfsRoot string // defaults to DefaultSysfsRoot
+
+	mu      sync.Mutex
+	started map[string]bool
+}
+
+// scrubState is persisted to StatePath
+type scrubState struct {
+	LastScrub map[string]time.Time `json:"last_scrub"`
+}
+
+// Run starts due scrubs if now is inside the window, and returns the
+// arrays with a scrub started by this Scrubber still in progress.
+func (s *Scrubber) Run(now time.Time) (running []string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started == nil {
+		s.started = make(map[string]bool)
+	}
+
+	// Forget scrubs that have finished
+	for name := range s.started {
+		action, err := s.syncAction(name)
+		if err != nil {
+			return nil, err
+		}
+		if action != "check" {
+			delete(s.started, name)
+		}
+	}
+
+	if s.Window.Contains(now) {
+		if err := s.startDue(now); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range s.Arrays {
+		if s.started[name] {
+			running = append(running, name)
+		}
+	}
+	return running, nil
+}
+
+func (s *Scrubber) startDue(now time.Time) error {
+	state, err := s.loadState()
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, name := range s.Arrays {
+		if last, ok := state.LastScrub[name]; ok && now.Sub(last) < s.Interval {
+			continue
+		}
+		action, err := s.syncAction(name)
+		if err != nil {
+			return err
+		}
+		if action != "idle" {
+			// Leave arrays alone while they resync or recover
+			continue
+		}
+		if err := s.writeSyncAction(name, "check"); err != nil {
+			return err
+		}
+		s.started[name] = true
+		state.LastScrub[name] = now
+		changed = true
+	}
+
+	if changed {
+		return s.saveState(state)
+	}
+	return nil
+}
+
+func (s *Scrubber) mdPath(name, attr string) string {
+	root := s.SysfsRoot
+	if root == "" {
+		root = DefaultSysfsRoot
+	}
+	return filepath.Join(root, name, "md", attr)
+}
+
+func (s *Scrubber) syncAction(name string) (string, error) {
+	data, err := os.ReadFile(s.mdPath(name, "sync_action"))
+	if err != nil {
+		return "", fmt.Errorf("read sync_action: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (s *Scrubber) writeSyncAction(name, action string) error {
+	if err := os.WriteFile(s.mdPath(name, "sync_action"), []byte(action), 0); err != nil {
+		return fmt.Errorf("start %s on %s: %w", action, name, err)
+	}
+	return nil
+}
+
+func (s *Scrubber) loadState() (scrubState, error) {
+	state := scrubState{LastScrub: make(map[string]time.Time)}
+	data, err := os.ReadFile(s.StatePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("read scrub state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("decode scrub state: %w", err)
+	}
+	if state.LastScrub == nil {
+		state.LastScrub = make(map[string]time.Time)
+	}
+	return state, nil
+}
+
+func (s *Scrubber) saveState(state scrubState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encode scrub state: %w", err)
+	}
+	tmp := s.StatePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write scrub state: %w", err)
+	}
+	return os.Rename(tmp, s.StatePath)
+}
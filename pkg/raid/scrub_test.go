@@ -0,0 +1,107 @@
+package raid
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWindow_Contains(t *testing.T) {
+	tests := []struct {
+		window string
+		clock  string
+		want   bool
+	}{
+		{"02:00-05:00", "03:30", true},
+		{"02:00-05:00", "05:00", false},
+		{"02:00-05:00", "01:59", false},
+		{"23:00-01:00", "23:30", true},
+		{"23:00-01:00", "00:30", true},
+		{"23:00-01:00", "12:00", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.window+" "+tt.clock, func(t *testing.T) {
+			w, err := ParseWindow(tt.window)
+			if err != nil {
+				t.Fatalf("ParseWindow: %v", err)
+			}
+			clock, _ := time.Parse("15:04", tt.clock)
+			now := time.Date(2024, 3, 1, clock.Hour(), clock.Minute(), 0, 0, time.UTC)
+			if got := w.Contains(now); got != tt.want {
+				t.Errorf("Contains(%s) = %v, want %v", tt.clock, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWindow_Invalid(t *testing.T) {
+	for _, s := range []string{"", "02:00", "2am-5am", "02:00-25:00"} {
+		if _, err := ParseWindow(s); err == nil {
+			t.Errorf("ParseWindow(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestScrubber_Run(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"md0", "md1"} {
+		if err := os.MkdirAll(filepath.Join(root, name, "md"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setAction := func(name, action string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, name, "md", "sync_action"), []byte(action+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	getAction := func(name string) string {
+		data, _ := os.ReadFile(filepath.Join(root, name, "md", "sync_action"))
+		return string(data)
+	}
+
+	setAction("md0", "idle")
+	setAction("md1", "recover") // busy, must not be touched
+
+	w, _ := ParseWindow("02:00-05:00")
+	s := &Scrubber{
+		Arrays:    []string{"md0", "md1"},
+		Interval:  30 * 24 * time.Hour,
+		Window:    w,
+		StatePath: filepath.Join(t.TempDir(), "scrub.json"),
+		SysfsRoot: root,
+	}
+
+	// Outside the window nothing starts
+	running, err := s.Run(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil || len(running) != 0 {
+		t.Fatalf("outside window: running = %v, err = %v", running, err)
+	}
+
+	inWindow := time.Date(2024, 3, 1, 3, 0, 0, 0, time.UTC)
+	running, err = s.Run(inWindow)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(running) != 1 || running[0] != "md0" {
+		t.Errorf("running = %v, want [md0]", running)
+	}
+	if getAction("md0") != "check" {
+		t.Errorf("md0 sync_action = %q, want check", getAction("md0"))
+	}
+	if getAction("md1") != "recover\n" {
+		t.Errorf("md1 was modified: %q", getAction("md1"))
+	}
+
+	// Scrub finishes; the next night is within the interval so nothing restarts
+	setAction("md0", "idle")
+	running, err = s.Run(inWindow.Add(24 * time.Hour))
+	if err != nil || len(running) != 0 {
+		t.Errorf("after completion: running = %v, err = %v", running, err)
+	}
+	if getAction("md0") != "idle\n" {
+		t.Errorf("md0 scrubbed again within interval")
+	}
+}
@@ -0,0 +1,125 @@
+package raid
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// SourceTimeout bounds reading a remote mdstat source
+var SourceTimeout = 10 * time.Second
+
+// ReadSource reads and parses mdstat from a source, which is one of:
+//
+//	/path/to/mdstat                     a local file
+//	/sys/block                          a directory, read with ReadSysfs
+//	http://node2:9101/mdstat            an HTTP endpoint (see Handler)
+//	ssh://[user@]node2[:port]/proc/mdstat  read over ssh in batch mode
+func ReadSource(ctx context.Context, source string) ([]Status, error) {
+	u, err := url.Parse(source)
+	if err != nil || u.Scheme == "" || u.Scheme == "file" {
+		if err == nil && u.Scheme == "file" {
+			source = u.Path
+		}
+		if info, err := os.Stat(source); err == nil && info.IsDir() {
+			return ReadSysfs(source)
+		}
+		return ParseMdstat(source)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, SourceTimeout)
+	defer cancel()
+
+	var data []byte
+	switch u.Scheme {
+	case "http", "https":
+		data, err = fetchHTTP(ctx, source)
+	case "ssh":
+		data, err = fetchSSH(ctx, u)
+	default:
+		return nil, fmt.Errorf("unsupported mdstat source %q", source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseMdstatReader(bytes.NewReader(data))
+}
+
+// SourceLabel returns the host of a remote source, to tell apart arrays of
+// the same name on different nodes. It is empty for local files.
+func SourceLabel(source string) string {
+	u, err := url.Parse(source)
+	if err != nil || u.Scheme == "" || u.Scheme == "file" {
+		return ""
+	}
+	return u.Hostname()
+}
+
+func fetchHTTP(ctx context.Context, source string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(resp.Body); err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func fetchSSH(ctx context.Context, u *url.URL) ([]byte, error) {
+	host := u.Hostname()
+	if u.User != nil {
+		host = u.User.Username() + "@" + host
+	}
+	path := u.Path
+	if path == "" || path == "/" {
+		path = DefaultMdstatPath
+	}
+
+	args := []string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=10"}
+	if port := u.Port(); port != "" {
+		args = append(args, "-p", port)
+	}
+	args = append(args, host, "cat", path)
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ssh", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ssh %s: %w: %s", host, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// Handler serves the local mdstat file so another node can use it as an
+// http:// source.
+func Handler(mdstatPath string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := os.ReadFile(mdstatPath)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(data)
+	})
+}
@@ -4,9 +4,12 @@ package raid
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Status represents the status of a RAID array
@@ -14,12 +17,77 @@ type Status struct {
 	Name       string
 	State      string // active, inactive, etc.
 	Level      string // raid1, raid5, etc.
-	Devices    int    // total devices
+	Total      int    // devices the array is built from
 	Active     int    // active devices
 	DeviceList string // e.g., "[UU]" or "[U_]"
-	Healthy    bool
-	Rebuilding bool
-	Progress   string // rebuild progress if applicable
+	Healthy    bool   // all members present, none failed, not recovering
+	Rebuilding bool   // recovering onto a replacement member
+	// SyncAction is the running sync operation: recovery, resync, check,
+	// repair or reshape; empty when idle
+	SyncAction string
+	Progress   string        // sync progress, e.g. "17.5%", or "delayed"/"pending"
+	Finish     time.Duration // estimated time until the sync completes
+	Faulty     []string      // member devices marked (F), e.g. "sdb1"
+	// Devices are the members listed on the array line, in mdstat order
+	Devices []DeviceStatus
+	// ReadOnly is set for arrays assembled read-only, e.g. with
+	// "mdadm --readonly"; auto-read-only arrays aren't
+	ReadOnly bool
+
+	// Set by AddDetail from sysfs, since mdstat doesn't show them
+	ArrayState    string // e.g. "clean", "active", "readonly", "read-auto"
+	MismatchCount int64  // sectors found inconsistent by the last check or repair
+}
+
+// DeviceStatus is one member device of an array, e.g. "sdb1[1](F)"
+type DeviceStatus struct {
+	Name        string // e.g. "sdb1"
+	Slot        int    // role number in brackets; from sysfs, -1 for spares
+	Faulty      bool   // (F): failed, no longer used
+	Spare       bool   // (S): spare, or not yet recovered into the array
+	WriteMostly bool   // (W): reads avoid it, e.g. a slow disk mirroring an SSD
+	Replacement bool   // (R): being recovered onto to replace another member
+	Journal     bool   // (J): write journal device
+}
+
+// Spares returns the names of spare members.
+func (s *Status) Spares() []string {
+	var names []string
+	for _, d := range s.Devices {
+		if d.Spare {
+			names = append(names, d.Name)
+		}
+	}
+	return names
+}
+
+// DegradedDetail describes what's wrong with an unhealthy array, e.g.
+// "[UU], sdb1 failed".
+func (s *Status) DegradedDetail() string {
+	if len(s.Faulty) > 0 {
+		return fmt.Sprintf("%s, %s failed", s.DeviceList, strings.Join(s.Faulty, ", "))
+	}
+	return s.DeviceList
+}
+
+// Mismatched reports whether the last finished check or repair found
+// inconsistent sectors. The count is reset when a sync starts, so it is
+// ignored while one runs.
+func (s *Status) Mismatched() bool {
+	return s.MismatchCount > 0 && !s.Syncing()
+}
+
+// Syncing reports whether any sync operation is running or queued.
+func (s *Status) Syncing() bool {
+	return s.SyncAction != ""
+}
+
+// SyncDetail describes the sync progress, e.g. "17.5%, 3h36m left".
+func (s *Status) SyncDetail() string {
+	if s.Finish > 0 {
+		return fmt.Sprintf("%s, %s left", s.Progress, s.Finish.Round(time.Minute))
+	}
+	return s.Progress
 }
 
 // DefaultMdstatPath is the default path to mdstat
@@ -31,9 +99,14 @@ func Check(mdstatPath string, expectedArrays []string) (healthy bool, reason str
 	if err != nil {
 		return false, "", fmt.Errorf("failed to read mdstat: %w", err)
 	}
+	healthy, reason = Evaluate(statuses, expectedArrays)
+	return healthy, reason, nil
+}
 
+// Evaluate checks parsed array statuses against the expected arrays.
+func Evaluate(statuses []Status, expectedArrays []string) (healthy bool, reason string) {
 	if len(statuses) == 0 {
-		return false, "no RAID arrays found", nil
+		return false, "no RAID arrays found"
 	}
 
 	// Check each expected array
@@ -42,16 +115,20 @@ func Check(mdstatPath string, expectedArrays []string) (healthy bool, reason str
 		for _, status := range statuses {
 			if status.Name == expected {
 				found = true
-				if !status.Healthy {
-					if status.Rebuilding {
-						return false, fmt.Sprintf("%s rebuilding: %s", status.Name, status.Progress), nil
-					}
-					return false, fmt.Sprintf("%s degraded: %s", status.Name, status.DeviceList), nil
+				switch {
+				case status.Rebuilding:
+					return false, fmt.Sprintf("%s rebuilding: %s", status.Name, status.SyncDetail())
+				case !status.Healthy:
+					return false, fmt.Sprintf("%s degraded: %s", status.Name, status.DegradedDetail())
+				case status.Syncing():
+					return false, fmt.Sprintf("%s %s: %s", status.Name, status.SyncAction, status.SyncDetail())
+				case status.ReadOnly:
+					return false, fmt.Sprintf("%s read-only", status.Name)
 				}
 			}
 		}
 		if !found {
-			return false, fmt.Sprintf("expected array %s not found", expected), nil
+			return false, fmt.Sprintf("expected array %s not found", expected)
 		}
 	}
 
@@ -60,7 +137,7 @@ func Check(mdstatPath string, expectedArrays []string) (healthy bool, reason str
 	for _, s := range statuses {
 		names = append(names, s.Name)
 	}
-	return true, fmt.Sprintf("all healthy: %s", strings.Join(names, ", ")), nil
+	return true, fmt.Sprintf("all healthy: %s", strings.Join(names, ", "))
 }
 
 // ParseMdstat parses /proc/mdstat and returns status for each array
@@ -74,14 +151,18 @@ func ParseMdstat(path string) ([]Status, error) {
 	return parseMdstatReader(file)
 }
 
-func parseMdstatReader(file *os.File) ([]Status, error) {
+func parseMdstatReader(r io.Reader) ([]Status, error) {
 	var statuses []Status
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 
 	// Regex patterns
-	arrayLine := regexp.MustCompile(`^(md\d+)\s*:\s*(\w+)\s+(\w+)\s+(.*)`)
+	// Read-only arrays show e.g. "active (auto-read-only) raid1"
+	arrayLine := regexp.MustCompile(`^(md\d+)\s*:\s*(\w+)\s+(?:\(([\w-]+)\)\s+)?(\w+)\s+(.*)`)
+	memberDevice := regexp.MustCompile(`(\S+)\[(\d+)\]((?:\([A-Z]\))*)`)
 	statusLine := regexp.MustCompile(`\[(\d+)/(\d+)\]\s*\[([U_]+)\]`)
-	recoveryLine := regexp.MustCompile(`recovery\s*=\s*([\d.]+%)`)
+	// Queued operations show as e.g. "resync=DELAYED" without progress
+	syncLine := regexp.MustCompile(`\b(recovery|resync|check|repair|reshape)\s*=\s*([\d.]+%|DELAYED|PENDING)`)
+	finishLine := regexp.MustCompile(`finish\s*=\s*([\d.]+)min`)
 
 	var current *Status
 
@@ -94,9 +175,25 @@ func parseMdstatReader(file *os.File) ([]Status, error) {
 				statuses = append(statuses, *current)
 			}
 			current = &Status{
-				Name:  matches[1],
-				State: matches[2],
-				Level: matches[3],
+				Name:     matches[1],
+				State:    matches[2],
+				Level:    matches[4],
+				ReadOnly: matches[3] == "read-only",
+			}
+			for _, m := range memberDevice.FindAllStringSubmatch(matches[5], -1) {
+				dev := DeviceStatus{
+					Name:        m[1],
+					Slot:        mustAtoi(m[2]),
+					Faulty:      strings.Contains(m[3], "(F)"),
+					Spare:       strings.Contains(m[3], "(S)"),
+					WriteMostly: strings.Contains(m[3], "(W)"),
+					Replacement: strings.Contains(m[3], "(R)"),
+					Journal:     strings.Contains(m[3], "(J)"),
+				}
+				current.Devices = append(current.Devices, dev)
+				if dev.Faulty {
+					current.Faulty = append(current.Faulty, dev.Name)
+				}
 			}
 			continue
 		}
@@ -107,17 +204,28 @@ func parseMdstatReader(file *os.File) ([]Status, error) {
 
 		// Check for status line with [UU] pattern
 		if matches := statusLine.FindStringSubmatch(line); matches != nil {
-			current.Devices = mustAtoi(matches[1])
+			current.Total = mustAtoi(matches[1])
 			current.Active = mustAtoi(matches[2])
 			current.DeviceList = "[" + matches[3] + "]"
-			current.Healthy = !strings.Contains(matches[3], "_")
+			// A failed member can still show as [UU] until md notices,
+			// e.g. when it fails while the array is idle
+			current.Healthy = !strings.Contains(matches[3], "_") && len(current.Faulty) == 0
 		}
 
-		// Check for recovery progress
-		if matches := recoveryLine.FindStringSubmatch(line); matches != nil {
-			current.Rebuilding = true
-			current.Progress = matches[1]
-			current.Healthy = false
+		// Check for sync progress
+		if matches := syncLine.FindStringSubmatch(line); matches != nil {
+			current.SyncAction = matches[1]
+			current.Progress = strings.ToLower(matches[2])
+			if current.SyncAction == "recovery" {
+				current.Rebuilding = true
+				current.Healthy = false
+			}
+
+			if finish := finishLine.FindStringSubmatch(line); finish != nil {
+				if mins, err := strconv.ParseFloat(finish[1], 64); err == nil {
+					current.Finish = time.Duration(mins * float64(time.Minute))
+				}
+			}
 		}
 	}
 
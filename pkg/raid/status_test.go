@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestCheck(t *testing.T) {
@@ -182,6 +183,125 @@ unused devices: <none>
 	}
 }
 
+func TestParseMdstat_Finish(t *testing.T) {
+	mdstatPath := filepath.Join(t.TempDir(), "mdstat")
+	content := `Personalities : [raid1]
+md0 : active raid1 sda[0] sdb[1]
+      3906886464 blocks super 1.2 [2/1] [U_]
+      [===>.................]  recovery = 17.5% (683954048/3906886464) finish=215.5min speed=250000K/sec
+
+unused devices: <none>
+`
+	if err := os.WriteFile(mdstatPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp mdstat: %v", err)
+	}
+
+	statuses, err := ParseMdstat(mdstatPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(statuses) != 1 {
+		t.Fatalf("got %d arrays, want 1", len(statuses))
+	}
+	if want := 215*time.Minute + 30*time.Second; statuses[0].Finish != want {
+		t.Errorf("Finish = %v, want %v", statuses[0].Finish, want)
+	}
+}
+
+func TestParseMdstat_SyncActions(t *testing.T) {
+	tests := []struct {
+		name         string
+		line         string
+		devices      string
+		wantAction   string
+		wantProgress string
+		wantFinish   time.Duration
+		wantHealthy  bool
+	}{
+		{
+			name:         "check scrub",
+			line:         "[=>...................]  check =  8.9% (348172800/3906886464) finish=290.1min speed=204446K/sec",
+			devices:      "[2/2] [UU]",
+			wantAction:   "check",
+			wantProgress: "8.9%",
+			wantFinish:   290*time.Minute + 6*time.Second,
+			wantHealthy:  true,
+		},
+		{
+			name:         "repair",
+			line:         "[>....................]  repair =  0.3% (11806720/3906886464) finish=330.0min speed=196778K/sec",
+			devices:      "[2/2] [UU]",
+			wantAction:   "repair",
+			wantProgress: "0.3%",
+			wantFinish:   330 * time.Minute,
+			wantHealthy:  true,
+		},
+		{
+			name:         "resync after unclean shutdown",
+			line:         "[====>................]  resync = 21.0% (820446208/3906886464) finish=251.2min speed=204722K/sec",
+			devices:      "[2/2] [UU]",
+			wantAction:   "resync",
+			wantProgress: "21.0%",
+			wantFinish:   251*time.Minute + 12*time.Second,
+			wantHealthy:  true,
+		},
+		{
+			name:         "resync queued",
+			line:         "  resync=DELAYED",
+			devices:      "[2/2] [UU]",
+			wantAction:   "resync",
+			wantProgress: "delayed",
+			wantHealthy:  true,
+		},
+		{
+			name:         "reshape",
+			line:         "[=====>...............]  reshape = 27.4% (1070506240/3906886464) finish=1480.6min speed=31925K/sec",
+			devices:      "[3/3] [UUU]",
+			wantAction:   "reshape",
+			wantProgress: "27.4%",
+			wantFinish:   1480*time.Minute + 36*time.Second,
+			wantHealthy:  true,
+		},
+		{
+			name:         "recovery",
+			line:         "[===>.................]  recovery = 17.5% (683954048/3906886464) finish=215.0min speed=250000K/sec",
+			devices:      "[2/1] [U_]",
+			wantAction:   "recovery",
+			wantProgress: "17.5%",
+			wantFinish:   215 * time.Minute,
+			wantHealthy:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "Personalities : [raid1]\nmd0 : active raid1 sda[0] sdb[1]\n" +
+				"      3906886464 blocks super 1.2 " + tt.devices + "\n" +
+				"      " + tt.line + "\n\nunused devices: <none>\n"
+			mdstatPath := filepath.Join(t.TempDir(), "mdstat")
+			if err := os.WriteFile(mdstatPath, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to write temp mdstat: %v", err)
+			}
+
+			statuses, err := ParseMdstat(mdstatPath)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			s := statuses[0]
+			if s.SyncAction != tt.wantAction || s.Progress != tt.wantProgress || s.Finish != tt.wantFinish || s.Healthy != tt.wantHealthy {
+				t.Errorf("got action %q, progress %q, finish %v, healthy %v; want %q, %q, %v, %v",
+					s.SyncAction, s.Progress, s.Finish, s.Healthy, tt.wantAction, tt.wantProgress, tt.wantFinish, tt.wantHealthy)
+			}
+
+			// Every sync operation holds up the legacy Check
+			healthy, reason, _ := Check(mdstatPath, []string{"md0"})
+			if healthy || !contains(reason, tt.wantProgress) {
+				t.Errorf("Check = %v, %q", healthy, reason)
+			}
+		})
+	}
+}
+
 func TestCheck_FileNotFound(t *testing.T) {
 	_, _, err := Check("/nonexistent/path/mdstat", []string{"md0"})
 	if err == nil {
@@ -0,0 +1,211 @@
+package raid
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var mdName = regexp.MustCompile(`^md\d+$`)
+
+// ReadSysfs reads the status of every assembled array from the md/
+// attributes under root ("" = DefaultSysfsRoot), as an alternative to
+// parsing mdstat. Unlike mdstat it gives the exact sync position and speed,
+// so Finish isn't rounded to a tenth of a minute, and it includes the state
+// AddDetail adds.
+func ReadSysfs(root string) ([]Status, error) {
+	if root == "" {
+		root = DefaultSysfsRoot
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, e := range entries {
+		if !mdName.MatchString(e.Name()) {
+			continue
+		}
+		dir := filepath.Join(root, e.Name(), "md")
+		if _, err := os.Stat(dir); err != nil {
+			// Device node without an array behind it
+			continue
+		}
+		s, err := readSysfsArray(e.Name(), dir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		if s.ArrayState == "clear" {
+			continue
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+func readSysfsArray(name, dir string) (Status, error) {
+	s := Status{Name: name, State: "active"}
+	if err := readDetail(dir, &s); err != nil {
+		return s, err
+	}
+	switch s.ArrayState {
+	case "clear":
+		// Stopped; ReadSysfs skips it
+		return s, nil
+	case "inactive":
+		s.State = "inactive"
+	}
+
+	var err error
+	if s.Level, err = readAttr(dir, "level"); err != nil {
+		return s, err
+	}
+	if s.Total, err = readIntAttr(dir, "raid_disks"); err != nil {
+		return s, err
+	}
+	// Levels without redundancy have no degraded count
+	degraded, err := readIntAttr(dir, "degraded")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return s, err
+	}
+	s.Active = s.Total - degraded
+
+	inSync := make([]bool, s.Total)
+	members, err := filepath.Glob(filepath.Join(dir, "dev-*"))
+	if err != nil {
+		return s, err
+	}
+	for _, m := range members {
+		dev, synced, err := readSysfsMember(m)
+		if err != nil {
+			return s, fmt.Errorf("%s: %w", filepath.Base(m), err)
+		}
+		s.Devices = append(s.Devices, dev)
+		if dev.Faulty {
+			s.Faulty = append(s.Faulty, dev.Name)
+		}
+		if synced && dev.Slot >= 0 && dev.Slot < s.Total {
+			inSync[dev.Slot] = true
+		}
+	}
+
+	var list strings.Builder
+	for _, ok := range inSync {
+		if ok {
+			list.WriteByte('U')
+		} else {
+			list.WriteByte('_')
+		}
+	}
+	s.DeviceList = "[" + list.String() + "]"
+	s.Healthy = degraded == 0 && len(s.Faulty) == 0 && !strings.Contains(list.String(), "_")
+
+	if err := readSysfsSync(dir, &s); err != nil {
+		return s, err
+	}
+	if s.SyncAction == "recovery" {
+		s.Rebuilding = true
+		s.Healthy = false
+	}
+	return s, nil
+}
+
+// readSysfsMember reads a dev-* directory, and whether the member is in
+// sync with the array.
+func readSysfsMember(dir string) (DeviceStatus, bool, error) {
+	dev := DeviceStatus{Name: strings.TrimPrefix(filepath.Base(dir), "dev-"), Slot: -1}
+
+	state, err := readAttr(dir, "state")
+	if err != nil {
+		return dev, false, err
+	}
+	flags := strings.Split(state, ",")
+	dev.Faulty = slices.Contains(flags, "faulty")
+	dev.WriteMostly = slices.Contains(flags, "write_mostly")
+	dev.Replacement = slices.Contains(flags, "replacement")
+	dev.Journal = slices.Contains(flags, "journal")
+
+	slot, err := readAttr(dir, "slot")
+	if err != nil {
+		return dev, false, err
+	}
+	if slot != "none" {
+		if dev.Slot, err = strconv.Atoi(slot); err != nil {
+			return dev, false, fmt.Errorf("parse slot: %w", err)
+		}
+	}
+	// A member being recovered onto has a slot, but mdstat only marks
+	// members without one as spares
+	dev.Spare = slices.Contains(flags, "spare") && dev.Slot < 0
+
+	return dev, slices.Contains(flags, "in_sync"), nil
+}
+
+// readSysfsSync fills in the running sync operation, its progress and the
+// time left at the current speed.
+func readSysfsSync(dir string, s *Status) error {
+	action, err := readAttr(dir, "sync_action")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	switch action {
+	case "recover":
+		s.SyncAction = "recovery"
+	case "resync", "check", "repair", "reshape":
+		s.SyncAction = action
+	default:
+		// idle or frozen
+		return nil
+	}
+
+	// "done / total" in sectors, or "delayed" or "pending" while queued
+	completed, err := readAttr(dir, "sync_completed")
+	if err != nil {
+		return err
+	}
+	doneStr, totalStr, ok := strings.Cut(completed, " / ")
+	if !ok {
+		s.Progress = completed
+		return nil
+	}
+	done, err1 := strconv.ParseInt(doneStr, 10, 64)
+	total, err2 := strconv.ParseInt(totalStr, 10, 64)
+	if err := errors.Join(err1, err2); err != nil {
+		return fmt.Errorf("parse sync_completed: %w", err)
+	}
+	if total <= 0 {
+		return nil
+	}
+	s.Progress = fmt.Sprintf("%.1f%%", float64(done)*100/float64(total))
+
+	// Current speed in KiB/s; "none" before the first window is measured
+	speed, err := readIntAttr(dir, "sync_speed")
+	if err != nil || speed <= 0 {
+		return nil
+	}
+	sectorsLeft := total - done
+	s.Finish = time.Duration(float64(sectorsLeft*512) / float64(speed*1024) * float64(time.Second))
+	return nil
+}
+
+func readIntAttr(dir, attr string) (int, error) {
+	v, err := readAttr(dir, attr)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", attr, err)
+	}
+	return n, nil
+}
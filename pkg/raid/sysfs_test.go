@@ -0,0 +1,90 @@
+package raid
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadSysfs(t *testing.T) {
+	root := t.TempDir()
+
+	// Healthy mirror with a spare
+	writeSysfs(t, root, "md0", map[string]string{
+		"array_state": "clean", "level": "raid1", "raid_disks": "2", "degraded": "0",
+		"sync_action": "idle", "mismatch_cnt": "0",
+	})
+	writeAttrs(t, filepath.Join(root, "md0/md/dev-sda1"), map[string]string{"state": "in_sync", "slot": "0"})
+	writeAttrs(t, filepath.Join(root, "md0/md/dev-sdb1"), map[string]string{"state": "in_sync,write_mostly", "slot": "1"})
+	writeAttrs(t, filepath.Join(root, "md0/md/dev-sdc1"), map[string]string{"state": "spare", "slot": "none"})
+
+	// RAID5 recovering onto sdf1, 1 TiB of 4 TiB left at 256 MiB/s
+	writeSysfs(t, root, "md1", map[string]string{
+		"array_state": "active", "level": "raid5", "raid_disks": "3", "degraded": "1",
+		"sync_action": "recover", "sync_completed": "6442450944 / 8589934592", "sync_speed": "262144",
+		"mismatch_cnt": "0",
+	})
+	writeAttrs(t, filepath.Join(root, "md1/md/dev-sdd1"), map[string]string{"state": "in_sync", "slot": "0"})
+	writeAttrs(t, filepath.Join(root, "md1/md/dev-sde1"), map[string]string{"state": "in_sync", "slot": "1"})
+	writeAttrs(t, filepath.Join(root, "md1/md/dev-sdf1"), map[string]string{"state": "spare", "slot": "2"})
+
+	// Check queued behind the recovery
+	writeSysfs(t, root, "md2", map[string]string{
+		"array_state": "clean", "level": "raid1", "raid_disks": "2", "degraded": "0",
+		"sync_action": "check", "sync_completed": "delayed", "sync_speed": "none",
+	})
+	writeAttrs(t, filepath.Join(root, "md2/md/dev-sdg1"), map[string]string{"state": "in_sync", "slot": "0"})
+	writeAttrs(t, filepath.Join(root, "md2/md/dev-sdh1"), map[string]string{"state": "faulty", "slot": "1"})
+
+	// Stopped array and a non-md device are skipped
+	writeSysfs(t, root, "md3", map[string]string{"array_state": "clear"})
+	writeAttrs(t, filepath.Join(root, "sda"), map[string]string{"size": "1000"})
+
+	statuses, err := ReadSysfs(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("got %d arrays, want 3: %+v", len(statuses), statuses)
+	}
+
+	md0 := statuses[0]
+	wantDevices := []DeviceStatus{
+		{Name: "sda1", Slot: 0},
+		{Name: "sdb1", Slot: 1, WriteMostly: true},
+		{Name: "sdc1", Slot: -1, Spare: true},
+	}
+	if !reflect.DeepEqual(md0.Devices, wantDevices) {
+		t.Errorf("md0 devices = %+v, want %+v", md0.Devices, wantDevices)
+	}
+	if !md0.Healthy || md0.Syncing() || md0.DeviceList != "[UU]" || md0.Level != "raid1" {
+		t.Errorf("md0 = %+v, want healthy idle raid1", md0)
+	}
+
+	md1 := statuses[1]
+	if !md1.Rebuilding || md1.Healthy || md1.Active != 2 || md1.DeviceList != "[UU_]" {
+		t.Errorf("md1 = %+v, want rebuilding [UU_]", md1)
+	}
+	if md1.Progress != "75.0%" || md1.Finish != 4096*time.Second {
+		t.Errorf("md1 progress = %s, finish = %s, want 75.0%%, 1h8m16s", md1.Progress, md1.Finish)
+	}
+	if md1.Devices[2].Spare {
+		t.Error("md1: member being recovered onto marked spare")
+	}
+
+	md2 := statuses[2]
+	if md2.SyncAction != "check" || md2.Progress != "delayed" || md2.Finish != 0 {
+		t.Errorf("md2 = %+v, want delayed check", md2)
+	}
+	if md2.Healthy || !reflect.DeepEqual(md2.Faulty, []string{"sdh1"}) || md2.DegradedDetail() != "[U_], sdh1 failed" {
+		t.Errorf("md2 = %+v, want sdh1 failed", md2)
+	}
+
+	// A directory source reads sysfs
+	fromSource, err := ReadSource(context.Background(), root)
+	if err != nil || !reflect.DeepEqual(fromSource, statuses) {
+		t.Errorf("ReadSource = %+v, %v, want the sysfs statuses", fromSource, err)
+	}
+}
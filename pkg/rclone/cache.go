@@ -0,0 +1,62 @@
+package rclone
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// cacheItem is the part of a VFS cache metadata file that matters here
+type cacheItem struct {
+	Dirty bool `json:"Dirty"`
+}
+
+// DirtyFiles returns the files in an rclone cache directory (--cache-dir,
+// by default ~/.cache/rclone) that a mount has written locally but not yet
+// uploaded, as "remote/path" sorted by name. A missing directory has none.
+//
+// rclone keeps a JSON metadata file per cached file under vfsMeta, with
+// Dirty set until the upload completes.
+func DirtyFiles(cacheDir string) ([]string, error) {
+	metaDir := filepath.Join(cacheDir, "vfsMeta")
+
+	var dirty []string
+	err := filepath.WalkDir(metaDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == metaDir && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var item cacheItem
+		if err := json.Unmarshal(data, &item); err != nil {
+			// Being rewritten; the next check will see it
+			return nil
+		}
+		if item.Dirty {
+			rel, err := filepath.Rel(metaDir, path)
+			if err != nil {
+				return err
+			}
+			dirty = append(dirty, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan vfs cache: %w", err)
+	}
+	sort.Strings(dirty)
+	return dirty, nil
+}
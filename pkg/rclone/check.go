@@ -0,0 +1,135 @@
+package rclone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/procscan"
+)
+
+// Checker implements check.Checker for rclone.
+// Returns an error while a sync, bisync, copy or move job is running, or a
+// mount still has files to upload, so a reboot doesn't leave files half
+// uploaded to the remote.
+//
+// Mounts are checked through their remote control API if Clients are set,
+// and by reading their cache directories otherwise. Cache directories are
+// only read while a mount is running, since nothing will upload their dirty
+// files until it starts again.
+type Checker struct {
+	Detector *Detector
+	// Clients are the remote control APIs of the mounts
+	Clients []*Client
+	// CacheDirs are the mounts' --cache-dir directories
+	CacheDirs []string
+	// MountPattern matches mount command lines; nil uses DefaultMountPattern
+	MountPattern *regexp.Regexp
+}
+
+// NewChecker creates an rclone checker.
+func NewChecker(d *Detector, clients []*Client, cacheDirs []string) *Checker {
+	return &Checker{Detector: d, Clients: clients, CacheDirs: cacheDirs}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "rclone"
+}
+
+// Check returns nil if nothing is being transferred, error otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	reasons, err := c.Activity(ctx)
+	if err != nil {
+		return fmt.Errorf("rclone check failed: %w", err)
+	}
+	if len(reasons) > 0 {
+		return errors.New(strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// Activity returns a description of each transfer in progress. Mounts whose
+// remote control API is unreachable are skipped, as they aren't running;
+// rejected credentials are returned as an error.
+func (c *Checker) Activity(ctx context.Context) ([]string, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	active, err := c.Detector.Active()
+	if err != nil {
+		return nil, err
+	}
+	var reasons []string
+	for _, a := range active {
+		reasons = append(reasons, "transfer "+a)
+	}
+
+	for _, client := range c.Clients {
+		stats, err := client.VFSStats(ctx)
+		if IsUnauthorized(err) {
+			return nil, fmt.Errorf("cannot query rclone: %w", err)
+		}
+		if err != nil {
+			continue
+		}
+		for _, s := range stats {
+			if s.Uploading > 0 || s.Queued > 0 {
+				reasons = append(reasons, fmt.Sprintf("%s: %d uploading, %d queued", s.Fs, s.Uploading, s.Queued))
+			}
+		}
+	}
+
+	if len(c.CacheDirs) > 0 {
+		mounted, err := c.mountRunning()
+		if err != nil {
+			return nil, err
+		}
+		if mounted {
+			for _, dir := range c.CacheDirs {
+				dirty, err := DirtyFiles(dir)
+				if err != nil {
+					return nil, err
+				}
+				if len(dirty) > 0 {
+					reasons = append(reasons, fmt.Sprintf("%d file(s) not yet uploaded from %s: %s",
+						len(dirty), dir, summarize(dirty, 3)))
+				}
+			}
+		}
+	}
+
+	return reasons, nil
+}
+
+func (c *Checker) mountRunning() (bool, error) {
+	pattern := c.MountPattern
+	if pattern == nil {
+		pattern = regexp.MustCompile(DefaultMountPattern)
+	}
+	procs, err := procscan.Find(c.Detector.ProcRoot, pattern)
+	if err != nil {
+		return false, fmt.Errorf("scan processes: %w", err)
+	}
+	return len(procs) > 0, nil
+}
+
+// summarize lists the first n names, noting how many more there are.
+func summarize(names []string, n int) string {
+	if len(names) <= n {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:n], ", "), len(names)-n)
+}
+
+// IsUnauthorized reports whether err is rclone rejecting the --rc-user and
+// --rc-pass, as opposed to the mount being unreachable.
+func IsUnauthorized(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && (apiErr.StatusCode == 401 || apiErr.StatusCode == 403)
+}
@@ -0,0 +1,46 @@
+// Package rclone detects rclone transfers in progress: sync, bisync, copy
+// and move jobs, and files a mount has written to its VFS cache but not yet
+// uploaded.
+package rclone
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/procscan"
+)
+
+// DefaultProcessPattern matches rclone commands that transfer files. Mounts
+// and serves are left to the cache and remote control checks, since they
+// run all the time.
+const DefaultProcessPattern = `(^|/)rclone\s(.*\s)?(sync|bisync|copy|copyto|move|moveto)(\s|$)`
+
+// DefaultMountPattern matches rclone processes that keep a VFS cache.
+const DefaultMountPattern = `(^|/)rclone\s(.*\s)?(mount|nfsmount|serve)(\s|$)`
+
+// Detector looks for rclone transfer jobs in progress
+type Detector struct {
+	// ProcessPattern matches transfer command lines
+	ProcessPattern *regexp.Regexp
+	// ProcRoot defaults to /proc
+	ProcRoot string
+}
+
+// Active returns a description of each transfer process running.
+func (d *Detector) Active() ([]string, error) {
+	pattern := d.ProcessPattern
+	if pattern == nil {
+		pattern = regexp.MustCompile(DefaultProcessPattern)
+	}
+
+	procs, err := procscan.Find(d.ProcRoot, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("scan processes: %w", err)
+	}
+
+	var active []string
+	for _, p := range procs {
+		active = append(active, fmt.Sprintf("pid %d: %s", p.PID, p.Cmdline))
+	}
+	return active, nil
+}
@@ -0,0 +1,140 @@
+package rclone
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultProcessPattern(t *testing.T) {
+	pattern := regexp.MustCompile(DefaultProcessPattern)
+
+	tests := []struct {
+		cmdline string
+		want    bool
+	}{
+		{"/usr/bin/rclone sync /srv/photos b2:photos", true},
+		{"rclone --config /etc/rclone.conf bisync /srv/docs drive:docs", true},
+		{"rclone move --delete-empty-src-dirs /srv/drop b2:archive", true},
+		{"rclone copyto /srv/db.dump b2:db/db.dump", true},
+		{"/usr/bin/rclone mount drive: /mnt/drive --vfs-cache-mode writes", false},
+		{"rclone serve webdav b2:share", false},
+		{"rclone ls b2:photos", false},
+	}
+
+	for _, tt := range tests {
+		if got := pattern.MatchString(tt.cmdline); got != tt.want {
+			t.Errorf("MatchString(%q) = %v, want %v", tt.cmdline, got, tt.want)
+		}
+	}
+}
+
+func writeMeta(t *testing.T, cacheDir, name string, dirty bool) {
+	t.Helper()
+	path := filepath.Join(cacheDir, "vfsMeta", name)
+	os.MkdirAll(filepath.Dir(path), 0755)
+	data, _ := json.Marshal(map[string]any{"ModTime": "2024-03-01T12:00:00Z", "Size": 1024, "Dirty": dirty})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirtyFiles(t *testing.T) {
+	cacheDir := t.TempDir()
+	if dirty, err := DirtyFiles(cacheDir); err != nil || len(dirty) != 0 {
+		t.Fatalf("empty cache: dirty = %v, err = %v", dirty, err)
+	}
+
+	writeMeta(t, cacheDir, "drive/docs/report.odt", true)
+	writeMeta(t, cacheDir, "drive/docs/old.odt", false)
+	writeMeta(t, cacheDir, "b2/photos/img.jpg", true)
+
+	dirty, err := DirtyFiles(cacheDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(dirty, ","); got != "b2/photos/img.jpg,drive/docs/report.odt" {
+		t.Errorf("DirtyFiles = %v", dirty)
+	}
+}
+
+func TestChecker_Activity(t *testing.T) {
+	root := t.TempDir()
+	cacheDir := t.TempDir()
+	writeMeta(t, cacheDir, "drive/docs/report.odt", true)
+
+	var uploading int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "rc" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "unauthorized", "status": 401}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/vfs/list":
+			w.Write([]byte(`{"vfses": ["drive:", "b2:"]}`))
+		case "/vfs/stats":
+			var params map[string]string
+			json.NewDecoder(r.Body).Decode(&params)
+			if params["fs"] == "b2:" {
+				// Mounted without a cache
+				w.Write([]byte(`{"fs": "b2:", "inUse": 1}`))
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"fs":        "drive:",
+				"diskCache": map[string]int{"uploadsInProgress": uploading, "uploadsQueued": 2},
+			})
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	os.MkdirAll(filepath.Join(root, "100"), 0755)
+	os.WriteFile(filepath.Join(root, "100", "cmdline"), []byte("/usr/bin/rclone\x00ls\x00b2:photos\x00"), 0644)
+
+	// Dirty files don't count until a mount is running to upload them
+	checker := NewChecker(&Detector{ProcRoot: root}, nil, []string{cacheDir})
+	if reasons, err := checker.Activity(context.Background()); err != nil || len(reasons) != 0 {
+		t.Fatalf("no mount: reasons = %v, err = %v", reasons, err)
+	}
+
+	os.MkdirAll(filepath.Join(root, "200"), 0755)
+	os.WriteFile(filepath.Join(root, "200", "cmdline"), []byte("rclone\x00mount\x00drive:\x00/mnt/drive\x00"), 0644)
+	os.MkdirAll(filepath.Join(root, "300"), 0755)
+	os.WriteFile(filepath.Join(root, "300", "cmdline"), []byte("rclone\x00bisync\x00/srv/docs\x00drive:docs\x00"), 0644)
+
+	uploading = 1
+	client := NewClient(server.URL, "rc", "secret", 5*time.Second)
+	checker = NewChecker(&Detector{ProcRoot: root}, []*Client{client}, []string{cacheDir})
+	reasons, err := checker.Activity(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		"transfer pid 300: rclone bisync /srv/docs drive:docs",
+		"drive:: 1 uploading, 2 queued",
+		"1 file(s) not yet uploaded from " + cacheDir + ": drive/docs/report.odt",
+	}
+	if strings.Join(reasons, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Activity =\n%s\nwant\n%s", strings.Join(reasons, "\n"), strings.Join(want, "\n"))
+	}
+
+	bad := NewChecker(&Detector{ProcRoot: root}, []*Client{NewClient(server.URL, "rc", "wrong", 5*time.Second)}, nil)
+	if _, err := bad.Activity(context.Background()); !IsUnauthorized(err) {
+		t.Errorf("wrong password: err = %v, want unauthorized", err)
+	}
+
+	down := NewChecker(&Detector{ProcRoot: t.TempDir()}, []*Client{NewClient("http://127.0.0.1:1", "", "", time.Second)}, nil)
+	if err := down.Check(context.Background()); err != nil {
+		t.Errorf("Check = %v, want nil when the mount is down", err)
+	}
+}
@@ -0,0 +1,46 @@
+// Package readiness delays the systemd READY=1 notification until the
+// sidecar's checker has completed a successful probe, so units ordered
+// After= the sidecar wait for a verified state rather than process start.
+package readiness
+
+import (
+	"context"
+	"sync"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/coreos/go-systemd/v22/daemon"
+)
+
+// notifyFunc sends a state string to systemd. Replaced in tests.
+var notifyFunc = func(state string) (bool, error) {
+	return daemon.SdNotify(false, state)
+}
+
+// gatedChecker sends READY=1 after the first error-free check
+type gatedChecker struct {
+	sidecar.Checker
+
+	once sync.Once
+}
+
+// Gate wraps checker so READY=1 is sent once Check first returns without
+// error. Use it with sidecar.Options.NotifyReady set to false.
+func Gate(checker sidecar.Checker) sidecar.Checker {
+	return &gatedChecker{Checker: checker}
+}
+
+// Check runs the wrapped checker and signals readiness on first success.
+func (c *gatedChecker) Check(ctx context.Context) (bool, string, error) {
+	busy, reason, err := c.Checker.Check(ctx)
+	if err == nil {
+		c.once.Do(func() {
+			if _, nerr := notifyFunc(daemon.SdNotifyReady); nerr != nil {
+				logging.Warnf("failed to send READY: %v", nerr)
+				return
+			}
+			logging.Infof("First check of %s succeeded, sent READY", c.Name())
+		})
+	}
+	return busy, reason, err
+}
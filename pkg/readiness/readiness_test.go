@@ -0,0 +1,35 @@
+package readiness
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+)
+
+func TestGate(t *testing.T) {
+	var sent []string
+	notifyFunc = func(state string) (bool, error) {
+		sent = append(sent, state)
+		return true, nil
+	}
+
+	var checkErr error
+	checker := Gate(sidecar.NewCheckerFunc("raid", func(ctx context.Context) (bool, string, error) {
+		return false, "", checkErr
+	}))
+
+	checkErr = errors.New("mdstat unreadable")
+	checker.Check(context.Background())
+	if len(sent) != 0 {
+		t.Fatalf("READY sent before a successful check: %v", sent)
+	}
+
+	checkErr = nil
+	checker.Check(context.Background())
+	checker.Check(context.Background())
+	if len(sent) != 1 || sent[0] != "READY=1" {
+		t.Errorf("sent = %v, want a single READY=1", sent)
+	}
+}
@@ -0,0 +1,522 @@
+package registry
+
+import (
+	"github.com/addisonbair/homelab-sidecars/pkg/acme"
+	"github.com/addisonbair/homelab-sidecars/pkg/backup"
+	"github.com/addisonbair/homelab-sidecars/pkg/btrbk"
+	"github.com/addisonbair/homelab-sidecars/pkg/ddns"
+	"github.com/addisonbair/homelab-sidecars/pkg/load"
+	"github.com/addisonbair/homelab-sidecars/pkg/manual"
+	"github.com/addisonbair/homelab-sidecars/pkg/minio"
+	"github.com/addisonbair/homelab-sidecars/pkg/raid"
+	"github.com/addisonbair/homelab-sidecars/pkg/rclone"
+	"github.com/addisonbair/homelab-sidecars/pkg/timemachine"
+	"github.com/addisonbair/homelab-sidecars/pkg/transfer"
+	"github.com/addisonbair/homelab-sidecars/pkg/vaultwarden"
+	"github.com/addisonbair/homelab-sidecars/pkg/wan"
+	"github.com/addisonbair/homelab-sidecars/pkg/zfs"
+)
+
+// Options shared by several checks
+var (
+	credScope     = Option{Name: "CREDENTIAL_SCOPE", Type: String, Values: []string{"off", "warn", "enforce"}, Default: "warn", Description: "what to do about credentials with more access than the check needs"}
+	procRoot      = Option{Name: "PROC_ROOT", Type: String, Description: "procfs mount to scan for processes, when /proc isn't the host's"}
+	sleepHooks    = Option{Name: "SLEEP_HOOKS", Type: Bool, Default: "false", Description: "follow suspend and resume, checking again as soon as the machine wakes"}
+	watchFiles    = Option{Name: "WATCH_FILES", Type: Bool, Default: "true", Description: "check as soon as a watched file changes, polling only to reconcile"}
+	jellyfinURL   = Option{Name: "JELLYFIN_URL", Type: URL, Description: "Jellyfin server"}
+	jellyfinKey   = Option{Name: "JELLYFIN_API_KEY", Type: String, Description: "Jellyfin API key"}
+	jellyfinFile  = Option{Name: "JELLYFIN_API_KEY_FILE", Type: String, Description: "file containing the Jellyfin API key"}
+	tvheadendUser = Option{Name: "TVHEADEND_USER", Type: String, Description: "Tvheadend user, if the API needs a login"}
+	tvheadendPass = Option{Name: "TVHEADEND_PASS", Type: Secret, Description: "password of TVHEADEND_USER"}
+)
+
+func init() {
+	for _, c := range []Check{
+		{
+			Name:        "acme",
+			Description: "blocks while a reverse proxy is obtaining or renewing a certificate over ACME",
+			Options: []Option{
+				{Name: "CADDY_DATA_DIR", Type: String, Description: "Caddy's data directory, whose lock files show orders in progress"},
+				{Name: "TRAEFIK_LOG", Type: String, Description: "Traefik log file to follow for orders"},
+				{Name: "ACME_MAX_ORDER", Type: Duration, Default: acme.DefaultMaxOrder.String(), Description: "stop blocking for an order whose end wasn't logged"},
+			},
+		},
+		{
+			Name:        "backup",
+			Description: "blocks while a restic or borg backup is running",
+			Options: []Option{
+				{Name: "BACKUP_RESTIC_REPOS", Type: List, Description: "restic repositories whose locks show a backup running"},
+				{Name: "BACKUP_BORG_REPOS", Type: List, Description: "borg repositories whose locks show a backup running"},
+				procRoot,
+				{Name: "BACKUP_PROCESS_PATTERN", Type: Regexp, Default: backup.DefaultProcessPattern, Description: `command lines of backup processes; "none" disables process matching`},
+			},
+		},
+		{
+			Name:        "btrbk",
+			Description: "blocks while btrbk or btrfs send/receive is transferring snapshots",
+			Options: []Option{
+				procRoot,
+				{Name: "BTRBK_PROCESS_PATTERN", Type: Regexp, Default: btrbk.DefaultProcessPattern, Description: "command lines of transfer processes"},
+			},
+		},
+		{
+			Name:        "ddns",
+			Description: "reports when a dynamic DNS record doesn't point at the host's public IP; never blocks",
+			Subcommands: []string{"healthcheck"},
+			Options: withHealthcheck([]Option{
+				{Name: "DDNS_HOSTNAME", Type: String, Required: true, Description: "name the record is for"},
+				{Name: "DDNS_THRESHOLD", Type: Duration, Default: "30m", Description: "how long the record may differ before each check fails"},
+				{Name: "DDNS_IP_URL", Type: URL, Default: ddns.DefaultIPURL, Description: "service returning the public IP; an IPv6 one checks the AAAA record"},
+				{Name: "DDNS_RESOLVER", Type: String, Description: "DNS server to ask, e.g. 1.1.1.1:53, bypassing a local resolver"},
+			}, "DDNS", "required", "10m"),
+		},
+		{
+			Name:        "dns",
+			Command:     "dns-healthcheck",
+			Description: "fails the boot unless the DNS filter is running and answering queries; takes no inhibitor",
+			Options: withHealthcheck([]Option{
+				{Name: "PIHOLE_URL", Type: URL, Description: "Pi-hole web interface, for the v6 API"},
+				{Name: "PIHOLE_PASSWORD", Type: Secret, Description: "Pi-hole web or app password, if one is set"},
+				{Name: "ADGUARD_URL", Type: URL, Description: "AdGuard Home web interface"},
+				{Name: "ADGUARD_USER", Type: String, Description: "AdGuard Home user"},
+				{Name: "ADGUARD_PASS", Type: Secret, Description: "password of ADGUARD_USER"},
+				{Name: "DNS_QUERY_NAME", Type: String, Default: "example.com", Description: "name looked up through each filter; a local record doesn't depend on the upstream"},
+			}, "DNS", "required", "2m"),
+		},
+		{
+			Name:        "dvr",
+			Description: "blocks while a DVR is recording or will start before the machine would be back up",
+			Options: []Option{
+				jellyfinURL, jellyfinKey, jellyfinFile,
+				{Name: "PLEX_URL", Type: URL, Description: "Plex server"},
+				{Name: "PLEX_TOKEN", Type: Secret, Description: "Plex token, required with PLEX_URL"},
+				{Name: "TVHEADEND_URL", Type: URL, Description: "Tvheadend server"},
+				tvheadendUser, tvheadendPass,
+				{Name: "DVR_LOOKAHEAD", Type: Duration, Default: "15m", Description: "how far ahead of a reboot to keep clear of recordings"},
+				{Name: "DVR_BOOT_TIME", Type: Duration, Default: "5m", Description: "how long until the DVR records again after a reboot"},
+			},
+		},
+		{
+			Name:        "garage",
+			Description: "blocks while a Garage cluster is degraded or resyncing",
+			Options: []Option{
+				{Name: "GARAGE_ADMIN_URL", Type: URL, Required: true, Description: "Garage admin API"},
+				{Name: "GARAGE_ADMIN_TOKEN", Type: String, Description: "admin API token"},
+				{Name: "GARAGE_ADMIN_TOKEN_FILE", Type: String, Description: "file containing the admin API token"},
+				{Name: "GARAGE_METRICS_TOKEN", Type: String, Description: "metrics token, if metrics_token is set in garage.toml"},
+				{Name: "GARAGE_RESYNC_THRESHOLD", Type: Int, Default: "0", Description: "blocks queued for resync above which the node is busy"},
+			},
+		},
+		{
+			Name:        "hass",
+			Description: "blocks while Home Assistant entities are active, and fires an event when the inhibitor changes",
+			Defaults:    map[string]string{"INHIBIT_WHAT": "shutdown"},
+			Options: []Option{
+				{Name: "HASS_URL", Type: URL, Required: true, Description: "Home Assistant"},
+				{Name: "HASS_TOKEN", Type: String, Description: "long-lived access token"},
+				{Name: "HASS_TOKEN_FILE", Type: String, Description: "file containing the access token"},
+				{Name: "HASS_ENTITIES", Type: List, Required: true, Description: "entities that block while on, e.g. input_boolean.backup_running"},
+				{Name: "HASS_EVENT", Type: String, Default: "homelab_inhibitor", Description: "event fired when the inhibitor is acquired or released"},
+			},
+		},
+		{
+			Name:        "httpapi",
+			Description: "blocks while a JSON status endpoint says a service is busy",
+			Subcommands: []string{"healthcheck"},
+			Options: withHealthcheck([]Option{
+				{Name: "HTTPAPI_NAME", Type: String, Default: "httpapi", Description: "check name"},
+				{Name: "HTTPAPI_URL", Type: URL, Required: true, Description: "status endpoint"},
+				{Name: "HTTPAPI_PATH", Type: String, Description: "JSONPath to the value to compare, e.g. $.state"},
+				{Name: "HTTPAPI_BUSY_WHEN", Type: String, Description: `condition on the value that means busy, e.g. "== indexing"`},
+				{Name: "HTTPAPI_COUNT", Type: Bool, Default: "false", Description: "compare the number of values at the path instead of each value"},
+				{Name: "HTTPAPI_HEADERS", Type: List, Description: `extra headers, e.g. "X-Api-Key: abc"`},
+				{Name: "HTTPAPI_AUTHORIZATION", Type: Secret, Description: "Authorization header"},
+				{Name: "HTTPAPI_HEALTH_PATH", Type: String, Description: "JSONPath the health check reads (default HTTPAPI_PATH)"},
+				{Name: "HTTPAPI_HEALTHY_WHEN", Type: String, Description: "condition that means healthy, required for the health check"},
+			}, "HTTPAPI", "required", "5m"),
+		},
+		{
+			Name:        "immich",
+			Description: "blocks while Immich is working through its job queues",
+			Options: []Option{
+				{Name: "IMMICH_URL", Type: URL, Default: "http://localhost:2283", Description: "Immich server"},
+				{Name: "IMMICH_API_KEY", Type: Secret, Required: true, Description: "Immich API key"},
+				{Name: "IMMICH_QUEUES", Type: List, Description: "queues that block, e.g. library,metadataExtraction (default all)"},
+				credScope,
+			},
+		},
+		{
+			Name:        "jellyfin",
+			Description: "blocks while users are streaming from Jellyfin",
+			Options: []Option{
+				{Name: "JELLYFIN_URL", Type: URL, Required: true, Description: "Jellyfin server"},
+				jellyfinKey, jellyfinFile,
+				{Name: "JELLYFIN_GRACE_PERIOD", Type: Duration, Default: "5m", Description: "keep blocking this long after the last stream ends"},
+				{Name: "JELLYFIN_BLOCK_TYPES", Type: List, Description: "only these media types block, e.g. Movie,Episode"},
+				{Name: "JELLYFIN_IGNORE_TYPES", Type: List, Description: "media types that never block"},
+				{Name: "JELLYFIN_BLOCK_USERS", Type: List, Description: "only these users' streams block"},
+				{Name: "JELLYFIN_IGNORE_USERS", Type: List, Description: "users whose streams never block"},
+				{Name: "JELLYFIN_BLOCK_CLIENTS", Type: List, Description: "only streams to these clients block"},
+				{Name: "JELLYFIN_IGNORE_CLIENTS", Type: List, Description: "clients whose streams never block"},
+				{Name: "JELLYFIN_BLOCK_DEVICES", Type: List, Description: "only streams to these devices block"},
+				{Name: "JELLYFIN_IGNORE_DEVICES", Type: List, Description: "devices whose streams never block"},
+				{Name: "JELLYFIN_PAUSE_TIMEOUT", Type: Duration, Default: "0s", Description: "stop counting sessions paused longer than this (0 never)"},
+				{Name: "JELLYFIN_BLOCK_TASKS", Type: List, Description: `scheduled tasks that also block while running; "default" picks scans and backups`},
+				{Name: "JELLYFIN_IGNORE_LOCAL", Type: Bool, Default: "false", Description: "ignore playback from the server itself"},
+				{Name: "JELLYFIN_MIN_BITRATE", Type: String, Description: `ignore streams below this combined bitrate, e.g. "20M"`},
+				sleepHooks,
+			},
+		},
+		{
+			Name:        "load",
+			Description: "reports, or with LOAD_BLOCK blocks, while the machine is under memory or I/O pressure",
+			Subcommands: []string{"healthcheck"},
+			Options: withHealthcheck([]Option{
+				{Name: "LOAD_PRESSURE", Type: List, Default: "memory:full=10,io:full=25", Description: "pressure thresholds as <resource>[:<kind>]=<percent> of time stalled"},
+				{Name: "LOAD_PRESSURE_WINDOW", Type: String, Values: load.Windows, Default: "avg60", Description: "pressure averaging window compared"},
+				{Name: "LOAD_MAX_PER_CPU", Type: Float, Default: "0", Description: "most the 5-minute load average may be per CPU (0 ignores it)"},
+				{Name: "LOAD_BLOCK", Type: Bool, Default: "false", Description: "hold the inhibitor while a threshold is exceeded"},
+				{Name: "PROC_ROOT", Type: String, Description: "procfs mount to read the load and pressure from, when /proc isn't the host's"},
+			}, "LOAD", "required", "5m"),
+		},
+		{
+			Name:        "logstore",
+			Description: "blocks while Elasticsearch is relocating shards",
+			Subcommands: []string{"healthcheck"},
+			Options: withHealthcheck([]Option{
+				{Name: "LOKI_URL", Type: URL, Description: "Loki, for the health check"},
+				{Name: "ELASTICSEARCH_URL", Type: URL, Description: "Elasticsearch"},
+				{Name: "ELASTICSEARCH_USER", Type: String, Description: "Elasticsearch user, if security is on"},
+				{Name: "ELASTICSEARCH_PASS", Type: Secret, Description: "password of ELASTICSEARCH_USER"},
+				{Name: "ELASTICSEARCH_API_KEY", Type: Secret, Description: "API key, instead of a user"},
+				{Name: "ELASTICSEARCH_MIN_STATUS", Type: String, Values: []string{"green", "yellow"}, Default: "green", Description: "least healthy cluster status that passes"},
+				credScope,
+			}, "LOGSTORE", "required", "5m"),
+		},
+		{
+			Name:        "mailqueue",
+			Description: "blocks while the Postfix or Exim mail queue is draining",
+			Subcommands: []string{"healthcheck"},
+			Options: withExecWrapper(withHealthcheck([]Option{
+				{Name: "MAIL_SERVER", Type: String, Values: []string{"postfix", "exim"}, Default: "postfix", Description: "mail server to query"},
+				{Name: "MAIL_QUEUE_COMMAND", Type: String, Description: `command to list the queue with, e.g. "podman exec mailserver postqueue"`},
+				procRoot,
+				{Name: "MAIL_HEALTH_WINDOW", Type: Duration, Default: "2m", Description: "time between the health check's two samples of the queue"},
+				{Name: "MAIL_HEALTH_MAX_GROWTH", Type: Int, Default: "50", Description: "messages the queue may grow by between the samples"},
+			}, "MAIL", "required", "5m"), "MAILQUEUE"),
+		},
+		{
+			Name:        "manual",
+			Description: "blocks, fails or force-allows shutdown on demand, for testing",
+			Options: []Option{
+				{Name: "MANUAL_FILE", Type: String, Default: manual.DefaultPath, Description: "flag file set by homelab-sidecar manual"},
+				watchFiles,
+			},
+		},
+		{
+			Name:        "minio",
+			Description: "blocks while MinIO is healing, rebalancing or receiving multipart uploads",
+			Options: []Option{
+				{Name: "MINIO_URL", Type: URL, Required: true, Description: "MinIO server"},
+				{Name: "MINIO_ACCESS_KEY", Type: String, Required: true, Description: "access key"},
+				{Name: "MINIO_SECRET_KEY", Type: String, Description: "secret key"},
+				{Name: "MINIO_SECRET_KEY_FILE", Type: String, Description: "file containing the secret key"},
+				{Name: "MINIO_REGION", Type: String, Default: minio.DefaultRegion, Description: "region to sign requests for"},
+				{Name: "MINIO_BUCKETS", Type: List, Description: "buckets whose uploads block (default all)"},
+				{Name: "MINIO_UPLOAD_MAX_AGE", Type: Duration, Default: minio.DefaultMaxUploadAge.String(), Description: "ignore multipart uploads started longer ago, as abandoned"},
+				{Name: "MINIO_CHECK_UPLOADS", Type: Bool, Default: "true", Description: "list multipart uploads; false for big deployments"},
+			},
+		},
+		{
+			Name:        "nextcloud",
+			Description: "blocks while Nextcloud is receiving uploads or upgrading",
+			Subcommands: []string{"healthcheck"},
+			Options: withHealthcheck([]Option{
+				{Name: "NEXTCLOUD_URL", Type: URL, Required: true, Description: "Nextcloud server"},
+				{Name: "NEXTCLOUD_TOKEN", Type: String, Description: "serverinfo token"},
+				{Name: "NEXTCLOUD_TOKEN_FILE", Type: String, Description: "file containing the serverinfo token"},
+				{Name: "NEXTCLOUD_MIN_UPLOAD_MB", Type: Int, Default: "10", Description: "smallest upload that blocks"},
+				{Name: "NEXTCLOUD_WORKER_THRESHOLD", Type: Int, Default: "0", Description: "busy PHP workers above which Nextcloud is busy (0 ignores them)"},
+			}, "NEXTCLOUD", "required", "5m"),
+		},
+		{
+			Name:        "nvr",
+			Description: "blocks while a ZoneMinder, Shinobi or Frigate camera is recording an event",
+			Subcommands: []string{"healthcheck"},
+			Options: withHealthcheck([]Option{
+				{Name: "ZONEMINDER_URL", Type: URL, Description: "ZoneMinder server"},
+				{Name: "ZONEMINDER_USER", Type: String, Description: "ZoneMinder user, if API authentication is on"},
+				{Name: "ZONEMINDER_PASS", Type: Secret, Description: "password of ZONEMINDER_USER"},
+				{Name: "SHINOBI_URL", Type: URL, Description: "Shinobi server"},
+				{Name: "SHINOBI_API_KEY", Type: Secret, Description: "Shinobi API key, required with SHINOBI_URL"},
+				{Name: "SHINOBI_GROUP_KEY", Type: String, Description: "Shinobi group key, required with SHINOBI_URL"},
+				{Name: "FRIGATE_URL", Type: URL, Description: "Frigate server"},
+				{Name: "FRIGATE_USER", Type: String, Description: "Frigate user, for the authenticated port"},
+				{Name: "FRIGATE_PASS", Type: Secret, Description: "password of FRIGATE_USER"},
+				{Name: "NVR_MONITORS", Type: List, Description: "cameras the health check expects to be capturing (default every enabled one)"},
+			}, "NVR", "required", "5m"),
+		},
+		{
+			Name:        "prometheus",
+			Description: "blocks while Prometheus is writing a compacted block or a snapshot, or while PromQL queries return series",
+			Subcommands: []string{"healthcheck"},
+			Options: withHealthcheck([]Option{
+				{Name: "PROMETHEUS_URL", Type: URL, Default: "http://localhost:9090", Description: "Prometheus server"},
+				{Name: "PROMETHEUS_BLOCK_COMPACTION", Type: Bool, Default: "true", Description: "block during compactions; false only blocks for snapshots"},
+				{Name: "PROMETHEUS_DATA_DIR", Type: String, Description: "storage.tsdb.path, to also block while a snapshot is written"},
+				{Name: "PROMETHEUS_QUERIES", Type: String, Description: `";"-separated PromQL queries whose results block, each "<name>=<query>"; "mdstat" checks every node's md arrays`},
+				{Name: "GRAFANA_URL", Type: URL, Description: "Grafana, which the health check also requires up"},
+			}, "PROMETHEUS", "required", "5m"),
+		},
+		{
+			Name:        "qbittorrent",
+			Description: "blocks while qBittorrent is downloading, rechecking or moving torrents",
+			Defaults:    map[string]string{"INHIBIT_WHAT": "shutdown"},
+			Options: []Option{
+				{Name: "QBITTORRENT_URL", Type: URL, Required: true, Description: "qBittorrent Web UI"},
+				{Name: "QBITTORRENT_USERNAME", Type: String, Description: "Web UI user"},
+				{Name: "QBITTORRENT_PASSWORD", Type: String, Description: "Web UI password"},
+				{Name: "QBITTORRENT_COOKIE_FILE", Type: String, Description: "file to keep the session in across restarts"},
+				credScope,
+				{Name: "ETA_THRESHOLD", Type: Duration, Default: "5m", Description: "only downloads finishing within this block"},
+				sleepHooks,
+				{Name: "SLEEP_PREPARE_TIMEOUT", Type: Duration, Default: "4s", Description: "time allowed to pause torrents before the machine sleeps"},
+			},
+		},
+		{
+			Name:        "raid",
+			Description: "blocks during RAID rebuilds and while arrays are degraded",
+			Subcommands: []string{"healthcheck"},
+			Defaults:    map[string]string{"INHIBIT_WHAT": "shutdown"},
+			Options: withHealthcheck([]Option{
+				{Name: "RAID_ARRAYS", Type: List, Required: true, Description: `arrays with per-array options, e.g. "md0,md1:warn"`},
+				{Name: "MDSTAT_PATH", Type: String, Default: raid.DefaultMdstatPath, Description: "mdstat to read; /sys/block reads sysfs for exact ETAs"},
+				{Name: "RAID_DETAIL", Type: String, Values: []string{"none", "sysfs"}, Default: "none", Description: "also read state mdstat doesn't show"},
+				{Name: "MDSTAT_LISTEN", Type: String, Description: "address to serve this node's mdstat on, for a controller node"},
+				{Name: "SCRUB_WINDOW", Type: String, Description: "daily HH:MM-HH:MM window to start scrubs in, in SCHEDULE_TZ"},
+				{Name: "SCRUB_INTERVAL", Type: Duration, Default: "720h", Description: "time between scrubs of an array"},
+				{Name: "SCRUB_STATE", Type: String, Default: "/var/lib/raid-sidecar/scrub.json", Description: "file recording when arrays were last scrubbed"},
+				watchFiles,
+			}, "RAID", "required", "1m"),
+		},
+		{
+			Name:        "rclone",
+			Description: "blocks while rclone is transferring or a mount has writes not yet uploaded",
+			Options: []Option{
+				procRoot,
+				{Name: "RCLONE_PROCESS_PATTERN", Type: Regexp, Default: rclone.DefaultProcessPattern, Description: "command lines of transfer processes"},
+				{Name: "RCLONE_MOUNT_PATTERN", Type: Regexp, Default: rclone.DefaultMountPattern, Description: "command lines of mounts"},
+				{Name: "RCLONE_RC_URLS", Type: List, Description: "remote control APIs of mounts started with --rc"},
+				{Name: "RCLONE_RC_USER", Type: String, Description: "remote control user"},
+				{Name: "RCLONE_RC_PASS", Type: String, Description: "remote control password"},
+				{Name: "RCLONE_RC_PASS_FILE", Type: String, Description: "file containing the remote control password"},
+				{Name: "RCLONE_CACHE_DIRS", Type: List, Description: "--cache-dir of mounts without --rc"},
+			},
+		},
+		{
+			Name:        "seaweedfs",
+			Description: "blocks while a SeaweedFS cluster is missing volume servers or replicas",
+			Options: []Option{
+				{Name: "SEAWEEDFS_MASTER_URL", Type: URL, Required: true, Description: "master server"},
+				{Name: "SEAWEEDFS_VOLUME_SERVERS", Type: Int, Default: "0", Description: "block while fewer volume servers are connected"},
+			},
+		},
+		{
+			Name:        "smart",
+			Description: "blocks while a disk is running a SMART self-test",
+			Options: withExecWrapper([]Option{
+				{Name: "SMART_DEVICES", Type: List, Required: true, Description: "disks to check, e.g. /dev/sda,/dev/nvme0"},
+				{Name: "SMART_COMMAND", Type: String, Description: `command to run smartctl with, e.g. "podman exec scrutiny smartctl"`},
+			}, "SMART"),
+		},
+		{
+			Name:        "ssh",
+			Description: "blocks while someone is logged in over SSH",
+			Options: []Option{
+				{Name: "SSH_SERVICES", Type: List, Default: "sshd", Description: "PAM services of SSH logins"},
+				{Name: "SSH_IGNORE_USERS", Type: List, Description: "users whose sessions never block, e.g. an automation account"},
+				{Name: "SSH_INTERACTIVE", Type: Bool, Default: "true", Description: "only count sessions with a terminal; false also counts commands run over ssh"},
+				{Name: "SSH_IDLE_TIMEOUT", Type: Duration, Default: "0s", Description: "stop counting a terminal left idle this long (0 never)"},
+			},
+		},
+		{
+			Name:        "sso",
+			Command:     "sso-healthcheck",
+			Description: "fails the boot unless the identity provider is ready; takes no inhibitor",
+			Options: withHealthcheck([]Option{
+				{Name: "KEYCLOAK_URL", Type: URL, Description: "Keycloak server"},
+				{Name: "KEYCLOAK_REALM", Type: String, Default: "master", Description: "realm whose discovery document is checked"},
+				{Name: "KEYCLOAK_HEALTH_URL", Type: URL, Description: "readiness endpoint of Keycloak 25 and later, on the management port"},
+				{Name: "AUTHELIA_URL", Type: URL, Description: "Authelia server"},
+				{Name: "AUTHELIA_OIDC", Type: Bool, Default: "true", Description: "check OpenID Connect; false for forward auth only"},
+			}, "SSO", "required", "5m"),
+		},
+		{
+			Name:        "synapse",
+			Description: "blocks while Synapse is migrating, purging or receiving media",
+			Options: []Option{
+				{Name: "SYNAPSE_URL", Type: URL, Required: true, Description: "Synapse homeserver"},
+				{Name: "SYNAPSE_ADMIN_TOKEN", Type: String, Description: "admin access token"},
+				{Name: "SYNAPSE_ADMIN_TOKEN_FILE", Type: String, Description: "file containing the admin access token"},
+				{Name: "SYNAPSE_METRICS_URLS", Type: List, Description: "metrics endpoints of the main process and workers"},
+			},
+		},
+		{
+			Name:        "syncthing",
+			Description: "blocks while Syncthing is syncing a folder",
+			Options: []Option{
+				{Name: "SYNCTHING_URL", Type: URL, Default: "http://localhost:8384", Description: "Syncthing GUI address"},
+				{Name: "SYNCTHING_API_KEY", Type: Secret, Required: true, Description: "Syncthing API key"},
+				{Name: "SYNCTHING_OUT_OF_SYNC_THRESHOLD", Type: Int, Default: "-1", Description: "also block while an idle folder needs more items than this (-1 never)"},
+			},
+		},
+		{
+			Name:        "thermal",
+			Description: "force-allows shutdown while a CPU, GPU or drive sensor is at its critical temperature",
+			Subcommands: []string{"healthcheck"},
+			Defaults:    map[string]string{"INHIBIT_WHAT": "shutdown", "BACKOFF_AFTER": "0"},
+			Options: withHealthcheck([]Option{
+				{Name: "THERMAL_SENSORS", Type: List, Description: `sensors to check as patterns, e.g. "coretemp/*,amdgpu/*"; all by default`},
+				{Name: "THERMAL_IGNORE", Type: List, Description: "patterns of sensors to skip"},
+				{Name: "THERMAL_CRITICAL", Type: Int, Default: "0", Description: "critical temperature in °C for every sensor (0 uses each sensor's own)"},
+				{Name: "THERMAL_HYSTERESIS", Type: Int, Default: "5", Description: "°C a sensor must cool before the force-allow is lifted"},
+			}, "THERMAL", "required", "1m"),
+		},
+		{
+			Name:        "timemachine",
+			Description: "blocks while a Mac is backing up with Time Machine",
+			Options: withExecWrapper([]Option{
+				{Name: "TIMEMACHINE_DIRS", Type: List, Description: "Time Machine shares, e.g. /srv/timemachine"},
+				procRoot,
+				{Name: "TIMEMACHINE_PROCESS_PATTERN", Type: Regexp, Default: timemachine.DefaultProcessPattern, Description: "command lines of file server processes"},
+				{Name: "TIMEMACHINE_SMBSTATUS", Type: Bool, Default: "false", Description: "also read Samba's lock list"},
+				{Name: "SMBSTATUS_COMMAND", Type: String, Description: "command to run smbstatus with"},
+			}, "TIMEMACHINE"),
+		},
+		{
+			Name:        "transfer",
+			Description: "blocks while SFTP or FTP uploads are in flight",
+			Options: []Option{
+				{Name: "TRANSFER_DIRS", Type: List, Required: true, Description: "upload folders to watch, e.g. /srv/drop"},
+				{Name: "TRANSFER_MIN_SIZE_MB", Type: Int, Default: "0", Description: "smallest upload that blocks"},
+				procRoot,
+				{Name: "TRANSFER_PROCESS_PATTERN", Type: Regexp, Default: transfer.DefaultProcessPattern, Description: "command lines of file server processes"},
+				{Name: "TRANSFER_GRACE_PERIOD", Type: Duration, Default: "2m", Description: "keep blocking this long after the last upload"},
+			},
+		},
+		{
+			Name:        "tvheadend",
+			Description: "blocks while Tvheadend's tuners are in use",
+			Options: []Option{
+				{Name: "TVHEADEND_URL", Type: URL, Required: true, Description: "Tvheadend server"},
+				tvheadendUser, tvheadendPass,
+			},
+		},
+		{
+			Name:        "update",
+			Description: "blocks while dpkg, rpm, unattended-upgrades, rpm-ostree or PackageKit is changing packages",
+			Options: []Option{
+				{Name: "UPDATE_ROOT", Type: String, Description: "where the host's / is mounted, in a container"},
+				{Name: "UPDATE_LOCKS", Type: List, Description: "lock files to check instead of dpkg's, rpm's and unattended-upgrades', each <name>=<path>"},
+				{Name: "UPDATE_DAEMONS", Type: Bool, Default: "true", Description: "ask rpm-ostree and PackageKit about their transactions over D-Bus"},
+			},
+		},
+		{
+			Name:        "ups",
+			Description: "force-allows shutdown once the host is on battery with little left",
+			Defaults:    map[string]string{"INHIBIT_WHAT": "shutdown", "BACKOFF_AFTER": "0"},
+			Options: []Option{
+				{Name: "UPS_NUT_ADDR", Type: String, Description: "NUT server, e.g. localhost:3493"},
+				{Name: "UPS_NAME", Type: String, Default: "ups", Description: "UPS name on the NUT server"},
+				{Name: "UPS_APCUPSD_ADDR", Type: String, Description: "apcupsd NIS address, instead of NUT"},
+				{Name: "UPS_MIN_CHARGE", Type: Int, Default: "30", Description: "battery percentage below which shutdown is force-allowed"},
+				{Name: "UPS_MIN_RUNTIME", Type: Duration, Default: "5m", Description: "runtime below which shutdown is force-allowed"},
+			},
+		},
+		{
+			Name:        "upstream",
+			Description: "power-cycles the modem after a sustained outage, blocking only while it does",
+			Subcommands: []string{"healthcheck"},
+			Options: withHealthcheck([]Option{
+				{Name: "UPSTREAM_TARGETS", Type: List, Required: true, Description: "host:port pairs of the router and modem"},
+				{Name: "UPSTREAM_FAIL_AFTER", Type: Duration, Default: "5m", Description: "outage before the modem is power-cycled"},
+				{Name: "UPSTREAM_INTERFACE", Type: String, Description: "interface that must be up, with carrier, a routable address and the default route"},
+				{Name: "UPSTREAM_LOOKUP", Type: String, Description: "name to resolve once the targets answer, through systemd-resolved if it is running"},
+				{Name: "UPSTREAM_ICMP", Type: Bool, Default: "false", Description: "ping the targets first, for routers that drop TCP"},
+				{Name: "UPSTREAM_PROBES", Type: Int, Default: "1", Description: "probes per target per check; above 1 also checks loss and latency"},
+				{Name: "UPSTREAM_MAX_LOSS_PERCENT", Type: Int, Default: "20", Description: "highest acceptable loss, with UPSTREAM_PROBES above 1"},
+				{Name: "UPSTREAM_MAX_LATENCY", Type: Duration, Default: "0s", Description: "highest acceptable average latency (0 disables)"},
+				{Name: "REMEDIATE_OFF_URL", Type: URL, Description: "URL switching the modem's plug off"},
+				{Name: "REMEDIATE_ON_URL", Type: URL, Description: "URL switching the modem's plug on, required with REMEDIATE_OFF_URL"},
+				{Name: "REMEDIATE_OFF_FOR", Type: Duration, Default: "30s", Description: "how long the plug stays off"},
+				{Name: "REMEDIATE_METHOD", Type: String, Default: "GET", Description: "HTTP method for the plug URLs"},
+				{Name: "REMEDIATE_COOLDOWN", Type: Duration, Default: "30m", Description: "least time between power cycles"},
+				{Name: "REMEDIATE_MAX", Type: Int, Default: "3", Description: "most power cycles per REMEDIATE_WINDOW"},
+				{Name: "REMEDIATE_WINDOW", Type: Duration, Default: "24h", Description: "window REMEDIATE_MAX applies to"},
+				{Name: "REMEDIATE_STATE", Type: String, Description: "file keeping the power cycles across restarts"},
+			}, "UPSTREAM", "required", "2m"),
+		},
+		{
+			Name:        "vaultwarden",
+			Description: "blocks while the Vaultwarden database is being backed up",
+			Subcommands: []string{"healthcheck"},
+			Options: withHealthcheck([]Option{
+				{Name: "VAULTWARDEN_URL", Type: URL, Default: "http://localhost", Description: "Vaultwarden server, for the health check"},
+				{Name: "VAULTWARDEN_BACKUP_LOCKS", Type: List, Description: "files the backup script holds while it runs"},
+				{Name: "VAULTWARDEN_STALE_LOCK_AGE", Type: Duration, Default: vaultwarden.DefaultStaleLockAge.String(), Description: "ignore lock files older than this"},
+				procRoot,
+				{Name: "VAULTWARDEN_PROCESS_PATTERN", Type: Regexp, Default: vaultwarden.DefaultProcessPattern, Description: `command lines of backup processes; "none" disables process matching`},
+			}, "VAULTWARDEN", "required", "5m"),
+		},
+		{
+			Name:        "vpn",
+			Command:     "vpn-healthcheck",
+			Description: "fails the boot unless the WireGuard or Tailscale peers used for remote access are reachable; takes no inhibitor",
+			Options: withHealthcheck([]Option{
+				{Name: "WIREGUARD_INTERFACES", Type: List, Description: "WireGuard interfaces to check, e.g. wg0"},
+				{Name: "WIREGUARD_PEERS", Type: List, Description: `public keys that must have handshaken, each optionally "<name>=<key>" (default every peer)`},
+				{Name: "WIREGUARD_MAX_HANDSHAKE_AGE", Type: Duration, Default: "5m", Description: "oldest acceptable handshake; the peers need PersistentKeepalive"},
+				{Name: "TAILSCALE_SOCKET", Type: String, Description: "tailscaled's local API socket, usually /run/tailscale/tailscaled.sock"},
+				{Name: "TAILSCALE_PEERS", Type: List, Description: "host names of peers that must be online"},
+				{Name: "TAILSCALE_MAX_HANDSHAKE_AGE", Type: Duration, Default: "0s", Description: "oldest acceptable handshake with each peer (0 only requires them online)"},
+			}, "VPN", "required", "5m"),
+		},
+		{
+			Name:        "wan",
+			Description: "measures internet latency, loss and reachability; never blocks",
+			Subcommands: []string{"healthcheck", "internet"},
+			Options: withHealthcheck([]Option{
+				{Name: "WAN_REFLECTORS", Type: List, Default: "1.1.1.1:443,8.8.8.8:443,9.9.9.9:443", Description: "host:port pairs to time TCP connections to"},
+				{Name: "WAN_MAX_LATENCY", Type: Duration, Default: wan.DefaultLimits.MaxLatency.String(), Description: "highest acceptable latency"},
+				{Name: "WAN_MAX_LOSS_PERCENT", Type: Int, Default: "20", Description: "highest acceptable loss"},
+				{Name: "SPEEDTEST_TRACKER_URL", Type: URL, Description: "speedtest-tracker, to check its latest result"},
+				{Name: "SPEEDTEST_TRACKER_TOKEN", Type: Secret, Description: "speedtest-tracker API token, required with SPEEDTEST_TRACKER_URL"},
+				{Name: "WAN_MIN_DOWNLOAD_MBPS", Type: Int, Default: "0", Description: "lowest acceptable download speed"},
+				{Name: "WAN_MIN_UPLOAD_MBPS", Type: Int, Default: "0", Description: "lowest acceptable upload speed"},
+				{Name: "WAN_MAX_SPEEDTEST_AGE", Type: Duration, Default: "0s", Description: "oldest acceptable speedtest (0 any)"},
+				{Name: "INTERNET_URLS", Type: List, Description: "HTTPS URLs that show the internet is reachable; opts in to the reachability check"},
+				{Name: "INTERNET_PORTAL_URL", Type: URL, Default: wan.DefaultPortalURL, Description: "URL answering 204, to tell a captive portal from an outage"},
+				{Name: "INTERNET_HEALTH_SEVERITY", Type: String, Values: severities, Default: "warning", Description: `whether a failing "internet" check fails the boot`},
+				{Name: "INTERNET_HEALTH_TIMEOUT", Type: Duration, Default: "2m", Description: `how long the "internet" check retries before giving up`},
+			}, "WAN", "warning", "2m"),
+		},
+		{
+			Name:        "zfs",
+			Description: "blocks while ZFS replication is transferring",
+			Subcommands: []string{"healthcheck"},
+			Options: withExecWrapper(withHealthcheck([]Option{
+				{Name: "ZFS_DATASETS", Type: List, Required: true, Description: "datasets whose snapshots the health check requires recent"},
+				{Name: "ZFS_SNAPSHOT_MAX_AGE", Type: Duration, Default: "25h", Description: "oldest acceptable newest snapshot"},
+				{Name: "ZFS_COMMAND", Type: String, Description: "command to run zfs with"},
+				procRoot,
+				{Name: "ZFS_PROCESS_PATTERN", Type: Regexp, Default: zfs.DefaultProcessPattern, Description: "command lines of replication processes"},
+			}, "ZFS", "required", "1m"), "ZFS"),
+		},
+	} {
+		if c.Command == "" {
+			c.Command = c.Name + "-sidecar"
+			c.Sidecar = true
+		}
+		Register(c)
+	}
+}
@@ -0,0 +1,252 @@
+// Package registry describes the checks the sidecars provide and the
+// environment variables that configure them: name, type, default and what
+// each does. The same descriptions validate a configuration before it is
+// deployed and document it at runtime ("homelab-sidecar checks list"), so
+// tooling such as Nix or Ansible can generate environment files from them
+// rather than from a copy of the docs.
+package registry
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/profile"
+)
+
+// Type is the kind of value an option takes
+type Type string
+
+const (
+	String   Type = "string"
+	Bool     Type = "bool" // "true" or "false"
+	Int      Type = "int"
+	Float    Type = "float"
+	Duration Type = "duration" // e.g. "90s" or "5m"
+	List     Type = "list"     // comma-separated
+	URL      Type = "url"      // with scheme and host
+	Regexp   Type = "regexp"
+	// Secret is a string that can instead be read from the file named by
+	// <NAME>_FILE
+	Secret Type = "secret"
+)
+
+// Option is an environment variable a check reads
+type Option struct {
+	Name        string   `json:"name"`
+	Type        Type     `json:"type"`
+	Default     string   `json:"default,omitempty"`
+	Values      []string `json:"values,omitempty"` // the only values allowed, if set
+	Required    bool     `json:"required,omitempty"`
+	Description string   `json:"description"`
+}
+
+// Check is a check and the sidecar that runs it
+type Check struct {
+	Name        string   `json:"name"`    // e.g. "raid"
+	Command     string   `json:"command"` // e.g. "raid-sidecar"
+	Description string   `json:"description"`
+	Options     []Option `json:"options"`
+	// Defaults overrides the defaults of common options, e.g. INHIBIT_WHAT
+	Defaults map[string]string `json:"defaults,omitempty"`
+	// Subcommands are the arguments that run something other than the
+	// sidecar, e.g. "healthcheck"
+	Subcommands []string `json:"subcommands,omitempty"`
+	// Sidecar is false for a command that only runs once, e.g. a health
+	// check, and so doesn't read the common options
+	Sidecar bool `json:"sidecar"`
+}
+
+var (
+	mu     sync.Mutex
+	checks = map[string]Check{}
+)
+
+// Register adds a check. It panics if the name is taken, as that is a
+// programming error.
+func Register(c Check) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := checks[c.Name]; ok {
+		panic("registry: check " + c.Name + " registered twice")
+	}
+	checks[c.Name] = c
+}
+
+// All returns the registered checks, sorted by name.
+func All() []Check {
+	mu.Lock()
+	defer mu.Unlock()
+	all := make([]Check, 0, len(checks))
+	for _, c := range checks {
+		all = append(all, c)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
+
+// Lookup returns the named check.
+func Lookup(name string) (Check, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	c, ok := checks[name]
+	return c, ok
+}
+
+// Common returns the options every sidecar reads, with c's defaults.
+func (c Check) Common() []Option {
+	if !c.Sidecar {
+		return nil
+	}
+	opts := slices.Clone(Common)
+	for i, o := range opts {
+		if d, ok := c.Defaults[o.Name]; ok {
+			opts[i].Default = d
+		}
+	}
+	return opts
+}
+
+// Option returns the check's option, or the common option, called name.
+func (c Check) Option(name string) (Option, bool) {
+	for _, opts := range [][]Option{c.Options, c.Common()} {
+		for _, o := range opts {
+			if o.Name == name {
+				return o, true
+			}
+		}
+	}
+	return Option{}, false
+}
+
+// Validate checks env, the variables a check would run with, e.g. read
+// from its environment files, against the check's options. It reports
+// values that don't parse as their option's type, required options that
+// are missing, and variables that no check knows of, which are usually
+// typos. Variables set for another profile are checked as their option,
+// but only those of env's SIDECAR_PROFILE count as set.
+func Validate(check string, env map[string]string) []error {
+	c, ok := Lookup(check)
+	if !ok {
+		return []error{fmt.Errorf("unknown check %q", check)}
+	}
+	env = withProfile(env)
+
+	var errs []error
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name := key
+		if _, unprefixed, ok := strings.Cut(key, profile.Separator); ok {
+			name = unprefixed
+		}
+		if o, ok := c.option(name); ok {
+			if err := o.Check(env[key]); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			}
+		} else if !known(name) {
+			errs = append(errs, fmt.Errorf("%s: not an option of any check", key))
+		}
+	}
+
+	for _, o := range c.Options {
+		if o.Required && env[o.Name] == "" && (o.Type != Secret || env[o.Name+"_FILE"] == "") {
+			errs = append(errs, fmt.Errorf("%s: required", o.Name))
+		}
+	}
+	return errs
+}
+
+// option is Option, also matching <NAME>_FILE of a secret
+func (c Check) option(name string) (Option, bool) {
+	if o, ok := c.Option(name); ok {
+		return o, true
+	}
+	if base, ok := strings.CutSuffix(name, "_FILE"); ok {
+		if o, ok := c.Option(base); ok && o.Type == Secret {
+			return Option{Name: name, Type: String}, true
+		}
+	}
+	return Option{}, false
+}
+
+// known reports whether any check, or the common options, has name
+func known(name string) bool {
+	if name == "SIDECAR_PROFILE" {
+		return true
+	}
+	for _, c := range All() {
+		if _, ok := c.option(name); ok {
+			return true
+		}
+	}
+	_, ok := Check{Sidecar: true}.option(name)
+	return ok
+}
+
+// withProfile applies env's SIDECAR_PROFILE to a copy of env, as
+// profile.Apply does to the process environment.
+func withProfile(env map[string]string) map[string]string {
+	out := make(map[string]string, len(env))
+	for k, v := range env {
+		out[k] = v
+	}
+	name := env["SIDECAR_PROFILE"]
+	if name == "" {
+		return out
+	}
+	prefix := profile.Prefix(name)
+	for k, v := range env {
+		if unprefixed, ok := strings.CutPrefix(k, prefix); ok && unprefixed != "" {
+			out[unprefixed] = v
+		}
+	}
+	return out
+}
+
+// Check reports whether value is valid for the option. Empty is always
+// valid: the sidecars treat it as unset.
+func (o Option) Check(value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(o.Values) > 0 && !slices.Contains(o.Values, value) {
+		return fmt.Errorf("%q is not one of %s", value, strings.Join(o.Values, ", "))
+	}
+	switch o.Type {
+	case Bool:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+	case Int:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%q is not a whole number", value)
+		}
+	case Float:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+	case Duration:
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("%q is not a duration, e.g. 90s or 5m", value)
+		}
+	case URL:
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("%q is not a URL", value)
+		}
+	case Regexp:
+		if _, err := regexp.Compile(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
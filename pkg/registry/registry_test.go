@@ -0,0 +1,153 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string // substrings of the errors, in order
+	}{
+		{
+			name: "valid",
+			env:  map[string]string{"RAID_ARRAYS": "md0", "POLL_INTERVAL": "1m", "RAID_DETAIL": "sysfs"},
+		},
+		{
+			name: "missing required",
+			env:  map[string]string{"POLL_INTERVAL": "1m"},
+			want: []string{"RAID_ARRAYS: required"},
+		},
+		{
+			name: "bad values",
+			env:  map[string]string{"RAID_ARRAYS": "md0", "POLL_INTERVAL": "often", "RAID_DETAIL": "mdadm", "WATCH_FILES": "yes"},
+			want: []string{"POLL_INTERVAL: \"often\" is not a duration", "RAID_DETAIL: \"mdadm\" is not one of", "WATCH_FILES: \"yes\" is not true or false"},
+		},
+		{
+			name: "typo",
+			env:  map[string]string{"RAID_ARRAYS": "md0", "RAID_ARRAY": "md1"},
+			want: []string{"RAID_ARRAY: not an option of any check"},
+		},
+		{
+			// Shared environment files set other checks' options
+			name: "other check's option",
+			env:  map[string]string{"RAID_ARRAYS": "md0", "JELLYFIN_URL": "http://jellyfin:8096"},
+		},
+		{
+			name: "profile",
+			env:  map[string]string{"SIDECAR_PROFILE": "nas", "NAS__RAID_ARRAYS": "md0", "LAPTOP__POLL_INTERVAL": "soon"},
+			want: []string{"LAPTOP__POLL_INTERVAL: \"soon\" is not a duration"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := Validate("raid", tt.env)
+			if len(errs) != len(tt.want) {
+				t.Fatalf("Validate() = %v, want %d errors", errs, len(tt.want))
+			}
+			for i, err := range errs {
+				if !strings.Contains(err.Error(), tt.want[i]) {
+					t.Errorf("error %d = %q, want it to contain %q", i, err, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSecretFile(t *testing.T) {
+	if errs := Validate("immich", map[string]string{"IMMICH_API_KEY_FILE": "/run/secrets/immich"}); len(errs) != 0 {
+		t.Errorf("Validate() = %v, want the _FILE variant to satisfy a required secret", errs)
+	}
+	if errs := Validate("nope", nil); len(errs) != 1 {
+		t.Errorf("Validate() of an unknown check = %v", errs)
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	c, ok := Lookup("ups")
+	if !ok {
+		t.Fatal("ups not registered")
+	}
+	if o, _ := c.Option("INHIBIT_WHAT"); o.Default != "shutdown" {
+		t.Errorf("ups INHIBIT_WHAT default = %q, want shutdown", o.Default)
+	}
+	if o, _ := c.Option("POLL_INTERVAL"); o.Default != "30s" {
+		t.Errorf("ups POLL_INTERVAL default = %q, want 30s", o.Default)
+	}
+	sso, _ := Lookup("sso")
+	if _, ok := sso.Option("POLL_INTERVAL"); ok {
+		t.Error("sso-healthcheck has the sidecar options")
+	}
+}
+
+// envCall matches the helpers the commands read their configuration with
+var envCall = regexp.MustCompile(`\bsidecarmain\.(?:Env|Duration|Int|RequireEnv|Secret|RequireSecret)\("([A-Z0-9_]+)"`)
+
+// helperSources are package files that read a command's options for it
+var helperSources = map[string][]string{
+	// The *FromEnv constructors shared with time-to-safe
+	"backup-sidecar":      {filepath.Join("..", "backup", "env.go")},
+	"btrbk-sidecar":       {filepath.Join("..", "btrbk", "env.go")},
+	"jellyfin-sidecar":    {filepath.Join("..", "jellyfin", "env.go")},
+	"timemachine-sidecar": {filepath.Join("..", "timemachine", "env.go")},
+}
+
+// TestCommands keeps the registry in sync with the commands: each variable
+// a command reads is registered for its check, and each option registered
+// for a check is read by its command.
+func TestCommands(t *testing.T) {
+	for _, c := range All() {
+		files, err := filepath.Glob(filepath.Join("..", "..", "cmd", c.Command, "*.go"))
+		if err != nil || len(files) == 0 {
+			t.Errorf("%s: no source for %s", c.Name, c.Command)
+			continue
+		}
+		var src strings.Builder
+		for _, f := range files {
+			if strings.HasSuffix(f, "_test.go") {
+				continue
+			}
+			b, err := os.ReadFile(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			src.Write(b)
+		}
+		var helpers strings.Builder
+		for _, f := range helperSources[c.Command] {
+			b, err := os.ReadFile(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			helpers.Write(b)
+		}
+
+		for _, m := range envCall.FindAllStringSubmatch(src.String(), -1) {
+			if _, ok := c.option(m[1]); !ok {
+				t.Errorf("%s reads %s, which isn't registered", c.Command, m[1])
+			}
+		}
+		read := src.String() + helpers.String()
+		for _, o := range c.Options {
+			if !strings.Contains(read, `"`+o.Name+`"`) && !fromHelper(o.Name) {
+				t.Errorf("%s: option %s isn't read by %s", c.Name, o.Name, c.Command)
+			}
+		}
+	}
+}
+
+// fromHelper reports whether name is read by a package rather than the
+// command itself
+func fromHelper(name string) bool {
+	for _, o := range healthcheck {
+		if o.Name == name {
+			return true
+		}
+	}
+	return strings.HasSuffix(name, "_HEALTH_SEVERITY") || strings.HasSuffix(name, "EXEC_WRAPPER")
+}
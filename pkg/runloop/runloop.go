@@ -0,0 +1,233 @@
+// Package runloop runs a sidecar the way sidecar.Run does, taking its
+// lock through an inhibitor.Lock so the same loop serves the real inhibitor and the
+// dry run. The real one comes from inhibitor.New, so on macOS and Windows,
+// where there is no logind, a sidecar still keeps the machine from
+// sleeping while it is busy.
+//
+// It also checks as soon as one of the files the check reads changes: a
+// flag file, the force-allow file, or /proc/mdstat when an array starts
+// rebuilding. The poll interval then only reconciles changes a watch
+// missed, so it can be long without delaying the inhibitor.
+package runloop
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"syscall"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/check"
+	"github.com/addisonbair/homelab-sidecars/pkg/filewatch"
+	"github.com/addisonbair/homelab-sidecars/pkg/inhibitor"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/logind"
+	"github.com/coreos/go-systemd/v22/daemon"
+	"github.com/godbus/dbus/v5"
+)
+
+// Config is what Run needs beyond sidecar.Options
+type Config struct {
+	// NewLock opens the lock; by default an inhibitor from inhibitor.New
+	NewLock func(ctx context.Context, what, who, mode string) (inhibitor.Lock, error)
+	// Watch are files whose changes run the check straight away
+	Watch []string
+	// StatusPrefix is put before every STATUS, e.g. "Dry run: "
+	StatusPrefix string
+	// Publisher, if set, is given the lock's state after every check
+	Publisher check.Publisher
+}
+
+// newInhibitor opens the default lock
+func newInhibitor(ctx context.Context, what, who, mode string) (inhibitor.Lock, error) {
+	return inhibitor.New(ctx, what, who, mode)
+}
+
+// notifyFunc sends a state string to systemd; replaced in tests
+var notifyFunc = func(state string) (bool, error) {
+	return daemon.SdNotify(false, state)
+}
+
+// subscribeShutdown follows PrepareForShutdown; replaced in tests
+var subscribeShutdown = func() (<-chan bool, func(), error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, nil, err
+	}
+	signals, cancel, err := logind.PrepareForShutdown(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return signals, func() { cancel(); conn.Close() }, nil
+}
+
+// Run polls checker every opts.PollInterval, and whenever one of
+// cfg.Watch changes, holding the lock while it is busy, until ctx is
+// cancelled or the process gets SIGTERM or SIGINT. It honours the same
+// options as sidecar.Run, except Logger: messages go to the logging
+// package.
+//
+// A check run because a file changed gets a context marked with
+// filewatch.WithChanged, so wrappers that skip polls still run it.
+func Run(ctx context.Context, checker sidecar.Checker, opts sidecar.Options, cfg Config) error {
+	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	what, mode := opts.InhibitWhat, opts.InhibitMode
+	if what == "" {
+		what = "shutdown"
+	}
+	if mode == "" {
+		mode = "block"
+	}
+	newLock := cfg.NewLock
+	if newLock == nil {
+		newLock = newInhibitor
+	}
+
+	inh, err := newLock(ctx, what, checker.Name(), mode)
+	if err != nil {
+		return fmt.Errorf("creating inhibitor: %w", err)
+	}
+	defer inh.Close()
+
+	var shutdown <-chan bool
+	if signals, unsubscribe, err := subscribeShutdown(); err != nil {
+		logging.Warnf("could not subscribe to PrepareForShutdown: %v", err)
+	} else {
+		defer unsubscribe()
+		shutdown = signals
+	}
+
+	if opts.NotifyReady {
+		if _, err := notifyFunc(daemon.SdNotifyReady); err != nil {
+			logging.Warnf("failed to send READY: %v", err)
+		}
+	}
+	status := func(s string) {
+		if opts.NotifyStatus {
+			notifyFunc("STATUS=" + cfg.StatusPrefix + s)
+		}
+	}
+	status("Starting")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var watchdog <-chan time.Time
+	if opts.WatchdogInterval > 0 {
+		t := time.NewTicker(opts.WatchdogInterval)
+		defer t.Stop()
+		watchdog = t.C
+	}
+
+	changes := filewatch.Changes(ctx, cfg.Watch...)
+
+	publish := func(ctx context.Context) {
+		if cfg.Publisher == nil {
+			return
+		}
+		held, why := inh.Held()
+		state := check.State{Who: checker.Name(), What: what, Set: checker.Name(), Held: held, Reason: why}
+		if err := cfg.Publisher.Publish(ctx, state); err != nil {
+			logging.Warnf("publish: %v", err)
+		}
+	}
+
+	var failing bool
+	run := func(ctx context.Context) {
+		defer publish(ctx)
+		busy, reason, err := checker.Check(ctx)
+		if err != nil {
+			// As sidecar.Run: keep the previous state, but say why it
+			// isn't being updated
+			logging.Errorf("Check error: %v", err)
+			status(fmt.Sprintf("Check error: %v", err))
+			failing = true
+			return
+		}
+		recovered := failing
+		failing = false
+		held, why := inh.Held()
+		switch {
+		case busy && !held:
+			if err := inh.Acquire(ctx, reason); err != nil {
+				logging.Errorf("Failed to acquire inhibitor: %v", err)
+				return
+			}
+			logging.Infof("Acquired inhibitor: %s", reason)
+			status(fmt.Sprintf("Busy: %s", reason))
+			if opts.OnBusy != nil {
+				opts.OnBusy(reason)
+			}
+		case busy && reason != why:
+			// The new lock is taken first, so shutdown is never let
+			// through in between
+			if err := inh.Replace(ctx, reason); err != nil {
+				logging.Errorf("Failed to update inhibitor: %v", err)
+				return
+			}
+			logging.Infof("Inhibitor reason changed: %s", reason)
+			status(fmt.Sprintf("Busy: %s", reason))
+		case busy:
+			status(fmt.Sprintf("Busy: %s", reason))
+		case held:
+			if err := inh.Release(ctx); err != nil {
+				logging.Errorf("Failed to release inhibitor: %v", err)
+				return
+			}
+			logging.Infof("Released inhibitor")
+			status("Idle")
+			if opts.OnIdle != nil {
+				opts.OnIdle()
+			}
+		case recovered:
+			status("Idle")
+		}
+	}
+
+	logging.Infof("Starting %s sidecar (poll=%s, inhibit=%s, watching %d files)",
+		checker.Name(), interval, what, len(cfg.Watch))
+	run(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			logging.Infof("Shutting down")
+			if opts.NotifyStatus {
+				notifyFunc(daemon.SdNotifyStopping)
+			}
+			return nil
+		case <-ticker.C:
+			run(ctx)
+		case <-changes:
+			logging.Debugf("Watched file changed, checking now")
+			run(filewatch.WithChanged(ctx))
+		case active := <-shutdown:
+			if active {
+				logging.Infof("PrepareForShutdown received, checking immediately")
+				if opts.OnShutdownSignal != nil {
+					opts.OnShutdownSignal()
+				}
+				run(ctx)
+			}
+		case <-watchdog:
+			if _, err := notifyFunc(daemon.SdNotifyWatchdog); err != nil {
+				logging.Warnf("watchdog ping failed: %v", err)
+			}
+		}
+	}
+}
+
+// MustRun calls Run and exits the process on error.
+func MustRun(ctx context.Context, checker sidecar.Checker, opts sidecar.Options, cfg Config) {
+	if err := Run(ctx, checker, opts, cfg); err != nil {
+		logging.Fatalf("%v", err)
+	}
+}
@@ -0,0 +1,229 @@
+package runloop
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/check"
+	"github.com/addisonbair/homelab-sidecars/pkg/filewatch"
+	"github.com/addisonbair/homelab-sidecars/pkg/inhibitor"
+)
+
+// fakeLock records the lock's state
+type fakeLock struct {
+	mu   sync.Mutex
+	held bool
+	why  string
+}
+
+func (f *fakeLock) Acquire(ctx context.Context, why string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.held, f.why = true, why
+	return nil
+}
+
+func (f *fakeLock) Release(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.held, f.why = false, ""
+	return nil
+}
+
+func (f *fakeLock) Replace(ctx context.Context, why string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.why = why
+	return nil
+}
+
+func (f *fakeLock) Held() (bool, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.held, f.why
+}
+
+func (f *fakeLock) Close() error { return nil }
+
+func TestRunChecksOnChange(t *testing.T) {
+	fake := &fakeLock{}
+	notifyFunc = func(string) (bool, error) { return true, nil }
+	subscribeShutdown = func() (<-chan bool, func(), error) { return nil, func() {}, nil }
+
+	path := filepath.Join(t.TempDir(), "manual")
+	var (
+		mu      sync.Mutex
+		changed int
+	)
+	checker := sidecar.NewCheckerFunc("manual", func(ctx context.Context) (bool, string, error) {
+		if filewatch.Changed(ctx) {
+			mu.Lock()
+			changed++
+			mu.Unlock()
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return false, "", nil
+		}
+		return true, string(data), nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, checker, sidecar.Options{PollInterval: time.Hour}, Config{
+			NewLock: func(ctx context.Context, what, who, mode string) (inhibitor.Lock, error) { return fake, nil },
+			Watch:   []string{path},
+		})
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	waitFor := func(want bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if held, _ := fake.Held(); held == want {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("lock held != %v long before the next poll", want)
+	}
+
+	os.WriteFile(path, []byte("testing the nightly update"), 0600)
+	waitFor(true)
+	waitForReason := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if _, why := fake.Held(); why == want {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		_, why := fake.Held()
+		t.Fatalf("why = %q, want %q", why, want)
+	}
+	waitForReason("testing the nightly update")
+
+	// A new reason replaces the lock without releasing it
+	os.WriteFile(path, []byte("restoring a backup"), 0600)
+	waitForReason("restoring a backup")
+	os.Remove(path)
+	waitFor(false)
+
+	mu.Lock()
+	if changed == 0 {
+		t.Error("checks after a change weren't marked with filewatch.WithChanged")
+	}
+	mu.Unlock()
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run: %v", err)
+	}
+}
+
+func TestRunStatus(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		sent []string
+	)
+	notifyFunc = func(state string) (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, state)
+		return true, nil
+	}
+	subscribeShutdown = func() (<-chan bool, func(), error) { return nil, func() {}, nil }
+
+	// busy, busy for a new reason, error, idle, error, idle...
+	results := []struct {
+		busy   bool
+		reason string
+		err    error
+	}{
+		{true, "md0 rebuilding", nil},
+		{true, "md0 checking", nil},
+		{false, "", errors.New("mdstat unreadable")},
+		{false, "", nil},
+		{false, "", errors.New("mdstat unreadable")},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runs := 0
+	checker := sidecar.NewCheckerFunc("raid", func(context.Context) (bool, string, error) {
+		defer func() { runs++ }()
+		if runs < len(results) {
+			return results[runs].busy, results[runs].reason, results[runs].err
+		}
+		if runs == len(results)+1 {
+			cancel()
+		}
+		return false, "", nil
+	})
+
+	fake := &fakeLock{}
+	pub := &fakePublisher{}
+	var idle int
+	err := Run(ctx, checker, sidecar.Options{
+		PollInterval: time.Millisecond,
+		NotifyStatus: true,
+		OnIdle:       func() { idle++ },
+	}, Config{
+		NewLock:      func(ctx context.Context, what, who, mode string) (inhibitor.Lock, error) { return fake, nil },
+		StatusPrefix: "Dry run: ",
+		Publisher:    pub,
+	})
+	if err != nil {
+		t.Fatalf("Run = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{
+		"STATUS=Dry run: Starting",
+		"STATUS=Dry run: Busy: md0 rebuilding",
+		"STATUS=Dry run: Busy: md0 checking",
+		"STATUS=Dry run: Check error: mdstat unreadable",
+		"STATUS=Dry run: Idle",
+		"STATUS=Dry run: Check error: mdstat unreadable",
+		"STATUS=Dry run: Idle",
+		"STOPPING=1",
+	}
+	if !reflect.DeepEqual(sent, want) {
+		t.Errorf("sent %q,\nwant %q", sent, want)
+	}
+	if idle != 1 {
+		t.Errorf("OnIdle called %d times, want 1", idle)
+	}
+
+	// Every check is published, failed ones with the state they kept
+	var reasons []string
+	for _, s := range pub.states {
+		if s.Who != "raid" || s.What != "shutdown" || s.Held != (s.Reason != "") {
+			t.Errorf("published %+v", s)
+		}
+		reasons = append(reasons, s.Reason)
+	}
+	if want := []string{"md0 rebuilding", "md0 checking", "md0 checking", "", "", ""}; len(reasons) < len(want) || !reflect.DeepEqual(reasons[:len(want)], want) {
+		t.Errorf("published reasons %q, want %q first", reasons, want)
+	}
+}
+
+// fakePublisher records the states published
+type fakePublisher struct {
+	states []check.State
+}
+
+func (f *fakePublisher) Publish(ctx context.Context, state check.State) error {
+	f.states = append(f.states, state)
+	return nil
+}
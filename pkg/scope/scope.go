@@ -0,0 +1,93 @@
+// Package scope checks that a sidecar's credentials reach no further than
+// its check needs. A sidecar only reads, so a leaked admin key gives away
+// far more than the sidecar ever uses.
+//
+// Clients that can tell offer a probe reporting the access their
+// credential has beyond what the check needs, e.g. an Immich API key with
+// every permission where job.read would do; Verify runs the probes once at
+// startup and warns, or refuses to start.
+package scope
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+)
+
+// Mode says what to do about a credential with more access than needed
+type Mode string
+
+const (
+	// Off skips the probes
+	Off Mode = "off"
+	// Warn logs a warning and carries on
+	Warn Mode = "warn"
+	// Enforce refuses to start
+	Enforce Mode = "enforce"
+)
+
+// ParseMode parses a mode, e.g. from CREDENTIAL_SCOPE; "" is Warn.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return Warn, nil
+	case Off, Warn, Enforce:
+		return m, nil
+	}
+	return "", fmt.Errorf("invalid credential scope mode %q: want off, warn or enforce", s)
+}
+
+// Probe reports what a credential can do beyond what the check needs, e.g.
+// "all permissions", or "" if nothing.
+type Probe func(ctx context.Context) (excess string, err error)
+
+// Credential is a credential to check
+type Credential struct {
+	// Option is the setting holding it, e.g. "IMMICH_API_KEY"
+	Option string
+	// Need is the least access that suffices, e.g. "a key with only the
+	// job.read permission"
+	Need  string
+	Probe Probe
+}
+
+// probeTimeout bounds each probe
+const probeTimeout = 10 * time.Second
+
+// Verify probes each credential and logs a warning for any with more
+// access than it needs; with Enforce it returns an error naming them
+// instead. A probe that fails, e.g. against a server too old to answer or
+// one that isn't up yet, can't tell either way and is only logged at debug
+// level.
+func Verify(ctx context.Context, mode Mode, creds ...Credential) error {
+	if mode == Off {
+		return nil
+	}
+	var errs []error
+	for _, c := range creds {
+		probeCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+		excess, err := c.Probe(probeCtx)
+		cancel()
+		if err != nil {
+			logging.Debugf("could not check the scope of %s: %v", c.Option, err)
+			continue
+		}
+		if excess == "" {
+			continue
+		}
+		msg := fmt.Sprintf("%s grants %s; %s is enough", c.Option, excess, c.Need)
+		if mode == Enforce {
+			errs = append(errs, errors.New(msg))
+			continue
+		}
+		logging.Warnf("%s", msg)
+	}
+	if len(errs) > 0 {
+		errs = append(errs, errors.New("set CREDENTIAL_SCOPE=warn to start anyway"))
+	}
+	return errors.Join(errs...)
+}
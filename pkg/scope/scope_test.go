@@ -0,0 +1,53 @@
+package scope
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	admin := Credential{
+		Option: "IMMICH_API_KEY",
+		Need:   "a key with only the job.read permission",
+		Probe:  func(context.Context) (string, error) { return "all permissions", nil },
+	}
+	scoped := Credential{
+		Option: "ELASTICSEARCH_API_KEY",
+		Probe:  func(context.Context) (string, error) { return "", nil },
+	}
+	unknown := Credential{
+		Option: "OLD_API_KEY",
+		Probe:  func(context.Context) (string, error) { return "", errors.New("404") },
+	}
+	ctx := context.Background()
+
+	if err := Verify(ctx, Warn, admin, scoped, unknown); err != nil {
+		t.Errorf("Verify(Warn) = %v, want warnings only", err)
+	}
+	err := Verify(ctx, Enforce, admin, scoped, unknown)
+	if err == nil || !strings.Contains(err.Error(), "IMMICH_API_KEY grants all permissions") {
+		t.Errorf("Verify(Enforce) = %v, want the admin key refused", err)
+	}
+	if err := Verify(ctx, Enforce, scoped, unknown); err != nil {
+		t.Errorf("Verify(Enforce) = %v, want scoped and unknown keys accepted", err)
+	}
+
+	probed := false
+	off := Credential{Probe: func(context.Context) (string, error) { probed = true; return "", nil }}
+	if Verify(ctx, Off, off); probed {
+		t.Error("Verify(Off) probed")
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for in, want := range map[string]Mode{"": Warn, "warn": Warn, "Enforce": Enforce, "off": Off} {
+		if got, err := ParseMode(in); err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseMode("strict"); err == nil {
+		t.Error("ParseMode(strict) succeeded")
+	}
+}
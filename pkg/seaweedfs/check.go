@@ -0,0 +1,67 @@
+package seaweedfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Checker implements check.Checker for a SeaweedFS cluster.
+// Returns an error while the masters have no leader, fewer than
+// VolumeServers volume servers are connected, or volumes are short of
+// replicas (a repair or balance is still copying data, or a server is down),
+// since rebooting then could make data unavailable.
+type Checker struct {
+	Client *Client
+	// VolumeServers is the expected number of volume servers, 0 = don't check
+	VolumeServers int
+}
+
+// NewChecker creates a SeaweedFS checker.
+func NewChecker(client *Client, volumeServers int) *Checker {
+	return &Checker{Client: client, VolumeServers: volumeServers}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "seaweedfs"
+}
+
+// Check returns nil if the cluster is settled, error otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	reasons, err := c.Activity(ctx)
+	if err != nil {
+		// SeaweedFS being down shouldn't hold up reboots
+		return nil
+	}
+	if len(reasons) > 0 {
+		return errors.New(strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// Activity returns a description of each reason the cluster isn't settled.
+func (c *Checker) Activity(ctx context.Context) ([]string, error) {
+	status, err := c.Client.ClusterStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if status.Leader == "" {
+		return []string{"no master leader elected"}, nil
+	}
+
+	topo, err := c.Client.Topology(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reasons []string
+	if c.VolumeServers > 0 && len(topo) < c.VolumeServers {
+		reasons = append(reasons, fmt.Sprintf("%d/%d volume servers connected", len(topo), c.VolumeServers))
+	}
+	if ids := topo.UnderReplicated(); len(ids) > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d volume(s) under-replicated", len(ids)))
+	}
+	return reasons, nil
+}
@@ -0,0 +1,145 @@
+// Package seaweedfs provides a client for the SeaweedFS master HTTP API,
+// used to detect missing volume servers and volumes short of replicas.
+package seaweedfs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ClusterStatus is the /cluster/status response
+type ClusterStatus struct {
+	IsLeader bool     `json:"IsLeader"`
+	Leader   string   `json:"Leader"`
+	Peers    []string `json:"Peers"`
+}
+
+// Volume is a volume replica as reported by /vol/status
+type Volume struct {
+	ID               int              `json:"Id"`
+	Collection       string           `json:"Collection"`
+	ReplicaPlacement ReplicaPlacement `json:"ReplicaPlacement"`
+	ReadOnly         bool             `json:"ReadOnly"`
+}
+
+// ReplicaPlacement is a volume's replication setting, e.g. "010"
+type ReplicaPlacement struct {
+	SameRackCount       int `json:"SameRackCount"`
+	DiffRackCount       int `json:"DiffRackCount"`
+	DiffDataCenterCount int `json:"DiffDataCenterCount"`
+}
+
+// Copies returns the number of replicas the placement asks for.
+func (p ReplicaPlacement) Copies() int {
+	return 1 + p.SameRackCount + p.DiffRackCount + p.DiffDataCenterCount
+}
+
+// Topology lists the volumes on each volume server, by server URL
+type Topology map[string][]Volume
+
+// UnderReplicated returns the IDs of volumes with fewer replicas than their
+// placement asks for, e.g. while "volume.fix.replication" or a balance is
+// still copying them, or because a server is down.
+func (t Topology) UnderReplicated() []int {
+	type volumeKey struct {
+		collection string
+		id         int
+	}
+	replicas := make(map[volumeKey]int)
+	want := make(map[volumeKey]int)
+	var order []volumeKey
+	for _, volumes := range t {
+		for _, v := range volumes {
+			k := volumeKey{v.Collection, v.ID}
+			if _, ok := replicas[k]; !ok {
+				order = append(order, k)
+			}
+			replicas[k]++
+			want[k] = v.ReplicaPlacement.Copies()
+		}
+	}
+
+	var ids []int
+	for _, k := range order {
+		if replicas[k] < want[k] {
+			ids = append(ids, k.id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Client handles communication with a SeaweedFS master (port 9333)
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a new SeaweedFS master client.
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// ClusterStatus returns the master's view of the master cluster.
+func (c *Client) ClusterStatus(ctx context.Context) (*ClusterStatus, error) {
+	var status ClusterStatus
+	if err := c.get(ctx, "/cluster/status", &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Topology returns the volumes on every volume server known to the master.
+func (c *Client) Topology(ctx context.Context) (Topology, error) {
+	// Volumes are nested by data center, then rack, then server
+	var status struct {
+		Volumes struct {
+			DataCenters map[string]map[string]map[string][]Volume `json:"DataCenters"`
+		} `json:"Volumes"`
+	}
+	if err := c.get(ctx, "/vol/status", &status); err != nil {
+		return nil, err
+	}
+
+	topo := make(Topology)
+	for _, racks := range status.Volumes.DataCenters {
+		for _, servers := range racks {
+			for url, volumes := range servers {
+				topo[url] = volumes
+			}
+		}
+	}
+	return topo, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
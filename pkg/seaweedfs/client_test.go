@@ -0,0 +1,93 @@
+package seaweedfs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// volStatus builds a /vol/status response with every server in dc1/rack1
+func volStatus(servers map[string]string) string {
+	var parts []string
+	for url, volumes := range servers {
+		parts = append(parts, `"`+url+`": [`+volumes+`]`)
+	}
+	return `{"Version": "3.68", "Volumes": {"DataCenters": {"dc1": {"rack1": {` + strings.Join(parts, ",") + `}}}}}`
+}
+
+func TestChecker_Activity(t *testing.T) {
+	replicated := func(id string) string {
+		return `{"Id": ` + id + `, "Collection": "", "ReplicaPlacement": {"SameRackCount": 1}}`
+	}
+	single := `{"Id": 9, "Collection": "logs", "ReplicaPlacement": {}}`
+
+	tests := []struct {
+		name    string
+		cluster string
+		volumes string
+		servers int
+		want    string
+	}{
+		{
+			name:    "settled",
+			cluster: `{"IsLeader": true, "Leader": "master1:9333"}`,
+			volumes: volStatus(map[string]string{
+				"vs1:8080": replicated("1") + "," + replicated("2") + "," + single,
+				"vs2:8080": replicated("1") + "," + replicated("2"),
+			}),
+			servers: 2,
+			want:    "",
+		},
+		{
+			name:    "no leader",
+			cluster: `{"IsLeader": false, "Leader": ""}`,
+			want:    "no master leader elected",
+		},
+		{
+			name:    "volume server down",
+			cluster: `{"IsLeader": true, "Leader": "master1:9333"}`,
+			volumes: volStatus(map[string]string{
+				"vs1:8080": replicated("1") + "," + replicated("2") + "," + single,
+			}),
+			servers: 2,
+			want:    "1/2 volume servers connected; 2 volume(s) under-replicated",
+		},
+		{
+			name:    "replication being fixed",
+			cluster: `{"IsLeader": true, "Leader": "master1:9333"}`,
+			volumes: volStatus(map[string]string{
+				"vs1:8080": replicated("1") + "," + replicated("2"),
+				"vs2:8080": replicated("1"),
+			}),
+			want: "1 volume(s) under-replicated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/cluster/status":
+					w.Write([]byte(tt.cluster))
+				case "/vol/status":
+					w.Write([]byte(tt.volumes))
+				default:
+					t.Errorf("unexpected path: %s", r.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			checker := NewChecker(NewClient(server.URL, 5*time.Second), tt.servers)
+			reasons, err := checker.Activity(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := strings.Join(reasons, "; "); got != tt.want {
+				t.Errorf("activity = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
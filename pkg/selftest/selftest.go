@@ -0,0 +1,117 @@
+// Package selftest runs a sidecar's checks once and reports whether each
+// one could reach what it checks, for ExecStartPre= or after editing the
+// environment file:
+//
+//	ExecStartPre=/usr/local/bin/jellyfin-sidecar check-config
+//
+// Configuration is parsed by the sidecar before the self-test starts, so a
+// missing or malformed setting already exits non-zero. The self-test then
+// catches what parsing can't: a wrong API key, an unreachable URL, an
+// unreadable /proc/mdstat.
+//
+// Most checkers don't block shutdown when their service is unreachable,
+// and so hide the error from the poll loop. They pass it through
+// Unreachable instead, which surfaces it during a self-test, and to
+// wrappers that watch for failures through WithReporter.
+package selftest
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+)
+
+type (
+	strictKey   struct{}
+	reporterKey struct{}
+)
+
+// Unreachable is what a checker returns for an error it would rather not
+// block shutdown on: nil normally, but err itself during a self-test.
+func Unreachable(ctx context.Context, err error) error {
+	if report, ok := ctx.Value(reporterKey{}).(func(error)); ok && err != nil {
+		report(err)
+	}
+	if strict, _ := ctx.Value(strictKey{}).(bool); strict {
+		return err
+	}
+	return nil
+}
+
+// WithReporter returns a context under which Unreachable passes each error
+// it hides to report, e.g. for check.Backoff to count a service that is
+// down as failing.
+func WithReporter(ctx context.Context, report func(error)) context.Context {
+	return context.WithValue(ctx, reporterKey{}, report)
+}
+
+// Result is one check's outcome
+type Result struct {
+	Name    string
+	Busy    bool
+	Reason  string
+	Err     error
+	Elapsed time.Duration
+}
+
+// Run checks each checker once, with errors that Unreachable would hide
+// passed through.
+func Run(ctx context.Context, checkers []sidecar.Checker, timeout time.Duration) []Result {
+	ctx = context.WithValue(ctx, strictKey{}, true)
+	results := make([]Result, len(checkers))
+	for i, c := range checkers {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		busy, reason, err := c.Check(checkCtx)
+		results[i] = Result{Name: c.Name(), Busy: busy, Reason: reason, Err: err, Elapsed: time.Since(start)}
+		cancel()
+	}
+	return results
+}
+
+// Print writes one line per result and reports whether they all passed.
+func Print(w io.Writer, results []Result) (ok bool) {
+	ok = true
+	for _, r := range results {
+		elapsed := r.Elapsed.Round(time.Millisecond)
+		switch {
+		case r.Err != nil:
+			fmt.Fprintf(w, "FAIL  %s: %v (%s)\n", r.Name, r.Err, elapsed)
+			ok = false
+		case r.Busy:
+			fmt.Fprintf(w, "ok    %s: busy: %s (%s)\n", r.Name, r.Reason, elapsed)
+		default:
+			fmt.Fprintf(w, "ok    %s: idle (%s)\n", r.Name, elapsed)
+		}
+	}
+	return ok
+}
+
+// Main is the "check-config" subcommand: it parses args, runs the
+// self-test, prints the results to stdout and returns the process exit
+// code, non-zero if any check failed.
+//
+//	if flag.Arg(0) == "check-config" {
+//		os.Exit(selftest.Main(flag.Args()[1:], checker))
+//	}
+func Main(args []string, checkers ...sidecar.Checker) int {
+	fs := flag.NewFlagSet("check-config", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 30*time.Second, "timeout for each check")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s [flags] check-config [-timeout d]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if !Print(os.Stdout, Run(context.Background(), checkers, *timeout)) {
+		return 1
+	}
+	return 0
+}
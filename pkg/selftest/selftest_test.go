@@ -0,0 +1,64 @@
+package selftest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+)
+
+func TestUnreachable(t *testing.T) {
+	err := errors.New("connection refused")
+	if got := Unreachable(context.Background(), err); got != nil {
+		t.Errorf("Unreachable outside a self-test = %v, want nil", got)
+	}
+	ctx := context.WithValue(context.Background(), strictKey{}, true)
+	if got := Unreachable(ctx, err); got != err {
+		t.Errorf("Unreachable in a self-test = %v, want %v", got, err)
+	}
+
+	var reported error
+	ctx = WithReporter(context.Background(), func(err error) { reported = err })
+	if got := Unreachable(ctx, err); got != nil || reported != err {
+		t.Errorf("Unreachable with a reporter = %v, reported %v", got, reported)
+	}
+}
+
+func TestRun(t *testing.T) {
+	// Swallows its error as the sidecars do for an unreachable service
+	down := sidecar.NewCheckerFunc("jellyfin", func(ctx context.Context) (bool, string, error) {
+		return false, "", Unreachable(ctx, errors.New("dial tcp: connection refused"))
+	})
+	busy := sidecar.NewCheckerFunc("raid", func(ctx context.Context) (bool, string, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("check run without a timeout")
+		}
+		return true, "md0 rebuilding", nil
+	})
+	idle := sidecar.NewCheckerFunc("manual", func(context.Context) (bool, string, error) {
+		return false, "", nil
+	})
+
+	results := Run(context.Background(), []sidecar.Checker{down, busy, idle}, time.Second)
+	var buf bytes.Buffer
+	if Print(&buf, results) {
+		t.Error("Print reported success with a failing check")
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines:\n%s", len(lines), buf.String())
+	}
+	for i, want := range []string{"FAIL  jellyfin: dial tcp: connection refused", "ok    raid: busy: md0 rebuilding", "ok    manual: idle"} {
+		if !strings.HasPrefix(lines[i], want) {
+			t.Errorf("line %d = %q, want prefix %q", i, lines[i], want)
+		}
+	}
+
+	if !Print(&buf, results[1:]) {
+		t.Error("Print reported failure with passing checks")
+	}
+}
@@ -0,0 +1,97 @@
+package sidecarmain
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+)
+
+// Env returns the environment variable key, or fallback if it is unset or
+// empty.
+func Env(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// Duration returns the environment variable key parsed as a duration, or
+// fallback if it is unset or doesn't parse.
+func Duration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fallback
+	}
+	return d
+}
+
+// Int returns the environment variable key parsed as an integer, or
+// fallback if it is unset or doesn't parse.
+func Int(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+// RequireEnv returns the environment variable key, exiting if it is unset
+// or empty.
+func RequireEnv(key string) string {
+	v := os.Getenv(key)
+	if v == "" {
+		logging.Fatalf("%s is required", key)
+	}
+	return v
+}
+
+// Secret returns the environment variable key, or else the trimmed
+// contents of the file named by key_FILE, e.g. a systemd credential. It
+// exits if that file can't be read.
+func Secret(key string) string {
+	if v := Env(key, ""); v != "" {
+		return v
+	}
+	file := Env(key+"_FILE", "")
+	if file == "" {
+		return ""
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		logging.Fatalf("reading %s: %v", key+"_FILE", err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// RequireSecret is Secret, exiting if neither key nor key_FILE is set.
+func RequireSecret(key string) string {
+	v := Secret(key)
+	if v == "" {
+		logging.Fatalf("%s or %s_FILE required", key, key)
+	}
+	return v
+}
+
+// SplitList splits a comma-separated list, trimming spaces around each
+// item. An empty string is an empty list.
+func SplitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts
+}
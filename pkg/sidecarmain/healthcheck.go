@@ -0,0 +1,73 @@
+package sidecarmain
+
+import (
+	"context"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/healthcheck"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+)
+
+// Healthcheck is a one-shot health check, e.g. a sidecar's "healthcheck"
+// argument for greenboot: Check is retried with backoff until it passes or
+// Budget runs out, since services are often still starting when it runs.
+type Healthcheck struct {
+	// Name labels the report and the HEALTH_HISTORY line
+	Name string
+	// Budget is how long to keep retrying; HEALTH_RETRY_INITIAL and
+	// HEALTH_RETRY_MAX set the backoff
+	Budget time.Duration
+	// Check returns nil once the service is healthy
+	Check func(ctx context.Context) error
+	// Then, if set, runs once Check has passed and may change the result,
+	// e.g. to sample again a while later
+	Then func(ctx context.Context, res *healthcheck.Result)
+	// Severity says whether a failure fails the boot; by default it is
+	// read from <Name>_HEALTH_SEVERITY, or HEALTH_SEVERITY
+	Severity func() (healthcheck.Severity, error)
+}
+
+// Run runs the check, reports the result and records it in
+// HEALTH_HISTORY. args are the -retries and -retry-interval flags, e.g.
+// flag.Args()[1:] after "healthcheck". Returns the process exit code.
+func (h Healthcheck) Run(args []string) int {
+	policy := healthcheck.DefaultPolicy
+	policy.Budget = h.Budget
+	policy.Initial = Duration("HEALTH_RETRY_INITIAL", policy.Initial)
+	policy.Max = Duration("HEALTH_RETRY_MAX", policy.Max)
+
+	severityFromEnv := h.Severity
+	if severityFromEnv == nil {
+		severityFromEnv = func() (healthcheck.Severity, error) {
+			return healthcheck.SeverityFromEnv(h.Name)
+		}
+	}
+	severity, err := severityFromEnv()
+	if err != nil {
+		logging.Fatalf("%v", err)
+	}
+
+	policy, err = healthcheck.ParseFlags(args, policy)
+	if err != nil {
+		logging.Fatalf("%v", err)
+	}
+
+	ctx := context.Background()
+	res := healthcheck.Run(ctx, h.Name, policy, h.Check)
+	if res.Healthy && h.Then != nil {
+		h.Then(ctx, &res)
+	}
+	res.Severity = severity
+	healthcheck.Report(res)
+
+	// HEALTH_HISTORY keeps a line per boot to compare against
+	prev, err := healthcheck.Record(Env("HEALTH_HISTORY", ""), res)
+	if err != nil {
+		logging.Warnf("HEALTH_HISTORY: %v", err)
+	}
+	if prev != "" {
+		logging.Infof("previous: %s", prev)
+	}
+
+	return res.ExitCode()
+}
@@ -0,0 +1,331 @@
+// Package sidecarmain is the main function the sidecars share: it parses
+// the common flags, wraps a check in the common behaviour configured from
+// the environment (timeouts, backoff, low-power polling, debouncing, flap
+// damping, gating, silences, metrics, notifications, the force-allow
+// override, the status endpoint, readiness and MQTT) and runs it until
+// stopped.
+//
+// A sidecar's main calls Init first, reads its own configuration, builds
+// its check and hands it to Run:
+//
+//	sidecarmain.Init()
+//	checker := &acmeChecker{...}
+//	sidecarmain.Run(checker)
+package sidecarmain
+
+import (
+	"context"
+	"flag"
+	"os"
+	"sync"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/audit"
+	"github.com/addisonbair/homelab-sidecars/pkg/bench"
+	"github.com/addisonbair/homelab-sidecars/pkg/check"
+	"github.com/addisonbair/homelab-sidecars/pkg/convergence"
+	"github.com/addisonbair/homelab-sidecars/pkg/dryrun"
+	"github.com/addisonbair/homelab-sidecars/pkg/flap"
+	"github.com/addisonbair/homelab-sidecars/pkg/gate"
+	"github.com/addisonbair/homelab-sidecars/pkg/hysteresis"
+	"github.com/addisonbair/homelab-sidecars/pkg/idleexit"
+	"github.com/addisonbair/homelab-sidecars/pkg/impact"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/lowpower"
+	"github.com/addisonbair/homelab-sidecars/pkg/metrics"
+	"github.com/addisonbair/homelab-sidecars/pkg/mqtt"
+	"github.com/addisonbair/homelab-sidecars/pkg/notify"
+	"github.com/addisonbair/homelab-sidecars/pkg/override"
+	"github.com/addisonbair/homelab-sidecars/pkg/profile"
+	"github.com/addisonbair/homelab-sidecars/pkg/readiness"
+	"github.com/addisonbair/homelab-sidecars/pkg/runloop"
+	"github.com/addisonbair/homelab-sidecars/pkg/selftest"
+	"github.com/addisonbair/homelab-sidecars/pkg/silence"
+	"github.com/addisonbair/homelab-sidecars/pkg/status"
+)
+
+var (
+	dryRun       = new(bool)
+	checkTimeout = new(time.Duration)
+)
+
+// Init applies SIDECAR_PROFILE and parses the flags every sidecar takes.
+// Call it before reading anything else from the environment, so the
+// profile's overrides are seen.
+func Init() {
+	// SIDECAR_PROFILE picks a named set of overrides from a shared environment file
+	if err := profile.Apply(os.Getenv("SIDECAR_PROFILE")); err != nil {
+		logging.Fatalf("SIDECAR_PROFILE: %v", err)
+	}
+
+	// -dry-run (or DRY_RUN=true) only logs when the inhibitor would be taken
+	dryRun = flag.Bool("dry-run", Env("DRY_RUN", "false") == "true",
+		"run the checks but only log when the inhibitor would be taken or released")
+
+	// -check-timeout (or CHECK_TIMEOUT) fails a check that takes longer,
+	// reporting "timed out after" that long
+	checkTimeout = flag.Duration("check-timeout", Duration("CHECK_TIMEOUT", 0),
+		"fail a check that takes longer than this (0 for no limit)")
+
+	// -log-format and -log-level (or LOG_FORMAT and LOG_LEVEL) configure logging
+	if err := logging.ParseFlags(); err != nil {
+		logging.Fatalf("%v", err)
+	}
+}
+
+// DryRun reports whether -dry-run (or DRY_RUN=true) was given, for checks
+// that act on their own, e.g. by writing the force-allow file.
+func DryRun() bool {
+	return *dryRun
+}
+
+// Notifier returns the notifier configured in the environment, or nil if
+// there is none. Every call returns the same one.
+var Notifier = sync.OnceValue(notify.FromEnv)
+
+// Options are what differs between the sidecars' run loops
+type Options struct {
+	// InhibitWhat is the default for INHIBIT_WHAT, "shutdown:sleep" if
+	// empty
+	InhibitWhat string
+	// Watch are files whose changes run the check straight away, along
+	// with the force-allow file; polling only reconciles what the watches
+	// miss
+	Watch []string
+	// OnBusy and OnIdle are called when the inhibitor is taken and
+	// released, along with the notifications
+	OnBusy func(reason string)
+	OnIdle func()
+	// Start, if set, is called with the fully wrapped check just before
+	// the loop starts, e.g. to follow suspend and resume; it must not
+	// block
+	Start func(ctx context.Context, wrapped sidecar.Checker, pollInterval time.Duration)
+	// AlwaysIdle is for checks that never report busy and act on their
+	// own instead, such as force-allowing shutdown: polls are never
+	// skipped in low-power mode or backed off by default, since a quiet
+	// CPU or a failing sensor says nothing about what they watch, and the
+	// wrappers that only shape when the inhibitor is taken are left out
+	AlwaysIdle bool
+}
+
+// Run runs checker with the default Options. sources are metrics the check
+// provides itself, served and written along with the common ones.
+func Run(checker sidecar.Checker, sources ...metrics.Source) {
+	RunWith(checker, Options{}, sources...)
+}
+
+// RunWith wraps checker in the common behaviour and runs it until the
+// process is stopped. The "check-config" and "bench" arguments instead run
+// the check once, or repeatedly, and exit.
+func RunWith(checker sidecar.Checker, opts Options, sources ...metrics.Source) {
+	notifier := Notifier()
+
+	// "check-config" runs the check once and reports errors it would
+	// otherwise ignore, e.g. for ExecStartPre=; "bench" runs it repeatedly
+	// and reports its latency and allocations
+	switch flag.Arg(0) {
+	case "check-config":
+		os.Exit(selftest.Main(flag.Args()[1:], checker))
+	case "bench":
+		os.Exit(bench.Main(flag.Args()[1:], checker))
+	}
+
+	if Env("AUDIT_SHUTDOWN", "false") == "true" {
+		go func() {
+			if err := audit.Log(context.Background(), checker.Name(), notifier); err != nil {
+				logging.Warnf("shutdown audit disabled: %v", err)
+			}
+		}()
+	}
+
+	var wrapped sidecar.Checker = check.SidecarTimeout(checker, *checkTimeout)
+	sources = append([]metrics.Source(nil), sources...)
+
+	// LOW_POWER=true rounds POLL_INTERVAL up to LOW_POWER_TICK, aligns
+	// polls to the wall clock so the sidecars wake together, and skips
+	// them while the CPU is idle
+	lowPower, err := lowpower.ConfigFromEnv()
+	if err != nil {
+		logging.Fatalf("%v", err)
+	}
+	pollInterval := lowPower.Interval(Duration("POLL_INTERVAL", 30*time.Second))
+
+	// After BACKOFF_AFTER consecutive failures the service is polled less
+	// often, backing off up to BACKOFF_MAX, until it recovers
+	backoffAfter := 3
+	if opts.AlwaysIdle {
+		backoffAfter = 0
+	}
+	wrapped = check.Backoff(wrapped, Int("BACKOFF_AFTER", backoffAfter), pollInterval, Duration("BACKOFF_MAX", 10*time.Minute))
+	if !opts.AlwaysIdle {
+		wrapped = lowpower.Wrap(wrapped, lowPower)
+	}
+
+	// IMPACT says what the inhibitor means for the household, e.g. "Plex is
+	// unavailable for the whole house", and is added to the busy reason
+	wrapped = impact.Wrap(wrapped, Env("IMPACT", ""))
+
+	// Report how long after boot the check first passed
+	if tracker := convergence.Track(wrapped, notifier, Duration("BOOT_REPORT_WINDOW", 15*time.Minute)); tracker != nil {
+		wrapped = tracker
+		sources = append(sources, tracker)
+	}
+
+	if !opts.AlwaysIdle {
+		wrapped, sources = shape(wrapped, checker.Name(), notifier, sources)
+	}
+
+	wrapped = metrics.Textfile(wrapped, Env("METRICS_TEXTFILE", ""), sources...)
+	wrapped = notify.Errors(wrapped, notifier)
+
+	if !opts.AlwaysIdle {
+		// Stand down while the UPS sidecar force-allows shutdown
+		wrapped = override.Wrap(wrapped, Env("FORCE_ALLOW_FILE", override.DefaultPath))
+	}
+
+	// STATUS_ADDR serves /healthz, /readyz and /status over HTTP, e.g.
+	// 127.0.0.1:9280; STATUS_TOKEN and STATUS_TLS_* add auth and TLS
+	served, err := status.Serve(wrapped, status.ConfigFromEnv(), sources...)
+	if err != nil {
+		logging.Fatalf("status server: %v", err)
+	}
+	wrapped = served
+
+	// EXIT_AFTER_IDLE exits once the check has been clear that long, for
+	// running the sidecar as a one-shot "wait until idle" step
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if d := Duration("EXIT_AFTER_IDLE", 0); d > 0 && !opts.AlwaysIdle {
+		wrapped = idleexit.Wrap(wrapped, d, cancel)
+	}
+
+	// READY_AFTER_CHECK holds back READY=1 until the first check succeeds
+	notifyReady := Env("NOTIFY_READY", "true") == "true"
+	if notifyReady && Env("READY_AFTER_CHECK", "false") == "true" {
+		wrapped = readiness.Gate(wrapped)
+		notifyReady = false
+	}
+
+	if opts.Start != nil {
+		opts.Start(ctx, wrapped, pollInterval)
+	}
+
+	inhibitWhat := opts.InhibitWhat
+	if inhibitWhat == "" {
+		inhibitWhat = "shutdown:sleep"
+	}
+	runOpts := sidecar.Options{
+		InhibitWhat:  Env("INHIBIT_WHAT", inhibitWhat),
+		PollInterval: pollInterval,
+		NotifyReady:  notifyReady,
+		NotifyStatus: true,
+	}
+	// An always idle check never takes the inhibitor, so there is nothing
+	// to announce
+	if !opts.AlwaysIdle {
+		runOpts.OnBusy = chainBusy(notify.OnBusy(notifier, checker.Name()), opts.OnBusy)
+		runOpts.OnIdle = chainIdle(notify.OnIdle(notifier, checker.Name()), opts.OnIdle)
+	}
+
+	var loop runloop.Config
+	if len(opts.Watch) > 0 {
+		loop.Watch = append(opts.Watch, Env("FORCE_ALLOW_FILE", override.DefaultPath))
+	}
+	// MQTT_URL publishes the inhibitor's state, with Home Assistant
+	// discovery so it shows up as a binary sensor
+	publisher, err := mqtt.FromEnv(checker.Name())
+	if err != nil {
+		logging.Fatalf("MQTT: %v", err)
+	}
+	if publisher != nil {
+		defer publisher.Close()
+		loop.Publisher = publisher
+	}
+	if *dryRun {
+		// OnBusy, OnIdle and the publisher would announce a lock that
+		// isn't taken
+		loop.NewLock = dryrun.NewLock
+		loop.StatusPrefix = dryrun.StatusPrefix
+		loop.Publisher = nil
+		runOpts.OnBusy, runOpts.OnIdle = nil, nil
+	}
+
+	lowPower.Align(ctx, pollInterval)
+	runloop.MustRun(ctx, wrapped, runOpts, loop)
+}
+
+// shape adds the wrappers that decide when the inhibitor follows the
+// check: debouncing, flap damping, gating units and silencing alerts.
+func shape(wrapped sidecar.Checker, name string, notifier notify.Notifier, sources []metrics.Source) (sidecar.Checker, []metrics.Source) {
+	// ACQUIRE_AFTER and RELEASE_AFTER debounce the check: the inhibitor
+	// follows only after that many consecutive busy or idle polls
+	wrapped = hysteresis.Wrap(wrapped, Int("ACQUIRE_AFTER", 1), Int("RELEASE_AFTER", 1))
+
+	// Pin the check to its last stable state if it changes state too often
+	if d := flap.Wrap(wrapped, Int("FLAP_THRESHOLD", 0), Duration("FLAP_STABLE_AFTER", 10*time.Minute), notifier); d != nil {
+		wrapped = d
+		sources = append(sources, d)
+	}
+
+	// GATE_UNITS are stopped while the check is unhealthy (GATE_WHEN, and
+	// GATE_MATCH if set) and started again once it recovers
+	gateConfig, err := gate.ConfigFromEnv(name)
+	if err != nil {
+		logging.Fatalf("%v", err)
+	}
+	gateConfig.DryRun = *dryRun
+	if g := gate.Wrap(wrapped, gateConfig, &gate.Systemd{}, notifier); g != nil {
+		wrapped = g
+	}
+
+	// SILENCE_ALERTMANAGER_URL silences SILENCE_MATCHERS in Alertmanager
+	// while the check is busy for a reason SILENCE_MATCH matches, e.g. a
+	// rebuild started by hand, and expires the silence once it is idle
+	silenceConfig, err := silence.ConfigFromEnv(name)
+	if err != nil {
+		logging.Fatalf("%v", err)
+	}
+	silenceConfig.DryRun = *dryRun
+	if s := silence.Wrap(wrapped, silenceConfig, nil); s != nil {
+		wrapped = s
+	}
+	return wrapped, sources
+}
+
+// chainBusy calls each non-nil callback in turn, or returns nil if there
+// are none.
+func chainBusy(fns ...func(reason string)) func(reason string) {
+	var set []func(string)
+	for _, fn := range fns {
+		if fn != nil {
+			set = append(set, fn)
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	return func(reason string) {
+		for _, fn := range set {
+			fn(reason)
+		}
+	}
+}
+
+// chainIdle is chainBusy for OnIdle.
+func chainIdle(fns ...func()) func() {
+	var set []func()
+	for _, fn := range fns {
+		if fn != nil {
+			set = append(set, fn)
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	return func() {
+		for _, fn := range set {
+			fn()
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package sidecarmain
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/healthcheck"
+)
+
+func TestEnv(t *testing.T) {
+	t.Setenv("SIDECARMAIN_STR", "x")
+	t.Setenv("SIDECARMAIN_DUR", "90s")
+	t.Setenv("SIDECARMAIN_BAD_DUR", "soon")
+	t.Setenv("SIDECARMAIN_INT", "7")
+	t.Setenv("SIDECARMAIN_BAD_INT", "seven")
+
+	if got := Env("SIDECARMAIN_STR", "y"); got != "x" {
+		t.Errorf("Env = %q, want x", got)
+	}
+	if got := Env("SIDECARMAIN_UNSET", "y"); got != "y" {
+		t.Errorf("Env unset = %q, want y", got)
+	}
+	if got := Duration("SIDECARMAIN_DUR", time.Second); got != 90*time.Second {
+		t.Errorf("Duration = %v, want 90s", got)
+	}
+	if got := Duration("SIDECARMAIN_BAD_DUR", time.Second); got != time.Second {
+		t.Errorf("Duration unparsable = %v, want the fallback", got)
+	}
+	if got := Int("SIDECARMAIN_INT", 1); got != 7 {
+		t.Errorf("Int = %d, want 7", got)
+	}
+	if got := Int("SIDECARMAIN_BAD_INT", 1); got != 1 {
+		t.Errorf("Int unparsable = %d, want the fallback", got)
+	}
+
+	file := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(file, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SIDECARMAIN_TOKEN_FILE", file)
+	if got := Secret("SIDECARMAIN_TOKEN"); got != "s3cret" {
+		t.Errorf("Secret from file = %q, want s3cret", got)
+	}
+	t.Setenv("SIDECARMAIN_TOKEN", "direct")
+	if got := Secret("SIDECARMAIN_TOKEN"); got != "direct" {
+		t.Errorf("Secret = %q, want the variable over the file", got)
+	}
+
+	if got := SplitList(" md0, md1 ,md2"); !reflect.DeepEqual(got, []string{"md0", "md1", "md2"}) {
+		t.Errorf("SplitList = %q", got)
+	}
+	if got := SplitList(""); got != nil {
+		t.Errorf("SplitList empty = %q, want nil", got)
+	}
+}
+
+func TestHealthcheck(t *testing.T) {
+	history := filepath.Join(t.TempDir(), "history")
+	t.Setenv("HEALTH_HISTORY", history)
+	t.Setenv("HEALTH_RETRY_INITIAL", "1ms")
+	t.Setenv("SIDECARMAIN_HEALTH_SEVERITY", "")
+	t.Setenv("HEALTH_SEVERITY", "")
+
+	calls := 0
+	h := Healthcheck{
+		Name:   "sidecarmain",
+		Budget: time.Second,
+		Check: func(ctx context.Context) error {
+			calls++
+			if calls < 2 {
+				return errors.New("starting")
+			}
+			return nil
+		},
+	}
+	if code := h.Run(nil); code != 0 || calls != 2 {
+		t.Errorf("Run = %d after %d calls, want 0 after 2", code, calls)
+	}
+	if data, err := os.ReadFile(history); err != nil || !strings.Contains(string(data), "sidecarmain") {
+		t.Errorf("HEALTH_HISTORY = %q, %v; want a line for the check", data, err)
+	}
+
+	// Then can fail a check that passed, and a warning severity passes it
+	h.Then = func(ctx context.Context, res *healthcheck.Result) {
+		res.Healthy = false
+		res.Attempts = append(res.Attempts, healthcheck.Attempt{Err: errors.New("grew")})
+	}
+	if code := h.Run([]string{"-retries", "0"}); code == 0 {
+		t.Error("Run = 0, want a failure from Then")
+	}
+	h.Severity = func() (healthcheck.Severity, error) {
+		return healthcheck.SeverityWarning, nil
+	}
+	if code := h.Run(nil); code != 0 {
+		t.Errorf("Run with warning severity = %d, want 0", code)
+	}
+}
+
+func TestChain(t *testing.T) {
+	if chainBusy(nil, nil) != nil || chainIdle(nil) != nil {
+		t.Error("expected nil callbacks when none are set")
+	}
+
+	var got []string
+	busy := chainBusy(
+		func(reason string) { got = append(got, "notify "+reason) },
+		nil,
+		func(reason string) { got = append(got, "event "+reason) },
+	)
+	busy("rebuilding")
+	if want := []string{"notify rebuilding", "event rebuilding"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,202 @@
+package silence
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Matcher selects alerts by a label, in Alertmanager's API form
+type Matcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual bool   `json:"isEqual"`
+}
+
+// String formats the matcher as in PromQL, e.g. `alertname=~"MdRaid.*"`.
+func (m Matcher) String() string {
+	op := "="
+	switch {
+	case m.IsEqual && m.IsRegex:
+		op = "=~"
+	case !m.IsEqual && m.IsRegex:
+		op = "!~"
+	case !m.IsEqual:
+		op = "!="
+	}
+	return m.Name + op + fmt.Sprintf("%q", m.Value)
+}
+
+// ParseMatchers parses comma-separated matchers as in PromQL or amtool,
+// e.g. `alertname=~"MdRaid.*", instance="nas:9100"`; quotes around values
+// are optional unless they contain a comma.
+func ParseMatchers(s string) ([]Matcher, error) {
+	var matchers []Matcher
+	for _, item := range splitOutsideQuotes(strings.Trim(strings.TrimSpace(s), "{}")) {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.IndexAny(item, "=!")
+		if i <= 0 {
+			return nil, fmt.Errorf("matcher %q: want <label><op><value>", item)
+		}
+		m := Matcher{Name: strings.TrimSpace(item[:i]), IsEqual: true}
+		rest, opLen := item[i:], 2
+		switch {
+		case strings.HasPrefix(rest, "=~"):
+			m.IsRegex = true
+		case strings.HasPrefix(rest, "!~"):
+			m.IsRegex, m.IsEqual = true, false
+		case strings.HasPrefix(rest, "!="):
+			m.IsEqual = false
+		case strings.HasPrefix(rest, "="):
+			opLen = 1
+		default:
+			return nil, fmt.Errorf("matcher %q: unknown operator", item)
+		}
+		value := strings.TrimSpace(rest[opLen:])
+		if strings.HasPrefix(value, `"`) {
+			v, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("matcher %q: %w", item, err)
+			}
+			value = v
+		}
+		m.Value = value
+		matchers = append(matchers, m)
+	}
+	if len(matchers) == 0 {
+		return nil, errors.New("no matchers")
+	}
+	return matchers, nil
+}
+
+// splitOutsideQuotes splits s at commas that aren't inside double quotes
+func splitOutsideQuotes(s string) []string {
+	var parts []string
+	start, quoted := 0, false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// Silence is an Alertmanager silence
+type Silence struct {
+	// ID is empty to create a silence, or set to update one
+	ID        string    `json:"id,omitempty"`
+	Matchers  []Matcher `json:"matchers"`
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt"`
+	CreatedBy string    `json:"createdBy"`
+	Comment   string    `json:"comment"`
+}
+
+// Alertmanager creates and expires silences through the v2 API
+type Alertmanager struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewAlertmanager creates a client, e.g. for http://localhost:9093. token,
+// if set, is sent as a bearer token, for an Alertmanager behind a proxy.
+func NewAlertmanager(baseURL, token string, timeout time.Duration) *Alertmanager {
+	return &Alertmanager{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// Put creates s, or updates it if s.ID is set, and returns its ID.
+// Alertmanager may give an updated silence a new ID.
+func (a *Alertmanager) Put(ctx context.Context, s Silence) (string, error) {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	resp, err := a.do(ctx, "POST", "/api/v2/silences", body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", statusError(resp)
+	}
+	var result struct {
+		SilenceID string `json:"silenceID"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decode response: %w", err)
+	}
+	return result.SilenceID, nil
+}
+
+// Expire ends the silence id. A silence that no longer exists, e.g. after
+// Alertmanager lost its state, counts as expired.
+func (a *Alertmanager) Expire(ctx context.Context, id string) error {
+	resp, err := a.do(ctx, "DELETE", "/api/v2/silence/"+url.PathEscape(id), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNotFound:
+		return nil
+	}
+	return statusError(resp)
+}
+
+func (a *Alertmanager) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if a.token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// statusError reports an unexpected status with Alertmanager's reason,
+// which it sends as a JSON string
+func statusError(resp *http.Response) error {
+	var reason string
+	if json.NewDecoder(resp.Body).Decode(&reason) == nil && reason != "" {
+		return fmt.Errorf("unexpected status: %d: %s", resp.StatusCode, reason)
+	}
+	return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+}
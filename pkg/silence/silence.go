@@ -0,0 +1,263 @@
+// Package silence silences Alertmanager alerts while a check is busy for a
+// reason the sidecar knows is expected, e.g. a RAID rebuild started by
+// hand, so the pager stays quiet for maintenance already accounted for.
+//
+// The silence is created when the check turns busy with a reason matching
+// the config, extended while it stays busy, and expired once it is idle
+// again. It is only ever created for a limited time, so a sidecar that
+// dies never leaves alerts silenced for good; its ID is kept in a state
+// file, so a restarted sidecar still expires it.
+package silence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+)
+
+// DefaultDuration is how long a silence lasts unless extended
+const DefaultDuration = time.Hour
+
+// Config configures silencing
+type Config struct {
+	// URL is the Alertmanager; empty disables silencing
+	URL string
+	// Token, if set, is sent as a bearer token
+	Token string
+	// Matchers select the alerts to silence
+	Matchers []Matcher
+	// Match, if set, must match the busy reason
+	Match *regexp.Regexp
+	// Duration is how far ahead the silence ends; it is extended while
+	// the check stays busy
+	Duration time.Duration
+	// StatePath keeps the silence ID across restarts; empty keeps it in
+	// memory only
+	StatePath string
+	// DryRun only logs what would be silenced
+	DryRun bool
+}
+
+// ConfigFromEnv reads the config from SILENCE_* environment variables:
+//
+//	SILENCE_ALERTMANAGER_URL  Alertmanager, e.g. http://localhost:9093
+//	SILENCE_MATCHERS          alerts to silence, e.g. `alertname=~"MdRaid.*",instance="nas:9100"`
+//	SILENCE_MATCH             regexp the busy reason must match, e.g. "rebuilding|resyncing"
+//	SILENCE_DURATION          how long the silence lasts unless extended (default 1h)
+//	SILENCE_TOKEN             bearer token, for an Alertmanager behind a proxy
+//	SILENCE_STATE             file keeping the silence ID, default /run/homelab-sidecars/<name>-silence
+func ConfigFromEnv(name string) (Config, error) {
+	cfg := Config{
+		URL:       os.Getenv("SILENCE_ALERTMANAGER_URL"),
+		Token:     os.Getenv("SILENCE_TOKEN"),
+		Duration:  DefaultDuration,
+		StatePath: filepath.Join("/run/homelab-sidecars", name+"-silence"),
+	}
+	if cfg.URL == "" {
+		return cfg, nil
+	}
+	if v := os.Getenv("SILENCE_STATE"); v != "" {
+		cfg.StatePath = v
+	}
+	matchers, err := ParseMatchers(os.Getenv("SILENCE_MATCHERS"))
+	if err != nil {
+		// A silence without matchers would silence everything
+		return cfg, fmt.Errorf("SILENCE_MATCHERS: %w", err)
+	}
+	cfg.Matchers = matchers
+	if v := os.Getenv("SILENCE_MATCH"); v != "" {
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return cfg, fmt.Errorf("SILENCE_MATCH: %w", err)
+		}
+		cfg.Match = re
+	}
+	if v := os.Getenv("SILENCE_DURATION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Minute {
+			return cfg, fmt.Errorf("SILENCE_DURATION: want a duration of at least 1m")
+		}
+		cfg.Duration = d
+	}
+	return cfg, nil
+}
+
+// API creates and expires silences
+type API interface {
+	Put(ctx context.Context, s Silence) (string, error)
+	Expire(ctx context.Context, id string) error
+}
+
+// Silencer wraps a checker and keeps a silence while it is busy
+type Silencer struct {
+	sidecar.Checker
+	cfg Config
+	api API
+	now func() time.Time
+
+	mu     sync.Mutex
+	id     string    // the silence held, if any
+	endsAt time.Time // when it ends unless extended
+	dryRun bool      // a dry run would be holding one
+}
+
+// Wrap returns a Silencer around checker. With no Alertmanager configured
+// it returns nil, meaning disabled; api nil talks to cfg.URL.
+func Wrap(checker sidecar.Checker, cfg Config, api API) *Silencer {
+	if cfg.URL == "" {
+		return nil
+	}
+	if api == nil {
+		api = NewAlertmanager(cfg.URL, cfg.Token, 10*time.Second)
+	}
+	s := &Silencer{Checker: checker, cfg: cfg, api: api, now: time.Now}
+	if id, err := readState(cfg.StatePath); err != nil {
+		logging.Warnf("silence: %v", err)
+	} else {
+		// Its end isn't known, so it is extended, or expired, on the
+		// first check
+		s.id = id
+	}
+	return s
+}
+
+// Check runs the wrapped checker, and creates, extends or expires the
+// silence to match. Its result is returned unchanged.
+func (s *Silencer) Check(ctx context.Context) (bool, string, error) {
+	busy, reason, err := s.Checker.Check(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case err != nil:
+		// Can't tell whether the condition has passed; the silence runs
+		// out by itself if the check keeps failing
+	case busy && (s.cfg.Match == nil || s.cfg.Match.MatchString(reason)):
+		s.hold(ctx, reason)
+	case s.id != "" || s.dryRun:
+		s.expire(ctx)
+	}
+	return busy, reason, err
+}
+
+// hold creates the silence, or extends it once half its time has passed
+func (s *Silencer) hold(ctx context.Context, reason string) {
+	now := s.now()
+	if s.cfg.DryRun {
+		if !s.dryRun {
+			logging.Infof("dry run: would silence %s: %s", s.describe(), reason)
+		}
+		s.dryRun = true
+		return
+	}
+	if s.id != "" && s.endsAt.Sub(now) > s.cfg.Duration/2 {
+		return
+	}
+
+	silence := Silence{
+		ID:        s.id,
+		Matchers:  s.cfg.Matchers,
+		StartsAt:  now,
+		EndsAt:    now.Add(s.cfg.Duration),
+		CreatedBy: "homelab-sidecars/" + s.Name(),
+		Comment:   reason,
+	}
+	id, err := s.api.Put(ctx, silence)
+	if err != nil && s.id != "" {
+		// The silence may have been expired by hand; start a new one
+		silence.ID = ""
+		id, err = s.api.Put(ctx, silence)
+	}
+	if err != nil {
+		logging.Warnf("silence: %v", err)
+		return
+	}
+	if s.id == "" {
+		logging.Infof("Silenced %s until %s: %s", s.describe(), silence.EndsAt.Format(time.RFC3339), reason)
+	}
+	s.id, s.endsAt = id, silence.EndsAt
+	s.save()
+}
+
+// expire ends the silence, retried each poll until it succeeds
+func (s *Silencer) expire(ctx context.Context) {
+	if s.cfg.DryRun {
+		logging.Infof("dry run: would expire the silence of %s", s.describe())
+		s.dryRun = false
+		return
+	}
+	if err := s.api.Expire(ctx, s.id); err != nil {
+		logging.Warnf("silence: expiring %s: %v", s.id, err)
+		return
+	}
+	logging.Infof("Expired the silence of %s", s.describe())
+	s.id, s.endsAt = "", time.Time{}
+	s.save()
+}
+
+// Silence returns the ID of the silence held, if any.
+func (s *Silencer) Silence() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+func (s *Silencer) describe() string {
+	parts := make([]string, len(s.cfg.Matchers))
+	for i, m := range s.cfg.Matchers {
+		parts[i] = m.String()
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func (s *Silencer) save() {
+	if s.cfg.StatePath == "" {
+		return
+	}
+	if err := writeState(s.cfg.StatePath, s.id); err != nil {
+		logging.Warnf("silence: %v", err)
+	}
+}
+
+// readState returns the silence ID in path, or "" if it doesn't exist
+func readState(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// writeState replaces path with id, or removes it if id is empty
+func writeState(path, id string) error {
+	if id == "" {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(id+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,171 @@
+package silence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+)
+
+// fakeAPI records the silences put and expired
+type fakeAPI struct {
+	silences map[string]Silence
+	expired  []string
+	puts     int
+}
+
+func (f *fakeAPI) Put(_ context.Context, s Silence) (string, error) {
+	f.puts++
+	if s.ID != "" {
+		if _, ok := f.silences[s.ID]; !ok {
+			return "", errors.New("silence not found")
+		}
+	} else {
+		s.ID = "s" + string(rune('0'+f.puts))
+	}
+	f.silences[s.ID] = s
+	return s.ID, nil
+}
+
+func (f *fakeAPI) Expire(_ context.Context, id string) error {
+	delete(f.silences, id)
+	f.expired = append(f.expired, id)
+	return nil
+}
+
+func TestSilencer(t *testing.T) {
+	var (
+		busy   bool
+		reason string
+	)
+	checker := sidecar.NewCheckerFunc("raid", func(ctx context.Context) (bool, string, error) {
+		return busy, reason, nil
+	})
+	api := &fakeAPI{silences: map[string]Silence{}}
+	state := filepath.Join(t.TempDir(), "raid-silence")
+	cfg := Config{
+		URL:       "http://alertmanager:9093",
+		Matchers:  []Matcher{{Name: "alertname", Value: "MdRaid.*", IsRegex: true, IsEqual: true}},
+		Match:     regexp.MustCompile("rebuilding"),
+		Duration:  time.Hour,
+		StatePath: state,
+	}
+	s := Wrap(checker, cfg, api)
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	poll := func(b bool, r string) {
+		t.Helper()
+		busy, reason = b, r
+		if gotBusy, gotReason, _ := s.Check(context.Background()); gotBusy != b || gotReason != r {
+			t.Fatalf("Check() = %v, %q, want the result unchanged", gotBusy, gotReason)
+		}
+	}
+
+	// A degraded array isn't expected, so it isn't silenced
+	poll(true, "md0 degraded")
+	if api.puts != 0 {
+		t.Fatalf("puts = %d, want none", api.puts)
+	}
+
+	poll(true, "md0 rebuilding")
+	id := s.Silence()
+	got, ok := api.silences[id]
+	if !ok || got.Comment != "md0 rebuilding" || got.CreatedBy != "homelab-sidecars/raid" || !got.EndsAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("silence = %+v, want one for an hour", got)
+	}
+	if data, _ := os.ReadFile(state); string(data) != id+"\n" {
+		t.Errorf("state = %q, want %q", data, id)
+	}
+
+	// Extended once half its time has passed
+	now = now.Add(10 * time.Minute)
+	poll(true, "md0 rebuilding")
+	if api.puts != 1 {
+		t.Errorf("puts = %d, want the silence left alone", api.puts)
+	}
+	now = now.Add(25 * time.Minute)
+	poll(true, "md0 rebuilding")
+	if api.puts != 2 || !api.silences[id].EndsAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("silence = %+v, want it extended", api.silences[id])
+	}
+
+	// A restarted sidecar expires the silence it left
+	s = Wrap(checker, cfg, api)
+	poll(false, "")
+	if !reflect.DeepEqual(api.expired, []string{id}) || s.Silence() != "" {
+		t.Errorf("expired = %v, want %s", api.expired, id)
+	}
+	if _, err := os.Stat(state); !os.IsNotExist(err) {
+		t.Errorf("state file left behind: %v", err)
+	}
+}
+
+func TestParseMatchers(t *testing.T) {
+	got, err := ParseMatchers(`{alertname=~"MdRaid.*", instance="nas:9100", severity!=info, job!~"a,b"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Matcher{
+		{Name: "alertname", Value: "MdRaid.*", IsRegex: true, IsEqual: true},
+		{Name: "instance", Value: "nas:9100", IsEqual: true},
+		{Name: "severity", Value: "info"},
+		{Name: "job", Value: "a,b", IsRegex: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseMatchers() = %+v, want %+v", got, want)
+	}
+
+	for _, bad := range []string{"", "alertname", `=foo`, `a="unterminated`} {
+		if _, err := ParseMatchers(bad); err == nil {
+			t.Errorf("ParseMatchers(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestAlertmanager(t *testing.T) {
+	var posted Silence
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/api/v2/silences":
+			json.NewDecoder(r.Body).Decode(&posted)
+			w.Write([]byte(`{"silenceID":"abc"}`))
+		case r.Method == "DELETE" && r.URL.Path == "/api/v2/silence/abc":
+		case r.Method == "DELETE":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`"silence store broken"`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	am := NewAlertmanager(server.URL, "secret", 5*time.Second)
+	matchers := []Matcher{{Name: "alertname", Value: "MdRaidRebuilding", IsEqual: true}}
+	id, err := am.Put(context.Background(), Silence{Matchers: matchers, Comment: "md0 rebuilding"})
+	if err != nil || id != "abc" {
+		t.Fatalf("Put() = %q, %v, want abc", id, err)
+	}
+	if !reflect.DeepEqual(posted.Matchers, matchers) || posted.Comment != "md0 rebuilding" {
+		t.Errorf("posted %+v", posted)
+	}
+	if err := am.Expire(context.Background(), "abc"); err != nil {
+		t.Errorf("Expire() = %v", err)
+	}
+	if err := am.Expire(context.Background(), "other"); err == nil || err.Error() != "unexpected status: 500: silence store broken" {
+		t.Errorf("Expire() = %v, want the reason", err)
+	}
+}
@@ -0,0 +1,270 @@
+// Package sleepwake follows suspend and resume through logind's
+// PrepareForSleep signal, for desktop-class servers that sleep when idle.
+//
+// A check can hook into the cycle by implementing any of Vetoer, Preparer
+// and Resumer: vetoing sleep without blocking shutdown, e.g. while media
+// is playing; acting just before the machine sleeps, e.g. pausing
+// torrents; and acting once it wakes. Whatever it implements, the check is
+// run again as soon as the machine resumes rather than at the next poll,
+// so its status and metrics don't describe the world before the suspend.
+package sleepwake
+
+import (
+	"context"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/inhibitor"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/logind"
+	"github.com/godbus/dbus/v5"
+)
+
+// DefaultPrepareTimeout keeps Preparer inside logind's default
+// InhibitDelayMaxSec of 5s, after which the machine sleeps anyway
+const DefaultPrepareTimeout = 4 * time.Second
+
+// Vetoer is implemented by checks that keep the machine awake for reasons
+// that don't block shutdown, e.g. media playing.
+type Vetoer interface {
+	// VetoSleep reports whether the machine should stay awake, and why
+	VetoSleep(ctx context.Context) (veto bool, reason string, err error)
+}
+
+// Preparer is implemented by checks with something to do before the
+// machine sleeps, e.g. pausing transfers that would time out.
+type Preparer interface {
+	PrepareSleep(ctx context.Context) error
+}
+
+// Resumer is implemented by checks with something to do once the machine
+// has woken, e.g. resuming what PrepareSleep paused.
+type Resumer interface {
+	Resume(ctx context.Context) error
+}
+
+// Config configures Watch
+type Config struct {
+	// Interval is how often a Vetoer is asked, normally the poll interval
+	Interval time.Duration
+	// PrepareTimeout bounds PrepareSleep (0 = DefaultPrepareTimeout)
+	PrepareTimeout time.Duration
+	// DryRun only logs the hooks and the veto, taking no locks
+	DryRun bool
+}
+
+// newLock opens an inhibitor; replaced in tests
+var newLock = func(ctx context.Context, what, who, mode string) (inhibitor.Lock, error) {
+	return inhibitor.New(ctx, what, who, mode)
+}
+
+// subscribeSleep follows PrepareForSleep; replaced in tests
+var subscribeSleep = func() (<-chan bool, func(), error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, nil, err
+	}
+	signals, cancel, err := logind.PrepareForSleep(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return signals, func() { cancel(); conn.Close() }, nil
+}
+
+// Watch follows suspend and resume until ctx is done. checker is run again
+// on every resume, and should be the fully wrapped check so its status
+// and metrics are updated; hooks is the check that may implement Vetoer,
+// Preparer and Resumer.
+//
+// A Vetoer is asked every cfg.Interval, and a "sleep" block lock is held
+// while it vetoes. A Preparer is given a "sleep" delay lock, released once
+// PrepareSleep returns, so the machine waits for it.
+func Watch(ctx context.Context, checker sidecar.Checker, hooks any, cfg Config) error {
+	w, err := newWatcher(ctx, checker, hooks, cfg)
+	if err != nil {
+		return err
+	}
+	defer w.close()
+
+	signals, unsubscribe, err := subscribeSleep()
+	if err != nil {
+		return err
+	}
+	defer unsubscribe()
+
+	var vetoes <-chan time.Time
+	if w.vetoer != nil {
+		interval := cfg.Interval
+		if interval <= 0 {
+			interval = 30 * time.Second
+		}
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		vetoes = t.C
+		w.veto(ctx)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-vetoes:
+			w.veto(ctx)
+		case sleeping, ok := <-signals:
+			if !ok {
+				return nil
+			}
+			if sleeping {
+				w.sleep(ctx)
+			} else {
+				w.wake(ctx)
+			}
+		}
+	}
+}
+
+type watcher struct {
+	checker sidecar.Checker
+	vetoer  Vetoer
+	prep    Preparer
+	resumer Resumer
+	cfg     Config
+
+	block inhibitor.Lock // held while vetoer vetoes
+	delay inhibitor.Lock // held while awake, so prep runs before sleep
+}
+
+func newWatcher(ctx context.Context, checker sidecar.Checker, hooks any, cfg Config) (*watcher, error) {
+	w := &watcher{checker: checker, cfg: cfg}
+	w.vetoer, _ = hooks.(Vetoer)
+	w.prep, _ = hooks.(Preparer)
+	w.resumer, _ = hooks.(Resumer)
+	if w.cfg.PrepareTimeout <= 0 {
+		w.cfg.PrepareTimeout = DefaultPrepareTimeout
+	}
+	if cfg.DryRun {
+		return w, nil
+	}
+
+	var err error
+	if w.vetoer != nil {
+		if w.block, err = newLock(ctx, "sleep", checker.Name(), "block"); err != nil {
+			return nil, err
+		}
+	}
+	if w.prep != nil {
+		if w.delay, err = newLock(ctx, "sleep", checker.Name(), "delay"); err != nil {
+			w.close()
+			return nil, err
+		}
+		w.holdDelay(ctx)
+	}
+	return w, nil
+}
+
+// veto asks the Vetoer, taking or dropping the block lock to match. An
+// error keeps the previous state.
+func (w *watcher) veto(ctx context.Context) {
+	veto, reason, err := w.vetoer.VetoSleep(ctx)
+	if err != nil {
+		logging.Warnf("sleep veto: %v", err)
+		return
+	}
+	if w.block == nil {
+		logging.Debugf("dry run: sleep veto %v: %s", veto, reason)
+		return
+	}
+
+	held, why := w.block.Held()
+	switch {
+	case veto && held && why == reason:
+		// Already vetoing for this reason
+	case veto && held:
+		// The new lock is taken first, so sleep is never let through
+		if err := w.block.Replace(ctx, reason); err != nil {
+			logging.Errorf("Failed to update sleep veto: %v", err)
+		}
+	case veto:
+		if err := w.block.Acquire(ctx, reason); err != nil {
+			logging.Errorf("Failed to veto sleep: %v", err)
+			return
+		}
+		logging.Infof("Vetoing sleep: %s", reason)
+	case held:
+		if err := w.block.Release(ctx); err != nil {
+			logging.Errorf("Failed to lift sleep veto: %v", err)
+			return
+		}
+		logging.Infof("Lifted sleep veto")
+	}
+}
+
+// sleep runs PrepareSleep, then lets the machine go
+func (w *watcher) sleep(ctx context.Context) {
+	logging.Infof("PrepareForSleep received")
+	if w.prep == nil {
+		return
+	}
+	if w.cfg.DryRun {
+		logging.Infof("dry run: would prepare %s for sleep", w.checker.Name())
+		return
+	}
+
+	prepCtx, cancel := context.WithTimeout(ctx, w.cfg.PrepareTimeout)
+	defer cancel()
+	if err := w.prep.PrepareSleep(prepCtx); err != nil {
+		logging.Warnf("preparing for sleep: %v", err)
+	}
+	if err := w.delay.Release(ctx); err != nil {
+		logging.Errorf("Failed to release sleep delay: %v", err)
+	}
+}
+
+// wake runs Resume and the check, and asks the Vetoer again rather than
+// waiting for the next tick
+func (w *watcher) wake(ctx context.Context) {
+	logging.Infof("Resumed from sleep, checking now")
+	if w.delay != nil {
+		w.holdDelay(ctx)
+	}
+	if w.resumer != nil {
+		if w.cfg.DryRun {
+			logging.Infof("dry run: would resume %s", w.checker.Name())
+		} else if err := w.resumer.Resume(ctx); err != nil {
+			logging.Warnf("resuming after sleep: %v", err)
+		}
+	}
+
+	busy, reason, err := w.checker.Check(ctx)
+	switch {
+	case err != nil:
+		logging.Warnf("Check after resume: %v", err)
+	case busy:
+		logging.Infof("Busy after resume: %s", reason)
+	}
+
+	if w.vetoer != nil {
+		w.veto(ctx)
+	}
+}
+
+// holdDelay takes the delay lock, unless a resume without a suspend left
+// it held
+func (w *watcher) holdDelay(ctx context.Context) {
+	if held, _ := w.delay.Held(); held {
+		return
+	}
+	if err := w.delay.Acquire(ctx, "preparing "+w.checker.Name()+" for sleep"); err != nil {
+		logging.Errorf("Failed to take sleep delay: %v", err)
+	}
+}
+
+func (w *watcher) close() {
+	if w.block != nil {
+		w.block.Close()
+	}
+	if w.delay != nil {
+		w.delay.Close()
+	}
+}
@@ -0,0 +1,129 @@
+package sleepwake
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/inhibitor"
+)
+
+// fakeLock records the lock's state
+type fakeLock struct {
+	held bool
+	why  string
+}
+
+func (f *fakeLock) Acquire(ctx context.Context, why string) error {
+	f.held, f.why = true, why
+	return nil
+}
+
+func (f *fakeLock) Release(ctx context.Context) error {
+	f.held, f.why = false, ""
+	return nil
+}
+
+func (f *fakeLock) Replace(ctx context.Context, why string) error {
+	f.why = why
+	return nil
+}
+
+func (f *fakeLock) Held() (bool, string) { return f.held, f.why }
+func (f *fakeLock) Close() error         { return nil }
+
+// torrents pauses before sleep and vetoes it while seeding to a friend
+type torrents struct {
+	seeding string
+	calls   []string
+}
+
+func (t *torrents) VetoSleep(ctx context.Context) (bool, string, error) {
+	return t.seeding != "", t.seeding, nil
+}
+
+func (t *torrents) PrepareSleep(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok {
+		t.calls = append(t.calls, "prepare without deadline")
+	}
+	t.calls = append(t.calls, "pause")
+	return nil
+}
+
+func (t *torrents) Resume(ctx context.Context) error {
+	t.calls = append(t.calls, "resume")
+	return nil
+}
+
+func TestWatcher(t *testing.T) {
+	locks := map[string]*fakeLock{}
+	newLock = func(ctx context.Context, what, who, mode string) (inhibitor.Lock, error) {
+		locks[mode] = &fakeLock{}
+		return locks[mode], nil
+	}
+
+	hooks := &torrents{}
+	checker := sidecar.NewCheckerFunc("qbittorrent", func(ctx context.Context) (bool, string, error) {
+		hooks.calls = append(hooks.calls, "check")
+		return false, "", nil
+	})
+	ctx := context.Background()
+	w, err := newWatcher(ctx, checker, hooks, Config{Interval: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !locks["delay"].held {
+		t.Fatal("delay lock not taken while awake")
+	}
+
+	hooks.seeding = "seeding ubuntu.iso"
+	w.veto(ctx)
+	if held, why := locks["block"].Held(); !held || why != "seeding ubuntu.iso" {
+		t.Errorf("block lock = %v, %q, want the veto", held, why)
+	}
+	hooks.seeding = "seeding debian.iso"
+	w.veto(ctx)
+	if _, why := locks["block"].Held(); why != "seeding debian.iso" {
+		t.Errorf("block lock reason = %q, want it updated", why)
+	}
+
+	hooks.seeding = ""
+	w.sleep(ctx)
+	if locks["delay"].held {
+		t.Error("delay lock still held after preparing")
+	}
+	w.wake(ctx)
+	if !locks["delay"].held {
+		t.Error("delay lock not taken again after resume")
+	}
+	if locks["block"].held {
+		t.Error("veto not lifted after resume")
+	}
+	if want := []string{"pause", "resume", "check"}; !reflect.DeepEqual(hooks.calls, want) {
+		t.Errorf("calls = %v, want %v", hooks.calls, want)
+	}
+}
+
+func TestWatcherDryRun(t *testing.T) {
+	newLock = func(ctx context.Context, what, who, mode string) (inhibitor.Lock, error) {
+		t.Fatal("lock taken in a dry run")
+		return nil, nil
+	}
+	hooks := &torrents{seeding: "seeding ubuntu.iso"}
+	checker := sidecar.NewCheckerFunc("qbittorrent", func(ctx context.Context) (bool, string, error) {
+		return false, "", nil
+	})
+	ctx := context.Background()
+	w, err := newWatcher(ctx, checker, hooks, Config{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.veto(ctx)
+	w.sleep(ctx)
+	w.wake(ctx)
+	if len(hooks.calls) != 0 {
+		t.Errorf("calls = %v, want hooks only logged", hooks.calls)
+	}
+}
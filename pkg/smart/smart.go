@@ -0,0 +1,192 @@
+// Package smart finds SMART self-tests in progress with smartctl. An
+// extended test of a large disk takes many hours, and a reboot aborts it,
+// so it has to start over from the beginning.
+//
+// ATA disks report a test in progress in their capabilities ("smartctl
+// -c"), NVMe disks in their self-test log; both are read as JSON, which
+// needs smartmontools 7.0 or later. Disks in standby are skipped rather
+// than woken: a disk running a test doesn't spin down.
+package smart
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/metrics"
+	"github.com/addisonbair/homelab-sidecars/pkg/privexec"
+)
+
+// Test is a self-test in progress
+type Test struct {
+	Device    string
+	Type      string // "short" or "extended"; "" if the disk doesn't say
+	Remaining int    // percent
+}
+
+// Describe names the test, e.g. "/dev/sda extended self-test, 90%
+// remaining".
+func (t Test) Describe() string {
+	kind := "self-test"
+	if t.Type != "" {
+		kind = t.Type + " self-test"
+	}
+	return fmt.Sprintf("%s %s, %d%% remaining", t.Device, kind, t.Remaining)
+}
+
+// Checker looks for self-tests in progress on Devices
+type Checker struct {
+	Runner privexec.Runner
+	// Command defaults to "smartctl"; e.g. "podman exec scrutiny
+	// smartctl" runs the one in Scrutiny's collector container
+	Command []string
+	// Devices are the disks to check, e.g. /dev/sda or /dev/nvme0
+	Devices []string
+
+	mu        sync.Mutex
+	remaining map[string]int
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "smart"
+}
+
+// Active returns the tests in progress. A device that can't be read
+// doesn't hide the tests found on the others: they are returned with the
+// error.
+func (c *Checker) Active(ctx context.Context) ([]Test, error) {
+	var tests []Test
+	var errs []error
+	remaining := make(map[string]int, len(c.Devices))
+	for _, dev := range c.Devices {
+		test, err := c.Status(ctx, dev)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		remaining[dev] = 0
+		if test != nil {
+			tests = append(tests, *test)
+			remaining[dev] = test.Remaining
+		}
+	}
+
+	c.mu.Lock()
+	c.remaining = remaining
+	c.mu.Unlock()
+	return tests, errors.Join(errs...)
+}
+
+// Status returns the self-test in progress on dev, or nil if there is
+// none or the disk is in standby.
+func (c *Checker) Status(ctx context.Context, dev string) (*Test, error) {
+	command := c.Command
+	if len(command) == 0 {
+		command = []string{"smartctl"}
+	}
+	// "-c" first, to match a sudoers rule for "smartctl -c *"
+	args := append(append([]string{}, command[1:]...), "-c", "-l", "selftest", "-n", "standby,0", "-j", dev)
+	out, err := c.Runner.Output(ctx, command[0], args...)
+	if len(out) == 0 && err != nil {
+		return nil, err
+	}
+	// smartctl's exit status is a bit mask, mostly of the disk's health,
+	// so only the JSON says whether the disk could be read
+	test, perr := parseStatus(out)
+	if perr != nil {
+		if err != nil && !json.Valid(out) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %w", dev, perr)
+	}
+	if test != nil {
+		test.Device = dev
+	}
+	return test, nil
+}
+
+type smartctlOutput struct {
+	Smartctl struct {
+		ExitStatus int `json:"exit_status"`
+		Messages   []struct {
+			String string `json:"string"`
+		} `json:"messages"`
+	} `json:"smartctl"`
+	ATASmartData *struct {
+		SelfTest struct {
+			Status struct {
+				Value int `json:"value"`
+			} `json:"status"`
+		} `json:"self_test"`
+	} `json:"ata_smart_data"`
+	NVMeSelfTestLog *struct {
+		CurrentOperation struct {
+			Value int `json:"value"`
+		} `json:"current_self_test_operation"`
+		CompletionPercent int `json:"current_self_test_completion_percent"`
+	} `json:"nvme_self_test_log"`
+}
+
+// nvmeOperations are the NVMe self-test codes
+var nvmeOperations = map[int]string{
+	1: "short",
+	2: "extended",
+}
+
+// parseStatus reads a self-test in progress from "smartctl -j -c -l
+// selftest" output.
+func parseStatus(out []byte) (*Test, error) {
+	var o smartctlOutput
+	if err := json.Unmarshal(out, &o); err != nil {
+		return nil, fmt.Errorf("parse smartctl output: %w", err)
+	}
+	// Bit 0: the command line didn't parse; bit 1: the device couldn't be
+	// opened or didn't answer
+	if o.Smartctl.ExitStatus&0x3 != 0 {
+		var msgs []string
+		for _, m := range o.Smartctl.Messages {
+			msgs = append(msgs, m.String)
+		}
+		return nil, fmt.Errorf("smartctl exit status %d: %s", o.Smartctl.ExitStatus, strings.Join(msgs, "; "))
+	}
+
+	switch {
+	case o.ATASmartData != nil:
+		// The high nibble is 15 while a test runs, the low one the tenths
+		// remaining
+		v := o.ATASmartData.SelfTest.Status.Value
+		if v>>4 == 0xf {
+			return &Test{Remaining: (v & 0xf) * 10}, nil
+		}
+	case o.NVMeSelfTestLog != nil:
+		if op := o.NVMeSelfTestLog.CurrentOperation.Value; op != 0 {
+			return &Test{Type: nvmeOperations[op], Remaining: 100 - o.NVMeSelfTestLog.CompletionPercent}, nil
+		}
+	}
+	return nil, nil
+}
+
+// Gauges returns the percentage of the test remaining on each device
+// Active last read, 0 without one.
+func (c *Checker) Gauges() []metrics.Gauge {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	gauges := make([]metrics.Gauge, 0, len(c.Devices))
+	for _, dev := range c.Devices {
+		v, ok := c.remaining[dev]
+		if !ok {
+			continue
+		}
+		gauges = append(gauges, metrics.Gauge{
+			Name:   "homelab_smart_selftest_remaining_percent",
+			Help:   "Percentage of the SMART self-test in progress remaining.",
+			Labels: map[string]string{"device": dev},
+			Value:  float64(v),
+		})
+	}
+	return gauges
+}
@@ -0,0 +1,93 @@
+package smart
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const (
+	ataTesting = `{"smartctl": {"exit_status": 0}, "device": {"name": "/dev/sda", "protocol": "ATA"},
+		"ata_smart_data": {"self_test": {"status": {"value": 249, "string": "in progress, 90% remaining", "remaining_percent": 90},
+			"polling_minutes": {"short": 2, "extended": 1046}}}}`
+	ataIdle = `{"smartctl": {"exit_status": 64}, "device": {"name": "/dev/sdb", "protocol": "ATA"},
+		"ata_smart_data": {"self_test": {"status": {"value": 0, "string": "completed without error", "passed": true}}}}`
+	ataStandby  = `{"smartctl": {"exit_status": 0, "messages": [{"string": "Device is in STANDBY mode, exit(0)", "severity": "information"}]}}`
+	nvmeTesting = `{"smartctl": {"exit_status": 0}, "device": {"name": "/dev/nvme0", "protocol": "NVMe"},
+		"nvme_self_test_log": {"current_self_test_operation": {"value": 2, "string": "Extended self-test in progress"},
+			"current_self_test_completion_percent": 35, "table": []}}`
+	nvmeIdle = `{"smartctl": {"exit_status": 0}, "device": {"name": "/dev/nvme1", "protocol": "NVMe"},
+		"nvme_self_test_log": {"current_self_test_operation": {"value": 0, "string": "No self-test in progress"}}}`
+	missing = `{"smartctl": {"exit_status": 2, "messages": [{"string": "Smartctl open device: /dev/sdz failed: No such device", "severity": "error"}]}}`
+)
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    string
+		wantErr string
+	}{
+		{name: "ata testing", out: ataTesting, want: " self-test, 90% remaining"},
+		{name: "ata idle", out: ataIdle},
+		{name: "standby", out: ataStandby},
+		{name: "nvme testing", out: nvmeTesting, want: " extended self-test, 65% remaining"},
+		{name: "nvme idle", out: nvmeIdle},
+		{name: "missing", out: missing, wantErr: "smartctl exit status 2: Smartctl open device: /dev/sdz failed: No such device"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test, err := parseStatus([]byte(tt.out))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("parseStatus() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got string
+			if test != nil {
+				got = test.Describe()
+			}
+			if got != tt.want {
+				t.Errorf("parseStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChecker_Active(t *testing.T) {
+	// "sh -c SCRIPT" stands in for smartctl, with the device last and
+	// smartctl's exit status, which only the JSON explains
+	script := `for dev; do :; done
+case "$dev" in
+/dev/sda) echo '` + ataTesting + `' ;;
+/dev/sdb) echo '` + ataIdle + `'; exit 64 ;;
+/dev/nvme0) echo '` + nvmeTesting + `' ;;
+*) echo '` + missing + `'; exit 2 ;;
+esac`
+	c := &Checker{
+		Command: []string{"sh", "-c", script},
+		Devices: []string{"/dev/sda", "/dev/sdb", "/dev/nvme0", "/dev/sdz"},
+	}
+
+	tests, err := c.Active(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "/dev/sdz") {
+		t.Errorf("Active() error = %v, want /dev/sdz failing", err)
+	}
+	var got []string
+	for _, test := range tests {
+		got = append(got, test.Describe())
+	}
+	want := []string{"/dev/sda self-test, 90% remaining", "/dev/nvme0 extended self-test, 65% remaining"}
+	if strings.Join(got, "; ") != strings.Join(want, "; ") {
+		t.Errorf("Active() = %q, want %q", got, want)
+	}
+
+	gauges := c.Gauges()
+	if len(gauges) != 3 || gauges[0].Value != 90 || gauges[1].Value != 0 || gauges[2].Labels["device"] != "/dev/nvme0" {
+		t.Errorf("Gauges() = %+v", gauges)
+	}
+}
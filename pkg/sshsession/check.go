@@ -0,0 +1,135 @@
+// Package sshsession finds interactive SSH sessions through logind, so
+// nobody is cut off mid-command by a scheduled reboot.
+//
+// sshd registers each login with logind through pam_systemd, recording the
+// user, the remote host and whether it has a terminal. A session with a
+// terminal is someone typing; one without is a command run over ssh, such
+// as an Ansible task, which only counts if Interactive is off.
+package sshsession
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logind"
+	"github.com/addisonbair/homelab-sidecars/pkg/metrics"
+	"github.com/godbus/dbus/v5"
+)
+
+// Lister lists login sessions
+type Lister interface {
+	Sessions(ctx context.Context) ([]logind.Session, error)
+}
+
+// Logind lists sessions through logind on the system bus. It connects on
+// first use and reconnects after the connection drops.
+type Logind struct {
+	mu   sync.Mutex
+	conn *dbus.Conn
+}
+
+// Sessions returns every session logind knows.
+func (l *Logind) Sessions(ctx context.Context) ([]logind.Session, error) {
+	l.mu.Lock()
+	if l.conn == nil || !l.conn.Connected() {
+		conn, err := dbus.ConnectSystemBus()
+		if err != nil {
+			l.mu.Unlock()
+			return nil, fmt.Errorf("connecting to the system bus: %w", err)
+		}
+		l.conn = conn
+	}
+	conn := l.conn
+	l.mu.Unlock()
+	return logind.ListSessions(ctx, conn)
+}
+
+// Checker finds the SSH sessions that should hold off a reboot
+type Checker struct {
+	Lister Lister
+	// Services are the PAM services of SSH logins (none = "sshd")
+	Services []string
+	// IgnoreUsers never block, e.g. an automation account
+	IgnoreUsers []string
+	// Interactive only counts sessions with a terminal
+	Interactive bool
+	// IdleTimeout stops counting a session idle this long (0 = never).
+	// logind tracks idleness from the terminal, so it only applies to
+	// interactive sessions.
+	IdleTimeout time.Duration
+
+	now func() time.Time
+
+	mu    sync.Mutex
+	count int
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "ssh"
+}
+
+// Active describes each session that blocks, e.g. "alice on pts/0 from
+// 192.168.1.20 for 25m".
+func (c *Checker) Active(ctx context.Context) ([]string, error) {
+	sessions, err := c.Lister.Sessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+	services := c.Services
+	if len(services) == 0 {
+		services = []string{"sshd"}
+	}
+
+	var active []string
+	for _, s := range sessions {
+		switch {
+		case !slices.Contains(services, s.Service),
+			s.Class != "" && s.Class != "user",
+			s.State == "closing",
+			slices.Contains(c.IgnoreUsers, s.User),
+			c.Interactive && s.Type != "tty",
+			c.IdleTimeout > 0 && s.Idle && !s.IdleSince.IsZero() && now().Sub(s.IdleSince) >= c.IdleTimeout:
+			continue
+		}
+		active = append(active, describe(s, now()))
+	}
+
+	c.mu.Lock()
+	c.count = len(active)
+	c.mu.Unlock()
+	return active, nil
+}
+
+func describe(s logind.Session, now time.Time) string {
+	desc := s.User
+	if s.TTY != "" {
+		desc += " on " + s.TTY
+	}
+	if s.RemoteHost != "" {
+		desc += " from " + s.RemoteHost
+	}
+	if !s.Since.IsZero() {
+		desc += " for " + now.Sub(s.Since).Round(time.Minute).String()
+	}
+	return desc
+}
+
+// Gauges returns the number of blocking sessions Active last found.
+func (c *Checker) Gauges() []metrics.Gauge {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return []metrics.Gauge{{
+		Name:  "homelab_ssh_sessions",
+		Help:  "SSH sessions holding off a reboot.",
+		Value: float64(c.count),
+	}}
+}
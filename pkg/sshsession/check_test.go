@@ -0,0 +1,51 @@
+package sshsession
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logind"
+)
+
+type fakeLister []logind.Session
+
+func (f fakeLister) Sessions(context.Context) ([]logind.Session, error) {
+	return f, nil
+}
+
+func TestChecker_Active(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	sessions := fakeLister{
+		{User: "alice", Service: "sshd", Type: "tty", Class: "user", State: "active", TTY: "pts/0", RemoteHost: "192.168.1.20", Since: now.Add(-25 * time.Minute)},
+		// A command run over ssh, without a terminal
+		{User: "bob", Service: "sshd", Type: "unspecified", Class: "user", State: "online", RemoteHost: "192.168.1.21"},
+		{User: "ansible", Service: "sshd", Type: "tty", Class: "user", State: "active", TTY: "pts/1"},
+		// Left logged in overnight
+		{User: "carol", Service: "sshd", Type: "tty", Class: "user", State: "active", TTY: "pts/2", Idle: true, IdleSince: now.Add(-8 * time.Hour)},
+		{User: "dave", Service: "sshd", Type: "tty", Class: "user", State: "closing", TTY: "pts/3"},
+		{User: "erin", Service: "login", Type: "tty", Class: "user", State: "active", TTY: "tty1"},
+		{User: "gdm", Service: "gdm-launch-environment", Type: "wayland", Class: "greeter", State: "online"},
+	}
+
+	checker := &Checker{Lister: sessions, IgnoreUsers: []string{"ansible"}, now: func() time.Time { return now }}
+	got, err := checker.Active(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"alice on pts/0 from 192.168.1.20 for 25m0s", "bob from 192.168.1.21", "carol on pts/2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Active() = %q, want %q", got, want)
+	}
+
+	checker.Interactive = true
+	checker.IdleTimeout = time.Hour
+	got, _ = checker.Active(context.Background())
+	if !reflect.DeepEqual(got, want[:1]) {
+		t.Errorf("Active() = %q, want only %q", got, want[0])
+	}
+	if g := checker.Gauges(); len(g) != 1 || g[0].Value != 1 {
+		t.Errorf("Gauges() = %+v, want 1 session", g)
+	}
+}
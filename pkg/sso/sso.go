@@ -0,0 +1,163 @@
+// Package sso checks that a self-hosted identity provider (Keycloak or
+// Authelia) is up and serving its OpenID Connect discovery document, since
+// every service that logs in through it is broken when it isn't.
+package sso
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Provider is an identity provider's health endpoint and OIDC discovery
+// document
+type Provider struct {
+	Name string
+	// HealthURL answers 200 with {"status": "UP"} (Keycloak) or
+	// {"status": "OK"} (Authelia) when the provider is ready
+	HealthURL string
+	// DiscoveryURL is the .well-known/openid-configuration document; empty
+	// skips it, e.g. for an Authelia that only does forward auth
+	DiscoveryURL string
+
+	httpClient *http.Client
+}
+
+// NewKeycloak creates a provider for a Keycloak realm. healthURL is the
+// readiness endpoint, which Keycloak 25 and later serve on the management
+// port (http://host:9000/health/ready) and only with health-enabled=true;
+// empty uses /health/ready on baseURL, as older releases do.
+func NewKeycloak(baseURL, realm, healthURL string, timeout time.Duration) *Provider {
+	baseURL = strings.TrimRight(baseURL, "/")
+	if healthURL == "" {
+		healthURL = baseURL + "/health/ready"
+	}
+	return &Provider{
+		Name:         "keycloak",
+		HealthURL:    healthURL,
+		DiscoveryURL: baseURL + "/realms/" + realm + "/.well-known/openid-configuration",
+		httpClient:   &http.Client{Timeout: timeout},
+	}
+}
+
+// NewAuthelia creates a provider for Authelia. With oidc false the
+// discovery document isn't checked, for an Authelia that isn't configured
+// as an OpenID Connect provider.
+func NewAuthelia(baseURL string, oidc bool, timeout time.Duration) *Provider {
+	baseURL = strings.TrimRight(baseURL, "/")
+	p := &Provider{
+		Name:       "authelia",
+		HealthURL:  baseURL + "/api/health",
+		httpClient: &http.Client{Timeout: timeout},
+	}
+	if oidc {
+		p.DiscoveryURL = baseURL + "/.well-known/openid-configuration"
+	}
+	return p
+}
+
+// Discovery is the part of the OIDC discovery document clients need to log
+// in
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Health returns nil if the provider reports itself ready and serves a
+// discovery document whose signing keys can be fetched.
+func (p *Provider) Health(ctx context.Context) error {
+	var health struct {
+		Status string `json:"status"`
+	}
+	if err := p.getJSON(ctx, p.HealthURL, &health); err != nil {
+		return fmt.Errorf("health: %w", err)
+	}
+	if health.Status != "UP" && health.Status != "OK" {
+		return fmt.Errorf("health: status %q", health.Status)
+	}
+
+	if p.DiscoveryURL == "" {
+		return nil
+	}
+	d, err := p.Discover(ctx)
+	if err != nil {
+		return fmt.Errorf("discovery: %w", err)
+	}
+
+	var jwks struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := p.getJSON(ctx, d.JWKSURI, &jwks); err != nil {
+		return fmt.Errorf("jwks: %w", err)
+	}
+	if len(jwks.Keys) == 0 {
+		return errors.New("jwks: no signing keys")
+	}
+	return nil
+}
+
+// Discover fetches the discovery document and checks it names the
+// endpoints a login needs.
+func (p *Provider) Discover(ctx context.Context) (*Discovery, error) {
+	var d Discovery
+	if err := p.getJSON(ctx, p.DiscoveryURL, &d); err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, f := range []struct{ name, value string }{
+		{"issuer", d.Issuer},
+		{"authorization_endpoint", d.AuthorizationEndpoint},
+		{"token_endpoint", d.TokenEndpoint},
+		{"jwks_uri", d.JWKSURI},
+	} {
+		if f.value == "" {
+			missing = append(missing, f.name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+	return &d, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// Health returns nil if every provider is healthy.
+func Health(ctx context.Context, providers []*Provider) error {
+	var problems []string
+	for _, p := range providers {
+		if err := p.Health(ctx); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", p.Name, err))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package sso
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// oidcServer serves a Keycloak realm "home" with the given health status
+// and signing keys
+func oidcServer(t *testing.T, status, keys string) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health/ready", "/api/health":
+			if status != "UP" && status != "OK" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			w.Write([]byte(`{"status": "` + status + `"}`))
+		case "/realms/home/.well-known/openid-configuration", "/.well-known/openid-configuration":
+			w.Write([]byte(`{
+				"issuer": "` + server.URL + `/realms/home",
+				"authorization_endpoint": "` + server.URL + `/auth",
+				"token_endpoint": "` + server.URL + `/token",
+				"jwks_uri": "` + server.URL + `/certs"
+			}`))
+		case "/certs":
+			w.Write([]byte(`{"keys": ` + keys + `}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestKeycloak(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  string
+		keys    string
+		realm   string
+		wantErr string
+	}{
+		{name: "healthy", status: "UP", keys: `[{"kid": "a"}]`, realm: "home"},
+		{name: "down", status: "DOWN", keys: `[]`, realm: "home", wantErr: "health: unexpected status: 503"},
+		{name: "no keys", status: "UP", keys: `[]`, realm: "home", wantErr: "jwks: no signing keys"},
+		{name: "unknown realm", status: "UP", keys: `[]`, realm: "work", wantErr: "discovery: unexpected status: 404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := oidcServer(t, tt.status, tt.keys)
+			err := NewKeycloak(server.URL, tt.realm, "", 5*time.Second).Health(context.Background())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Health() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Health() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthelia(t *testing.T) {
+	server := oidcServer(t, "OK", `[{"kid": "a"}]`)
+	if err := NewAuthelia(server.URL, true, 5*time.Second).Health(context.Background()); err != nil {
+		t.Errorf("Health() error = %v", err)
+	}
+
+	// Without OIDC only the health endpoint is checked
+	server = oidcServer(t, "OK", `[]`)
+	if err := NewAuthelia(server.URL, false, 5*time.Second).Health(context.Background()); err != nil {
+		t.Errorf("Health() error = %v", err)
+	}
+}
+
+func TestDiscover_Missing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"issuer": "x", "authorization_endpoint": "y"}`))
+	}))
+	defer server.Close()
+
+	p := &Provider{DiscoveryURL: server.URL, httpClient: &http.Client{Timeout: 5 * time.Second}}
+	if _, err := p.Discover(context.Background()); err == nil || err.Error() != "missing token_endpoint, jwks_uri" {
+		t.Errorf("Discover() error = %v", err)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	up := oidcServer(t, "UP", `[{"kid": "a"}]`)
+	providers := []*Provider{
+		NewKeycloak(up.URL, "home", "", 5*time.Second),
+		NewAuthelia("http://127.0.0.1:1", false, 5*time.Second),
+	}
+	err := Health(context.Background(), providers)
+	if err == nil || !strings.HasPrefix(err.Error(), "authelia: health: request failed") {
+		t.Errorf("Health() error = %v, want authelia unreachable", err)
+	}
+}
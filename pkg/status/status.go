@@ -0,0 +1,307 @@
+// Package status serves a sidecar's state over HTTP, so it can be curled or
+// watched by an uptime monitor without reading the journal:
+//
+//	/healthz  200 if the last check ran without error, 503 otherwise
+//	/readyz   200 once a check has completed without error
+//	/status   the last result, inhibitor state and timings as JSON
+//
+// All endpoints are read-only. To expose them beyond localhost, e.g. to a
+// dashboard, require a bearer token, client certificates or both.
+package status
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+	"github.com/addisonbair/homelab-sidecars/pkg/metrics"
+)
+
+// Report is the /status response
+type Report struct {
+	Check   string    `json:"check"`
+	Started time.Time `json:"started"`
+	// Inhibiting mirrors the sidecar's inhibitor: it follows the last
+	// error-free result, since a failed check keeps the previous state
+	Inhibiting      bool       `json:"inhibiting"`
+	InhibitingSince *time.Time `json:"inhibiting_since,omitempty"`
+	Reason          string     `json:"reason,omitempty"`
+	Last            *Run       `json:"last,omitempty"`
+	Runs            int        `json:"runs"`
+	Errors          int        `json:"errors"`
+	Gauges          []Gauge    `json:"gauges,omitempty"`
+}
+
+// Run is the outcome of one check run
+type Run struct {
+	Busy            bool      `json:"busy"`
+	Reason          string    `json:"reason,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Time            time.Time `json:"time"`
+}
+
+// Gauge is a metrics.Gauge from one of the wrapped checker's sources
+type Gauge struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+}
+
+// Config is where the status server listens and who it lets in
+type Config struct {
+	Addr string // e.g. "127.0.0.1:9280"; empty disables the server
+
+	// Token, or the contents of TokenFile, is required on every request
+	// as "Authorization: Bearer <token>"
+	Token     string
+	TokenFile string
+
+	// CertFile and KeyFile serve HTTPS; ClientCAFile additionally requires
+	// a client certificate signed by one of its CAs
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+}
+
+// ConfigFromEnv reads the config from STATUS_* environment variables:
+//
+//	STATUS_ADDR           listen address, e.g. 127.0.0.1:9280
+//	STATUS_TOKEN          bearer token required on every request
+//	STATUS_TOKEN_FILE     file holding the token instead
+//	STATUS_TLS_CERT       certificate to serve HTTPS with
+//	STATUS_TLS_KEY        its private key
+//	STATUS_TLS_CLIENT_CA  CA bundle client certificates must chain to
+func ConfigFromEnv() Config {
+	return Config{
+		Addr:         os.Getenv("STATUS_ADDR"),
+		Token:        os.Getenv("STATUS_TOKEN"),
+		TokenFile:    os.Getenv("STATUS_TOKEN_FILE"),
+		CertFile:     os.Getenv("STATUS_TLS_CERT"),
+		KeyFile:      os.Getenv("STATUS_TLS_KEY"),
+		ClientCAFile: os.Getenv("STATUS_TLS_CLIENT_CA"),
+	}
+}
+
+// statusChecker records each check run for the HTTP handlers
+type statusChecker struct {
+	sidecar.Checker
+	sources []metrics.Source
+	token   string
+
+	mu     sync.Mutex
+	report Report
+	ready  bool
+}
+
+// Serve wraps checker so its results are served on cfg.Addr, together
+// with any gauges from sources. An empty address returns checker
+// unchanged. The listener is opened and the token and certificates are
+// loaded before returning, so a port already in use or a missing file is
+// reported here rather than logged later.
+func Serve(checker sidecar.Checker, cfg Config, sources ...metrics.Source) (sidecar.Checker, error) {
+	if cfg.Addr == "" {
+		return checker, nil
+	}
+
+	token := cfg.Token
+	if token == "" && cfg.TokenFile != "" {
+		data, err := os.ReadFile(cfg.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("read token: %w", err)
+		}
+		if token = strings.TrimSpace(string(data)); token == "" {
+			return nil, fmt.Errorf("read token: %s is empty", cfg.TokenFile)
+		}
+	}
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if token == "" && cfg.ClientCAFile == "" && !loopback(cfg.Addr) {
+		logging.Warnf("status server on %s has no authentication; set STATUS_TOKEN or STATUS_TLS_CLIENT_CA", cfg.Addr)
+	}
+
+	ln, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	scheme := "http"
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+		scheme = "https"
+	}
+
+	c := newStatusChecker(checker, sources...)
+	c.token = token
+	srv := &http.Server{Handler: c.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Errorf("status server: %v", err)
+		}
+	}()
+	logging.Infof("Serving status on %s://%s/status", scheme, ln.Addr())
+	return c, nil
+}
+
+// tlsConfig loads the certificates, or returns nil to serve plain HTTP.
+func (cfg Config) tlsConfig() (*tls.Config, error) {
+	if cfg.CertFile == "" && cfg.KeyFile == "" {
+		if cfg.ClientCAFile != "" {
+			return nil, errors.New("a client CA needs a certificate and key to serve HTTPS")
+		}
+		return nil, nil
+	}
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.New("TLS needs both a certificate and a key")
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load certificate: %w", err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("load client CA: no certificates in %s", cfg.ClientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// loopback reports whether addr only listens on the local machine.
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func newStatusChecker(checker sidecar.Checker, sources ...metrics.Source) *statusChecker {
+	return &statusChecker{
+		Checker: checker,
+		sources: sources,
+		report:  Report{Check: checker.Name(), Started: time.Now()},
+	}
+}
+
+// Check runs the wrapped checker and records the result.
+func (c *statusChecker) Check(ctx context.Context) (bool, string, error) {
+	start := time.Now()
+	busy, reason, err := c.Checker.Check(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	run := &Run{
+		Busy:            busy,
+		Reason:          reason,
+		DurationSeconds: time.Since(start).Seconds(),
+		Time:            start,
+	}
+	r := &c.report
+	r.Runs++
+	if err != nil {
+		run.Error = err.Error()
+		r.Errors++
+	} else {
+		c.ready = true
+		if busy && !r.Inhibiting {
+			since := start
+			r.InhibitingSince = &since
+		} else if !busy {
+			r.InhibitingSince = nil
+		}
+		r.Inhibiting = busy
+		r.Reason = reason
+	}
+	r.Last = run
+	return busy, reason, err
+}
+
+// Handler serves /healthz, /readyz and /status, behind the token if set.
+func (c *statusChecker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", c.healthz)
+	mux.HandleFunc("GET /readyz", c.readyz)
+	mux.HandleFunc("GET /status", c.status)
+	if c.token == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(c.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="status"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (c *statusChecker) healthz(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	last := c.report.Last
+	c.mu.Unlock()
+
+	if last != nil && last.Error != "" {
+		http.Error(w, fmt.Sprintf("%s: %s", c.Name(), last.Error), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+func (c *statusChecker) readyz(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	ready := c.ready
+	c.mu.Unlock()
+
+	if !ready {
+		http.Error(w, "no successful check yet", http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+func (c *statusChecker) status(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	report := c.report
+	c.mu.Unlock()
+
+	for _, src := range c.sources {
+		for _, g := range src.Gauges() {
+			report.Gauges = append(report.Gauges, Gauge{Name: g.Name, Labels: g.Labels, Value: g.Value})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		logging.Warnf("status: %v", err)
+	}
+}
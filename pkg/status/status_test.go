@@ -0,0 +1,257 @@
+package status
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sidecar "github.com/addisonbair/go-systemd-sidecar"
+	"github.com/addisonbair/homelab-sidecars/pkg/metrics"
+)
+
+type fakeSource struct{}
+
+func (fakeSource) Gauges() []metrics.Gauge {
+	return []metrics.Gauge{{Name: "homelab_check_flapping", Labels: map[string]string{"check": "raid"}, Value: 1}}
+}
+
+func TestStatusChecker(t *testing.T) {
+	var (
+		busy   bool
+		reason string
+		err    error
+	)
+	c := newStatusChecker(sidecar.NewCheckerFunc("raid", func(ctx context.Context) (bool, string, error) {
+		return busy, reason, err
+	}), fakeSource{})
+	srv := httptest.NewServer(c.Handler())
+	defer srv.Close()
+
+	get := func(path string) int {
+		t.Helper()
+		resp, gerr := http.Get(srv.URL + path)
+		if gerr != nil {
+			t.Fatalf("GET %s: %v", path, gerr)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	report := func() Report {
+		t.Helper()
+		resp, gerr := http.Get(srv.URL + "/status")
+		if gerr != nil {
+			t.Fatalf("GET /status: %v", gerr)
+		}
+		defer resp.Body.Close()
+		var r Report
+		if derr := json.NewDecoder(resp.Body).Decode(&r); derr != nil {
+			t.Fatalf("decode /status: %v", derr)
+		}
+		return r
+	}
+
+	if code := get("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz before a check = %d, want 503", code)
+	}
+	if code := get("/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz before a check = %d, want 200", code)
+	}
+
+	busy, reason = true, "md0 rebuilding"
+	c.Check(context.Background())
+	r := report()
+	if !r.Inhibiting || r.Reason != "md0 rebuilding" || r.InhibitingSince == nil || r.Runs != 1 {
+		t.Errorf("busy: report = %+v", r)
+	}
+	if len(r.Gauges) != 1 || r.Gauges[0].Name != "homelab_check_flapping" {
+		t.Errorf("gauges = %+v", r.Gauges)
+	}
+	if code := get("/readyz"); code != http.StatusOK {
+		t.Errorf("/readyz after a check = %d, want 200", code)
+	}
+
+	// A failed check keeps the inhibitor as it was
+	busy, reason, err = false, "", errors.New("mdstat unreadable")
+	c.Check(context.Background())
+	r = report()
+	if !r.Inhibiting || r.Errors != 1 || r.Last.Error != "mdstat unreadable" {
+		t.Errorf("error: report = %+v", r)
+	}
+	if code := get("/healthz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/healthz after an error = %d, want 503", code)
+	}
+
+	err = nil
+	c.Check(context.Background())
+	r = report()
+	if r.Inhibiting || r.InhibitingSince != nil || r.Runs != 3 {
+		t.Errorf("idle: report = %+v", r)
+	}
+	if code := get("/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz after recovery = %d, want 200", code)
+	}
+}
+
+func TestServe_Disabled(t *testing.T) {
+	checker := sidecar.NewCheckerFunc("raid", func(ctx context.Context) (bool, string, error) {
+		return false, "", nil
+	})
+	got, err := Serve(checker, Config{})
+	if err != nil || got != checker {
+		t.Errorf("Serve with no address = %v, %v; want the checker unchanged", got, err)
+	}
+}
+
+func TestHandler_Token(t *testing.T) {
+	c := newStatusChecker(sidecar.NewCheckerFunc("raid", func(ctx context.Context) (bool, string, error) {
+		return false, "", nil
+	}))
+	c.token = "s3cret"
+	srv := httptest.NewServer(c.Handler())
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		auth string
+		want int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"s3cret", http.StatusUnauthorized},
+		{"Bearer s3cret", http.StatusOK},
+	} {
+		req, _ := http.NewRequest("GET", srv.URL+"/status", nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("Authorization %q: status %d, want %d", tt.auth, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+// writeCert writes a certificate and key signed by parent (self-signed if
+// nil) to dir and returns it.
+func writeCert(t *testing.T, dir, name string, parent *tls.Certificate, isCA bool) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if isCA {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	}
+	signer, signerKey := tmpl, any(key)
+	if parent != nil {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	os.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	leaf, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestServe_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := writeCert(t, dir, "ca", nil, true)
+	writeCert(t, dir, "server", &ca, false)
+	client := writeCert(t, dir, "client", &ca, false)
+	stranger := writeCert(t, dir, "stranger", nil, false)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	checker := sidecar.NewCheckerFunc("raid", func(ctx context.Context) (bool, string, error) {
+		return false, "", nil
+	})
+	_, err = Serve(checker, Config{
+		Addr:         addr,
+		Token:        "s3cret",
+		CertFile:     filepath.Join(dir, "server.crt"),
+		KeyFile:      filepath.Join(dir, "server.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+	get := func(cert *tls.Certificate) (int, error) {
+		tlsConfig := &tls.Config{RootCAs: roots}
+		if cert != nil {
+			tlsConfig.Certificates = []tls.Certificate{*cert}
+		}
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}, Timeout: 5 * time.Second}
+		req, _ := http.NewRequest("GET", "https://"+addr+"/healthz", nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		resp, err := c.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	if code, err := get(&client); err != nil || code != http.StatusOK {
+		t.Errorf("with a client certificate: %d, %v; want 200", code, err)
+	}
+	if _, err := get(nil); err == nil {
+		t.Error("without a client certificate: want a handshake error")
+	}
+	if _, err := get(&stranger); err == nil {
+		t.Error("with a certificate from another CA: want a handshake error")
+	}
+}
+
+func TestServe_InvalidConfig(t *testing.T) {
+	checker := sidecar.NewCheckerFunc("raid", func(ctx context.Context) (bool, string, error) {
+		return false, "", nil
+	})
+	for _, cfg := range []Config{
+		{Addr: "127.0.0.1:0", CertFile: "server.crt"},
+		{Addr: "127.0.0.1:0", ClientCAFile: "ca.crt"},
+		{Addr: "127.0.0.1:0", TokenFile: filepath.Join(t.TempDir(), "missing")},
+	} {
+		if _, err := Serve(checker, cfg); err == nil {
+			t.Errorf("Serve(%+v): want error", cfg)
+		}
+	}
+}
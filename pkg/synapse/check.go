@@ -0,0 +1,79 @@
+package synapse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Checker implements check.Checker for Synapse maintenance work.
+// Returns an error while background database updates are running, history
+// or rooms are being purged, or media is being uploaded, so the homeserver
+// isn't rebooted in the middle of them.
+//
+// The purge and upload checks need the metrics endpoints; if they can't be
+// read only background updates are checked.
+type Checker struct {
+	Client *Client
+}
+
+// NewChecker creates a Synapse activity checker.
+func NewChecker(client *Client) *Checker {
+	return &Checker{Client: client}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "synapse"
+}
+
+// Check returns nil if Synapse is idle, error describing the activity
+// otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	reasons, err := c.Activity(ctx)
+	if IsUnauthorized(err) {
+		// Synapse is up but the token isn't an admin's; don't assume idle
+		return fmt.Errorf("cannot query synapse: %w", err)
+	}
+	if err != nil {
+		// Synapse being down shouldn't hold up reboots
+		return nil
+	}
+	if len(reasons) > 0 {
+		return errors.New(strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// Activity returns a description of each reason Synapse is busy.
+func (c *Checker) Activity(ctx context.Context) ([]string, error) {
+	updates, err := c.Client.BackgroundUpdates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("background updates: %w", err)
+	}
+
+	var reasons []string
+	for _, u := range updates {
+		reasons = append(reasons, fmt.Sprintf("database update %s on %s", u.Name, u.Database))
+	}
+
+	if f, err := c.Client.InFlight(ctx); err == nil && f != nil {
+		if len(f.Purges) > 0 {
+			reasons = append(reasons, fmt.Sprintf("purging: %s", strings.Join(f.PurgeNames(), ", ")))
+		}
+		if f.Uploads > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d media upload(s) in progress", f.Uploads))
+		}
+	}
+
+	return reasons, nil
+}
+
+// IsUnauthorized reports whether err is Synapse rejecting the access token,
+// or the token not belonging to a server admin, as opposed to Synapse being
+// unreachable.
+func IsUnauthorized(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && (apiErr.StatusCode == 401 || apiErr.StatusCode == 403)
+}
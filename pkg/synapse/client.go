@@ -0,0 +1,117 @@
+// Package synapse provides a client for the parts of the Synapse Matrix
+// homeserver's admin API and metrics that show maintenance work: background
+// database migrations, history and room purges, and media uploads.
+package synapse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BackgroundUpdate is a database migration Synapse runs in the background
+// after an upgrade, e.g. populating a new index
+type BackgroundUpdate struct {
+	Database       string  // e.g. "master", or the name of a state database
+	Name           string  `json:"name"`
+	TotalItemCount int     `json:"total_item_count"`
+	ItemsPerMs     float64 `json:"average_items_per_ms"`
+}
+
+type backgroundUpdatesResponse struct {
+	Enabled        bool                        `json:"enabled"`
+	CurrentUpdates map[string]BackgroundUpdate `json:"current_updates"`
+}
+
+// APIError is an error response from the admin API
+type APIError struct {
+	StatusCode int
+	Errcode    string `json:"errcode"` // e.g. "M_FORBIDDEN"
+	Message    string `json:"error"`
+}
+
+func (e *APIError) Error() string {
+	if e.Errcode != "" {
+		return fmt.Sprintf("%s (status %d): %s", e.Errcode, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("unexpected status: %d", e.StatusCode)
+}
+
+// Client handles communication with Synapse. The admin API needs the access
+// token of a server admin; the metrics endpoints are only read if
+// enable_metrics is set and a metrics listener is configured.
+type Client struct {
+	baseURL     string
+	token       string
+	metricsURLs []string
+	httpClient  *http.Client
+}
+
+// NewClient creates a new Synapse client. metricsURLs are the metrics
+// endpoints of the main process and any workers, e.g.
+// "http://localhost:9000/_synapse/metrics"; none disables the purge and
+// upload checks.
+func NewClient(baseURL, token string, metricsURLs []string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		token:       token,
+		metricsURLs: metricsURLs,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// BackgroundUpdates returns the background updates currently running, one
+// per database, sorted by database. It is empty once all updates are done.
+func (c *Client) BackgroundUpdates(ctx context.Context) ([]BackgroundUpdate, error) {
+	resp, err := c.get(ctx, c.baseURL+"/_synapse/admin/v1/background_updates/status", c.token)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var status backgroundUpdatesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	var updates []BackgroundUpdate
+	for db, u := range status.CurrentUpdates {
+		u.Database = db
+		updates = append(updates, u)
+	}
+	sort.Slice(updates, func(i, j int) bool {
+		return updates[i].Database < updates[j].Database
+	})
+	return updates, nil
+}
+
+// get performs a GET, returning a non-200 response as an *APIError.
+func (c *Client) get(ctx context.Context, url, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		json.Unmarshal(body, apiErr)
+		return nil, apiErr
+	}
+	return resp, nil
+}
@@ -0,0 +1,134 @@
+package synapse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const idleUpdates = `{"enabled": true, "current_updates": {}}`
+
+const idleMetrics = `# HELP synapse_background_process_in_flight_count Number of background processes in flight
+# TYPE synapse_background_process_in_flight_count gauge
+synapse_background_process_in_flight_count{name="_purge_history"} 0.0
+synapse_background_process_in_flight_count{name="notify_app_services"} 1.0
+synapse_http_server_in_flight_requests_count{method="GET",servlet="RoomMessageListRestServlet"} 2.0
+`
+
+func TestChecker_Activity(t *testing.T) {
+	tests := []struct {
+		name         string
+		updates      string
+		updateStatus int
+		metrics      string
+		worker       string // media worker metrics
+		want         string
+		wantErr      bool
+	}{
+		{
+			name:    "idle",
+			updates: idleUpdates,
+			metrics: idleMetrics,
+			worker:  "synapse_http_server_in_flight_requests_count{method=\"GET\",servlet=\"DownloadResource\"} 3.0\n",
+			want:    "",
+		},
+		{
+			name: "background update after upgrade",
+			updates: `{"enabled": true, "current_updates": {"master": {"name": "event_search_fts_index",
+				"total_item_count": 50, "total_duration_ms": 1000.0, "average_items_per_ms": 0.05}}}`,
+			metrics: idleMetrics,
+			want:    "database update event_search_fts_index on master",
+		},
+		{
+			name:    "history purge and room deletion",
+			updates: idleUpdates,
+			metrics: idleMetrics + `synapse_background_process_in_flight_count{name="_shutdown_and_purge_room"} 1.0
+synapse_background_process_in_flight_count{name="purge_history_for_rooms_in_range"} 2.0
+`,
+			want: "purging: _shutdown_and_purge_room, purge_history_for_rooms_in_range",
+		},
+		{
+			name:    "uploads on the media worker",
+			updates: idleUpdates,
+			metrics: idleMetrics,
+			worker: `synapse_http_server_in_flight_requests_count{method="POST",servlet="UploadServlet"} 2.0
+synapse_http_server_in_flight_requests_count{method="PUT",servlet="AsyncUploadServlet"} 1.0
+synapse_http_server_in_flight_requests_count{method="OPTIONS",servlet="UploadServlet"} 1.0
+`,
+			want: "3 media upload(s) in progress",
+		},
+		{
+			name:    "metrics unavailable",
+			updates: idleUpdates,
+			want:    "",
+		},
+		{
+			name:         "not an admin",
+			updates:      `{"errcode": "M_FORBIDDEN", "error": "You are not a server admin"}`,
+			updateStatus: 403,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/_synapse/admin/v1/background_updates/status":
+					if r.Header.Get("Authorization") != "Bearer admin-token" {
+						t.Errorf("missing admin token")
+					}
+					if tt.updateStatus != 0 {
+						w.WriteHeader(tt.updateStatus)
+					}
+					w.Write([]byte(tt.updates))
+				case "/main/_synapse/metrics", "/worker/_synapse/metrics":
+					body := tt.metrics
+					if strings.HasPrefix(r.URL.Path, "/worker") {
+						body = tt.worker
+					}
+					if body == "" {
+						w.WriteHeader(http.StatusNotFound)
+					}
+					w.Write([]byte(body))
+				default:
+					t.Errorf("unexpected path: %s", r.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			metricsURLs := []string{server.URL + "/main/_synapse/metrics"}
+			if tt.worker != "" {
+				metricsURLs = append(metricsURLs, server.URL+"/worker/_synapse/metrics")
+			}
+			checker := NewChecker(NewClient(server.URL, "admin-token", metricsURLs, 5*time.Second))
+
+			reasons, err := checker.Activity(context.Background())
+			if tt.wantErr {
+				if !IsUnauthorized(err) {
+					t.Fatalf("err = %v, want unauthorized", err)
+				}
+				if err := checker.Check(context.Background()); err == nil {
+					t.Error("Check passed with a rejected token")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := strings.Join(reasons, "; "); got != tt.want {
+				t.Errorf("Activity = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChecker_Unreachable(t *testing.T) {
+	checker := NewChecker(NewClient("http://127.0.0.1:1", "admin-token", nil, time.Second))
+	if err := checker.Check(context.Background()); err != nil {
+		t.Errorf("Check = %v, want nil when Synapse is down", err)
+	}
+}
@@ -0,0 +1,132 @@
+package synapse
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// InFlight is the maintenance work Synapse's metrics show as running
+type InFlight struct {
+	// Purges counts running purge background processes by name, e.g.
+	// "_purge_history" or "_shutdown_and_purge_room"
+	Purges map[string]int
+	// Uploads counts media upload requests being received
+	Uploads int
+}
+
+// PurgeNames returns the names of the running purges, sorted.
+func (f *InFlight) PurgeNames() []string {
+	return slices.Sorted(maps.Keys(f.Purges))
+}
+
+const (
+	backgroundProcessMetric = "synapse_background_process_in_flight_count"
+	requestsMetric          = "synapse_http_server_in_flight_requests_count"
+)
+
+// InFlight sums the running purges and uploads over every metrics endpoint,
+// since uploads are usually handled by a media repository worker. It
+// returns nil without error if no metrics endpoints are configured.
+func (c *Client) InFlight(ctx context.Context) (*InFlight, error) {
+	if len(c.metricsURLs) == 0 {
+		return nil, nil
+	}
+
+	f := &InFlight{Purges: make(map[string]int)}
+	for _, url := range c.metricsURLs {
+		resp, err := c.get(ctx, url, "")
+		if err != nil {
+			return nil, fmt.Errorf("metrics %s: %w", url, err)
+		}
+		samples, err := parseSamples(resp.Body, backgroundProcessMetric, requestsMetric)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("metrics %s: %w", url, err)
+		}
+		f.add(samples)
+	}
+	return f, nil
+}
+
+func (f *InFlight) add(samples []sample) {
+	for _, s := range samples {
+		n := int(s.value)
+		if n <= 0 {
+			continue
+		}
+		switch s.name {
+		case backgroundProcessMetric:
+			// Covers history purges, retention purges and room deletion
+			if name := s.labels["name"]; strings.Contains(name, "purge") {
+				f.Purges[name] += n
+			}
+		case requestsMetric:
+			// UploadServlet, or AsyncUploadServlet for PUTs to a
+			// media ID created beforehand
+			method := s.labels["method"]
+			if strings.Contains(s.labels["servlet"], "Upload") && (method == "POST" || method == "PUT") {
+				f.Uploads += n
+			}
+		}
+	}
+}
+
+type sample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+var labelPair = regexp.MustCompile(`(\w+)="((?:[^"\\]|\\.)*)"`)
+
+// parseSamples returns the samples of the named metrics from Prometheus
+// text exposition.
+func parseSamples(r io.Reader, names ...string) ([]sample, error) {
+	var samples []sample
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		name, rest, _ := strings.Cut(line, "{")
+		labels := ""
+		if rest != "" {
+			end := strings.LastIndex(rest, "}")
+			if end < 0 {
+				continue
+			}
+			labels, rest = rest[:end], rest[end+1:]
+		} else {
+			name, rest, _ = strings.Cut(line, " ")
+		}
+
+		if !slices.Contains(names, name) {
+			continue
+		}
+
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		v, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", name, err)
+		}
+
+		s := sample{name: name, labels: make(map[string]string), value: v}
+		for _, m := range labelPair.FindAllStringSubmatch(labels, -1) {
+			s.labels[m[1]] = m[2]
+		}
+		samples = append(samples, s)
+	}
+	return samples, scanner.Err()
+}
@@ -0,0 +1,181 @@
+package syncthing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Checker implements check.Checker for Syncthing.
+// Returns an error while a folder is syncing, or has more than
+// OutOfSyncThreshold items it still needs from other devices.
+//
+// Each folder's status is read from /rest/db/status once, and then kept up
+// to date from FolderSummary and StateChanged events, so a poll costs one
+// request however many folders there are. The statuses are read again
+// when Syncthing restarts or its configuration changes.
+type Checker struct {
+	Client *Client
+	// OutOfSyncThreshold is how many needed items an idle folder may have
+	// before it blocks, e.g. while the device that has them is offline;
+	// negative only blocks while syncing
+	OutOfSyncThreshold int
+
+	mu        sync.Mutex
+	started   time.Time
+	lastEvent int
+	folders   map[string]*folderState
+}
+
+type folderState struct {
+	folder Folder
+	status FolderStatus
+}
+
+// NewChecker creates a Syncthing checker that only blocks while syncing.
+func NewChecker(client *Client) *Checker {
+	return &Checker{Client: client, OutOfSyncThreshold: -1}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "syncthing"
+}
+
+// Check returns nil if no folder is syncing, error otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	reasons, err := c.Activity(ctx)
+	if err != nil {
+		return fmt.Errorf("syncthing check failed: %w", err)
+	}
+	if len(reasons) > 0 {
+		return errors.New(strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// Activity describes each folder that is syncing or too far out of sync,
+// e.g. "Photos syncing (120 items, 1.5 GB left)".
+func (c *Checker) Activity(ctx context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.update(ctx); err != nil {
+		// Read everything again next time rather than trust a partial update
+		c.folders = nil
+		return nil, err
+	}
+
+	var reasons []string
+	for _, f := range c.folders {
+		s := f.status
+		switch {
+		case f.folder.Paused:
+		case s.Syncing():
+			reasons = append(reasons, fmt.Sprintf("%s %s (%d items, %s left)", f.folder.Name(), s.State, s.NeedTotalItems, formatBytes(s.NeedBytes)))
+		case c.OutOfSyncThreshold >= 0 && s.NeedTotalItems > c.OutOfSyncThreshold:
+			reasons = append(reasons, fmt.Sprintf("%s out of sync (%d items, %s)", f.folder.Name(), s.NeedTotalItems, formatBytes(s.NeedBytes)))
+		}
+	}
+	sort.Strings(reasons)
+	return reasons, nil
+}
+
+// update applies the events since the last call, or reads every folder's
+// status if Syncthing restarted, its configuration changed, or this is the
+// first call.
+func (c *Checker) update(ctx context.Context) error {
+	sys, err := c.Client.SystemStatus(ctx)
+	if err != nil {
+		return err
+	}
+	if c.folders == nil || !sys.StartTime.Equal(c.started) {
+		return c.refresh(ctx, sys.StartTime)
+	}
+
+	events, err := c.Client.Events(ctx, c.lastEvent, 0, "FolderSummary", "StateChanged", "ConfigSaved")
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		c.lastEvent = e.ID
+		switch e.Type {
+		case "ConfigSaved":
+			return c.refresh(ctx, sys.StartTime)
+		case "FolderSummary":
+			var data struct {
+				Folder  string       `json:"folder"`
+				Summary FolderStatus `json:"summary"`
+			}
+			if err := json.Unmarshal(e.Data, &data); err != nil {
+				return fmt.Errorf("decode %s event: %w", e.Type, err)
+			}
+			if f, ok := c.folders[data.Folder]; ok {
+				f.status = data.Summary
+			}
+		case "StateChanged":
+			var data struct {
+				Folder string `json:"folder"`
+				To     string `json:"to"`
+			}
+			if err := json.Unmarshal(e.Data, &data); err != nil {
+				return fmt.Errorf("decode %s event: %w", e.Type, err)
+			}
+			if f, ok := c.folders[data.Folder]; ok {
+				f.status.State = data.To
+			}
+		}
+	}
+	return nil
+}
+
+// refresh reads every folder's status. The latest event ID is taken first,
+// so changes made while the folders are read are applied next time.
+func (c *Checker) refresh(ctx context.Context, started time.Time) error {
+	latest, err := c.Client.Events(ctx, 0, 1)
+	if err != nil {
+		return err
+	}
+	folders, err := c.Client.Folders(ctx)
+	if err != nil {
+		return err
+	}
+
+	states := make(map[string]*folderState, len(folders))
+	for _, f := range folders {
+		state := &folderState{folder: f}
+		if !f.Paused {
+			status, err := c.Client.FolderStatus(ctx, f.ID)
+			if err != nil {
+				return fmt.Errorf("folder %s: %w", f.Name(), err)
+			}
+			state.status = *status
+		}
+		states[f.ID] = state
+	}
+
+	c.folders = states
+	c.started = started
+	c.lastEvent = 0
+	if len(latest) > 0 {
+		c.lastEvent = latest[len(latest)-1].ID
+	}
+	return nil
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
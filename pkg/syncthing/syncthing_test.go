@@ -0,0 +1,143 @@
+package syncthing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSyncthing serves folders, their statuses and an event log the test
+// can change between checks
+type fakeSyncthing struct {
+	t         *testing.T
+	started   string
+	folders   []Folder
+	statuses  map[string]FolderStatus
+	events    []Event
+	dbQueries int
+}
+
+func (f *fakeSyncthing) event(typ string, data any) {
+	raw, _ := json.Marshal(data)
+	f.events = append(f.events, Event{ID: len(f.events) + 1, Type: typ, Data: raw})
+}
+
+func (f *fakeSyncthing) serve() *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "key" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var v any
+		switch r.URL.Path {
+		case "/rest/system/status":
+			v = map[string]string{"myID": "ME", "startTime": f.started}
+		case "/rest/config/folders":
+			v = f.folders
+		case "/rest/db/status":
+			f.dbQueries++
+			v = f.statuses[r.URL.Query().Get("folder")]
+		case "/rest/events":
+			since, _ := strconv.Atoi(r.URL.Query().Get("since"))
+			types := r.URL.Query().Get("events")
+			var out []Event
+			for _, e := range f.events {
+				if e.ID > since && (types == "" || strings.Contains(types, e.Type)) {
+					out = append(out, e)
+				}
+			}
+			if limit, _ := strconv.Atoi(r.URL.Query().Get("limit")); limit > 0 && len(out) > limit {
+				out = out[len(out)-limit:]
+			}
+			v = out
+		default:
+			f.t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(v)
+	}))
+	f.t.Cleanup(server.Close)
+	return server
+}
+
+func TestChecker(t *testing.T) {
+	f := &fakeSyncthing{
+		t:       t,
+		started: "2024-06-01T10:00:00Z",
+		folders: []Folder{
+			{ID: "abcd-1234", Label: "Photos"},
+			{ID: "docs"},
+			{ID: "old", Paused: true},
+		},
+		statuses: map[string]FolderStatus{
+			"abcd-1234": {State: "syncing", NeedTotalItems: 120, NeedBytes: 3 << 29},
+			"docs":      {State: "idle"},
+		},
+	}
+	f.event("Starting", nil)
+	server := f.serve()
+	checker := NewChecker(NewClient(server.URL, "key", 5*time.Second))
+
+	activity := func() string {
+		t.Helper()
+		reasons, err := checker.Activity(context.Background())
+		if err != nil {
+			t.Fatalf("Activity() error = %v", err)
+		}
+		return strings.Join(reasons, "|")
+	}
+
+	if got, want := activity(), "Photos syncing (120 items, 1.5 GB left)"; got != want {
+		t.Errorf("Activity() = %q, want %q", got, want)
+	}
+	if f.dbQueries != 2 {
+		t.Errorf("db queries = %d, want 2 (paused folder skipped)", f.dbQueries)
+	}
+
+	// Events bring the statuses up to date without querying each folder
+	f.event("FolderSummary", map[string]any{"folder": "abcd-1234", "summary": FolderStatus{State: "idle", NeedTotalItems: 3, NeedBytes: 2048}})
+	f.event("StateChanged", map[string]any{"folder": "docs", "from": "idle", "to": "sync-preparing"})
+	if got, want := activity(), "docs sync-preparing (0 items, 0 B left)"; got != want {
+		t.Errorf("Activity() after events = %q, want %q", got, want)
+	}
+	if f.dbQueries != 2 {
+		t.Errorf("db queries = %d, want still 2", f.dbQueries)
+	}
+
+	// Out-of-sync items block above the threshold
+	f.event("StateChanged", map[string]any{"folder": "docs", "from": "sync-preparing", "to": "idle"})
+	checker.OutOfSyncThreshold = 2
+	if got, want := activity(), "Photos out of sync (3 items, 2.0 KB)"; got != want {
+		t.Errorf("Activity() with threshold = %q, want %q", got, want)
+	}
+	checker.OutOfSyncThreshold = -1
+
+	// A restart starts the event IDs over, so everything is read again
+	f.started = "2024-06-01T11:00:00Z"
+	f.events = nil
+	f.event("Starting", nil)
+	f.statuses["abcd-1234"] = FolderStatus{State: "idle"}
+	if got := activity(); got != "" {
+		t.Errorf("Activity() after restart = %q, want none", got)
+	}
+	if f.dbQueries != 4 {
+		t.Errorf("db queries = %d, want 4 after restart", f.dbQueries)
+	}
+}
+
+func TestChecker_Unauthorized(t *testing.T) {
+	f := &fakeSyncthing{t: t, started: "2024-06-01T10:00:00Z"}
+	server := f.serve()
+
+	err := NewChecker(NewClient(server.URL, "wrong", 5*time.Second)).Check(context.Background())
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Check() error = %v, want ErrUnauthorized", err)
+	}
+}
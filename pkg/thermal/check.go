@@ -0,0 +1,115 @@
+package thermal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/check"
+)
+
+// Checker implements check.Evaluator for temperatures.
+// It never blocks: it returns ForceAllow while a sensor is at or above its
+// critical temperature, so other checks can't keep an overheating machine
+// running, and Neutral otherwise.
+type Checker struct {
+	// Root is where sysfs is mounted ("" = DefaultSysfsRoot)
+	Root string
+	// Sensors picks the sensors checked, as path.Match patterns against
+	// Reading.Sensor, e.g. "coretemp/*"; empty checks all of them
+	Sensors []string
+	// Ignore skips sensors matching any of these patterns, e.g. a chipset
+	// sensor that always reads high
+	Ignore []string
+	// Critical is the temperature, in °C, at or above which a sensor is
+	// overheating; 0 uses each sensor's own critical temperature, and
+	// skips sensors without one
+	Critical float64
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "thermal"
+}
+
+// Check always returns nil; temperature never blocks shutdown.
+func (c *Checker) Check(ctx context.Context) error {
+	return nil
+}
+
+// Evaluate returns ForceAllow while a sensor is overheating.
+func (c *Checker) Evaluate(ctx context.Context) check.Result {
+	hot, err := c.Hot(0)
+	if err != nil || len(hot) == 0 {
+		// Unreadable sensors can't vouch for anything
+		return check.Result{Check: c.Name(), Verdict: check.Neutral}
+	}
+	return check.Result{Check: c.Name(), Verdict: check.ForceAllow, Reason: strings.Join(hot, ", ")}
+}
+
+// Health returns an error naming the sensors that are overheating.
+func (c *Checker) Health(ctx context.Context) error {
+	hot, err := c.Hot(0)
+	if err != nil {
+		return err
+	}
+	if len(hot) > 0 {
+		return fmt.Errorf("overheating: %s", strings.Join(hot, ", "))
+	}
+	return nil
+}
+
+// Hot describes the sensors at or above their critical temperature less
+// margin, e.g. "coretemp/Package id 0 98°C (critical 100°C)". A margin
+// lets a caller keep reporting a sensor until it has cooled by that much.
+func (c *Checker) Hot(margin float64) ([]string, error) {
+	readings, err := c.Readings()
+	if err != nil {
+		return nil, err
+	}
+	var hot []string
+	for _, r := range readings {
+		limit := c.limit(r)
+		if limit > 0 && r.Celsius >= limit-margin {
+			hot = append(hot, fmt.Sprintf("%s %.0f°C (critical %.0f°C)", r.Sensor, r.Celsius, limit))
+		}
+	}
+	return hot, nil
+}
+
+// Readings returns the sensors Sensors and Ignore select.
+func (c *Checker) Readings() ([]Reading, error) {
+	all, err := Read(c.Root)
+	if err != nil {
+		return nil, err
+	}
+	var readings []Reading
+	for _, r := range all {
+		if (len(c.Sensors) == 0 || matchAny(c.Sensors, r.Sensor)) && !matchAny(c.Ignore, r.Sensor) {
+			readings = append(readings, r)
+		}
+	}
+	if len(readings) == 0 {
+		return nil, errors.New("no temperature sensors found")
+	}
+	return readings, nil
+}
+
+// limit returns the critical temperature that applies to r, 0 for none
+func (c *Checker) limit(r Reading) float64 {
+	if c.Critical > 0 {
+		return c.Critical
+	}
+	return r.Critical
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+// Package thermal reads temperature sensors from sysfs: the kernel's
+// thermal zones and the hwmon chips, which between them cover CPU
+// packages, GPUs, NVMe drives and chipsets. An overheating machine should
+// shut down rather than wait for a stream or a rebuild, so the checker
+// force-allows shutdown, as the UPS does on a critical battery.
+package thermal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultSysfsRoot is where sysfs is mounted
+const DefaultSysfsRoot = "/sys"
+
+// Reading is one sensor's temperature
+type Reading struct {
+	// Sensor names the sensor as "<chip>/<label>", e.g.
+	// "coretemp/Package id 0", "nvme/Composite" or "zone/x86_pkg_temp"
+	Sensor  string
+	Celsius float64
+	// Critical is the sensor's own critical temperature, 0 if it has none
+	Critical float64
+}
+
+// Read returns every temperature sensor under root ("" = DefaultSysfsRoot),
+// hwmon chips first. A sensor whose temperature can't be read, e.g. a
+// drive in standby, is skipped.
+func Read(root string) ([]Reading, error) {
+	if root == "" {
+		root = DefaultSysfsRoot
+	}
+	hwmon, err := readHwmon(filepath.Join(root, "class/hwmon"))
+	if err != nil {
+		return nil, err
+	}
+	zones, err := readZones(filepath.Join(root, "class/thermal"))
+	if err != nil {
+		return nil, err
+	}
+	readings := append(hwmon, zones...)
+
+	// Two drives of the same model give the same names
+	seen := map[string]int{}
+	for i, r := range readings {
+		seen[r.Sensor]++
+		if n := seen[r.Sensor]; n > 1 {
+			readings[i].Sensor = fmt.Sprintf("%s #%d", r.Sensor, n)
+		}
+	}
+	return readings, nil
+}
+
+// readHwmon reads the temp<N>_input attributes of each hwmon<N> chip
+func readHwmon(dir string) ([]Reading, error) {
+	chips, err := filepath.Glob(filepath.Join(dir, "hwmon*"))
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(chips, compareNumbered)
+
+	var readings []Reading
+	for _, chip := range chips {
+		name := readString(filepath.Join(chip, "name"))
+		if name == "" {
+			name = filepath.Base(chip)
+		}
+		inputs, _ := filepath.Glob(filepath.Join(chip, "temp*_input"))
+		slices.SortFunc(inputs, compareNumbered)
+		for _, input := range inputs {
+			prefix := strings.TrimSuffix(input, "_input")
+			milli, err := readInt(input)
+			if err != nil {
+				continue
+			}
+			label := readString(prefix + "_label")
+			if label == "" {
+				label = filepath.Base(prefix)
+			}
+			r := Reading{Sensor: name + "/" + label, Celsius: float64(milli) / 1000}
+			if crit, err := readInt(prefix + "_crit"); err == nil && crit > 0 {
+				r.Critical = float64(crit) / 1000
+			}
+			readings = append(readings, r)
+		}
+	}
+	return readings, nil
+}
+
+// readZones reads each thermal_zone<N>, taking its critical temperature
+// from the trip point of type "critical"
+func readZones(dir string) ([]Reading, error) {
+	zones, err := filepath.Glob(filepath.Join(dir, "thermal_zone*"))
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(zones, compareNumbered)
+
+	var readings []Reading
+	for _, zone := range zones {
+		milli, err := readInt(filepath.Join(zone, "temp"))
+		if err != nil {
+			continue
+		}
+		typ := readString(filepath.Join(zone, "type"))
+		if typ == "" {
+			typ = filepath.Base(zone)
+		}
+		r := Reading{Sensor: "zone/" + typ, Celsius: float64(milli) / 1000}
+		trips, _ := filepath.Glob(filepath.Join(zone, "trip_point_*_type"))
+		for _, trip := range trips {
+			if readString(trip) != "critical" {
+				continue
+			}
+			if crit, err := readInt(strings.TrimSuffix(trip, "_type") + "_temp"); err == nil && crit > 0 {
+				r.Critical = float64(crit) / 1000
+			}
+		}
+		readings = append(readings, r)
+	}
+	return readings, nil
+}
+
+func readString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func readInt(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// compareNumbered orders paths such as hwmon2 and hwmon10, or temp2_input
+// and temp10_input, by their number
+func compareNumbered(a, b string) int {
+	if n := len(a) - len(b); n != 0 {
+		return n
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,101 @@
+package thermal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/check"
+)
+
+func writeAttrs(t *testing.T, dir string, attrs map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range attrs {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(value+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// testRoot is a sysfs with a CPU, two identical NVMe drives and a thermal
+// zone
+func testRoot(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeAttrs(t, filepath.Join(root, "class/hwmon/hwmon0"), map[string]string{"name": "acpitz"})
+	writeAttrs(t, filepath.Join(root, "class/hwmon/hwmon1"), map[string]string{
+		"name":        "coretemp",
+		"temp1_input": "62000", "temp1_label": "Package id 0", "temp1_crit": "100000",
+		"temp10_input": "58000", "temp10_label": "Core 8", "temp10_crit": "100000",
+	})
+	writeAttrs(t, filepath.Join(root, "class/hwmon/hwmon2"), map[string]string{
+		"name": "nvme", "temp1_input": "45850", "temp1_label": "Composite", "temp1_crit": "84850",
+	})
+	writeAttrs(t, filepath.Join(root, "class/hwmon/hwmon10"), map[string]string{
+		"name": "nvme", "temp1_input": "86850", "temp1_label": "Composite", "temp1_crit": "84850",
+	})
+	writeAttrs(t, filepath.Join(root, "class/thermal/thermal_zone0"), map[string]string{
+		"type": "x86_pkg_temp", "temp": "63000",
+		"trip_point_0_type": "passive", "trip_point_0_temp": "0",
+		"trip_point_1_type": "critical", "trip_point_1_temp": "105000",
+	})
+	// Sensor of a drive in standby
+	writeAttrs(t, filepath.Join(root, "class/thermal/thermal_zone1"), map[string]string{"type": "pch"})
+	return root
+}
+
+func TestRead(t *testing.T) {
+	readings, err := Read(testRoot(t))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	want := []Reading{
+		{Sensor: "coretemp/Package id 0", Celsius: 62, Critical: 100},
+		{Sensor: "coretemp/Core 8", Celsius: 58, Critical: 100},
+		{Sensor: "nvme/Composite", Celsius: 45.85, Critical: 84.85},
+		{Sensor: "nvme/Composite #2", Celsius: 86.85, Critical: 84.85},
+		{Sensor: "zone/x86_pkg_temp", Celsius: 63, Critical: 105},
+	}
+	if !reflect.DeepEqual(readings, want) {
+		t.Errorf("Read() = %+v\nwant %+v", readings, want)
+	}
+}
+
+func TestChecker(t *testing.T) {
+	c := &Checker{Root: testRoot(t)}
+
+	res := c.Evaluate(context.Background())
+	if res.Verdict != check.ForceAllow || res.Reason != "nvme/Composite #2 87°C (critical 85°C)" {
+		t.Errorf("Evaluate() = %+v", res)
+	}
+	if err := c.Health(context.Background()); err == nil {
+		t.Error("Health() = nil while a drive overheats")
+	}
+
+	// Ignoring the drives, the CPU is well below its limit
+	c.Ignore = []string{"nvme/*"}
+	if res := c.Evaluate(context.Background()); res.Verdict != check.Neutral {
+		t.Errorf("Evaluate() ignoring nvme = %+v", res)
+	}
+
+	// A configured limit applies to every sensor; the margin keeps one
+	// that has only just cooled reported
+	c.Sensors, c.Critical = []string{"coretemp/*"}, 60
+	hot, err := c.Hot(0)
+	if err != nil || len(hot) != 1 {
+		t.Errorf("Hot(0) = %v, %v", hot, err)
+	}
+	if hot, _ := c.Hot(5); len(hot) != 2 {
+		t.Errorf("Hot(5) = %v, want both cores", hot)
+	}
+
+	c.Sensors = []string{"amdgpu/*"}
+	if err := c.Health(context.Background()); err == nil {
+		t.Error("Health() with no matching sensors succeeded")
+	}
+}
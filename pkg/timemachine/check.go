@@ -0,0 +1,52 @@
+package timemachine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Checker implements check.Checker for Time Machine backups.
+// Returns an error while a Mac is writing a backup, since a backup cut off
+// by a reboot can leave the sparse bundle needing verification, or
+// corrupt enough that Time Machine starts over.
+type Checker struct {
+	Detector *Detector
+}
+
+// NewChecker creates a Time Machine checker.
+func NewChecker(d *Detector) *Checker {
+	return &Checker{Detector: d}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "timemachine"
+}
+
+// Check returns nil if no backup is running, error otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	backups, err := c.Detector.Active(ctx)
+	if err != nil {
+		return fmt.Errorf("timemachine check failed: %w", err)
+	}
+	if len(backups) > 0 {
+		return fmt.Errorf("time machine backup in progress: %s", Describe(backups))
+	}
+	return nil
+}
+
+// Describe summarizes backups for an inhibitor reason.
+func Describe(backups []Backup) string {
+	parts := make([]string, len(backups))
+	for i, b := range backups {
+		parts[i] = b.Describe()
+	}
+	return strings.Join(parts, "; ")
+}
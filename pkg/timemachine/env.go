@@ -0,0 +1,47 @@
+package timemachine
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/privexec"
+)
+
+// DetectorFromEnv returns a Detector configured from the environment
+// variables timemachine-sidecar reads, so time-to-safe looks for the same
+// backups:
+//
+//	TIMEMACHINE_DIRS             the Time Machine shares, e.g. /srv/timemachine
+//	TIMEMACHINE_PROCESS_PATTERN  file server command lines (default DefaultProcessPattern)
+//	TIMEMACHINE_SMBSTATUS        "true" also reads Samba's lock list
+//	TIMEMACHINE_EXEC_WRAPPER     e.g. "sudo -n", if smbstatus needs privileges
+//	SMBSTATUS_COMMAND            command to run smbstatus with
+//	PROC_ROOT                    procfs to scan, when /proc isn't the host's
+func DetectorFromEnv() (*Detector, error) {
+	d := &Detector{ProcRoot: os.Getenv("PROC_ROOT")}
+	for _, dir := range strings.Split(os.Getenv("TIMEMACHINE_DIRS"), ",") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			d.Dirs = append(d.Dirs, dir)
+		}
+	}
+
+	pattern := os.Getenv("TIMEMACHINE_PROCESS_PATTERN")
+	if pattern == "" {
+		pattern = DefaultProcessPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("TIMEMACHINE_PROCESS_PATTERN: %w", err)
+	}
+	d.ProcessPattern = re
+
+	if os.Getenv("TIMEMACHINE_SMBSTATUS") == "true" {
+		d.Locks = &Locks{
+			Runner:  privexec.FromEnv("timemachine"),
+			Command: strings.Fields(os.Getenv("SMBSTATUS_COMMAND")),
+		}
+	}
+	return d, nil
+}
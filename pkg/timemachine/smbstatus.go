@@ -0,0 +1,59 @@
+package timemachine
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/privexec"
+)
+
+// Locks lists the files Samba has open with "smbstatus -L"
+type Locks struct {
+	Runner privexec.Runner
+	// Command defaults to "smbstatus"
+	Command []string
+}
+
+// lockLine splits a row of smbstatus's lock list into the pid, R/W and
+// what follows the oplock: "sharepath   name   time".
+var lockLine = regexp.MustCompile(`^(\S+)\s+\S+\s+DENY_\S+\s+0x[0-9a-fA-F]+\s+(RDWR|WRONLY|RDONLY)\s+\S+\s+(.*)$`)
+
+// Bundles returns the sparse bundles with files open for writing.
+func (l *Locks) Bundles(ctx context.Context) ([]Backup, error) {
+	command := l.Command
+	if len(command) == 0 {
+		command = []string{"smbstatus"}
+	}
+	args := append(append([]string{}, command[1:]...), "-L")
+	out, err := l.Runner.Output(ctx, command[0], args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseLocks(out)
+}
+
+// parseLocks parses smbstatus's lock list, which prints the share path,
+// file name and open time separated by three spaces since either may
+// contain single spaces.
+func parseLocks(out []byte) ([]Backup, error) {
+	var backups []Backup
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		m := lockLine.FindStringSubmatch(scanner.Text())
+		if m == nil || m[2] == "RDONLY" {
+			continue
+		}
+		parts := strings.Split(strings.TrimSpace(m[3]), "   ")
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("unexpected smbstatus line: %q", scanner.Text())
+		}
+		if bundle, ok := bundleOf(parts[1]); ok {
+			backups = append(backups, Backup{Bundle: bundle, Source: "smbstatus: pid " + m[1]})
+		}
+	}
+	return backups, scanner.Err()
+}
@@ -0,0 +1,104 @@
+// Package timemachine detects Time Machine backups in progress to a Samba
+// or netatalk share.
+//
+// A Mac backs up over the network into a sparse bundle disk image on the
+// share, which it attaches for the backup and detaches afterwards. While
+// attached, the file server holds the image's band files open for writing,
+// so open bands show a backup is running. They are found through the open
+// file descriptors of the smbd and afpd session processes, which needs
+// root or CAP_SYS_PTRACE, and optionally through smbstatus's lock list.
+package timemachine
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/transfer"
+)
+
+// DefaultProcessPattern matches Samba and netatalk session processes. smbd
+// retitles each client's process "smbd: client [address]".
+const DefaultProcessPattern = `(^|/)(smbd|afpd)\b`
+
+// Backup is a sparse bundle being written to
+type Backup struct {
+	Bundle string // e.g. "Jane's MacBook Pro.sparsebundle"
+	Source string // the process or lock that shows it
+}
+
+// Describe returns a human-readable description of the backup
+func (b Backup) Describe() string {
+	return fmt.Sprintf("%s (%s)", b.Bundle, b.Source)
+}
+
+// Detector looks for Time Machine backups in progress
+type Detector struct {
+	// Dirs limits which files count, e.g. the Time Machine share; empty
+	// counts any sparse bundle
+	Dirs []string
+	// ProcessPattern matches file server session command lines
+	ProcessPattern *regexp.Regexp
+	// ProcRoot defaults to /proc
+	ProcRoot string
+	// Locks, if set, also reads Samba's lock list, for when the sidecar
+	// can't read smbd's descriptors
+	Locks *Locks
+}
+
+// Active returns the backups in progress, one per sparse bundle, sorted by
+// bundle name.
+func (d *Detector) Active(ctx context.Context) ([]Backup, error) {
+	pattern := d.ProcessPattern
+	if pattern == nil {
+		pattern = regexp.MustCompile(DefaultProcessPattern)
+	}
+	files := &transfer.Detector{Dirs: d.Dirs, ProcessPattern: pattern, ProcRoot: d.ProcRoot}
+	open, err := files.Active()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var backups []Backup
+	add := func(b Backup) {
+		if !seen[b.Bundle] {
+			seen[b.Bundle] = true
+			backups = append(backups, b)
+		}
+	}
+
+	for _, t := range open {
+		if bundle, ok := bundleOf(t.Path); ok {
+			add(Backup{Bundle: bundle, Source: fmt.Sprintf("pid %d: %s", t.PID, t.Process)})
+		}
+	}
+
+	if d.Locks != nil {
+		locked, err := d.Locks.Bundles(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range locked {
+			add(b)
+		}
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Bundle < backups[j].Bundle
+	})
+	return backups, nil
+}
+
+// bundleOf returns the name of the sparse bundle a file belongs to.
+func bundleOf(path string) (string, bool) {
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		if strings.HasSuffix(part, ".sparsebundle") {
+			return part, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,111 @@
+package timemachine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const smbstatusLocks = `
+Locked files:
+Pid          User(ID)   DenyMode   Access      R/W        Oplock           SharePath   Name   Time
+--------------------------------------------------------------------------------------------------
+2041         1000       DENY_NONE  0x12019f    RDWR       LEASE(RWH)       /srv/timemachine   Jane's MacBook Pro.sparsebundle/bands/1f   Mon Mar  4 10:00:01 2024
+2041         1000       DENY_NONE  0x120089    RDONLY     NONE             /srv/timemachine   Jane's MacBook Pro.sparsebundle/Info.plist   Mon Mar  4 10:00:00 2024
+2077         1001       DENY_WRITE 0x120089    RDONLY     LEASE(R)         /srv/timemachine   Office iMac.sparsebundle/bands/3   Mon Mar  4 10:02:00 2024
+2100         1002       DENY_NONE  0x12019f    RDWR       NONE             /srv/share   report.odt   Mon Mar  4 10:03:00 2024
+
+`
+
+func TestParseLocks(t *testing.T) {
+	backups, err := parseLocks([]byte(smbstatusLocks))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(backups) != 1 || backups[0].Bundle != "Jane's MacBook Pro.sparsebundle" || backups[0].Source != "smbstatus: pid 2041" {
+		t.Errorf("parseLocks = %+v", backups)
+	}
+
+	if backups, err := parseLocks([]byte("\nNo locked files\n\n")); err != nil || len(backups) != 0 {
+		t.Errorf("no locks: %+v, %v", backups, err)
+	}
+}
+
+// fakeSession adds a session process to a procfs tree with one open file,
+// flags in octal as in fdinfo.
+func fakeSession(t *testing.T, root, pid, cmdline, path, flags string) {
+	t.Helper()
+	for _, dir := range []string{"fd", "fdinfo"} {
+		if err := os.MkdirAll(filepath.Join(root, pid, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(root, pid, "cmdline"), []byte(cmdline), 0644)
+	if err := os.Symlink(path, filepath.Join(root, pid, "fd", "12")); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(root, pid, "fdinfo", "12"), []byte("pos:\t0\nflags:\t"+flags+"\n"), 0644)
+}
+
+func TestDetector_Active(t *testing.T) {
+	share := t.TempDir()
+	band := func(bundle, name string) string {
+		path := filepath.Join(share, bundle, "bands", name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, make([]byte, 1024), 0644)
+		return path
+	}
+
+	root := t.TempDir()
+	fakeSession(t, root, "100", "smbd: client [192.168.1.20]", band("Studio.sparsebundle", "a0"), "0100002")
+	// Attached but only being read, e.g. browsing old backups
+	fakeSession(t, root, "200", "smbd: client [192.168.1.21]", band("Old Mac.sparsebundle", "4"), "0100000")
+	fakeSession(t, root, "300", "/usr/sbin/afpd\x00-d\x00", band("Laptop.sparsebundle", "7"), "0100001")
+
+	// "sh -c SCRIPT" stands in for smbstatus, repeating a bundle seen above
+	script := `printf '%s\n' "2041         1000       DENY_NONE  0x12019f    RDWR       NONE             /srv/timemachine   Studio.sparsebundle/bands/a1   Mon Mar  4 10:00:01 2024"`
+	d := &Detector{
+		Dirs:     []string{share},
+		ProcRoot: root,
+		Locks:    &Locks{Command: []string{"sh", "-c", script}},
+	}
+
+	backups, err := d.Active(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := Describe(backups)
+	want := "Laptop.sparsebundle (pid 300: /usr/sbin/afpd -d); Studio.sparsebundle (pid 100: smbd: client [192.168.1.20])"
+	if got != want {
+		t.Errorf("Active = %q\nwant %q", got, want)
+	}
+
+	if err := NewChecker(d).Check(context.Background()); err == nil || !strings.Contains(err.Error(), "Laptop.sparsebundle") {
+		t.Errorf("Check = %v, want the backups reported", err)
+	}
+}
+
+func TestDetectorFromEnv(t *testing.T) {
+	t.Setenv("TIMEMACHINE_DIRS", "/srv/timemachine")
+	t.Setenv("TIMEMACHINE_PROCESS_PATTERN", "")
+	t.Setenv("TIMEMACHINE_SMBSTATUS", "true")
+	t.Setenv("SMBSTATUS_COMMAND", "podman exec samba smbstatus")
+
+	d, err := DetectorFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Dirs) != 1 || d.Dirs[0] != "/srv/timemachine" || d.ProcessPattern.String() != DefaultProcessPattern {
+		t.Errorf("Dirs = %q, ProcessPattern = %v", d.Dirs, d.ProcessPattern)
+	}
+	if d.Locks == nil || strings.Join(d.Locks.Command, " ") != "podman exec samba smbstatus" {
+		t.Errorf("Locks = %+v, want the smbstatus command", d.Locks)
+	}
+
+	t.Setenv("TIMEMACHINE_SMBSTATUS", "false")
+	if d, err := DetectorFromEnv(); err != nil || d.Locks != nil {
+		t.Errorf("Locks = %+v, %v; want none without TIMEMACHINE_SMBSTATUS", d.Locks, err)
+	}
+}
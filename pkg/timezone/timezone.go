@@ -0,0 +1,67 @@
+// Package timezone picks the zone that schedules and reported clock times
+// use. A server often runs in UTC while the household it serves doesn't,
+// so SCHEDULE_TZ can name an IANA zone, e.g. "Europe/London", for
+// maintenance windows and "until 21:30" reasons, independent of the host's
+// local time.
+package timezone
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+	// The images are built FROM scratch, without /usr/share/zoneinfo
+	_ "time/tzdata"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+)
+
+// Env is the variable naming the zone
+const Env = "SCHEDULE_TZ"
+
+// Parse loads an IANA zone name. Empty or "Local" is the host's local
+// time.
+func Parse(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// FromEnv loads the zone named by SCHEDULE_TZ.
+func FromEnv() (*time.Location, error) {
+	loc, err := Parse(os.Getenv(Env))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", Env, err)
+	}
+	return loc, nil
+}
+
+var location = sync.OnceValue(func() *time.Location {
+	loc, err := FromEnv()
+	if err != nil {
+		logging.Warnf("%v, using local time", err)
+		return time.Local
+	}
+	return loc
+})
+
+// Location returns the zone from SCHEDULE_TZ, read on first use. An unset
+// or invalid value is the host's local time; the latter is logged.
+func Location() *time.Location {
+	return location()
+}
+
+// In returns t in Location.
+func In(t time.Time) time.Time {
+	return t.In(Location())
+}
+
+// Now returns the current time in Location.
+func Now() time.Time {
+	return In(time.Now())
+}
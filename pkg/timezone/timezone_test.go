@@ -0,0 +1,41 @@
+package timezone
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	for _, name := range []string{"", "Local"} {
+		if loc, err := Parse(name); err != nil || loc != time.Local {
+			t.Errorf("Parse(%q) = %v, %v, want local time", name, loc, err)
+		}
+	}
+
+	loc, err := Parse("America/New_York")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	// 02:30 UTC is still the previous evening in New York
+	got := time.Date(2024, 7, 1, 2, 30, 0, 0, time.UTC).In(loc).Format("Jan 2 15:04 MST")
+	if want := "Jun 30 22:30 EDT"; got != want {
+		t.Errorf("in America/New_York = %q, want %q", got, want)
+	}
+
+	if _, err := Parse("Mars/Olympus_Mons"); err == nil {
+		t.Error("Parse() of an unknown zone succeeded")
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv(Env, "Europe/London")
+	loc, err := FromEnv()
+	if err != nil || loc.String() != "Europe/London" {
+		t.Errorf("FromEnv() = %v, %v", loc, err)
+	}
+
+	t.Setenv(Env, "bogus")
+	if _, err := FromEnv(); err == nil {
+		t.Error("FromEnv() with an invalid zone succeeded")
+	}
+}
@@ -0,0 +1,70 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Checker implements check.Checker for SFTP/FTP uploads.
+// Returns an error while an upload is in progress, so reboots wait for it.
+//
+// GracePeriod keeps blocking for a while after the last upload was seen,
+// since clients upload a batch of files one after another and the gap
+// between two files would otherwise let a reboot through.
+type Checker struct {
+	Detector    *Detector
+	GracePeriod time.Duration
+
+	mu         sync.Mutex
+	lastActive time.Time
+}
+
+// NewChecker creates an upload checker.
+func NewChecker(d *Detector, gracePeriod time.Duration) *Checker {
+	return &Checker{Detector: d, GracePeriod: gracePeriod}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "transfer"
+}
+
+// Check returns nil if no upload is in progress, error otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	active, err := c.Detector.Active()
+	if err != nil {
+		return fmt.Errorf("transfer check failed: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(active) > 0 {
+		c.lastActive = time.Now()
+		return fmt.Errorf("upload in progress: %s", Describe(active))
+	}
+	if c.GracePeriod > 0 && !c.lastActive.IsZero() {
+		if elapsed := time.Since(c.lastActive); elapsed < c.GracePeriod {
+			return fmt.Errorf("grace period: upload ended %s ago, waiting %s", elapsed.Round(time.Second), (c.GracePeriod - elapsed).Round(time.Second))
+		}
+	}
+	return nil
+}
+
+// Describe summarizes transfers for an inhibitor reason.
+func Describe(transfers []Transfer) string {
+	parts := make([]string, len(transfers))
+	for i, t := range transfers {
+		parts[i] = t.Describe()
+	}
+	return strings.Join(parts, "; ")
+}
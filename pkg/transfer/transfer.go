@@ -0,0 +1,128 @@
+// Package transfer detects in-flight SFTP and FTP uploads by looking for
+// files that a transfer daemon holds open for writing.
+//
+// Every common server (OpenSSH sftp-server and internal-sftp, vsftpd,
+// proftpd, pure-ftpd) handles each session in its own process and writes
+// uploads straight to their destination, so the open file descriptors of
+// those processes show exactly what is being uploaded. Reading another
+// process's descriptors needs root or CAP_SYS_PTRACE.
+package transfer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/procscan"
+)
+
+// DefaultProcessPattern matches SFTP and FTP session processes. sshd runs
+// internal-sftp sessions in a process titled "sshd: user@internal-sftp".
+const DefaultProcessPattern = `(^|/)(sftp-server|vsftpd|proftpd|pure-ftpd)\b|@internal-sftp\b`
+
+// Transfer is a file being written by a transfer daemon
+type Transfer struct {
+	PID     int
+	Process string // command line of the session process
+	Path    string
+	Size    int64 // bytes written so far
+}
+
+// Describe returns a human-readable description of the transfer
+func (t Transfer) Describe() string {
+	return fmt.Sprintf("%s (%.1f MB) by pid %d: %s", t.Path, float64(t.Size)/1e6, t.PID, t.Process)
+}
+
+// Detector looks for uploads in progress
+type Detector struct {
+	// Dirs limits which files count, e.g. the drop folder; empty counts
+	// any file (including daemon log files, so set it)
+	Dirs []string
+	// MinSize ignores files smaller than this many bytes, so small
+	// uploads don't block; a large upload blocks once it passes MinSize
+	MinSize int64
+	// ProcessPattern matches transfer session command lines
+	ProcessPattern *regexp.Regexp
+	// ProcRoot defaults to /proc
+	ProcRoot string
+}
+
+// Active returns the uploads currently in progress.
+func (d *Detector) Active() ([]Transfer, error) {
+	procRoot := d.ProcRoot
+	if procRoot == "" {
+		procRoot = procscan.DefaultProcRoot
+	}
+	pattern := d.ProcessPattern
+	if pattern == nil {
+		pattern = regexp.MustCompile(DefaultProcessPattern)
+	}
+
+	procs, err := procscan.Find(procRoot, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("scan processes: %w", err)
+	}
+
+	var active []Transfer
+	for _, p := range procs {
+		fdDir := filepath.Join(procRoot, strconv.Itoa(p.PID), "fd")
+		entries, err := os.ReadDir(fdDir)
+		if err != nil {
+			// Exited, or not ours to read
+			continue
+		}
+		for _, e := range entries {
+			target, err := os.Readlink(filepath.Join(fdDir, e.Name()))
+			if err != nil || !filepath.IsAbs(target) || !d.inDirs(target) {
+				continue
+			}
+			if !writable(filepath.Join(procRoot, strconv.Itoa(p.PID), "fdinfo", e.Name())) {
+				continue
+			}
+			// Stat through the fd so deleted or renamed files still resolve
+			info, err := os.Stat(filepath.Join(fdDir, e.Name()))
+			if err != nil || !info.Mode().IsRegular() || info.Size() < d.MinSize {
+				continue
+			}
+			active = append(active, Transfer{PID: p.PID, Process: p.Cmdline, Path: target, Size: info.Size()})
+		}
+	}
+	return active, nil
+}
+
+func (d *Detector) inDirs(path string) bool {
+	if len(d.Dirs) == 0 {
+		return true
+	}
+	for _, dir := range d.Dirs {
+		dir = filepath.Clean(dir)
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// writable reports whether the fdinfo file shows the descriptor was opened
+// for writing (O_WRONLY or O_RDWR).
+func writable(fdinfo string) bool {
+	data, err := os.ReadFile(fdinfo)
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		v, ok := strings.CutPrefix(line, "flags:")
+		if !ok {
+			continue
+		}
+		flags, err := strconv.ParseUint(strings.TrimSpace(v), 8, 64)
+		if err != nil {
+			return false
+		}
+		return flags&uint64(os.O_WRONLY|os.O_RDWR) != 0
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package transfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeProc builds a procfs tree with one process per pid. Each open file is
+// "fd:path:flags" with flags in octal as in fdinfo.
+func fakeProc(t *testing.T, root string, procs map[string][]string, cmdlines map[string]string) {
+	t.Helper()
+	for pid, files := range procs {
+		for _, dir := range []string{"fd", "fdinfo"} {
+			if err := os.MkdirAll(filepath.Join(root, pid, dir), 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.WriteFile(filepath.Join(root, pid, "cmdline"), []byte(cmdlines[pid]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range files {
+			parts := strings.SplitN(f, ":", 3)
+			if err := os.Symlink(parts[1], filepath.Join(root, pid, "fd", parts[0])); err != nil {
+				t.Fatal(err)
+			}
+			info := "pos:\t0\nflags:\t" + parts[2] + "\nmnt_id:\t25\n"
+			if err := os.WriteFile(filepath.Join(root, pid, "fdinfo", parts[0]), []byte(info), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestDetector_Active(t *testing.T) {
+	files := t.TempDir()
+	drop := filepath.Join(files, "drop")
+	os.MkdirAll(drop, 0755)
+	write := func(name string, size int) string {
+		path := filepath.Join(files, name)
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	bigUpload := write("drop/movie.mkv", 4096)
+	smallUpload := write("drop/notes.txt", 10)
+	download := write("drop/photo.jpg", 4096)
+	logFile := write("vsftpd.log", 4096)
+	elsewhere := write("home.tar", 4096)
+
+	root := t.TempDir()
+	fakeProc(t, root, map[string][]string{
+		"100": {"0:/dev/null:0100002", "3:" + bigUpload + ":0100101"},
+		"200": {"4:" + download + ":0100000"},
+		"300": {"3:" + logFile + ":02102001", "5:" + smallUpload + ":0100001"},
+		"400": {"3:" + elsewhere + ":0100001"},
+		"500": {"3:" + bigUpload + ":0100001"},
+	}, map[string]string{
+		"100": "sshd: alice@internal-sftp",
+		"200": "/usr/libexec/openssh/sftp-server\x00",
+		"300": "vsftpd\x00",
+		"400": "/usr/lib/sftp-server\x00",
+		"500": "rsync\x00--server\x00", // not a transfer daemon
+	})
+
+	tests := []struct {
+		name    string
+		dirs    []string
+		minSize int64
+		want    []string
+	}{
+		{name: "uploads to the drop folder", dirs: []string{drop}, want: []string{bigUpload, smallUpload}},
+		{name: "min size", dirs: []string{drop}, minSize: 1024, want: []string{bigUpload}},
+		{name: "no dirs counts any writer", want: []string{bigUpload, logFile, smallUpload, elsewhere}},
+		{name: "other folder", dirs: []string{filepath.Join(files, "incoming")}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Detector{Dirs: tt.dirs, MinSize: tt.minSize, ProcRoot: root}
+			active, err := d.Active()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, tr := range active {
+				got = append(got, tr.Path)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("active = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChecker_GracePeriod(t *testing.T) {
+	drop := t.TempDir()
+	upload := filepath.Join(drop, "movie.mkv")
+	os.WriteFile(upload, []byte("data"), 0644)
+
+	root := t.TempDir()
+	fakeProc(t, root, map[string][]string{"100": {"3:" + upload + ":0100001"}},
+		map[string]string{"100": "/usr/libexec/sftp-server\x00"})
+
+	checker := NewChecker(&Detector{Dirs: []string{drop}, ProcRoot: root}, time.Minute)
+	if err := checker.Check(context.Background()); err == nil || !strings.Contains(err.Error(), "movie.mkv") {
+		t.Fatalf("Check = %v, want upload in progress", err)
+	}
+
+	// Upload finished: still blocked for the grace period
+	os.RemoveAll(filepath.Join(root, "100"))
+	if err := checker.Check(context.Background()); err == nil || !strings.Contains(err.Error(), "grace period") {
+		t.Errorf("Check = %v, want grace period", err)
+	}
+
+	checker.GracePeriod = 0
+	if err := checker.Check(context.Background()); err != nil {
+		t.Errorf("Check = %v, want nil", err)
+	}
+}
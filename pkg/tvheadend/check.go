@@ -0,0 +1,73 @@
+package tvheadend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Checker implements check.Checker for Tvheadend tuner use. Returns an
+// error while anyone is streaming from it or a recording is in progress,
+// so the TV backend isn't rebooted out from under them.
+type Checker struct {
+	Client *Client
+}
+
+// NewChecker creates a Tvheadend activity checker.
+func NewChecker(client *Client) *Checker {
+	return &Checker{Client: client}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "tvheadend"
+}
+
+// Check returns nil if Tvheadend is idle, error describing the activity
+// otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	reasons, err := c.Activity(ctx)
+	if errors.Is(err, ErrUnauthorized) {
+		// Tvheadend is up but we can't see what it's doing; don't assume idle
+		return fmt.Errorf("cannot query tvheadend: %w", err)
+	}
+	if err != nil {
+		// Tvheadend being down shouldn't hold up reboots
+		return nil
+	}
+	if len(reasons) > 0 {
+		return errors.New(strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// Activity returns a description of each recording in progress and each
+// other subscription. A recording's own subscription isn't listed twice.
+func (c *Checker) Activity(ctx context.Context) ([]string, error) {
+	entries, err := c.Client.Upcoming(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("dvr entries: %w", err)
+	}
+	subs, err := c.Client.Subscriptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("subscriptions: %w", err)
+	}
+
+	var reasons []string
+	for _, e := range entries {
+		if e.Recording() {
+			reasons = append(reasons, fmt.Sprintf("recording %s on %s", e.Title, e.Channel))
+		}
+	}
+	var streams []string
+	for _, s := range subs {
+		if !s.Recording() {
+			streams = append(streams, s.Describe())
+		}
+	}
+	if len(streams) > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d subscription(s): %s", len(streams), strings.Join(streams, ", ")))
+	}
+	return reasons, nil
+}
@@ -0,0 +1,157 @@
+// Package tvheadend provides a client for the Tvheadend HTTP API.
+package tvheadend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrUnauthorized is returned when Tvheadend rejects the credentials
+var ErrUnauthorized = errors.New("tvheadend rejected credentials")
+
+// Subscription is a client of Tvheadend's streaming engine: a viewer, a
+// running recording, or an EPG grab, each holding a tuner or IPTV input
+type Subscription struct {
+	ID       int    `json:"id"`
+	State    string `json:"state"` // e.g. "Running", "Testing", "Bad"
+	Title    string `json:"title"` // client name, or "DVR: <title>" for recordings
+	Channel  string `json:"channel"`
+	Service  string `json:"service"`
+	Client   string `json:"client"`
+	Username string `json:"username"`
+	Hostname string `json:"hostname"`
+	Start    int64  `json:"start"` // unix seconds
+}
+
+// Recording reports whether the subscription is the DVR recording, rather
+// than someone watching.
+func (s *Subscription) Recording() bool {
+	return strings.HasPrefix(s.Title, "DVR:")
+}
+
+// Describe summarizes the subscription, e.g. "BBC One HD to
+// kodi@192.168.1.5 (Kodi)".
+func (s *Subscription) Describe() string {
+	who := s.Hostname
+	if s.Username != "" {
+		who = s.Username + "@" + s.Hostname
+	}
+	desc := s.Channel
+	if desc == "" {
+		desc = s.Service
+	}
+	if who != "" {
+		desc += " to " + who
+	}
+	if s.Client != "" {
+		desc += fmt.Sprintf(" (%s)", s.Client)
+	}
+	return desc
+}
+
+// Entry is a DVR entry from the upcoming grid: a scheduled recording or
+// one in progress
+type Entry struct {
+	UUID    string `json:"uuid"`
+	Title   string `json:"disp_title"`
+	Channel string `json:"channelname"`
+	// StartReal and StopReal include the pre and post padding, in unix
+	// seconds
+	StartReal int64 `json:"start_real"`
+	StopReal  int64 `json:"stop_real"`
+	// SchedStatus is "scheduled" or "recording"
+	SchedStatus string `json:"sched_status"`
+}
+
+// Recording reports whether the entry is being recorded now.
+func (e *Entry) Recording() bool {
+	return e.SchedStatus == "recording"
+}
+
+// Start returns the padded start time.
+func (e *Entry) Start() time.Time {
+	return time.Unix(e.StartReal, 0)
+}
+
+// Stop returns the padded stop time.
+func (e *Entry) Stop() time.Time {
+	return time.Unix(e.StopReal, 0)
+}
+
+// Client handles communication with the Tvheadend API
+type Client struct {
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+// NewClient creates a Tvheadend API client. An empty username skips
+// authentication; otherwise Tvheadend's HTTP authentication type must
+// allow plain (basic) authentication. Subscriptions needs the "Admin"
+// permission, Upcoming the "Video recorder" one.
+func NewClient(baseURL, username, password string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		username: username,
+		password: password,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+type gridResponse[T any] struct {
+	Entries []T `json:"entries"`
+}
+
+// Subscriptions returns the active streaming subscriptions.
+func (c *Client) Subscriptions(ctx context.Context) ([]Subscription, error) {
+	var resp gridResponse[Subscription]
+	if err := c.get(ctx, "/api/status/subscriptions", &resp); err != nil {
+		return nil, err
+	}
+	return resp.Entries, nil
+}
+
+// Upcoming returns the scheduled DVR entries and those being recorded.
+func (c *Client) Upcoming(ctx context.Context) ([]Entry, error) {
+	var resp gridResponse[Entry]
+	if err := c.get(ctx, "/api/dvr/entry/grid_upcoming?limit=1000", &resp); err != nil {
+		return nil, err
+	}
+	return resp.Entries, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("unexpected status: %d: %w", resp.StatusCode, ErrUnauthorized)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package tvheadend
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChecker(t *testing.T) {
+	tests := []struct {
+		name          string
+		subscriptions string
+		upcoming      string
+		status        int
+		wantErr       string // "" = idle
+	}{
+		{
+			name:          "idle",
+			subscriptions: `{"entries": [], "totalCount": 0}`,
+			upcoming:      `{"entries": [{"disp_title": "News", "channelname": "BBC One", "sched_status": "scheduled"}]}`,
+		},
+		{
+			name: "watching",
+			subscriptions: `{"entries": [
+				{"id": 3, "state": "Running", "title": "Kodi Media Center", "channel": "BBC One HD",
+				 "client": "Kodi", "username": "kodi", "hostname": "192.168.1.5"}
+			]}`,
+			upcoming: `{"entries": []}`,
+			wantErr:  "1 subscription(s): BBC One HD to kodi@192.168.1.5 (Kodi)",
+		},
+		{
+			name: "recording",
+			subscriptions: `{"entries": [
+				{"id": 4, "state": "Running", "title": "DVR: Match of the Day", "channel": "BBC One HD", "hostname": "127.0.0.1"}
+			]}`,
+			upcoming: `{"entries": [
+				{"disp_title": "Match of the Day", "channelname": "BBC One HD", "sched_status": "recording"},
+				{"disp_title": "News", "channelname": "BBC One HD", "sched_status": "scheduled"}
+			]}`,
+			wantErr: "recording Match of the Day on BBC One HD",
+		},
+		{
+			name:          "wrong password",
+			status:        http.StatusUnauthorized,
+			subscriptions: `{}`,
+			upcoming:      `{}`,
+			wantErr:       "cannot query tvheadend",
+		},
+		{
+			name:          "server error is idle",
+			status:        http.StatusInternalServerError,
+			subscriptions: `{}`,
+			upcoming:      `{}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+					t.Errorf("basic auth = %q, %q, %v", user, pass, ok)
+				}
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+					return
+				}
+				switch r.URL.Path {
+				case "/tvh/api/status/subscriptions":
+					w.Write([]byte(tt.subscriptions))
+				case "/tvh/api/dvr/entry/grid_upcoming":
+					w.Write([]byte(tt.upcoming))
+				default:
+					t.Errorf("unexpected path: %s", r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			checker := NewChecker(NewClient(server.URL+"/tvh/", "admin", "secret", 5*time.Second))
+			err := checker.Check(context.Background())
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Check() = %v, want idle", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Check() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestChecker_Unreachable(t *testing.T) {
+	checker := NewChecker(NewClient("http://127.0.0.1:1", "", "", time.Second))
+	if err := checker.Check(context.Background()); err != nil {
+		t.Errorf("Check() = %v, want nil when Tvheadend is down", err)
+	}
+	if _, err := checker.Activity(context.Background()); err == nil || errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Activity() error = %v, want a connection error", err)
+	}
+}
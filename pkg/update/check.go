@@ -0,0 +1,111 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/metrics"
+)
+
+// Staged files mark updates that the next boot applies: an rpm-ostree or
+// bootc deployment staged by ostree, or an offline update prepared by
+// PackageKit. Rebooting is what they are waiting for.
+var Staged = map[string]string{
+	"/run/ostree/staged-deployment": "ostree deployment staged",
+	"/system-update":                "offline update prepared",
+}
+
+// Transactor reports the transactions of package manager daemons
+type Transactor interface {
+	Transactions(ctx context.Context) ([]string, error)
+}
+
+// Checker looks for package manager transactions in progress
+type Checker struct {
+	// Root is where the host's filesystem is mounted ("" = "/"), for a
+	// sidecar in a container
+	Root string
+	// Locks are the lock files to check
+	Locks []Lock
+	// Daemons, if set, is asked about daemon transactions
+	Daemons Transactor
+
+	mu      sync.Mutex
+	pending []string
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "update"
+}
+
+// Active describes each transaction in progress, e.g. "dpkg
+// (/var/lib/dpkg/lock-frontend locked)". If the daemons can't be asked,
+// the locks held are returned with the error.
+func (c *Checker) Active(ctx context.Context) ([]string, error) {
+	var active []string
+	for _, lock := range c.Locks {
+		held, err := Held(c.path(lock.Path))
+		if err != nil {
+			return nil, err
+		}
+		desc := fmt.Sprintf("%s (%s locked)", lock.Name, lock.Path)
+		if held && !slices.Contains(active, desc) {
+			active = append(active, desc)
+		}
+	}
+
+	if c.Daemons != nil {
+		transactions, err := c.Daemons.Transactions(ctx)
+		if err != nil {
+			return active, err
+		}
+		active = append(active, transactions...)
+	}
+	return active, nil
+}
+
+// Pending describes the updates staged for the next boot.
+func (c *Checker) Pending() ([]string, error) {
+	var pending []string
+	for _, path := range slices.Sorted(maps.Keys(Staged)) {
+		if _, err := os.Lstat(c.path(path)); err == nil {
+			pending = append(pending, Staged[path])
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	c.pending = pending
+	c.mu.Unlock()
+	return pending, nil
+}
+
+// Gauges returns whether Pending last found an update staged.
+func (c *Checker) Gauges() []metrics.Gauge {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var v float64
+	if len(c.pending) > 0 {
+		v = 1
+	}
+	return []metrics.Gauge{{
+		Name:  "homelab_update_pending",
+		Help:  "Whether an update is staged for the next boot.",
+		Value: v,
+	}}
+}
+
+func (c *Checker) path(p string) string {
+	if c.Root == "" {
+		return p
+	}
+	return filepath.Join(c.Root, p)
+}
@@ -0,0 +1,132 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	rpmOstreeDest  = "org.projectatomic.rpmostree1"
+	rpmOstreePath  = "/org/projectatomic/rpmostree1/Sysroot"
+	packageKitDest = "org.freedesktop.PackageKit"
+	packageKitPath = "/org/freedesktop/PackageKit"
+)
+
+// PackageKit roles that change packages. Queries such as refreshing the
+// cache or searching are safe to interrupt.
+var packageKitRoles = map[uint32]string{
+	10: "install-files",
+	11: "install-packages",
+	14: "remove-packages",
+	22: "update-packages",
+	29: "repair-system",
+	33: "upgrade-system",
+}
+
+// Daemons asks the rpm-ostree and PackageKit daemons about their
+// transactions over the system bus. Both are started on demand, so a
+// daemon that isn't running is skipped rather than woken up: it can't be
+// in the middle of anything. It connects on first use.
+type Daemons struct {
+	mu   sync.Mutex
+	conn *dbus.Conn
+}
+
+func (d *Daemons) connect() (*dbus.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.conn != nil && d.conn.Connected() {
+		return d.conn, nil
+	}
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, fmt.Errorf("connecting to the system bus: %w", err)
+	}
+	d.conn = conn
+	return conn, nil
+}
+
+// Transactions describes the transactions changing packages, e.g.
+// "rpm-ostree Upgrade" or "PackageKit update-packages".
+func (d *Daemons) Transactions(ctx context.Context) ([]string, error) {
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	var active []string
+	if running, err := hasOwner(ctx, conn, rpmOstreeDest); err != nil {
+		return nil, err
+	} else if running {
+		method, err := rpmOstreeTransaction(conn)
+		if err != nil {
+			return nil, err
+		}
+		if method != "" {
+			active = append(active, "rpm-ostree "+method)
+		}
+	}
+	if running, err := hasOwner(ctx, conn, packageKitDest); err != nil {
+		return nil, err
+	} else if running {
+		roles, err := packageKitTransactions(ctx, conn)
+		if err != nil {
+			return nil, err
+		}
+		for _, role := range roles {
+			active = append(active, "PackageKit "+role)
+		}
+	}
+	return active, nil
+}
+
+func hasOwner(ctx context.Context, conn *dbus.Conn, name string) (bool, error) {
+	var owned bool
+	if err := conn.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.NameHasOwner", 0, name).Store(&owned); err != nil {
+		return false, fmt.Errorf("NameHasOwner %s: %w", name, err)
+	}
+	return owned, nil
+}
+
+// rpmOstreeTransaction returns the method of the daemon's active
+// transaction, e.g. "Upgrade", or "" if it is idle.
+func rpmOstreeTransaction(conn *dbus.Conn) (string, error) {
+	v, err := conn.Object(rpmOstreeDest, rpmOstreePath).GetProperty(rpmOstreeDest + ".Sysroot.ActiveTransaction")
+	if err != nil {
+		return "", fmt.Errorf("rpm-ostree ActiveTransaction: %w", err)
+	}
+	// (method, sender, object path), all empty while idle
+	active, _ := v.Value().([]any)
+	if len(active) == 0 {
+		return "", nil
+	}
+	method, _ := active[0].(string)
+	return method, nil
+}
+
+// packageKitTransactions returns the roles of the transactions changing
+// packages.
+func packageKitTransactions(ctx context.Context, conn *dbus.Conn) ([]string, error) {
+	var paths []dbus.ObjectPath
+	obj := conn.Object(packageKitDest, packageKitPath)
+	if err := obj.CallWithContext(ctx, packageKitDest+".GetTransactionList", 0).Store(&paths); err != nil {
+		return nil, fmt.Errorf("PackageKit GetTransactionList: %w", err)
+	}
+
+	var roles []string
+	for _, path := range paths {
+		v, err := conn.Object(packageKitDest, path).GetProperty(packageKitDest + ".Transaction.Role")
+		if err != nil {
+			// Finished since it was listed
+			continue
+		}
+		role, _ := v.Value().(uint32)
+		if name, ok := packageKitRoles[role]; ok {
+			roles = append(roles, name)
+		}
+	}
+	return roles, nil
+}
@@ -0,0 +1,42 @@
+// Package update detects package manager transactions that a reboot would
+// cut short, leaving half-installed packages or an unbootable system: dpkg
+// and rpm holding their database locks, an unattended-upgrades run, and
+// transactions of the rpm-ostree and PackageKit daemons. It also notices
+// updates staged to be applied by the next boot, which need no waiting.
+package update
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Lock is a file a package manager holds an fcntl lock on while it
+// changes packages
+type Lock struct {
+	Name string // e.g. "dpkg"
+	Path string
+}
+
+// DefaultLocks are the locks of dpkg, rpm and unattended-upgrades; the
+// ones that don't exist on a system are skipped
+var DefaultLocks = []Lock{
+	{Name: "dpkg", Path: "/var/lib/dpkg/lock-frontend"},
+	{Name: "dpkg", Path: "/var/lib/dpkg/lock"},
+	{Name: "unattended-upgrades", Path: "/run/unattended-upgrades.lock"},
+	{Name: "rpm", Path: "/var/lib/rpm/.rpm.lock"},
+	{Name: "rpm", Path: "/usr/lib/sysimage/rpm/.rpm.lock"},
+}
+
+// ParseLocks parses lock files, each "<name>=<path>" or just a path named
+// after its file.
+func ParseLocks(items []string) []Lock {
+	locks := make([]Lock, 0, len(items))
+	for _, item := range items {
+		name, path, ok := strings.Cut(item, "=")
+		if !ok || name == "" || strings.Contains(name, "/") {
+			name, path = filepath.Base(item), item
+		}
+		locks = append(locks, Lock{Name: name, Path: path})
+	}
+	return locks
+}
@@ -0,0 +1,32 @@
+//go:build linux
+
+package update
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Held reports whether another process holds a write lock on path. Read
+// locks, such as rpm's for queries, don't count. A missing file isn't
+// held.
+func Held(path string) (bool, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	// F_GETLK reports a lock that would conflict with ours; only a write
+	// lock conflicts with a read lock
+	lk := syscall.Flock_t{Type: syscall.F_RDLCK}
+	if err := syscall.FcntlFlock(f.Fd(), syscall.F_GETLK, &lk); err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	return lk.Type != syscall.F_UNLCK, nil
+}
@@ -0,0 +1,57 @@
+//go:build linux
+
+package update
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+// fOFDSetLK takes an open file description lock, which unlike a classic
+// fcntl lock conflicts with locks of the same process
+const fOFDSetLK = 37
+
+func lockFile(t *testing.T, path string, typ int16) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o640)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	lk := syscall.Flock_t{Type: typ}
+	if err := syscall.FcntlFlock(f.Fd(), fOFDSetLK, &lk); err != nil {
+		t.Skipf("OFD locks unsupported: %v", err)
+	}
+}
+
+func TestChecker_Active(t *testing.T) {
+	root := t.TempDir()
+	lockFile(t, filepath.Join(root, "var/lib/dpkg/lock-frontend"), syscall.F_WRLCK)
+	lockFile(t, filepath.Join(root, "var/lib/dpkg/lock"), syscall.F_WRLCK)
+	// rpm -q takes a read lock, which doesn't block
+	lockFile(t, filepath.Join(root, "var/lib/rpm/.rpm.lock"), syscall.F_RDLCK)
+	if err := os.WriteFile(filepath.Join(root, "run-unattended-upgrades.lock"), nil, 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	checker := &Checker{
+		Root:    root,
+		Locks:   append(DefaultLocks, ParseLocks([]string{"/run-unattended-upgrades.lock"})...),
+		Daemons: fakeDaemons{"rpm-ostree Upgrade"},
+	}
+	got, err := checker.Active(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"dpkg (/var/lib/dpkg/lock-frontend locked)", "dpkg (/var/lib/dpkg/lock locked)", "rpm-ostree Upgrade"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Active() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package update
+
+import "errors"
+
+// Held reports whether another process holds a write lock on path. Only
+// Linux can tell.
+func Held(path string) (bool, error) {
+	return false, errors.New("lock files can only be checked on Linux")
+}
@@ -0,0 +1,45 @@
+package update
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type fakeDaemons []string
+
+func (f fakeDaemons) Transactions(context.Context) ([]string, error) {
+	return f, nil
+}
+
+func TestChecker_Pending(t *testing.T) {
+	root := t.TempDir()
+	checker := &Checker{Root: root}
+	if got, err := checker.Pending(); err != nil || len(got) != 0 {
+		t.Fatalf("Pending() = %q, %v, want none", got, err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, "run/ostree"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "run/ostree/staged-deployment"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := checker.Pending()
+	if err != nil || !reflect.DeepEqual(got, []string{"ostree deployment staged"}) {
+		t.Errorf("Pending() = %q, %v, want the staged deployment", got, err)
+	}
+	if g := checker.Gauges(); len(g) != 1 || g[0].Value != 1 {
+		t.Errorf("Gauges() = %+v, want pending", g)
+	}
+}
+
+func TestParseLocks(t *testing.T) {
+	got := ParseLocks([]string{"apt=/var/cache/apt/archives/lock", "/var/lib/apt/lists/lock"})
+	want := []Lock{{Name: "apt", Path: "/var/cache/apt/archives/lock"}, {Name: "lock", Path: "/var/lib/apt/lists/lock"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseLocks() = %+v, want %+v", got, want)
+	}
+}
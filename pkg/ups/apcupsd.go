@@ -0,0 +1,113 @@
+package ups
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultAPCUPSDPort is apcupsd's network information server port
+const DefaultAPCUPSDPort = "3551"
+
+// APCUPSD reads UPS status from apcupsd's network information server (NIS)
+type APCUPSD struct {
+	Addr    string // host:port
+	Timeout time.Duration
+}
+
+// NewAPCUPSD creates an apcupsd client. addr defaults to port 3551 if none
+// is given.
+func NewAPCUPSD(addr string, timeout time.Duration) *APCUPSD {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, DefaultAPCUPSDPort)
+	}
+	return &APCUPSD{Addr: addr, Timeout: timeout}
+}
+
+// Status implements Source.
+func (a *APCUPSD) Status(ctx context.Context) (*Status, error) {
+	fields, err := a.Fields(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, ok := fields["STATUS"]
+	if !ok {
+		return nil, fmt.Errorf("apcupsd reports no STATUS")
+	}
+	status := &Status{Charge: -1, Runtime: -1, Raw: raw}
+	for _, f := range strings.Fields(raw) {
+		switch f {
+		case "ONBATT":
+			status.OnBattery = true
+		case "LOWBATT":
+			status.LowBattery = true
+		case "SHUTTING":
+			// "SHUTTING DOWN"
+			status.ForcedShutdown = true
+		}
+	}
+	// Values carry units, e.g. "100.0 Percent" and "45.0 Minutes"
+	if v, err := strconv.ParseFloat(firstField(fields["BCHARGE"]), 64); err == nil {
+		status.Charge = v
+	}
+	if v, err := strconv.ParseFloat(firstField(fields["TIMELEFT"]), 64); err == nil {
+		status.Runtime = time.Duration(v * float64(time.Minute))
+	}
+	return status, nil
+}
+
+// Fields returns the NIS status report, e.g. "STATUS": "ONLINE".
+func (a *APCUPSD) Fields(ctx context.Context) (map[string]string, error) {
+	d := net.Dialer{Timeout: a.Timeout}
+	conn, err := d.DialContext(ctx, "tcp", a.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("connect to apcupsd: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else if a.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(a.Timeout))
+	}
+
+	// Messages are framed with a 2-byte big-endian length
+	cmd := []byte("status")
+	frame := binary.BigEndian.AppendUint16(nil, uint16(len(cmd)))
+	if _, err := conn.Write(append(frame, cmd...)); err != nil {
+		return nil, fmt.Errorf("send command: %w", err)
+	}
+
+	fields := make(map[string]string)
+	var size [2]byte
+	for {
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return nil, fmt.Errorf("read response: %w", err)
+		}
+		n := binary.BigEndian.Uint16(size[:])
+		if n == 0 {
+			// End of report
+			return fields, nil
+		}
+		record := make([]byte, n)
+		if _, err := io.ReadFull(conn, record); err != nil {
+			return nil, fmt.Errorf("read response: %w", err)
+		}
+		// Records look like "STATUS   : ONLINE \n"
+		if key, value, ok := strings.Cut(string(record), ":"); ok {
+			fields[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+}
+
+func firstField(s string) string {
+	if f := strings.Fields(s); len(f) > 0 {
+		return f[0]
+	}
+	return ""
+}
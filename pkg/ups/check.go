@@ -0,0 +1,48 @@
+package ups
+
+import (
+	"context"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/check"
+)
+
+// Checker implements check.Evaluator for UPS battery state.
+// It never blocks: it returns ForceAllow while the host is on a critical
+// battery, so other checks can't hold up the UPS shutdown, and Neutral
+// otherwise.
+type Checker struct {
+	Source     Source
+	MinCharge  float64       // percent, 0 = only the UPS's own low-battery flag
+	MinRuntime time.Duration // 0 = only the UPS's own low-battery flag
+}
+
+// NewChecker creates a UPS checker that force-allows below 30% charge or
+// 5 minutes of runtime.
+func NewChecker(source Source) *Checker {
+	return &Checker{
+		Source:     source,
+		MinCharge:  30,
+		MinRuntime: 5 * time.Minute,
+	}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "ups"
+}
+
+// Check always returns nil; the UPS never blocks shutdown.
+func (c *Checker) Check(ctx context.Context) error {
+	return nil
+}
+
+// Evaluate returns ForceAllow while the battery is critical.
+func (c *Checker) Evaluate(ctx context.Context) check.Result {
+	status, err := c.Source.Status(ctx)
+	if err != nil || !status.Critical(c.MinCharge, c.MinRuntime) {
+		// An unreachable UPS can't vouch for anything
+		return check.Result{Check: c.Name(), Verdict: check.Neutral}
+	}
+	return check.Result{Check: c.Name(), Verdict: check.ForceAllow, Reason: status.Describe()}
+}
@@ -0,0 +1,94 @@
+package ups
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultNUTPort is upsd's default port
+const DefaultNUTPort = "3493"
+
+// NUT reads UPS variables from a NUT upsd server
+type NUT struct {
+	Addr    string // host:port
+	UPS     string // UPS name as configured in ups.conf
+	Timeout time.Duration
+}
+
+// NewNUT creates a NUT client. addr defaults to port 3493 if none is given.
+func NewNUT(addr, ups string, timeout time.Duration) *NUT {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, DefaultNUTPort)
+	}
+	return &NUT{Addr: addr, UPS: ups, Timeout: timeout}
+}
+
+// Status implements Source.
+func (n *NUT) Status(ctx context.Context) (*Status, error) {
+	vars, err := n.Vars(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	flags, ok := vars["ups.status"]
+	if !ok {
+		return nil, fmt.Errorf("ups %s reports no ups.status", n.UPS)
+	}
+	status := &Status{Charge: -1, Runtime: -1}
+	status.applyFlags(flags)
+	if v, err := strconv.ParseFloat(vars["battery.charge"], 64); err == nil {
+		status.Charge = v
+	}
+	if v, err := strconv.ParseFloat(vars["battery.runtime"], 64); err == nil {
+		status.Runtime = time.Duration(v * float64(time.Second))
+	}
+	return status, nil
+}
+
+// Vars returns all variables of the UPS, e.g. "battery.charge": "100".
+func (n *NUT) Vars(ctx context.Context) (map[string]string, error) {
+	d := net.Dialer{Timeout: n.Timeout}
+	conn, err := d.DialContext(ctx, "tcp", n.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("connect to upsd: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else if n.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(n.Timeout))
+	}
+
+	if _, err := fmt.Fprintf(conn, "LIST VAR %s\n", n.UPS); err != nil {
+		return nil, fmt.Errorf("send command: %w", err)
+	}
+
+	vars := make(map[string]string)
+	prefix := "VAR " + n.UPS + " "
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "ERR "):
+			return nil, fmt.Errorf("upsd: %s", strings.TrimPrefix(line, "ERR "))
+		case strings.HasPrefix(line, "BEGIN LIST"):
+		case strings.HasPrefix(line, "END LIST"):
+			fmt.Fprint(conn, "LOGOUT\n")
+			return vars, nil
+		case strings.HasPrefix(line, prefix):
+			name, value, ok := strings.Cut(strings.TrimPrefix(line, prefix), " ")
+			if ok {
+				vars[name] = strings.Trim(value, `"`)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	return nil, fmt.Errorf("read response: connection closed before END LIST")
+}
@@ -0,0 +1,83 @@
+// Package ups reads UPS state from NUT's upsd or apcupsd's network
+// information server, to tell when the host is running on a battery that is
+// about to run out.
+package ups
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Status is the UPS state common to NUT and apcupsd
+type Status struct {
+	OnBattery  bool
+	LowBattery bool // the UPS itself signals low battery
+	// ForcedShutdown is set when the UPS master has started shutting down
+	ForcedShutdown bool
+	Charge         float64       // percent, -1 if unknown
+	Runtime        time.Duration // estimated runtime left, -1 if unknown
+	Raw            string        // status flags as reported, e.g. "OB LB"
+}
+
+// Source reads the current UPS status
+type Source interface {
+	Status(ctx context.Context) (*Status, error)
+}
+
+// Critical reports whether the host is on battery and should be allowed to
+// shut down: the UPS signals low battery or forced shutdown, or charge or
+// runtime is below the given minimums (0 disables either).
+func (s *Status) Critical(minCharge float64, minRuntime time.Duration) bool {
+	if !s.OnBattery && !s.ForcedShutdown {
+		return false
+	}
+	switch {
+	case s.LowBattery, s.ForcedShutdown:
+		return true
+	case minCharge > 0 && s.Charge >= 0 && s.Charge < minCharge:
+		return true
+	case minRuntime > 0 && s.Runtime >= 0 && s.Runtime < minRuntime:
+		return true
+	}
+	return false
+}
+
+// Describe returns a human-readable summary, e.g.
+// "on battery, 23% charge, 4m0s runtime left".
+func (s *Status) Describe() string {
+	parts := []string{"on line power"}
+	if s.OnBattery {
+		parts[0] = "on battery"
+	}
+	if s.LowBattery {
+		parts = append(parts, "low battery")
+	}
+	if s.ForcedShutdown {
+		parts = append(parts, "forced shutdown")
+	}
+	if s.Charge >= 0 {
+		parts = append(parts, fmt.Sprintf("%.0f%% charge", s.Charge))
+	}
+	if s.Runtime >= 0 {
+		parts = append(parts, fmt.Sprintf("%s runtime left", s.Runtime.Round(time.Second)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// applyFlags sets the boolean fields from NUT-style status flags
+// ("OL", "OB", "LB", "FSD", ...).
+func (s *Status) applyFlags(flags string) {
+	s.Raw = flags
+	for _, f := range strings.Fields(flags) {
+		switch f {
+		case "OB":
+			s.OnBattery = true
+		case "LB":
+			s.LowBattery = true
+		case "FSD":
+			s.ForcedShutdown = true
+		}
+	}
+}
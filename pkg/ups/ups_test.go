@@ -0,0 +1,160 @@
+package ups
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/check"
+)
+
+// serve runs handler for each connection on a local listener.
+func serve(t *testing.T, handler func(net.Conn)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handler(conn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func fakeUPSD(vars map[string]string) func(net.Conn) {
+	return func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		line, _ := r.ReadString('\n')
+		if strings.TrimSpace(line) != "LIST VAR ups" {
+			io.WriteString(conn, "ERR UNKNOWN-UPS\n")
+			return
+		}
+		io.WriteString(conn, "BEGIN LIST VAR ups\n")
+		for k, v := range vars {
+			io.WriteString(conn, "VAR ups "+k+` "`+v+`"`+"\n")
+		}
+		io.WriteString(conn, "END LIST VAR ups\n")
+		r.ReadString('\n') // LOGOUT
+	}
+}
+
+func TestNUT_Status(t *testing.T) {
+	addr := serve(t, fakeUPSD(map[string]string{
+		"ups.status":      "OB DISCHRG",
+		"battery.charge":  "23",
+		"battery.runtime": "240",
+		"ups.model":       "Back-UPS ES 700G",
+	}))
+
+	status, err := NewNUT(addr, "ups", time.Second).Status(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !status.OnBattery || status.LowBattery || status.Charge != 23 || status.Runtime != 4*time.Minute {
+		t.Errorf("status = %+v", status)
+	}
+	if got, want := status.Describe(), "on battery, 23% charge, 4m0s runtime left"; got != want {
+		t.Errorf("Describe = %q, want %q", got, want)
+	}
+
+	if _, err := NewNUT(addr, "other", time.Second).Status(context.Background()); err == nil || !strings.Contains(err.Error(), "UNKNOWN-UPS") {
+		t.Errorf("err = %v, want UNKNOWN-UPS", err)
+	}
+}
+
+func TestAPCUPSD_Status(t *testing.T) {
+	records := []string{
+		"APC      : 001,036,0879\n",
+		"STATUS   : ONBATT LOWBATT \n",
+		"BCHARGE  : 8.0 Percent\n",
+		"TIMELEFT : 1.5 Minutes\n",
+	}
+	addr := serve(t, func(conn net.Conn) {
+		var size [2]byte
+		io.ReadFull(conn, size[:])
+		cmd := make([]byte, binary.BigEndian.Uint16(size[:]))
+		io.ReadFull(conn, cmd)
+		if string(cmd) != "status" {
+			return
+		}
+		for _, r := range records {
+			conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(r))))
+			io.WriteString(conn, r)
+		}
+		conn.Write([]byte{0, 0})
+	})
+
+	status, err := NewAPCUPSD(addr, time.Second).Status(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !status.OnBattery || !status.LowBattery || status.Charge != 8 || status.Runtime != 90*time.Second {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestStatus_Critical(t *testing.T) {
+	tests := []struct {
+		name   string
+		status Status
+		want   bool
+	}{
+		{"online", Status{Charge: 5, Runtime: time.Minute}, false},
+		{"on battery, plenty left", Status{OnBattery: true, Charge: 90, Runtime: time.Hour}, false},
+		{"low charge", Status{OnBattery: true, Charge: 20, Runtime: time.Hour}, true},
+		{"low runtime", Status{OnBattery: true, Charge: 90, Runtime: 2 * time.Minute}, true},
+		{"ups low battery", Status{OnBattery: true, LowBattery: true, Charge: -1, Runtime: -1}, true},
+		{"forced shutdown", Status{ForcedShutdown: true, Charge: -1, Runtime: -1}, true},
+		{"unknown charge", Status{OnBattery: true, Charge: -1, Runtime: -1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.status.Critical(30, 5*time.Minute); got != tt.want {
+				t.Errorf("Critical = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type fakeSource struct {
+	status *Status
+	err    error
+}
+
+func (f *fakeSource) Status(ctx context.Context) (*Status, error) { return f.status, f.err }
+
+func TestChecker_Evaluate(t *testing.T) {
+	source := &fakeSource{status: &Status{OnBattery: true, Charge: 80, Runtime: time.Hour}}
+	checker := NewChecker(source)
+
+	if r := checker.Evaluate(context.Background()); r.Verdict != check.Neutral {
+		t.Errorf("verdict = %s, want neutral with plenty of battery", r.Verdict)
+	}
+
+	source.status = &Status{OnBattery: true, Charge: 12, Runtime: 3 * time.Minute}
+	if r := checker.Evaluate(context.Background()); r.Verdict != check.ForceAllow || r.Reason != "on battery, 12% charge, 3m0s runtime left" {
+		t.Errorf("result = %+v, want force-allow", r)
+	}
+
+	source.err = errors.New("connection refused")
+	if r := checker.Evaluate(context.Background()); r.Verdict != check.Neutral {
+		t.Errorf("verdict = %s, want neutral when the UPS is unreachable", r.Verdict)
+	}
+}
@@ -0,0 +1,120 @@
+package upstream
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	resolvedDest = "org.freedesktop.resolve1"
+	resolvedPath = "/org/freedesktop/resolve1"
+	// resolvedStub is the address of resolved's stub listener
+	resolvedStub = "127.0.0.53"
+)
+
+// resolvConf is read to tell a stale resolved stub from other resolvers;
+// replaced in tests
+var resolvConf = "/etc/resolv.conf"
+
+// resolver looks names up through systemd-resolved over D-Bus when it is
+// running, so a broken resolved is reported as such, and otherwise through
+// the nameservers in resolv.conf. It connects on first use.
+type resolver struct {
+	mu   sync.Mutex
+	conn *dbus.Conn
+}
+
+// lookup resolves name, returning an error that says which path failed,
+// e.g. "lookup example.com via systemd-resolved: No appropriate name
+// servers or networks for name found".
+func (r *resolver) lookup(ctx context.Context, name string) error {
+	if conn := r.resolved(ctx); conn != nil {
+		if err := resolveHostname(ctx, conn, name); err != nil {
+			return fmt.Errorf("lookup %s via systemd-resolved: %w", name, err)
+		}
+		return nil
+	}
+
+	if _, err := net.DefaultResolver.LookupHost(ctx, name); err != nil {
+		if usesStub(resolvConf) {
+			// The stub only answers while resolved runs
+			return fmt.Errorf("lookup %s: %w (resolv.conf points at the systemd-resolved stub, but resolved isn't running)", name, err)
+		}
+		return fmt.Errorf("lookup %s: %w", name, err)
+	}
+	return nil
+}
+
+// resolved returns a system bus connection if resolved is on it, or nil
+func (r *resolver) resolved(ctx context.Context) *dbus.Conn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn == nil || !r.conn.Connected() {
+		conn, err := dbus.ConnectSystemBus()
+		if err != nil {
+			return nil
+		}
+		r.conn = conn
+	}
+	var owned bool
+	if err := r.conn.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.NameHasOwner", 0, resolvedDest).Store(&owned); err != nil || !owned {
+		return nil
+	}
+	return r.conn
+}
+
+// resolveHostname asks resolved for name's addresses, over every
+// interface and for both address families
+func resolveHostname(ctx context.Context, conn *dbus.Conn, name string) error {
+	var (
+		addrs []struct {
+			Ifindex int32
+			Family  int32
+			Addr    []byte
+		}
+		canonical string
+		flags     uint64
+	)
+	call := conn.Object(resolvedDest, resolvedPath).CallWithContext(ctx, resolvedDest+".Manager.ResolveHostname", 0,
+		int32(0), name, int32(syscall.AF_UNSPEC), uint64(0))
+	if err := call.Store(&addrs, &canonical, &flags); err != nil {
+		// resolved's errors carry their explanation in the body
+		var derr dbus.Error
+		if errors.As(err, &derr) && len(derr.Body) > 0 {
+			if msg, ok := derr.Body[0].(string); ok {
+				return errors.New(msg)
+			}
+		}
+		return err
+	}
+	if len(addrs) == 0 {
+		return errors.New("no addresses")
+	}
+	return nil
+}
+
+// usesStub reports whether the resolv.conf at path sends queries to
+// resolved's stub listener
+func usesStub(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) >= 2 && fields[0] == "nameserver" && fields[1] == resolvedStub {
+			return true
+		}
+	}
+	return false
+}
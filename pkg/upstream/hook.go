@@ -0,0 +1,97 @@
+package upstream
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Hook is a remediation: something that may bring the upstream back
+type Hook interface {
+	// Remediate runs the remediation, returning once it is complete
+	Remediate(ctx context.Context) error
+	String() string
+}
+
+// onRetryInterval is the wait between attempts to switch a plug back on;
+// replaced in tests
+var onRetryInterval = 5 * time.Second
+
+// PowerCycle switches a smart plug off and on again through its HTTP API,
+// e.g. for a Shelly plug http://plug/relay/0?turn=off and ...?turn=on, or
+// for Tasmota http://plug/cm?cmnd=Power%20Off and ...%20On.
+type PowerCycle struct {
+	OffURL string
+	OnURL  string
+	// OffFor is how long the plug stays off, long enough for the modem's
+	// capacitors to drain
+	OffFor time.Duration
+	// Method is the HTTP method, GET by default as most plugs take
+	Method string
+
+	httpClient *http.Client
+}
+
+// NewPowerCycle creates a power cycle hook.
+func NewPowerCycle(offURL, onURL string, offFor, timeout time.Duration) *PowerCycle {
+	return &PowerCycle{
+		OffURL: offURL,
+		OnURL:  onURL,
+		OffFor: offFor,
+		Method: "GET",
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+func (p *PowerCycle) String() string {
+	return "power cycle"
+}
+
+// Remediate switches the plug off, waits OffFor and switches it on. Once
+// the plug is off, switching it on again is retried for up to a minute
+// even if ctx is cancelled: a modem left off is worse than one that hangs.
+func (p *PowerCycle) Remediate(ctx context.Context) error {
+	if err := p.call(ctx, p.OffURL); err != nil {
+		return fmt.Errorf("switch off: %w", err)
+	}
+
+	select {
+	case <-time.After(p.OffFor):
+	case <-ctx.Done():
+	}
+
+	onCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+	defer cancel()
+	var err error
+	for {
+		if err = p.call(onCtx, p.OnURL); err == nil {
+			return nil
+		}
+		select {
+		case <-time.After(onRetryInterval):
+		case <-onCtx.Done():
+			return fmt.Errorf("switch on: %w", err)
+		}
+	}
+}
+
+func (p *PowerCycle) call(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, p.Method, url, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	return nil
+}
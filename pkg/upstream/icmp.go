@@ -0,0 +1,103 @@
+package upstream
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// errICMPUnavailable is returned when the kernel won't open an
+// unprivileged ICMP socket: not Linux, or the sidecar's group is outside
+// net.ipv4.ping_group_range
+var errICMPUnavailable = errors.New("unprivileged ICMP sockets unavailable")
+
+// ICMP message types
+const (
+	icmpEchoReply   = 0
+	icmpEchoRequest = 8
+)
+
+// ping sends one ICMP echo request to host and waits for the reply until
+// ctx is done. The datagram socket it uses needs no privileges, but
+// carries IPv4 only.
+func ping(ctx context.Context, host string, seq uint16) error {
+	ip, err := resolve4(ctx, host)
+	if err != nil {
+		return err
+	}
+	conn, err := listenICMP()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	// Unblock the read if ctx is cancelled before its deadline
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if _, err := conn.WriteTo(echoRequest(seq), &net.UDPAddr{IP: ip}); err != nil {
+		return fmt.Errorf("icmp echo to %s: %w", ip, err)
+	}
+	buf := make([]byte, 512)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return fmt.Errorf("icmp echo to %s: %w", ip, err)
+		}
+		// The kernel only delivers replies to this socket's identifier
+		if isEchoReply(buf[:n], seq) {
+			return nil
+		}
+	}
+}
+
+// resolve4 returns host's first IPv4 address
+func resolve4(ctx context.Context, host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			return ip4, nil
+		}
+		return nil, fmt.Errorf("icmp echo: %s is not IPv4", host)
+	}
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip4", host)
+	if err != nil {
+		return nil, err
+	}
+	return ips[0], nil
+}
+
+// echoRequest builds an echo request. The kernel fills in the identifier.
+func echoRequest(seq uint16) []byte {
+	msg := make([]byte, 16)
+	msg[0] = icmpEchoRequest
+	binary.BigEndian.PutUint16(msg[6:], seq)
+	copy(msg[8:], "sidecar!")
+	binary.BigEndian.PutUint16(msg[2:], checksum(msg))
+	return msg
+}
+
+func isEchoReply(msg []byte, seq uint16) bool {
+	return len(msg) >= 8 && msg[0] == icmpEchoReply && msg[1] == 0 &&
+		binary.BigEndian.Uint16(msg[6:]) == seq
+}
+
+// checksum is the Internet checksum of RFC 1071
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,20 @@
+package upstream
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// listenICMP opens an unprivileged ICMP datagram socket
+func listenICMP() (net.PacketConn, error) {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.IPPROTO_ICMP)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errICMPUnavailable, err)
+	}
+	f := os.NewFile(uintptr(fd), "icmp")
+	defer f.Close()
+	// FilePacketConn dups the descriptor, so f is closed either way
+	return net.FilePacketConn(f)
+}
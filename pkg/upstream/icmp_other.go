@@ -0,0 +1,9 @@
+//go:build !linux
+
+package upstream
+
+import "net"
+
+func listenICMP() (net.PacketConn, error) {
+	return nil, errICMPUnavailable
+}
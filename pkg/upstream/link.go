@@ -0,0 +1,51 @@
+package upstream
+
+import (
+	"fmt"
+	"net"
+)
+
+// linkState is what the kernel says about the interface the targets are
+// reached through
+type linkState struct {
+	Up      bool     // administratively up
+	Carrier bool     // cable plugged in and negotiated (IFF_LOWER_UP)
+	Addrs   []net.IP // addresses, link-local included
+	// DefaultRoute is set if a default route leaves through the interface
+	DefaultRoute bool
+}
+
+// linkProblem checks Interface step by step and describes the first step
+// that fails, e.g. "eno1: no carrier", or returns "" if it is usable or
+// no Interface is set. A broken link explains every unreachable target,
+// and power-cycling the modem won't fix it.
+func (c *Checker) linkProblem() string {
+	if c.Interface == "" {
+		return ""
+	}
+	st, err := c.link(c.Interface)
+	switch {
+	case err != nil:
+		return fmt.Sprintf("%s: %v", c.Interface, err)
+	case !st.Up:
+		return fmt.Sprintf("%s: link down", c.Interface)
+	case !st.Carrier:
+		return fmt.Sprintf("%s: no carrier", c.Interface)
+	case !routable(st.Addrs):
+		return fmt.Sprintf("%s: no address other than link-local", c.Interface)
+	case !st.DefaultRoute:
+		return fmt.Sprintf("%s: no default route", c.Interface)
+	}
+	return ""
+}
+
+// routable reports whether any of addrs is neither link-local nor
+// loopback: a 169.254/16 address means DHCP never answered
+func routable(addrs []net.IP) bool {
+	for _, ip := range addrs {
+		if !ip.IsLinkLocalUnicast() && !ip.IsLoopback() {
+			return true
+		}
+	}
+	return false
+}
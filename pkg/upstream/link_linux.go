@@ -0,0 +1,84 @@
+package upstream
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// iffLowerUp is set in a link's flags while it has carrier
+const iffLowerUp = 0x10000
+
+// readLink asks rtnetlink for name's flags, addresses and routes
+func readLink(name string) (linkState, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return linkState{}, errors.New("no such interface")
+	}
+	st := linkState{Up: iface.Flags&net.FlagUp != 0}
+
+	links, err := dump(syscall.RTM_GETLINK)
+	if err != nil {
+		return linkState{}, err
+	}
+	for _, m := range links {
+		// struct ifinfomsg: family, pad, type, then the index and flags
+		if m.Header.Type != syscall.RTM_NEWLINK || len(m.Data) < syscall.SizeofIfInfomsg {
+			continue
+		}
+		if int(int32(binary.NativeEndian.Uint32(m.Data[4:]))) == iface.Index {
+			st.Carrier = binary.NativeEndian.Uint32(m.Data[8:])&iffLowerUp != 0
+		}
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return linkState{}, fmt.Errorf("addresses: %w", err)
+	}
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok {
+			st.Addrs = append(st.Addrs, n.IP)
+		}
+	}
+
+	routes, err := dump(syscall.RTM_GETROUTE)
+	if err != nil {
+		return linkState{}, err
+	}
+	for _, m := range routes {
+		// struct rtmsg: family, dst_len, src_len, tos, table, protocol,
+		// scope, type
+		if m.Header.Type != syscall.RTM_NEWROUTE || len(m.Data) < syscall.SizeofRtMsg {
+			continue
+		}
+		if m.Data[1] != 0 || m.Data[4] != syscall.RT_TABLE_MAIN || m.Data[7] != syscall.RTN_UNICAST {
+			continue
+		}
+		attrs, err := syscall.ParseNetlinkRouteAttr(&m)
+		if err != nil {
+			continue
+		}
+		for _, a := range attrs {
+			if a.Attr.Type == syscall.RTA_OIF && len(a.Value) >= 4 &&
+				int(int32(binary.NativeEndian.Uint32(a.Value))) == iface.Index {
+				st.DefaultRoute = true
+			}
+		}
+	}
+	return st, nil
+}
+
+// dump runs an rtnetlink dump request for both address families
+func dump(request int) ([]syscall.NetlinkMessage, error) {
+	rib, err := syscall.NetlinkRIB(request, syscall.AF_UNSPEC)
+	if err != nil {
+		return nil, fmt.Errorf("netlink: %w", err)
+	}
+	msgs, err := syscall.ParseNetlinkMessage(rib)
+	if err != nil {
+		return nil, fmt.Errorf("netlink: %w", err)
+	}
+	return msgs, nil
+}
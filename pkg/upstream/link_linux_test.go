@@ -0,0 +1,19 @@
+package upstream
+
+import "testing"
+
+func TestReadLink(t *testing.T) {
+	st, err := readLink("lo")
+	if err != nil {
+		t.Fatalf("readLink(lo) = %v", err)
+	}
+	if !st.Up || !st.Carrier || len(st.Addrs) == 0 {
+		t.Errorf("readLink(lo) = %+v, want up with carrier and addresses", st)
+	}
+	if st.DefaultRoute {
+		t.Error("readLink(lo) found a default route through loopback")
+	}
+	if _, err := readLink("no-such-if0"); err == nil {
+		t.Error("readLink of a missing interface succeeded")
+	}
+}
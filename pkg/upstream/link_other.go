@@ -0,0 +1,33 @@
+//go:build !linux
+
+package upstream
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// readLink reads name's flags and addresses. Without rtnetlink, a running
+// interface counts as having carrier, and routes aren't checked.
+func readLink(name string) (linkState, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return linkState{}, errors.New("no such interface")
+	}
+	st := linkState{
+		Up:           iface.Flags&net.FlagUp != 0,
+		Carrier:      iface.Flags&net.FlagRunning != 0,
+		DefaultRoute: true,
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return linkState{}, fmt.Errorf("addresses: %w", err)
+	}
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok {
+			st.Addrs = append(st.Addrs, n.IP)
+		}
+	}
+	return st, nil
+}
@@ -0,0 +1,375 @@
+// Package upstream checks that the router and modem (the customer premises
+// equipment, or CPE) answer, and after a sustained outage runs a
+// remediation hook such as power-cycling the modem's smart plug.
+//
+// Remediation is rate limited so a line that is down at the provider's end
+// doesn't have its modem power-cycled all day: a cooldown between attempts
+// and a maximum per window, recorded in a state file so restarting the
+// sidecar, or the host, doesn't reset them.
+package upstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/logging"
+)
+
+// Checker implements check.Checker for upstream reachability.
+// Check returns an error while the hook is running, since a shutdown then
+// could leave the modem switched off.
+type Checker struct {
+	// Targets are host:port pairs that accept TCP connections, e.g. the
+	// router's and the modem's web interfaces
+	Targets []string
+	Timeout time.Duration
+	// Interface, if set, is checked before the targets: it must be up,
+	// have carrier, an address other than link-local and the default
+	// route. While it doesn't, that is reported instead of the targets,
+	// and the hook isn't run, since the modem isn't to blame.
+	Interface string
+	// LookupName, if set, is resolved once the targets answer, through
+	// systemd-resolved if it is running and resolv.conf otherwise. A
+	// failed lookup is reported but isn't an outage: power-cycling the
+	// modem rarely fixes DNS.
+	LookupName string
+	// ICMP pings each target's host before trying its port, for routers
+	// that drop TCP from the LAN. It needs unprivileged ICMP sockets (the
+	// sidecar's group inside net.ipv4.ping_group_range); without them, or
+	// without a reply, the TCP connect decides.
+	ICMP bool
+	// Probes is how many times each target is probed per check. Above 1,
+	// a target that answers but loses more than MaxLoss of the probes, or
+	// takes longer than MaxLatency on average, is reported degraded: a
+	// port negotiated at 10Mb half duplex still answers a single connect.
+	// Only an unreachable target counts towards FailAfter.
+	Probes     int
+	MaxLoss    float64 // fraction, 0 to 1; 0 disables
+	MaxLatency time.Duration
+	// FailAfter is how long a target must be unreachable before the hook
+	// runs
+	FailAfter time.Duration
+	// Hook is the remediation; nil only reports
+	Hook Hook
+	// Cooldown is the minimum time between remediations, which also gives
+	// the modem time to come back up
+	Cooldown time.Duration
+	// MaxAttempts remediations are allowed per Window
+	MaxAttempts int
+	Window      time.Duration
+	// StatePath records when the hook ran; empty keeps it in memory
+	StatePath string
+
+	mu           sync.Mutex
+	failingSince time.Time
+	running      bool
+	lastErr      error
+	attempts     []time.Time
+
+	noICMP atomic.Bool // ICMP sockets turned out to be unavailable
+	seq    atomic.Uint32
+
+	dial    func(ctx context.Context, addr string) (net.Conn, error) // replaced in tests
+	now     func() time.Time                                         // replaced in tests
+	ping    func(ctx context.Context, host string, seq uint16) error // replaced in tests
+	link    func(name string) (linkState, error)                     // replaced in tests
+	resolve func(ctx context.Context, name string) error             // replaced in tests
+}
+
+// NewChecker creates an upstream checker with conservative safeguards:
+// remediate after 5 minutes down, at most 3 times a day, 30 minutes apart.
+func NewChecker(targets []string, hook Hook) *Checker {
+	return &Checker{
+		Targets:     targets,
+		Timeout:     3 * time.Second,
+		Probes:      1,
+		MaxLoss:     0.2,
+		FailAfter:   5 * time.Minute,
+		Hook:        hook,
+		Cooldown:    30 * time.Minute,
+		MaxAttempts: 3,
+		Window:      24 * time.Hour,
+		dial: func(ctx context.Context, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "tcp", addr)
+		},
+		now:     time.Now,
+		ping:    ping,
+		link:    readLink,
+		resolve: (&resolver{}).lookup,
+	}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "upstream"
+}
+
+// Check returns nil unless a remediation is running.
+func (c *Checker) Check(ctx context.Context) error {
+	reasons, _ := c.Activity(ctx)
+	if len(reasons) > 0 {
+		return errors.New(strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// Activity probes the targets and starts the hook if they have been
+// unreachable for FailAfter and the safeguards allow it. It describes a
+// remediation in progress, and returns an error naming the unreachable
+// targets, so the outage is reported while it lasts.
+func (c *Checker) Activity(ctx context.Context) ([]string, error) {
+	problem := c.linkProblem()
+	var (
+		down, degraded []string
+		lookupErr      error
+	)
+	if problem == "" {
+		down, degraded = c.probe(ctx)
+	}
+	if problem == "" && len(down) == 0 {
+		lookupErr = c.lookup(ctx)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if c.running {
+		return []string{fmt.Sprintf("%s of upstream in progress", c.Hook)}, nil
+	}
+	if problem != "" {
+		// Not an upstream outage: FailAfter starts over once the link is back
+		c.failingSince = time.Time{}
+		return nil, errors.New(problem)
+	}
+	if len(down) == 0 {
+		c.failingSince = time.Time{}
+		return nil, trouble(degraded, lookupErr)
+	}
+	if c.failingSince.IsZero() {
+		c.failingSince = now
+	}
+	outage := now.Sub(c.failingSince)
+	err := fmt.Errorf("unreachable for %s: %s", outage.Truncate(time.Second), strings.Join(down, ", "))
+	if c.Hook == nil || outage < c.FailAfter {
+		return nil, err
+	}
+
+	if reason := c.blocked(now); reason != "" {
+		return nil, fmt.Errorf("%w; not remediating: %s", err, reason)
+	}
+	if rerr := c.record(now); rerr != nil {
+		// Without a record the safeguards can't hold across restarts
+		return nil, fmt.Errorf("%w; not remediating: state: %v", err, rerr)
+	}
+	c.running = true
+	go c.remediate()
+	return []string{fmt.Sprintf("%s of upstream in progress", c.Hook)}, nil
+}
+
+// Health probes the targets and returns an error naming those that don't
+// answer, the step of Interface that fails, or a failed lookup of
+// LookupName. Unlike Activity it never runs the hook, so it suits a
+// one-off check such as a boot health check.
+func (c *Checker) Health(ctx context.Context) error {
+	if problem := c.linkProblem(); problem != "" {
+		return errors.New(problem)
+	}
+	down, degraded := c.probe(ctx)
+	if len(down) > 0 {
+		problems := []string{"unreachable: " + strings.Join(down, ", ")}
+		if len(degraded) > 0 {
+			problems = append(problems, "degraded: "+strings.Join(degraded, ", "))
+		}
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return trouble(degraded, c.lookup(ctx))
+}
+
+// lookup resolves LookupName, if set, within Timeout
+func (c *Checker) lookup(ctx context.Context) error {
+	if c.LookupName == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+	return c.resolve(ctx, c.LookupName)
+}
+
+// trouble describes what is wrong while every target answers: those
+// degraded, and a failed lookup. It returns nil if nothing is.
+func trouble(degraded []string, lookupErr error) error {
+	var problems []string
+	if len(degraded) > 0 {
+		problems = append(problems, "degraded: "+strings.Join(degraded, ", "))
+	}
+	if lookupErr != nil {
+		problems = append(problems, lookupErr.Error())
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(problems, "; "))
+}
+
+// probe probes each target Probes times and returns those that never
+// answered, and descriptions of those over MaxLoss or MaxLatency, e.g.
+// "192.168.1.1:80 loss 40%".
+func (c *Checker) probe(ctx context.Context) (down, degraded []string) {
+	for _, t := range c.Targets {
+		sent, received, latency := c.measure(ctx, t)
+		if received == 0 {
+			down = append(down, t)
+			continue
+		}
+		if sent < 2 {
+			continue
+		}
+		if loss := float64(sent-received) / float64(sent); c.MaxLoss > 0 && loss > c.MaxLoss {
+			degraded = append(degraded, fmt.Sprintf("%s loss %.0f%%", t, loss*100))
+		}
+		if c.MaxLatency > 0 && latency > c.MaxLatency {
+			degraded = append(degraded, fmt.Sprintf("%s latency %s", t, latency.Round(time.Millisecond)))
+		}
+	}
+	return down, degraded
+}
+
+// measure probes addr Probes times in a row, returning how many probes
+// were sent and answered and the average time of those answered
+func (c *Checker) measure(ctx context.Context, addr string) (sent, received int, latency time.Duration) {
+	var total time.Duration
+	for i := 0; i < max(c.Probes, 1); i++ {
+		if i > 0 && ctx.Err() != nil {
+			break
+		}
+		sent++
+		start := c.now()
+		if !c.reachable(ctx, addr) {
+			continue
+		}
+		received++
+		total += c.now().Sub(start)
+	}
+	if received > 0 {
+		latency = total / time.Duration(received)
+	}
+	return sent, received, latency
+}
+
+// LastError returns the error from the last remediation, if it failed.
+func (c *Checker) LastError() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastErr
+}
+
+func (c *Checker) remediate() {
+	err := c.Hook.Remediate(context.Background())
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.running = false
+	c.lastErr = err
+	// The modem needs time to come back: FailAfter starts over
+	c.failingSince = c.now()
+}
+
+func (c *Checker) reachable(ctx context.Context, addr string) bool {
+	if c.ICMP && !c.noICMP.Load() && c.echo(ctx, addr) {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+	conn, err := c.dial(ctx, addr)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// echo pings addr's host. Once ICMP sockets turn out to be unavailable it
+// says so and isn't tried again.
+func (c *Checker) echo(ctx context.Context, addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+	err = c.ping(ctx, host, uint16(c.seq.Add(1)))
+	if errors.Is(err, errICMPUnavailable) && c.noICMP.CompareAndSwap(false, true) {
+		logging.Warnf("upstream: %v, falling back to TCP connects", err)
+	}
+	return err == nil
+}
+
+// blocked returns why a remediation isn't allowed now, or "".
+func (c *Checker) blocked(now time.Time) string {
+	c.load()
+	var recent []time.Time
+	for _, t := range c.attempts {
+		if now.Sub(t) < c.Window {
+			recent = append(recent, t)
+		}
+	}
+	c.attempts = recent
+
+	if n := len(recent); n > 0 {
+		if since := now.Sub(recent[n-1]); since < c.Cooldown {
+			return fmt.Sprintf("last attempt %s ago, cooldown %s", since.Truncate(time.Second), c.Cooldown)
+		}
+	}
+	if len(recent) >= c.MaxAttempts {
+		return fmt.Sprintf("%d attempts in the last %s", len(recent), c.Window)
+	}
+	return ""
+}
+
+// load reads the attempts from StatePath, if there is one. A missing or
+// unreadable file keeps the attempts in memory.
+func (c *Checker) load() {
+	if c.StatePath == "" {
+		return
+	}
+	data, err := os.ReadFile(c.StatePath)
+	if err != nil {
+		return
+	}
+	var state struct {
+		Attempts []time.Time `json:"attempts"`
+	}
+	if json.Unmarshal(data, &state) == nil {
+		c.attempts = state.Attempts
+	}
+}
+
+// record adds an attempt and writes the attempts to StatePath.
+func (c *Checker) record(now time.Time) error {
+	c.attempts = append(c.attempts, now)
+	if c.StatePath == "" {
+		return nil
+	}
+	data, err := json.Marshal(struct {
+		Attempts []time.Time `json:"attempts"`
+	}{c.attempts})
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(filepath.Dir(c.StatePath), "."+filepath.Base(c.StatePath)+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.StatePath)
+}
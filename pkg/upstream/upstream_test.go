@@ -0,0 +1,363 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeHook records its runs and blocks until released
+type fakeHook struct {
+	mu      sync.Mutex
+	runs    int
+	release chan struct{}
+}
+
+func (h *fakeHook) Remediate(ctx context.Context) error {
+	h.mu.Lock()
+	h.runs++
+	h.mu.Unlock()
+	<-h.release
+	return nil
+}
+
+func (h *fakeHook) String() string { return "fake" }
+
+func newTestChecker(t *testing.T, up *bool, now *time.Time, statePath string) (*Checker, *fakeHook) {
+	t.Helper()
+	hook := &fakeHook{release: make(chan struct{})}
+	c := NewChecker([]string{"router:80", "modem:80"}, hook)
+	c.StatePath = statePath
+	c.dial = func(ctx context.Context, addr string) (net.Conn, error) {
+		if addr == "modem:80" && !*up {
+			return nil, errors.New("no route to host")
+		}
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	}
+	c.now = func() time.Time { return *now }
+	return c, hook
+}
+
+// waitIdle waits for a released remediation to finish
+func waitIdle(t *testing.T, c *Checker) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		c.mu.Lock()
+		running := c.running
+		c.mu.Unlock()
+		if !running {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("remediation still running")
+}
+
+func TestChecker(t *testing.T) {
+	up := false
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	c, hook := newTestChecker(t, &up, &now, filepath.Join(t.TempDir(), "state.json"))
+
+	// Down, but not for long enough
+	reasons, err := c.Activity(context.Background())
+	if len(reasons) != 0 || err == nil || err.Error() != "unreachable for 0s: modem:80" {
+		t.Fatalf("Activity() = %q, %v", reasons, err)
+	}
+
+	now = now.Add(5 * time.Minute)
+	reasons, _ = c.Activity(context.Background())
+	if len(reasons) != 1 || reasons[0] != "fake of upstream in progress" {
+		t.Fatalf("Activity() after FailAfter = %q, want remediation", reasons)
+	}
+	if err := c.Check(context.Background()); err == nil {
+		t.Error("Check() during remediation: want error to block shutdown")
+	}
+	close(hook.release)
+	waitIdle(t, c)
+
+	// Still down: the cooldown holds off a second attempt
+	now = now.Add(10 * time.Minute)
+	_, err = c.Activity(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "not remediating: last attempt 10m0s ago, cooldown 30m0s") {
+		t.Errorf("Activity() in cooldown = %v", err)
+	}
+
+	// A restarted sidecar reads the attempts back
+	for i := 0; i < 2; i++ {
+		now = now.Add(time.Hour)
+		c2, hook2 := newTestChecker(t, &up, &now, c.StatePath)
+		close(hook2.release)
+		c2.Activity(context.Background())
+		now = now.Add(5 * time.Minute)
+		if reasons, _ := c2.Activity(context.Background()); len(reasons) != 1 {
+			t.Fatalf("attempt %d: Activity() = %q, want remediation", i+2, reasons)
+		}
+		waitIdle(t, c2)
+	}
+	now = now.Add(time.Hour)
+	c3, _ := newTestChecker(t, &up, &now, c.StatePath)
+	c3.Activity(context.Background())
+	now = now.Add(5 * time.Minute)
+	_, err = c3.Activity(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "not remediating: 3 attempts in the last 24h0m0s") {
+		t.Errorf("Activity() after MaxAttempts = %v", err)
+	}
+	if hook.runs != 1 {
+		t.Errorf("first hook ran %d times, want 1", hook.runs)
+	}
+
+	// Back up
+	up = true
+	if reasons, err := c3.Activity(context.Background()); len(reasons) != 0 || err != nil {
+		t.Errorf("Activity() when up = %q, %v", reasons, err)
+	}
+}
+
+func TestICMP(t *testing.T) {
+	up := false
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	c, _ := newTestChecker(t, &up, &now, "")
+	c.ICMP = true
+
+	// The modem drops TCP but answers pings
+	var pinged []string
+	c.ping = func(ctx context.Context, host string, seq uint16) error {
+		pinged = append(pinged, host)
+		return nil
+	}
+	if _, err := c.Activity(context.Background()); err != nil {
+		t.Errorf("Activity() with ICMP replies = %v", err)
+	}
+	if got := strings.Join(pinged, ","); got != "router,modem" {
+		t.Errorf("pinged %s, want the targets' hosts", got)
+	}
+
+	// Without ICMP sockets every target falls back to TCP, and ICMP isn't
+	// tried again
+	pinged = nil
+	c.ping = func(ctx context.Context, host string, seq uint16) error {
+		pinged = append(pinged, host)
+		return errICMPUnavailable
+	}
+	up = true
+	c.Activity(context.Background())
+	if _, err := c.Activity(context.Background()); err != nil {
+		t.Errorf("Activity() falling back to TCP = %v", err)
+	}
+	if len(pinged) != 1 {
+		t.Errorf("pinged %d times after ICMP was unavailable, want 1", len(pinged))
+	}
+}
+
+func TestEchoRequest(t *testing.T) {
+	msg := echoRequest(7)
+	if msg[0] != icmpEchoRequest || checksum(msg) != 0 {
+		t.Errorf("echoRequest() = % x, want a checksummed echo request", msg)
+	}
+	reply := append([]byte{icmpEchoReply}, msg[1:]...)
+	if !isEchoReply(reply, 7) || isEchoReply(reply, 8) || isEchoReply(msg, 7) {
+		t.Error("isEchoReply() doesn't match the reply to sequence 7 only")
+	}
+
+	// Where the kernel allows it, ping the loopback interface for real
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := ping(ctx, "127.0.0.1", 1); errors.Is(err, errICMPUnavailable) {
+		t.Logf("skipping live ping: %v", err)
+	} else if err != nil {
+		t.Errorf("ping(127.0.0.1) = %v", err)
+	}
+}
+
+func TestPowerCycle(t *testing.T) {
+	var calls []string
+	fails := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.RawQuery)
+		// The plug misses the first "on"
+		if r.URL.RawQuery == "turn=on" && fails > 0 {
+			fails--
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	onRetryInterval = time.Millisecond
+	defer func() { onRetryInterval = 5 * time.Second }()
+
+	p := NewPowerCycle(server.URL+"/relay/0?turn=off", server.URL+"/relay/0?turn=on", time.Millisecond, time.Second)
+	if err := p.Remediate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(calls, ","); got != "turn=off,turn=on,turn=on" {
+		t.Errorf("calls = %s", got)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	up := false
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	c, _ := newTestChecker(t, &up, &now, "")
+
+	// Never remediates, however long the outage
+	for i := 0; i < 2; i++ {
+		if err := c.Health(context.Background()); err == nil || err.Error() != "unreachable: modem:80" {
+			t.Errorf("Health() = %v", err)
+		}
+		now = now.Add(time.Hour)
+	}
+	up = true
+	if err := c.Health(context.Background()); err != nil {
+		t.Errorf("Health() when up = %v", err)
+	}
+}
+
+func TestProbes(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := NewChecker([]string{"router:80", "switch:80"}, nil)
+	c.Probes = 5
+	c.MaxLatency = 10 * time.Millisecond
+	c.now = func() time.Time { return now }
+
+	// router drops every other connect; switch answers, slowly
+	n := 0
+	c.dial = func(ctx context.Context, addr string) (net.Conn, error) {
+		switch addr {
+		case "router:80":
+			n++
+			if n%2 == 0 {
+				return nil, errors.New("i/o timeout")
+			}
+		case "switch:80":
+			now = now.Add(30 * time.Millisecond)
+		}
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	}
+
+	err := c.Health(context.Background())
+	if err == nil || err.Error() != "degraded: router:80 loss 40%, switch:80 latency 30ms" {
+		t.Errorf("Health() = %v", err)
+	}
+	// A degraded link is reported, but isn't an outage
+	if _, err := c.Activity(context.Background()); err == nil || !strings.HasPrefix(err.Error(), "degraded: ") {
+		t.Errorf("Activity() = %v", err)
+	}
+	if !c.failingSince.IsZero() {
+		t.Error("degraded link counted towards FailAfter")
+	}
+
+	c.Probes, c.MaxLatency = 1, 0
+	if err := c.Health(context.Background()); err != nil {
+		t.Errorf("Health() with one probe = %v", err)
+	}
+}
+
+func TestInterface(t *testing.T) {
+	up := false
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	c, hook := newTestChecker(t, &up, &now, "")
+	c.Interface = "eno1"
+	c.FailAfter = 0
+
+	st := linkState{}
+	var linkErr error
+	c.link = func(name string) (linkState, error) { return st, linkErr }
+
+	// Each step is reported in turn, and none runs the hook
+	steps := []struct {
+		fix  func()
+		want string
+	}{
+		{func() { linkErr = errors.New("no such interface") }, "eno1: no such interface"},
+		{func() { linkErr = nil }, "eno1: link down"},
+		{func() { st.Up = true }, "eno1: no carrier"},
+		{func() { st.Carrier = true; st.Addrs = []net.IP{net.ParseIP("169.254.7.1"), net.ParseIP("fe80::1")} }, "eno1: no address other than link-local"},
+		{func() { st.Addrs = append(st.Addrs, net.ParseIP("192.168.1.20")) }, "eno1: no default route"},
+	}
+	for _, step := range steps {
+		step.fix()
+		if err := c.Health(context.Background()); err == nil || err.Error() != step.want {
+			t.Errorf("Health() = %v, want %s", err, step.want)
+		}
+		if reasons, err := c.Activity(context.Background()); len(reasons) != 0 || err == nil || err.Error() != step.want {
+			t.Errorf("Activity() = %q, %v; want %s", reasons, err, step.want)
+		}
+	}
+	if hook.runs != 0 || !c.failingSince.IsZero() {
+		t.Error("a broken link counted as an upstream outage")
+	}
+
+	// Once the link is usable the targets decide
+	st.DefaultRoute = true
+	if err := c.Health(context.Background()); err == nil || err.Error() != "unreachable: modem:80" {
+		t.Errorf("Health() with the link up = %v", err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	up := true
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	c, _ := newTestChecker(t, &up, &now, "")
+	c.LookupName = "example.com"
+	var resolveErr error
+	var looked []string
+	c.resolve = func(ctx context.Context, name string) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("lookup without a timeout")
+		}
+		looked = append(looked, name)
+		return resolveErr
+	}
+
+	if err := c.Health(context.Background()); err != nil || len(looked) != 1 {
+		t.Errorf("Health() = %v after %d lookups, want nil after 1", err, len(looked))
+	}
+
+	// A failed lookup is reported, but isn't an outage
+	resolveErr = errors.New("lookup example.com via systemd-resolved: No appropriate name servers or networks for name found")
+	if err := c.Health(context.Background()); err == nil || err.Error() != resolveErr.Error() {
+		t.Errorf("Health() = %v, want the lookup error", err)
+	}
+	if _, err := c.Activity(context.Background()); err == nil || err.Error() != resolveErr.Error() {
+		t.Errorf("Activity() = %v, want the lookup error", err)
+	}
+	if !c.failingSince.IsZero() {
+		t.Error("a failed lookup counted towards FailAfter")
+	}
+
+	// Names aren't looked up while a target is down
+	up = false
+	looked = nil
+	if err := c.Health(context.Background()); err == nil || err.Error() != "unreachable: modem:80" || len(looked) != 0 {
+		t.Errorf("Health() = %v after %d lookups, want the outage alone", err, len(looked))
+	}
+}
+
+func TestResolver(t *testing.T) {
+	dir := t.TempDir()
+	stub := filepath.Join(dir, "stub-resolv.conf")
+	os.WriteFile(stub, []byte("# managed by systemd-resolved\nnameserver 127.0.0.53\noptions edns0 trust-ad\n"), 0644)
+	direct := filepath.Join(dir, "resolv.conf")
+	os.WriteFile(direct, []byte("nameserver 192.168.1.1\n"), 0644)
+	if !usesStub(stub) || usesStub(direct) || usesStub(filepath.Join(dir, "missing")) {
+		t.Error("usesStub() doesn't recognise only the resolved stub")
+	}
+
+	// localhost resolves with or without resolved on the bus
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := (&resolver{}).lookup(ctx, "localhost"); err != nil {
+		t.Errorf("lookup(localhost) = %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package vaultwarden
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/procscan"
+)
+
+// DefaultProcessPattern matches the built-in "vaultwarden backup" command
+// and sqlite3 copying a Vaultwarden database with .backup or VACUUM INTO,
+// as backup scripts and containers do.
+const DefaultProcessPattern = `(^|/)vaultwarden\s+backup\b|(^|/)sqlite3\s.*\bdb\.sqlite3\b.*(\.backup\b|(?i:vacuum\s+into))`
+
+// DefaultStaleLockAge is how old a lock file can get before it is treated
+// as left over from a backup script that crashed.
+const DefaultStaleLockAge = time.Hour
+
+// Detector looks for evidence of a running Vaultwarden backup
+type Detector struct {
+	// LockFiles are files a backup script holds while it runs, e.g. a
+	// flock target or a marker it removes when done
+	LockFiles []string
+	// StaleLockAge ignores older lock files, 0 = DefaultStaleLockAge
+	StaleLockAge time.Duration
+	// ProcessPattern matches backup command lines; nil disables process matching
+	ProcessPattern *regexp.Regexp
+	// ProcRoot defaults to /proc
+	ProcRoot string
+}
+
+// Active returns a description of each backup that appears to be running.
+func (d *Detector) Active(now time.Time) ([]string, error) {
+	staleAge := d.StaleLockAge
+	if staleAge <= 0 {
+		staleAge = DefaultStaleLockAge
+	}
+
+	var active []string
+	for _, path := range d.LockFiles {
+		info, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("stat backup lock: %w", err)
+		}
+		if now.Sub(info.ModTime()) < staleAge {
+			active = append(active, fmt.Sprintf("backup lock %s held", path))
+		}
+	}
+
+	if d.ProcessPattern != nil {
+		procs, err := procscan.Find(d.ProcRoot, d.ProcessPattern)
+		if err != nil {
+			return nil, fmt.Errorf("scan processes: %w", err)
+		}
+		for _, p := range procs {
+			active = append(active, fmt.Sprintf("pid %d: %s", p.PID, p.Cmdline))
+		}
+	}
+
+	return active, nil
+}
@@ -0,0 +1,67 @@
+package vaultwarden
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestDetector_Active(t *testing.T) {
+	now := time.Now()
+	lock := filepath.Join(t.TempDir(), "backup.lock")
+	procRoot := t.TempDir()
+
+	d := &Detector{
+		LockFiles:      []string{lock},
+		ProcessPattern: regexp.MustCompile(DefaultProcessPattern),
+		ProcRoot:       procRoot,
+	}
+
+	active, err := d.Active(now)
+	if err != nil || len(active) != 0 {
+		t.Fatalf("idle: active = %v, err = %v", active, err)
+	}
+
+	if err := os.WriteFile(lock, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if active, _ := d.Active(now); len(active) != 1 {
+		t.Errorf("fresh lock: active = %v", active)
+	}
+
+	os.Chtimes(lock, now.Add(-2*time.Hour), now.Add(-2*time.Hour))
+	if active, _ := d.Active(now); len(active) != 0 {
+		t.Errorf("stale lock counted: active = %v", active)
+	}
+
+	os.MkdirAll(filepath.Join(procRoot, "4242"), 0755)
+	os.WriteFile(filepath.Join(procRoot, "4242", "cmdline"), []byte("/vaultwarden\x00backup\x00"), 0644)
+	if active, _ := d.Active(now); len(active) != 1 {
+		t.Errorf("backup process: active = %v", active)
+	}
+}
+
+func TestDefaultProcessPattern(t *testing.T) {
+	pattern := regexp.MustCompile(DefaultProcessPattern)
+
+	tests := []struct {
+		cmdline string
+		want    bool
+	}{
+		{"/vaultwarden backup", true},
+		{"sqlite3 /data/db.sqlite3 .backup '/backups/db-20240301.sqlite3'", true},
+		{"sqlite3 /data/db.sqlite3 VACUUM INTO '/backups/db.sqlite3'", true},
+		{"sqlite3 /data/db.sqlite3 vacuum into '/backups/db.sqlite3'", true},
+		{"/vaultwarden", false},
+		{"sqlite3 /data/db.sqlite3 .tables", false},
+		{"sqlite3 /srv/app.db .backup /tmp/app.db", false},
+	}
+
+	for _, tt := range tests {
+		if got := pattern.MatchString(tt.cmdline); got != tt.want {
+			t.Errorf("MatchString(%q) = %v, want %v", tt.cmdline, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package vaultwarden
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Checker implements check.Checker for Vaultwarden backups.
+// Returns an error while the SQLite database is being backed up, since a
+// copy interrupted by a reboot is left incomplete.
+type Checker struct {
+	Detector *Detector
+}
+
+// NewChecker creates a Vaultwarden backup checker.
+func NewChecker(d *Detector) *Checker {
+	return &Checker{Detector: d}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "vaultwarden"
+}
+
+// Check returns nil if no backup is running, error otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	active, err := c.Detector.Active(time.Now())
+	if err != nil {
+		return fmt.Errorf("vaultwarden check failed: %w", err)
+	}
+	if len(active) > 0 {
+		return fmt.Errorf("vaultwarden backup in progress: %s", strings.Join(active, "; "))
+	}
+	return nil
+}
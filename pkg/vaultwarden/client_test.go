@@ -0,0 +1,31 @@
+package vaultwarden
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_Alive(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/alive" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`"2024-03-01T12:00:00.000000Z"`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/", 5*time.Second)
+	if err := client.Alive(context.Background()); err != nil {
+		t.Errorf("Alive = %v, want nil", err)
+	}
+
+	status = http.StatusBadGateway
+	if err := client.Alive(context.Background()); err == nil {
+		t.Error("Alive succeeded on 502")
+	}
+}
@@ -0,0 +1,143 @@
+package vpn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Tailscale checks the node's state and its peers through the tailscaled
+// local API. Tailscale only builds a tunnel to a peer when there is
+// traffic for it, so a peer counts if the coordination server sees it
+// online; MaxAge additionally requires a recent handshake, for peers kept
+// busy, e.g. an exit node.
+type Tailscale struct {
+	// Peers are the host names, or MagicDNS names, that must be online;
+	// none only checks the node itself
+	Peers []string
+	// MaxAge is how long ago the last handshake with each peer may be
+	// (0 = not checked)
+	MaxAge time.Duration
+
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewTailscale creates a Tailscale check against the local API on socket,
+// which on Linux is /run/tailscale/tailscaled.sock.
+func NewTailscale(socket string, peers []string, maxAge, timeout time.Duration) *Tailscale {
+	return &Tailscale{
+		Peers:  peers,
+		MaxAge: maxAge,
+		httpClient: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+type tailscaleStatus struct {
+	BackendState string                    `json:"BackendState"`
+	Health       []string                  `json:"Health"`
+	Peer         map[string]*tailscalePeer `json:"Peer"`
+}
+
+type tailscalePeer struct {
+	HostName      string    `json:"HostName"`
+	DNSName       string    `json:"DNSName"` // e.g. "nas.tailnet-1234.ts.net."
+	Online        bool      `json:"Online"`
+	LastHandshake time.Time `json:"LastHandshake"`
+}
+
+// matches reports whether name is the peer's host name, or its MagicDNS
+// name, in full or the first label.
+func (p *tailscalePeer) matches(name string) bool {
+	dns := strings.TrimSuffix(p.DNSName, ".")
+	short, _, _ := strings.Cut(dns, ".")
+	return strings.EqualFold(name, p.HostName) || strings.EqualFold(name, dns) || strings.EqualFold(name, short)
+}
+
+// Name returns the tunnel name.
+func (t *Tailscale) Name() string {
+	return "tailscale"
+}
+
+// Health returns nil if the node is logged in and running and each
+// expected peer is online.
+func (t *Tailscale) Health(ctx context.Context) error {
+	status, err := t.status(ctx)
+	if err != nil {
+		return err
+	}
+	if status.BackendState != "Running" {
+		// e.g. NeedsLogin after the node key expired
+		msg := fmt.Sprintf("state %s", status.BackendState)
+		if len(status.Health) > 0 {
+			msg += ": " + strings.Join(status.Health, "; ")
+		}
+		return errors.New(msg)
+	}
+
+	now := time.Now
+	if t.now != nil {
+		now = t.now
+	}
+	var problems []string
+	for _, name := range t.Peers {
+		var peer *tailscalePeer
+		for _, p := range status.Peer {
+			if p.matches(name) {
+				peer = p
+				break
+			}
+		}
+		switch {
+		case peer == nil:
+			problems = append(problems, fmt.Sprintf("peer %s not in the tailnet", name))
+		case !peer.Online:
+			problems = append(problems, fmt.Sprintf("peer %s offline", name))
+		case t.MaxAge > 0:
+			if problem := handshakeProblem(peer.LastHandshake, now(), t.MaxAge); problem != "" {
+				problems = append(problems, fmt.Sprintf("peer %s: %s", name, problem))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (t *Tailscale) status(ctx context.Context) (*tailscaleStatus, error) {
+	// The host is ignored by the dialer, but tailscaled checks it
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://local-tailscaled.sock/localapi/v0/status", nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Sec-Tailscale", "localapi")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	var status tailscaleStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &status, nil
+}
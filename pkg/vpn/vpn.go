@@ -0,0 +1,44 @@
+// Package vpn checks that the tunnels used for remote access (WireGuard or
+// Tailscale) are up and reaching their peers, since an update that breaks
+// them locks out whoever would fix it.
+package vpn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Tunnel is a VPN whose peers are checked
+type Tunnel interface {
+	Name() string
+	Health(ctx context.Context) error
+}
+
+// Health returns nil if every tunnel is healthy.
+func Health(ctx context.Context, tunnels []Tunnel) error {
+	var problems []string
+	for _, t := range tunnels {
+		if err := t.Health(ctx); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", t.Name(), err))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// handshakeProblem describes why a handshake at last doesn't count as
+// recent, or returns "" if it does. maxAge 0 accepts any handshake.
+func handshakeProblem(last, now time.Time, maxAge time.Duration) string {
+	switch {
+	case last.IsZero():
+		return "no handshake"
+	case maxAge > 0 && now.Sub(last) > maxAge:
+		return fmt.Sprintf("last handshake %s ago", now.Sub(last).Round(time.Second))
+	}
+	return ""
+}
@@ -0,0 +1,125 @@
+package vpn
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2026, 1, 10, 3, 0, 0, 0, time.UTC)
+
+const (
+	laptopKey = "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="
+	phoneKey  = "TrMvSoP4jYQlY6RIzBgbssQqY3vxI2Pi+y71lOWWXX0="
+	officeKey = "gN65BkIKy1eCE9pP1wdc8ROUtkHLF2PfAqYdyYBz6EA="
+)
+
+type fakeLister []Device
+
+func (f fakeLister) Devices() ([]Device, error) { return f, nil }
+
+func TestWireGuard(t *testing.T) {
+	lister := fakeLister{
+		{Name: "wg0", Peers: []Peer{
+			{PublicKey: laptopKey, LastHandshake: now.Add(-time.Minute)},
+			{PublicKey: phoneKey, LastHandshake: now.Add(-time.Hour)},
+		}},
+		{Name: "wg1", Peers: []Peer{
+			{PublicKey: officeKey},
+		}},
+	}
+	tests := []struct {
+		name       string
+		interfaces []string
+		peers      []string
+		wantErr    string
+	}{
+		{name: "recent", interfaces: []string{"wg0"}, peers: []string{"laptop=" + laptopKey}},
+		{name: "stale", interfaces: []string{"wg0"}, peers: []string{laptopKey, "phone=" + phoneKey},
+			wantErr: "peer phone: last handshake 1h0m0s ago"},
+		{name: "every peer", interfaces: []string{"wg0", "wg1"},
+			wantErr: "peer TrMvSoP4...: last handshake 1h0m0s ago; peer gN65BkIK...: no handshake"},
+		{name: "missing", interfaces: []string{"wg2"}, peers: []string{"office=" + officeKey},
+			wantErr: "interface wg2 not found; peer office not configured"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wg := &WireGuard{Lister: lister, Interfaces: tt.interfaces, Peers: tt.peers, MaxAge: 5 * time.Minute,
+				now: func() time.Time { return now }}
+			err := wg.Health(context.Background())
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Health() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Errorf("Health() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// tailscaleSocket serves status on a local API socket, returning its path
+func tailscaleSocket(t *testing.T, status string) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "tailscaled.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "local-tailscaled.sock" || r.URL.Path != "/localapi/v0/status" {
+			t.Errorf("unexpected request: %s %s", r.Host, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(status))
+	}))
+	server.Listener = l
+	server.Start()
+	t.Cleanup(server.Close)
+	return socket
+}
+
+func TestTailscale(t *testing.T) {
+	running := `{"BackendState": "Running", "Peer": {
+		"nodekey:1": {"HostName": "nas", "DNSName": "nas.tailnet-1234.ts.net.", "Online": true, "LastHandshake": "2026-01-10T02:59:00Z"},
+		"nodekey:2": {"HostName": "Pixel 8", "DNSName": "pixel-8.tailnet-1234.ts.net.", "Online": true, "LastHandshake": "0001-01-01T00:00:00Z"},
+		"nodekey:3": {"HostName": "office", "DNSName": "office.tailnet-1234.ts.net.", "Online": false}
+	}}`
+	tests := []struct {
+		name    string
+		status  string
+		peers   []string
+		maxAge  time.Duration
+		wantErr string
+	}{
+		{name: "online", status: running, peers: []string{"nas", "pixel-8", "Pixel 8"}},
+		{name: "handshake", status: running, peers: []string{"nas.tailnet-1234.ts.net", "pixel-8"}, maxAge: 5 * time.Minute,
+			wantErr: "peer pixel-8: no handshake"},
+		{name: "offline", status: running, peers: []string{"office", "printer"},
+			wantErr: "peer office offline; peer printer not in the tailnet"},
+		{name: "logged out", status: `{"BackendState": "NeedsLogin", "Health": ["not logged in"]}`,
+			wantErr: "state NeedsLogin: not logged in"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := NewTailscale(tailscaleSocket(t, tt.status), tt.peers, tt.maxAge, 5*time.Second)
+			ts.now = func() time.Time { return now }
+			err := ts.Health(context.Background())
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Health() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Errorf("Health() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	down := NewTailscale(filepath.Join(t.TempDir(), "missing.sock"), nil, 0, time.Second)
+	if err := Health(context.Background(), []Tunnel{down}); err == nil {
+		t.Error("Health() = nil with tailscaled down")
+	}
+}
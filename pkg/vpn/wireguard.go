@@ -0,0 +1,146 @@
+package vpn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.zx2c4.com/wireguard/wgctrl"
+)
+
+// Peer is a WireGuard peer
+type Peer struct {
+	PublicKey     string // base64
+	LastHandshake time.Time
+}
+
+// Device is a WireGuard interface and its peers
+type Device struct {
+	Name  string
+	Peers []Peer
+}
+
+// DeviceLister lists WireGuard devices
+type DeviceLister interface {
+	Devices() ([]Device, error)
+}
+
+// WGCtrl lists the devices of the kernel module, or of a userspace
+// implementation such as wireguard-go, through wgctrl. Reading them needs
+// CAP_NET_ADMIN. It opens the client on first use.
+type WGCtrl struct {
+	mu     sync.Mutex
+	client *wgctrl.Client
+}
+
+// Devices returns every WireGuard device.
+func (w *WGCtrl) Devices() ([]Device, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.client == nil {
+		client, err := wgctrl.New()
+		if err != nil {
+			return nil, fmt.Errorf("opening wgctrl: %w", err)
+		}
+		w.client = client
+	}
+
+	devices, err := w.client.Devices()
+	if err != nil {
+		return nil, fmt.Errorf("listing devices: %w", err)
+	}
+	out := make([]Device, 0, len(devices))
+	for _, d := range devices {
+		dev := Device{Name: d.Name}
+		for _, p := range d.Peers {
+			dev.Peers = append(dev.Peers, Peer{PublicKey: p.PublicKey.String(), LastHandshake: p.LastHandshakeTime})
+		}
+		out = append(out, dev)
+	}
+	return out, nil
+}
+
+// WireGuard checks the handshakes of WireGuard peers. A peer only
+// handshakes while there is traffic, so the expected ones need
+// PersistentKeepalive set, as a remote access tunnel usually has.
+type WireGuard struct {
+	Lister DeviceLister
+	// Interfaces are the devices to check
+	Interfaces []string
+	// Peers are the public keys that must have handshaken, each optionally
+	// named, e.g. "laptop=<key>"; none requires every peer of Interfaces
+	Peers []string
+	// MaxAge is how long ago the last handshake may be (0 = any)
+	MaxAge time.Duration
+
+	now func() time.Time
+}
+
+// Name returns the tunnel name.
+func (w *WireGuard) Name() string {
+	return "wireguard"
+}
+
+// Health returns nil if each interface exists and each expected peer
+// handshook within MaxAge.
+func (w *WireGuard) Health(ctx context.Context) error {
+	devices, err := w.Lister.Devices()
+	if err != nil {
+		return err
+	}
+	now := time.Now
+	if w.now != nil {
+		now = w.now
+	}
+
+	var problems []string
+	var all []string
+	peers := make(map[string]Peer)
+	for _, name := range w.Interfaces {
+		i := slices.IndexFunc(devices, func(d Device) bool { return d.Name == name })
+		if i < 0 {
+			problems = append(problems, fmt.Sprintf("interface %s not found", name))
+			continue
+		}
+		for _, p := range devices[i].Peers {
+			peers[p.PublicKey] = p
+			all = append(all, p.PublicKey)
+		}
+	}
+
+	expected := w.Peers
+	if len(expected) == 0 {
+		expected = all
+	}
+	for _, e := range expected {
+		name, key, ok := strings.Cut(e, "=")
+		if !ok || key == "" {
+			// A bare key: the "=" was its base64 padding
+			name, key = shortKey(e), e
+		}
+		p, ok := peers[key]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("peer %s not configured", name))
+			continue
+		}
+		if problem := handshakeProblem(p.LastHandshake, now(), w.MaxAge); problem != "" {
+			problems = append(problems, fmt.Sprintf("peer %s: %s", name, problem))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// shortKey abbreviates a public key the way wg(8) users recognise it.
+func shortKey(key string) string {
+	if len(key) > 8 {
+		return key[:8] + "..."
+	}
+	return key
+}
@@ -0,0 +1,176 @@
+package wan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/metrics"
+)
+
+// Limits are the thresholds below which the connection counts as degraded;
+// zero disables a limit
+type Limits struct {
+	MaxLatency   time.Duration
+	MaxLoss      float64 // fraction, 0 to 1
+	MinDownload  float64 // bits/s, from speedtest-tracker
+	MinUpload    float64 // bits/s, from speedtest-tracker
+	MaxTestAge   time.Duration
+	MinReflected int // reflectors that must answer at all
+}
+
+// DefaultLimits suit a typical broadband connection
+var DefaultLimits = Limits{
+	MaxLatency:   100 * time.Millisecond,
+	MaxLoss:      0.2,
+	MinReflected: 1,
+}
+
+// Report is one measurement of the connection
+type Report struct {
+	Samples   []Sample
+	Speedtest *Speedtest
+	// SpeedtestErr is why the speedtest result couldn't be fetched
+	SpeedtestErr error
+}
+
+// Checker measures WAN quality. It has no Check method: a poor connection
+// isn't a reason to hold up a shutdown, so it only reports.
+type Checker struct {
+	Reflectors []string
+	Count      int
+	Timeout    time.Duration
+	Tracker    *SpeedtestTracker // nil skips speedtest-tracker
+	Limits     Limits
+
+	mu   sync.Mutex
+	last *Report
+
+	now func() time.Time // replaced in tests
+}
+
+// NewChecker creates a WAN checker probing each reflector count times.
+func NewChecker(reflectors []string, tracker *SpeedtestTracker) *Checker {
+	return &Checker{
+		Reflectors: reflectors,
+		Count:      5,
+		Timeout:    2 * time.Second,
+		Tracker:    tracker,
+		Limits:     DefaultLimits,
+		now:        time.Now,
+	}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "wan"
+}
+
+// Measure probes the reflectors and fetches the latest speedtest, and
+// keeps the report for Gauges.
+func (c *Checker) Measure(ctx context.Context) *Report {
+	r := &Report{}
+	for _, addr := range c.Reflectors {
+		r.Samples = append(r.Samples, Probe(ctx, addr, c.Count, c.Timeout))
+	}
+	if c.Tracker != nil {
+		r.Speedtest, r.SpeedtestErr = c.Tracker.Latest(ctx)
+	}
+
+	c.mu.Lock()
+	c.last = r
+	c.mu.Unlock()
+	return r
+}
+
+// Problems describes each limit the report exceeds.
+func (c *Checker) Problems(r *Report) []string {
+	l := c.Limits
+	var problems []string
+	reflected := 0
+	for _, s := range r.Samples {
+		if s.Received == 0 {
+			problems = append(problems, fmt.Sprintf("%s unreachable", s.Target))
+			continue
+		}
+		reflected++
+		if l.MaxLoss > 0 && s.Loss() > l.MaxLoss {
+			problems = append(problems, fmt.Sprintf("%s loss %.0f%%", s.Target, s.Loss()*100))
+		}
+		if l.MaxLatency > 0 && s.Latency > l.MaxLatency {
+			problems = append(problems, fmt.Sprintf("%s latency %s", s.Target, s.Latency.Round(time.Millisecond)))
+		}
+	}
+	if len(r.Samples) > 0 && reflected < l.MinReflected {
+		problems = append(problems, fmt.Sprintf("%d of %d reflectors answered", reflected, len(r.Samples)))
+	}
+
+	if r.SpeedtestErr != nil {
+		problems = append(problems, fmt.Sprintf("speedtest: %v", r.SpeedtestErr))
+	}
+	if st := r.Speedtest; st != nil {
+		if st.Status != "" && st.Status != "completed" {
+			problems = append(problems, fmt.Sprintf("speedtest %s", st.Status))
+		} else {
+			if l.MinDownload > 0 && st.DownloadBits < l.MinDownload {
+				problems = append(problems, fmt.Sprintf("download %.1f Mbit/s", st.DownloadBits/1e6))
+			}
+			if l.MinUpload > 0 && st.UploadBits < l.MinUpload {
+				problems = append(problems, fmt.Sprintf("upload %.1f Mbit/s", st.UploadBits/1e6))
+			}
+		}
+		if t := st.Time(); l.MaxTestAge > 0 && !t.IsZero() && c.now().Sub(t) > l.MaxTestAge {
+			problems = append(problems, fmt.Sprintf("last speedtest %s ago", c.now().Sub(t).Truncate(time.Minute)))
+		}
+	}
+	return problems
+}
+
+// Health measures the connection and returns an error describing any
+// problems.
+func (c *Checker) Health(ctx context.Context) error {
+	if problems := c.Problems(c.Measure(ctx)); len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Gauges returns the last report as metrics.
+func (c *Checker) Gauges() []metrics.Gauge {
+	c.mu.Lock()
+	r := c.last
+	c.mu.Unlock()
+	if r == nil {
+		return nil
+	}
+
+	degraded := 0.0
+	if len(c.Problems(r)) > 0 {
+		degraded = 1
+	}
+	gauges := []metrics.Gauge{
+		{Name: "homelab_wan_degraded", Help: "Whether the connection is outside its limits (1) or not (0).", Value: degraded},
+	}
+	for _, s := range r.Samples {
+		labels := map[string]string{"target": s.Target}
+		gauges = append(gauges,
+			metrics.Gauge{Name: "homelab_wan_probe_loss_ratio", Help: "Fraction of connection probes to the reflector that failed.", Labels: labels, Value: s.Loss()},
+		)
+		if s.Received > 0 {
+			gauges = append(gauges,
+				metrics.Gauge{Name: "homelab_wan_probe_latency_seconds", Help: "Average time to connect to the reflector.", Labels: labels, Value: s.Latency.Seconds()},
+			)
+		}
+	}
+	if st := r.Speedtest; st != nil {
+		gauges = append(gauges,
+			metrics.Gauge{Name: "homelab_wan_speedtest_ping_seconds", Help: "Ping of the latest speedtest.", Value: st.Ping / 1000},
+			metrics.Gauge{Name: "homelab_wan_speedtest_download_bits_per_second", Help: "Download speed of the latest speedtest.", Value: st.DownloadBits},
+			metrics.Gauge{Name: "homelab_wan_speedtest_upload_bits_per_second", Help: "Upload speed of the latest speedtest.", Value: st.UploadBits},
+		)
+	}
+	return gauges
+}
@@ -0,0 +1,113 @@
+package wan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultPortalURL answers 204 No Content to anyone on the open internet;
+// anything else means a captive portal or proxy intercepted it
+const DefaultPortalURL = "http://connectivitycheck.gstatic.com/generate_204"
+
+// Internet checks that the internet is reachable at all: that HTTPS URLs
+// answer and, if none do, whether a captive portal is in the way. It is
+// separate from the LAN check (upstream) and from the quality
+// measurements, so an outage at the provider can be a warning while a
+// broken LAN fails the boot.
+type Internet struct {
+	// URLs are fetched with HEAD; any response from any of them counts,
+	// since a TLS handshake can't be faked by a portal
+	URLs []string
+	// PortalURL is fetched over plain HTTP when no URL answers, to tell a
+	// captive portal from an outage; empty skips it
+	PortalURL string
+	Client    *http.Client
+}
+
+// NewInternet creates an internet check with a timeout per request.
+func NewInternet(urls []string, portalURL string, timeout time.Duration) *Internet {
+	return &Internet{
+		URLs:      urls,
+		PortalURL: portalURL,
+		Client: &http.Client{
+			Timeout: timeout,
+			// A portal shows itself by redirecting
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Name returns the check name.
+func (i *Internet) Name() string {
+	return "internet"
+}
+
+// Health returns nil if any URL answers, or an error saying why none did.
+func (i *Internet) Health(ctx context.Context) error {
+	if len(i.URLs) == 0 {
+		return errors.New("no URLs to check")
+	}
+	var failures []string
+	for _, u := range i.URLs {
+		err := i.head(ctx, u)
+		if err == nil {
+			return nil
+		}
+		failures = append(failures, err.Error())
+	}
+	if portal := i.portal(ctx); portal != "" {
+		return fmt.Errorf("captive portal: %s", portal)
+	}
+	return fmt.Errorf("internet unreachable: %s", strings.Join(failures, "; "))
+}
+
+func (i *Internet) head(ctx context.Context, u string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", u, err)
+	}
+	resp, err := i.Client.Do(req)
+	if err != nil {
+		// The *url.Error already names the URL
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// portal describes a captive portal intercepting PortalURL, or returns ""
+// if there is none or it can't be told.
+func (i *Internet) portal(ctx context.Context) string {
+	if i.PortalURL == "" {
+		return ""
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, i.PortalURL, nil)
+	if err != nil {
+		return ""
+	}
+	resp, err := i.Client.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode == http.StatusNoContent:
+		return ""
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		if loc, err := url.Parse(resp.Header.Get("Location")); err == nil && loc.Host != "" {
+			return "redirected to " + loc.Host
+		}
+		return fmt.Sprintf("redirected (status %d)", resp.StatusCode)
+	}
+	return fmt.Sprintf("%s answered status %d, not 204", i.PortalURL, resp.StatusCode)
+}
@@ -0,0 +1,63 @@
+// Package wan measures the quality of the internet connection: latency and
+// loss to a few reflectors, and the latest result from a speedtest-tracker
+// instance. It never blocks shutdown; a poor connection is reported as a
+// warning and as metrics.
+package wan
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// Sample is the outcome of probing one reflector
+type Sample struct {
+	Target   string
+	Sent     int
+	Received int
+	// Latency is the average time to connect, over the probes that did
+	Latency time.Duration
+}
+
+// Loss is the fraction of probes that failed, from 0 to 1.
+func (s Sample) Loss() float64 {
+	if s.Sent == 0 {
+		return 0
+	}
+	return float64(s.Sent-s.Received) / float64(s.Sent)
+}
+
+// dial opens a connection; replaced in tests
+var dial = func(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", addr)
+}
+
+// Probe connects to addr, e.g. "1.1.1.1:443", count times in a row and
+// times each TCP handshake, which needs no privileges unlike ICMP echo. A
+// connection that takes longer than timeout counts as lost.
+func Probe(ctx context.Context, addr string, count int, timeout time.Duration) Sample {
+	s := Sample{Target: addr}
+	var total time.Duration
+	for i := 0; i < count; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+		s.Sent++
+		pctx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		conn, err := dial(pctx, addr)
+		elapsed := time.Since(start)
+		cancel()
+		if err != nil {
+			continue
+		}
+		conn.Close()
+		s.Received++
+		total += elapsed
+	}
+	if s.Received > 0 {
+		s.Latency = total / time.Duration(s.Received)
+	}
+	return s
+}
@@ -0,0 +1,84 @@
+package wan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrUnauthorized is returned when speedtest-tracker rejects the API token
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Speedtest is a speedtest-tracker result
+type Speedtest struct {
+	ID           int     `json:"id"`
+	Ping         float64 `json:"ping"`          // ms
+	DownloadBits float64 `json:"download_bits"` // bits/s
+	UploadBits   float64 `json:"upload_bits"`   // bits/s
+	Status       string  `json:"status"`        // completed, failed, ...
+	CreatedAt    string  `json:"created_at"`
+}
+
+// Time returns when the test ran, or the zero time if it can't be parsed.
+func (s *Speedtest) Time() time.Time {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, s.CreatedAt); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// SpeedtestTracker is a client for the speedtest-tracker API
+type SpeedtestTracker struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewSpeedtestTracker creates a client. The API token needs the
+// results:read ability.
+func NewSpeedtestTracker(baseURL, token string, timeout time.Duration) *SpeedtestTracker {
+	return &SpeedtestTracker{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// Latest returns the most recent result.
+func (t *SpeedtestTracker) Latest(ctx context.Context) (*Speedtest, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", t.baseURL+"/api/v1/results/latest", nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+t.token)
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("unexpected status: %d: %w", resp.StatusCode, ErrUnauthorized)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data Speedtest `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &body.Data, nil
+}
@@ -0,0 +1,194 @@
+package wan
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDial answers for "good:1" and "slow:1" and fails every other attempt
+// for "lossy:1"
+func fakeDial(t *testing.T) {
+	t.Helper()
+	orig := dial
+	t.Cleanup(func() { dial = orig })
+	attempts := 0
+	dial = func(ctx context.Context, addr string) (net.Conn, error) {
+		switch addr {
+		case "good:1":
+		case "slow:1":
+			time.Sleep(20 * time.Millisecond)
+		case "lossy:1":
+			attempts++
+			if attempts%2 == 0 {
+				return nil, errors.New("timeout")
+			}
+		default:
+			return nil, errors.New("connection refused")
+		}
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	}
+}
+
+func TestProbe(t *testing.T) {
+	fakeDial(t)
+
+	s := Probe(context.Background(), "lossy:1", 4, time.Second)
+	if s.Sent != 4 || s.Received != 2 || s.Loss() != 0.5 {
+		t.Errorf("Probe(lossy) = %+v, loss %v", s, s.Loss())
+	}
+	s = Probe(context.Background(), "slow:1", 2, time.Second)
+	if s.Latency < 20*time.Millisecond {
+		t.Errorf("Probe(slow) latency = %v, want at least 20ms", s.Latency)
+	}
+	s = Probe(context.Background(), "down:1", 3, time.Second)
+	if s.Received != 0 || s.Loss() != 1 {
+		t.Errorf("Probe(down) = %+v", s)
+	}
+}
+
+func TestProblems(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := NewChecker(nil, nil)
+	c.now = func() time.Time { return now }
+	c.Limits.MinDownload = 100e6
+	c.Limits.MaxTestAge = 2 * time.Hour
+
+	tests := []struct {
+		name   string
+		report *Report
+		want   string
+	}{
+		{
+			name: "good",
+			report: &Report{
+				Samples:   []Sample{{Target: "a", Sent: 5, Received: 5, Latency: 20 * time.Millisecond}},
+				Speedtest: &Speedtest{DownloadBits: 500e6, Status: "completed", CreatedAt: "2024-06-01T11:00:00.000000Z"},
+			},
+		},
+		{
+			name: "degraded",
+			report: &Report{
+				Samples: []Sample{
+					{Target: "a", Sent: 5, Received: 2, Latency: 150 * time.Millisecond},
+					{Target: "b", Sent: 5, Received: 0},
+				},
+				Speedtest: &Speedtest{DownloadBits: 12.5e6, Status: "completed", CreatedAt: "2024-06-01 06:00:00"},
+			},
+			want: "a loss 60%|a latency 150ms|b unreachable|download 12.5 Mbit/s|last speedtest 6h0m0s ago",
+		},
+		{
+			name: "no reflector answers",
+			report: &Report{
+				Samples: []Sample{{Target: "a", Sent: 5}},
+			},
+			want: "a unreachable|0 of 1 reflectors answered",
+		},
+		{
+			name: "speedtest failed",
+			report: &Report{
+				Speedtest: &Speedtest{Status: "failed", CreatedAt: "2024-06-01T11:30:00Z"},
+			},
+			want: "speedtest failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(c.Problems(tt.report), "|"); got != tt.want {
+				t.Errorf("Problems() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpeedtestTracker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/results/latest" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data": {"id": 7, "ping": 11.5, "download_bits": 250000000, "upload_bits": 40000000,
+			"status": "completed", "created_at": "2024-06-01T11:00:00.000000Z"}}`))
+	}))
+	defer server.Close()
+
+	st, err := NewSpeedtestTracker(server.URL, "tok", 5*time.Second).Latest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.ID != 7 || st.DownloadBits != 250e6 || !st.Time().Equal(time.Date(2024, 6, 1, 11, 0, 0, 0, time.UTC)) {
+		t.Errorf("Latest() = %+v", st)
+	}
+
+	_, err = NewSpeedtestTracker(server.URL, "wrong", 5*time.Second).Latest(context.Background())
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Latest() error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestHealthAndGauges(t *testing.T) {
+	fakeDial(t)
+	c := NewChecker([]string{"good:1", "down:1"}, nil)
+	if c.Gauges() != nil {
+		t.Error("Gauges() before a measurement, want none")
+	}
+
+	err := c.Health(context.Background())
+	if err == nil || err.Error() != "down:1 unreachable" {
+		t.Errorf("Health() = %v, want down:1 unreachable", err)
+	}
+
+	names := map[string]float64{}
+	for _, g := range c.Gauges() {
+		names[g.Name+"{"+g.Labels["target"]+"}"] = g.Value
+	}
+	if names["homelab_wan_degraded{}"] != 1 || names["homelab_wan_probe_loss_ratio{down:1}"] != 1 {
+		t.Errorf("Gauges() = %v", names)
+	}
+	if _, ok := names["homelab_wan_probe_latency_seconds{down:1}"]; ok {
+		t.Error("latency reported for an unreachable reflector")
+	}
+}
+
+func TestInternet(t *testing.T) {
+	site := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer site.Close()
+	var portalStatus int
+	portal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if portalStatus == http.StatusFound {
+			http.Redirect(w, r, "http://login.hotspot.example/", http.StatusFound)
+			return
+		}
+		w.WriteHeader(portalStatus)
+	}))
+	defer portal.Close()
+
+	i := NewInternet([]string{"https://127.0.0.1:1/", site.URL}, portal.URL, time.Second)
+	i.Client.Transport = site.Client().Transport
+	if err := i.Health(context.Background()); err != nil {
+		t.Errorf("Health() with one URL answering = %v", err)
+	}
+
+	i.URLs = i.URLs[:1]
+	portalStatus = http.StatusFound
+	err := i.Health(context.Background())
+	if err == nil || err.Error() != "captive portal: redirected to login.hotspot.example" {
+		t.Errorf("Health() behind a portal = %v", err)
+	}
+
+	portalStatus = http.StatusNoContent
+	err = i.Health(context.Background())
+	if err == nil || !strings.HasPrefix(err.Error(), "internet unreachable: ") {
+		t.Errorf("Health() during an outage = %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Checker implements check.Checker for ZFS replication.
+// Returns an error while a send or receive is running, since an
+// interrupted incremental stream has to be started over, or resumed only if
+// the receiving side kept a resume token.
+type Checker struct {
+	Detector *Detector
+}
+
+// NewChecker creates a ZFS replication checker.
+func NewChecker(d *Detector) *Checker {
+	return &Checker{Detector: d}
+}
+
+// Name returns the check name.
+func (c *Checker) Name() string {
+	return "zfs"
+}
+
+// Check returns nil if no replication is running, error otherwise.
+func (c *Checker) Check(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	active, err := c.Detector.Active()
+	if err != nil {
+		return fmt.Errorf("zfs check failed: %w", err)
+	}
+	if len(active) > 0 {
+		return fmt.Errorf("zfs replication in progress: %s", strings.Join(active, "; "))
+	}
+	return nil
+}
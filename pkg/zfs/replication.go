@@ -0,0 +1,43 @@
+// Package zfs detects ZFS replication in progress and checks that datasets
+// are still being snapshotted.
+package zfs
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/procscan"
+)
+
+// DefaultProcessPattern matches syncoid runs and the "zfs send" and
+// "zfs receive" processes that syncoid, zrepl and hand-written pipelines
+// spawn for each transfer. syncoid is a Perl script, so its command line
+// starts with the interpreter.
+const DefaultProcessPattern = `(^|/)syncoid(\s|$)|(^|/)zfs\s+(send|receive|recv)\b`
+
+// Detector looks for replication jobs in progress
+type Detector struct {
+	// ProcessPattern matches replication command lines
+	ProcessPattern *regexp.Regexp
+	// ProcRoot defaults to /proc
+	ProcRoot string
+}
+
+// Active returns a description of each replication process running.
+func (d *Detector) Active() ([]string, error) {
+	pattern := d.ProcessPattern
+	if pattern == nil {
+		pattern = regexp.MustCompile(DefaultProcessPattern)
+	}
+
+	procs, err := procscan.Find(d.ProcRoot, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("scan processes: %w", err)
+	}
+
+	var active []string
+	for _, p := range procs {
+		active = append(active, fmt.Sprintf("pid %d: %s", p.PID, p.Cmdline))
+	}
+	return active, nil
+}
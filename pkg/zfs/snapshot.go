@@ -0,0 +1,95 @@
+package zfs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/addisonbair/homelab-sidecars/pkg/privexec"
+)
+
+// Snapshot is a ZFS snapshot
+type Snapshot struct {
+	Name    string // e.g. "tank/data@autosnap_2024-03-01_00:00:01_daily"
+	Created time.Time
+}
+
+// Lister lists snapshots with "zfs list"
+type Lister struct {
+	Runner privexec.Runner
+	// Command defaults to "zfs"
+	Command []string
+}
+
+// Newest returns the most recent snapshot of dataset itself, not of its
+// children, or nil if it has none.
+func (l *Lister) Newest(ctx context.Context, dataset string) (*Snapshot, error) {
+	command := l.Command
+	if len(command) == 0 {
+		command = []string{"zfs"}
+	}
+	args := append(append([]string{}, command[1:]...), "list", "-H", "-p", "-t", "snapshot",
+		"-o", "name,creation", "-s", "creation", "-d", "1", dataset)
+	out, err := l.Runner.Output(ctx, command[0], args...)
+	if err != nil {
+		return nil, err
+	}
+
+	snaps, err := parseSnapshots(out)
+	if err != nil {
+		return nil, err
+	}
+	if len(snaps) == 0 {
+		return nil, nil
+	}
+	// Sorted oldest first
+	return &snaps[len(snaps)-1], nil
+}
+
+// parseSnapshots parses "zfs list -H -p -o name,creation" output, which
+// is tab-separated with the creation time in Unix seconds.
+func parseSnapshots(out []byte) ([]Snapshot, error) {
+	var snaps []Snapshot
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		name, created, ok := strings.Cut(line, "\t")
+		if !ok {
+			return nil, fmt.Errorf("unexpected zfs list output: %q", line)
+		}
+		secs, err := strconv.ParseInt(strings.TrimSpace(created), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse creation of %s: %w", name, err)
+		}
+		snaps = append(snaps, Snapshot{Name: name, Created: time.Unix(secs, 0)})
+	}
+	return snaps, scanner.Err()
+}
+
+// Stale returns a description of each dataset whose newest snapshot is
+// older than maxAge, or that has none, e.g. after sanoid stopped running.
+func (l *Lister) Stale(ctx context.Context, datasets []string, maxAge time.Duration, now time.Time) ([]string, error) {
+	var stale []string
+	for _, ds := range datasets {
+		snap, err := l.Newest(ctx, ds)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ds, err)
+		}
+		if snap == nil {
+			stale = append(stale, fmt.Sprintf("%s has no snapshots", ds))
+			continue
+		}
+		if age := now.Sub(snap.Created); age > maxAge {
+			stale = append(stale, fmt.Sprintf("%s newest snapshot is %s old (%s)",
+				ds, age.Round(time.Minute), snap.Name))
+		}
+	}
+	return stale, nil
+}
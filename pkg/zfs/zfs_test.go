@@ -0,0 +1,91 @@
+package zfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultProcessPattern(t *testing.T) {
+	pattern := regexp.MustCompile(DefaultProcessPattern)
+
+	tests := []struct {
+		cmdline string
+		want    bool
+	}{
+		{"/usr/bin/perl /usr/sbin/syncoid --recursive tank/data backup@nas:tank/data", true},
+		{"zfs send -I tank/data@a tank/data@b", true},
+		{"/sbin/zfs receive -s -F backup/data", true},
+		{"zfs recv backup/data", true},
+		{"/usr/bin/zrepl daemon", false},
+		{"/usr/sbin/sanoid --cron", false},
+		{"zfs list -t snapshot", false},
+	}
+
+	for _, tt := range tests {
+		if got := pattern.MatchString(tt.cmdline); got != tt.want {
+			t.Errorf("MatchString(%q) = %v, want %v", tt.cmdline, got, tt.want)
+		}
+	}
+}
+
+func TestDetector_Active(t *testing.T) {
+	root := t.TempDir()
+	d := &Detector{ProcRoot: root}
+
+	os.MkdirAll(filepath.Join(root, "100"), 0755)
+	os.WriteFile(filepath.Join(root, "100", "cmdline"), []byte("/usr/bin/zrepl\x00daemon\x00"), 0644)
+	if active, err := d.Active(); err != nil || len(active) != 0 {
+		t.Fatalf("idle: active = %v, err = %v", active, err)
+	}
+
+	// zrepl spawns zfs send for each step of a replication
+	os.MkdirAll(filepath.Join(root, "200"), 0755)
+	os.WriteFile(filepath.Join(root, "200", "cmdline"), []byte("zfs\x00send\x00-w\x00tank/data@zrepl_1\x00"), 0644)
+	if active, _ := d.Active(); len(active) != 1 || !strings.Contains(active[0], "pid 200") {
+		t.Errorf("zfs send: active = %v", active)
+	}
+}
+
+func TestLister_Stale(t *testing.T) {
+	now := time.Unix(1709337600, 0) // 2024-03-02 00:00 UTC
+
+	// "sh -c SCRIPT" stands in for zfs, with "list" landing in $0 and the
+	// dataset last
+	script := `for ds; do :; done
+case "$ds" in
+tank/data) printf 'tank/data@daily_0229\t1709164800\ntank/data@daily_0301\t1709251200\n' ;;
+tank/media) printf 'tank/media@weekly_0220\t1708387200\n' ;;
+tank/empty) ;;
+*) echo "cannot open '$ds': dataset does not exist" >&2; exit 1 ;;
+esac`
+	l := &Lister{Command: []string{"sh", "-c", script}}
+
+	snap, err := l.Newest(context.Background(), "tank/data")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if snap.Name != "tank/data@daily_0301" || !snap.Created.Equal(time.Unix(1709251200, 0)) {
+		t.Errorf("Newest = %+v, want tank/data@daily_0301", snap)
+	}
+
+	stale, err := l.Stale(context.Background(), []string{"tank/data", "tank/media", "tank/empty"}, 25*time.Hour, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		"tank/media newest snapshot is 264h0m0s old (tank/media@weekly_0220)",
+		"tank/empty has no snapshots",
+	}
+	if strings.Join(stale, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Stale = %q, want %q", stale, want)
+	}
+
+	if _, err := l.Stale(context.Background(), []string{"tank/missing"}, time.Hour, now); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("missing dataset: err = %v", err)
+	}
+}